@@ -0,0 +1,62 @@
+package aggregator
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// DefaultAgentTimeout the default duration without results after which an
+// agent is considered as lost
+const DefaultAgentTimeout = healthcheck.Duration(5 * time.Minute)
+
+// DefaultRetention the default duration during which the results of an
+// agent are kept
+const DefaultRetention = healthcheck.Duration(24 * time.Hour)
+
+// DefaultSignatureTolerance the default maximum difference between the
+// timestamp of a signed payload and the current time
+const DefaultSignatureTolerance = healthcheck.Duration(5 * time.Minute)
+
+// Configuration the aggregator configuration
+type Configuration struct {
+	// AgentTimeout the agents not sending results during the timeout are
+	// lost, their results are not used to compute the fleet health
+	AgentTimeout healthcheck.Duration `yaml:"agent-timeout"`
+	// Retention the results not updated during the retention are removed
+	Retention healthcheck.Duration
+	// Secret the secret of the HMAC signatures of the pushed results. The
+	// unsigned results are rejected if set.
+	Secret string
+	// SignatureTolerance the signed results whose timestamp differs from
+	// the current time by more than the tolerance are rejected as replays
+	SignatureTolerance healthcheck.Duration `yaml:"signature-tolerance"`
+}
+
+// UnmarshalYAML parses the aggregator configuration from YAML
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the aggregator configuration")
+	}
+	if raw.AgentTimeout == 0 {
+		raw.AgentTimeout = DefaultAgentTimeout
+	}
+	if raw.Retention == 0 {
+		raw.Retention = DefaultRetention
+	}
+	if raw.SignatureTolerance == 0 {
+		raw.SignatureTolerance = DefaultSignatureTolerance
+	}
+	if raw.SignatureTolerance < 0 {
+		return errors.New("The aggregator signature tolerance should be positive")
+	}
+	if raw.Retention < raw.AgentTimeout {
+		return errors.New("The aggregator retention should be greater than the agent timeout")
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
@@ -0,0 +1,244 @@
+package aggregator
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/memorystore"
+	"github.com/appclacks/cabourotte/signature"
+)
+
+// Agent a Cabourotte node pushing its results to the aggregator
+type Agent struct {
+	Name      string            `json:"name"`
+	Region    string            `json:"region,omitempty"`
+	Zone      string            `json:"zone,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	LastSeen  time.Time         `json:"last-seen"`
+	Lost      bool              `json:"lost"`
+	Healthy   int               `json:"healthy"`
+	Unhealthy int               `json:"unhealthy"`
+}
+
+// Health the health of an healthcheck across the agents executing it. The
+// state is degraded if the healthcheck is only unhealthy on some agents.
+type Health struct {
+	ID        string   `json:"id"`
+	State     string   `json:"state"`
+	Healthy   []string `json:"healthy"`
+	Unhealthy []string `json:"unhealthy"`
+}
+
+// agent the state of an agent
+type agent struct {
+	node     healthcheck.Node
+	lastSeen time.Time
+	results  map[string]healthcheck.Result
+}
+
+// Aggregator stores the results pushed by the agents
+type Aggregator struct {
+	logger *zap.Logger
+	config *Configuration
+	lock   sync.RWMutex
+	agents map[string]*agent
+	tick   *time.Ticker
+	t      tomb.Tomb
+}
+
+// New creates a new aggregator
+func New(logger *zap.Logger, config *Configuration) *Aggregator {
+	return &Aggregator{
+		logger: logger,
+		config: config,
+		agents: make(map[string]*agent),
+	}
+}
+
+// Start starts the aggregator, which periodically removes the expired
+// results
+func (a *Aggregator) Start() {
+	a.logger.Info("Starting the aggregator")
+	a.tick = time.NewTicker(time.Minute)
+	a.t.Go(func() error {
+		for {
+			select {
+			case <-a.tick.C:
+				a.purge(time.Now())
+			case <-a.t.Dying():
+				return nil
+			}
+		}
+	})
+}
+
+// Stop stops the aggregator
+func (a *Aggregator) Stop() error {
+	a.logger.Info("Stopping the aggregator")
+	a.tick.Stop()
+	a.t.Kill(nil)
+	return a.t.Wait()
+}
+
+// Verify verifies the signature of the results pushed by an agent, if a
+// secret is configured
+func (a *Aggregator) Verify(timestamp string, sig string, payload []byte, now time.Time) error {
+	if a.config.Secret == "" {
+		return nil
+	}
+	return signature.Verify(a.config.Secret, timestamp, sig, payload, now, time.Duration(a.config.SignatureTolerance))
+}
+
+// Add stores the results pushed by an agent. The agent is identified by
+// the node of the results, and by name if the node identity is not
+// configured on the agent.
+func (a *Aggregator) Add(name string, results []healthcheck.Result, now time.Time) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for _, result := range results {
+		if result.Node == nil || result.Node.Name == "" {
+			result.Node = &healthcheck.Node{Name: name}
+		}
+		node := *result.Node
+		current, ok := a.agents[node.Name]
+		if !ok {
+			a.logger.Info("New agent registered", zap.String("agent", node.Name))
+			current = &agent{results: make(map[string]healthcheck.Result)}
+			a.agents[node.Name] = current
+		}
+		current.node = node
+		current.lastSeen = now
+		current.results[result.ID()] = result
+	}
+}
+
+// purge removes the expired results and the agents without results
+func (a *Aggregator) purge(now time.Time) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	retention := time.Duration(a.config.Retention)
+	for name, agent := range a.agents {
+		for id, result := range agent.results {
+			if now.Sub(time.Unix(result.HealthcheckTimestamp, 0)) > retention {
+				delete(agent.results, id)
+			}
+		}
+		if len(agent.results) == 0 || now.Sub(agent.lastSeen) > retention {
+			a.logger.Info("Removing the agent", zap.String("agent", name))
+			delete(a.agents, name)
+		}
+	}
+}
+
+// lost returns true if the agent did not send results during the timeout
+func (a *Aggregator) lost(agent *agent, now time.Time) bool {
+	return now.Sub(agent.lastSeen) > time.Duration(a.config.AgentTimeout)
+}
+
+// Agents returns the agents, sorted by name
+func (a *Aggregator) Agents(now time.Time) []Agent {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	result := make([]Agent, 0, len(a.agents))
+	for _, agent := range a.agents {
+		current := Agent{
+			Name:     agent.node.Name,
+			Region:   agent.node.Region,
+			Zone:     agent.node.Zone,
+			Labels:   agent.node.Labels,
+			LastSeen: agent.lastSeen,
+			Lost:     a.lost(agent, now),
+		}
+		for _, r := range agent.results {
+			if r.Healthy() {
+				current.Healthy++
+			} else {
+				current.Unhealthy++
+			}
+		}
+		result = append(result, current)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Results returns the latest results of the agents, sorted by
+// healthcheck ID and agent. The results can be filtered by agent and by
+// healthcheck ID.
+func (a *Aggregator) Results(agentName string, id string) []healthcheck.Result {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	result := []healthcheck.Result{}
+	for name, agent := range a.agents {
+		if agentName != "" && name != agentName {
+			continue
+		}
+		for checkID, r := range agent.results {
+			if id != "" && checkID != id {
+				continue
+			}
+			result = append(result, r)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ID() != result[j].ID() {
+			return result[i].ID() < result[j].ID()
+		}
+		return result[i].Node.Name < result[j].Node.Name
+	})
+	return result
+}
+
+// Health returns the health of each healthcheck across the agents which
+// are not lost, sorted by healthcheck ID. The shadow healthchecks are
+// ignored.
+func (a *Aggregator) Health(now time.Time) []Health {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	checks := make(map[string]*Health)
+	for name, agent := range a.agents {
+		if a.lost(agent, now) {
+			continue
+		}
+		for id, r := range agent.results {
+			if r.Shadow {
+				continue
+			}
+			health, ok := checks[id]
+			if !ok {
+				health = &Health{ID: id, Healthy: []string{}, Unhealthy: []string{}}
+				checks[id] = health
+			}
+			if r.Healthy() {
+				health.Healthy = append(health.Healthy, name)
+			} else {
+				health.Unhealthy = append(health.Unhealthy, name)
+			}
+		}
+	}
+	result := make([]Health, 0, len(checks))
+	for _, health := range checks {
+		sort.Strings(health.Healthy)
+		sort.Strings(health.Unhealthy)
+		switch {
+		case len(health.Unhealthy) == 0:
+			health.State = healthcheck.StateHealthy
+		case len(health.Healthy) == 0:
+			health.State = healthcheck.StateUnhealthy
+		default:
+			health.State = memorystore.GroupStateDegraded
+		}
+		result = append(result, *health)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
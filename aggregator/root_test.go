@@ -0,0 +1,86 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/memorystore"
+)
+
+func TestUnmarshalConfiguration(t *testing.T) {
+	var config Configuration
+	err := yaml.Unmarshal([]byte("agent-timeout: 1m"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	if config.AgentTimeout != healthcheck.Duration(time.Minute) || config.Retention != DefaultRetention || config.SignatureTolerance != DefaultSignatureTolerance {
+		t.Fatalf("Invalid configuration %+v", config)
+	}
+	err = yaml.Unmarshal([]byte("agent-timeout: 1h\nretention: 10m"), &config)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	aggregator := New(zap.NewExample(), &Configuration{
+		AgentTimeout: healthcheck.Duration(time.Minute),
+		Retention:    healthcheck.Duration(time.Hour),
+	})
+	now := time.Now()
+	node := &healthcheck.Node{Name: "agent-1", Region: "eu-west"}
+	aggregator.Add("10.0.0.1", []healthcheck.Result{
+		{Name: "foo", Success: true, HealthcheckTimestamp: now.Unix(), Node: node},
+		{Name: "bar", Success: true, HealthcheckTimestamp: now.Unix(), Node: node},
+	}, now)
+	aggregator.Add("10.0.0.2", []healthcheck.Result{
+		{Name: "foo", Success: false, HealthcheckTimestamp: now.Unix()},
+	}, now)
+	aggregator.Add("10.0.0.3", []healthcheck.Result{
+		{Name: "bar", Success: false, HealthcheckTimestamp: now.Unix()},
+	}, now.Add(-10*time.Minute))
+
+	agents := aggregator.Agents(now)
+	if len(agents) != 3 {
+		t.Fatalf("Invalid agents %+v", agents)
+	}
+	if agents[2].Name != "agent-1" || agents[2].Region != "eu-west" || agents[2].Healthy != 2 || agents[2].Lost {
+		t.Fatalf("Invalid agent %+v", agents[2])
+	}
+	if agents[0].Name != "10.0.0.2" || agents[0].Unhealthy != 1 {
+		t.Fatalf("Invalid agent %+v", agents[0])
+	}
+	if !agents[1].Lost {
+		t.Fatalf("The agent %s should be lost", agents[1].Name)
+	}
+
+	results := aggregator.Results("", "foo")
+	if len(results) != 2 || results[0].Node.Name != "10.0.0.2" || results[1].Node.Name != "agent-1" {
+		t.Fatalf("Invalid results %+v", results)
+	}
+	results = aggregator.Results("agent-1", "")
+	if len(results) != 2 || results[0].Name != "bar" {
+		t.Fatalf("Invalid results %+v", results)
+	}
+
+	// the results of the lost agent are ignored
+	health := aggregator.Health(now)
+	if len(health) != 2 {
+		t.Fatalf("Invalid health %+v", health)
+	}
+	if health[0].ID != "bar" || health[0].State != healthcheck.StateHealthy {
+		t.Fatalf("Invalid health %+v", health[0])
+	}
+	if health[1].ID != "foo" || health[1].State != memorystore.GroupStateDegraded || health[1].Unhealthy[0] != "10.0.0.2" {
+		t.Fatalf("Invalid health %+v", health[1])
+	}
+
+	aggregator.purge(now.Add(55 * time.Minute))
+	if len(aggregator.Agents(now)) != 2 {
+		t.Fatalf("The expired agent should be removed")
+	}
+}
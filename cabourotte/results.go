@@ -0,0 +1,91 @@
+package cabourotte
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// channelExporter an exporter sending the results to a channel
+type channelExporter struct {
+	logger  *zap.Logger
+	results chan *healthcheck.Result
+	lock    sync.RWMutex
+	started bool
+	closed  bool
+}
+
+// newChannelExporter creates a new channel exporter
+func newChannelExporter(logger *zap.Logger, size uint) *channelExporter {
+	return &channelExporter{
+		logger:  logger,
+		results: make(chan *healthcheck.Result, size),
+	}
+}
+
+// Start starts the exporter
+func (c *channelExporter) Start() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.started = true
+	return nil
+}
+
+// Stop stops the exporter
+func (c *channelExporter) Stop() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.started = false
+	return nil
+}
+
+// Reconnect restarts the exporter
+func (c *channelExporter) Reconnect() error {
+	return c.Start()
+}
+
+// IsStarted returns true if the exporter is started
+func (c *channelExporter) IsStarted() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.started
+}
+
+// Name returns the exporter name
+func (c *channelExporter) Name() string {
+	return "cabourotte-results"
+}
+
+// GetConfig returns the exporter configuration
+func (c *channelExporter) GetConfig() interface{} {
+	return nil
+}
+
+// Push sends a result to the channel, the result is dropped if the channel
+// is full
+func (c *channelExporter) Push(result *healthcheck.Result) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.closed {
+		return nil
+	}
+	select {
+	case c.results <- result:
+	default:
+		c.logger.Debug("The results channel is full, dropping the result",
+			zap.String("name", result.Name))
+	}
+	return nil
+}
+
+// close closes the results channel
+func (c *channelExporter) close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.results)
+	}
+}
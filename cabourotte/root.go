@@ -0,0 +1,325 @@
+// Package cabourotte allows to embed the Cabourotte healthchecks engine
+// (scheduling, execution and export of the healthchecks) in another Go
+// program, without running the daemon or its HTTP server.
+package cabourotte
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/exporter"
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/maintenance"
+	"github.com/appclacks/cabourotte/memorystore"
+	"github.com/appclacks/cabourotte/prometheus"
+	"github.com/appclacks/cabourotte/tracing"
+)
+
+// DefaultBufferSize the default size of the results buffers
+const DefaultBufferSize = 5000
+
+// Configuration the engine configuration
+type Configuration struct {
+	// ResultBuffer the size of the buffer of the results waiting to be
+	// exported, and of the channel returned by Results
+	ResultBuffer uint
+	// ResultHistory the number of results kept in memory for each
+	// healthcheck
+	ResultHistory uint
+	// ResultRetention the retention of the results kept in memory
+	ResultRetention memorystore.RetentionConfiguration
+	// MetricsLabels the healthchecks labels added to the metrics
+	MetricsLabels []string
+	Concurrency   healthcheck.ConcurrencyConfiguration
+	Exporters     exporter.Configuration
+}
+
+// Option configures the engine
+type Option func(*Engine)
+
+// WithLogger sets the logger of the engine. Nothing is logged by default.
+func WithLogger(logger *zap.Logger) Option {
+	return func(e *Engine) {
+		e.logger = logger
+	}
+}
+
+// WithPrometheus registers the engine metrics in an existing Prometheus
+// component. A new registry is created by default.
+func WithPrometheus(prom *prometheus.Prometheus) Option {
+	return func(e *Engine) {
+		e.prometheus = prom
+	}
+}
+
+// WithExporter adds an exporter to the exporters of the configuration
+func WithExporter(e exporter.Exporter) Option {
+	return func(engine *Engine) {
+		engine.exporters = append(engine.exporters, e)
+	}
+}
+
+// WithTracer traces the healthchecks executions
+func WithTracer(tracer *tracing.Tracer) Option {
+	return func(e *Engine) {
+		e.tracer = tracer
+	}
+}
+
+// WithResolver resolves the TCP and HTTP healthchecks targets using the
+// resolver, for example a DNS cache
+func WithResolver(resolver healthcheck.Resolver) Option {
+	return func(e *Engine) {
+		e.resolver = resolver
+	}
+}
+
+// WithTargetPolicy restricts the addresses of the healthchecks targets.
+// The policy should be initialized.
+func WithTargetPolicy(policy *healthcheck.TargetPolicy) Option {
+	return func(e *Engine) {
+		e.policy = policy
+	}
+}
+
+// WithCommandPolicy restricts the executables of the command
+// healthchecks, and sets the cgroup limiting their resources. The policy
+// should be initialized.
+func WithCommandPolicy(policy *healthcheck.CommandPolicy) Option {
+	return func(e *Engine) {
+		e.commandPolicy = policy
+	}
+}
+
+// WithProxy sets the proxy used by the healthchecks which do not
+// configure their own proxy. The proxy should be initialized.
+func WithProxy(proxy *healthcheck.Proxy) Option {
+	return func(e *Engine) {
+		e.proxy = proxy
+	}
+}
+
+// WithCredentials sets the named credentials referenced by the
+// healthchecks
+func WithCredentials(credentials healthcheck.Credentials) Option {
+	return func(e *Engine) {
+		e.credentials = credentials
+	}
+}
+
+// WithTargetRateLimit limits the executions per second against each
+// target host. The executions exceeding the limit are deferred.
+func WithTargetRateLimit(limit *healthcheck.RateLimit) Option {
+	return func(e *Engine) {
+		e.rateLimit = limit
+	}
+}
+
+// WithNode attaches the node identity to the results. The node labels are
+// also added to the metrics if the Prometheus component is created by the
+// engine.
+func WithNode(node *healthcheck.Node) Option {
+	return func(e *Engine) {
+		e.node = node
+	}
+}
+
+// WithNativeHistograms exposes the healthchecks durations as Prometheus
+// native histograms, with exponential buckets
+func WithNativeHistograms(config *prometheus.NativeHistograms) Option {
+	return func(e *Engine) {
+		e.nativeHistograms = config
+	}
+}
+
+// Engine schedules the healthchecks, and exports their results
+type Engine struct {
+	logger           *zap.Logger
+	prometheus       *prometheus.Prometheus
+	tracer           *tracing.Tracer
+	resolver         healthcheck.Resolver
+	policy           *healthcheck.TargetPolicy
+	commandPolicy    *healthcheck.CommandPolicy
+	proxy            *healthcheck.Proxy
+	credentials      healthcheck.Credentials
+	rateLimit        *healthcheck.RateLimit
+	node             *healthcheck.Node
+	nativeHistograms *prometheus.NativeHistograms
+	exporters        []exporter.Exporter
+	chanResult       chan *healthcheck.Result
+	results          *channelExporter
+	healthcheck      *healthcheck.Component
+	exporter         *exporter.Component
+	store            *memorystore.MemoryStore
+	lock             sync.Mutex
+	started          bool
+}
+
+// New creates a new engine
+func New(config *Configuration, options ...Option) (*Engine, error) {
+	engine := &Engine{}
+	for _, option := range options {
+		option(engine)
+	}
+	if engine.logger == nil {
+		engine.logger = zap.NewNop()
+	}
+	if err := engine.credentials.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "Invalid credentials")
+	}
+	if engine.prometheus == nil {
+		var labels map[string]string
+		if engine.node != nil {
+			labels = engine.node.MetricsLabels()
+		}
+		prom, err := prometheus.NewWithLabels(labels)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to create the Prometheus registry")
+		}
+		engine.prometheus = prom
+	}
+	if engine.nativeHistograms != nil {
+		engine.prometheus.SetNativeHistograms(engine.nativeHistograms)
+	}
+	bufferSize := config.ResultBuffer
+	if bufferSize == 0 {
+		bufferSize = DefaultBufferSize
+	}
+	engine.chanResult = make(chan *healthcheck.Result, bufferSize)
+	checkComponent, err := healthcheck.New(engine.logger.Named("healthcheck"), engine.chanResult, engine.prometheus, config.MetricsLabels, config.Concurrency)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to create the healthcheck component")
+	}
+	if engine.tracer != nil {
+		checkComponent.SetTracer(engine.tracer)
+	}
+	if engine.resolver != nil {
+		checkComponent.SetResolver(engine.resolver)
+	}
+	if engine.policy != nil {
+		checkComponent.SetTargetPolicy(engine.policy)
+	}
+	if engine.commandPolicy != nil {
+		checkComponent.SetCommandPolicy(engine.commandPolicy)
+	}
+	checkComponent.SetNode(engine.node)
+	if engine.proxy != nil {
+		checkComponent.SetProxy(engine.proxy)
+	}
+	checkComponent.SetCredentials(engine.credentials)
+	if engine.rateLimit != nil {
+		checkComponent.SetTargetRateLimit(engine.rateLimit)
+	}
+	engine.healthcheck = checkComponent
+	if err := config.ResultRetention.Validate(); err != nil {
+		return nil, err
+	}
+	engine.store = memorystore.NewMemoryStore(engine.logger, config.ResultHistory)
+	engine.store.SetRetention(config.ResultRetention)
+	if err := engine.store.EnableMetrics(engine.prometheus); err != nil {
+		return nil, err
+	}
+	exporterConfig := config.Exporters
+	exporterComponent, err := exporter.New(engine.logger.Named("exporter"), engine.store, maintenance.New(engine.logger), engine.chanResult, engine.prometheus, &exporterConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to create the exporter component")
+	}
+	exporterComponent.SetNode(engine.node)
+	engine.results = newChannelExporter(engine.logger, bufferSize)
+	for _, e := range append([]exporter.Exporter{engine.results}, engine.exporters...) {
+		err := exporterComponent.Register(e)
+		if err != nil {
+			return nil, err
+		}
+	}
+	engine.exporter = exporterComponent
+	return engine, nil
+}
+
+// Start starts executing the healthchecks and exporting their results
+func (e *Engine) Start() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.started {
+		return errors.New("The engine is already started")
+	}
+	e.store.Start()
+	err := e.exporter.Start()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to start the exporter component")
+	}
+	err = e.healthcheck.Start()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to start the healthcheck component")
+	}
+	e.started = true
+	return nil
+}
+
+// Stop stops the healthchecks and the exporters. The engine can not be
+// restarted, and the channel returned by Results is closed.
+func (e *Engine) Stop() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if !e.started {
+		return errors.New("The engine is not started")
+	}
+	err := e.healthcheck.Stop()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to stop the healthcheck component")
+	}
+	close(e.chanResult)
+	err = e.exporter.Stop()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to stop the exporter component")
+	}
+	err = e.store.Stop()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to stop the results store")
+	}
+	e.results.close()
+	e.started = false
+	return nil
+}
+
+// AddCheck validates, adds and schedules an healthcheck, replacing the
+// healthcheck with the same ID
+func (e *Engine) AddCheck(check healthcheck.Healthcheck) error {
+	if config, ok := check.GetConfig().(interface{ Validate() error }); ok {
+		err := config.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "Invalid healthcheck")
+		}
+	}
+	check.SetSource(healthcheck.SourceAPI)
+	return e.healthcheck.AddCheck(check)
+}
+
+// RemoveCheck removes an healthcheck by ID
+func (e *Engine) RemoveCheck(id string) error {
+	return e.healthcheck.RemoveCheck(id)
+}
+
+// ListChecks returns the healthchecks
+func (e *Engine) ListChecks() []healthcheck.Healthcheck {
+	return e.healthcheck.ListChecks()
+}
+
+// Results returns the channel receiving the exported healthchecks results.
+// The results are dropped if the channel is full.
+func (e *Engine) Results() <-chan *healthcheck.Result {
+	return e.results.results
+}
+
+// LatestResult returns the latest result of an healthcheck
+func (e *Engine) LatestResult(id string) (healthcheck.Result, error) {
+	return e.store.Get(id)
+}
+
+// Prometheus returns the Prometheus component containing the engine
+// metrics
+func (e *Engine) Prometheus() *prometheus.Prometheus {
+	return e.prometheus
+}
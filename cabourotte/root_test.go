@@ -0,0 +1,92 @@
+package cabourotte
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestEngine(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the listener\n%v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+	logger := zap.NewExample()
+	engine, err := New(&Configuration{ResultBuffer: 10}, WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Fail to create the engine\n%v", err)
+	}
+	err = engine.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the engine\n%v", err)
+	}
+	err = engine.AddCheck(healthcheck.NewTCPHealthcheck(logger, &healthcheck.TCPHealthcheckConfiguration{
+		Base: healthcheck.Base{
+			Name:     "foo",
+			Interval: healthcheck.Duration(3 * time.Second),
+		},
+		Target:  "127.0.0.1",
+		Port:    uint(port),
+		Timeout: healthcheck.Duration(time.Second),
+	}))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	err = engine.AddCheck(healthcheck.NewTCPHealthcheck(logger, &healthcheck.TCPHealthcheckConfiguration{
+		Base: healthcheck.Base{Name: "invalid"},
+	}))
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if len(engine.ListChecks()) != 1 {
+		t.Fatalf("Invalid healthchecks %v", engine.ListChecks())
+	}
+	select {
+	case result := <-engine.Results():
+		if result.Name != "foo" || !result.Success {
+			t.Fatalf("Invalid result %+v", result)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("No result received")
+	}
+	latest, err := engine.LatestResult("foo")
+	if err != nil || latest.Name != "foo" {
+		t.Fatalf("Invalid latest result %+v: %v", latest, err)
+	}
+	err = engine.RemoveCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+	err = engine.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the engine\n%v", err)
+	}
+	for range engine.Results() {
+	}
+	err = engine.Stop()
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestEngineExporterConflict(t *testing.T) {
+	_, err := New(&Configuration{}, WithExporter(newChannelExporter(zap.NewNop(), 1)))
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
@@ -0,0 +1,284 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/discovery/directory"
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/tls"
+)
+
+// DefaultURL the default URL of the Cabourotte API
+const DefaultURL = "http://127.0.0.1:9013"
+
+// Configuration the client configuration
+type Configuration struct {
+	URL       string
+	Username  string
+	Password  string
+	Token     string
+	Namespace string
+	Key       string
+	Cert      string
+	Cacert    string
+	Insecure  bool
+	Timeout   time.Duration
+}
+
+// Client a client for the Cabourotte API
+type Client struct {
+	config *Configuration
+	client *http.Client
+	url    string
+}
+
+// Healthcheck an healthcheck configuration, as returned by the API
+type Healthcheck map[string]interface{}
+
+// Field returns a field of the healthcheck as a string
+func (h Healthcheck) Field(name string) string {
+	value, ok := h[name]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// CheckResult the result of the execution of a check sent to the API
+type CheckResult struct {
+	Name    string `json:"name"`
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// response the body of the API responses, including the errors
+type response struct {
+	Messages []string `json:"messages"`
+}
+
+// New creates a new client
+func New(config *Configuration) (*Client, error) {
+	if config.URL == "" {
+		config.URL = DefaultURL
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if !((config.Key != "" && config.Cert != "") ||
+		(config.Key == "" && config.Cert == "")) {
+		return nil, errors.New("Invalid certificates")
+	}
+	tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, "", config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		config: config,
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+			Timeout: config.Timeout,
+		},
+		url: strings.TrimSuffix(config.URL, "/"),
+	}, nil
+}
+
+// do sends a request to the API, scoped to the client namespace
+func (c *Client) do(method string, path string, payload interface{}, result interface{}) error {
+	return c.request(method, path, c.config.Namespace, payload, result)
+}
+
+// request sends a request to the API and decodes the response body in
+// result. The API error messages are returned as errors.
+func (c *Client) request(method string, path string, namespace string, payload interface{}, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		jsonBytes, err := json.Marshal(payload)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to convert the payload to json")
+		}
+		body = bytes.NewBuffer(jsonBytes)
+	}
+	reqURL := c.url + path
+	if namespace != "" {
+		reqURL = fmt.Sprintf("%s?namespace=%s", reqURL, url.QueryEscape(namespace))
+	}
+	req, err := http.NewRequest(method, reqURL, body)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to create the request for %s", reqURL)
+	}
+	req.Header.Set("User-Agent", "Cabourotte")
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.Username != "" {
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to send the request to %s", reqURL)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to read the response body")
+	}
+	if resp.StatusCode >= 300 {
+		var apiError response
+		if err := json.Unmarshal(respBody, &apiError); err == nil && len(apiError.Messages) != 0 {
+			return fmt.Errorf("%s (status %d)", strings.Join(apiError.Messages, ", "), resp.StatusCode)
+		}
+		return fmt.Errorf("Request to %s failed, status %d", reqURL, resp.StatusCode)
+	}
+	if result != nil {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return errors.Wrapf(err, "Fail to convert the response from json")
+		}
+	}
+	return nil
+}
+
+// ListChecks lists the healthchecks
+func (c *Client) ListChecks() ([]Healthcheck, error) {
+	var result []Healthcheck
+	err := c.do("GET", "/healthcheck", nil, &result)
+	return result, err
+}
+
+// DeleteCheck deletes an healthcheck
+func (c *Client) DeleteCheck(name string) error {
+	return c.do("DELETE", fmt.Sprintf("/healthcheck/%s", url.PathEscape(name)), nil, nil)
+}
+
+// ListResults lists the latest healthchecks results
+func (c *Client) ListResults() ([]healthcheck.Result, error) {
+	var result []healthcheck.Result
+	err := c.do("GET", "/result", nil, &result)
+	return result, err
+}
+
+// GetResult returns the latest result of an healthcheck
+func (c *Client) GetResult(name string) (healthcheck.Result, error) {
+	var result healthcheck.Result
+	err := c.do("GET", fmt.Sprintf("/result/%s", url.PathEscape(name)), nil, &result)
+	return result, err
+}
+
+// ExecuteCheck executes an healthcheck once on the node, outside of its
+// schedule, with the given fields of its configuration overridden
+func (c *Client) ExecuteCheck(name string, overrides map[string]interface{}) (healthcheck.Result, error) {
+	var result healthcheck.Result
+	if overrides == nil {
+		overrides = map[string]interface{}{}
+	}
+	err := c.do("POST", fmt.Sprintf("/healthcheck/%s/execute", url.PathEscape(name)), overrides, &result)
+	return result, err
+}
+
+// ListSources lists the sources of healthchecks and their last
+// reconciliation
+func (c *Client) ListSources() ([]healthcheck.SourceStatus, error) {
+	var result []healthcheck.SourceStatus
+	err := c.do("GET", "/source", nil, &result)
+	return result, err
+}
+
+// Heartbeat sends a heartbeat to an heartbeat healthcheck
+func (c *Client) Heartbeat(name string) error {
+	return c.do("POST", fmt.Sprintf("/heartbeat/%s", url.PathEscape(name)), nil, nil)
+}
+
+// AddExternalResult sends the result of a check executed outside of
+// Cabourotte, which is exported like the healthchecks results
+func (c *Client) AddExternalResult(result *healthcheck.ExternalResult) error {
+	return c.do("POST", "/result/external", result, nil)
+}
+
+// check an healthcheck to send to the API
+type check struct {
+	name    string
+	path    string
+	payload interface{}
+}
+
+// apiChecks returns the healthchecks to send to the API. The checks are
+// executed once if oneOff is true.
+func apiChecks(checks *directory.Checks, oneOff bool) []check {
+	var result []check
+	for i := range checks.CommandChecks {
+		config := &checks.CommandChecks[i]
+		config.Base.OneOff = oneOff
+		result = append(result, check{name: config.Base.Name, path: "/healthcheck/command", payload: config})
+	}
+	for i := range checks.DNSChecks {
+		config := &checks.DNSChecks[i]
+		config.Base.OneOff = oneOff
+		result = append(result, check{name: config.Base.Name, path: "/healthcheck/dns", payload: config})
+	}
+	for i := range checks.TCPChecks {
+		config := &checks.TCPChecks[i]
+		config.Base.OneOff = oneOff
+		result = append(result, check{name: config.Base.Name, path: "/healthcheck/tcp", payload: config})
+	}
+	for i := range checks.HTTPChecks {
+		config := &checks.HTTPChecks[i]
+		config.Base.OneOff = oneOff
+		result = append(result, check{name: config.Base.Name, path: "/healthcheck/http", payload: config})
+	}
+	for i := range checks.TLSChecks {
+		config := &checks.TLSChecks[i]
+		config.Base.OneOff = oneOff
+		result = append(result, check{name: config.Base.Name, path: "/healthcheck/tls", payload: config})
+	}
+	for i := range checks.GRPCChecks {
+		config := &checks.GRPCChecks[i]
+		config.Base.OneOff = oneOff
+		result = append(result, check{name: config.Base.Name, path: "/healthcheck/grpc", payload: config})
+	}
+	for i := range checks.ProfileChecks {
+		config := &checks.ProfileChecks[i]
+		config.Base.OneOff = oneOff
+		result = append(result, check{name: config.Base.Name, path: "/healthcheck/profile", payload: config})
+	}
+	return result
+}
+
+// AddChecks adds or replaces healthchecks
+func (c *Client) AddChecks(checks *directory.Checks) error {
+	for _, check := range apiChecks(checks, false) {
+		err := c.do("POST", check.path, check.payload, nil)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to add the healthcheck %s", check.name)
+		}
+	}
+	return nil
+}
+
+// RunChecks executes the healthchecks once on the remote node
+func (c *Client) RunChecks(checks *directory.Checks) []CheckResult {
+	var results []CheckResult
+	for _, check := range apiChecks(checks, true) {
+		var resp response
+		result := CheckResult{Name: check.name, Success: true}
+		err := c.do("POST", check.path, check.payload, &resp)
+		if err != nil {
+			result.Success = false
+			result.Message = err.Error()
+		} else {
+			result.Message = strings.Join(resp.Messages, ", ")
+		}
+		results = append(results, result)
+	}
+	return results
+}
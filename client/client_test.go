@@ -0,0 +1,177 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/discovery/directory"
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestClient(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		requests = append(requests, fmt.Sprintf("%s %s", r.Method, r.URL.String()))
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/healthcheck":
+			fmt.Fprint(w, `[{"name": "foo", "namespace": "team-a", "target": "127.0.0.1", "port": 22, "interval": "10s"}]`)
+		case r.Method == "POST" && r.URL.Path == "/healthcheck/http":
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"messages": ["Execution of one off healthcheck web failed"]}`)
+		case r.Method == "POST":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"messages": ["Healthcheck successfully added"]}`)
+		case r.Method == "DELETE":
+			fmt.Fprint(w, `{"messages": ["Successfully deleted healthcheck"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	client, err := New(&Configuration{URL: ts.URL, Username: "user", Password: "pass", Namespace: "team-a"})
+	if err != nil {
+		t.Fatalf("Fail to create the client: %s", err.Error())
+	}
+	checks, err := client.ListChecks()
+	if err != nil {
+		t.Fatalf("Fail to list the healthchecks: %s", err.Error())
+	}
+	if len(checks) != 1 || checks[0].Field("name") != "foo" || checks[0].Field("port") != "22" {
+		t.Fatalf("Invalid healthchecks %v", checks)
+	}
+	in := `
+tcp-checks:
+  - name: "ssh"
+    target: "127.0.0.1"
+    port: 22
+    timeout: 3s
+    interval: 10s
+http-checks:
+  - name: "web"
+    target: "127.0.0.1"
+    port: 80
+    protocol: "http"
+    valid-status: [200]
+    timeout: 3s
+    interval: 10s
+`
+	var file directory.Checks
+	err = yaml.Unmarshal([]byte(in), &file)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %s", err.Error())
+	}
+	results := client.RunChecks(&file)
+	if len(results) != 2 || !results[0].Success || results[1].Success {
+		t.Fatalf("Invalid results %v", results)
+	}
+	if !strings.Contains(results[1].Message, "Execution of one off healthcheck web failed") {
+		t.Fatalf("Invalid message %s", results[1].Message)
+	}
+	err = client.DeleteCheck("ssh")
+	if err != nil {
+		t.Fatalf("Fail to delete the healthcheck: %s", err.Error())
+	}
+	err = client.AddExternalResult(&healthcheck.ExternalResult{Name: "backup", Success: true})
+	if err != nil {
+		t.Fatalf("Fail to push the external result: %s", err.Error())
+	}
+	expected := []string{
+		"GET /healthcheck?namespace=team-a",
+		"POST /healthcheck/tcp?namespace=team-a",
+		"POST /healthcheck/http?namespace=team-a",
+		"DELETE /healthcheck/ssh?namespace=team-a",
+		"POST /result/external?namespace=team-a",
+	}
+	if fmt.Sprintf("%v", requests) != fmt.Sprintf("%v", expected) {
+		t.Fatalf("Invalid requests %v", requests)
+	}
+	client, err = New(&Configuration{URL: ts.URL, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Fail to create the client: %s", err.Error())
+	}
+	_, err = client.ListChecks()
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	bulk := make(map[string]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/healthcheck":
+			fmt.Fprint(w, `[{"name": "ssh", "target": "127.0.0.1", "port": 22, "interval": "10s", "source": "api"},
+{"name": "web", "namespace": "team-a", "target": "127.0.0.1", "port": 80, "valid-status": [200], "source": "api", "labels": {"team": "a"}},
+{"name": "disk", "command": "ls", "interval": "10s", "source": ""}]`)
+		case r.Method == "POST" && r.URL.Path == "/healthcheck/bulk":
+			body, _ := io.ReadAll(r.Body)
+			bulk[r.URL.Query().Get("namespace")] = string(body)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"messages": ["Healthchecks successfully added"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	client, err := New(&Configuration{URL: ts.URL})
+	if err != nil {
+		t.Fatalf("Fail to create the client: %s", err.Error())
+	}
+	checks, err := client.ExportChecks([]string{"api"})
+	if err != nil {
+		t.Fatalf("Fail to export the healthchecks: %s", err.Error())
+	}
+	if len(checks.TCPChecks) != 1 || len(checks.HTTPChecks) != 1 || len(checks.CommandChecks) != 0 {
+		t.Fatalf("Invalid healthchecks %v", checks)
+	}
+	_, err = ParseChecks([]byte("unknown-checks: []"))
+	if err == nil {
+		t.Fatalf("Was expecting an error for the unknown field")
+	}
+	parsed, err := ParseChecks([]byte(`
+tcp-checks:
+  - name: ssh
+    target: 127.0.0.1
+    port: 22
+    source: api
+http-checks:
+  - name: web
+    namespace: team-a
+    target: 127.0.0.1
+    port: 80
+    valid-status: [200]
+    labels:
+      team: a
+heartbeat-checks:
+  - name: backup
+    interval: 1h
+`))
+	if err != nil {
+		t.Fatalf("Fail to parse the healthchecks: %s", err.Error())
+	}
+	err = client.ImportChecks(parsed)
+	if err != nil {
+		t.Fatalf("Fail to import the healthchecks: %s", err.Error())
+	}
+	if len(bulk) != 2 {
+		t.Fatalf("Was expecting a bulk request per namespace: %v", bulk)
+	}
+	if !strings.Contains(bulk["team-a"], `"name":"web"`) || !strings.Contains(bulk["team-a"], `"team":"a"`) {
+		t.Fatalf("Invalid bulk request %s", bulk["team-a"])
+	}
+	if !strings.Contains(bulk[""], `"name":"ssh"`) || !strings.Contains(bulk[""], `"heartbeat-checks":[{"interval":"1h","name":"backup"}]`) || strings.Contains(bulk[""], "web") {
+		t.Fatalf("Invalid bulk request %s", bulk[""])
+	}
+}
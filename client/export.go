@@ -0,0 +1,212 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// Type returns the type of the healthcheck, guessed from its fields as
+// the API does not return it
+func (h Healthcheck) Type() string {
+	has := func(field string) bool {
+		_, ok := h[field]
+		return ok
+	}
+	switch {
+	case has("protocols"):
+		return "profile"
+	case has("command"):
+		return "command"
+	case has("domain"):
+		return "dns"
+	case has("expiration-delay"):
+		return "tls"
+	case has("valid-status"):
+		return "http"
+	case has("tls"):
+		return "grpc"
+	case !has("target"):
+		return "heartbeat"
+	}
+	return "tcp"
+}
+
+// Checks healthchecks grouped by type, using the bulk API format
+type Checks struct {
+	CommandChecks   []Healthcheck `json:"command-checks,omitempty"`
+	DNSChecks       []Healthcheck `json:"dns-checks,omitempty"`
+	TCPChecks       []Healthcheck `json:"tcp-checks,omitempty"`
+	HTTPChecks      []Healthcheck `json:"http-checks,omitempty"`
+	TLSChecks       []Healthcheck `json:"tls-checks,omitempty"`
+	HeartbeatChecks []Healthcheck `json:"heartbeat-checks,omitempty"`
+	GRPCChecks      []Healthcheck `json:"grpc-checks,omitempty"`
+	ProfileChecks   []Healthcheck `json:"profile-checks,omitempty"`
+}
+
+// Add adds an healthcheck to the list of its type
+func (c *Checks) Add(check Healthcheck) {
+	switch check.Type() {
+	case "command":
+		c.CommandChecks = append(c.CommandChecks, check)
+	case "dns":
+		c.DNSChecks = append(c.DNSChecks, check)
+	case "tls":
+		c.TLSChecks = append(c.TLSChecks, check)
+	case "http":
+		c.HTTPChecks = append(c.HTTPChecks, check)
+	case "heartbeat":
+		c.HeartbeatChecks = append(c.HeartbeatChecks, check)
+	case "grpc":
+		c.GRPCChecks = append(c.GRPCChecks, check)
+	case "profile":
+		c.ProfileChecks = append(c.ProfileChecks, check)
+	default:
+		c.TCPChecks = append(c.TCPChecks, check)
+	}
+}
+
+// All returns all the healthchecks
+func (c *Checks) All() []Healthcheck {
+	var result []Healthcheck
+	for _, checks := range [][]Healthcheck{c.CommandChecks, c.DNSChecks, c.TCPChecks, c.HTTPChecks, c.TLSChecks, c.HeartbeatChecks, c.GRPCChecks, c.ProfileChecks} {
+		result = append(result, checks...)
+	}
+	return result
+}
+
+// ExportChecks returns the healthchecks registered on the node, including
+// their sources and labels. If sources is not empty, only the
+// healthchecks of these sources are returned.
+func (c *Client) ExportChecks(sources []string) (*Checks, error) {
+	checks, err := c.ListChecks()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to list the healthchecks")
+	}
+	result := &Checks{}
+	for _, check := range checks {
+		if len(sources) != 0 && !contains(sources, check.Field("source")) {
+			continue
+		}
+		result.Add(check)
+	}
+	return result, nil
+}
+
+// ImportChecks pushes healthchecks to the node using the bulk API. A
+// bulk request is sent for each namespace, replacing the healthchecks
+// managed by the API in this namespace. The node registers the
+// healthchecks as managed by the API, whatever their original source.
+func (c *Client) ImportChecks(checks *Checks) error {
+	namespaces := make(map[string]*Checks)
+	for _, check := range checks.All() {
+		namespace := check.Field("namespace")
+		if c.config.Namespace != "" && namespace == "" {
+			namespace = c.config.Namespace
+		}
+		if _, ok := namespaces[namespace]; !ok {
+			namespaces[namespace] = &Checks{}
+		}
+		namespaces[namespace].Add(check)
+	}
+	names := make([]string, 0, len(namespaces))
+	for namespace := range namespaces {
+		names = append(names, namespace)
+	}
+	sort.Strings(names)
+	for _, namespace := range names {
+		err := c.request("POST", "/healthcheck/bulk", namespace, namespaces[namespace], nil)
+		if err != nil {
+			if namespace == "" {
+				return errors.Wrapf(err, "Fail to import the healthchecks")
+			}
+			return errors.Wrapf(err, "Fail to import the healthchecks of the namespace %s", namespace)
+		}
+	}
+	return nil
+}
+
+// ReplaceSourceChecks replaces the healthchecks managed by the source on
+// the node by the given healthchecks, and returns the changes
+func (c *Client) ReplaceSourceChecks(source string, checks *Checks) (*healthcheck.Reconciliation, error) {
+	var result healthcheck.Reconciliation
+	err := c.do("PUT", fmt.Sprintf("/healthcheck/source/%s", url.PathEscape(source)), checks, &result)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to replace the healthchecks of the source %s", source)
+	}
+	return &result, nil
+}
+
+// contains returns true if the value is in the list
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonValue converts a value decoded from YAML to a value which can be
+// converted to JSON
+func jsonValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			k, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("Invalid key %v, keys should be strings", key)
+			}
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = converted
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = converted
+		}
+		return result, nil
+	}
+	return value, nil
+}
+
+// ParseChecks reads healthchecks exported in YAML
+func ParseChecks(content []byte) (*Checks, error) {
+	var raw interface{}
+	err := yaml.Unmarshal(content, &raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid yaml")
+	}
+	value, err := jsonValue(raw)
+	if err != nil {
+		return nil, err
+	}
+	jsonBytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to convert the healthchecks to json")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.DisallowUnknownFields()
+	var checks Checks
+	err = decoder.Decode(&checks)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid healthchecks")
+	}
+	return &checks, nil
+}
@@ -0,0 +1,75 @@
+package cluster
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// Peer another Cabourotte instance of the cluster
+type Peer struct {
+	Name string
+	URL  string
+}
+
+// Configuration the cluster configuration
+type Configuration struct {
+	Name     string
+	Peers    []Peer
+	Interval healthcheck.Duration `json:"interval"`
+	Timeout  healthcheck.Duration `json:"timeout"`
+	Key      string               `json:"key,omitempty"`
+	Cert     string               `json:"cert,omitempty"`
+	Cacert   string               `json:"cacert,omitempty"`
+	Insecure bool
+}
+
+// Enabled returns true if the clustering mode is enabled
+func (configuration *Configuration) Enabled() bool {
+	return len(configuration.Peers) != 0
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the cluster configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("The cluster node name is missing")
+	}
+	names := map[string]bool{raw.Name: true}
+	for _, peer := range raw.Peers {
+		if peer.Name == "" || peer.URL == "" {
+			return errors.New("The cluster peers name and url are mandatory")
+		}
+		peerURL, err := url.Parse(peer.URL)
+		if err != nil || (peerURL.Scheme != "http" && peerURL.Scheme != "https") || peerURL.Host == "" {
+			return fmt.Errorf("Invalid url %s for the cluster peer %s, the scheme should be http or https", peer.URL, peer.Name)
+		}
+		if names[peer.Name] {
+			return fmt.Errorf("The cluster nodes names should be unique (duplicate found for %s)", peer.Name)
+		}
+		names[peer.Name] = true
+	}
+	if raw.Interval == 0 {
+		raw.Interval = healthcheck.Duration(5 * time.Second)
+	}
+	if raw.Timeout == 0 {
+		raw.Timeout = healthcheck.Duration(2 * time.Second)
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates for the cluster peers probes")
+	}
+	if raw.Timeout >= raw.Interval {
+		return errors.New("The cluster peers probe timeout should be lower than the interval")
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
@@ -0,0 +1,189 @@
+package cluster
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/appclacks/cabourotte/prometheus"
+	"github.com/appclacks/cabourotte/tls"
+)
+
+// Component shards the healthchecks between the Cabourotte instances of
+// a cluster.
+// Peers are probed periodically on their /healthz endpoint, and each
+// healthcheck is owned by one alive node, chosen using rendezvous hashing.
+// When a peer fails, its healthchecks are taken over by the other nodes.
+type Component struct {
+	Logger *zap.Logger
+	Config *Configuration
+	Client *http.Client
+
+	peerGauge *prom.GaugeVec
+	alive     map[string]bool
+	lock      sync.RWMutex
+	t         tomb.Tomb
+	tick      *time.Ticker
+}
+
+// New creates a new cluster component
+func New(logger *zap.Logger, config *Configuration, promComponent *prometheus.Prometheus) (*Component, error) {
+	tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, "", config.Insecure)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to build the cluster peers TLS configuration")
+	}
+	peerGauge := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Name: "cluster_peer_up",
+			Help: "1 if the cluster peer is alive, 0 otherwise.",
+		},
+		[]string{"peer"})
+	err = promComponent.Register(peerGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the cluster peers Prometheus gauge")
+	}
+	// peers are considered alive until proven otherwise, in order to
+	// avoid executing the same healthchecks on several nodes at startup
+	alive := make(map[string]bool)
+	for _, peer := range config.Peers {
+		alive[peer.Name] = true
+	}
+	return &Component{
+		Logger:    logger,
+		Config:    config,
+		peerGauge: peerGauge,
+		alive:     alive,
+		Client: &http.Client{
+			Timeout: time.Duration(config.Timeout),
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}, nil
+}
+
+// probe verifies if a peer is alive
+func (c *Component) probe(peer Peer) bool {
+	url := fmt.Sprintf("%s/healthz", strings.TrimSuffix(peer.URL, "/"))
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		c.Logger.Error(fmt.Sprintf("Cluster: fail to create request for peer %s: %s", peer.Name, err.Error()))
+		return false
+	}
+	req.Header.Set("User-Agent", "Cabourotte")
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		c.Logger.Debug(fmt.Sprintf("Cluster: peer %s is unreachable: %s", peer.Name, err.Error()))
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// probePeers probes all peers and updates their status
+func (c *Component) probePeers() {
+	for _, peer := range c.Config.Peers {
+		up := c.probe(peer)
+		c.lock.Lock()
+		if c.alive[peer.Name] != up {
+			if up {
+				c.Logger.Info(fmt.Sprintf("Cluster: peer %s is alive", peer.Name))
+			} else {
+				c.Logger.Error(fmt.Sprintf("Cluster: peer %s is down, taking over its healthchecks", peer.Name))
+			}
+		}
+		c.alive[peer.Name] = up
+		c.lock.Unlock()
+		value := 0.0
+		if up {
+			value = 1
+		}
+		c.peerGauge.With(prom.Labels{"peer": peer.Name}).Set(value)
+	}
+}
+
+// Members returns the names of the alive nodes, including the current one
+func (c *Component) Members() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	members := []string{c.Config.Name}
+	for _, peer := range c.Config.Peers {
+		if c.alive[peer.Name] {
+			members = append(members, peer.Name)
+		}
+	}
+	return members
+}
+
+func score(node string, check string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(node))
+	h.Write([]byte{0})
+	h.Write([]byte(check))
+	// fnv does not mix the high bits well, a finalizer is applied to
+	// get an uniform distribution of the scores
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// owner returns the node owning a healthcheck
+func owner(members []string, check string) string {
+	result := ""
+	var best uint64
+	for _, member := range members {
+		s := score(member, check)
+		if result == "" || s > best || (s == best && member < result) {
+			result = member
+			best = s
+		}
+	}
+	return result
+}
+
+// Owns returns true if the healthcheck should be executed by this node
+func (c *Component) Owns(check string) bool {
+	return owner(c.Members(), check) == c.Config.Name
+}
+
+// Start starts the cluster component
+func (c *Component) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the cluster component as node %s", c.Config.Name))
+	c.tick = time.NewTicker(time.Duration(c.Config.Interval))
+	c.t.Go(func() error {
+		c.probePeers()
+		for {
+			select {
+			case <-c.tick.C:
+				c.probePeers()
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops the cluster component
+func (c *Component) Stop() error {
+	c.Logger.Info("Stopping the cluster component")
+	c.tick.Stop()
+	c.t.Kill(nil)
+	err := c.t.Wait()
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package cluster
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestOwnerIsStable(t *testing.T) {
+	members := []string{"node1", "node2", "node3"}
+	counts := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		check := fmt.Sprintf("check-%d", i)
+		o := owner(members, check)
+		counts[o]++
+		// removing a node which is not the owner should not change the owner
+		for _, removed := range members {
+			if removed == o {
+				continue
+			}
+			var remaining []string
+			for _, m := range members {
+				if m != removed {
+					remaining = append(remaining, m)
+				}
+			}
+			if owner(remaining, check) != o {
+				t.Fatalf("The owner of %s changed when removing %s", check, removed)
+			}
+		}
+	}
+	for _, member := range members {
+		if counts[member] == 0 {
+			t.Fatalf("No healthcheck owned by %s", member)
+		}
+	}
+}
+
+func TestPeerTakeOver(t *testing.T) {
+	up := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(zap.NewExample(), &Configuration{
+		Name:     "node1",
+		Peers:    []Peer{{Name: "node2", URL: ts.URL}},
+		Interval: healthcheck.Duration(time.Second),
+		Timeout:  healthcheck.Duration(500 * time.Millisecond),
+	}, prom)
+	if err != nil {
+		t.Fatalf("Fail to create the cluster component\n%v", err)
+	}
+	component.probePeers()
+	owned := 0
+	for i := 0; i < 100; i++ {
+		if component.Owns(fmt.Sprintf("check-%d", i)) {
+			owned++
+		}
+	}
+	if owned == 0 || owned == 100 {
+		t.Fatalf("The healthchecks should be shared between nodes (%d owned)", owned)
+	}
+	up = false
+	component.probePeers()
+	for i := 0; i < 100; i++ {
+		if !component.Owns(fmt.Sprintf("check-%d", i)) {
+			t.Fatalf("The healthchecks of the failed peer should be taken over")
+		}
+	}
+}
+
+func TestPeerTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	config := &Configuration{
+		Name:     "node1",
+		Peers:    []Peer{{Name: "node2", URL: ts.URL}},
+		Interval: healthcheck.Duration(time.Second),
+		Timeout:  healthcheck.Duration(500 * time.Millisecond),
+	}
+	component, err := New(zap.NewExample(), config, prom)
+	if err != nil {
+		t.Fatalf("Fail to create the cluster component\n%v", err)
+	}
+	if component.probe(config.Peers[0]) {
+		t.Fatalf("The peer certificate should not be trusted")
+	}
+	config.Insecure = true
+	prom, err = prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err = New(zap.NewExample(), config, prom)
+	if err != nil {
+		t.Fatalf("Fail to create the cluster component\n%v", err)
+	}
+	if !component.probe(config.Peers[0]) {
+		t.Fatalf("The peer should be alive")
+	}
+}
+
+func TestUnmarshalConfigError(t *testing.T) {
+	cases := []string{
+		`
+name: "node1"
+peers:
+  - name: "node2"
+    url: "node-2:9013"
+`,
+		`
+name: "node1"
+peers:
+  - name: "node2"
+    url: "ftp://node-2:9013"
+`,
+		`
+name: "node1"
+key: /tmp/key.pem
+peers:
+  - name: "node2"
+    url: "https://node-2:9013"
+`,
+	}
+	for _, c := range cases {
+		var result Configuration
+		err := yaml.Unmarshal([]byte(c), &result)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", c)
+		}
+	}
+}
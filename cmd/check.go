@@ -0,0 +1,672 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// checkBase builds the base configuration of an ad-hoc healthcheck from
+// the check command flags
+func checkBase(c *cli.Context) healthcheck.Base {
+	return healthcheck.Base{
+		Name:       c.String("name"),
+		OneOff:     true,
+		Labels:     map[string]string{},
+		MaxRetries: c.Uint("max-retries"),
+		RetryDelay: healthcheck.Duration(c.Duration("retry-delay")),
+	}
+}
+
+// keyValues parses a list of key=value flags
+func keyValues(flag string, values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]string)
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid %s %s, the expected format is key=value", flag, value)
+		}
+		result[parts[0]] = parts[1]
+	}
+	return result, nil
+}
+
+// parseIP parses an optional IP flag
+func parseIP(value string) (healthcheck.IP, error) {
+	var ip healthcheck.IP
+	if value == "" {
+		return ip, nil
+	}
+	err := ip.UnmarshalText([]byte(value))
+	return ip, err
+}
+
+// runCheck executes an ad-hoc healthcheck once and prints its result
+func runCheck(c *cli.Context, build func(logger *zap.Logger) (healthcheck.Healthcheck, error)) error {
+	if err := checkOutput(c); err != nil {
+		return err
+	}
+	logger := zap.NewNop()
+	if c.Bool("debug") {
+		var err error
+		logger, err = zap.NewDevelopment()
+		if err != nil {
+			return errors.Wrapf(err, "Fail to start the logger")
+		}
+	}
+	check, err := build(logger)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid healthcheck")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	result := healthcheck.ExecuteOnce(ctx, check)
+	status := "success"
+	if !result.Success {
+		status = "failure"
+	}
+	rows := [][]string{
+		{"name", result.Name},
+		{"summary", fmt.Sprintf("%v", result.Summary)},
+		{"status", status},
+		{"duration", fmt.Sprintf("%dms", result.Duration)},
+		{"probe-id", result.ProbeID},
+	}
+	if !result.Success {
+		rows = append(rows, []string{"error-category", result.ErrorCategory})
+	}
+	for _, phase := range healthcheck.Phases {
+		if duration, ok := result.Phases[phase]; ok {
+			rows = append(rows, []string{"phase " + phase, fmt.Sprintf("%.3fms", duration)})
+		}
+	}
+	if result.TLS != nil {
+		rows = append(rows,
+			[]string{"tls-version", result.TLS.Version},
+			[]string{"tls-cipher-suite", result.TLS.CipherSuite})
+		for i, cert := range result.TLS.Certificates {
+			rows = append(rows, []string{fmt.Sprintf("certificate %d", i), fmt.Sprintf("%s (issuer %s, expires %s)", cert.Subject, cert.Issuer, cert.NotAfter.Format(time.RFC3339))})
+		}
+	}
+	if result.Response != nil {
+		rows = append(rows, []string{"response-status", strconv.Itoa(result.Response.Status)})
+		names := make([]string, 0, len(result.Response.Headers))
+		for name := range result.Response.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			rows = append(rows, []string{"response-header " + name, result.Response.Headers[name]})
+		}
+		rows = append(rows, []string{"response-body", result.Response.Body})
+	}
+	rows = append(rows,
+		[]string{"timestamp", time.Unix(result.HealthcheckTimestamp, 0).Format(time.RFC3339)},
+		[]string{"message", result.Message})
+	err = printOutput(c, result, table{
+		header: []string{"FIELD", "VALUE"},
+		rows:   rows,
+	})
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return cli.Exit(fmt.Sprintf("The healthcheck %s failed", result.Name), 1)
+	}
+	return nil
+}
+
+func checkCommand() *cli.Command {
+	commonFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:  "name",
+			Usage: "Name of the healthcheck",
+			Value: "adhoc",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "Timeout of the healthcheck",
+			Value: 5 * time.Second,
+		},
+		&cli.UintFlag{
+			Name:  "max-retries",
+			Usage: "Number of retries before reporting a failure",
+		},
+		&cli.DurationFlag{
+			Name:  "retry-delay",
+			Usage: "Delay between two retries",
+			Value: time.Second,
+		},
+		&cli.BoolFlag{
+			Name:  "debug",
+			Usage: "Enable debug logging",
+		},
+		outputFlag(),
+	}
+	tlsFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:  "key",
+			Usage: "Path to the client private key",
+		},
+		&cli.StringFlag{
+			Name:  "cert",
+			Usage: "Path to the client certificate",
+		},
+		&cli.StringFlag{
+			Name:  "cacert",
+			Usage: "Path to the CA certificate",
+		},
+		&cli.StringFlag{
+			Name:  "server-name",
+			Usage: "Server name used to verify the certificate",
+		},
+		&cli.BoolFlag{
+			Name:  "insecure",
+			Usage: "Skip the server certificate verification",
+		},
+	}
+	flags := func(flags ...[]cli.Flag) []cli.Flag {
+		result := append([]cli.Flag{}, commonFlags...)
+		for _, f := range flags {
+			result = append(result, f...)
+		}
+		return result
+	}
+	return &cli.Command{
+		Name:  "check",
+		Usage: "executes once an healthcheck defined by the command flags and prints its result",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "command",
+				Usage: "executes a command healthcheck",
+				Flags: flags([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "command",
+						Usage:    "Command to execute",
+						Required: true,
+					},
+					&cli.StringSliceFlag{
+						Name:  "argument",
+						Usage: "Argument of the command, can be repeated",
+					},
+					&cli.StringFlag{
+						Name:  "directory",
+						Usage: "Working directory of the command",
+					},
+					&cli.StringFlag{
+						Name:  "user",
+						Usage: "User executing the command, name or ID",
+					},
+					&cli.StringFlag{
+						Name:  "user-group",
+						Usage: "Group executing the command, name or ID",
+					},
+				}),
+				Action: func(c *cli.Context) error {
+					return runCheck(c, func(logger *zap.Logger) (healthcheck.Healthcheck, error) {
+						config := &healthcheck.CommandHealthcheckConfiguration{
+							Base:      checkBase(c),
+							Command:   c.String("command"),
+							Arguments: c.StringSlice("argument"),
+							Timeout:   healthcheck.Duration(c.Duration("timeout")),
+							Directory: c.String("directory"),
+							User:      c.String("user"),
+							UserGroup: c.String("user-group"),
+						}
+						if err := config.Validate(); err != nil {
+							return nil, err
+						}
+						return healthcheck.NewCommandHealthcheck(logger, config), nil
+					})
+				},
+			},
+			{
+				Name:  "dns",
+				Usage: "executes a DNS healthcheck",
+				Flags: flags([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "domain",
+						Usage:    "Domain to resolve",
+						Required: true,
+					},
+					&cli.StringSliceFlag{
+						Name:  "expected-ip",
+						Usage: "IP the domain should resolve to, can be repeated",
+					},
+					&cli.StringSliceFlag{
+						Name:  "assertion",
+						Usage: "Expression on the resolved IPs which should be true, can be repeated",
+					},
+				}),
+				Action: func(c *cli.Context) error {
+					return runCheck(c, func(logger *zap.Logger) (healthcheck.Healthcheck, error) {
+						config := &healthcheck.DNSHealthcheckConfiguration{
+							Base:       checkBase(c),
+							Domain:     c.String("domain"),
+							Timeout:    healthcheck.Duration(c.Duration("timeout")),
+							Assertions: c.StringSlice("assertion"),
+						}
+						for _, value := range c.StringSlice("expected-ip") {
+							ip, err := parseIP(value)
+							if err != nil {
+								return nil, err
+							}
+							config.ExpectedIPs = append(config.ExpectedIPs, ip)
+						}
+						if err := config.Validate(); err != nil {
+							return nil, err
+						}
+						return healthcheck.NewDNSHealthcheck(logger, config), nil
+					})
+				},
+			},
+			{
+				Name:  "tcp",
+				Usage: "executes a TCP healthcheck",
+				Flags: flags([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "target",
+						Usage:    "IP or domain of the target",
+						Required: true,
+					},
+					&cli.UintFlag{
+						Name:     "port",
+						Usage:    "Port of the target",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "source-ip",
+						Usage: "Source IP of the connection",
+					},
+					&cli.BoolFlag{
+						Name:  "should-fail",
+						Usage: "The healthcheck is successful if the connection fails",
+					},
+					&cli.StringSliceFlag{
+						Name:  "assertion",
+						Usage: "Expression on the connection which should be true, can be repeated",
+					},
+				}),
+				Action: func(c *cli.Context) error {
+					return runCheck(c, func(logger *zap.Logger) (healthcheck.Healthcheck, error) {
+						sourceIP, err := parseIP(c.String("source-ip"))
+						if err != nil {
+							return nil, err
+						}
+						config := &healthcheck.TCPHealthcheckConfiguration{
+							Base:       checkBase(c),
+							Target:     c.String("target"),
+							Port:       c.Uint("port"),
+							SourceIP:   sourceIP,
+							Timeout:    healthcheck.Duration(c.Duration("timeout")),
+							ShouldFail: c.Bool("should-fail"),
+							Assertions: c.StringSlice("assertion"),
+						}
+						if err := config.Validate(); err != nil {
+							return nil, err
+						}
+						return healthcheck.NewTCPHealthcheck(logger, config), nil
+					})
+				},
+			},
+			{
+				Name:  "http",
+				Usage: "executes an HTTP healthcheck",
+				Flags: flags(tlsFlags, []cli.Flag{
+					&cli.StringFlag{
+						Name:     "target",
+						Usage:    "IP or domain of the target",
+						Required: true,
+					},
+					&cli.UintFlag{
+						Name:     "port",
+						Usage:    "Port of the target",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "protocol",
+						Usage: "Protocol (http or https)",
+						Value: "http",
+					},
+					&cli.StringFlag{
+						Name:  "path",
+						Usage: "Path of the request",
+						Value: "/",
+					},
+					&cli.StringFlag{
+						Name:  "method",
+						Usage: "Method of the request",
+						Value: "GET",
+					},
+					&cli.StringFlag{
+						Name:  "host",
+						Usage: "Host header of the request",
+					},
+					&cli.StringFlag{
+						Name:  "body",
+						Usage: "Body of the request",
+					},
+					&cli.StringSliceFlag{
+						Name:  "header",
+						Usage: "Header of the request (key=value), can be repeated",
+					},
+					&cli.StringSliceFlag{
+						Name:  "query",
+						Usage: "Query parameter of the request (key=value), can be repeated",
+					},
+					&cli.StringSliceFlag{
+						Name:  "valid-status",
+						Usage: "Valid status code or range of status codes (200-299), can be repeated",
+						Value: cli.NewStringSlice("200"),
+					},
+					&cli.StringSliceFlag{
+						Name:  "body-regexp",
+						Usage: "Regular expression the response body should match, can be repeated",
+					},
+					&cli.StringSliceFlag{
+						Name:  "assertion",
+						Usage: "Expression on the response which should be true, can be repeated",
+					},
+					&cli.BoolFlag{
+						Name:  "redirect",
+						Usage: "Follow the redirections",
+					},
+					&cli.BoolFlag{
+						Name:  "capture-response",
+						Usage: "Print the response headers and the truncated body on failure",
+					},
+					&cli.StringFlag{
+						Name:  "user-agent",
+						Usage: "User-Agent of the request",
+						Value: healthcheck.DefaultUserAgent,
+					},
+					&cli.StringFlag{
+						Name:  "source-ip",
+						Usage: "Source IP of the connection",
+					},
+				}),
+				Action: func(c *cli.Context) error {
+					return runCheck(c, func(logger *zap.Logger) (healthcheck.Healthcheck, error) {
+						sourceIP, err := parseIP(c.String("source-ip"))
+						if err != nil {
+							return nil, err
+						}
+						var protocol healthcheck.Protocol
+						err = protocol.UnmarshalText([]byte(c.String("protocol")))
+						if err != nil {
+							return nil, err
+						}
+						headers, err := keyValues("header", c.StringSlice("header"))
+						if err != nil {
+							return nil, err
+						}
+						query, err := keyValues("query", c.StringSlice("query"))
+						if err != nil {
+							return nil, err
+						}
+						validStatus, err := healthcheck.ParseStatusCodes(c.StringSlice("valid-status"))
+						if err != nil {
+							return nil, err
+						}
+						config := &healthcheck.HTTPHealthcheckConfiguration{
+							Base:        checkBase(c),
+							ValidStatus: validStatus,
+							Target:      c.String("target"),
+							Host:        c.String("host"),
+							Method:      c.String("method"),
+							Port:        c.Uint("port"),
+							Redirect:    c.Bool("redirect"),
+							Body:        c.String("body"),
+							Query:       query,
+							Headers:     headers,
+							UserAgent:   c.String("user-agent"),
+							Protocol:    protocol,
+							Path:        c.String("path"),
+							SourceIP:    sourceIP,
+							Insecure:    c.Bool("insecure"),
+							ServerName:  c.String("server-name"),
+							Timeout:     healthcheck.Duration(c.Duration("timeout")),
+							Key:         c.String("key"),
+							Cert:        c.String("cert"),
+							Cacert:      c.String("cacert"),
+							Assertions:  c.StringSlice("assertion"),
+						}
+						for _, value := range c.StringSlice("body-regexp") {
+							var r healthcheck.Regexp
+							err := r.UnmarshalText([]byte(value))
+							if err != nil {
+								return nil, err
+							}
+							config.BodyRegexp = append(config.BodyRegexp, r)
+						}
+						if c.Bool("capture-response") {
+							config.CaptureResponse = &healthcheck.CaptureResponse{}
+						}
+						if err := config.Validate(); err != nil {
+							return nil, err
+						}
+						return healthcheck.NewHTTPHealthcheck(logger, config), nil
+					})
+				},
+			},
+			{
+				Name:  "tls",
+				Usage: "executes a TLS healthcheck",
+				Flags: flags(tlsFlags, []cli.Flag{
+					&cli.StringFlag{
+						Name:     "target",
+						Usage:    "IP or domain of the target",
+						Required: true,
+					},
+					&cli.UintFlag{
+						Name:     "port",
+						Usage:    "Port of the target",
+						Required: true,
+					},
+					&cli.DurationFlag{
+						Name:  "expiration-delay",
+						Usage: "The healthcheck fails if the certificate expires in less than this duration",
+						Value: 168 * time.Hour,
+					},
+					&cli.StringFlag{
+						Name:  "source-ip",
+						Usage: "Source IP of the connection",
+					},
+				}),
+				Action: func(c *cli.Context) error {
+					return runCheck(c, func(logger *zap.Logger) (healthcheck.Healthcheck, error) {
+						sourceIP, err := parseIP(c.String("source-ip"))
+						if err != nil {
+							return nil, err
+						}
+						config := &healthcheck.TLSHealthcheckConfiguration{
+							Base:            checkBase(c),
+							Target:          c.String("target"),
+							Port:            c.Uint("port"),
+							SourceIP:        sourceIP,
+							Timeout:         healthcheck.Duration(c.Duration("timeout")),
+							Key:             c.String("key"),
+							Cert:            c.String("cert"),
+							Cacert:          c.String("cacert"),
+							ServerName:      c.String("server-name"),
+							Insecure:        c.Bool("insecure"),
+							ExpirationDelay: healthcheck.Duration(c.Duration("expiration-delay")),
+						}
+						if err := config.Validate(); err != nil {
+							return nil, err
+						}
+						return healthcheck.NewTLSHealthcheck(logger, config), nil
+					})
+				},
+			},
+			{
+				Name:  "grpc",
+				Usage: "executes a gRPC healthcheck",
+				Flags: flags(tlsFlags, []cli.Flag{
+					&cli.StringFlag{
+						Name:     "target",
+						Usage:    "IP or domain of the target",
+						Required: true,
+					},
+					&cli.UintFlag{
+						Name:     "port",
+						Usage:    "Port of the target",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "tls",
+						Usage: "Connect to the target using TLS",
+					},
+					&cli.StringSliceFlag{
+						Name:  "metadata",
+						Usage: "Metadata of the requests (key=value), can be repeated",
+					},
+					&cli.StringFlag{
+						Name:  "service",
+						Usage: "Service checked using the gRPC health protocol",
+					},
+					&cli.StringFlag{
+						Name:  "method",
+						Usage: "Unary method (package.Service/Method) invoked using the server reflection, instead of the health protocol",
+					},
+					&cli.StringFlag{
+						Name:  "request",
+						Usage: "JSON encoded request of the method",
+					},
+					&cli.StringSliceFlag{
+						Name:  "valid-code",
+						Usage: "Status code of the method considered successful (OK, NOT_FOUND...), can be repeated",
+					},
+					&cli.StringSliceFlag{
+						Name:  "response-field",
+						Usage: "Expected value of a field of the response (path=value), can be repeated",
+					},
+				}),
+				Action: func(c *cli.Context) error {
+					return runCheck(c, func(logger *zap.Logger) (healthcheck.Healthcheck, error) {
+						metadata, err := keyValues("metadata", c.StringSlice("metadata"))
+						if err != nil {
+							return nil, err
+						}
+						fields, err := keyValues("response-field", c.StringSlice("response-field"))
+						if err != nil {
+							return nil, err
+						}
+						config := &healthcheck.GRPCHealthcheckConfiguration{
+							Base:           checkBase(c),
+							Target:         c.String("target"),
+							Port:           c.Uint("port"),
+							Timeout:        healthcheck.Duration(c.Duration("timeout")),
+							TLS:            c.Bool("tls"),
+							Key:            c.String("key"),
+							Cert:           c.String("cert"),
+							Cacert:         c.String("cacert"),
+							ServerName:     c.String("server-name"),
+							Insecure:       c.Bool("insecure"),
+							Metadata:       metadata,
+							Service:        c.String("service"),
+							Method:         c.String("method"),
+							Request:        c.String("request"),
+							ValidCodes:     c.StringSlice("valid-code"),
+							ResponseFields: fields,
+						}
+						if err := config.Validate(); err != nil {
+							return nil, err
+						}
+						return healthcheck.NewGRPCHealthcheck(logger, config), nil
+					})
+				},
+			},
+			{
+				Name:  "profile",
+				Usage: "executes an ICMP echo, a TCP connection and an HTTP GET request on a target",
+				Flags: flags([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "target",
+						Usage:    "IP or domain of the target",
+						Required: true,
+					},
+					&cli.StringSliceFlag{
+						Name:  "protocol",
+						Usage: "Protocol checked (icmp, tcp or http), can be repeated, all by default",
+					},
+					&cli.UintFlag{
+						Name:  "port",
+						Usage: "Port of the TCP connection",
+					},
+					&cli.BoolFlag{
+						Name:  "privileged",
+						Usage: "Send the ICMP echo requests using raw sockets",
+					},
+					&cli.StringFlag{
+						Name:  "http-protocol",
+						Usage: "Protocol of the HTTP request (http or https)",
+						Value: "http",
+					},
+					&cli.UintFlag{
+						Name:  "http-port",
+						Usage: "Port of the HTTP request, 80 or 443 by default",
+					},
+					&cli.StringFlag{
+						Name:  "http-path",
+						Usage: "Path of the HTTP request",
+						Value: "/",
+					},
+					&cli.StringSliceFlag{
+						Name:  "valid-status",
+						Usage: "Valid status code or range of status codes (200-299) of the HTTP request, can be repeated",
+						Value: cli.NewStringSlice("200"),
+					},
+					&cli.BoolFlag{
+						Name:  "insecure",
+						Usage: "Skip the server certificate verification",
+					},
+				}),
+				Action: func(c *cli.Context) error {
+					return runCheck(c, func(logger *zap.Logger) (healthcheck.Healthcheck, error) {
+						var protocol healthcheck.Protocol
+						err := protocol.UnmarshalText([]byte(c.String("http-protocol")))
+						if err != nil {
+							return nil, err
+						}
+						validStatus, err := healthcheck.ParseStatusCodes(c.StringSlice("valid-status"))
+						if err != nil {
+							return nil, err
+						}
+						config := &healthcheck.ProfileHealthcheckConfiguration{
+							Base:         checkBase(c),
+							Target:       c.String("target"),
+							Timeout:      healthcheck.Duration(c.Duration("timeout")),
+							Protocols:    c.StringSlice("protocol"),
+							Port:         c.Uint("port"),
+							Privileged:   c.Bool("privileged"),
+							HTTPProtocol: protocol,
+							HTTPPort:     c.Uint("http-port"),
+							HTTPPath:     c.String("http-path"),
+							ValidStatus:  validStatus,
+							Insecure:     c.Bool("insecure"),
+						}
+						if err := config.Validate(); err != nil {
+							return nil, err
+						}
+						return healthcheck.NewProfileHealthcheck(logger, config), nil
+					})
+				},
+			},
+		},
+	}
+}
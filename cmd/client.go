@@ -0,0 +1,402 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/client"
+	"github.com/appclacks/cabourotte/discovery/directory"
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// newClient creates an API client from the client command flags
+func newClient(c *cli.Context) (*client.Client, error) {
+	return client.New(&client.Configuration{
+		URL:       c.String("url"),
+		Username:  c.String("username"),
+		Password:  c.String("password"),
+		Token:     c.String("token"),
+		Namespace: c.String("namespace"),
+		Key:       c.String("key"),
+		Cert:      c.String("cert"),
+		Cacert:    c.String("cacert"),
+		Insecure:  c.Bool("insecure"),
+	})
+}
+
+// readChecks reads the healthchecks of a file, using the checks directory
+// files format
+func readChecks(path string) (*directory.Checks, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the file %s", path)
+	}
+	var checks directory.Checks
+	if err := yaml.UnmarshalStrict([]byte(os.ExpandEnv(string(content))), &checks); err != nil {
+		return nil, errors.Wrapf(err, "Invalid healthchecks in %s", path)
+	}
+	return &checks, nil
+}
+
+// checkTarget returns the target of an healthcheck, depending on its type
+func checkTarget(check client.Healthcheck) string {
+	switch {
+	case check.Field("domain") != "":
+		return check.Field("domain")
+	case check.Field("command") != "":
+		return check.Field("command")
+	case check.Field("port") != "":
+		return fmt.Sprintf("%s:%s", check.Field("target"), check.Field("port"))
+	}
+	return check.Field("target")
+}
+
+// clientFlags returns the flags configuring the API client
+func clientFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "url",
+			Usage:   "URL of the Cabourotte API",
+			Value:   client.DefaultURL,
+			EnvVars: []string{"CABOUROTTE_URL"},
+		},
+		&cli.StringFlag{
+			Name:    "username",
+			Usage:   "Basic auth username",
+			EnvVars: []string{"CABOUROTTE_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:    "password",
+			Usage:   "Basic auth password",
+			EnvVars: []string{"CABOUROTTE_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:    "token",
+			Usage:   "API token",
+			EnvVars: []string{"CABOUROTTE_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:    "namespace",
+			Usage:   "Scope the requests to a namespace",
+			EnvVars: []string{"CABOUROTTE_NAMESPACE"},
+		},
+		&cli.StringFlag{
+			Name:  "key",
+			Usage: "Path to the client private key",
+		},
+		&cli.StringFlag{
+			Name:  "cert",
+			Usage: "Path to the client certificate",
+		},
+		&cli.StringFlag{
+			Name:  "cacert",
+			Usage: "Path to the CA certificate",
+		},
+		&cli.BoolFlag{
+			Name:  "insecure",
+			Usage: "Skip the server certificate verification",
+		},
+	}
+}
+
+func clientCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "client",
+		Usage:  "manages the healthchecks of a remote Cabourotte node using its API",
+		Flags:  append(clientFlags(), outputFlag()),
+		Before: checkOutput,
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "lists the healthchecks",
+				Action: func(c *cli.Context) error {
+					apiClient, err := newClient(c)
+					if err != nil {
+						return err
+					}
+					checks, err := apiClient.ListChecks()
+					if err != nil {
+						return err
+					}
+					rows := make([][]string, 0, len(checks))
+					for _, check := range checks {
+						rows = append(rows, []string{
+							check.Field("name"),
+							check.Field("namespace"),
+							checkTarget(check),
+							check.Field("interval"),
+							check.Field("source"),
+						})
+					}
+					return printOutput(c, checks, table{
+						header: []string{"NAME", "NAMESPACE", "TARGET", "INTERVAL", "SOURCE"},
+						rows:   rows,
+					})
+				},
+			},
+			{
+				Name:  "add",
+				Usage: "adds or replaces the healthchecks defined in a file",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Aliases:  []string{"f"},
+						Usage:    "Path to the healthchecks file",
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					apiClient, err := newClient(c)
+					if err != nil {
+						return err
+					}
+					checks, err := readChecks(c.String("file"))
+					if err != nil {
+						return err
+					}
+					err = apiClient.AddChecks(checks)
+					if err != nil {
+						return err
+					}
+					return printOutput(c, messages{Messages: []string{"Healthchecks successfully added"}}, table{
+						rows: [][]string{{"Healthchecks successfully added"}},
+					})
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "deletes healthchecks",
+				ArgsUsage: "<name>...",
+				Action: func(c *cli.Context) error {
+					if c.NArg() == 0 {
+						return errors.New("The names of the healthchecks to delete are missing")
+					}
+					apiClient, err := newClient(c)
+					if err != nil {
+						return err
+					}
+					output := messages{Messages: []string{}}
+					rows := [][]string{}
+					for _, name := range c.Args().Slice() {
+						err := apiClient.DeleteCheck(name)
+						if err != nil {
+							return errors.Wrapf(err, "Fail to delete the healthcheck %s", name)
+						}
+						msg := fmt.Sprintf("Healthcheck %s deleted", name)
+						output.Messages = append(output.Messages, msg)
+						rows = append(rows, []string{msg})
+					}
+					return printOutput(c, output, table{rows: rows})
+				},
+			},
+			{
+				Name:      "heartbeat",
+				Usage:     "sends a heartbeat to heartbeat healthchecks",
+				ArgsUsage: "<name>...",
+				Action: func(c *cli.Context) error {
+					if c.NArg() == 0 {
+						return errors.New("The names of the healthchecks are missing")
+					}
+					apiClient, err := newClient(c)
+					if err != nil {
+						return err
+					}
+					output := messages{Messages: []string{}}
+					rows := [][]string{}
+					for _, name := range c.Args().Slice() {
+						err := apiClient.Heartbeat(name)
+						if err != nil {
+							return errors.Wrapf(err, "Fail to send the heartbeat to the healthcheck %s", name)
+						}
+						msg := fmt.Sprintf("Heartbeat sent to %s", name)
+						output.Messages = append(output.Messages, msg)
+						rows = append(rows, []string{msg})
+					}
+					return printOutput(c, output, table{rows: rows})
+				},
+			},
+			{
+				Name:      "push",
+				Usage:     "pushes the result of a check executed outside of Cabourotte",
+				ArgsUsage: "<name>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "failure",
+						Usage: "The check failed",
+					},
+					&cli.StringFlag{
+						Name:  "message",
+						Usage: "The result message",
+					},
+					&cli.DurationFlag{
+						Name:  "duration",
+						Usage: "The duration of the check",
+					},
+					&cli.StringSliceFlag{
+						Name:  "label",
+						Usage: "The result labels (key=value)",
+					},
+					&cli.StringFlag{
+						Name:  "severity",
+						Usage: "The result severity (critical, warning or info)",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return errors.New("The name of the result is missing")
+					}
+					labels, err := keyValues("label", c.StringSlice("label"))
+					if err != nil {
+						return err
+					}
+					apiClient, err := newClient(c)
+					if err != nil {
+						return err
+					}
+					name := c.Args().First()
+					err = apiClient.AddExternalResult(&healthcheck.ExternalResult{
+						Name:     name,
+						Success:  !c.Bool("failure"),
+						Message:  c.String("message"),
+						Duration: c.Duration("duration").Milliseconds(),
+						Labels:   labels,
+						Severity: c.String("severity"),
+					})
+					if err != nil {
+						return errors.Wrapf(err, "Fail to push the result %s", name)
+					}
+					msg := fmt.Sprintf("Result %s pushed", name)
+					return printOutput(c, messages{Messages: []string{msg}}, table{
+						rows: [][]string{{msg}},
+					})
+				},
+			},
+			{
+				Name:  "run",
+				Usage: "executes once on the remote node the healthchecks defined in a file",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Aliases:  []string{"f"},
+						Usage:    "Path to the healthchecks file",
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					apiClient, err := newClient(c)
+					if err != nil {
+						return err
+					}
+					checks, err := readChecks(c.String("file"))
+					if err != nil {
+						return err
+					}
+					results := apiClient.RunChecks(checks)
+					failures := 0
+					rows := make([][]string, 0, len(results))
+					for _, result := range results {
+						status := "success"
+						if !result.Success {
+							status = "failure"
+							failures++
+						}
+						rows = append(rows, []string{result.Name, status, result.Message})
+					}
+					err = printOutput(c, results, table{
+						header: []string{"NAME", "STATUS", "MESSAGE"},
+						rows:   rows,
+					})
+					if err != nil {
+						return err
+					}
+					if failures != 0 {
+						return cli.Exit(fmt.Sprintf("%d healthchecks failed", failures), 1)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "results",
+				Usage:     "lists the latest healthchecks results",
+				ArgsUsage: "[name]",
+				Action: func(c *cli.Context) error {
+					apiClient, err := newClient(c)
+					if err != nil {
+						return err
+					}
+					var results []healthcheck.Result
+					if c.NArg() != 0 {
+						result, err := apiClient.GetResult(c.Args().First())
+						if err != nil {
+							return err
+						}
+						results = append(results, result)
+					} else {
+						results, err = apiClient.ListResults()
+						if err != nil {
+							return err
+						}
+					}
+					rows := make([][]string, 0, len(results))
+					for _, result := range results {
+						state := result.State
+						if state == "" {
+							state = "failure"
+							if result.Success {
+								state = "success"
+							}
+						}
+						rows = append(rows, []string{
+							result.Name,
+							result.Namespace,
+							state,
+							time.Unix(result.HealthcheckTimestamp, 0).Format(time.RFC3339),
+							result.Message,
+						})
+					}
+					return printOutput(c, results, table{
+						header: []string{"NAME", "NAMESPACE", "STATE", "TIMESTAMP", "MESSAGE"},
+						rows:   rows,
+					})
+				},
+			},
+			{
+				Name:  "sources",
+				Usage: "lists the sources of healthchecks and their last reconciliation",
+				Action: func(c *cli.Context) error {
+					apiClient, err := newClient(c)
+					if err != nil {
+						return err
+					}
+					sources, err := apiClient.ListSources()
+					if err != nil {
+						return err
+					}
+					rows := make([][]string, 0, len(sources))
+					for _, source := range sources {
+						lastSuccess := ""
+						if source.LastSuccess != nil {
+							lastSuccess = source.LastSuccess.Format(time.RFC3339)
+						}
+						rows = append(rows, []string{
+							source.Source,
+							fmt.Sprintf("%d", source.Checks),
+							fmt.Sprintf("%d", source.Failures),
+							lastSuccess,
+							source.LastError,
+						})
+					}
+					return printOutput(c, sources, table{
+						header: []string{"SOURCE", "CHECKS", "FAILURES", "LAST SUCCESS", "LAST ERROR"},
+						rows:   rows,
+					})
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/appclacks/cabourotte/daemon"
+)
+
+// driftSource formats the source of an healthcheck of the drift table
+// output
+func driftSource(source string) string {
+	if source == "" {
+		return "configuration"
+	}
+	return source
+}
+
+// driftValue formats a field value of the drift table output
+func driftValue(value interface{}) string {
+	if value == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func diffCommand() *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "config",
+			Usage:    "Path to the configuration file",
+			Required: true,
+		},
+		&cli.BoolFlag{
+			Name:     "strict",
+			Usage:    "Reject the unknown fields of the configuration",
+			Required: false,
+		},
+	}
+	return &cli.Command{
+		Name:  "diff",
+		Usage: "compares the healthchecks of a configuration file with the healthchecks registered on a node, and exits with an error if they differ",
+		Flags: append(append(flags, clientFlags()...), outputFlag()),
+		Action: func(c *cli.Context) error {
+			if err := checkOutput(c); err != nil {
+				return err
+			}
+			apiClient, err := newClient(c)
+			if err != nil {
+				return err
+			}
+			config, err := daemon.LoadConfiguration(c.String("config"), c.Bool("strict"))
+			if err != nil {
+				return err
+			}
+			checks, err := apiClient.ListChecks()
+			if err != nil {
+				return err
+			}
+			nodeChecks := make([]map[string]interface{}, 0, len(checks))
+			for _, check := range checks {
+				nodeChecks = append(nodeChecks, check)
+			}
+			drift, err := daemon.ConfigDrift(config, c.String("namespace"), nodeChecks)
+			if err != nil {
+				return err
+			}
+			rows := [][]string{}
+			for _, check := range drift.Added {
+				rows = append(rows, []string{"added", check.ID, "-", "-", "-", "-"})
+			}
+			for _, check := range drift.Removed {
+				rows = append(rows, []string{"removed", check.ID, driftSource(check.Source), "-", "-", "-"})
+			}
+			for _, check := range drift.Changed {
+				for _, field := range check.Fields {
+					rows = append(rows, []string{"changed", check.ID, driftSource(check.Source), field.Field, driftValue(field.Config), driftValue(field.Node)})
+				}
+			}
+			err = printOutput(c, drift, table{
+				header: []string{"CHANGE", "ID", "SOURCE", "FIELD", "CONFIG", "NODE"},
+				rows:   rows,
+			})
+			if err != nil {
+				return err
+			}
+			if !drift.Empty() {
+				return cli.Exit(fmt.Sprintf("%d healthchecks differ", len(drift.Added)+len(drift.Removed)+len(drift.Changed)), 1)
+			}
+			return nil
+		},
+	}
+}
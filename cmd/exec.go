@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/daemon"
+)
+
+func execCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "exec",
+		Usage: "executes once the healthchecks of a configuration file, and exits with an error if one of them fails",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "config",
+				Usage:    "Path to the configuration file",
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:     "strict",
+				Usage:    "Reject the unknown fields of the configuration",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "debug",
+				Usage:    "Enable debug logging",
+				Required: false,
+			},
+			outputFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			if err := checkOutput(c); err != nil {
+				return err
+			}
+			config, err := daemon.LoadConfiguration(c.String("config"), c.Bool("strict"))
+			if err != nil {
+				return err
+			}
+			logger := zap.NewNop()
+			if c.Bool("debug") {
+				logger, err = zap.NewDevelopment()
+				if err != nil {
+					return errors.Wrapf(err, "Fail to start the logger")
+				}
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			results := daemon.Exec(ctx, logger, config)
+			failures := 0
+			rows := make([][]string, 0, len(results))
+			for _, result := range results {
+				status := "success"
+				if !result.Success {
+					status = "failure"
+					failures++
+				}
+				rows = append(rows, []string{
+					result.Name,
+					result.Namespace,
+					status,
+					fmt.Sprintf("%dms", result.Duration),
+					result.Message,
+				})
+			}
+			err = printOutput(c, results, table{
+				header: []string{"NAME", "NAMESPACE", "STATUS", "DURATION", "MESSAGE"},
+				rows:   rows,
+			})
+			if err != nil {
+				return err
+			}
+			if failures != 0 {
+				return cli.Exit(fmt.Sprintf("%d/%d healthchecks failed", failures, len(results)), 1)
+			}
+			fmt.Fprintf(os.Stderr, "%d healthchecks successful\n", len(results))
+			return nil
+		},
+	}
+}
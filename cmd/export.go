@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/appclacks/cabourotte/client"
+)
+
+func exportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "exports in YAML the healthchecks registered on a node",
+		Flags: append(clientFlags(),
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Usage:   "Path to the file to write, the healthchecks are printed if not set",
+			},
+			&cli.StringSliceFlag{
+				Name:  "source",
+				Usage: "Only export the healthchecks of this source (configuration, api or a discovery name), can be repeated",
+			},
+		),
+		Action: func(c *cli.Context) error {
+			apiClient, err := newClient(c)
+			if err != nil {
+				return err
+			}
+			sources := []string{}
+			for _, source := range c.StringSlice("source") {
+				// the healthchecks of the configuration have an empty source
+				if source == "configuration" {
+					source = ""
+				}
+				sources = append(sources, source)
+			}
+			checks, err := apiClient.ExportChecks(sources)
+			if err != nil {
+				return err
+			}
+			content, err := toYAML(checks)
+			if err != nil {
+				return err
+			}
+			if c.String("file") == "" {
+				fmt.Print(string(content))
+				return nil
+			}
+			err = os.WriteFile(c.String("file"), content, 0600)
+			if err != nil {
+				return errors.Wrapf(err, "Fail to write the file %s", c.String("file"))
+			}
+			return nil
+		},
+	}
+}
+
+func importCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "import",
+		Usage: "imports on a node the healthchecks of a file generated by the export command. The healthchecks managed by the API are replaced.",
+		Flags: append(clientFlags(),
+			&cli.StringFlag{
+				Name:     "file",
+				Aliases:  []string{"f"},
+				Usage:    "Path to the file to import",
+				Required: true,
+			},
+			outputFlag(),
+		),
+		Action: func(c *cli.Context) error {
+			if err := checkOutput(c); err != nil {
+				return err
+			}
+			apiClient, err := newClient(c)
+			if err != nil {
+				return err
+			}
+			content, err := os.ReadFile(c.String("file"))
+			if err != nil {
+				return errors.Wrapf(err, "Fail to read the file %s", c.String("file"))
+			}
+			checks, err := client.ParseChecks(content)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid file %s", c.String("file"))
+			}
+			err = apiClient.ImportChecks(checks)
+			if err != nil {
+				return err
+			}
+			msg := fmt.Sprintf("%d healthchecks successfully imported", len(checks.All()))
+			return printOutput(c, messages{Messages: []string{msg}}, table{rows: [][]string{{msg}}})
+		},
+	}
+}
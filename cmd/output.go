@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// outputs the valid output formats
+var outputs = []string{outputTable, outputJSON, outputYAML}
+
+// table the table representation of a command output. The header is
+// optional.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// messages the output of the commands only returning messages, using the
+// same format as the API responses
+type messages struct {
+	Messages []string `json:"messages"`
+}
+
+// outputFlag returns the flag selecting the output format
+func outputFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "output",
+		Aliases: []string{"o"},
+		Usage:   fmt.Sprintf("Output format (%s)", strings.Join(outputs, ", ")),
+		Value:   outputTable,
+	}
+}
+
+// checkOutput verifies the output format selected by the output flag
+func checkOutput(c *cli.Context) error {
+	output := c.String("output")
+	for _, valid := range outputs {
+		if output == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid output %s, valid outputs are %s", output, strings.Join(outputs, ", "))
+}
+
+// printOutput prints a value using the output format selected by the
+// output flag. The table is only used by the table format.
+func printOutput(c *cli.Context, value interface{}, t table) error {
+	switch c.String("output") {
+	case outputJSON:
+		return printJSON(value)
+	case outputYAML:
+		return printYAML(value)
+	}
+	return printTable(t)
+}
+
+// printJSON prints a value as JSON
+func printJSON(value interface{}) error {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "Fail to convert the output to json")
+	}
+	fmt.Println(string(content))
+	return nil
+}
+
+// toYAML converts a value to YAML. The value is converted to JSON first,
+// so the fields names are the same in both formats.
+func toYAML(value interface{}) ([]byte, error) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to convert the output to json")
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, errors.Wrapf(err, "Fail to convert the output to yaml")
+	}
+	content, err = yaml.Marshal(raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to convert the output to yaml")
+	}
+	return content, nil
+}
+
+// printYAML prints a value as YAML
+func printYAML(value interface{}) error {
+	content, err := toYAML(value)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(content))
+	return nil
+}
+
+// printTable prints rows as an aligned table
+func printTable(t table) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if len(t.header) != 0 {
+		fmt.Fprintln(w, strings.Join(t.header, "\t"))
+	}
+	for _, row := range t.rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
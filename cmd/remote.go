@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/daemon"
+	"github.com/appclacks/cabourotte/tls"
+)
+
+// remoteConfig fetches the configuration from an URL and stores it in a
+// local cache file. The cache file is only updated when the remote
+// configuration is valid, so the last good configuration is used when
+// the remote configuration is unavailable.
+type remoteConfig struct {
+	url         string
+	checksumURL string
+	cache       string
+	interval    time.Duration
+	strict      bool
+	client      *http.Client
+	logger      *zap.Logger
+	onChange    func()
+	stop        chan struct{}
+}
+
+func newRemoteConfig(logger *zap.Logger, url string, checksumURL string, cache string, key string, cert string, cacert string, interval time.Duration, strict bool) (*remoteConfig, error) {
+	if (key == "") != (cert == "") {
+		return nil, errors.New("The remote configuration key and cert should be configured together")
+	}
+	tlsConfig, err := tls.GetTLSConfig(key, cert, cacert, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteConfig{
+		url:         url,
+		checksumURL: checksumURL,
+		cache:       cache,
+		interval:    interval,
+		strict:      strict,
+		logger:      logger,
+		stop:        make(chan struct{}),
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+			Timeout: time.Second * 30,
+		},
+	}, nil
+}
+
+// get sends a GET request and returns the response body
+func (r *remoteConfig) get(url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to create request for %s", url)
+	}
+	req.Header.Set("User-Agent", "Cabourotte")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to send request to %s", url)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read request body")
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Request to %s failed, status %d", url, resp.StatusCode)
+	}
+	return body, nil
+}
+
+// verify verifies the configuration checksum. The checksum file uses the
+// sha256sum format.
+func (r *remoteConfig) verify(content []byte) error {
+	if r.checksumURL == "" {
+		return nil
+	}
+	body, err := r.get(r.checksumURL)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to fetch the configuration checksum")
+	}
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 {
+		return errors.New("The configuration checksum is empty")
+	}
+	sum := sha256.Sum256(content)
+	if !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+		return fmt.Errorf("Invalid configuration checksum: expected %s, got %s", fields[0], hex.EncodeToString(sum[:]))
+	}
+	return nil
+}
+
+// fetch fetches the remote configuration and updates the cache file if
+// the configuration is valid. It returns true if the cache was updated.
+func (r *remoteConfig) fetch() (bool, error) {
+	content, err := r.get(r.url)
+	if err != nil {
+		return false, errors.Wrapf(err, "Fail to fetch the remote configuration")
+	}
+	err = r.verify(content)
+	if err != nil {
+		return false, err
+	}
+	current, err := os.ReadFile(r.cache)
+	if err == nil && bytes.Equal(current, content) {
+		return false, nil
+	}
+	// the configuration is written next to the cache file in order to
+	// resolve the includes from the same directory
+	tmp := r.cache + ".tmp"
+	err = os.WriteFile(tmp, content, 0600)
+	if err != nil {
+		return false, errors.Wrapf(err, "Fail to write the configuration file %s", tmp)
+	}
+	_, err = daemon.LoadConfiguration(tmp, r.strict)
+	if err != nil {
+		// nolint
+		os.Remove(tmp)
+		return false, errors.Wrapf(err, "Invalid remote configuration")
+	}
+	err = os.Rename(tmp, r.cache)
+	if err != nil {
+		return false, errors.Wrapf(err, "Fail to update the configuration file %s", r.cache)
+	}
+	return true, nil
+}
+
+// init fetches the configuration at startup. The cached configuration is
+// used if the remote configuration is not available.
+func (r *remoteConfig) init() error {
+	_, err := r.fetch()
+	if err != nil {
+		if _, statErr := os.Stat(r.cache); statErr != nil {
+			return errors.Wrapf(err, "No cached configuration available")
+		}
+		r.logger.Error(fmt.Sprintf("Using the cached configuration %s: %s", r.cache, err.Error()))
+	}
+	return nil
+}
+
+// start periodically refreshes the configuration and calls onChange when
+// the cache is updated
+func (r *remoteConfig) start(onChange func()) {
+	r.onChange = onChange
+	ticker := time.NewTicker(r.interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				updated, err := r.fetch()
+				if err != nil {
+					r.logger.Error(err.Error())
+					continue
+				}
+				if updated {
+					r.logger.Info(fmt.Sprintf("Remote configuration %s changed", r.url))
+					r.onChange()
+				}
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (r *remoteConfig) close() {
+	close(r.stop)
+}
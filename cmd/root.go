@@ -5,22 +5,101 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
-
-	"gopkg.in/yaml.v2"
+	"time"
 
 	"github.com/appclacks/cabourotte/daemon"
+	"github.com/appclacks/cabourotte/logging"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
 	"go.uber.org/zap"
 )
 
+// validation the output of the validate command
+type validation struct {
+	Config string   `json:"config"`
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors"`
+}
+
 // Main the main entrypoint
 func Main() {
 	app := &cli.App{
 		Usage: "Cabourotte, a monitoring tool to execute healthchecks on your infrastructure",
 		Commands: []*cli.Command{
+			clientCommand(),
+			{
+				Name:  "init",
+				Usage: "prints a commented example configuration",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "type",
+						Usage:    fmt.Sprintf("Only print the example of this type (%s)", strings.Join(daemon.ExampleTypes, ", ")),
+						Required: false,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					example, err := daemon.Example(c.String("type"))
+					if err != nil {
+						return err
+					}
+					fmt.Print(example)
+					return nil
+				},
+			},
+			{
+				Name:  "validate",
+				Usage: "validates a configuration file",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "config",
+						Usage:    "Path to the configuration file",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:     "strict",
+						Usage:    "Reject the unknown fields of the configuration",
+						Required: false,
+					},
+					outputFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					if err := checkOutput(c); err != nil {
+						return err
+					}
+					errs := daemon.ValidateConfiguration(c.String("config"), c.Bool("strict"))
+					if c.String("output") == outputTable {
+						if len(errs) == 0 {
+							fmt.Printf("The configuration %s is valid\n", c.String("config"))
+							return nil
+						}
+						for _, err := range errs {
+							fmt.Fprintln(os.Stderr, err.Error())
+						}
+					} else {
+						result := validation{Config: c.String("config"), Valid: len(errs) == 0, Errors: []string{}}
+						for _, err := range errs {
+							result.Errors = append(result.Errors, err.Error())
+						}
+						err := printOutput(c, result, table{})
+						if err != nil {
+							return err
+						}
+						if result.Valid {
+							return nil
+						}
+					}
+					return cli.Exit(fmt.Sprintf("The configuration %s is invalid", c.String("config")), 1)
+				},
+			},
+			execCommand(),
+			checkCommand(),
+			diffCommand(),
+			exportCommand(),
+			importCommand(),
 			{
 				Name:  "daemon",
 				Usage: "starts the Cabourotte daemon",
@@ -30,40 +109,155 @@ func Main() {
 						Usage:    "Path to the configuration file",
 						Required: true,
 					},
+					&cli.BoolFlag{
+						Name:     "strict",
+						Usage:    "Reject the unknown fields of the configuration",
+						Required: false,
+					},
 					&cli.BoolFlag{
 						Name:     "debug",
 						Usage:    "Enable debug logging",
 						Required: false,
 					},
+					&cli.BoolFlag{
+						Name:     "watch",
+						Usage:    "Reload the configuration automatically when the file changes",
+						Required: false,
+					},
+					&cli.DurationFlag{
+						Name:     "watch-interval",
+						Usage:    "Interval between two checks of the configuration file when watch is enabled, and of the templates targets files",
+						Value:    5 * time.Second,
+						Required: false,
+					},
+					&cli.StringFlag{
+						Name:     "config-url",
+						Usage:    "URL of the configuration. The configuration is stored in the file set by --config, which is used if the URL is unavailable",
+						Required: false,
+					},
+					&cli.StringFlag{
+						Name:     "config-url-checksum",
+						Usage:    "URL of the sha256 checksum of the remote configuration",
+						Required: false,
+					},
+					&cli.StringFlag{
+						Name:     "config-url-key",
+						Usage:    "Path to the private key used to fetch the remote configuration",
+						Required: false,
+					},
+					&cli.StringFlag{
+						Name:     "config-url-cert",
+						Usage:    "Path to the certificate used to fetch the remote configuration",
+						Required: false,
+					},
+					&cli.StringFlag{
+						Name:     "config-url-cacert",
+						Usage:    "Path to the CA certificate used to fetch the remote configuration",
+						Required: false,
+					},
+					&cli.DurationFlag{
+						Name:     "config-url-refresh",
+						Usage:    "Interval between two fetches of the remote configuration, 0 to disable the refresh",
+						Value:    time.Minute,
+						Required: false,
+					},
 				},
 				Action: func(c *cli.Context) error {
-					file, err := os.ReadFile(c.String("config"))
-					if err != nil {
-						return errors.Wrapf(err, "fail to read the configuration file")
+					var remote *remoteConfig
+					if c.String("config-url") != "" {
+						bootstrapLogger, err := zap.NewProduction()
+						if err != nil {
+							return errors.Wrapf(err, "Fail to start the logger")
+						}
+						remote, err = newRemoteConfig(
+							bootstrapLogger,
+							c.String("config-url"),
+							c.String("config-url-checksum"),
+							c.String("config"),
+							c.String("config-url-key"),
+							c.String("config-url-cert"),
+							c.String("config-url-cacert"),
+							c.Duration("config-url-refresh"),
+							c.Bool("strict"))
+						if err != nil {
+							return errors.Wrapf(err, "Fail to configure the remote configuration")
+						}
+						err = remote.init()
+						if err != nil {
+							return err
+						}
 					}
-					var config daemon.Configuration
-					if err := yaml.Unmarshal(file, &config); err != nil {
-						return errors.Wrapf(err, "Fail to read the yaml config file")
+					config, err := daemon.LoadConfiguration(c.String("config"), c.Bool("strict"))
+					if err != nil {
+						return err
 					}
 					if config.HTTP.Host == "" {
 						return errors.New("Invalid HTTP server configuration")
 					}
-					zapConfig := zap.NewProductionConfig()
-					if c.Bool("debug") {
-						zapConfig.Level.SetLevel(zap.DebugLevel)
-					}
-					logger, err := zapConfig.Build()
+					logger, closeLogs, err := logging.New(&config.Logging, c.Bool("debug"))
 					if err != nil {
 						return errors.Wrapf(err, "Fail to start the logger")
 					}
 					// nolint
+					defer closeLogs()
+					// nolint
 					defer logger.Sync()
-					daemonComponent, err := daemon.New(logger, &config)
+					daemonComponent, err := daemon.New(logger, config)
 					if err != nil {
 						return errors.Wrapf(err, "Fail to creae the daemon")
 					}
 					signals := make(chan os.Signal, 1)
 					errChan := make(chan error)
+					var reloadLock sync.Mutex
+					var watcher *fileWatcher
+					// the configuration file is watched if enabled, the
+					// templates targets files are always watched
+					watchedFiles := func(config *daemon.Configuration) []string {
+						files := config.TargetsFiles()
+						if c.Bool("watch") {
+							files = append([]string{c.String("config")}, files...)
+						}
+						return files
+					}
+					reload := func() {
+						reloadLock.Lock()
+						defer reloadLock.Unlock()
+						newConfig, err := daemon.LoadConfiguration(c.String("config"), c.Bool("strict"))
+						if err != nil {
+							logger.Error(err.Error())
+							daemonComponent.ReloadStatus(err)
+							return
+						}
+						err = daemonComponent.Reload(newConfig)
+						if err != nil {
+							logger.Error(fmt.Sprintf("Fail to reload: %s", err.Error()))
+							errChan <- err
+							return
+						}
+						err = watcher.setPaths(watchedFiles(newConfig))
+						if err != nil {
+							logger.Error(fmt.Sprintf("Fail to watch the configuration files: %s", err.Error()))
+						}
+					}
+					watcher, err = newFileWatcher(logger, watchedFiles(config), c.Duration("watch-interval"), reload)
+					if err != nil {
+						return errors.Wrapf(err, "Fail to watch the configuration files")
+					}
+					watcher.start()
+					defer watcher.close()
+					if remote != nil && c.Duration("config-url-refresh") != 0 {
+						remote.logger = logger
+						remote.start(reload)
+						defer remote.close()
+					}
+					if config.Vault != nil {
+						renewer, err := newVaultRenewer(logger, config.Vault, reload)
+						if err != nil {
+							return errors.Wrapf(err, "Fail to create the Vault client")
+						}
+						renewer.start()
+						defer renewer.close()
+					}
 
 					signal.Notify(
 						signals,
@@ -84,21 +278,7 @@ func Main() {
 								errChan <- nil
 							case syscall.SIGHUP:
 								logger.Info(fmt.Sprintf("Received signal %s, reload", sig))
-								newFile, err := os.ReadFile(c.String("config"))
-								if err != nil {
-									logger.Error(err.Error())
-								} else {
-									var newConfig daemon.Configuration
-									if err := yaml.Unmarshal(newFile, &newConfig); err != nil {
-										logger.Error(err.Error())
-									} else {
-										err := daemonComponent.Reload(&newConfig)
-										if err != nil {
-											logger.Error(fmt.Sprintf("Fail to reload: %s", err.Error()))
-											errChan <- err
-										}
-									}
-								}
+								reload()
 							}
 
 						}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/secret"
+)
+
+// vaultRenewer periodically renews the Vault token and calls onRenew in
+// order to reload the configuration with the latest secrets.
+type vaultRenewer struct {
+	vault    *secret.Vault
+	interval time.Duration
+	logger   *zap.Logger
+	onRenew  func()
+	stop     chan struct{}
+}
+
+func newVaultRenewer(logger *zap.Logger, config *secret.VaultConfiguration, onRenew func()) (*vaultRenewer, error) {
+	vault, err := secret.NewVault(config)
+	if err != nil {
+		return nil, err
+	}
+	return &vaultRenewer{
+		vault:    vault,
+		interval: time.Duration(config.RenewInterval),
+		logger:   logger,
+		onRenew:  onRenew,
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// renew renews the token and reloads the secrets
+func (r *vaultRenewer) renew() {
+	err := r.vault.RenewToken()
+	if err != nil {
+		r.logger.Error(fmt.Sprintf("Fail to renew the Vault token: %s", err.Error()))
+	}
+	r.onRenew()
+}
+
+func (r *vaultRenewer) start() {
+	ticker := time.NewTicker(r.interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.renew()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (r *vaultRenewer) close() {
+	close(r.stop)
+}
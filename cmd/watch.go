@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fileWatcher polls files and calls onChange when their content changes.
+// Polling is used instead of inotify in order to detect files replaced by
+// rename or through symlinks updates (for example Kubernetes ConfigMaps).
+type fileWatcher struct {
+	interval time.Duration
+	logger   *zap.Logger
+	onChange func()
+	lock     sync.Mutex
+	// hashes the hash of the content of the watched files, by path
+	hashes map[string][]byte
+	stop   chan struct{}
+}
+
+func fileHash(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(content)
+	return hash[:], nil
+}
+
+func newFileWatcher(logger *zap.Logger, paths []string, interval time.Duration, onChange func()) (*fileWatcher, error) {
+	w := &fileWatcher{
+		interval: interval,
+		logger:   logger,
+		onChange: onChange,
+		hashes:   make(map[string][]byte),
+		stop:     make(chan struct{}),
+	}
+	if err := w.setPaths(paths); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// setPaths sets the watched files, the hashes of the files already
+// watched being kept
+func (w *fileWatcher) setPaths(paths []string) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	hashes := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		if hash, ok := w.hashes[path]; ok {
+			hashes[path] = hash
+			continue
+		}
+		hash, err := fileHash(path)
+		if err != nil {
+			return err
+		}
+		hashes[path] = hash
+	}
+	w.hashes = hashes
+	return nil
+}
+
+// check verifies if the files changed since the last check
+func (w *fileWatcher) check() {
+	changed := false
+	w.lock.Lock()
+	for path, previous := range w.hashes {
+		hash, err := fileHash(path)
+		if err != nil {
+			w.logger.Error(fmt.Sprintf("Fail to read the watched file %s: %s", path, err.Error()))
+			continue
+		}
+		if !bytes.Equal(hash, previous) {
+			w.hashes[path] = hash
+			w.logger.Info(fmt.Sprintf("File %s changed", path))
+			changed = true
+		}
+	}
+	w.lock.Unlock()
+	if changed {
+		w.onChange()
+	}
+}
+
+func (w *fileWatcher) start() {
+	ticker := time.NewTicker(w.interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.check()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (w *fileWatcher) close() {
+	close(w.stop)
+}
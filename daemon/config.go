@@ -1,28 +1,123 @@
 package daemon
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/pkg/errors"
 
+	"github.com/appclacks/cabourotte/aggregator"
+	"github.com/appclacks/cabourotte/cluster"
 	"github.com/appclacks/cabourotte/discovery"
 	"github.com/appclacks/cabourotte/exporter"
+	"github.com/appclacks/cabourotte/grpcapi"
 	"github.com/appclacks/cabourotte/healthcheck"
 	"github.com/appclacks/cabourotte/http"
+	"github.com/appclacks/cabourotte/ingest"
+	"github.com/appclacks/cabourotte/logging"
+	"github.com/appclacks/cabourotte/maintenance"
+	"github.com/appclacks/cabourotte/memorystore"
+	"github.com/appclacks/cabourotte/prometheus"
+	"github.com/appclacks/cabourotte/resolver"
+	"github.com/appclacks/cabourotte/secret"
+	"github.com/appclacks/cabourotte/tracing"
 )
 
 // Configuration the HTTP server configuration
 type Configuration struct {
 	ResultBuffer  uint `yaml:"result-buffer"`
-	HTTP          http.Configuration
-	MetricsLabels []string                                      `yaml:"metrics-labels"`
-	CommandChecks []healthcheck.CommandHealthcheckConfiguration `yaml:"command-checks"`
-	DNSChecks     []healthcheck.DNSHealthcheckConfiguration     `yaml:"dns-checks"`
-	TCPChecks     []healthcheck.TCPHealthcheckConfiguration     `yaml:"tcp-checks"`
-	HTTPChecks    []healthcheck.HTTPHealthcheckConfiguration    `yaml:"http-checks"`
-	TLSChecks     []healthcheck.TLSHealthcheckConfiguration     `yaml:"tls-checks"`
-	Exporters     exporter.Configuration
-	Discovery     discovery.Configuration
+	ResultHistory uint `yaml:"result-history"`
+	// ResultPersistence persists the results history on disk, applied
+	// on startup
+	ResultPersistence *memorystore.PersistenceConfiguration `yaml:"result-persistence"`
+	HTTP              http.Configuration
+	// GRPC the gRPC server exposing the management API, disabled if not
+	// configured
+	GRPC            *grpcapi.Configuration                          `yaml:"grpc"`
+	MetricsLabels   []string                                        `yaml:"metrics-labels"`
+	CommandChecks   []healthcheck.CommandHealthcheckConfiguration   `yaml:"command-checks"`
+	DNSChecks       []healthcheck.DNSHealthcheckConfiguration       `yaml:"dns-checks"`
+	TCPChecks       []healthcheck.TCPHealthcheckConfiguration       `yaml:"tcp-checks"`
+	HTTPChecks      []healthcheck.HTTPHealthcheckConfiguration      `yaml:"http-checks"`
+	TLSChecks       []healthcheck.TLSHealthcheckConfiguration       `yaml:"tls-checks"`
+	HeartbeatChecks []healthcheck.HeartbeatHealthcheckConfiguration `yaml:"heartbeat-checks"`
+	GRPCChecks      []healthcheck.GRPCHealthcheckConfiguration      `yaml:"grpc-checks"`
+	ProfileChecks   []healthcheck.ProfileHealthcheckConfiguration   `yaml:"profile-checks"`
+	Exporters       exporter.Configuration
+	Discovery       discovery.Configuration
+	Maintenance     []maintenance.Window `yaml:"maintenance-windows"`
+	Concurrency     healthcheck.ConcurrencyConfiguration
+	PersistenceFile string `yaml:"persistence-file"`
+	ChecksDirectory string `yaml:"checks-directory"`
+	Cluster         cluster.Configuration
+	Shutdown        ShutdownConfiguration
+	// Include glob patterns of files defining additional healthchecks
+	// and exporters
+	Include []string
+	// Templates parameterized healthchecks expanded for each target
+	Templates []Template
+	// Defaults the default values of the healthchecks parameters
+	Defaults Defaults
+	// Vault the Vault configuration used to resolve the _vault fields
+	Vault *secret.VaultConfiguration
+	// Logging the logging configuration, applied on startup
+	Logging logging.Configuration
+	// Tracing the tracing of the healthchecks executions, applied on
+	// startup
+	Tracing *tracing.Configuration
+	// DNSCache the caching resolver of the TCP and HTTP healthchecks
+	// targets, applied on startup
+	DNSCache *resolver.Configuration `yaml:"dns-cache"`
+	// Node the identity of the node, attached to the exported results and
+	// metrics, applied on startup
+	Node *healthcheck.Node
+	// NativeHistograms exposes the healthchecks durations as Prometheus
+	// native histograms, applied on startup
+	NativeHistograms *prometheus.NativeHistograms `yaml:"native-histograms"`
+	// Aggregator receives the results pushed by other Cabourotte nodes
+	// using the HTTP exporter, applied on startup
+	Aggregator *aggregator.Configuration
+	// Ingest receives the results forwarded by other Cabourotte nodes
+	// using the cabourotte exporter, applied on startup
+	Ingest *ingest.Configuration
+	// HTTPBandwidthLimit the maximum bandwidth in bytes per second used by
+	// all the HTTP healthchecks to read the responses, unlimited if 0
+	HTTPBandwidthLimit uint64 `yaml:"http-bandwidth-limit"`
+	// TargetPolicy restricts the addresses of the healthchecks targets
+	TargetPolicy *healthcheck.TargetPolicy `yaml:"target-policy"`
+	// CommandPolicy restricts the executables of the command healthchecks,
+	// and sets the cgroup limiting their resources
+	CommandPolicy *healthcheck.CommandPolicy `yaml:"command-policy"`
+	// TargetRateLimit limits the executions per second against each
+	// target host
+	TargetRateLimit *healthcheck.RateLimit `yaml:"target-rate-limit"`
+	// Proxy the proxy used by the healthchecks which do not configure
+	// their own proxy
+	Proxy *healthcheck.Proxy
+	// Credentials the named credentials referenced by the healthchecks
+	Credentials healthcheck.Credentials
+	// HealthStaleness the maximum age of the groups states returned by the
+	// health endpoints, computed on each request if 0
+	HealthStaleness healthcheck.Duration `yaml:"health-staleness"`
+	// ResultRetention the retention of the results kept in memory
+	ResultRetention memorystore.RetentionConfiguration `yaml:"result-retention"`
+	// targetsFiles the targets files of the templates, watched for
+	// changes
+	targetsFiles []string
+}
+
+// ShutdownConfiguration the graceful shutdown configuration
+type ShutdownConfiguration struct {
+	// Timeout the maximum time to wait for the healthchecks being executed
+	Timeout healthcheck.Duration
+	// Event emits an event when the daemon is shutting down
+	Event bool
 }
 
+// DefaultShutdownTimeout the default maximum time to wait for the
+// healthchecks being executed on shutdown
+const DefaultShutdownTimeout = healthcheck.Duration(10 * time.Second)
+
 // DefaultBufferSize the default siez for the buffer containing healthchecks results
 const DefaultBufferSize = 5000
 
@@ -34,7 +129,30 @@ func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) er
 	if err := unmarshal(&raw); err != nil {
 		return errors.Wrap(err, "Unable to read Cabourotte configuration")
 	}
+	expanded, err := expandTemplates(raw.Templates, false)
+	if err != nil {
+		return errors.Wrap(err, "Invalid template configuration")
+	}
+	raw.CommandChecks = append(raw.CommandChecks, expanded.CommandChecks...)
+	raw.DNSChecks = append(raw.DNSChecks, expanded.DNSChecks...)
+	raw.TCPChecks = append(raw.TCPChecks, expanded.TCPChecks...)
+	raw.HTTPChecks = append(raw.HTTPChecks, expanded.HTTPChecks...)
+	raw.TLSChecks = append(raw.TLSChecks, expanded.TLSChecks...)
+	raw.targetsFiles = expanded.targetsFiles
+	raw.Defaults.apply(&includedConfiguration{
+		CommandChecks:   raw.CommandChecks,
+		DNSChecks:       raw.DNSChecks,
+		TCPChecks:       raw.TCPChecks,
+		HTTPChecks:      raw.HTTPChecks,
+		TLSChecks:       raw.TLSChecks,
+		HeartbeatChecks: raw.HeartbeatChecks,
+		GRPCChecks:      raw.GRPCChecks,
+		ProfileChecks:   raw.ProfileChecks,
+	})
 	for i := range raw.CommandChecks {
+		if err := healthcheck.GenerateName(&raw.CommandChecks[i]); err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
 		check := raw.CommandChecks[i]
 		err := check.Validate()
 		if err != nil {
@@ -42,6 +160,9 @@ func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) er
 		}
 	}
 	for i := range raw.DNSChecks {
+		if err := healthcheck.GenerateName(&raw.DNSChecks[i]); err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
 		check := raw.DNSChecks[i]
 		err := check.Validate()
 		if err != nil {
@@ -49,6 +170,9 @@ func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) er
 		}
 	}
 	for i := range raw.TCPChecks {
+		if err := healthcheck.GenerateName(&raw.TCPChecks[i]); err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
 		check := raw.TCPChecks[i]
 		err := check.Validate()
 		if err != nil {
@@ -56,6 +180,9 @@ func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) er
 		}
 	}
 	for i := range raw.HTTPChecks {
+		if err := healthcheck.GenerateName(&raw.HTTPChecks[i]); err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
 		check := raw.HTTPChecks[i]
 		err := check.Validate()
 		if err != nil {
@@ -63,12 +190,86 @@ func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) er
 		}
 	}
 	for i := range raw.TLSChecks {
+		if err := healthcheck.GenerateName(&raw.TLSChecks[i]); err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
 		check := raw.TLSChecks[i]
 		err := check.Validate()
 		if err != nil {
 			return errors.Wrap(err, "Invalid healthcheck configuration")
 		}
 	}
+	for i := range raw.HeartbeatChecks {
+		if err := healthcheck.GenerateName(&raw.HeartbeatChecks[i]); err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+		check := raw.HeartbeatChecks[i]
+		err := check.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+	}
+	for i := range raw.GRPCChecks {
+		if err := healthcheck.GenerateName(&raw.GRPCChecks[i]); err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+		check := raw.GRPCChecks[i]
+		err := check.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+	}
+	for i := range raw.ProfileChecks {
+		if err := healthcheck.GenerateName(&raw.ProfileChecks[i]); err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+		check := raw.ProfileChecks[i]
+		err := check.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+	}
+	if err := raw.Credentials.Validate(); err != nil {
+		return errors.Wrap(err, "Invalid credentials configuration")
+	}
+	for i := range raw.HTTPChecks {
+		name := raw.HTTPChecks[i].Credential
+		if name != "" && raw.Credentials.Get(name) == nil {
+			return fmt.Errorf("The credential %s of the healthcheck %s does not exist", name, raw.HTTPChecks[i].Base.Name)
+		}
+	}
+	for i := range raw.TLSChecks {
+		name := raw.TLSChecks[i].Credential
+		if name != "" && raw.Credentials.Get(name) == nil {
+			return fmt.Errorf("The credential %s of the healthcheck %s does not exist", name, raw.TLSChecks[i].Base.Name)
+		}
+	}
+	// the node identity is attached to the forwarded results, and
+	// detects the forwarding loops
+	if raw.Ingest != nil && raw.Node == nil {
+		return errors.New("The node identity is required to ingest the forwarded results")
+	}
+	if len(raw.Exporters.Cabourotte) != 0 && raw.Node == nil {
+		return errors.New("The node identity is required to forward the results with the cabourotte exporter")
+	}
+	if err := raw.Exporters.CircuitBreaker.Validate(); err != nil {
+		return err
+	}
+	for i := range raw.Maintenance {
+		err := raw.Maintenance[i].Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid maintenance window configuration")
+		}
+	}
+	if raw.HealthStaleness < 0 {
+		return errors.New("The health-staleness should be positive")
+	}
+	if err := raw.ResultRetention.Validate(); err != nil {
+		return err
+	}
+	if raw.Shutdown.Timeout == 0 {
+		raw.Shutdown.Timeout = DefaultShutdownTimeout
+	}
 	if raw.ResultBuffer == 0 {
 		raw.ResultBuffer = chanSize
 	}
@@ -14,6 +14,7 @@ import (
 	"github.com/appclacks/cabourotte/exporter"
 	"github.com/appclacks/cabourotte/healthcheck"
 	"github.com/appclacks/cabourotte/http"
+	"github.com/appclacks/cabourotte/maintenance"
 )
 
 func TestUnmarshalConfig(t *testing.T) {
@@ -38,6 +39,7 @@ discovery:
 `,
 			want: Configuration{
 				ResultBuffer: DefaultBufferSize,
+				Shutdown:     ShutdownConfiguration{Timeout: DefaultShutdownTimeout},
 				HTTP: http.Configuration{
 					Host: "127.0.0.1",
 					Port: 2000,
@@ -77,6 +79,7 @@ dns-checks:
 `,
 			want: Configuration{
 				ResultBuffer: DefaultBufferSize,
+				Shutdown:     ShutdownConfiguration{Timeout: DefaultShutdownTimeout},
 				HTTP: http.Configuration{
 					Host: "127.0.0.1",
 					Port: 2000},
@@ -221,6 +224,7 @@ exporters:
 `,
 			want: Configuration{
 				ResultBuffer: 1000,
+				Shutdown:     ShutdownConfiguration{Timeout: DefaultShutdownTimeout},
 				HTTP: http.Configuration{
 					Host: "127.0.0.1",
 					Port: 2000,
@@ -375,6 +379,64 @@ exporters:
 				},
 			},
 		},
+		{
+			in: `
+http:
+  host: "127.0.0.1"
+  port: 2000
+maintenance-windows:
+  - name: upgrade
+    start: 2023-06-01T20:00:00Z
+    duration: 2h
+    selector:
+      environment: prod
+`,
+			want: Configuration{
+				ResultBuffer: DefaultBufferSize,
+				Shutdown:     ShutdownConfiguration{Timeout: DefaultShutdownTimeout},
+				HTTP: http.Configuration{
+					Host: "127.0.0.1",
+					Port: 2000,
+				},
+				Maintenance: []maintenance.Window{
+					{
+						Name:     "upgrade",
+						Start:    time.Date(2023, 6, 1, 20, 0, 0, 0, time.UTC),
+						Duration: healthcheck.Duration(2 * time.Hour),
+						Selector: map[string]string{
+							"environment": "prod",
+						},
+					},
+				},
+			},
+		},
+		{
+			in: `
+http:
+  host: "127.0.0.1"
+  port: 2000
+node:
+  name: prober-1
+  region: eu-west
+  zone: eu-west-1a
+  labels:
+    datacenter: dc1
+`,
+			want: Configuration{
+				ResultBuffer: DefaultBufferSize,
+				Shutdown:     ShutdownConfiguration{Timeout: DefaultShutdownTimeout},
+				HTTP: http.Configuration{
+					Host: "127.0.0.1",
+					Port: 2000,
+				},
+				Node: &healthcheck.Node{
+					Name:   "prober-1",
+					Region: "eu-west",
+					Zone:   "eu-west-1a",
+					Labels: map[string]string{"datacenter": "dc1"},
+				},
+			},
+		},
 	}
 	for _, c := range cases {
 		var result Configuration
@@ -390,6 +452,14 @@ exporters:
 func TestInvalidConfig(t *testing.T) {
 	cases := []string{
 		`
+http:
+  host: "127.0.0.1"
+  port: 2000
+node:
+  labels:
+    data-center: dc1
+`,
+		`
 http:
   host: ""
   port: 2000
@@ -513,6 +583,92 @@ http-checks:
       - 201
     labels:
       environment: prod
+`,
+		`
+http:
+  host: "127.0.0.1"
+  port: 2000
+credentials:
+  - name: api
+    token: foo
+http-checks:
+  - name: foo
+    target: "mcorbin.fr"
+    port: 443
+    interval: 10s
+    timeout: 5s
+    protocol: https
+    valid-status: [200]
+    credential: unknown
+`,
+		`
+http:
+  host: "127.0.0.1"
+  port: 2000
+credentials:
+  - name: api
+    token: foo
+  - name: api
+    username: foo
+`,
+		`
+http:
+  host: "127.0.0.1"
+  port: 2000
+ingest:
+  secret: foo
+`,
+		`
+http:
+  host: "127.0.0.1"
+  port: 2000
+exporters:
+  cabourotte:
+    - name: regional
+      host: "127.0.0.1"
+      port: 9013
+`,
+		`
+http:
+  host: "127.0.0.1"
+  port: 2000
+health-staleness: -1s
+`,
+		`
+http:
+  host: "127.0.0.1"
+  port: 2000
+exporters:
+  circuit-breaker:
+    threshold: 5
+`,
+		`
+http:
+  host: "127.0.0.1"
+  port: 2000
+result-retention:
+  max-age: -1m
+`,
+		`
+http:
+  host: "127.0.0.1"
+  port: 2000
+tcp-checks:
+  - name: "postgres primary"
+    target: "10.0.0.10"
+    port: 5432
+    timeout: 3s
+    interval: 10s
+`,
+		`
+http:
+  host: "127.0.0.1"
+  port: 2000
+command-checks:
+  - generate-name: true
+    command: "true"
+    timeout: 3s
+    interval: 10s
 `,
 	}
 	for _, c := range cases {
@@ -0,0 +1,139 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// CheckDefaults the default values of the healthchecks parameters
+type CheckDefaults struct {
+	Interval   healthcheck.Duration
+	Timeout    healthcheck.Duration
+	MaxRetries uint                 `yaml:"max-retries"`
+	RetryDelay healthcheck.Duration `yaml:"retry-delay"`
+	Labels     map[string]string
+	// Spread spreads the executions of the healthchecks without cron
+	// and jitter
+	Spread bool
+}
+
+// Defaults the default values applied to the healthchecks which do not
+// override them. The values defined for a healthcheck type take
+// precedence over the values defined for all the healthchecks.
+type Defaults struct {
+	CheckDefaults `yaml:",inline"`
+	Command       CheckDefaults
+	DNS           CheckDefaults
+	TCP           CheckDefaults
+	HTTP          CheckDefaults
+	TLS           CheckDefaults
+	GRPC          CheckDefaults
+	Profile       CheckDefaults
+}
+
+// merge returns the defaults of a healthcheck type
+func (d *Defaults) merge(typeDefaults CheckDefaults) CheckDefaults {
+	result := typeDefaults
+	if result.Interval == 0 {
+		result.Interval = d.Interval
+	}
+	if result.Timeout == 0 {
+		result.Timeout = d.Timeout
+	}
+	if result.MaxRetries == 0 {
+		result.MaxRetries = d.MaxRetries
+	}
+	if result.RetryDelay == 0 {
+		result.RetryDelay = d.RetryDelay
+	}
+	if !result.Spread {
+		result.Spread = d.Spread
+	}
+	if len(d.Labels) != 0 {
+		result.Labels = make(map[string]string)
+		for k, v := range d.Labels {
+			result.Labels[k] = v
+		}
+		for k, v := range typeDefaults.Labels {
+			result.Labels[k] = v
+		}
+	}
+	return result
+}
+
+// apply applies the defaults to a healthcheck
+func (d *CheckDefaults) apply(base *healthcheck.Base, timeout *healthcheck.Duration) {
+	if base.Interval == 0 && base.Cron == nil && !base.OneOff {
+		base.Interval = d.Interval
+	}
+	if *timeout == 0 {
+		*timeout = d.Timeout
+	}
+	if base.MaxRetries == 0 {
+		base.MaxRetries = d.MaxRetries
+	}
+	if base.RetryDelay == 0 {
+		base.RetryDelay = d.RetryDelay
+	}
+	if d.Spread && base.Cron == nil && base.Jitter.Max(time.Duration(base.Interval)) == 0 {
+		base.Spread = true
+	}
+	if len(d.Labels) != 0 {
+		labels := make(map[string]string)
+		for k, v := range d.Labels {
+			labels[k] = v
+		}
+		for k, v := range base.Labels {
+			labels[k] = v
+		}
+		base.Labels = labels
+	}
+}
+
+// apply applies the defaults to the healthchecks
+func (d *Defaults) apply(checks *includedConfiguration) {
+	command := d.merge(d.Command)
+	for i := range checks.CommandChecks {
+		check := &checks.CommandChecks[i]
+		command.apply(&check.Base, &check.Timeout)
+	}
+	dns := d.merge(d.DNS)
+	for i := range checks.DNSChecks {
+		check := &checks.DNSChecks[i]
+		dns.apply(&check.Base, &check.Timeout)
+	}
+	tcp := d.merge(d.TCP)
+	for i := range checks.TCPChecks {
+		check := &checks.TCPChecks[i]
+		tcp.apply(&check.Base, &check.Timeout)
+	}
+	http := d.merge(d.HTTP)
+	for i := range checks.HTTPChecks {
+		check := &checks.HTTPChecks[i]
+		http.apply(&check.Base, &check.Timeout)
+	}
+	tls := d.merge(d.TLS)
+	for i := range checks.TLSChecks {
+		check := &checks.TLSChecks[i]
+		tls.apply(&check.Base, &check.Timeout)
+	}
+	grpc := d.merge(d.GRPC)
+	for i := range checks.GRPCChecks {
+		check := &checks.GRPCChecks[i]
+		grpc.apply(&check.Base, &check.Timeout)
+	}
+	profile := d.merge(d.Profile)
+	for i := range checks.ProfileChecks {
+		check := &checks.ProfileChecks[i]
+		profile.apply(&check.Base, &check.Timeout)
+	}
+	// the heartbeat healthchecks have no timeout and are not retried
+	heartbeat := d.CheckDefaults
+	heartbeat.MaxRetries = 0
+	heartbeat.RetryDelay = 0
+	for i := range checks.HeartbeatChecks {
+		var timeout healthcheck.Duration
+		heartbeat.apply(&checks.HeartbeatChecks[i].Base, &timeout)
+	}
+}
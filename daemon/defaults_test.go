@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestDefaults(t *testing.T) {
+	in := `
+http:
+  host: "127.0.0.1"
+  port: 2000
+defaults:
+  interval: 10s
+  timeout: 2s
+  spread: true
+  labels:
+    env: "prod"
+  http:
+    timeout: 5s
+    max-retries: 2
+    labels:
+      team: "web"
+tcp-checks:
+  - name: "tcp"
+    target: "127.0.0.1"
+    port: 22
+  - name: "tcp-override"
+    target: "127.0.0.1"
+    port: 22
+    interval: 30s
+    timeout: 10s
+    jitter: 1s
+    labels:
+      env: "staging"
+http-checks:
+  - name: "http"
+    target: "127.0.0.1"
+    port: 80
+    valid-status: [200]
+`
+	var config Configuration
+	err := yaml.Unmarshal([]byte(in), &config)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %s", err.Error())
+	}
+	tcp := config.TCPChecks[0]
+	if tcp.Base.Interval != healthcheck.Duration(10*time.Second) || tcp.Timeout != healthcheck.Duration(2*time.Second) || !tcp.Base.Spread {
+		t.Fatalf("Invalid defaults %v", tcp)
+	}
+	if !reflect.DeepEqual(tcp.Base.Labels, map[string]string{"env": "prod"}) {
+		t.Fatalf("Invalid labels %v", tcp.Base.Labels)
+	}
+	override := config.TCPChecks[1]
+	if override.Base.Interval != healthcheck.Duration(30*time.Second) || override.Timeout != healthcheck.Duration(10*time.Second) || override.Base.Spread {
+		t.Fatalf("The defaults should not override the check %v", override)
+	}
+	if !reflect.DeepEqual(override.Base.Labels, map[string]string{"env": "staging"}) {
+		t.Fatalf("Invalid labels %v", override.Base.Labels)
+	}
+	http := config.HTTPChecks[0]
+	if http.Base.Interval != healthcheck.Duration(10*time.Second) || http.Timeout != healthcheck.Duration(5*time.Second) || http.Base.MaxRetries != 2 {
+		t.Fatalf("Invalid defaults %v", http)
+	}
+	if !reflect.DeepEqual(http.Base.Labels, map[string]string{"env": "prod", "team": "web"}) {
+		t.Fatalf("Invalid labels %v", http.Base.Labels)
+	}
+}
+
+func TestDefaultsInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.yaml"), `
+http:
+  host: "127.0.0.1"
+  port: 2000
+include:
+  - checks.yaml
+defaults:
+  interval: 10s
+  timeout: 2s
+`)
+	writeFile(t, filepath.Join(dir, "checks.yaml"), `
+tcp-checks:
+  - name: "tcp"
+    target: "127.0.0.1"
+    port: 22
+`)
+	config, err := LoadConfiguration(filepath.Join(dir, "config.yaml"), false)
+	if err != nil {
+		t.Fatalf("Fail to load the configuration: %s", err.Error())
+	}
+	if config.TCPChecks[0].Timeout != healthcheck.Duration(2*time.Second) {
+		t.Fatalf("Invalid defaults %v", config.TCPChecks[0])
+	}
+	errs := ValidateConfiguration(filepath.Join(dir, "config.yaml"), false)
+	if len(errs) != 0 {
+		t.Fatalf("Invalid configuration: %v", errs)
+	}
+}
@@ -0,0 +1,74 @@
+package daemon
+
+import (
+	"reflect"
+	"sort"
+)
+
+// configDiff the differences between the healthchecks of two configurations
+type configDiff struct {
+	Added    []string
+	Removed  []string
+	Modified []string
+}
+
+// Empty returns true if there is no difference
+func (d *configDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// configChecks returns the healthchecks configurations indexed by
+// identifier
+func configChecks(config *Configuration) map[string]interface{} {
+	result := make(map[string]interface{})
+	for i := range config.CommandChecks {
+		result[config.CommandChecks[i].Base.ID()] = &config.CommandChecks[i]
+	}
+	for i := range config.DNSChecks {
+		result[config.DNSChecks[i].Base.ID()] = &config.DNSChecks[i]
+	}
+	for i := range config.TCPChecks {
+		result[config.TCPChecks[i].Base.ID()] = &config.TCPChecks[i]
+	}
+	for i := range config.HTTPChecks {
+		result[config.HTTPChecks[i].Base.ID()] = &config.HTTPChecks[i]
+	}
+	for i := range config.TLSChecks {
+		result[config.TLSChecks[i].Base.ID()] = &config.TLSChecks[i]
+	}
+	for i := range config.HeartbeatChecks {
+		result[config.HeartbeatChecks[i].Base.ID()] = &config.HeartbeatChecks[i]
+	}
+	for i := range config.GRPCChecks {
+		result[config.GRPCChecks[i].Base.ID()] = &config.GRPCChecks[i]
+	}
+	for i := range config.ProfileChecks {
+		result[config.ProfileChecks[i].Base.ID()] = &config.ProfileChecks[i]
+	}
+	return result
+}
+
+// diffChecks computes the differences between the healthchecks of two
+// configurations
+func diffChecks(oldConfig *Configuration, newConfig *Configuration) configDiff {
+	diff := configDiff{}
+	oldChecks := configChecks(oldConfig)
+	newChecks := configChecks(newConfig)
+	for name, newCheck := range newChecks {
+		oldCheck, ok := oldChecks[name]
+		if !ok {
+			diff.Added = append(diff.Added, name)
+		} else if !reflect.DeepEqual(oldCheck, newCheck) {
+			diff.Modified = append(diff.Modified, name)
+		}
+	}
+	for name := range oldChecks {
+		if _, ok := newChecks[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Modified)
+	return diff
+}
@@ -0,0 +1,114 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestDiffChecks(t *testing.T) {
+	tcpCheck := func(name string, port uint) healthcheck.TCPHealthcheckConfiguration {
+		return healthcheck.TCPHealthcheckConfiguration{
+			Base: healthcheck.Base{
+				Name:     name,
+				Interval: healthcheck.Duration(10 * time.Second),
+			},
+			Target:  "127.0.0.1",
+			Port:    port,
+			Timeout: healthcheck.Duration(time.Second),
+		}
+	}
+	oldConfig := &Configuration{
+		TCPChecks: []healthcheck.TCPHealthcheckConfiguration{
+			tcpCheck("kept", 80),
+			tcpCheck("modified", 80),
+			tcpCheck("removed", 80),
+		},
+	}
+	newConfig := &Configuration{
+		TCPChecks: []healthcheck.TCPHealthcheckConfiguration{
+			tcpCheck("kept", 80),
+			tcpCheck("modified", 443),
+			tcpCheck("added", 80),
+		},
+	}
+	diff := diffChecks(oldConfig, newConfig)
+	expected := configDiff{
+		Added:    []string{"added"},
+		Removed:  []string{"removed"},
+		Modified: []string{"modified"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("Invalid diff: %+v", diff)
+	}
+	diff = diffChecks(newConfig, newConfig)
+	if !diff.Empty() {
+		t.Fatalf("The diff should be empty: %+v", diff)
+	}
+}
+
+func TestConfigDrift(t *testing.T) {
+	config := &Configuration{
+		TCPChecks: []healthcheck.TCPHealthcheckConfiguration{
+			{
+				Base: healthcheck.Base{
+					Name:     "same",
+					Interval: healthcheck.Duration(10 * time.Second),
+				},
+				Target:  "127.0.0.1",
+				Port:    80,
+				Timeout: healthcheck.Duration(time.Second),
+			},
+			{
+				Base: healthcheck.Base{
+					Name:     "changed",
+					Interval: healthcheck.Duration(10 * time.Second),
+				},
+				Target:  "127.0.0.1",
+				Port:    443,
+				Timeout: healthcheck.Duration(time.Second),
+			},
+			{
+				Base: healthcheck.Base{
+					Name:     "added",
+					Interval: healthcheck.Duration(10 * time.Second),
+				},
+				Target:  "127.0.0.1",
+				Port:    80,
+				Timeout: healthcheck.Duration(time.Second),
+			},
+		},
+	}
+	var nodeChecks []map[string]interface{}
+	for i := range config.TCPChecks[:2] {
+		fields, err := checkFields(&config.TCPChecks[i])
+		if err != nil {
+			t.Fatalf("Fail to convert the healthcheck: %s", err.Error())
+		}
+		nodeChecks = append(nodeChecks, fields)
+	}
+	nodeChecks[1]["port"] = float64(8443)
+	nodeChecks = append(nodeChecks, map[string]interface{}{"name": "api-check", "source": "api"})
+
+	drift, err := ConfigDrift(config, "", nodeChecks)
+	if err != nil {
+		t.Fatalf("Fail to compute the drift: %s", err.Error())
+	}
+	expected := Drift{
+		Added:   []DriftCheck{{ID: "added"}},
+		Removed: []DriftCheck{{ID: "api-check", Source: "api"}},
+		Changed: []DriftCheck{{ID: "changed", Fields: []DriftField{{Field: "port", Config: float64(443), Node: float64(8443)}}}},
+	}
+	if !reflect.DeepEqual(drift, expected) {
+		t.Fatalf("Invalid drift: %+v", drift)
+	}
+	drift, err = ConfigDrift(config, "other", nil)
+	if err != nil {
+		t.Fatalf("Fail to compute the drift: %s", err.Error())
+	}
+	if !drift.Empty() {
+		t.Fatalf("The drift should be empty: %+v", drift)
+	}
+}
@@ -0,0 +1,141 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// DriftField a field whose value differs between the configuration and
+// the node
+type DriftField struct {
+	Field  string      `json:"field"`
+	Config interface{} `json:"config"`
+	Node   interface{} `json:"node"`
+}
+
+// DriftCheck an healthcheck differing between the configuration and the
+// node
+type DriftCheck struct {
+	ID     string       `json:"id"`
+	Source string       `json:"source,omitempty"`
+	Fields []DriftField `json:"fields,omitempty"`
+}
+
+// Drift the differences between the healthchecks of a configuration and
+// the healthchecks registered on a node. Added are the healthchecks of
+// the configuration missing on the node, Removed the healthchecks of the
+// node (whatever their source) missing in the configuration.
+type Drift struct {
+	Added   []DriftCheck `json:"added"`
+	Removed []DriftCheck `json:"removed"`
+	Changed []DriftCheck `json:"changed"`
+}
+
+// Empty returns true if there is no difference
+func (d *Drift) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// checkFields converts an healthcheck to a map of fields, using its JSON
+// representation
+func checkFields(check interface{}) (map[string]interface{}, error) {
+	content, err := json.Marshal(check)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to convert the healthcheck to json")
+	}
+	var result map[string]interface{}
+	err = json.Unmarshal(content, &result)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to convert the healthcheck from json")
+	}
+	return result, nil
+}
+
+// fieldString returns a field of an healthcheck as a string
+func fieldString(check map[string]interface{}, field string) string {
+	value, ok := check[field]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// diffFields returns the fields whose values differ, sorted by name
+func diffFields(config map[string]interface{}, node map[string]interface{}) []DriftField {
+	var result []DriftField
+	for field, value := range config {
+		if !reflect.DeepEqual(value, node[field]) {
+			result = append(result, DriftField{Field: field, Config: value, Node: node[field]})
+		}
+	}
+	for field, value := range node {
+		if _, ok := config[field]; !ok && value != nil {
+			result = append(result, DriftField{Field: field, Node: value})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Field < result[j].Field
+	})
+	return result
+}
+
+// ConfigDrift compares the healthchecks of a configuration with the
+// healthchecks registered on a node, as returned by its API. If namespace
+// is not empty, only the healthchecks of this namespace are compared.
+func ConfigDrift(config *Configuration, namespace string, nodeChecks []map[string]interface{}) (Drift, error) {
+	drift := Drift{
+		Added:   []DriftCheck{},
+		Removed: []DriftCheck{},
+		Changed: []DriftCheck{},
+	}
+	expected := make(map[string]map[string]interface{})
+	for id, check := range configChecks(config) {
+		// the validation sets the default values, as the node does when
+		// registering the healthchecks
+		if v, ok := check.(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return drift, errors.Wrapf(err, "Invalid healthcheck %s", id)
+			}
+		}
+		fields, err := checkFields(check)
+		if err != nil {
+			return drift, errors.Wrapf(err, "Invalid healthcheck %s", id)
+		}
+		if namespace != "" && fieldString(fields, "namespace") != namespace {
+			continue
+		}
+		expected[id] = fields
+	}
+	node := make(map[string]map[string]interface{})
+	for _, check := range nodeChecks {
+		node[healthcheck.ID(fieldString(check, "namespace"), fieldString(check, "name"))] = check
+	}
+	for id, configCheck := range expected {
+		nodeCheck, ok := node[id]
+		if !ok {
+			drift.Added = append(drift.Added, DriftCheck{ID: id})
+			continue
+		}
+		fields := diffFields(configCheck, nodeCheck)
+		if len(fields) != 0 {
+			drift.Changed = append(drift.Changed, DriftCheck{ID: id, Source: fieldString(nodeCheck, "source"), Fields: fields})
+		}
+	}
+	for id, nodeCheck := range node {
+		if _, ok := expected[id]; !ok {
+			drift.Removed = append(drift.Removed, DriftCheck{ID: id, Source: fieldString(nodeCheck, "source")})
+		}
+	}
+	for _, checks := range [][]DriftCheck{drift.Added, drift.Removed, drift.Changed} {
+		sort.Slice(checks, func(i, j int) bool {
+			return checks[i].ID < checks[j].ID
+		})
+	}
+	return drift, nil
+}
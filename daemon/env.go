@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ExpandEnv replaces the environment variables references in a
+// configuration:
+//   - ${VAR} is replaced by the value of VAR, or by an empty string
+//   - ${VAR:-default} is replaced by default if VAR is unset or empty
+//   - ${VAR:?message} fails with message if VAR is unset or empty
+//   - $$ is replaced by $
+func ExpandEnv(content []byte) ([]byte, error) {
+	input := string(content)
+	var result strings.Builder
+	var missing []string
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		if c != '$' || i+1 >= len(input) {
+			result.WriteByte(c)
+			continue
+		}
+		if input[i+1] == '$' {
+			result.WriteByte('$')
+			i++
+			continue
+		}
+		if input[i+1] != '{' {
+			result.WriteByte(c)
+			continue
+		}
+		end := strings.IndexByte(input[i+2:], '}')
+		if end == -1 {
+			return nil, errors.New("Unclosed environment variable reference in the configuration")
+		}
+		expr := input[i+2 : i+2+end]
+		i = i + 2 + end
+		name := expr
+		operator := ""
+		argument := ""
+		if j := strings.Index(expr, ":"); j != -1 && j+1 < len(expr) && (expr[j+1] == '-' || expr[j+1] == '?') {
+			name = expr[:j]
+			operator = expr[j : j+2]
+			argument = expr[j+2:]
+		}
+		if name == "" {
+			return nil, fmt.Errorf("Invalid environment variable reference ${%s} in the configuration", expr)
+		}
+		value := os.Getenv(name)
+		if value == "" {
+			switch operator {
+			case ":-":
+				value = argument
+			case ":?":
+				message := argument
+				if message == "" {
+					message = "required variable is not set"
+				}
+				missing = append(missing, fmt.Sprintf("%s: %s", name, message))
+			}
+		}
+		result.WriteString(value)
+	}
+	if len(missing) != 0 {
+		return nil, fmt.Errorf("Missing environment variables in the configuration: %s", strings.Join(missing, ", "))
+	}
+	return []byte(result.String()), nil
+}
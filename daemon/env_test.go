@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("CABOUROTTE_HOST", "10.0.0.1")
+	t.Setenv("CABOUROTTE_EMPTY", "")
+	cases := []struct {
+		input    string
+		expected string
+		err      bool
+	}{
+		{input: "host: ${CABOUROTTE_HOST}", expected: "host: 10.0.0.1"},
+		{input: "host: ${CABOUROTTE_UNSET}", expected: "host: "},
+		{input: "host: ${CABOUROTTE_UNSET:-127.0.0.1}", expected: "host: 127.0.0.1"},
+		{input: "host: ${CABOUROTTE_EMPTY:-127.0.0.1}", expected: "host: 127.0.0.1"},
+		{input: "host: ${CABOUROTTE_HOST:-127.0.0.1}", expected: "host: 10.0.0.1"},
+		{input: "host: ${CABOUROTTE_HOST:?the host is required}", expected: "host: 10.0.0.1"},
+		{input: "host: ${CABOUROTTE_UNSET:?the host is required}", err: true},
+		{input: "regexp: foo$", expected: "regexp: foo$"},
+		{input: "password: a$$b", expected: "password: a$b"},
+		{input: "host: ${CABOUROTTE_HOST", err: true},
+		{input: "host: ${}", err: true},
+	}
+	for _, c := range cases {
+		result, err := ExpandEnv([]byte(c.input))
+		if c.err {
+			if err == nil {
+				t.Fatalf("Was expecting an error for %s", c.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error for %s\n%v", c.input, err)
+		}
+		if string(result) != c.expected {
+			t.Fatalf("Invalid result for %s: %s", c.input, string(result))
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExample(t *testing.T) {
+	for _, kind := range append([]string{""}, ExampleTypes...) {
+		example, err := Example(kind)
+		if err != nil {
+			t.Fatalf("Fail to generate the example %s: %s", kind, err.Error())
+		}
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		writeFile(t, path, example)
+		_, err = LoadConfiguration(path, true)
+		if err != nil {
+			t.Fatalf("Invalid example %s: %s", kind, err.Error())
+		}
+		errs := ValidateConfiguration(path, true)
+		if len(errs) != 0 {
+			t.Fatalf("Invalid example %s: %v", kind, errs)
+		}
+	}
+	_, err := Example("invalid")
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
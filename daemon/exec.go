@@ -0,0 +1,69 @@
+package daemon
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// configHealthchecks builds the enabled healthchecks of a configuration
+func configHealthchecks(logger *zap.Logger, config *Configuration) []healthcheck.Healthcheck {
+	var checks []healthcheck.Healthcheck
+	for i := range config.CommandChecks {
+		checks = append(checks, healthcheck.NewCommandHealthcheck(logger, &config.CommandChecks[i]))
+	}
+	for i := range config.DNSChecks {
+		checks = append(checks, healthcheck.NewDNSHealthcheck(logger, &config.DNSChecks[i]))
+	}
+	for i := range config.TCPChecks {
+		checks = append(checks, healthcheck.NewTCPHealthcheck(logger, &config.TCPChecks[i]))
+	}
+	for i := range config.HTTPChecks {
+		checks = append(checks, healthcheck.NewHTTPHealthcheck(logger, &config.HTTPChecks[i]))
+	}
+	for i := range config.TLSChecks {
+		checks = append(checks, healthcheck.NewTLSHealthcheck(logger, &config.TLSChecks[i]))
+	}
+	for i := range config.GRPCChecks {
+		checks = append(checks, healthcheck.NewGRPCHealthcheck(logger, &config.GRPCChecks[i]))
+	}
+	for i := range config.ProfileChecks {
+		checks = append(checks, healthcheck.NewProfileHealthcheck(logger, &config.ProfileChecks[i]))
+	}
+	result := make([]healthcheck.Healthcheck, 0, len(checks))
+	for _, check := range checks {
+		base := check.Base()
+		if base.IsEnabled() {
+			result = append(result, check)
+		}
+	}
+	return result
+}
+
+// Exec executes once all the healthchecks of a configuration concurrently,
+// and returns their results sorted by healthcheck identifier
+func Exec(ctx context.Context, logger *zap.Logger, config *Configuration) []*healthcheck.Result {
+	checks := configHealthchecks(logger, config)
+	results := make([]*healthcheck.Result, len(checks))
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := config.Credentials.Resolve(checks[i]); err != nil {
+				results[i] = healthcheck.NewResult(checks[i], 0, err)
+				return
+			}
+			results[i] = healthcheck.ExecuteOnce(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID() < results[j].ID()
+	})
+	return results
+}
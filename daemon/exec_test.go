@@ -0,0 +1,56 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestExec(t *testing.T) {
+	disabled := false
+	config := &Configuration{
+		CommandChecks: []healthcheck.CommandHealthcheckConfiguration{
+			{
+				Base: healthcheck.Base{
+					Name:     "success",
+					Interval: healthcheck.Duration(time.Second * 10),
+				},
+				Command: "ls",
+				Timeout: healthcheck.Duration(time.Second * 2),
+			},
+			{
+				Base: healthcheck.Base{
+					Name:       "failure",
+					Interval:   healthcheck.Duration(time.Second * 10),
+					MaxRetries: 1,
+				},
+				Command:   "ls",
+				Arguments: []string{"/doesnotexist"},
+				Timeout:   healthcheck.Duration(time.Second * 2),
+			},
+			{
+				Base: healthcheck.Base{
+					Name:     "disabled",
+					Interval: healthcheck.Duration(time.Second * 10),
+					Enabled:  &disabled,
+				},
+				Command: "ls",
+				Timeout: healthcheck.Duration(time.Second * 2),
+			},
+		},
+	}
+	results := Exec(context.Background(), zap.NewExample(), config)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Name != "failure" || results[0].Success {
+		t.Fatalf("Invalid result for the failure healthcheck: %+v", results[0])
+	}
+	if results[1].Name != "success" || !results[1].Success {
+		t.Fatalf("Invalid result for the success healthcheck: %+v", results[1])
+	}
+}
@@ -0,0 +1,392 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/exporter"
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/secret"
+)
+
+// includedConfiguration the configuration which can be defined in the
+// included files
+type includedConfiguration struct {
+	CommandChecks   []healthcheck.CommandHealthcheckConfiguration   `yaml:"command-checks"`
+	DNSChecks       []healthcheck.DNSHealthcheckConfiguration       `yaml:"dns-checks"`
+	TCPChecks       []healthcheck.TCPHealthcheckConfiguration       `yaml:"tcp-checks"`
+	HTTPChecks      []healthcheck.HTTPHealthcheckConfiguration      `yaml:"http-checks"`
+	TLSChecks       []healthcheck.TLSHealthcheckConfiguration       `yaml:"tls-checks"`
+	HeartbeatChecks []healthcheck.HeartbeatHealthcheckConfiguration `yaml:"heartbeat-checks"`
+	GRPCChecks      []healthcheck.GRPCHealthcheckConfiguration      `yaml:"grpc-checks"`
+	ProfileChecks   []healthcheck.ProfileHealthcheckConfiguration   `yaml:"profile-checks"`
+	Exporters       exporter.Configuration
+	Templates       []Template
+	// targetsFiles the targets files of the expanded templates
+	targetsFiles []string
+}
+
+// readFile reads a configuration file, expands the environment variables
+// and resolves the secrets
+func readFile(path string, vault *secret.Vault) ([]byte, error) {
+	content, err := readLocalFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err = secret.ResolveVault(content, vault)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid configuration file %s", path)
+	}
+	return content, nil
+}
+
+// readLocalFile reads a configuration file, expands the environment
+// variables and resolves the secrets stored in files
+func readLocalFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the configuration file %s", path)
+	}
+	content, err = ExpandEnv(content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid configuration file %s", path)
+	}
+	content, err = secret.ResolveFiles(content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid configuration file %s", path)
+	}
+	return content, nil
+}
+
+// loadVault creates the Vault client from the Vault configuration of the
+// configuration file. It returns nil if Vault is not configured.
+func loadVault(path string) (*secret.Vault, error) {
+	content, err := readLocalFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Vault *secret.VaultConfiguration
+	}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the Vault configuration in %s", path)
+	}
+	if config.Vault == nil {
+		return nil, nil
+	}
+	return secret.NewVault(config.Vault)
+}
+
+// definitions tracks the file defining each healthcheck and exporter in
+// order to report conflicts
+type definitions struct {
+	checks    map[string]string
+	exporters map[string]string
+}
+
+func (d *definitions) check(name string, file string) error {
+	if previous, ok := d.checks[name]; ok {
+		return fmt.Errorf("The healthcheck %s is defined in %s and in %s", name, previous, file)
+	}
+	d.checks[name] = file
+	return nil
+}
+
+func (d *definitions) exporter(name string, file string) error {
+	if previous, ok := d.exporters[name]; ok {
+		return fmt.Errorf("The exporter %s is defined in %s and in %s", name, previous, file)
+	}
+	d.exporters[name] = file
+	return nil
+}
+
+// add registers the healthchecks and exporters of a file
+func (d *definitions) add(config *includedConfiguration, file string) error {
+	for _, check := range config.CommandChecks {
+		if err := d.check(check.Base.ID(), file); err != nil {
+			return err
+		}
+	}
+	for _, check := range config.DNSChecks {
+		if err := d.check(check.Base.ID(), file); err != nil {
+			return err
+		}
+	}
+	for _, check := range config.TCPChecks {
+		if err := d.check(check.Base.ID(), file); err != nil {
+			return err
+		}
+	}
+	for _, check := range config.HTTPChecks {
+		if err := d.check(check.Base.ID(), file); err != nil {
+			return err
+		}
+	}
+	for _, check := range config.TLSChecks {
+		if err := d.check(check.Base.ID(), file); err != nil {
+			return err
+		}
+	}
+	for _, check := range config.HeartbeatChecks {
+		if err := d.check(check.Base.ID(), file); err != nil {
+			return err
+		}
+	}
+	for _, check := range config.GRPCChecks {
+		if err := d.check(check.Base.ID(), file); err != nil {
+			return err
+		}
+	}
+	for _, check := range config.ProfileChecks {
+		if err := d.check(check.Base.ID(), file); err != nil {
+			return err
+		}
+	}
+	for _, e := range config.Exporters.HTTP {
+		if err := d.exporter(e.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, e := range config.Exporters.Riemann {
+		if err := d.exporter(e.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, e := range config.Exporters.Exec {
+		if err := d.exporter(e.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, e := range config.Exporters.Plugin {
+		if err := d.exporter(e.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, e := range config.Exporters.RemoteWrite {
+		if err := d.exporter(e.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, e := range config.Exporters.Zabbix {
+		if err := d.exporter(e.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, e := range config.Exporters.Cabourotte {
+		if err := d.exporter(e.Name, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate validates the healthchecks of an included file
+func (c *includedConfiguration) validate() error {
+	for i := range c.CommandChecks {
+		if err := healthcheck.GenerateName(&c.CommandChecks[i]); err != nil {
+			return err
+		}
+		if err := c.CommandChecks[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range c.DNSChecks {
+		if err := healthcheck.GenerateName(&c.DNSChecks[i]); err != nil {
+			return err
+		}
+		if err := c.DNSChecks[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range c.TCPChecks {
+		if err := healthcheck.GenerateName(&c.TCPChecks[i]); err != nil {
+			return err
+		}
+		if err := c.TCPChecks[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range c.HTTPChecks {
+		if err := healthcheck.GenerateName(&c.HTTPChecks[i]); err != nil {
+			return err
+		}
+		if err := c.HTTPChecks[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range c.TLSChecks {
+		if err := healthcheck.GenerateName(&c.TLSChecks[i]); err != nil {
+			return err
+		}
+		if err := c.TLSChecks[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range c.HeartbeatChecks {
+		if err := healthcheck.GenerateName(&c.HeartbeatChecks[i]); err != nil {
+			return err
+		}
+		if err := c.HeartbeatChecks[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range c.GRPCChecks {
+		if err := healthcheck.GenerateName(&c.GRPCChecks[i]); err != nil {
+			return err
+		}
+		if err := c.GRPCChecks[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range c.ProfileChecks {
+		if err := healthcheck.GenerateName(&c.ProfileChecks[i]); err != nil {
+			return err
+		}
+		if err := c.ProfileChecks[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// merge merges the healthchecks of another configuration
+func (c *includedConfiguration) merge(other *includedConfiguration) {
+	c.CommandChecks = append(c.CommandChecks, other.CommandChecks...)
+	c.DNSChecks = append(c.DNSChecks, other.DNSChecks...)
+	c.TCPChecks = append(c.TCPChecks, other.TCPChecks...)
+	c.HTTPChecks = append(c.HTTPChecks, other.HTTPChecks...)
+	c.TLSChecks = append(c.TLSChecks, other.TLSChecks...)
+	c.HeartbeatChecks = append(c.HeartbeatChecks, other.HeartbeatChecks...)
+	c.GRPCChecks = append(c.GRPCChecks, other.GRPCChecks...)
+	c.ProfileChecks = append(c.ProfileChecks, other.ProfileChecks...)
+	c.targetsFiles = append(c.targetsFiles, other.targetsFiles...)
+}
+
+// merge merges an included configuration into the main configuration
+func (configuration *Configuration) merge(included *includedConfiguration) {
+	configuration.CommandChecks = append(configuration.CommandChecks, included.CommandChecks...)
+	configuration.DNSChecks = append(configuration.DNSChecks, included.DNSChecks...)
+	configuration.TCPChecks = append(configuration.TCPChecks, included.TCPChecks...)
+	configuration.HTTPChecks = append(configuration.HTTPChecks, included.HTTPChecks...)
+	configuration.TLSChecks = append(configuration.TLSChecks, included.TLSChecks...)
+	configuration.HeartbeatChecks = append(configuration.HeartbeatChecks, included.HeartbeatChecks...)
+	configuration.GRPCChecks = append(configuration.GRPCChecks, included.GRPCChecks...)
+	configuration.ProfileChecks = append(configuration.ProfileChecks, included.ProfileChecks...)
+	configuration.targetsFiles = append(configuration.targetsFiles, included.targetsFiles...)
+	configuration.Exporters.HTTP = append(configuration.Exporters.HTTP, included.Exporters.HTTP...)
+	configuration.Exporters.Riemann = append(configuration.Exporters.Riemann, included.Exporters.Riemann...)
+	configuration.Exporters.Exec = append(configuration.Exporters.Exec, included.Exporters.Exec...)
+	configuration.Exporters.Plugin = append(configuration.Exporters.Plugin, included.Exporters.Plugin...)
+	configuration.Exporters.RemoteWrite = append(configuration.Exporters.RemoteWrite, included.Exporters.RemoteWrite...)
+	configuration.Exporters.Zabbix = append(configuration.Exporters.Zabbix, included.Exporters.Zabbix...)
+	configuration.Exporters.Cabourotte = append(configuration.Exporters.Cabourotte, included.Exporters.Cabourotte...)
+}
+
+// TargetsFiles returns the targets files of the templates, the
+// configuration being reloaded when they change
+func (configuration *Configuration) TargetsFiles() []string {
+	return configuration.targetsFiles
+}
+
+// unmarshalFunc returns the function used to parse the configuration.
+// The unknown fields are rejected in strict mode.
+func unmarshalFunc(strict bool) func([]byte, interface{}) error {
+	if strict {
+		return yaml.UnmarshalStrict
+	}
+	return yaml.Unmarshal
+}
+
+// LoadConfiguration loads the configuration file and the files it
+// includes. The include patterns are relative to the configuration file
+// directory. The unknown fields are rejected in strict mode.
+func LoadConfiguration(path string, strict bool) (*Configuration, error) {
+	vault, err := loadVault(path)
+	if err != nil {
+		return nil, err
+	}
+	return load(path, func(path string) ([]byte, error) {
+		return readFile(path, vault)
+	}, strict)
+}
+
+// load loads the configuration file and the files it includes, using read
+// to read the files
+func load(path string, read func(path string) ([]byte, error), strict bool) (*Configuration, error) {
+	content, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	var config Configuration
+	if err := unmarshalFunc(strict)(content, &config); err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the configuration file %s", path)
+	}
+	if strict {
+		// the templates were already expanded, the expansion is done
+		// again in order to reject the unknown fields
+		if _, err := expandTemplates(config.Templates, true); err != nil {
+			return nil, errors.Wrapf(err, "Invalid template configuration in %s", path)
+		}
+	}
+	defs := definitions{
+		checks:    make(map[string]string),
+		exporters: make(map[string]string),
+	}
+	err = defs.add(&includedConfiguration{
+		CommandChecks:   config.CommandChecks,
+		DNSChecks:       config.DNSChecks,
+		TCPChecks:       config.TCPChecks,
+		HTTPChecks:      config.HTTPChecks,
+		TLSChecks:       config.TLSChecks,
+		HeartbeatChecks: config.HeartbeatChecks,
+		GRPCChecks:      config.GRPCChecks,
+		ProfileChecks:   config.ProfileChecks,
+		Exporters:       config.Exporters,
+	}, path)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+	for _, pattern := range config.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid include pattern %s", pattern)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			content, err := read(file)
+			if err != nil {
+				return nil, err
+			}
+			var included includedConfiguration
+			if err := unmarshalFunc(strict)(content, &included); err != nil {
+				return nil, errors.Wrapf(err, "Fail to read the included file %s", file)
+			}
+			expanded, err := expandTemplates(included.Templates, strict)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Invalid template configuration in %s", file)
+			}
+			included.merge(expanded)
+			config.Defaults.apply(&included)
+			if err := included.validate(); err != nil {
+				return nil, errors.Wrapf(err, "Invalid healthcheck configuration in %s", file)
+			}
+			if err := defs.add(&included, file); err != nil {
+				return nil, err
+			}
+			config.merge(&included)
+		}
+	}
+	if len(config.Exporters.Cabourotte) != 0 && config.Node == nil {
+		return nil, errors.New("The node identity is required to forward the results with the cabourotte exporter")
+	}
+	return &config, nil
+}
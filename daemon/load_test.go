@@ -0,0 +1,225 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write %s: %s", path, err.Error())
+	}
+}
+
+func TestLoadConfigurationInclude(t *testing.T) {
+	dir := t.TempDir()
+	err := os.Mkdir(filepath.Join(dir, "conf.d"), 0700)
+	if err != nil {
+		t.Fatalf("Fail to create the directory: %s", err.Error())
+	}
+	writeFile(t, filepath.Join(dir, "config.yaml"), `
+http:
+  host: "127.0.0.1"
+  port: 2000
+include:
+  - conf.d/*.yaml
+tcp-checks:
+  - name: "main"
+    target: "127.0.0.1"
+    port: 9000
+    timeout: 3s
+    interval: 10s
+`)
+	writeFile(t, filepath.Join(dir, "conf.d", "b.yaml"), `
+http-checks:
+  - name: "team-b"
+    target: "127.0.0.1"
+    port: 9000
+    valid-status: [200]
+    timeout: 3s
+    interval: 10s
+exporters:
+  http:
+    - name: "exporter-b"
+      host: "127.0.0.1"
+      port: 9001
+      path: "/"
+`)
+	writeFile(t, filepath.Join(dir, "conf.d", "a.yaml"), `
+tcp-checks:
+  - name: "team-a"
+    target: "127.0.0.1"
+    port: 9000
+    timeout: 3s
+    interval: 10s
+`)
+	config, err := LoadConfiguration(filepath.Join(dir, "config.yaml"), false)
+	if err != nil {
+		t.Fatalf("Fail to load the configuration: %s", err.Error())
+	}
+	if len(config.TCPChecks) != 2 {
+		t.Fatalf("Invalid TCP checks: %v", config.TCPChecks)
+	}
+	if config.TCPChecks[0].Base.Name != "main" || config.TCPChecks[1].Base.Name != "team-a" {
+		t.Fatalf("Invalid TCP checks order: %v", config.TCPChecks)
+	}
+	if len(config.HTTPChecks) != 1 || config.HTTPChecks[0].Base.Name != "team-b" {
+		t.Fatalf("Invalid HTTP checks: %v", config.HTTPChecks)
+	}
+	if len(config.Exporters.HTTP) != 1 || config.Exporters.HTTP[0].Name != "exporter-b" {
+		t.Fatalf("Invalid exporters: %v", config.Exporters.HTTP)
+	}
+
+	writeFile(t, filepath.Join(dir, "conf.d", "c.yaml"), `
+command-checks:
+  - name: "team-a"
+    command: "ls"
+    timeout: 3s
+    interval: 10s
+`)
+	_, err = LoadConfiguration(filepath.Join(dir, "config.yaml"), false)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if !strings.Contains(err.Error(), "a.yaml") || !strings.Contains(err.Error(), "c.yaml") {
+		t.Fatalf("The error should contain the conflicting files: %s", err.Error())
+	}
+}
+
+func TestLoadConfigurationStrict(t *testing.T) {
+	cases := []struct {
+		main     string
+		included string
+	}{
+		{
+			main: `
+tcp-checks:
+  - name: "foo"
+    target: "127.0.0.1"
+    port: 22
+    timeout: 3s
+    interval: 10s
+    intreval: 20s
+`,
+		},
+		{
+			main: `
+exporter:
+  http: []
+`,
+		},
+		{
+			main: `
+templates:
+  - name: "foo"
+    targets: ["127.0.0.1"]
+    tcp-check:
+      target: "$target"
+      port: 22
+      timeout: 3s
+      interval: 10s
+      intreval: 20s
+`,
+		},
+		{
+			included: `
+tcp-checks:
+  - name: "foo"
+    target: "127.0.0.1"
+    port: 22
+    timeout: 3s
+    interval: 10s
+    intreval: 20s
+`,
+		},
+	}
+	for _, c := range cases {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.yaml")
+		writeFile(t, path, `
+http:
+  host: "127.0.0.1"
+  port: 2000
+include:
+  - included.yaml
+`+c.main)
+		writeFile(t, filepath.Join(dir, "included.yaml"), c.included)
+		_, err := LoadConfiguration(path, false)
+		if err != nil {
+			t.Fatalf("Fail to load the configuration: %s", err.Error())
+		}
+		_, err = LoadConfiguration(path, true)
+		if err == nil {
+			t.Fatalf("Was expecting an error in strict mode for\n%s%s", c.main, c.included)
+		}
+		errs := ValidateConfiguration(path, true)
+		if len(errs) == 0 {
+			t.Fatalf("Was expecting a validation error in strict mode for\n%s%s", c.main, c.included)
+		}
+	}
+}
+
+func TestLoadConfigurationIncludeCabourotteExporter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeFile(t, path, `
+http:
+  host: "127.0.0.1"
+  port: 2000
+node:
+  name: "edge-1"
+include:
+  - included.yaml
+exporters:
+  cabourotte:
+    - name: "regional"
+      host: "127.0.0.1"
+      port: 9013
+`)
+	writeFile(t, filepath.Join(dir, "included.yaml"), `
+exporters:
+  cabourotte:
+    - name: "global"
+      host: "127.0.0.2"
+      port: 9013
+`)
+	config, err := LoadConfiguration(path, true)
+	if err != nil {
+		t.Fatalf("Fail to load the configuration: %s", err.Error())
+	}
+	if len(config.Exporters.Cabourotte) != 2 || config.Exporters.Cabourotte[1].Name != "global" {
+		t.Fatalf("Invalid cabourotte exporters: %v", config.Exporters.Cabourotte)
+	}
+	errs := ValidateConfiguration(path, true)
+	if len(errs) != 0 {
+		t.Fatalf("Invalid configuration: %v", errs)
+	}
+
+	writeFile(t, filepath.Join(dir, "included.yaml"), `
+exporters:
+  cabourotte:
+    - name: "regional"
+      host: "127.0.0.2"
+      port: 9013
+`)
+	_, err = LoadConfiguration(path, true)
+	if err == nil || !strings.Contains(err.Error(), "included.yaml") {
+		t.Fatalf("Was expecting a duplicated exporter error, got %v", err)
+	}
+
+	// the node identity is required by the included exporters
+	writeFile(t, path, `
+http:
+  host: "127.0.0.1"
+  port: 2000
+include:
+  - included.yaml
+`)
+	_, err = LoadConfiguration(path, true)
+	if err == nil {
+		t.Fatalf("Was expecting an error without node identity")
+	}
+}
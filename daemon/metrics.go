@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+// reloadMetrics the configuration reloads metrics
+type reloadMetrics struct {
+	successGauge   prom.Gauge
+	timestampGauge prom.Gauge
+}
+
+// newReloadMetrics creates and registers the configuration reloads
+// metrics. The startup counts as a successful reload.
+func newReloadMetrics(promComponent *prometheus.Prometheus) (*reloadMetrics, error) {
+	metrics := &reloadMetrics{
+		successGauge: prom.NewGauge(prom.GaugeOpts{
+			Name: "config_last_reload_successful",
+			Help: "1 if the last configuration reload was successful, 0 otherwise.",
+		}),
+		timestampGauge: prom.NewGauge(prom.GaugeOpts{
+			Name: "config_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the last successful configuration reload.",
+		}),
+	}
+	err := promComponent.Register(metrics.successGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the reload Prometheus gauge")
+	}
+	err = promComponent.Register(metrics.timestampGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the reload timestamp Prometheus gauge")
+	}
+	metrics.record(nil, time.Now())
+	return metrics, nil
+}
+
+// record records the status of a reload
+func (m *reloadMetrics) record(err error, now time.Time) {
+	if err != nil {
+		m.successGauge.Set(0)
+		return
+	}
+	m.successGauge.Set(1)
+	m.timestampGauge.Set(float64(now.Unix()))
+}
@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+// gaugeValue returns the value of a gauge without labels
+func gaugeValue(t *testing.T, prom *prometheus.Prometheus, name string) float64 {
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics: %s", err.Error())
+	}
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("The metric %s was not found", name)
+	return 0
+}
+
+func TestReloadMetrics(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Fail to create the prometheus component: %s", err.Error())
+	}
+	metrics, err := newReloadMetrics(prom)
+	if err != nil {
+		t.Fatalf("Fail to create the metrics: %s", err.Error())
+	}
+	if gaugeValue(t, prom, "config_last_reload_successful") != 1 {
+		t.Fatalf("The startup should be a successful reload")
+	}
+	now := time.Unix(1000, 0)
+	metrics.record(nil, now)
+	if gaugeValue(t, prom, "config_last_reload_success_timestamp_seconds") != 1000 {
+		t.Fatalf("Invalid reload timestamp")
+	}
+	metrics.record(errors.New("invalid configuration"), now.Add(time.Minute))
+	if gaugeValue(t, prom, "config_last_reload_successful") != 0 {
+		t.Fatalf("The reload should be failed")
+	}
+	if gaugeValue(t, prom, "config_last_reload_success_timestamp_seconds") != 1000 {
+		t.Fatalf("The timestamp of the failed reload should not be recorded")
+	}
+}
+
+func TestNodeMetricsLabels(t *testing.T) {
+	node := &healthcheck.Node{Name: "prober-1", Zone: "eu-west-1a", Labels: map[string]string{"datacenter": "dc1"}}
+	prom, err := prometheus.NewWithLabels(node.MetricsLabels())
+	if err != nil {
+		t.Fatalf("Fail to create the prometheus component: %s", err.Error())
+	}
+	metrics, err := newReloadMetrics(prom)
+	if err != nil {
+		t.Fatalf("Fail to create the metrics: %s", err.Error())
+	}
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics: %s", err.Error())
+	}
+	expected := map[string]string{"node": "prober-1", "node_zone": "eu-west-1a", "node_datacenter": "dc1"}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			for name, value := range expected {
+				if labels[name] != value {
+					t.Fatalf("Invalid labels %v for the metric %s", labels, family.GetName())
+				}
+			}
+		}
+	}
+	prom.Unregister(metrics.successGauge)
+	if err := prom.Register(metrics.successGauge); err != nil {
+		t.Fatalf("The metric should be unregistered: %s", err.Error())
+	}
+}
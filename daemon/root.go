@@ -1,18 +1,29 @@
 package daemon
 
 import (
+	"os"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
+	"github.com/appclacks/cabourotte/aggregator"
+	"github.com/appclacks/cabourotte/cluster"
 	"github.com/appclacks/cabourotte/discovery"
+	"github.com/appclacks/cabourotte/discovery/directory"
 	"github.com/appclacks/cabourotte/exporter"
+	"github.com/appclacks/cabourotte/grpcapi"
 	"github.com/appclacks/cabourotte/healthcheck"
 	"github.com/appclacks/cabourotte/http"
+	"github.com/appclacks/cabourotte/ingest"
+	"github.com/appclacks/cabourotte/maintenance"
 	"github.com/appclacks/cabourotte/memorystore"
 	"github.com/appclacks/cabourotte/prometheus"
+	"github.com/appclacks/cabourotte/resolver"
+	"github.com/appclacks/cabourotte/systemd"
+	"github.com/appclacks/cabourotte/tracing"
 )
 
 // Component is the component which will manage the HTTP server and the program
@@ -22,49 +33,134 @@ type Component struct {
 	MemoryStore *memorystore.MemoryStore
 	Logger      *zap.Logger
 	HTTP        *http.Component
+	GRPC        *grpcapi.Component
 	Healthcheck *healthcheck.Component
 	Exporter    *exporter.Component
 	Prometheus  *prometheus.Prometheus
 	Discovery   *discovery.Component
+	Directory   *directory.Directory
+	Cluster     *cluster.Component
+	Maintenance *maintenance.Component
+	Tracer      *tracing.Tracer
+	Aggregator  *aggregator.Aggregator
+	Ingester    *ingest.Ingester
 	lock        sync.RWMutex
+	reload      *reloadMetrics
+	watchdog    *watchdog
 	ChanResult  chan *healthcheck.Result
+	// broadcaster streams the results to the gRPC clients, registered
+	// in the exporter component when the gRPC server is first started
+	broadcaster *grpcapi.Broadcaster
 }
 
 // New creates and start a new daemon component
 func New(logger *zap.Logger, config *Configuration) (*Component, error) {
 	logger.Info("Starting the Cabourotte daemon")
-	prom, err := prometheus.New()
+	var metricsLabels map[string]string
+	if config.Node != nil {
+		metricsLabels = config.Node.MetricsLabels()
+	}
+	prom, err := prometheus.NewWithLabels(metricsLabels)
+	if err != nil {
+		return nil, err
+	}
+	prom.SetNativeHistograms(config.NativeHistograms)
+	reload, err := newReloadMetrics(prom)
 	if err != nil {
 		return nil, err
 	}
 	chanResult := make(chan *healthcheck.Result, config.ResultBuffer)
-	checkComponent, err := healthcheck.New(logger, chanResult, prom, config.MetricsLabels)
+	checkComponent, err := healthcheck.New(logger.Named("healthcheck"), chanResult, prom, config.MetricsLabels, config.Concurrency)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to create the healthcheck component")
 	}
-	memstore := memorystore.NewMemoryStore(logger)
+	var tracer *tracing.Tracer
+	if config.Tracing != nil {
+		tracer, err = tracing.New(logger.Named("tracing"), config.Tracing)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to create the tracer")
+		}
+		tracer.Start()
+		checkComponent.SetTracer(tracer)
+	}
+	if config.DNSCache != nil {
+		r, err := resolver.New(logger.Named("resolver"), config.DNSCache)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to create the DNS cache")
+		}
+		checkComponent.SetResolver(r)
+	}
+	checkComponent.SetNode(config.Node)
+	checkComponent.SetBandwidthLimit(config.HTTPBandwidthLimit)
+	checkComponent.SetTargetPolicy(config.TargetPolicy)
+	checkComponent.SetCommandPolicy(config.CommandPolicy)
+	checkComponent.SetProxy(config.Proxy)
+	checkComponent.SetCredentials(config.Credentials)
+	checkComponent.SetTargetRateLimit(config.TargetRateLimit)
+	memstore := memorystore.NewMemoryStore(logger, config.ResultHistory)
+	memstore.SetRegistered(func(id string) bool {
+		return checkComponent.GetCheck(id) != nil
+	})
+	memstore.SetHealthStaleness(time.Duration(config.HealthStaleness))
+	memstore.SetRetention(config.ResultRetention)
+	err = memstore.EnableMetrics(prom)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to enable the results store metrics")
+	}
+	if config.ResultPersistence != nil {
+		err = memstore.EnablePersistence(config.ResultPersistence)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to enable the results persistence")
+		}
+	}
 	memstore.Start()
+	maintenanceComponent := maintenance.New(logger)
 	err = checkComponent.Start()
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to start the healthcheck component")
 	}
-	http, err := http.New(logger, memstore, prom, &config.HTTP, checkComponent)
-	if err != nil {
-		return nil, errors.Wrapf(err, "Fail to create the HTTP server")
-	}
-	err = http.Start()
-	if err != nil {
-		return nil, errors.Wrapf(err, "Fail to start the HTTP server")
+	var aggregatorComponent *aggregator.Aggregator
+	if config.Aggregator != nil {
+		aggregatorComponent = aggregator.New(logger.Named("aggregator"), config.Aggregator)
+		aggregatorComponent.Start()
 	}
-	exporterComponent, err := exporter.New(logger, memstore, chanResult, prom, &config.Exporters)
+	exporterComponent, err := exporter.New(logger.Named("exporter"), memstore, maintenanceComponent, chanResult, prom, &config.Exporters)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to create the exporter component")
 	}
+	exporterComponent.SetNode(config.Node)
 	err = exporterComponent.Start()
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to start the exporter component")
 	}
-	discoveryComponent, err := discovery.New(logger, config.Discovery, prom, checkComponent)
+	http, err := http.New(logger.Named("http"), memstore, prom, &config.HTTP, checkComponent, maintenanceComponent)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to create the HTTP server")
+	}
+	http.SetAggregator(aggregatorComponent)
+	http.SetExporter(exporterComponent)
+	var ingester *ingest.Ingester
+	if config.Ingest != nil {
+		ingester = ingest.New(logger.Named("ingest"), config.Ingest, config.Node, chanResult)
+	}
+	http.SetIngester(ingester)
+	err = http.Start()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to start the HTTP server")
+	}
+	var clusterComponent *cluster.Component
+	if config.Cluster.Enabled() {
+		clusterComponent, err = cluster.New(logger.Named("cluster"), &config.Cluster, prom)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to create the cluster component")
+		}
+		err = clusterComponent.Start()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to start the cluster component")
+		}
+		checkComponent.SetOwnership(clusterComponent.Owns)
+	}
+	discoveryComponent, err := discovery.New(logger.Named("discovery"), config.Discovery, prom, checkComponent)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to create the service discovery component")
 	}
@@ -81,79 +177,282 @@ func New(logger *zap.Logger, config *Configuration) (*Component, error) {
 		Logger:      logger,
 		Exporter:    exporterComponent,
 		Discovery:   discoveryComponent,
+		Cluster:     clusterComponent,
 		Healthcheck: checkComponent,
+		Maintenance: maintenanceComponent,
+		Tracer:      tracer,
+		Aggregator:  aggregatorComponent,
+		Ingester:    ingester,
+		reload:      reload,
+	}
+	if config.GRPC != nil {
+		err = component.startGRPC(config.GRPC, config.HTTP.Tokens)
+		if err != nil {
+			return nil, err
+		}
 	}
 	err = component.ReloadHealthchecks(config)
 	if err != nil {
 		return nil, err
 	}
+	err = maintenanceComponent.ReloadConfiguration(config.Maintenance)
+	if err != nil {
+		return nil, err
+	}
+	if config.PersistenceFile != "" {
+		err = checkComponent.EnablePersistence(config.PersistenceFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to enable the healthchecks persistence")
+		}
+	}
+	if config.ChecksDirectory != "" {
+		component.Directory = directory.New(logger, config.ChecksDirectory, checkComponent)
+		err = component.Directory.Start()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to load the checks directory")
+		}
+	}
+	component.watchdog = newWatchdog(logger, checkComponent.Alive)
+	if component.watchdog != nil {
+		component.watchdog.start()
+	}
+	component.notify(systemd.StateReady)
 	return &component, nil
 }
 
+// startGRPC creates and starts the gRPC server, authenticating the
+// requests with the API tokens
+func (c *Component) startGRPC(config *grpcapi.Configuration, tokens []http.Token) error {
+	if c.broadcaster == nil {
+		broadcaster := grpcapi.NewBroadcaster()
+		err := c.Exporter.Register(broadcaster)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to register the gRPC results broadcaster")
+		}
+		c.broadcaster = broadcaster
+	}
+	grpcComponent, err := grpcapi.New(c.Logger.Named("grpc"), c.MemoryStore, config, c.Healthcheck, c.broadcaster, tokens)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to create the gRPC server")
+	}
+	err = grpcComponent.Start()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to start the gRPC server")
+	}
+	c.GRPC = grpcComponent
+	return nil
+}
+
+// shutdownEvent builds the event emitted when the daemon is shutting down
+func shutdownEvent() *healthcheck.Result {
+	labels := make(map[string]string)
+	hostname, err := os.Hostname()
+	if err == nil {
+		labels["host"] = hostname
+	}
+	return &healthcheck.Result{
+		Name:                 "cabourotte-shutdown",
+		Summary:              "Cabourotte node shutting down",
+		Labels:               labels,
+		Success:              true,
+		HealthcheckTimestamp: time.Now().Unix(),
+		Message:              "node shutting down",
+		Source:               "daemon",
+	}
+}
+
 // Stop stops the Cabourotte daemon
 func (c *Component) Stop() error {
 	c.Logger.Info("Stopping the Cabourotte daemon")
+	c.notify(systemd.StateStopping)
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	if c.watchdog != nil {
+		err := c.watchdog.stop()
+		if err != nil {
+			return errors.Wrapf(err, "Fail to stop the systemd watchdog")
+		}
+	}
 	err := c.Discovery.Stop()
 	if err != nil {
 		return errors.Wrapf(err, "Fail to stop the service discovery component")
 	}
+	if c.Directory != nil {
+		err = c.Directory.Stop()
+		if err != nil {
+			return errors.Wrapf(err, "Fail to stop the checks directory watcher")
+		}
+	}
 	err = c.HTTP.Stop()
 	if err != nil {
 		return errors.Wrapf(err, "Fail to stop the HTTP server")
 	}
-	err = c.Healthcheck.Stop()
+	if c.GRPC != nil {
+		err = c.GRPC.Stop()
+		if err != nil {
+			return errors.Wrapf(err, "Fail to stop the gRPC server")
+		}
+	}
+	if c.Cluster != nil {
+		err = c.Cluster.Stop()
+		if err != nil {
+			return errors.Wrapf(err, "Fail to stop the cluster component")
+		}
+	}
+	err = c.Healthcheck.Shutdown(time.Duration(c.Config.Shutdown.Timeout))
 	if err != nil {
 		return errors.Wrapf(err, "Fail to stop the healthcheck component")
 	}
+	if c.Config.Shutdown.Event {
+		c.ChanResult <- shutdownEvent()
+	}
 	close(c.ChanResult)
 	err = c.Exporter.Stop()
 	if err != nil {
 		return errors.Wrapf(err, "Fail to stop the exporter component")
 	}
+	if c.Aggregator != nil {
+		err = c.Aggregator.Stop()
+		if err != nil {
+			return errors.Wrapf(err, "Fail to stop the aggregator")
+		}
+	}
+	if c.Tracer != nil {
+		err = c.Tracer.Stop()
+		if err != nil {
+			return errors.Wrapf(err, "Fail to stop the tracer")
+		}
+	}
+	err = c.MemoryStore.Stop()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to stop the results store")
+	}
 	return nil
 }
 
 // ReloadHealthchecks reloads the healthchecks from a configuration
 func (c *Component) ReloadHealthchecks(daemonConfig *Configuration) error {
-	return c.Healthcheck.ReloadForSource(
+	err := c.Healthcheck.ReloadForSource(
 		healthcheck.SourceConfig,
 		nil,
 		daemonConfig.CommandChecks,
 		daemonConfig.DNSChecks,
 		daemonConfig.TCPChecks,
 		daemonConfig.HTTPChecks,
-		daemonConfig.TLSChecks)
+		daemonConfig.TLSChecks,
+		daemonConfig.HeartbeatChecks,
+		daemonConfig.GRPCChecks,
+		daemonConfig.ProfileChecks)
+	c.Healthcheck.RecordReconciliation(healthcheck.SourceConfig, err)
+	return err
 }
 
 // Reload reloads the Cabourotte daemon. This function will remove or keep
 // existing healthchecks depending of the new configuration. New checks will be added.
 // The HTTP server will also be reloaded if its configuration has changed.
 func (c *Component) Reload(daemonConfig *Configuration) error {
+	c.notify(systemd.StateReloading)
+	err := c.reloadConfiguration(daemonConfig)
+	c.notify(systemd.StateReady)
+	c.ReloadStatus(err)
+	return err
+}
+
+// ReloadStatus records the status of a configuration reload, including
+// the reloads failing before reaching the daemon (invalid configuration)
+func (c *Component) ReloadStatus(err error) {
+	c.reload.record(err, time.Now())
+}
+
+// reloadConfiguration applies a new configuration
+func (c *Component) reloadConfiguration(daemonConfig *Configuration) error {
 	c.Logger.Info("Reloading the Cabourotte daemon")
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	diff := diffChecks(c.Config, daemonConfig)
+	if diff.Empty() {
+		c.Logger.Info("Reload: no healthcheck modified")
+	} else {
+		c.Logger.Info("Reload: healthchecks modified",
+			zap.Strings("added", diff.Added),
+			zap.Strings("removed", diff.Removed),
+			zap.Strings("modified", diff.Modified))
+	}
+	c.Healthcheck.SetBandwidthLimit(daemonConfig.HTTPBandwidthLimit)
+	c.Healthcheck.SetTargetPolicy(daemonConfig.TargetPolicy)
+	c.Healthcheck.SetCommandPolicy(daemonConfig.CommandPolicy)
+	c.Healthcheck.SetProxy(daemonConfig.Proxy)
+	c.Healthcheck.SetCredentials(daemonConfig.Credentials)
+	c.Healthcheck.SetTargetRateLimit(daemonConfig.TargetRateLimit)
+	c.MemoryStore.SetHealthStaleness(time.Duration(daemonConfig.HealthStaleness))
+	c.MemoryStore.SetRetention(daemonConfig.ResultRetention)
 	err := c.ReloadHealthchecks(daemonConfig)
 	if err != nil {
 		return errors.Wrapf(err, "Fail to reload healthchecks")
 	}
+	err = c.Exporter.Reload(&daemonConfig.Exporters)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to reload exporters")
+	}
+	err = c.Maintenance.ReloadConfiguration(daemonConfig.Maintenance)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to reload maintenance windows")
+	}
+	if c.Config.ChecksDirectory != daemonConfig.ChecksDirectory {
+		if c.Directory != nil {
+			err := c.Directory.Stop()
+			if err != nil {
+				return errors.Wrapf(err, "Fail to stop the checks directory watcher")
+			}
+			err = c.Directory.RemoveChecks()
+			if err != nil {
+				return errors.Wrapf(err, "Fail to remove the checks of the previous checks directory")
+			}
+			c.Directory = nil
+		}
+		if daemonConfig.ChecksDirectory != "" {
+			dir := directory.New(c.Logger, daemonConfig.ChecksDirectory, c.Healthcheck)
+			err := dir.Start()
+			if err != nil {
+				return errors.Wrapf(err, "Fail to load the checks directory")
+			}
+			c.Directory = dir
+		}
+	}
 	// compare the server config to see if we need to recreate it
 	if !reflect.DeepEqual(c.Config.HTTP, daemonConfig.HTTP) {
 		err := c.HTTP.Stop()
 		if err != nil {
 			return errors.Wrapf(err, "Fail to stop the HTTP server")
 		}
-		http, err := http.New(c.Logger, c.MemoryStore, c.Prometheus, &daemonConfig.HTTP, c.Healthcheck)
+		http, err := http.New(c.Logger.Named("http"), c.MemoryStore, c.Prometheus, &daemonConfig.HTTP, c.Healthcheck, c.Maintenance)
 		if err != nil {
 			return errors.Wrapf(err, "Fail to create the HTTP server")
 		}
+		http.SetAggregator(c.Aggregator)
+		http.SetIngester(c.Ingester)
+		http.SetExporter(c.Exporter)
 		err = http.Start()
 		if err != nil {
 			return errors.Wrapf(err, "Fail to start the HTTP server")
 		}
 		c.HTTP = http
 	}
+	if !reflect.DeepEqual(c.Config.GRPC, daemonConfig.GRPC) || !reflect.DeepEqual(c.Config.HTTP.Tokens, daemonConfig.HTTP.Tokens) {
+		if c.GRPC != nil {
+			err := c.GRPC.Stop()
+			if err != nil {
+				return errors.Wrapf(err, "Fail to stop the gRPC server")
+			}
+			c.GRPC = nil
+		}
+		if daemonConfig.GRPC != nil {
+			err := c.startGRPC(daemonConfig.GRPC, daemonConfig.HTTP.Tokens)
+			if err != nil {
+				return err
+			}
+		}
+	}
 	c.Config = daemonConfig
 	c.Logger.Info("Reloaded")
 	return nil
@@ -7,6 +7,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/appclacks/cabourotte/grpcapi"
 	"github.com/appclacks/cabourotte/healthcheck"
 	"github.com/appclacks/cabourotte/http"
 )
@@ -201,3 +202,55 @@ func TestReload(t *testing.T) {
 		t.Fatalf("Fail to start the component\n%v", err)
 	}
 }
+
+func TestReloadGRPC(t *testing.T) {
+	httpConfig := http.Configuration{
+		Host: "127.0.0.1",
+		Port: 2002,
+	}
+	component, err := New(zap.NewExample(), &Configuration{HTTP: httpConfig})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	if component.GRPC != nil {
+		t.Fatalf("The gRPC server should not be started")
+	}
+	grpcConfig := &grpcapi.Configuration{Host: "127.0.0.1", Port: 2014}
+	err = component.Reload(&Configuration{HTTP: httpConfig, GRPC: grpcConfig})
+	if err != nil {
+		t.Fatalf("Fail to reload the component\n%v", err)
+	}
+	if component.GRPC == nil {
+		t.Fatalf("The gRPC server was not started")
+	}
+	if _, ok := component.Exporter.Exporters[grpcapi.BroadcasterName]; !ok {
+		t.Fatalf("The gRPC results broadcaster was not registered")
+	}
+	err = component.Reload(&Configuration{HTTP: httpConfig})
+	if err != nil {
+		t.Fatalf("Fail to reload the component\n%v", err)
+	}
+	if component.GRPC != nil {
+		t.Fatalf("The gRPC server was not stopped")
+	}
+	// the broadcaster is registered once
+	err = component.Reload(&Configuration{HTTP: httpConfig, GRPC: grpcConfig})
+	if err != nil {
+		t.Fatalf("Fail to reload the component\n%v", err)
+	}
+	// the gRPC server is recreated when the API tokens change
+	previous := component.GRPC
+	tokensConfig := httpConfig
+	tokensConfig.Tokens = []http.Token{{Name: "admin", Token: "admin-secret"}}
+	err = component.Reload(&Configuration{HTTP: tokensConfig, GRPC: grpcConfig})
+	if err != nil {
+		t.Fatalf("Fail to reload the component\n%v", err)
+	}
+	if component.GRPC == previous {
+		t.Fatalf("The gRPC server was not recreated")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/appclacks/cabourotte/systemd"
+)
+
+// watchdog pings the systemd watchdog as long as the healthchecks
+// scheduler is alive, so systemd restarts a wedged daemon
+type watchdog struct {
+	logger   *zap.Logger
+	interval time.Duration
+	alive    func(timeout time.Duration) bool
+	t        tomb.Tomb
+}
+
+// newWatchdog creates the watchdog. It returns nil if the systemd
+// watchdog is not enabled.
+func newWatchdog(logger *zap.Logger, alive func(timeout time.Duration) bool) *watchdog {
+	timeout, err := systemd.WatchdogInterval()
+	if err != nil {
+		logger.Error(err.Error())
+		return nil
+	}
+	if timeout == 0 {
+		return nil
+	}
+	// systemd recommends to ping the watchdog every half of its timeout
+	return &watchdog{
+		logger:   logger,
+		interval: timeout / 2,
+		alive:    alive,
+	}
+}
+
+// ping pings the watchdog if the daemon is alive
+func (w *watchdog) ping() {
+	if !w.alive(w.interval / 2) {
+		w.logger.Error("The healthchecks scheduler is not responding, not pinging the systemd watchdog")
+		return
+	}
+	_, err := systemd.Notify(systemd.StateWatchdog)
+	if err != nil {
+		w.logger.Error(err.Error())
+	}
+}
+
+// start starts pinging the watchdog
+func (w *watchdog) start() {
+	w.logger.Info("Starting the systemd watchdog", zap.Duration("interval", w.interval))
+	ticker := time.NewTicker(w.interval)
+	w.t.Go(func() error {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.ping()
+			case <-w.t.Dying():
+				return nil
+			}
+		}
+	})
+}
+
+// stop stops pinging the watchdog
+func (w *watchdog) stop() error {
+	w.t.Kill(nil)
+	return w.t.Wait()
+}
+
+// notify sends a state notification to systemd, if the daemon is
+// managed by systemd
+func (c *Component) notify(state string) {
+	sent, err := systemd.Notify(state)
+	if err != nil {
+		c.Logger.Error(err.Error())
+		return
+	}
+	if sent {
+		c.Logger.Debug("Notification sent to systemd", zap.String("state", state))
+	}
+}
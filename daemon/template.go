@@ -0,0 +1,284 @@
+package daemon
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// TargetVariable the variable replaced by the targets in the templates
+const TargetVariable = "$target"
+
+// Template a parameterized healthcheck, expanded into one healthcheck per
+// target. The $target variable is replaced by the target in all the
+// string values of the check. The target is appended to the check name
+// if the name does not reference $target.
+type Template struct {
+	Name    string
+	Targets []string
+	// TargetsFile a CSV or YAML file listing additional targets, with
+	// labels overriding the labels of the check
+	TargetsFile  string        `yaml:"targets-file"`
+	CommandCheck yaml.MapSlice `yaml:"command-check"`
+	DNSCheck     yaml.MapSlice `yaml:"dns-check"`
+	TCPCheck     yaml.MapSlice `yaml:"tcp-check"`
+	HTTPCheck    yaml.MapSlice `yaml:"http-check"`
+	TLSCheck     yaml.MapSlice `yaml:"tls-check"`
+}
+
+// TemplateTarget a target of a template read from a targets file
+type TemplateTarget struct {
+	Target string
+	Labels map[string]string
+}
+
+// UnmarshalYAML parses a target, which can be a string or an object with
+// labels
+func (t *TemplateTarget) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var target string
+	if err := unmarshal(&target); err == nil {
+		t.Target = target
+		return nil
+	}
+	type rawTarget TemplateTarget
+	raw := rawTarget{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	*t = TemplateTarget(raw)
+	return nil
+}
+
+// readTargetsCSV reads the targets of a CSV file. The first line is the
+// header, the target column containing the targets and the other columns
+// the labels. The empty values are ignored.
+func readTargetsCSV(content []byte) ([]TemplateTarget, error) {
+	records, err := csv.NewReader(strings.NewReader(string(content))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("The header is missing")
+	}
+	header := records[0]
+	column := -1
+	for i, name := range header {
+		if strings.TrimSpace(name) == "target" {
+			column = i
+		}
+	}
+	if column == -1 {
+		return nil, errors.New("The target column is missing")
+	}
+	result := make([]TemplateTarget, 0, len(records)-1)
+	for _, record := range records[1:] {
+		target := TemplateTarget{Target: strings.TrimSpace(record[column])}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if i == column || value == "" {
+				continue
+			}
+			if target.Labels == nil {
+				target.Labels = make(map[string]string)
+			}
+			target.Labels[strings.TrimSpace(header[i])] = value
+		}
+		result = append(result, target)
+	}
+	return result, nil
+}
+
+// readTargetsFile reads the targets of a targets file, in the CSV format
+// if its extension is .csv and in the YAML format otherwise
+func readTargetsFile(path string) ([]TemplateTarget, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the targets file %s", path)
+	}
+	var targets []TemplateTarget
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		targets, err = readTargetsCSV(content)
+	} else {
+		err = yaml.Unmarshal(content, &targets)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid targets file %s", path)
+	}
+	for _, target := range targets {
+		if target.Target == "" {
+			return nil, fmt.Errorf("Invalid targets file %s: a target is empty", path)
+		}
+	}
+	return targets, nil
+}
+
+// targets returns the targets of the template and of its targets file
+func (t *Template) targets() ([]TemplateTarget, error) {
+	result := make([]TemplateTarget, 0, len(t.Targets))
+	for _, target := range t.Targets {
+		result = append(result, TemplateTarget{Target: target})
+	}
+	if t.TargetsFile != "" {
+		targets, err := readTargetsFile(t.TargetsFile)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, targets...)
+	}
+	return result, nil
+}
+
+// applyLabels overrides the labels of an healthcheck with the labels of
+// its target
+func applyLabels(base *healthcheck.Base, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	result := make(map[string]string, len(base.Labels)+len(labels))
+	for k, v := range base.Labels {
+		result[k] = v
+	}
+	for k, v := range labels {
+		result[k] = v
+	}
+	base.Labels = result
+}
+
+// replace replaces the target variable in all the string values
+func replace(value interface{}, target string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.ReplaceAll(v, TargetVariable, target)
+	case yaml.MapSlice:
+		result := make(yaml.MapSlice, 0, len(v))
+		for _, item := range v {
+			result = append(result, yaml.MapItem{Key: item.Key, Value: replace(item.Value, target)})
+		}
+		return result
+	case map[interface{}]interface{}:
+		result := make(map[interface{}]interface{}, len(v))
+		for key, item := range v {
+			result[key] = replace(item, target)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			result = append(result, replace(item, target))
+		}
+		return result
+	}
+	return value
+}
+
+// render renders the check template for a target into out
+func (t *Template) render(check yaml.MapSlice, target string, out interface{}, strict bool) error {
+	rendered := replace(check, target).(yaml.MapSlice)
+	hasName := false
+	for i, item := range check {
+		if item.Key != "name" {
+			continue
+		}
+		hasName = true
+		name, ok := item.Value.(string)
+		if !ok {
+			return fmt.Errorf("Invalid check name in the template %s", t.Name)
+		}
+		if !strings.Contains(name, TargetVariable) {
+			rendered[i].Value = fmt.Sprintf("%s-%s", name, target)
+		}
+	}
+	if !hasName {
+		rendered = append(yaml.MapSlice{{Key: "name", Value: fmt.Sprintf("%s-%s", t.Name, target)}}, rendered...)
+	}
+	content, err := yaml.Marshal(rendered)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to render the template %s", t.Name)
+	}
+	err = unmarshalFunc(strict)(content, out)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to render the template %s for the target %s", t.Name, target)
+	}
+	return nil
+}
+
+// validate validates the template
+func (t *Template) validate() error {
+	if t.Name == "" {
+		return errors.New("The template name is missing")
+	}
+	if len(t.Targets) == 0 && t.TargetsFile == "" {
+		return fmt.Errorf("The targets of the template %s are missing", t.Name)
+	}
+	count := 0
+	for _, check := range []yaml.MapSlice{t.CommandCheck, t.DNSCheck, t.TCPCheck, t.HTTPCheck, t.TLSCheck} {
+		if check != nil {
+			count++
+		}
+	}
+	if count != 1 {
+		return fmt.Errorf("The template %s needs exactly one check (command-check, dns-check, tcp-check, http-check or tls-check)", t.Name)
+	}
+	return nil
+}
+
+// expandTemplates expands the templates into healthchecks. The unknown
+// fields of the checks templates are rejected in strict mode.
+func expandTemplates(templates []Template, strict bool) (*includedConfiguration, error) {
+	result := includedConfiguration{}
+	for i := range templates {
+		template := templates[i]
+		if err := template.validate(); err != nil {
+			return nil, err
+		}
+		if template.TargetsFile != "" {
+			result.targetsFiles = append(result.targetsFiles, template.TargetsFile)
+		}
+		targets, err := template.targets()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid template %s", template.Name)
+		}
+		for _, t := range targets {
+			target := t.Target
+			var err error
+			switch {
+			case template.CommandCheck != nil:
+				var check healthcheck.CommandHealthcheckConfiguration
+				err = template.render(template.CommandCheck, target, &check, strict)
+				applyLabels(&check.Base, t.Labels)
+				result.CommandChecks = append(result.CommandChecks, check)
+			case template.DNSCheck != nil:
+				var check healthcheck.DNSHealthcheckConfiguration
+				err = template.render(template.DNSCheck, target, &check, strict)
+				applyLabels(&check.Base, t.Labels)
+				result.DNSChecks = append(result.DNSChecks, check)
+			case template.TCPCheck != nil:
+				var check healthcheck.TCPHealthcheckConfiguration
+				err = template.render(template.TCPCheck, target, &check, strict)
+				applyLabels(&check.Base, t.Labels)
+				result.TCPChecks = append(result.TCPChecks, check)
+			case template.HTTPCheck != nil:
+				var check healthcheck.HTTPHealthcheckConfiguration
+				err = template.render(template.HTTPCheck, target, &check, strict)
+				applyLabels(&check.Base, t.Labels)
+				result.HTTPChecks = append(result.HTTPChecks, check)
+			case template.TLSCheck != nil:
+				var check healthcheck.TLSHealthcheckConfiguration
+				err = template.render(template.TLSCheck, target, &check, strict)
+				applyLabels(&check.Base, t.Labels)
+				result.TLSChecks = append(result.TLSChecks, check)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &result, nil
+}
@@ -0,0 +1,215 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestTemplatesExpansion(t *testing.T) {
+	in := `
+http:
+  host: "127.0.0.1"
+  port: 2000
+templates:
+  - name: "web"
+    targets: ["a.example.com", "b.example.com"]
+    http-check:
+      description: "health of $target"
+      target: "$target"
+      port: 443
+      protocol: "https"
+      path: "/health"
+      valid-status: [200]
+      timeout: 3s
+      interval: 10s
+      labels:
+        host: "$target"
+  - name: "ssh"
+    targets: ["10.0.0.1"]
+    tcp-check:
+      name: "ssh-$target-check"
+      target: "$target"
+      port: 22
+      timeout: 3s
+      interval: 10s
+`
+	var config Configuration
+	err := yaml.Unmarshal([]byte(in), &config)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %s", err.Error())
+	}
+	if len(config.HTTPChecks) != 2 {
+		t.Fatalf("Invalid HTTP checks: %v", config.HTTPChecks)
+	}
+	for i, target := range []string{"a.example.com", "b.example.com"} {
+		check := config.HTTPChecks[i]
+		if check.Base.Name != "web-"+target {
+			t.Fatalf("Invalid name %s", check.Base.Name)
+		}
+		if check.Target != target || check.Base.Labels["host"] != target {
+			t.Fatalf("Invalid target %s", check.Target)
+		}
+		if check.Base.Description != "health of "+target {
+			t.Fatalf("Invalid description %s", check.Base.Description)
+		}
+		if check.Port != 443 || check.Path != "/health" {
+			t.Fatalf("Invalid check %v", check)
+		}
+	}
+	if len(config.TCPChecks) != 1 || config.TCPChecks[0].Base.Name != "ssh-10.0.0.1-check" {
+		t.Fatalf("Invalid TCP checks: %v", config.TCPChecks)
+	}
+
+	errorCases := []string{
+		`
+templates:
+  - name: "web"
+    http-check:
+      target: "$target"
+`,
+		`
+templates:
+  - name: "web"
+    targets: ["a"]
+`,
+		`
+templates:
+  - name: "web"
+    targets: ["a"]
+    tcp-check:
+      target: "$target"
+      port: 22
+      timeout: 3s
+      interval: 10s
+    command-check:
+      command: "ls"
+`,
+		`
+templates:
+  - name: "web"
+    targets: ["a"]
+    tcp-check:
+      target: "$target"
+`,
+	}
+	for _, c := range errorCases {
+		var config Configuration
+		err := yaml.Unmarshal([]byte(c), &config)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", c)
+		}
+	}
+}
+
+func TestTemplatesTargetsFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "targets.yaml")
+	err := os.WriteFile(yamlFile, []byte(`
+- "a.example.com"
+- target: "b.example.com"
+  labels:
+    team: "web"
+`), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the targets file: %s", err.Error())
+	}
+	csvFile := filepath.Join(dir, "targets.csv")
+	err = os.WriteFile(csvFile, []byte("target,team,env\n10.0.0.1,db,\n10.0.0.2,,staging\n"), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the targets file: %s", err.Error())
+	}
+	in := fmt.Sprintf(`
+http:
+  host: "127.0.0.1"
+  port: 2000
+templates:
+  - name: "web"
+    targets: ["c.example.com"]
+    targets-file: %s
+    http-check:
+      target: "$target"
+      port: 443
+      protocol: "https"
+      valid-status: [200]
+      timeout: 3s
+      interval: 10s
+      labels:
+        team: "infra"
+  - name: "ssh"
+    targets-file: %s
+    tcp-check:
+      target: "$target"
+      port: 22
+      timeout: 3s
+      interval: 10s
+      labels:
+        env: "production"
+`, yamlFile, csvFile)
+	var config Configuration
+	err = yaml.Unmarshal([]byte(in), &config)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %s", err.Error())
+	}
+	files := config.TargetsFiles()
+	if len(files) != 2 || files[0] != yamlFile || files[1] != csvFile {
+		t.Fatalf("Invalid targets files %v", files)
+	}
+	if len(config.HTTPChecks) != 3 {
+		t.Fatalf("Invalid HTTP checks: %v", config.HTTPChecks)
+	}
+	httpCases := []struct {
+		name string
+		team string
+	}{
+		{name: "web-c.example.com", team: "infra"},
+		{name: "web-a.example.com", team: "infra"},
+		{name: "web-b.example.com", team: "web"},
+	}
+	for i, c := range httpCases {
+		check := config.HTTPChecks[i]
+		if check.Base.Name != c.name || check.Base.Labels["team"] != c.team {
+			t.Fatalf("Invalid check %s with labels %v", check.Base.Name, check.Base.Labels)
+		}
+	}
+	if len(config.TCPChecks) != 2 {
+		t.Fatalf("Invalid TCP checks: %v", config.TCPChecks)
+	}
+	tcpCases := []struct {
+		name   string
+		labels map[string]string
+	}{
+		{name: "ssh-10.0.0.1", labels: map[string]string{"env": "production", "team": "db"}},
+		{name: "ssh-10.0.0.2", labels: map[string]string{"env": "staging"}},
+	}
+	for i, c := range tcpCases {
+		check := config.TCPChecks[i]
+		if check.Base.Name != c.name || !reflect.DeepEqual(check.Base.Labels, c.labels) {
+			t.Fatalf("Invalid check %s with labels %v", check.Base.Name, check.Base.Labels)
+		}
+	}
+
+	invalidFiles := map[string]string{
+		"missing-column.csv": "host,team\n10.0.0.1,db\n",
+		"empty-target.yaml":  "- target: \"\"\n",
+		"invalid.yaml":       "target: foo\n",
+	}
+	for name, content := range invalidFiles {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Fail to write the targets file: %s", err.Error())
+		}
+		_, err := readTargetsFile(path)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", name)
+		}
+	}
+	_, err = readTargetsFile(filepath.Join(dir, "missing.yaml"))
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
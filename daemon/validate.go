@@ -0,0 +1,291 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/discovery/consul"
+	"github.com/appclacks/cabourotte/discovery/docker"
+	"github.com/appclacks/cabourotte/discovery/ec2"
+	"github.com/appclacks/cabourotte/discovery/eureka"
+	"github.com/appclacks/cabourotte/discovery/filesd"
+	dhttp "github.com/appclacks/cabourotte/discovery/http"
+	"github.com/appclacks/cabourotte/discovery/kubernetes"
+	"github.com/appclacks/cabourotte/discovery/kv"
+	"github.com/appclacks/cabourotte/discovery/srv"
+	"github.com/appclacks/cabourotte/exporter"
+	"github.com/appclacks/cabourotte/maintenance"
+	"github.com/appclacks/cabourotte/secret"
+)
+
+// secretPlaceholder the value of the secrets during the validation
+const secretPlaceholder = "secret"
+
+// validator validates an element of a list of the configuration
+type validator func(content []byte, defaults *Defaults, strict bool) error
+
+// validatorFor returns a validator unmarshalling the element into the
+// value returned by newValue and calling its Validate function
+func validatorFor(newValue func() interface{}) validator {
+	return func(content []byte, defaults *Defaults, strict bool) error {
+		value := newValue()
+		if err := unmarshalFunc(strict)(content, value); err != nil {
+			return err
+		}
+		if v, ok := value.(interface{ Validate() error }); ok {
+			return v.Validate()
+		}
+		return nil
+	}
+}
+
+// checksValidator returns a validator for the elements of a healthchecks
+// or templates list. The defaults are applied before the validation.
+func checksValidator(section string) validator {
+	return func(content []byte, defaults *Defaults, strict bool) error {
+		var item interface{}
+		if err := yaml.Unmarshal(content, &item); err != nil {
+			return err
+		}
+		wrapped, err := yaml.Marshal(yaml.MapSlice{{Key: section, Value: []interface{}{item}}})
+		if err != nil {
+			return err
+		}
+		var checks includedConfiguration
+		if err := unmarshalFunc(strict)(wrapped, &checks); err != nil {
+			return err
+		}
+		expanded, err := expandTemplates(checks.Templates, strict)
+		if err != nil {
+			return err
+		}
+		checks.merge(expanded)
+		defaults.apply(&checks)
+		return checks.validate()
+	}
+}
+
+// validators the validators of the lists of the configuration, by path
+var validators = []struct {
+	path      []string
+	validator validator
+}{
+	{[]string{"command-checks"}, checksValidator("command-checks")},
+	{[]string{"dns-checks"}, checksValidator("dns-checks")},
+	{[]string{"tcp-checks"}, checksValidator("tcp-checks")},
+	{[]string{"http-checks"}, checksValidator("http-checks")},
+	{[]string{"tls-checks"}, checksValidator("tls-checks")},
+	{[]string{"heartbeat-checks"}, checksValidator("heartbeat-checks")},
+	{[]string{"grpc-checks"}, checksValidator("grpc-checks")},
+	{[]string{"profile-checks"}, checksValidator("profile-checks")},
+	{[]string{"templates"}, checksValidator("templates")},
+	{[]string{"maintenance-windows"}, validatorFor(func() interface{} { return &maintenance.Window{} })},
+	{[]string{"exporters", "http"}, validatorFor(func() interface{} { return &exporter.HTTPConfiguration{} })},
+	{[]string{"exporters", "riemann"}, validatorFor(func() interface{} { return &exporter.RiemannConfiguration{} })},
+	{[]string{"exporters", "exec"}, validatorFor(func() interface{} { return &exporter.ExecConfiguration{} })},
+	{[]string{"exporters", "plugin"}, validatorFor(func() interface{} { return &exporter.PluginConfiguration{} })},
+	{[]string{"exporters", "remote-write"}, validatorFor(func() interface{} { return &exporter.RemoteWriteConfiguration{} })},
+	{[]string{"exporters", "zabbix"}, validatorFor(func() interface{} { return &exporter.ZabbixConfiguration{} })},
+	{[]string{"exporters", "cabourotte"}, validatorFor(func() interface{} { return &exporter.CabourotteConfiguration{} })},
+	{[]string{"discovery", "http"}, validatorFor(func() interface{} { return &dhttp.Configuration{} })},
+	{[]string{"discovery", "kubernetes"}, validatorFor(func() interface{} { return &kubernetes.Configuration{} })},
+	{[]string{"discovery", "consul"}, validatorFor(func() interface{} { return &consul.Configuration{} })},
+	{[]string{"discovery", "srv"}, validatorFor(func() interface{} { return &srv.Configuration{} })},
+	{[]string{"discovery", "docker"}, validatorFor(func() interface{} { return &docker.Configuration{} })},
+	{[]string{"discovery", "ec2"}, validatorFor(func() interface{} { return &ec2.Configuration{} })},
+	{[]string{"discovery", "eureka"}, validatorFor(func() interface{} { return &eureka.Configuration{} })},
+	{[]string{"discovery", "kv"}, validatorFor(func() interface{} { return &kv.Configuration{} })},
+	{[]string{"discovery", "file-sd"}, validatorFor(func() interface{} { return &filesd.Configuration{} })},
+}
+
+// readOffline reads a configuration file without reading the secrets
+func readOffline(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the configuration file %s", path)
+	}
+	content, err = ExpandEnv(content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid configuration file %s", path)
+	}
+	content, err = secret.Placeholders(content, secretPlaceholder)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid configuration file %s", path)
+	}
+	return content, nil
+}
+
+// lookup returns the value at path in a document
+func lookup(document yaml.MapSlice, path []string) interface{} {
+	var value interface{} = document
+	for _, key := range path {
+		mapping, ok := value.(yaml.MapSlice)
+		if !ok {
+			return nil
+		}
+		value = nil
+		for _, item := range mapping {
+			if item.Key == key {
+				value = item.Value
+				break
+			}
+		}
+	}
+	return value
+}
+
+// itemLine returns the line of the index-th element of the list at path,
+// or 0 if the line is not found
+func itemLine(lines []string, path []string, index int) int {
+	start := 0
+	indent := -1
+	for _, key := range path {
+		found := false
+		for i := start; i < len(lines); i++ {
+			trimmed := strings.TrimLeft(lines[i], " ")
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			current := len(lines[i]) - len(trimmed)
+			if current <= indent {
+				return 0
+			}
+			if strings.HasPrefix(trimmed, key+":") {
+				start = i + 1
+				indent = current
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+	}
+	count := -1
+	itemIndent := -1
+	for i := start; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		current := len(lines[i]) - len(trimmed)
+		isItem := trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+		if current < indent || (current == indent && !isItem) {
+			return 0
+		}
+		if isItem {
+			if itemIndent == -1 {
+				itemIndent = current
+			}
+			if current == itemIndent {
+				count++
+				if count == index {
+					return i + 1
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// validateDocument validates each element of the lists of a configuration
+// file, and returns the errors with the position of the invalid elements
+func validateDocument(path string, content []byte, defaults *Defaults, strict bool) []error {
+	var document yaml.MapSlice
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return []error{fmt.Errorf("%s: %s", path, err.Error())}
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return []error{err}
+	}
+	lines := strings.Split(string(raw), "\n")
+	var result []error
+	for _, v := range validators {
+		list, ok := lookup(document, v.path).([]interface{})
+		if !ok {
+			continue
+		}
+		for i, item := range list {
+			itemContent, err := yaml.Marshal(item)
+			if err != nil {
+				result = append(result, err)
+				continue
+			}
+			err = v.validator(itemContent, defaults, strict)
+			if err == nil {
+				continue
+			}
+			location := path
+			if line := itemLine(lines, v.path, i); line != 0 {
+				location = fmt.Sprintf("%s:%d", path, line)
+			}
+			element := fmt.Sprintf("%s[%d]", strings.Join(v.path, "."), i)
+			if mapping, ok := item.(yaml.MapSlice); ok {
+				if name, ok := lookup(mapping, []string{"name"}).(string); ok {
+					element = fmt.Sprintf("%s (%s)", element, name)
+				}
+			}
+			result = append(result, fmt.Errorf("%s: %s: %s", location, element, err.Error()))
+		}
+	}
+	return result
+}
+
+// ValidateConfiguration validates a configuration file and the files it
+// includes, and returns all the errors found. The secrets are not read.
+// The unknown fields are rejected in strict mode.
+func ValidateConfiguration(path string, strict bool) []error {
+	content, err := readOffline(path)
+	if err != nil {
+		return []error{err}
+	}
+	var main struct {
+		Include  []string
+		Defaults Defaults
+	}
+	if err := yaml.Unmarshal(content, &main); err != nil {
+		return []error{fmt.Errorf("%s: %s", path, err.Error())}
+	}
+	result := validateDocument(path, content, &main.Defaults, strict)
+	for _, pattern := range main.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(path), pattern)
+		}
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			result = append(result, errors.Wrapf(err, "Invalid include pattern %s", pattern))
+			continue
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			content, err := readOffline(file)
+			if err != nil {
+				result = append(result, err)
+				continue
+			}
+			result = append(result, validateDocument(file, content, &main.Defaults, strict)...)
+		}
+	}
+	if len(result) != 0 {
+		return result
+	}
+	// the elements are valid, checks the configuration as a whole
+	config, err := load(path, readOffline, strict)
+	if err != nil {
+		return []error{err}
+	}
+	if config.HTTP.Host == "" {
+		return []error{errors.New("Invalid HTTP server configuration")}
+	}
+	if err := config.Discovery.Validate(); err != nil {
+		return []error{err}
+	}
+	return nil
+}
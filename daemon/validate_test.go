@@ -0,0 +1,159 @@
+package daemon
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfiguration(t *testing.T) {
+	cases := []struct {
+		in     string
+		errors []string
+	}{
+		{
+			in: `
+http:
+  host: "127.0.0.1"
+  port: 2000
+tcp-checks:
+  - name: "foo"
+    target: "127.0.0.1"
+    port: 22
+    timeout: 3s
+    interval: 10s
+`,
+		},
+		{
+			in: `
+http:
+  host: "127.0.0.1"
+  port: 2000
+tcp-checks:
+  - name: "foo"
+    target: "127.0.0.1"
+    port: 22
+    timeout: 3s
+    interval: 10s
+  - name: "bar"
+    target: "127.0.0.1"
+    timeout: 3s
+    interval: 10s
+exporters:
+  http:
+    - name: "exporter"
+      host: "127.0.0.1"
+`,
+			errors: []string{
+				"config.yaml:11: tcp-checks[1] (bar): The healthcheck port is missing",
+				"config.yaml:17: exporters.http[0] (exporter): Invalid port for the HTTP server",
+			},
+		},
+		{
+			in: `
+http:
+  host: "127.0.0.1"
+  port: 2000
+exporters:
+  remote-write:
+    - name: "prometheus"
+  zabbix:
+    - name: "zabbix"
+  cabourotte:
+    - name: "regional"
+`,
+			errors: []string{
+				"config.yaml:7: exporters.remote-write[0] (prometheus): Invalid URL for the remote-write exporter configuration",
+				"config.yaml:9: exporters.zabbix[0] (zabbix): Invalid host for the Zabbix exporter configuration",
+				"config.yaml:11: exporters.cabourotte[0] (regional): Invalid host for the cabourotte exporter configuration",
+			},
+		},
+		{
+			in: `
+http:
+  host: "127.0.0.1"
+  port: 2000
+http-checks:
+- name: "foo"
+  target: "127.0.0.1"
+  port: 443
+  password_vault: "secret/data/foo#password"
+  timeout: 3s
+  interval: 10s
+`,
+			errors: []string{
+				"config.yaml:6: http-checks[0] (foo): At least one valid status code should be provided",
+			},
+		},
+		{
+			in: `
+http:
+  host: "127.0.0.1"
+  port: 2000
+tcp-checks:
+  - name: "foo"
+    target: "127.0.0.1"
+    port: 22
+    timeout: 3s
+    interval: 10s
+templates:
+  - name: "foo"
+    targets: ["bar"]
+    tcp-check:
+      name: "$target"
+      target: "$target"
+      port: 22
+      timeout: 3s
+      interval: 10s
+discovery:
+  srv:
+    - name: "srv"
+      records: ["_http._tcp.example.com"]
+      tcp-check:
+        name: "srv"
+        target: "127.0.0.1"
+        port: 22
+        timeout: 3s
+        interval: 10s
+    - name: "srv"
+      records: ["_http._tcp.example.com"]
+      tcp-check:
+        name: "srv"
+        target: "127.0.0.1"
+        port: 22
+        timeout: 3s
+        interval: 10s
+`,
+			errors: []string{
+				"DNS SRV discovery names should be unique (duplicate found for srv)",
+			},
+		},
+		{
+			in: `
+http:
+  host: "127.0.0.1"
+  port: 2000
+discovery:
+  file-sd:
+    - name: "blackbox"
+      files: ["/etc/cabourotte/targets/*.json"]
+`,
+			errors: []string{
+				"config.yaml:7: discovery.file-sd[0] (blackbox): The file_sd discovery modules are missing",
+			},
+		},
+	}
+	for _, c := range cases {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		writeFile(t, path, c.in)
+		errs := ValidateConfiguration(path, false)
+		if len(errs) != len(c.errors) {
+			t.Fatalf("Invalid errors for %s: %v", c.in, errs)
+		}
+		for i, err := range errs {
+			if !strings.HasSuffix(err.Error(), c.errors[i]) {
+				t.Fatalf("Invalid error\n%s\n%s", err.Error(), c.errors[i])
+			}
+		}
+	}
+}
@@ -1,10 +1,93 @@
 package discovery
 
 import (
+	"fmt"
+
+	"github.com/appclacks/cabourotte/discovery/consul"
+	"github.com/appclacks/cabourotte/discovery/docker"
+	"github.com/appclacks/cabourotte/discovery/ec2"
+	"github.com/appclacks/cabourotte/discovery/eureka"
+	"github.com/appclacks/cabourotte/discovery/filesd"
 	"github.com/appclacks/cabourotte/discovery/http"
+	"github.com/appclacks/cabourotte/discovery/kubernetes"
+	"github.com/appclacks/cabourotte/discovery/kv"
+	"github.com/appclacks/cabourotte/discovery/srv"
 )
 
 // Configuration the service discovery mechanisms configuration
 type Configuration struct {
-	HTTP []http.Configuration
+	HTTP       []http.Configuration
+	Kubernetes []kubernetes.Configuration
+	Consul     []consul.Configuration
+	SRV        []srv.Configuration
+	Docker     []docker.Configuration
+	EC2        []ec2.Configuration
+	Eureka     []eureka.Configuration
+	KV         []kv.Configuration
+	FileSD     []filesd.Configuration `yaml:"file-sd"`
+}
+
+// names returns an error if a name is used by several discovery
+// mechanisms of the same kind
+func names(kind string, list []string) error {
+	seen := make(map[string]bool)
+	for _, name := range list {
+		if seen[name] {
+			return fmt.Errorf("%s discovery names should be unique (duplicate found for %s)", kind, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// Validate validates the discovery configuration
+func (c *Configuration) Validate() error {
+	var httpNames, k8sNames, consulNames, srvNames, dockerNames, ec2Names, eurekaNames, kvNames, fileSDNames []string
+	for _, config := range c.HTTP {
+		httpNames = append(httpNames, config.Name)
+	}
+	for _, config := range c.Kubernetes {
+		k8sNames = append(k8sNames, config.Name)
+	}
+	for _, config := range c.Consul {
+		consulNames = append(consulNames, config.Name)
+	}
+	for _, config := range c.SRV {
+		srvNames = append(srvNames, config.Name)
+	}
+	for _, config := range c.Docker {
+		dockerNames = append(dockerNames, config.Name)
+	}
+	for _, config := range c.EC2 {
+		ec2Names = append(ec2Names, config.Name)
+	}
+	for _, config := range c.Eureka {
+		eurekaNames = append(eurekaNames, config.Name)
+	}
+	for _, config := range c.KV {
+		kvNames = append(kvNames, config.Name)
+	}
+	for _, config := range c.FileSD {
+		fileSDNames = append(fileSDNames, config.Name)
+	}
+	checks := []struct {
+		kind  string
+		names []string
+	}{
+		{"HTTP", httpNames},
+		{"Kubernetes", k8sNames},
+		{"Consul", consulNames},
+		{"DNS SRV", srvNames},
+		{"Docker", dockerNames},
+		{"EC2", ec2Names},
+		{"Eureka", eurekaNames},
+		{"KV", kvNames},
+		{"file_sd", fileSDNames},
+	}
+	for _, check := range checks {
+		if err := names(check.kind, check.names); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,79 @@
+package consul
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+const (
+	// DefaultAddress the default Consul agent address
+	DefaultAddress = "http://127.0.0.1:8500"
+	// DefaultInterval the default interval between two catalog listings
+	DefaultInterval = healthcheck.Duration(30 * time.Second)
+)
+
+// CheckConfiguration the healthchecks created for the services instances.
+// The type (tcp, http or https) and the path can be overridden per instance
+// using the `cabourotte-type` and `cabourotte-path` service metadata.
+type CheckConfiguration struct {
+	Type        string
+	Path        string
+	ValidStatus healthcheck.StatusCodes `json:"valid-status" yaml:"valid-status"`
+	Interval    healthcheck.Duration    `json:"interval"`
+	Timeout     healthcheck.Duration    `json:"timeout"`
+}
+
+// Configuration the Consul discovery configuration.
+// All services of the catalog are watched if no service is configured.
+// Only the instances passing their Consul health checks are probed if
+// passing is true.
+type Configuration struct {
+	Name       string
+	Address    string
+	Token      string
+	Datacenter string
+	Services   []string
+	Tag        string
+	Passing    bool
+	Check      CheckConfiguration
+	Interval   healthcheck.Duration `json:"interval"`
+	Labels     map[string]string    `json:"labels,omitempty"`
+	Key        string               `json:"key,omitempty"`
+	Cert       string               `json:"cert,omitempty"`
+	Cacert     string               `json:"cacert,omitempty"`
+	Insecure   bool
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read Consul discovery configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid Consul discovery name configuration")
+	}
+	if raw.Address == "" {
+		raw.Address = DefaultAddress
+	}
+	if raw.Interval == 0 {
+		raw.Interval = DefaultInterval
+	}
+	if raw.Interval < healthcheck.Duration(10*time.Second) {
+		return errors.New("The Consul discovery interval should be greater or equal than 10 seconds")
+	}
+	if raw.Check.Type != "" && raw.Check.Type != "tcp" && raw.Check.Type != "http" && raw.Check.Type != "https" {
+		return fmt.Errorf("Invalid Consul discovery check type %s", raw.Check.Type)
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
@@ -0,0 +1,282 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/tls"
+)
+
+const (
+	defaultCheckInterval = healthcheck.Duration(10 * time.Second)
+	defaultCheckTimeout  = healthcheck.Duration(5 * time.Second)
+)
+
+type serviceEntry struct {
+	Node struct {
+		Node    string `json:"Node"`
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		ID      string            `json:"ID"`
+		Service string            `json:"Service"`
+		Address string            `json:"Address"`
+		Port    uint              `json:"Port"`
+		Meta    map[string]string `json:"Meta"`
+	} `json:"Service"`
+}
+
+// ConsulDiscovery the Consul discovery struct
+type ConsulDiscovery struct {
+	Logger          *zap.Logger
+	responseCounter *prom.CounterVec
+	Healthcheck     *healthcheck.Component
+	Config          *Configuration
+	Client          *http.Client
+	Address         string
+	t               tomb.Tomb
+	tick            *time.Ticker
+}
+
+// New creates a new Consul discovery
+func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, counter *prom.CounterVec) (*ConsulDiscovery, error) {
+	tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, "", config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	component := ConsulDiscovery{
+		Logger:          logger,
+		responseCounter: counter,
+		Healthcheck:     checkComponent,
+		Config:          config,
+		Address:         strings.TrimSuffix(config.Address, "/"),
+		Client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+			Timeout: time.Second * 10,
+		},
+	}
+	return &component, nil
+}
+
+// get sends a GET request to the Consul API and decodes the response
+func (c *ConsulDiscovery) get(ctx context.Context, path string, query url.Values, result interface{}) error {
+	if c.Config.Datacenter != "" {
+		query.Set("dc", c.Config.Datacenter)
+	}
+	reqURL := c.Address + path
+	if len(query) != 0 {
+		reqURL = reqURL + "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return errors.Wrapf(err, "Consul discovery: fail to create request for %s", reqURL)
+	}
+	req.Header.Set("User-Agent", "Cabourotte")
+	if c.Config.Token != "" {
+		req.Header.Set("X-Consul-Token", c.Config.Token)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "Consul discovery: fail to send request to %s", reqURL)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to read request body")
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("Consul discovery: request to %s failed, status %d, body %s", reqURL, resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return errors.Wrapf(err, "Consul discovery: fail to convert the payload from json")
+	}
+	return nil
+}
+
+// services returns the names of the services to watch
+func (c *ConsulDiscovery) services(ctx context.Context) ([]string, error) {
+	if len(c.Config.Services) != 0 {
+		return c.Config.Services, nil
+	}
+	catalog := make(map[string][]string)
+	err := c.get(ctx, "/v1/catalog/services", url.Values{}, &catalog)
+	if err != nil {
+		return nil, err
+	}
+	var services []string
+	for name := range catalog {
+		// the consul service itself is not probed
+		if name != "consul" {
+			services = append(services, name)
+		}
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// buildChecks builds the healthchecks for the instances of a service
+func (c *ConsulDiscovery) buildChecks(entries []serviceEntry, tcpChecks *[]healthcheck.TCPHealthcheckConfiguration, httpChecks *[]healthcheck.HTTPHealthcheckConfiguration) {
+	for _, entry := range entries {
+		target := entry.Service.Address
+		if target == "" {
+			target = entry.Node.Address
+		}
+		if target == "" || entry.Service.Port == 0 {
+			continue
+		}
+		base := healthcheck.Base{
+			Name:     fmt.Sprintf("consul-%s-%s-%s", entry.Service.Service, entry.Node.Node, entry.Service.ID),
+			Interval: c.Config.Check.Interval,
+			Labels: map[string]string{
+				"service": entry.Service.Service,
+				"node":    entry.Node.Node,
+			},
+		}
+		if base.Interval == 0 {
+			base.Interval = defaultCheckInterval
+		}
+		timeout := c.Config.Check.Timeout
+		if timeout == 0 {
+			timeout = defaultCheckTimeout
+		}
+		checkType := c.Config.Check.Type
+		if value, ok := entry.Service.Meta["cabourotte-type"]; ok {
+			checkType = value
+		}
+		var err error
+		switch checkType {
+		case "", "tcp":
+			config := healthcheck.TCPHealthcheckConfiguration{
+				Base:    base,
+				Target:  target,
+				Port:    entry.Service.Port,
+				Timeout: timeout,
+			}
+			if err = config.Validate(); err == nil {
+				*tcpChecks = append(*tcpChecks, config)
+			}
+		case "http", "https":
+			config := healthcheck.HTTPHealthcheckConfiguration{
+				Base:        base,
+				Target:      target,
+				Port:        entry.Service.Port,
+				Path:        c.Config.Check.Path,
+				Protocol:    healthcheck.HTTP,
+				ValidStatus: c.Config.Check.ValidStatus,
+				Timeout:     timeout,
+			}
+			if checkType == "https" {
+				config.Protocol = healthcheck.HTTPS
+			}
+			if value, ok := entry.Service.Meta["cabourotte-path"]; ok {
+				config.Path = value
+			}
+			if config.Path == "" {
+				config.Path = "/"
+			}
+			if len(config.ValidStatus) == 0 {
+				config.ValidStatus = []uint{200}
+			}
+			if err = config.Validate(); err == nil {
+				*httpChecks = append(*httpChecks, config)
+			}
+		default:
+			err = fmt.Errorf("Invalid healthcheck type %s", checkType)
+		}
+		if err != nil {
+			c.Logger.Error(fmt.Sprintf("Consul discovery: invalid healthcheck for the service %s on %s: %s", entry.Service.Service, entry.Node.Node, err.Error()))
+		}
+	}
+}
+
+// reconcile lists the Consul services instances and reloads the
+// healthchecks
+func (c *ConsulDiscovery) reconcile() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	services, err := c.services(ctx)
+	if err != nil {
+		return err
+	}
+	var tcpChecks []healthcheck.TCPHealthcheckConfiguration
+	var httpChecks []healthcheck.HTTPHealthcheckConfiguration
+	for _, service := range services {
+		query := url.Values{}
+		if c.Config.Passing {
+			query.Set("passing", "true")
+		}
+		if c.Config.Tag != "" {
+			query.Set("tag", c.Config.Tag)
+		}
+		var entries []serviceEntry
+		err := c.get(ctx, "/v1/health/service/"+url.PathEscape(service), query, &entries)
+		if err != nil {
+			return err
+		}
+		c.buildChecks(entries, &tcpChecks, &httpChecks)
+	}
+	return c.Healthcheck.ReloadForSource(
+		c.source(),
+		c.Config.Labels,
+		nil,
+		nil,
+		tcpChecks,
+		httpChecks,
+		nil,
+		nil,
+		nil,
+		nil)
+}
+
+// source returns the source of the healthchecks of the discovery
+func (c *ConsulDiscovery) source() string {
+	return fmt.Sprintf("%s-%s", healthcheck.SourceConsulDiscovery, c.Config.Name)
+}
+
+// Start starts the Consul discovery component
+func (c *ConsulDiscovery) Start() error {
+	c.tick = time.NewTicker(time.Duration(c.Config.Interval))
+	c.Healthcheck.RegisterSource(c.source())
+	c.t.Go(func() error {
+		c.Logger.Info(fmt.Sprintf("Starting the Consul healthcheck discovery %s", c.Config.Name))
+		for {
+			status := "success"
+			err := c.reconcile()
+			if err != nil {
+				status = "failure"
+				c.Logger.Error(fmt.Sprintf("Consul discovery error: %s", err.Error()))
+			}
+			c.responseCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+			c.Healthcheck.RecordReconciliation(c.source(), err)
+			select {
+			case <-c.tick.C:
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops the Consul discovery component
+func (c *ConsulDiscovery) Stop() error {
+	c.Logger.Info("Stopping the Consul discovery")
+	c.tick.Stop()
+	c.t.Kill(nil)
+	return c.t.Wait()
+}
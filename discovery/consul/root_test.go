@@ -0,0 +1,101 @@
+package consul
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestReconcile(t *testing.T) {
+	payloads := map[string]string{
+		"/v1/catalog/services": `{"consul": [], "api": ["prod"], "web": []}`,
+		"/v1/health/service/api": `[
+  {"Node": {"Node": "node1", "Address": "10.0.0.1"},
+   "Service": {"ID": "api-1", "Service": "api", "Address": "", "Port": 8080}},
+  {"Node": {"Node": "node2", "Address": "10.0.0.2"},
+   "Service": {"ID": "api-2", "Service": "api", "Address": "10.0.1.2", "Port": 8080,
+               "Meta": {"cabourotte-type": "http", "cabourotte-path": "/health"}}}]`,
+		"/v1/health/service/web": `[
+  {"Node": {"Node": "node1", "Address": "10.0.0.1"},
+   "Service": {"ID": "web", "Service": "web", "Port": 0}}]`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/catalog/services" && r.URL.Query().Get("passing") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		payload, ok := payloads[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, err := w.Write([]byte(payload))
+		if err != nil {
+			t.Fatalf("Error writing body:\n%v", err)
+		}
+	}))
+	defer ts.Close()
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), promComponent, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "consul_discovery_requests_total",
+			Help: "Count the number of Consul discovery reconciliations.",
+		},
+		[]string{"status", "name"})
+	config := Configuration{
+		Name:     "test",
+		Address:  ts.URL,
+		Token:    "secret",
+		Passing:  true,
+		Interval: DefaultInterval,
+	}
+	discovery, err := New(logger, &config, checkComponent, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the Consul discovery component :\n%v", err)
+	}
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("Consul discovery failed\n%v", err)
+	}
+	checks := make(map[string]interface{})
+	for _, check := range checkComponent.ListChecks() {
+		checks[check.Base().Name] = check.GetConfig()
+	}
+	if len(checks) != 2 {
+		t.Fatalf("Expected 2 configured healthchecks, got %v", checks)
+	}
+	tcpCheck, ok := checks["consul-api-node1-api-1"].(*healthcheck.TCPHealthcheckConfiguration)
+	if !ok || tcpCheck.Target != "10.0.0.1" || tcpCheck.Port != 8080 || tcpCheck.Base.Labels["service"] != "api" {
+		t.Fatalf("Invalid TCP healthcheck %v", checks["consul-api-node1-api-1"])
+	}
+	httpCheck, ok := checks["consul-api-node2-api-2"].(*healthcheck.HTTPHealthcheckConfiguration)
+	if !ok || httpCheck.Target != "10.0.1.2" || httpCheck.Path != "/health" {
+		t.Fatalf("Invalid HTTP healthcheck %v", checks["consul-api-node2-api-2"])
+	}
+	payloads["/v1/health/service/api"] = "[]"
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("Consul discovery failed\n%v", err)
+	}
+	if len(checkComponent.ListChecks()) != 0 {
+		t.Fatalf("The healthchecks should have been removed")
+	}
+}
@@ -0,0 +1,177 @@
+package directory
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// DefaultInterval the default interval between two scans of the directory
+const DefaultInterval = 5 * time.Second
+
+// Checks the healthchecks defined in a file of the directory
+type Checks struct {
+	CommandChecks   []healthcheck.CommandHealthcheckConfiguration   `yaml:"command-checks"`
+	DNSChecks       []healthcheck.DNSHealthcheckConfiguration       `yaml:"dns-checks"`
+	TCPChecks       []healthcheck.TCPHealthcheckConfiguration       `yaml:"tcp-checks"`
+	HTTPChecks      []healthcheck.HTTPHealthcheckConfiguration      `yaml:"http-checks"`
+	TLSChecks       []healthcheck.TLSHealthcheckConfiguration       `yaml:"tls-checks"`
+	HeartbeatChecks []healthcheck.HeartbeatHealthcheckConfiguration `yaml:"heartbeat-checks"`
+	GRPCChecks      []healthcheck.GRPCHealthcheckConfiguration      `yaml:"grpc-checks"`
+	ProfileChecks   []healthcheck.ProfileHealthcheckConfiguration   `yaml:"profile-checks"`
+}
+
+// Directory loads the healthchecks from the yaml files of a directory.
+// The directory is scanned periodically: the healthchecks of a file are
+// reloaded when the file changes, and removed when the file is deleted.
+// Each file has its own source (directory-<file name>).
+type Directory struct {
+	Logger      *zap.Logger
+	Healthcheck *healthcheck.Component
+	Path        string
+	Interval    time.Duration
+	// hashes the hash of the files content, by file name
+	hashes map[string][]byte
+	t      tomb.Tomb
+	tick   *time.Ticker
+}
+
+// New creates a new directory component
+func New(logger *zap.Logger, path string, checkComponent *healthcheck.Component) *Directory {
+	return &Directory{
+		Logger:      logger,
+		Healthcheck: checkComponent,
+		Path:        path,
+		Interval:    DefaultInterval,
+		hashes:      make(map[string][]byte),
+	}
+}
+
+// source returns the source of the healthchecks of a file
+func source(name string) string {
+	return fmt.Sprintf("%s-%s", healthcheck.SourceDirectory, name)
+}
+
+// load loads the healthchecks of a file
+func (d *Directory) load(name string, content []byte) error {
+	var checks Checks
+	if err := yaml.UnmarshalStrict(content, &checks); err != nil {
+		return errors.Wrapf(err, "Fail to read the checks file %s", name)
+	}
+	return d.Healthcheck.ReloadForSource(
+		source(name),
+		nil,
+		checks.CommandChecks,
+		checks.DNSChecks,
+		checks.TCPChecks,
+		checks.HTTPChecks,
+		checks.TLSChecks,
+		checks.HeartbeatChecks,
+		checks.GRPCChecks,
+		checks.ProfileChecks)
+}
+
+// scan loads the new or modified files and removes the healthchecks of the
+// deleted files
+func (d *Directory) scan() error {
+	entries, err := os.ReadDir(d.Path)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to read the checks directory %s", d.Path)
+	}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || (!strings.HasSuffix(name, ".yaml") && !strings.HasSuffix(name, ".yml")) {
+			continue
+		}
+		seen[name] = true
+		content, err := os.ReadFile(filepath.Join(d.Path, name))
+		if err != nil {
+			d.Logger.Error(fmt.Sprintf("Fail to read the checks file %s: %s", name, err.Error()))
+			d.Healthcheck.RecordReconciliation(source(name), err)
+			continue
+		}
+		sum := sha256.Sum256(content)
+		if bytes.Equal(d.hashes[name], sum[:]) {
+			continue
+		}
+		d.Logger.Info(fmt.Sprintf("Loading the checks file %s", name))
+		// the hash is updated even on error in order to not log the same
+		// error on each scan
+		d.hashes[name] = sum[:]
+		err = d.load(name, content)
+		if err != nil {
+			d.Logger.Error(err.Error())
+		}
+		d.Healthcheck.RecordReconciliation(source(name), err)
+	}
+	for name := range d.hashes {
+		if seen[name] {
+			continue
+		}
+		d.Logger.Info(fmt.Sprintf("The checks file %s was removed", name))
+		delete(d.hashes, name)
+		err := d.Healthcheck.ReloadForSource(source(name), nil, nil, nil, nil, nil, nil, nil, nil, nil)
+		if err != nil {
+			d.Logger.Error(err.Error())
+		}
+		d.Healthcheck.RemoveSource(source(name))
+	}
+	return nil
+}
+
+// Start loads the directory and starts watching it
+func (d *Directory) Start() error {
+	err := d.scan()
+	if err != nil {
+		return err
+	}
+	d.tick = time.NewTicker(d.Interval)
+	d.t.Go(func() error {
+		d.Logger.Info(fmt.Sprintf("Watching the checks directory %s", d.Path))
+		for {
+			select {
+			case <-d.tick.C:
+				err := d.scan()
+				if err != nil {
+					d.Logger.Error(err.Error())
+				}
+			case <-d.t.Dying():
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops watching the directory
+func (d *Directory) Stop() error {
+	d.Logger.Info("Stopping the checks directory watcher")
+	d.tick.Stop()
+	d.t.Kill(nil)
+	return d.t.Wait()
+}
+
+// RemoveChecks removes all the healthchecks loaded from the directory
+func (d *Directory) RemoveChecks() error {
+	for name := range d.hashes {
+		err := d.Healthcheck.ReloadForSource(source(name), nil, nil, nil, nil, nil, nil, nil, nil, nil)
+		if err != nil {
+			return err
+		}
+		d.Healthcheck.RemoveSource(source(name))
+		delete(d.hashes, name)
+	}
+	return nil
+}
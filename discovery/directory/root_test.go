@@ -0,0 +1,106 @@
+package directory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+const apiChecks = `
+tcp-checks:
+  - name: api-tcp
+    description: api
+    target: 127.0.0.1
+    port: 8080
+    timeout: 2s
+    interval: 10s
+`
+
+const dbChecks = `
+tcp-checks:
+  - name: db-tcp
+    description: db
+    target: 127.0.0.1
+    port: 5432
+    timeout: 2s
+    interval: 10s
+heartbeat-checks:
+  - name: db-backup
+    description: db backup
+    interval: 1h
+`
+
+func checkNames(component *healthcheck.Component) map[string]string {
+	result := make(map[string]string)
+	for _, check := range component.ListChecks() {
+		result[check.Base().Name] = check.Base().Source
+	}
+	return result
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("Fail to write the file %s\n%v", name, err)
+		}
+	}
+	write("api.yaml", apiChecks)
+	write("db.yml", dbChecks)
+	write("ignored.txt", dbChecks)
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	directory := New(logger, dir, checkComponent)
+	err = directory.scan()
+	if err != nil {
+		t.Fatalf("Fail to scan the directory\n%v", err)
+	}
+	names := checkNames(checkComponent)
+	if len(names) != 3 || names["api-tcp"] != "directory-api.yaml" || names["db-tcp"] != "directory-db.yml" || names["db-backup"] != "directory-db.yml" {
+		t.Fatalf("Invalid healthchecks %v", names)
+	}
+
+	// invalid files are ignored and the previous checks are kept
+	write("db.yml", "tcp-checks: [")
+	err = directory.scan()
+	if err != nil {
+		t.Fatalf("Fail to scan the directory\n%v", err)
+	}
+	if len(checkNames(checkComponent)) != 3 {
+		t.Fatalf("The healthchecks should be kept")
+	}
+
+	err = os.Remove(filepath.Join(dir, "api.yaml"))
+	if err != nil {
+		t.Fatalf("Fail to remove the file\n%v", err)
+	}
+	err = directory.scan()
+	if err != nil {
+		t.Fatalf("Fail to scan the directory\n%v", err)
+	}
+	names = checkNames(checkComponent)
+	if len(names) != 2 || names["db-tcp"] == "" || names["db-backup"] == "" {
+		t.Fatalf("Invalid healthchecks %v", names)
+	}
+
+	err = directory.RemoveChecks()
+	if err != nil {
+		t.Fatalf("Fail to remove the healthchecks\n%v", err)
+	}
+	if len(checkNames(checkComponent)) != 0 {
+		t.Fatalf("The healthchecks should have been removed")
+	}
+}
@@ -0,0 +1,56 @@
+package docker
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+const (
+	// DefaultHost the default Docker daemon address
+	DefaultHost = "unix:///var/run/docker.sock"
+	// DefaultInterval the default interval between two full listings of
+	// the containers. The containers are also listed on each container
+	// event.
+	DefaultInterval = healthcheck.Duration(60 * time.Second)
+)
+
+// Configuration the Docker discovery configuration.
+// The healthchecks target the container IP on the configured network, or
+// on its first network if no network is configured.
+type Configuration struct {
+	Name     string
+	Host     string
+	Network  string
+	Interval healthcheck.Duration `json:"interval"`
+	Labels   map[string]string    `json:"labels,omitempty"`
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read Docker discovery configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid Docker discovery name configuration")
+	}
+	if raw.Host == "" {
+		raw.Host = DefaultHost
+	}
+	if !strings.HasPrefix(raw.Host, "unix://") && !strings.HasPrefix(raw.Host, "tcp://") {
+		return errors.New("The Docker discovery host should start with unix:// or tcp://")
+	}
+	if raw.Interval == 0 {
+		raw.Interval = DefaultInterval
+	}
+	if raw.Interval < healthcheck.Duration(10*time.Second) {
+		return errors.New("The Docker discovery interval should be greater or equal than 10 seconds")
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
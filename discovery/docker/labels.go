@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// LabelPrefix the prefix of the container labels used to configure the
+// healthchecks
+const LabelPrefix = "cabourotte."
+
+const (
+	defaultCheckInterval = healthcheck.Duration(10 * time.Second)
+	defaultCheckTimeout  = healthcheck.Duration(5 * time.Second)
+)
+
+// checks the healthchecks built from the containers
+type checks struct {
+	tcp  []healthcheck.TCPHealthcheckConfiguration
+	http []healthcheck.HTTPHealthcheckConfiguration
+}
+
+func label(labels map[string]string, key string) string {
+	return strings.TrimSpace(labels[LabelPrefix+key])
+}
+
+// add builds an healthcheck for a container from its labels:
+// cabourotte.port, cabourotte.type (http, https or tcp, http by default),
+// cabourotte.interval, cabourotte.timeout, cabourotte.http.path and
+// cabourotte.http.valid-status (a comma-separated list of status codes and
+// ranges, for example 200-299,401).
+// It returns false if the container has no cabourotte.port label.
+func (c *checks) add(name string, target string, containerLabels map[string]string, labels map[string]string) (bool, error) {
+	portValue := label(containerLabels, "port")
+	if portValue == "" {
+		return false, nil
+	}
+	port, err := strconv.ParseUint(portValue, 10, 16)
+	if err != nil || port == 0 {
+		return false, fmt.Errorf("Invalid port label %s", portValue)
+	}
+	base := healthcheck.Base{
+		Name:     name,
+		Interval: defaultCheckInterval,
+		Labels:   labels,
+	}
+	timeout := defaultCheckTimeout
+	if value := label(containerLabels, "interval"); value != "" {
+		err := base.Interval.UnmarshalText([]byte(value))
+		if err != nil {
+			return false, errors.Wrapf(err, "Invalid interval label %s", value)
+		}
+	}
+	if value := label(containerLabels, "timeout"); value != "" {
+		err := timeout.UnmarshalText([]byte(value))
+		if err != nil {
+			return false, errors.Wrapf(err, "Invalid timeout label %s", value)
+		}
+	}
+	checkType := label(containerLabels, "type")
+	switch checkType {
+	case "tcp":
+		config := healthcheck.TCPHealthcheckConfiguration{
+			Base:    base,
+			Target:  target,
+			Port:    uint(port),
+			Timeout: timeout,
+		}
+		if err := config.Validate(); err != nil {
+			return false, err
+		}
+		c.tcp = append(c.tcp, config)
+	case "", "http", "https":
+		config := healthcheck.HTTPHealthcheckConfiguration{
+			Base:        base,
+			Target:      target,
+			Port:        uint(port),
+			Path:        label(containerLabels, "http.path"),
+			Protocol:    healthcheck.HTTP,
+			ValidStatus: []uint{200},
+			Timeout:     timeout,
+		}
+		if checkType == "https" {
+			config.Protocol = healthcheck.HTTPS
+		}
+		if config.Path == "" {
+			config.Path = "/"
+		}
+		if value := label(containerLabels, "http.valid-status"); value != "" {
+			codes, err := healthcheck.ParseStatusCodes(strings.Split(value, ","))
+			if err != nil {
+				return false, fmt.Errorf("Invalid http.valid-status label %s", value)
+			}
+			config.ValidStatus = codes
+		}
+		if err := config.Validate(); err != nil {
+			return false, err
+		}
+		c.http = append(c.http, config)
+	default:
+		return false, fmt.Errorf("Invalid type label %s", checkType)
+	}
+	return true, nil
+}
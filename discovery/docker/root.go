@@ -0,0 +1,272 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+type container struct {
+	ID              string            `json:"Id"`
+	Names           []string          `json:"Names"`
+	Labels          map[string]string `json:"Labels"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress string `json:"IPAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+type event struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+}
+
+// DockerDiscovery the Docker discovery struct
+type DockerDiscovery struct {
+	Logger          *zap.Logger
+	responseCounter *prom.CounterVec
+	Healthcheck     *healthcheck.Component
+	Config          *Configuration
+	// Client is used for the containers listing, streamClient for the
+	// events stream which has no timeout
+	Client       *http.Client
+	streamClient *http.Client
+	URL          string
+	trigger      chan struct{}
+	cancel       context.CancelFunc
+	t            tomb.Tomb
+	tick         *time.Ticker
+}
+
+// New creates a new Docker discovery
+func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, counter *prom.CounterVec) (*DockerDiscovery, error) {
+	transport := &http.Transport{}
+	baseURL := ""
+	switch {
+	case strings.HasPrefix(config.Host, "unix://"):
+		path := strings.TrimPrefix(config.Host, "unix://")
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			dialer := net.Dialer{}
+			return dialer.DialContext(ctx, "unix", path)
+		}
+		baseURL = "http://docker"
+	case strings.HasPrefix(config.Host, "tcp://"):
+		baseURL = "http://" + strings.TrimPrefix(config.Host, "tcp://")
+	default:
+		return nil, fmt.Errorf("Invalid Docker host %s", config.Host)
+	}
+	component := DockerDiscovery{
+		Logger:          logger,
+		responseCounter: counter,
+		Healthcheck:     checkComponent,
+		Config:          config,
+		Client: &http.Client{
+			Transport: transport,
+			Timeout:   time.Second * 10,
+		},
+		streamClient: &http.Client{
+			Transport: transport,
+		},
+		URL:     baseURL,
+		trigger: make(chan struct{}, 1),
+	}
+	return &component, nil
+}
+
+// request sends a GET request to the Docker API
+func (c *DockerDiscovery) request(ctx context.Context, client *http.Client, path string, filters map[string][]string) (*http.Response, error) {
+	reqURL := c.URL + path
+	if len(filters) != 0 {
+		value, err := json.Marshal(filters)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Docker discovery: fail to convert the filters to json")
+		}
+		reqURL = reqURL + "?filters=" + url.QueryEscape(string(value))
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Docker discovery: fail to create request for %s", reqURL)
+	}
+	req.Header.Set("User-Agent", "Cabourotte")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Docker discovery: fail to send request to %s", reqURL)
+	}
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Docker discovery: request to %s failed, status %d, body %s", reqURL, resp.StatusCode, string(body))
+	}
+	return resp, nil
+}
+
+// containerIP returns the IP of the container on the configured network
+func (c *DockerDiscovery) containerIP(ctr container) string {
+	if c.Config.Network != "" {
+		return ctr.NetworkSettings.Networks[c.Config.Network].IPAddress
+	}
+	for _, network := range ctr.NetworkSettings.Networks {
+		if network.IPAddress != "" {
+			return network.IPAddress
+		}
+	}
+	return ""
+}
+
+// reconcile lists the running containers and reloads the healthchecks
+func (c *DockerDiscovery) reconcile() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	resp, err := c.request(ctx, c.Client, "/containers/json", map[string][]string{
+		"status": {"running"},
+		"label":  {LabelPrefix + "port"},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var containers []container
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return errors.Wrapf(err, "Docker discovery: fail to convert the payload from json")
+	}
+	result := &checks{}
+	for _, ctr := range containers {
+		name := ctr.ID
+		if len(name) > 12 {
+			name = name[:12]
+		}
+		if len(ctr.Names) != 0 {
+			name = strings.TrimPrefix(ctr.Names[0], "/")
+		}
+		target := c.containerIP(ctr)
+		if target == "" {
+			c.Logger.Debug(fmt.Sprintf("Docker discovery: no IP address for the container %s", name))
+			continue
+		}
+		labels := map[string]string{
+			"container": name,
+		}
+		_, err := result.add(fmt.Sprintf("docker-%s", name), target, ctr.Labels, labels)
+		if err != nil {
+			c.Logger.Error(fmt.Sprintf("Docker discovery: invalid labels on container %s: %s", name, err.Error()))
+		}
+	}
+	return c.Healthcheck.ReloadForSource(
+		c.source(),
+		c.Config.Labels,
+		nil,
+		nil,
+		result.tcp,
+		result.http,
+		nil,
+		nil,
+		nil,
+		nil)
+}
+
+// watchEvents reads the containers events stream and triggers a
+// reconciliation for each container start or stop
+func (c *DockerDiscovery) watchEvents(ctx context.Context) error {
+	resp, err := c.request(ctx, c.streamClient, "/events", map[string][]string{
+		"type":  {"container"},
+		"event": {"start", "stop", "die", "destroy"},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var e event
+		if err := decoder.Decode(&e); err != nil {
+			return errors.Wrapf(err, "Docker discovery: fail to read the events stream")
+		}
+		c.Logger.Debug(fmt.Sprintf("Docker discovery: container event %s", e.Action))
+		select {
+		case c.trigger <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// reconcileAndCount reconciles the containers and updates the counter
+func (c *DockerDiscovery) reconcileAndCount() {
+	status := "success"
+	err := c.reconcile()
+	if err != nil {
+		status = "failure"
+		c.Logger.Error(fmt.Sprintf("Docker discovery error: %s", err.Error()))
+	}
+	c.responseCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+	c.Healthcheck.RecordReconciliation(c.source(), err)
+}
+
+// source returns the source of the healthchecks of the discovery
+func (c *DockerDiscovery) source() string {
+	return fmt.Sprintf("%s-%s", healthcheck.SourceDockerDiscovery, c.Config.Name)
+}
+
+// Start starts the Docker discovery component
+func (c *DockerDiscovery) Start() error {
+	c.tick = time.NewTicker(time.Duration(c.Config.Interval))
+	c.Healthcheck.RegisterSource(c.source())
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.t.Go(func() error {
+		c.Logger.Info(fmt.Sprintf("Starting the Docker healthcheck discovery %s", c.Config.Name))
+		for {
+			c.reconcileAndCount()
+			select {
+			case <-c.tick.C:
+			case <-c.trigger:
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	c.t.Go(func() error {
+		for {
+			err := c.watchEvents(ctx)
+			select {
+			case <-c.t.Dying():
+				return nil
+			default:
+			}
+			c.Logger.Error(fmt.Sprintf("Docker discovery: events stream error: %s", err.Error()))
+			// the containers may have changed while the stream was down
+			select {
+			case c.trigger <- struct{}{}:
+			default:
+			}
+			select {
+			case <-time.After(5 * time.Second):
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops the Docker discovery component
+func (c *DockerDiscovery) Stop() error {
+	c.Logger.Info("Stopping the Docker discovery")
+	c.tick.Stop()
+	c.t.Kill(nil)
+	c.cancel()
+	return c.t.Wait()
+}
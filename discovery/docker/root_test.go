@@ -0,0 +1,132 @@
+package docker
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestReconcile(t *testing.T) {
+	containers := `[
+  {"Id": "0123456789abcdef", "Names": ["/api"],
+   "Labels": {"cabourotte.port": "8080", "cabourotte.http.path": "/health"},
+   "NetworkSettings": {"Networks": {"bridge": {"IPAddress": "172.17.0.2"}}}},
+  {"Id": "abcdef0123456789", "Names": ["/db"],
+   "Labels": {"cabourotte.port": "5432", "cabourotte.type": "tcp"},
+   "NetworkSettings": {"Networks": {"bridge": {"IPAddress": "172.17.0.3"}}}},
+  {"Id": "fedcba9876543210", "Names": ["/invalid"],
+   "Labels": {"cabourotte.port": "abc"},
+   "NetworkSettings": {"Networks": {"bridge": {"IPAddress": "172.17.0.4"}}}}]`
+	events := make(chan string, 1)
+	var listings int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			atomic.AddInt32(&listings, 1)
+			_, err := w.Write([]byte(containers))
+			if err != nil {
+				t.Fatalf("Error writing body:\n%v", err)
+			}
+		case "/events":
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case e := <-events:
+					_, err := w.Write([]byte(e))
+					if err != nil {
+						return
+					}
+					w.(http.Flusher).Flush()
+				case <-r.Context().Done():
+					return
+				}
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Fail to listen on the unix socket\n%v", err)
+	}
+	ts.Listener = listener
+	ts.Start()
+	defer ts.Close()
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), promComponent, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "docker_discovery_requests_total",
+			Help: "Count the number of Docker discovery reconciliations.",
+		},
+		[]string{"status", "name"})
+	config := Configuration{
+		Name:     "test",
+		Host:     "unix://" + socket,
+		Interval: healthcheck.Duration(time.Hour),
+	}
+	discovery, err := New(logger, &config, checkComponent, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the Docker discovery component :\n%v", err)
+	}
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("Docker discovery failed\n%v", err)
+	}
+	checks := make(map[string]interface{})
+	for _, check := range checkComponent.ListChecks() {
+		checks[check.Base().Name] = check.GetConfig()
+	}
+	if len(checks) != 2 {
+		t.Fatalf("Expected 2 configured healthchecks, got %v", checks)
+	}
+	httpCheck, ok := checks["docker-api"].(*healthcheck.HTTPHealthcheckConfiguration)
+	if !ok || httpCheck.Target != "172.17.0.2" || httpCheck.Port != 8080 || httpCheck.Path != "/health" {
+		t.Fatalf("Invalid HTTP healthcheck %v", checks["docker-api"])
+	}
+	tcpCheck, ok := checks["docker-db"].(*healthcheck.TCPHealthcheckConfiguration)
+	if !ok || tcpCheck.Target != "172.17.0.3" || tcpCheck.Port != 5432 {
+		t.Fatalf("Invalid TCP healthcheck %v", checks["docker-db"])
+	}
+
+	// a container event triggers a new reconciliation
+	err = discovery.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the Docker discovery\n%v", err)
+	}
+	waitListings := func(expected int32) {
+		deadline := time.Now().Add(2 * time.Second)
+		for atomic.LoadInt32(&listings) < expected && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if atomic.LoadInt32(&listings) < expected {
+			t.Fatalf("Expected %d containers listings, got %d", expected, atomic.LoadInt32(&listings))
+		}
+	}
+	// initial reconciliation
+	waitListings(2)
+	events <- `{"Type": "container", "Action": "start"}` + "\n"
+	waitListings(3)
+	err = discovery.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the Docker discovery\n%v", err)
+	}
+}
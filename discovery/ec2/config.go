@@ -0,0 +1,79 @@
+package ec2
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+const (
+	// DefaultInterval the default interval between two instances listings
+	DefaultInterval = healthcheck.Duration(60 * time.Second)
+	// AddressPrivate use the instances private IP
+	AddressPrivate = "private"
+	// AddressPublic use the instances public IP
+	AddressPublic = "public"
+)
+
+// Configuration the EC2 discovery configuration.
+// The running instances matching the tags are listed periodically and an
+// healthcheck is created for each instance from the check template. The
+// template target is replaced by the instance IP, and the template name is
+// used as prefix for the healthchecks names.
+// The credentials are read from the configuration, then from the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+// environment variables, then from the instance metadata service.
+type Configuration struct {
+	Name      string
+	Region    string
+	Endpoint  string
+	AccessKey string            `json:"access-key" yaml:"access-key"`
+	SecretKey string            `json:"secret-key" yaml:"secret-key"`
+	Tags      map[string]string `json:"tags"`
+	Address   string
+	Interval  healthcheck.Duration                      `json:"interval"`
+	Labels    map[string]string                         `json:"labels,omitempty"`
+	HTTPCheck *healthcheck.HTTPHealthcheckConfiguration `json:"http-check,omitempty" yaml:"http-check,omitempty"`
+	TCPCheck  *healthcheck.TCPHealthcheckConfiguration  `json:"tcp-check,omitempty" yaml:"tcp-check,omitempty"`
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read EC2 discovery configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid EC2 discovery name configuration")
+	}
+	if raw.Region == "" {
+		raw.Region = os.Getenv("AWS_REGION")
+	}
+	if raw.Region == "" {
+		return errors.New("The EC2 discovery region is missing")
+	}
+	if (raw.AccessKey == "") != (raw.SecretKey == "") {
+		return errors.New("The EC2 discovery access-key and secret-key should be set together")
+	}
+	if raw.Address == "" {
+		raw.Address = AddressPrivate
+	}
+	if raw.Address != AddressPrivate && raw.Address != AddressPublic {
+		return errors.New("The EC2 discovery address should be private or public")
+	}
+	if raw.Interval == 0 {
+		raw.Interval = DefaultInterval
+	}
+	if raw.Interval < healthcheck.Duration(10*time.Second) {
+		return errors.New("The EC2 discovery interval should be greater or equal than 10 seconds")
+	}
+	if (raw.HTTPCheck == nil) == (raw.TCPCheck == nil) {
+		return errors.New("The EC2 discovery needs exactly one check template (http-check or tcp-check)")
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
@@ -0,0 +1,307 @@
+package ec2
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// metadataEndpoint the instance metadata service address
+const metadataEndpoint = "http://169.254.169.254"
+
+type instance struct {
+	InstanceID       string `xml:"instanceId"`
+	PrivateIPAddress string `xml:"privateIpAddress"`
+	IPAddress        string `xml:"ipAddress"`
+}
+
+type describeInstancesResponse struct {
+	Reservations []struct {
+		Instances []instance `xml:"instancesSet>item"`
+	} `xml:"reservationSet>item"`
+	NextToken string `xml:"nextToken"`
+}
+
+// EC2Discovery the EC2 discovery struct
+type EC2Discovery struct {
+	Logger           *zap.Logger
+	responseCounter  *prom.CounterVec
+	Healthcheck      *healthcheck.Component
+	Config           *Configuration
+	Client           *http.Client
+	Endpoint         string
+	metadataEndpoint string
+	credsLock        sync.Mutex
+	creds            credentials
+	t                tomb.Tomb
+	tick             *time.Ticker
+}
+
+// New creates a new EC2 discovery
+func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, counter *prom.CounterVec) *EC2Discovery {
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://ec2.%s.amazonaws.com", config.Region)
+	}
+	return &EC2Discovery{
+		Logger:           logger,
+		responseCounter:  counter,
+		Healthcheck:      checkComponent,
+		Config:           config,
+		Endpoint:         strings.TrimSuffix(endpoint, "/"),
+		metadataEndpoint: metadataEndpoint,
+		Client: &http.Client{
+			Timeout: time.Second * 10,
+		},
+	}
+}
+
+// metadata sends a request to the instance metadata service (IMDSv2)
+func (c *EC2Discovery) metadata(ctx context.Context, token string, path string) (string, error) {
+	method := "GET"
+	if path == "/latest/api/token" {
+		method = "PUT"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.metadataEndpoint+path, nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "EC2 discovery: fail to create the metadata request")
+	}
+	if token == "" {
+		req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	} else {
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "EC2 discovery: fail to send the metadata request")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrapf(err, "Fail to read request body")
+	}
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("EC2 discovery: metadata request on %s failed, status %d", path, resp.StatusCode)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// credentials returns the credentials used to sign the requests
+func (c *EC2Discovery) credentials(ctx context.Context) (credentials, error) {
+	if c.Config.AccessKey != "" {
+		return credentials{AccessKey: c.Config.AccessKey, SecretKey: c.Config.SecretKey}, nil
+	}
+	if os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		return credentials{
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+	c.credsLock.Lock()
+	defer c.credsLock.Unlock()
+	// the instance profile credentials are renewed before their expiration
+	if c.creds.AccessKey != "" && time.Now().Add(5*time.Minute).Before(c.creds.Expiration) {
+		return c.creds, nil
+	}
+	token, err := c.metadata(ctx, "", "/latest/api/token")
+	if err != nil {
+		return credentials{}, err
+	}
+	role, err := c.metadata(ctx, token, "/latest/meta-data/iam/security-credentials/")
+	if err != nil {
+		return credentials{}, err
+	}
+	role = strings.Split(role, "\n")[0]
+	payload, err := c.metadata(ctx, token, "/latest/meta-data/iam/security-credentials/"+role)
+	if err != nil {
+		return credentials{}, err
+	}
+	var result struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.Unmarshal([]byte(payload), &result); err != nil {
+		return credentials{}, errors.Wrapf(err, "EC2 discovery: fail to read the instance profile credentials")
+	}
+	c.creds = credentials{
+		AccessKey:    result.AccessKeyID,
+		SecretKey:    result.SecretAccessKey,
+		SessionToken: result.Token,
+		Expiration:   result.Expiration,
+	}
+	return c.creds, nil
+}
+
+// describeInstances lists the running instances matching the tags
+func (c *EC2Discovery) describeInstances(ctx context.Context) ([]instance, error) {
+	creds, err := c.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("Action", "DescribeInstances")
+	query.Set("Version", "2016-11-15")
+	query.Set("Filter.1.Name", "instance-state-name")
+	query.Set("Filter.1.Value.1", "running")
+	tags := make([]string, 0, len(c.Config.Tags))
+	for k := range c.Config.Tags {
+		tags = append(tags, k)
+	}
+	sort.Strings(tags)
+	for i, k := range tags {
+		query.Set(fmt.Sprintf("Filter.%d.Name", i+2), "tag:"+k)
+		query.Set(fmt.Sprintf("Filter.%d.Value.1", i+2), c.Config.Tags[k])
+	}
+	var instances []instance
+	for {
+		req, err := http.NewRequestWithContext(ctx, "GET", c.Endpoint+"/?"+canonicalQuery(query), nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "EC2 discovery: fail to create the request")
+		}
+		req.Header.Set("User-Agent", "Cabourotte")
+		sign(req, creds, c.Config.Region, "ec2", time.Now())
+		resp, err := c.Client.Do(req)
+		if err != nil {
+			return nil, errors.Wrapf(err, "EC2 discovery: fail to send the request")
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to read request body")
+		}
+		if resp.StatusCode != 200 {
+			return nil, fmt.Errorf("EC2 discovery: request failed, status %d, body %s", resp.StatusCode, string(body))
+		}
+		var result describeInstancesResponse
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, errors.Wrapf(err, "EC2 discovery: fail to convert the payload from xml")
+		}
+		for _, reservation := range result.Reservations {
+			instances = append(instances, reservation.Instances...)
+		}
+		if result.NextToken == "" {
+			return instances, nil
+		}
+		query.Set("NextToken", result.NextToken)
+	}
+}
+
+// reconcile lists the instances and reloads the healthchecks
+func (c *EC2Discovery) reconcile() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	instances, err := c.describeInstances(ctx)
+	if err != nil {
+		return err
+	}
+	var tcpChecks []healthcheck.TCPHealthcheckConfiguration
+	var httpChecks []healthcheck.HTTPHealthcheckConfiguration
+	for _, i := range instances {
+		target := i.PrivateIPAddress
+		if c.Config.Address == AddressPublic {
+			target = i.IPAddress
+		}
+		if target == "" {
+			continue
+		}
+		labels := map[string]string{
+			"instance_id": i.InstanceID,
+		}
+		if c.Config.HTTPCheck != nil {
+			config := c.Config.HTTPCheck.DeepCopy()
+			config.Base.Name = c.checkName(config.Base.Name, i.InstanceID)
+			config.Target = target
+			healthcheck.MergeLabels(&config.Base, labels)
+			if err := config.Validate(); err != nil {
+				return errors.Wrapf(err, "Invalid healthcheck template for the instance %s", i.InstanceID)
+			}
+			httpChecks = append(httpChecks, *config)
+		}
+		if c.Config.TCPCheck != nil {
+			config := c.Config.TCPCheck.DeepCopy()
+			config.Base.Name = c.checkName(config.Base.Name, i.InstanceID)
+			config.Target = target
+			healthcheck.MergeLabels(&config.Base, labels)
+			if err := config.Validate(); err != nil {
+				return errors.Wrapf(err, "Invalid healthcheck template for the instance %s", i.InstanceID)
+			}
+			tcpChecks = append(tcpChecks, *config)
+		}
+	}
+	return c.Healthcheck.ReloadForSource(
+		c.source(),
+		c.Config.Labels,
+		nil,
+		nil,
+		tcpChecks,
+		httpChecks,
+		nil,
+		nil,
+		nil,
+		nil)
+}
+
+// checkName builds the name of the healthcheck for an instance
+func (c *EC2Discovery) checkName(prefix string, instanceID string) string {
+	if prefix == "" {
+		prefix = c.Config.Name
+	}
+	return fmt.Sprintf("%s-%s", prefix, instanceID)
+}
+
+// source returns the source of the healthchecks of the discovery
+func (c *EC2Discovery) source() string {
+	return fmt.Sprintf("%s-%s", healthcheck.SourceEC2Discovery, c.Config.Name)
+}
+
+// Start starts the EC2 discovery component
+func (c *EC2Discovery) Start() error {
+	c.tick = time.NewTicker(time.Duration(c.Config.Interval))
+	c.Healthcheck.RegisterSource(c.source())
+	c.t.Go(func() error {
+		c.Logger.Info(fmt.Sprintf("Starting the EC2 healthcheck discovery %s", c.Config.Name))
+		for {
+			status := "success"
+			err := c.reconcile()
+			if err != nil {
+				status = "failure"
+				c.Logger.Error(fmt.Sprintf("EC2 discovery error: %s", err.Error()))
+			}
+			c.responseCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+			c.Healthcheck.RecordReconciliation(c.source(), err)
+			select {
+			case <-c.tick.C:
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops the EC2 discovery component
+func (c *EC2Discovery) Stop() error {
+	c.Logger.Info("Stopping the EC2 discovery")
+	c.tick.Stop()
+	c.t.Kill(nil)
+	return c.t.Wait()
+}
@@ -0,0 +1,142 @@
+package ec2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+const firstPage = `<?xml version="1.0" encoding="UTF-8"?>
+<DescribeInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <reservationSet>
+    <item>
+      <instancesSet>
+        <item>
+          <instanceId>i-1</instanceId>
+          <privateIpAddress>10.0.0.1</privateIpAddress>
+          <ipAddress>1.2.3.4</ipAddress>
+        </item>
+      </instancesSet>
+    </item>
+  </reservationSet>
+  <nextToken>page2</nextToken>
+</DescribeInstancesResponse>`
+
+const secondPage = `<?xml version="1.0" encoding="UTF-8"?>
+<DescribeInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <reservationSet>
+    <item>
+      <instancesSet>
+        <item>
+          <instanceId>i-2</instanceId>
+          <privateIpAddress>10.0.0.2</privateIpAddress>
+        </item>
+      </instancesSet>
+    </item>
+  </reservationSet>
+</DescribeInstancesResponse>`
+
+func TestReconcile(t *testing.T) {
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		switch r.URL.Path {
+		case "/latest/api/token":
+			body = "imds-token"
+		case "/latest/meta-data/iam/security-credentials/":
+			body = "role"
+		case "/latest/meta-data/iam/security-credentials/role":
+			body = `{"AccessKeyId": "AKID", "SecretAccessKey": "secret", "Token": "session", "Expiration": "` +
+				time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`
+		}
+		if r.URL.Path != "/latest/api/token" && r.Header.Get("X-aws-ec2-metadata-token") != "imds-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatalf("Error writing body:\n%v", err)
+		}
+	}))
+	defer metadata.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			r.Header.Get("X-Amz-Security-Token") != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if query.Get("Filter.2.Name") != "tag:env" || query.Get("Filter.2.Value.1") != "prod" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		page := firstPage
+		if query.Get("NextToken") == "page2" {
+			page = secondPage
+		}
+		_, err := w.Write([]byte(page))
+		if err != nil {
+			t.Fatalf("Error writing body:\n%v", err)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), promComponent, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "ec2_discovery_requests_total",
+			Help: "Count the number of EC2 discovery reconciliations.",
+		},
+		[]string{"status", "name"})
+	config := Configuration{
+		Name:     "test",
+		Region:   "eu-west-1",
+		Endpoint: ts.URL,
+		Tags:     map[string]string{"env": "prod"},
+		Address:  AddressPrivate,
+		Interval: DefaultInterval,
+		TCPCheck: &healthcheck.TCPHealthcheckConfiguration{
+			Base: healthcheck.Base{
+				Name:     "ssh",
+				Interval: healthcheck.Duration(10 * time.Second),
+			},
+			Port:    22,
+			Timeout: healthcheck.Duration(5 * time.Second),
+		},
+	}
+	discovery := New(logger, &config, checkComponent, counter)
+	discovery.metadataEndpoint = metadata.URL
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("EC2 discovery failed\n%v", err)
+	}
+	checks := make(map[string]*healthcheck.TCPHealthcheckConfiguration)
+	for _, check := range checkComponent.ListChecks() {
+		checks[check.Base().Name] = check.GetConfig().(*healthcheck.TCPHealthcheckConfiguration)
+	}
+	if len(checks) != 2 {
+		t.Fatalf("Expected 2 configured healthchecks, got %v", checks)
+	}
+	check, ok := checks["ssh-i-1"]
+	if !ok || check.Target != "10.0.0.1" || check.Port != 22 || check.Base.Labels["instance_id"] != "i-1" {
+		t.Fatalf("Invalid healthcheck %v", check)
+	}
+	if checks["ssh-i-2"].Target != "10.0.0.2" {
+		t.Fatalf("Invalid healthcheck %v", checks["ssh-i-2"])
+	}
+}
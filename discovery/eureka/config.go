@@ -0,0 +1,81 @@
+package eureka
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+const (
+	// DefaultInterval the default interval between two registry listings
+	DefaultInterval = healthcheck.Duration(30 * time.Second)
+	// CheckHealthURL probe the instances health check URL
+	CheckHealthURL = "health-url"
+	// CheckTCP probe the instances port
+	CheckTCP = "tcp"
+)
+
+// CheckConfiguration the healthchecks created for the instances.
+// By default, the health check URL registered by the instances is probed.
+type CheckConfiguration struct {
+	Type        string
+	ValidStatus healthcheck.StatusCodes `json:"valid-status" yaml:"valid-status"`
+	Interval    healthcheck.Duration    `json:"interval"`
+	Timeout     healthcheck.Duration    `json:"timeout"`
+}
+
+// Configuration the Eureka discovery configuration.
+// Only the instances with the UP status are probed.
+type Configuration struct {
+	Name         string
+	URL          string
+	Username     string
+	Password     string
+	Applications []string
+	Check        CheckConfiguration
+	Interval     healthcheck.Duration `json:"interval"`
+	Labels       map[string]string    `json:"labels,omitempty"`
+	Key          string               `json:"key,omitempty"`
+	Cert         string               `json:"cert,omitempty"`
+	Cacert       string               `json:"cacert,omitempty"`
+	Insecure     bool
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read Eureka discovery configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid Eureka discovery name configuration")
+	}
+	if raw.URL == "" {
+		return errors.New("The Eureka discovery URL is missing")
+	}
+	if len(raw.Applications) == 0 {
+		return errors.New("The Eureka discovery applications are missing")
+	}
+	if raw.Interval == 0 {
+		raw.Interval = DefaultInterval
+	}
+	if raw.Interval < healthcheck.Duration(10*time.Second) {
+		return errors.New("The Eureka discovery interval should be greater or equal than 10 seconds")
+	}
+	if raw.Check.Type == "" {
+		raw.Check.Type = CheckHealthURL
+	}
+	if raw.Check.Type != CheckHealthURL && raw.Check.Type != CheckTCP {
+		return fmt.Errorf("Invalid Eureka discovery check type %s", raw.Check.Type)
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
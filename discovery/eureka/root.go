@@ -0,0 +1,298 @@
+package eureka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/tls"
+)
+
+const (
+	defaultCheckInterval = healthcheck.Duration(10 * time.Second)
+	defaultCheckTimeout  = healthcheck.Duration(5 * time.Second)
+)
+
+type port struct {
+	Port    uint   `json:"$"`
+	Enabled string `json:"@enabled"`
+}
+
+type instance struct {
+	InstanceID     string `json:"instanceId"`
+	HostName       string `json:"hostName"`
+	IPAddr         string `json:"ipAddr"`
+	Status         string `json:"status"`
+	Port           port   `json:"port"`
+	SecurePort     port   `json:"securePort"`
+	HealthCheckURL string `json:"healthCheckUrl"`
+}
+
+type application struct {
+	Application struct {
+		Name string `json:"name"`
+		// Eureka returns an object instead of a list when the application
+		// has only one instance
+		Instance json.RawMessage `json:"instance"`
+	} `json:"application"`
+}
+
+// instances decodes the instances of an application
+func (a *application) instances() ([]instance, error) {
+	raw := strings.TrimSpace(string(a.Application.Instance))
+	if raw == "" || raw == "null" {
+		return nil, nil
+	}
+	if strings.HasPrefix(raw, "{") {
+		var i instance
+		if err := json.Unmarshal([]byte(raw), &i); err != nil {
+			return nil, err
+		}
+		return []instance{i}, nil
+	}
+	var result []instance
+	if err := json.Unmarshal([]byte(raw), &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// EurekaDiscovery the Eureka discovery struct
+type EurekaDiscovery struct {
+	Logger          *zap.Logger
+	responseCounter *prom.CounterVec
+	Healthcheck     *healthcheck.Component
+	Config          *Configuration
+	Client          *http.Client
+	URL             string
+	t               tomb.Tomb
+	tick            *time.Ticker
+}
+
+// New creates a new Eureka discovery
+func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, counter *prom.CounterVec) (*EurekaDiscovery, error) {
+	tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, "", config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	component := EurekaDiscovery{
+		Logger:          logger,
+		responseCounter: counter,
+		Healthcheck:     checkComponent,
+		Config:          config,
+		URL:             strings.TrimSuffix(config.URL, "/"),
+		Client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+			Timeout: time.Second * 10,
+		},
+	}
+	return &component, nil
+}
+
+// getApplication fetches the instances of an application
+func (c *EurekaDiscovery) getApplication(ctx context.Context, name string) ([]instance, error) {
+	reqURL := fmt.Sprintf("%s/apps/%s", c.URL, url.PathEscape(strings.ToUpper(name)))
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Eureka discovery: fail to create request for %s", reqURL)
+	}
+	req.Header.Set("User-Agent", "Cabourotte")
+	req.Header.Set("Accept", "application/json")
+	if c.Config.Username != "" {
+		req.SetBasicAuth(c.Config.Username, c.Config.Password)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Eureka discovery: fail to send request to %s", reqURL)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read request body")
+	}
+	// the application is not registered
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Eureka discovery: request to %s failed, status %d, body %s", reqURL, resp.StatusCode, string(body))
+	}
+	var app application
+	if err := json.Unmarshal(body, &app); err != nil {
+		return nil, errors.Wrapf(err, "Eureka discovery: fail to convert the payload from json")
+	}
+	instances, err := app.instances()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Eureka discovery: fail to read the instances of %s", name)
+	}
+	return instances, nil
+}
+
+// healthURLCheck builds an HTTP healthcheck from the instance health
+// check URL
+func (c *EurekaDiscovery) healthURLCheck(base healthcheck.Base, timeout healthcheck.Duration, i instance) (*healthcheck.HTTPHealthcheckConfiguration, error) {
+	if i.HealthCheckURL == "" {
+		return nil, errors.New("No health check URL registered")
+	}
+	healthURL, err := url.Parse(i.HealthCheckURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid health check URL %s", i.HealthCheckURL)
+	}
+	config := healthcheck.HTTPHealthcheckConfiguration{
+		Base:        base,
+		Target:      healthURL.Hostname(),
+		Path:        healthURL.Path,
+		Protocol:    healthcheck.HTTP,
+		ValidStatus: c.Config.Check.ValidStatus,
+		Timeout:     timeout,
+	}
+	if config.Path == "" {
+		config.Path = "/"
+	}
+	port := uint64(80)
+	if healthURL.Scheme == "https" {
+		config.Protocol = healthcheck.HTTPS
+		port = 443
+	}
+	if healthURL.Port() != "" {
+		port, err = strconv.ParseUint(healthURL.Port(), 10, 16)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid port in the health check URL %s", i.HealthCheckURL)
+		}
+	}
+	config.Port = uint(port)
+	if len(healthURL.Query()) != 0 {
+		config.Query = make(map[string]string)
+		for k := range healthURL.Query() {
+			config.Query[k] = healthURL.Query().Get(k)
+		}
+	}
+	if len(config.ValidStatus) == 0 {
+		config.ValidStatus = []uint{200}
+	}
+	return &config, nil
+}
+
+// reconcile lists the applications instances and reloads the healthchecks
+func (c *EurekaDiscovery) reconcile() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var tcpChecks []healthcheck.TCPHealthcheckConfiguration
+	var httpChecks []healthcheck.HTTPHealthcheckConfiguration
+	timeout := c.Config.Check.Timeout
+	if timeout == 0 {
+		timeout = defaultCheckTimeout
+	}
+	for _, app := range c.Config.Applications {
+		instances, err := c.getApplication(ctx, app)
+		if err != nil {
+			return err
+		}
+		for _, i := range instances {
+			if i.Status != "UP" {
+				continue
+			}
+			base := healthcheck.Base{
+				Name:     fmt.Sprintf("eureka-%s-%s", strings.ToLower(app), i.InstanceID),
+				Interval: c.Config.Check.Interval,
+				Labels: map[string]string{
+					"application": strings.ToLower(app),
+					"instance":    i.InstanceID,
+				},
+			}
+			if base.Interval == 0 {
+				base.Interval = defaultCheckInterval
+			}
+			if c.Config.Check.Type == CheckTCP {
+				config := healthcheck.TCPHealthcheckConfiguration{
+					Base:    base,
+					Target:  i.IPAddr,
+					Port:    i.Port.Port,
+					Timeout: timeout,
+				}
+				if i.SecurePort.Enabled == "true" {
+					config.Port = i.SecurePort.Port
+				}
+				err = config.Validate()
+				if err == nil {
+					tcpChecks = append(tcpChecks, config)
+				}
+			} else {
+				var config *healthcheck.HTTPHealthcheckConfiguration
+				config, err = c.healthURLCheck(base, timeout, i)
+				if err == nil {
+					err = config.Validate()
+				}
+				if err == nil {
+					httpChecks = append(httpChecks, *config)
+				}
+			}
+			if err != nil {
+				c.Logger.Error(fmt.Sprintf("Eureka discovery: invalid healthcheck for the instance %s of %s: %s", i.InstanceID, app, err.Error()))
+			}
+		}
+	}
+	return c.Healthcheck.ReloadForSource(
+		c.source(),
+		c.Config.Labels,
+		nil,
+		nil,
+		tcpChecks,
+		httpChecks,
+		nil,
+		nil,
+		nil,
+		nil)
+}
+
+// source returns the source of the healthchecks of the discovery
+func (c *EurekaDiscovery) source() string {
+	return fmt.Sprintf("%s-%s", healthcheck.SourceEurekaDiscovery, c.Config.Name)
+}
+
+// Start starts the Eureka discovery component
+func (c *EurekaDiscovery) Start() error {
+	c.tick = time.NewTicker(time.Duration(c.Config.Interval))
+	c.Healthcheck.RegisterSource(c.source())
+	c.t.Go(func() error {
+		c.Logger.Info(fmt.Sprintf("Starting the Eureka healthcheck discovery %s", c.Config.Name))
+		for {
+			status := "success"
+			err := c.reconcile()
+			if err != nil {
+				status = "failure"
+				c.Logger.Error(fmt.Sprintf("Eureka discovery error: %s", err.Error()))
+			}
+			c.responseCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+			c.Healthcheck.RecordReconciliation(c.source(), err)
+			select {
+			case <-c.tick.C:
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops the Eureka discovery component
+func (c *EurekaDiscovery) Stop() error {
+	c.Logger.Info("Stopping the Eureka discovery")
+	c.tick.Stop()
+	c.t.Kill(nil)
+	return c.t.Wait()
+}
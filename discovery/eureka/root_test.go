@@ -0,0 +1,108 @@
+package eureka
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestReconcile(t *testing.T) {
+	payloads := map[string]string{
+		"/eureka/apps/API": `{"application": {"name": "API", "instance": [
+  {"instanceId": "api-1", "hostName": "api-1.local", "ipAddr": "10.0.0.1", "status": "UP",
+   "port": {"$": 8080, "@enabled": "true"}, "securePort": {"$": 443, "@enabled": "false"},
+   "healthCheckUrl": "http://api-1.local:8080/actuator/health"},
+  {"instanceId": "api-2", "hostName": "api-2.local", "ipAddr": "10.0.0.2", "status": "DOWN",
+   "port": {"$": 8080, "@enabled": "true"},
+   "healthCheckUrl": "http://api-2.local:8080/actuator/health"}]}}`,
+		"/eureka/apps/WEB": `{"application": {"name": "WEB", "instance":
+  {"instanceId": "web-1", "hostName": "web-1.local", "ipAddr": "10.0.1.1", "status": "UP",
+   "port": {"$": 80, "@enabled": "true"},
+   "healthCheckUrl": "https://web-1.local/health?full=true"}}}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		payload, ok := payloads[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, err := w.Write([]byte(payload))
+		if err != nil {
+			t.Fatalf("Error writing body:\n%v", err)
+		}
+	}))
+	defer ts.Close()
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), promComponent, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "eureka_discovery_requests_total",
+			Help: "Count the number of Eureka discovery reconciliations.",
+		},
+		[]string{"status", "name"})
+	config := Configuration{
+		Name:         "test",
+		URL:          ts.URL + "/eureka/",
+		Username:     "user",
+		Password:     "pass",
+		Applications: []string{"api", "web", "unknown"},
+		Check:        CheckConfiguration{Type: CheckHealthURL},
+		Interval:     DefaultInterval,
+	}
+	discovery, err := New(logger, &config, checkComponent, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the Eureka discovery component :\n%v", err)
+	}
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("Eureka discovery failed\n%v", err)
+	}
+	checks := make(map[string]*healthcheck.HTTPHealthcheckConfiguration)
+	for _, check := range checkComponent.ListChecks() {
+		checks[check.Base().Name] = check.GetConfig().(*healthcheck.HTTPHealthcheckConfiguration)
+	}
+	if len(checks) != 2 {
+		t.Fatalf("Expected 2 configured healthchecks, got %v", checks)
+	}
+	api := checks["eureka-api-api-1"]
+	if api == nil || api.Target != "api-1.local" || api.Port != 8080 || api.Path != "/actuator/health" || api.Protocol != healthcheck.HTTP {
+		t.Fatalf("Invalid healthcheck %v", api)
+	}
+	web := checks["eureka-web-web-1"]
+	if web == nil || web.Port != 443 || web.Protocol != healthcheck.HTTPS || web.Query["full"] != "true" {
+		t.Fatalf("Invalid healthcheck %v", web)
+	}
+
+	config.Check.Type = CheckTCP
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("Eureka discovery failed\n%v", err)
+	}
+	for _, check := range checkComponent.ListChecks() {
+		tcp, ok := check.GetConfig().(*healthcheck.TCPHealthcheckConfiguration)
+		if !ok {
+			t.Fatalf("Expected a TCP healthcheck, got %v", check.GetConfig())
+		}
+		if tcp.Base.Name == "eureka-api-api-1" && (tcp.Target != "10.0.0.1" || tcp.Port != 8080) {
+			t.Fatalf("Invalid healthcheck %v", tcp)
+		}
+	}
+}
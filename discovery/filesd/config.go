@@ -0,0 +1,92 @@
+package filesd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+const (
+	// DefaultInterval the default interval between two reads of the files
+	DefaultInterval = healthcheck.Duration(30 * time.Second)
+	// ModuleLabel the label of the targets groups selecting the module,
+	// as in the blackbox_exporter relabeling configurations
+	ModuleLabel = "__param_module"
+)
+
+// Module the healthcheck template of a module. The template target and
+// port are replaced by the target of the file, the template name being
+// used as prefix for the healthchecks names.
+type Module struct {
+	HTTPCheck *healthcheck.HTTPHealthcheckConfiguration `json:"http-check,omitempty" yaml:"http-check,omitempty"`
+	TCPCheck  *healthcheck.TCPHealthcheckConfiguration  `json:"tcp-check,omitempty" yaml:"tcp-check,omitempty"`
+	TLSCheck  *healthcheck.TLSHealthcheckConfiguration  `json:"tls-check,omitempty" yaml:"tls-check,omitempty"`
+}
+
+// Configuration the Prometheus file_sd discovery configuration.
+// The files contain targets groups in the Prometheus file_sd format (JSON
+// or YAML), and an healthcheck is created for each target from the
+// template of the module of its group. The files are read periodically,
+// and the healthchecks are reconciled when they change.
+type Configuration struct {
+	Name string
+	// Files the paths of the files, glob patterns are supported
+	Files    []string
+	Interval healthcheck.Duration `json:"interval"`
+	Labels   map[string]string    `json:"labels,omitempty"`
+	// Modules the healthchecks templates, by blackbox_exporter module
+	// name
+	Modules map[string]Module
+	// DefaultModule the module of the groups without __param_module
+	// label
+	DefaultModule string `json:"default-module,omitempty" yaml:"default-module,omitempty"`
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read file_sd discovery configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid file_sd discovery name configuration")
+	}
+	if len(raw.Files) == 0 {
+		return errors.New("The file_sd discovery files are missing")
+	}
+	if raw.Interval == 0 {
+		raw.Interval = DefaultInterval
+	}
+	if raw.Interval < healthcheck.Duration(5*time.Second) {
+		return errors.New("The file_sd discovery interval should be greater or equal than 5 seconds")
+	}
+	if len(raw.Modules) == 0 {
+		return errors.New("The file_sd discovery modules are missing")
+	}
+	for name, module := range raw.Modules {
+		templates := 0
+		if module.HTTPCheck != nil {
+			templates++
+		}
+		if module.TCPCheck != nil {
+			templates++
+		}
+		if module.TLSCheck != nil {
+			templates++
+		}
+		if templates != 1 {
+			return fmt.Errorf("The file_sd discovery module %s needs exactly one check template (http-check, tcp-check or tls-check)", name)
+		}
+	}
+	if raw.DefaultModule != "" {
+		if _, ok := raw.Modules[raw.DefaultModule]; !ok {
+			return fmt.Errorf("The file_sd discovery default module %s does not exist", raw.DefaultModule)
+		}
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
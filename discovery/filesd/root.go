@@ -0,0 +1,316 @@
+package filesd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// TargetGroup a group of targets of a Prometheus file_sd file
+type TargetGroup struct {
+	Targets []string          `json:"targets" yaml:"targets"`
+	Labels  map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// nameRegexp the characters of the targets replaced in the healthchecks
+// names
+var nameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// FileSDDiscovery the Prometheus file_sd discovery struct
+type FileSDDiscovery struct {
+	Logger          *zap.Logger
+	responseCounter *prom.CounterVec
+	Healthcheck     *healthcheck.Component
+	Config          *Configuration
+	// hash the hash of the files content of the last successful
+	// reconciliation
+	hash []byte
+	t    tomb.Tomb
+	tick *time.Ticker
+}
+
+// New creates a new Prometheus file_sd discovery
+func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, counter *prom.CounterVec) *FileSDDiscovery {
+	return &FileSDDiscovery{
+		Logger:          logger,
+		responseCounter: counter,
+		Healthcheck:     checkComponent,
+		Config:          config,
+	}
+}
+
+// read reads the targets groups of the files, and returns them with the
+// hash of the files
+func (c *FileSDDiscovery) read() ([]TargetGroup, []byte, error) {
+	paths := make(map[string]bool)
+	for _, pattern := range c.Config.Files {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Invalid file_sd files pattern %s", pattern)
+		}
+		for _, match := range matches {
+			paths[match] = true
+		}
+	}
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+	hash := sha256.New()
+	var groups []TargetGroup
+	for _, path := range sorted {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Fail to read the file_sd file %s", path)
+		}
+		hash.Write([]byte(path))
+		hash.Write(content)
+		var fileGroups []TargetGroup
+		// the JSON files are also valid YAML
+		if err := yaml.Unmarshal(content, &fileGroups); err != nil {
+			return nil, nil, errors.Wrapf(err, "Invalid targets groups in the file_sd file %s", path)
+		}
+		groups = append(groups, fileGroups...)
+	}
+	return groups, hash.Sum(nil), nil
+}
+
+// checkName builds the name of the healthcheck of a target, the prefix
+// being the discovery and module names by default
+func (c *FileSDDiscovery) checkName(prefix string, module string, target string) string {
+	if prefix == "" {
+		prefix = fmt.Sprintf("%s-%s", c.Config.Name, module)
+	}
+	return fmt.Sprintf("%s-%s", prefix, strings.Trim(nameRegexp.ReplaceAllString(target, "-"), "-"))
+}
+
+// splitTarget returns the host and the port of a target, the default port
+// being used if the target has no port
+func splitTarget(target string, defaultPort uint) (string, uint, error) {
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		if defaultPort == 0 {
+			return "", 0, fmt.Errorf("The target %s has no port", target)
+		}
+		return strings.Trim(target, "[]"), defaultPort, nil
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("Invalid port for the target %s", target)
+	}
+	return host, uint(port), nil
+}
+
+// httpTarget configures the HTTP healthcheck for a target, which can be an
+// URL as for the blackbox_exporter http prober
+func httpTarget(config *healthcheck.HTTPHealthcheckConfiguration, target string) error {
+	if !strings.Contains(target, "://") {
+		host, port, err := splitTarget(target, config.Port)
+		if err != nil {
+			return err
+		}
+		config.Target = host
+		config.Port = port
+		return nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid target %s", target)
+	}
+	defaultPort := uint(80)
+	switch u.Scheme {
+	case "http":
+		config.Protocol = healthcheck.HTTP
+	case "https":
+		config.Protocol = healthcheck.HTTPS
+		defaultPort = 443
+	default:
+		return fmt.Errorf("Invalid scheme for the target %s", target)
+	}
+	config.Target = u.Hostname()
+	config.Port = defaultPort
+	if u.Port() != "" {
+		port, err := strconv.ParseUint(u.Port(), 10, 16)
+		if err != nil {
+			return fmt.Errorf("Invalid port for the target %s", target)
+		}
+		config.Port = uint(port)
+	}
+	if u.Path != "" {
+		config.Path = u.Path
+	}
+	if u.RawQuery != "" {
+		query := make(map[string]string)
+		for k, v := range config.Query {
+			query[k] = v
+		}
+		for k, values := range u.Query() {
+			query[k] = values[0]
+		}
+		config.Query = query
+	}
+	return nil
+}
+
+// checks builds the healthchecks of the targets groups
+func (c *FileSDDiscovery) checks(groups []TargetGroup) ([]healthcheck.TCPHealthcheckConfiguration, []healthcheck.HTTPHealthcheckConfiguration, []healthcheck.TLSHealthcheckConfiguration, error) {
+	var tcpChecks []healthcheck.TCPHealthcheckConfiguration
+	var httpChecks []healthcheck.HTTPHealthcheckConfiguration
+	var tlsChecks []healthcheck.TLSHealthcheckConfiguration
+	names := make(map[string]bool)
+	for _, group := range groups {
+		moduleName := group.Labels[ModuleLabel]
+		if moduleName == "" {
+			moduleName = c.Config.DefaultModule
+		}
+		if moduleName == "" {
+			return nil, nil, nil, fmt.Errorf("No module for the targets %s", strings.Join(group.Targets, ", "))
+		}
+		module, ok := c.Config.Modules[moduleName]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("The module %s of the targets %s does not exist", moduleName, strings.Join(group.Targets, ", "))
+		}
+		for _, target := range group.Targets {
+			// the labels starting with __ are internal, as in Prometheus
+			labels := make(map[string]string)
+			for k, v := range group.Labels {
+				if !strings.HasPrefix(k, "__") {
+					labels[k] = v
+				}
+			}
+			var name string
+			var err error
+			switch {
+			case module.HTTPCheck != nil:
+				config := module.HTTPCheck.DeepCopy()
+				config.Base.Name = c.checkName(config.Base.Name, moduleName, target)
+				name = config.Base.Name
+				if err = httpTarget(config, target); err == nil {
+					healthcheck.MergeLabels(&config.Base, labels)
+					err = config.Validate()
+				}
+				if err == nil && !names[name] {
+					httpChecks = append(httpChecks, *config)
+				}
+			case module.TCPCheck != nil:
+				config := module.TCPCheck.DeepCopy()
+				config.Base.Name = c.checkName(config.Base.Name, moduleName, target)
+				name = config.Base.Name
+				if config.Target, config.Port, err = splitTarget(target, config.Port); err == nil {
+					healthcheck.MergeLabels(&config.Base, labels)
+					err = config.Validate()
+				}
+				if err == nil && !names[name] {
+					tcpChecks = append(tcpChecks, *config)
+				}
+			case module.TLSCheck != nil:
+				config := module.TLSCheck.DeepCopy()
+				config.Base.Name = c.checkName(config.Base.Name, moduleName, target)
+				name = config.Base.Name
+				if config.Target, config.Port, err = splitTarget(target, config.Port); err == nil {
+					healthcheck.MergeLabels(&config.Base, labels)
+					err = config.Validate()
+				}
+				if err == nil && !names[name] {
+					tlsChecks = append(tlsChecks, *config)
+				}
+			}
+			if err != nil {
+				return nil, nil, nil, errors.Wrapf(err, "Invalid healthcheck for the target %s of the module %s", target, moduleName)
+			}
+			// a target listed several times is only checked once
+			names[name] = true
+		}
+	}
+	return tcpChecks, httpChecks, tlsChecks, nil
+}
+
+// reconcile reads the files and reloads the healthchecks if they changed.
+// It returns true if the healthchecks were reconciled.
+func (c *FileSDDiscovery) reconcile() (bool, error) {
+	groups, hash, err := c.read()
+	if err != nil {
+		return true, err
+	}
+	if c.hash != nil && bytes.Equal(hash, c.hash) {
+		return false, nil
+	}
+	tcpChecks, httpChecks, tlsChecks, err := c.checks(groups)
+	if err != nil {
+		return true, err
+	}
+	err = c.Healthcheck.ReloadForSource(
+		c.source(),
+		c.Config.Labels,
+		nil,
+		nil,
+		tcpChecks,
+		httpChecks,
+		tlsChecks,
+		nil,
+		nil,
+		nil)
+	if err != nil {
+		return true, err
+	}
+	c.hash = hash
+	return true, nil
+}
+
+// source returns the source of the healthchecks of the discovery
+func (c *FileSDDiscovery) source() string {
+	return fmt.Sprintf("%s-%s", healthcheck.SourceFileSDDiscovery, c.Config.Name)
+}
+
+// Start starts the Prometheus file_sd discovery component
+func (c *FileSDDiscovery) Start() error {
+	c.tick = time.NewTicker(time.Duration(c.Config.Interval))
+	c.Healthcheck.RegisterSource(c.source())
+	c.t.Go(func() error {
+		c.Logger.Info(fmt.Sprintf("Starting the file_sd healthcheck discovery %s", c.Config.Name))
+		for {
+			reconciled, err := c.reconcile()
+			if reconciled {
+				status := "success"
+				if err != nil {
+					status = "failure"
+					c.Logger.Error(fmt.Sprintf("file_sd discovery error: %s", err.Error()))
+				}
+				c.responseCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+				c.Healthcheck.RecordReconciliation(c.source(), err)
+			}
+			select {
+			case <-c.tick.C:
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops the Prometheus file_sd discovery component
+func (c *FileSDDiscovery) Stop() error {
+	c.Logger.Info("Stopping the file_sd discovery")
+	c.tick.Stop()
+	c.t.Kill(nil)
+	return c.t.Wait()
+}
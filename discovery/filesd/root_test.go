@@ -0,0 +1,168 @@
+package filesd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestUnmarshalConfiguration(t *testing.T) {
+	var config Configuration
+	err := yaml.Unmarshal([]byte(`
+name: blackbox
+files: ["/tmp/*.json"]
+modules:
+  tcp_connect:
+    tcp-check:
+      timeout: 5s
+`), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	if config.Interval != DefaultInterval || config.Modules["tcp_connect"].TCPCheck == nil {
+		t.Fatalf("Invalid configuration %+v", config)
+	}
+	cases := []string{
+		`name: blackbox
+modules:
+  tcp_connect:
+    tcp-check:
+      timeout: 5s`,
+		`name: blackbox
+files: ["/tmp/*.json"]`,
+		`name: blackbox
+files: ["/tmp/*.json"]
+modules:
+  tcp_connect: {}`,
+		`name: blackbox
+files: ["/tmp/*.json"]
+default-module: http_2xx
+modules:
+  tcp_connect:
+    tcp-check:
+      timeout: 5s`,
+	}
+	for _, c := range cases {
+		var config Configuration
+		if err := yaml.Unmarshal([]byte(c), &config); err == nil {
+			t.Fatalf("Was expecting an error for\n%s", c)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "targets.json")
+	err := os.WriteFile(path, []byte(`[
+  {"targets": ["https://a.example.com/health?verbose=1", "b.example.com:8080"], "labels": {"env": "prod", "__meta_foo": "bar"}},
+  {"targets": ["c.example.com:22", "c.example.com:22"], "labels": {"__param_module": "tcp_connect"}}
+]`), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the targets file\n%v", err)
+	}
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), promComponent, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "filesd_discovery_requests_total",
+			Help: "Count the number of file_sd discovery reconciliations.",
+		},
+		[]string{"status", "name"})
+	config := Configuration{
+		Name:          "blackbox",
+		Files:         []string{filepath.Join(dir, "*.json")},
+		Interval:      DefaultInterval,
+		DefaultModule: "http_2xx",
+		Modules: map[string]Module{
+			"http_2xx": {
+				HTTPCheck: &healthcheck.HTTPHealthcheckConfiguration{
+					Base: healthcheck.Base{
+						Interval: healthcheck.Duration(10 * time.Second),
+					},
+					ValidStatus: []uint{200},
+					Timeout:     healthcheck.Duration(5 * time.Second),
+				},
+			},
+			"tcp_connect": {
+				TCPCheck: &healthcheck.TCPHealthcheckConfiguration{
+					Base: healthcheck.Base{
+						Interval: healthcheck.Duration(10 * time.Second),
+					},
+					Timeout: healthcheck.Duration(5 * time.Second),
+				},
+			},
+		},
+	}
+	discovery := New(logger, &config, checkComponent, counter)
+	reconciled, err := discovery.reconcile()
+	if err != nil || !reconciled {
+		t.Fatalf("file_sd discovery failed\n%v", err)
+	}
+	checks := make(map[string]interface{})
+	for _, check := range checkComponent.ListChecks() {
+		checks[check.Base().Name] = check.GetConfig()
+	}
+	if len(checks) != 3 {
+		t.Fatalf("Expected 3 configured healthchecks, got %v", checks)
+	}
+	a, ok := checks["blackbox-http_2xx-https-a.example.com-health-verbose-1"].(*healthcheck.HTTPHealthcheckConfiguration)
+	if !ok || a.Target != "a.example.com" || a.Port != 443 || a.Protocol != healthcheck.HTTPS || a.Path != "/health" || a.Query["verbose"] != "1" {
+		t.Fatalf("Invalid healthcheck %+v", a)
+	}
+	if a.Base.Labels["env"] != "prod" || len(a.Base.Labels) != 1 {
+		t.Fatalf("Invalid healthcheck labels %v", a.Base.Labels)
+	}
+	b, ok := checks["blackbox-http_2xx-b.example.com-8080"].(*healthcheck.HTTPHealthcheckConfiguration)
+	if !ok || b.Target != "b.example.com" || b.Port != 8080 {
+		t.Fatalf("Invalid healthcheck %+v", b)
+	}
+	c, ok := checks["blackbox-tcp_connect-c.example.com-22"].(*healthcheck.TCPHealthcheckConfiguration)
+	if !ok || c.Target != "c.example.com" || c.Port != 22 {
+		t.Fatalf("Invalid healthcheck %+v", c)
+	}
+	if config.Modules["http_2xx"].HTTPCheck.Target != "" || config.Modules["http_2xx"].HTTPCheck.Base.Labels != nil {
+		t.Fatalf("The template should not be modified")
+	}
+	// the files did not change
+	reconciled, err = discovery.reconcile()
+	if err != nil || reconciled {
+		t.Fatalf("The healthchecks should not be reconciled\n%v", err)
+	}
+	err = os.WriteFile(path, []byte(`[{"targets": ["c.example.com"], "labels": {"__param_module": "tcp_connect"}}]`), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the targets file\n%v", err)
+	}
+	reconciled, err = discovery.reconcile()
+	if err == nil || !reconciled {
+		t.Fatalf("Was expecting an error for a target without port")
+	}
+	if len(checkComponent.ListChecks()) != 3 {
+		t.Fatalf("The healthchecks should be kept when the files are invalid")
+	}
+	err = os.WriteFile(path, []byte("- targets: [\"c.example.com:2222\"]\n  labels:\n    __param_module: tcp_connect\n"), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the targets file\n%v", err)
+	}
+	_, err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("file_sd discovery failed\n%v", err)
+	}
+	if len(checkComponent.ListChecks()) != 1 {
+		t.Fatalf("Expected 1 configured healthcheck")
+	}
+}
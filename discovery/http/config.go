@@ -22,11 +22,14 @@ type Configuration struct {
 }
 
 type ResultPayload struct {
-	CommandChecks []healthcheck.CommandHealthcheckConfiguration `json:"command-checks"`
-	DNSChecks     []healthcheck.DNSHealthcheckConfiguration     `json:"dns-checks"`
-	TCPChecks     []healthcheck.TCPHealthcheckConfiguration     `json:"tcp-checks"`
-	HTTPChecks    []healthcheck.HTTPHealthcheckConfiguration    `json:"http-checks"`
-	TLSChecks     []healthcheck.TLSHealthcheckConfiguration     `json:"tls-checks"`
+	CommandChecks   []healthcheck.CommandHealthcheckConfiguration   `json:"command-checks"`
+	DNSChecks       []healthcheck.DNSHealthcheckConfiguration       `json:"dns-checks"`
+	TCPChecks       []healthcheck.TCPHealthcheckConfiguration       `json:"tcp-checks"`
+	HTTPChecks      []healthcheck.HTTPHealthcheckConfiguration      `json:"http-checks"`
+	TLSChecks       []healthcheck.TLSHealthcheckConfiguration       `json:"tls-checks"`
+	HeartbeatChecks []healthcheck.HeartbeatHealthcheckConfiguration `json:"heartbeat-checks"`
+	GRPCChecks      []healthcheck.GRPCHealthcheckConfiguration      `json:"grpc-checks"`
+	ProfileChecks   []healthcheck.ProfileHealthcheckConfiguration   `json:"profile-checks"`
 }
 
 // UnmarshalYAML Parse a configuration from YAML.
@@ -26,8 +26,10 @@ type HTTPDiscovery struct {
 	URL              string
 	Config           *Configuration
 	Client           *http.Client
-	t                tomb.Tomb
-	tick             *time.Ticker
+	// etag the ETag of the last payload, sent in the If-None-Match header
+	etag string
+	t    tomb.Tomb
+	tick *time.Ticker
 }
 
 // New creates a new HTTP Discovery
@@ -73,6 +75,9 @@ func (c *HTTPDiscovery) request() error {
 		return errors.Wrapf(err, "HTTP discovery: fail to create request for %s", c.URL)
 	}
 	req.Header.Set("User-Agent", "Cabourotte")
+	if c.etag != "" {
+		req.Header.Set("If-None-Match", c.etag)
+	}
 	for k, v := range c.Config.Headers {
 		req.Header.Set(k, v)
 	}
@@ -88,6 +93,10 @@ func (c *HTTPDiscovery) request() error {
 		return errors.Wrapf(err, "HTTP discovery: fail to send request to %s", c.URL)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		c.Logger.Debug("HTTP discovery: the healthchecks were not modified")
+		return nil
+	}
 	if resp.StatusCode != 200 {
 		return fmt.Errorf("HTTP discovery: request failed, status %d", resp.StatusCode)
 	}
@@ -102,19 +111,35 @@ func (c *HTTPDiscovery) request() error {
 	if err := json.Unmarshal(responseBody, &payload); err != nil {
 		return fmt.Errorf("HTTP Discovery: fail to convert the payload %s from json", string(responseBody))
 	}
-	return c.Healthcheck.ReloadForSource(
-		fmt.Sprintf("%s-%s", healthcheck.SourceHTTPDiscovery, c.Config.Name),
+	err = c.Healthcheck.ReloadForSource(
+		c.source(),
 		nil,
 		payload.CommandChecks,
 		payload.DNSChecks,
 		payload.TCPChecks,
 		payload.HTTPChecks,
-		payload.TLSChecks)
+		payload.TLSChecks,
+		payload.HeartbeatChecks,
+		payload.GRPCChecks,
+		payload.ProfileChecks)
+	if err != nil {
+		// the payload will be fetched again on the next request
+		c.etag = ""
+		return err
+	}
+	c.etag = resp.Header.Get("ETag")
+	return nil
+}
+
+// source returns the source of the healthchecks of the discovery
+func (c *HTTPDiscovery) source() string {
+	return fmt.Sprintf("%s-%s", healthcheck.SourceHTTPDiscovery, c.Config.Name)
 }
 
 // Start starts the HTTP discovery component
 func (c *HTTPDiscovery) Start() error {
 	c.tick = time.NewTicker(time.Duration(c.Config.Interval))
+	c.Healthcheck.RegisterSource(c.source())
 	c.t.Go(func() error {
 		c.Logger.Info(fmt.Sprintf("Starting the HTTP healthcheck discovery on %s:%d", c.Config.Host, c.Config.Port))
 		for {
@@ -132,6 +157,7 @@ func (c *HTTPDiscovery) Start() error {
 				}
 				c.requestHistogram.With(prom.Labels{"name": c.Config.Name}).Observe(duration.Seconds())
 				c.responseCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+				c.Healthcheck.RecordReconciliation(c.source(), err)
 			case <-c.t.Dying():
 				return nil
 			}
@@ -59,6 +59,14 @@ func TestRequest(t *testing.T) {
 				Timeout:  healthcheck.Duration(time.Second * 5),
 			},
 		},
+		HeartbeatChecks: []healthcheck.HeartbeatHealthcheckConfiguration{
+			healthcheck.HeartbeatHealthcheckConfiguration{
+				Base: healthcheck.Base{
+					Name:     "backup",
+					Interval: healthcheck.Duration(time.Hour),
+				},
+			},
+		},
 	}
 	buckets := []float64{
 		0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 0.75, 1,
@@ -81,7 +89,7 @@ func TestRequest(t *testing.T) {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
 	}
 	logger := zap.NewExample()
-	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
 	if err != nil {
 		t.Fatalf("Fail to create the healthcheck component\n%v", err)
 	}
@@ -140,19 +148,97 @@ func TestRequest(t *testing.T) {
 		t.Fatalf("HTTP discovery request failed\n%v", err)
 	}
 	checks = checkComponent.ListChecks()
-	if len(checks) != 2 {
-		t.Fatalf("Expected 2 configured healthchecks, got %d", len(checks))
-	}
-	if checks[0].Base().Name != "tcp" && checks[1].Base().Name != "tcp" {
-		t.Fatalf("Invalid healthcheck names: %s, %s",
-			checks[0].Base().Name,
-			checks[1].Base().Name,
-		)
-	}
-	if checks[0].Base().Name != "new" && checks[1].Base().Name != "new" {
-		t.Fatalf("Invalid healthcheck names: %s, %s",
-			checks[0].Base().Name,
-			checks[1].Base().Name,
-		)
+	if len(checks) != 3 {
+		t.Fatalf("Expected 3 configured healthchecks, got %d", len(checks))
+	}
+	names := make(map[string]bool)
+	for _, check := range checks {
+		names[check.Base().Name] = true
+	}
+	if !names["tcp"] || !names["new"] || !names["backup"] {
+		t.Fatalf("Invalid healthcheck names: %v", names)
+	}
+}
+
+func TestRequestETag(t *testing.T) {
+	payload := ResultPayload{
+		DNSChecks: []healthcheck.DNSHealthcheckConfiguration{
+			healthcheck.DNSHealthcheckConfiguration{
+				Base: healthcheck.Base{
+					Name:        "foo",
+					Description: "bar",
+					Interval:    healthcheck.Duration(time.Second * 10),
+				},
+				Timeout: healthcheck.Duration(time.Second * 2),
+				Domain:  "mcorbin.fr",
+			},
+		},
+	}
+	histo := prom.NewHistogramVec(prom.HistogramOpts{
+		Name: "http_discovery_duration_seconds",
+		Help: "Time to execute the HTTP request for healthchecks discovery.",
+	},
+		[]string{"name"},
+	)
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "http_discovery_responses_total",
+			Help: "Count the number of HTTP responses for discovery requests.",
+		},
+		[]string{"status", "name"})
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), promComponent, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	notModified := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("Error marshaling to json\n%v", err)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, err = w.Write(body)
+		if err != nil {
+			t.Fatalf("Error writing body:\n%v", err)
+		}
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	discoveryConfig := Configuration{
+		Host:     "127.0.0.1",
+		Path:     "/",
+		Port:     uint32(port),
+		Protocol: healthcheck.HTTP,
+		Interval: 10,
+	}
+	discovery, err := New(logger, &discoveryConfig, checkComponent, counter, histo)
+	if err != nil {
+		t.Fatalf("Fail to create the HTTP discovery component :\n%v", err)
+	}
+	for i := 0; i < 2; i++ {
+		err = discovery.request()
+		if err != nil {
+			t.Fatalf("HTTP discovery request failed\n%v", err)
+		}
+		checks := checkComponent.ListChecks()
+		if len(checks) != 1 {
+			t.Fatalf("Expected 1 configured healthchecks, got %d", len(checks))
+		}
+	}
+	if notModified != 1 {
+		t.Fatalf("Expected 1 not modified response, got %d", notModified)
 	}
 }
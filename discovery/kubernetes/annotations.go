@@ -0,0 +1,125 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// AnnotationPrefix the prefix of the annotations used to configure the
+// healthchecks
+const AnnotationPrefix = "cabourotte.appclacks.com/"
+
+const (
+	defaultCheckInterval = healthcheck.Duration(10 * time.Second)
+	defaultCheckTimeout  = healthcheck.Duration(5 * time.Second)
+)
+
+// checks the healthchecks built from Kubernetes objects
+type checks struct {
+	tcp  []healthcheck.TCPHealthcheckConfiguration
+	http []healthcheck.HTTPHealthcheckConfiguration
+}
+
+// defaults the values used when an object is not annotated
+type defaults struct {
+	checkType string
+	port      uint
+}
+
+func annotation(annotations map[string]string, key string) string {
+	return strings.TrimSpace(annotations[AnnotationPrefix+key])
+}
+
+// add builds an healthcheck for the target from the object annotations:
+// type (http, https or tcp, http by default), port, path, interval,
+// timeout and valid-status (a comma-separated list of status codes and
+// ranges, for example 200-299,401).
+// The annotations override the defaults. It returns false if no port is
+// known for the object or if the object is annotated with enabled=false.
+func (c *checks) add(name string, target string, def defaults, annotations map[string]string, labels map[string]string) (bool, error) {
+	if annotation(annotations, "enabled") == "false" {
+		return false, nil
+	}
+	port := uint64(def.port)
+	if portValue := annotation(annotations, "port"); portValue != "" {
+		p, err := strconv.ParseUint(portValue, 10, 16)
+		if err != nil || p == 0 {
+			return false, fmt.Errorf("Invalid port annotation %s", portValue)
+		}
+		port = p
+	}
+	if port == 0 {
+		return false, nil
+	}
+	base := healthcheck.Base{
+		Name:     name,
+		Interval: defaultCheckInterval,
+		Labels:   labels,
+	}
+	timeout := defaultCheckTimeout
+	if value := annotation(annotations, "interval"); value != "" {
+		err := base.Interval.UnmarshalText([]byte(value))
+		if err != nil {
+			return false, errors.Wrapf(err, "Invalid interval annotation %s", value)
+		}
+	}
+	if value := annotation(annotations, "timeout"); value != "" {
+		err := timeout.UnmarshalText([]byte(value))
+		if err != nil {
+			return false, errors.Wrapf(err, "Invalid timeout annotation %s", value)
+		}
+	}
+	checkType := annotation(annotations, "type")
+	if checkType == "" {
+		checkType = def.checkType
+	}
+	switch checkType {
+	case "tcp":
+		config := healthcheck.TCPHealthcheckConfiguration{
+			Base:    base,
+			Target:  target,
+			Port:    uint(port),
+			Timeout: timeout,
+		}
+		if err := config.Validate(); err != nil {
+			return false, err
+		}
+		c.tcp = append(c.tcp, config)
+	case "", "http", "https":
+		config := healthcheck.HTTPHealthcheckConfiguration{
+			Base:        base,
+			Target:      target,
+			Port:        uint(port),
+			Path:        annotation(annotations, "path"),
+			Protocol:    healthcheck.HTTP,
+			ValidStatus: []uint{200},
+			Timeout:     timeout,
+		}
+		if checkType == "https" {
+			config.Protocol = healthcheck.HTTPS
+		}
+		if config.Path == "" {
+			config.Path = "/"
+		}
+		if value := annotation(annotations, "valid-status"); value != "" {
+			codes, err := healthcheck.ParseStatusCodes(strings.Split(value, ","))
+			if err != nil {
+				return false, fmt.Errorf("Invalid valid-status annotation %s", value)
+			}
+			config.ValidStatus = codes
+		}
+		if err := config.Validate(); err != nil {
+			return false, err
+		}
+		c.http = append(c.http, config)
+	default:
+		return false, fmt.Errorf("Invalid type annotation %s", checkType)
+	}
+	return true, nil
+}
@@ -0,0 +1,75 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+const (
+	// DefaultTokenFile the service account token path inside a pod
+	DefaultTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	// DefaultCacert the service account ca certificate path inside a pod
+	DefaultCacert = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	// DefaultInterval the default interval between two listings of the Kubernetes objects
+	DefaultInterval = healthcheck.Duration(30 * time.Second)
+)
+
+const (
+	// ResourcePods discover healthchecks from pods
+	ResourcePods = "pods"
+	// ResourceServices discover healthchecks from services
+	ResourceServices = "services"
+	// ResourceIngresses discover healthchecks from ingresses
+	ResourceIngresses = "ingresses"
+)
+
+// Configuration the Kubernetes discovery configuration.
+// The in-cluster configuration (service account token and ca certificate)
+// is used by default.
+// Resources lists the kind of objects to watch (pods, services and
+// ingresses, pods by default).
+type Configuration struct {
+	Name          string
+	APIServer     string `json:"api-server" yaml:"api-server"`
+	TokenFile     string `json:"token-file" yaml:"token-file"`
+	Cacert        string
+	Insecure      bool
+	Namespace     string
+	LabelSelector string               `json:"label-selector" yaml:"label-selector"`
+	NodeName      string               `json:"node-name" yaml:"node-name"`
+	Resources     []string             `json:"resources"`
+	Interval      healthcheck.Duration `json:"interval"`
+	Labels        map[string]string    `json:"labels,omitempty"`
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read Kubernetes discovery configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid Kubernetes discovery name configuration")
+	}
+	if raw.Interval == 0 {
+		raw.Interval = DefaultInterval
+	}
+	if raw.Interval < healthcheck.Duration(10*time.Second) {
+		return errors.New("The Kubernetes discovery interval should be greater or equal than 10 seconds")
+	}
+	if len(raw.Resources) == 0 {
+		raw.Resources = []string{ResourcePods}
+	}
+	for _, resource := range raw.Resources {
+		if resource != ResourcePods && resource != ResourceServices && resource != ResourceIngresses {
+			return fmt.Errorf("Invalid Kubernetes discovery resource %s", resource)
+		}
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
@@ -0,0 +1,384 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/tls"
+)
+
+type objectMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type pod struct {
+	Metadata objectMeta `json:"metadata"`
+	Status   struct {
+		Phase string `json:"phase"`
+		PodIP string `json:"podIP"`
+	} `json:"status"`
+}
+
+type podList struct {
+	Items []pod `json:"items"`
+}
+
+type loadBalancerIngress struct {
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+}
+
+type service struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		Type      string `json:"type"`
+		ClusterIP string `json:"clusterIP"`
+		Ports     []struct {
+			Port uint `json:"port"`
+		} `json:"ports"`
+	} `json:"spec"`
+	Status struct {
+		LoadBalancer struct {
+			Ingress []loadBalancerIngress `json:"ingress"`
+		} `json:"loadBalancer"`
+	} `json:"status"`
+}
+
+type serviceList struct {
+	Items []service `json:"items"`
+}
+
+type ingress struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		TLS []struct {
+			Hosts []string `json:"hosts"`
+		} `json:"tls"`
+		Rules []struct {
+			Host string `json:"host"`
+		} `json:"rules"`
+	} `json:"spec"`
+}
+
+type ingressList struct {
+	Items []ingress `json:"items"`
+}
+
+// KubernetesDiscovery the Kubernetes discovery struct
+type KubernetesDiscovery struct {
+	Logger          *zap.Logger
+	responseCounter *prom.CounterVec
+	Healthcheck     *healthcheck.Component
+	Config          *Configuration
+	Client          *http.Client
+	APIServer       string
+	TokenFile       string
+	t               tomb.Tomb
+	tick            *time.Ticker
+}
+
+// New creates a new Kubernetes discovery
+func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, counter *prom.CounterVec) (*KubernetesDiscovery, error) {
+	apiServer := config.APIServer
+	tokenFile := config.TokenFile
+	cacert := config.Cacert
+	if apiServer == "" {
+		host := os.Getenv("KUBERNETES_SERVICE_HOST")
+		port := os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("Kubernetes discovery: no api-server configured and not running in a cluster")
+		}
+		apiServer = "https://" + net.JoinHostPort(host, port)
+		if tokenFile == "" {
+			tokenFile = DefaultTokenFile
+		}
+		if cacert == "" {
+			cacert = DefaultCacert
+		}
+	}
+	tlsConfig, err := tls.GetTLSConfig("", "", cacert, "", config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	component := KubernetesDiscovery{
+		Logger:          logger,
+		responseCounter: counter,
+		Healthcheck:     checkComponent,
+		Config:          config,
+		APIServer:       strings.TrimSuffix(apiServer, "/"),
+		TokenFile:       tokenFile,
+		Client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+			Timeout: time.Second * 10,
+		},
+	}
+	return &component, nil
+}
+
+// get sends a GET request to the API server and decodes the response
+func (c *KubernetesDiscovery) get(ctx context.Context, path string, query url.Values, result interface{}) error {
+	reqURL := c.APIServer + path
+	if len(query) != 0 {
+		reqURL = reqURL + "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return errors.Wrapf(err, "Kubernetes discovery: fail to create request for %s", reqURL)
+	}
+	req.Header.Set("User-Agent", "Cabourotte")
+	req.Header.Set("Accept", "application/json")
+	if c.TokenFile != "" {
+		// the token is read for each request because it is rotated
+		token, err := os.ReadFile(c.TokenFile)
+		if err != nil {
+			return errors.Wrapf(err, "Kubernetes discovery: fail to read the token file")
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "Kubernetes discovery: fail to send request to %s", reqURL)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to read request body")
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("Kubernetes discovery: request to %s failed, status %d, body %s", reqURL, resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return errors.Wrapf(err, "Kubernetes discovery: fail to convert the payload from json")
+	}
+	return nil
+}
+
+// namespacedPath returns the API path for a resource, in the configured
+// namespace or in all namespaces
+func (c *KubernetesDiscovery) namespacedPath(prefix string, resource string) string {
+	if c.Config.Namespace != "" {
+		return fmt.Sprintf("%s/namespaces/%s/%s", prefix, url.PathEscape(c.Config.Namespace), resource)
+	}
+	return fmt.Sprintf("%s/%s", prefix, resource)
+}
+
+// podsChecks builds the healthchecks for the running pods
+func (c *KubernetesDiscovery) podsChecks(ctx context.Context, result *checks) error {
+	query := url.Values{}
+	if c.Config.LabelSelector != "" {
+		query.Set("labelSelector", c.Config.LabelSelector)
+	}
+	fieldSelector := "status.phase=Running"
+	if c.Config.NodeName != "" {
+		fieldSelector = fieldSelector + ",spec.nodeName=" + c.Config.NodeName
+	}
+	query.Set("fieldSelector", fieldSelector)
+	var pods podList
+	err := c.get(ctx, c.namespacedPath("/api/v1", "pods"), query, &pods)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.PodIP == "" {
+			continue
+		}
+		name := fmt.Sprintf("kubernetes-pod-%s-%s", pod.Metadata.Namespace, pod.Metadata.Name)
+		labels := map[string]string{
+			"namespace": pod.Metadata.Namespace,
+			"pod":       pod.Metadata.Name,
+		}
+		_, err := result.add(name, pod.Status.PodIP, defaults{}, pod.Metadata.Annotations, labels)
+		if err != nil {
+			c.Logger.Error(fmt.Sprintf("Kubernetes discovery: invalid annotations on pod %s/%s: %s", pod.Metadata.Namespace, pod.Metadata.Name, err.Error()))
+		}
+	}
+	return nil
+}
+
+// servicesChecks builds TCP healthchecks on the first port of the
+// services, targeting the cluster IP or the load balancer addresses
+func (c *KubernetesDiscovery) servicesChecks(ctx context.Context, result *checks) error {
+	query := url.Values{}
+	if c.Config.LabelSelector != "" {
+		query.Set("labelSelector", c.Config.LabelSelector)
+	}
+	var services serviceList
+	err := c.get(ctx, c.namespacedPath("/api/v1", "services"), query, &services)
+	if err != nil {
+		return err
+	}
+	for _, svc := range services.Items {
+		def := defaults{checkType: "tcp"}
+		if len(svc.Spec.Ports) != 0 {
+			def.port = svc.Spec.Ports[0].Port
+		}
+		name := fmt.Sprintf("kubernetes-service-%s-%s", svc.Metadata.Namespace, svc.Metadata.Name)
+		targets := make(map[string]string)
+		switch svc.Spec.Type {
+		case "", "ClusterIP", "NodePort":
+			// headless services have no cluster IP
+			if svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != "None" {
+				targets[name] = svc.Spec.ClusterIP
+			}
+		case "LoadBalancer":
+			for _, lb := range svc.Status.LoadBalancer.Ingress {
+				address := lb.IP
+				if address == "" {
+					address = lb.Hostname
+				}
+				if address != "" {
+					targets[fmt.Sprintf("%s-%s", name, address)] = address
+				}
+			}
+		}
+		for checkName, target := range targets {
+			labels := map[string]string{
+				"namespace": svc.Metadata.Namespace,
+				"service":   svc.Metadata.Name,
+			}
+			_, err := result.add(checkName, target, def, svc.Metadata.Annotations, labels)
+			if err != nil {
+				c.Logger.Error(fmt.Sprintf("Kubernetes discovery: invalid annotations on service %s/%s: %s", svc.Metadata.Namespace, svc.Metadata.Name, err.Error()))
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// ingressesChecks builds HTTP healthchecks on the ingresses hosts. HTTPS
+// is used for the hosts listed in the ingress TLS section.
+func (c *KubernetesDiscovery) ingressesChecks(ctx context.Context, result *checks) error {
+	query := url.Values{}
+	if c.Config.LabelSelector != "" {
+		query.Set("labelSelector", c.Config.LabelSelector)
+	}
+	var ingresses ingressList
+	err := c.get(ctx, c.namespacedPath("/apis/networking.k8s.io/v1", "ingresses"), query, &ingresses)
+	if err != nil {
+		return err
+	}
+	for _, ing := range ingresses.Items {
+		tlsHosts := make(map[string]bool)
+		for _, t := range ing.Spec.TLS {
+			for _, host := range t.Hosts {
+				tlsHosts[host] = true
+			}
+		}
+		hosts := make(map[string]bool)
+		for _, rule := range ing.Spec.Rules {
+			// wildcard hosts cannot be probed
+			if rule.Host == "" || strings.HasPrefix(rule.Host, "*") || hosts[rule.Host] {
+				continue
+			}
+			hosts[rule.Host] = true
+			def := defaults{checkType: "http", port: 80}
+			if tlsHosts[rule.Host] {
+				def = defaults{checkType: "https", port: 443}
+			}
+			name := fmt.Sprintf("kubernetes-ingress-%s-%s-%s", ing.Metadata.Namespace, ing.Metadata.Name, rule.Host)
+			labels := map[string]string{
+				"namespace": ing.Metadata.Namespace,
+				"ingress":   ing.Metadata.Name,
+			}
+			_, err := result.add(name, rule.Host, def, ing.Metadata.Annotations, labels)
+			if err != nil {
+				c.Logger.Error(fmt.Sprintf("Kubernetes discovery: invalid annotations on ingress %s/%s: %s", ing.Metadata.Namespace, ing.Metadata.Name, err.Error()))
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// reconcile lists the Kubernetes objects and reloads the healthchecks
+func (c *KubernetesDiscovery) reconcile() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result := &checks{}
+	resources := c.Config.Resources
+	if len(resources) == 0 {
+		resources = []string{ResourcePods}
+	}
+	for _, resource := range resources {
+		var err error
+		switch resource {
+		case ResourcePods:
+			err = c.podsChecks(ctx, result)
+		case ResourceServices:
+			err = c.servicesChecks(ctx, result)
+		case ResourceIngresses:
+			err = c.ingressesChecks(ctx, result)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return c.Healthcheck.ReloadForSource(
+		c.source(),
+		c.Config.Labels,
+		nil,
+		nil,
+		result.tcp,
+		result.http,
+		nil,
+		nil,
+		nil,
+		nil)
+}
+
+// source returns the source of the healthchecks of the discovery
+func (c *KubernetesDiscovery) source() string {
+	return fmt.Sprintf("%s-%s", healthcheck.SourceKubernetesDiscovery, c.Config.Name)
+}
+
+// Start starts the Kubernetes discovery component
+func (c *KubernetesDiscovery) Start() error {
+	c.tick = time.NewTicker(time.Duration(c.Config.Interval))
+	c.Healthcheck.RegisterSource(c.source())
+	c.t.Go(func() error {
+		c.Logger.Info(fmt.Sprintf("Starting the Kubernetes healthcheck discovery %s", c.Config.Name))
+		for {
+			status := "success"
+			err := c.reconcile()
+			if err != nil {
+				status = "failure"
+				c.Logger.Error(fmt.Sprintf("Kubernetes discovery error: %s", err.Error()))
+			}
+			c.responseCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+			c.Healthcheck.RecordReconciliation(c.source(), err)
+			select {
+			case <-c.tick.C:
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops the Kubernetes discovery component
+func (c *KubernetesDiscovery) Stop() error {
+	c.Logger.Info("Stopping the Kubernetes discovery")
+	c.tick.Stop()
+	c.t.Kill(nil)
+	return c.t.Wait()
+}
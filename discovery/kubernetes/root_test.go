@@ -0,0 +1,253 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func newPod(namespace string, name string, ip string, annotations map[string]string) pod {
+	p := pod{
+		Metadata: objectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Annotations: annotations,
+		},
+	}
+	p.Status.Phase = "Running"
+	p.Status.PodIP = ip
+	return p
+}
+
+func TestChecksAdd(t *testing.T) {
+	cases := []struct {
+		annotations map[string]string
+		added       bool
+		err         bool
+		tcp         int
+		http        int
+	}{
+		{annotations: map[string]string{}, added: false},
+		{annotations: map[string]string{AnnotationPrefix + "port": "8080"}, added: true, http: 1},
+		{annotations: map[string]string{AnnotationPrefix + "port": "9000", AnnotationPrefix + "type": "tcp"}, added: true, tcp: 1},
+		{annotations: map[string]string{AnnotationPrefix + "port": "abc"}, err: true},
+		{annotations: map[string]string{AnnotationPrefix + "port": "80", AnnotationPrefix + "type": "udp"}, err: true},
+		{annotations: map[string]string{AnnotationPrefix + "port": "80", AnnotationPrefix + "interval": "1s"}, err: true},
+		{annotations: map[string]string{AnnotationPrefix + "port": "80", AnnotationPrefix + "valid-status": "200,x"}, err: true},
+	}
+	for _, c := range cases {
+		result := &checks{}
+		added, err := result.add("foo", "10.0.0.1", defaults{}, c.annotations, nil)
+		if c.err && err == nil {
+			t.Fatalf("Was expecting an error for %v", c.annotations)
+		}
+		if !c.err && err != nil {
+			t.Fatalf("Unexpected error for %v\n%v", c.annotations, err)
+		}
+		if added != c.added || len(result.tcp) != c.tcp || len(result.http) != c.http {
+			t.Fatalf("Invalid result for %v: added=%t tcp=%d http=%d", c.annotations, added, len(result.tcp), len(result.http))
+		}
+	}
+	result := &checks{}
+	_, err := result.add("foo", "10.0.0.1", defaults{}, map[string]string{
+		AnnotationPrefix + "port":         "8443",
+		AnnotationPrefix + "type":         "https",
+		AnnotationPrefix + "path":         "/health",
+		AnnotationPrefix + "valid-status": "200, 300-302",
+	}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error\n%v", err)
+	}
+	config := result.http[0]
+	if config.Protocol != healthcheck.HTTPS || config.Path != "/health" || config.Port != 8443 || len(config.ValidStatus) != 4 {
+		t.Fatalf("Invalid HTTP healthcheck %+v", config)
+	}
+}
+
+func TestReconcilePods(t *testing.T) {
+	pods := podList{
+		Items: []pod{
+			newPod("default", "foo", "10.0.0.1", map[string]string{AnnotationPrefix + "port": "8080"}),
+			newPod("default", "bar", "10.0.0.2", map[string]string{AnnotationPrefix + "port": "9000", AnnotationPrefix + "type": "tcp"}),
+			newPod("default", "ignored", "10.0.0.3", nil),
+		},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/default/pods" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("labelSelector") != "app=foo" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, err := json.Marshal(pods)
+		if err != nil {
+			t.Fatalf("Error marshaling to json\n%v", err)
+		}
+		_, err = w.Write(body)
+		if err != nil {
+			t.Fatalf("Error writing body:\n%v", err)
+		}
+	}))
+	defer ts.Close()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	err := os.WriteFile(tokenFile, []byte("secret\n"), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the token file\n%v", err)
+	}
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), promComponent, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "kubernetes_discovery_requests_total",
+			Help: "Count the number of Kubernetes discovery reconciliations.",
+		},
+		[]string{"status", "name"})
+	config := Configuration{
+		Name:          "test",
+		APIServer:     ts.URL,
+		TokenFile:     tokenFile,
+		Namespace:     "default",
+		LabelSelector: "app=foo",
+		Interval:      DefaultInterval,
+	}
+	discovery, err := New(logger, &config, checkComponent, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the Kubernetes discovery component :\n%v", err)
+	}
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("Kubernetes discovery failed\n%v", err)
+	}
+	checks := checkComponent.ListChecks()
+	if len(checks) != 2 {
+		t.Fatalf("Expected 2 configured healthchecks, got %d", len(checks))
+	}
+	for _, check := range checks {
+		name := check.Base().Name
+		if name != "kubernetes-pod-default-foo" && name != "kubernetes-pod-default-bar" {
+			t.Fatalf("Invalid healthcheck name %s", name)
+		}
+		if check.Base().Labels["namespace"] != "default" {
+			t.Fatalf("Invalid healthcheck labels %v", check.Base().Labels)
+		}
+	}
+	pods.Items = pods.Items[:1]
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("Kubernetes discovery failed\n%v", err)
+	}
+	checks = checkComponent.ListChecks()
+	if len(checks) != 1 {
+		t.Fatalf("Expected 1 configured healthcheck, got %d", len(checks))
+	}
+	if checks[0].Base().Name != "kubernetes-pod-default-foo" {
+		t.Fatalf("Invalid healthcheck name %s", checks[0].Base().Name)
+	}
+}
+
+func TestReconcileServicesIngresses(t *testing.T) {
+	payloads := map[string]string{
+		"/api/v1/services": `{"items": [
+  {"metadata": {"name": "api", "namespace": "default"},
+   "spec": {"type": "ClusterIP", "clusterIP": "10.1.0.1", "ports": [{"port": 8080}]}},
+  {"metadata": {"name": "headless", "namespace": "default"},
+   "spec": {"type": "ClusterIP", "clusterIP": "None", "ports": [{"port": 8080}]}},
+  {"metadata": {"name": "lb", "namespace": "default",
+                "annotations": {"cabourotte.appclacks.com/type": "http", "cabourotte.appclacks.com/path": "/health"}},
+   "spec": {"type": "LoadBalancer", "clusterIP": "10.1.0.2", "ports": [{"port": 80}]},
+   "status": {"loadBalancer": {"ingress": [{"ip": "192.168.1.1"}]}}},
+  {"metadata": {"name": "disabled", "namespace": "default",
+                "annotations": {"cabourotte.appclacks.com/enabled": "false"}},
+   "spec": {"type": "ClusterIP", "clusterIP": "10.1.0.3", "ports": [{"port": 80}]}}]}`,
+		"/apis/networking.k8s.io/v1/ingresses": `{"items": [
+  {"metadata": {"name": "web", "namespace": "default"},
+   "spec": {"tls": [{"hosts": ["secure.example.com"]}],
+            "rules": [{"host": "secure.example.com"}, {"host": "www.example.com"}, {"host": "*.example.com"}]}}]}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, ok := payloads[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, err := w.Write([]byte(payload))
+		if err != nil {
+			t.Fatalf("Error writing body:\n%v", err)
+		}
+	}))
+	defer ts.Close()
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), promComponent, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "kubernetes_discovery_requests_total",
+			Help: "Count the number of Kubernetes discovery reconciliations.",
+		},
+		[]string{"status", "name"})
+	config := Configuration{
+		Name:      "test",
+		APIServer: ts.URL,
+		Resources: []string{ResourceServices, ResourceIngresses},
+		Interval:  DefaultInterval,
+	}
+	discovery, err := New(logger, &config, checkComponent, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the Kubernetes discovery component :\n%v", err)
+	}
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("Kubernetes discovery failed\n%v", err)
+	}
+	checks := make(map[string]interface{})
+	for _, check := range checkComponent.ListChecks() {
+		checks[check.Base().Name] = check.GetConfig()
+	}
+	if len(checks) != 4 {
+		t.Fatalf("Expected 4 configured healthchecks, got %v", checks)
+	}
+	api, ok := checks["kubernetes-service-default-api"].(*healthcheck.TCPHealthcheckConfiguration)
+	if !ok || api.Target != "10.1.0.1" || api.Port != 8080 {
+		t.Fatalf("Invalid service healthcheck %v", checks["kubernetes-service-default-api"])
+	}
+	lb, ok := checks["kubernetes-service-default-lb-192.168.1.1"].(*healthcheck.HTTPHealthcheckConfiguration)
+	if !ok || lb.Target != "192.168.1.1" || lb.Port != 80 || lb.Path != "/health" {
+		t.Fatalf("Invalid load balancer healthcheck %v", checks["kubernetes-service-default-lb-192.168.1.1"])
+	}
+	secure, ok := checks["kubernetes-ingress-default-web-secure.example.com"].(*healthcheck.HTTPHealthcheckConfiguration)
+	if !ok || secure.Protocol != healthcheck.HTTPS || secure.Port != 443 {
+		t.Fatalf("Invalid ingress healthcheck %v", checks["kubernetes-ingress-default-web-secure.example.com"])
+	}
+	www, ok := checks["kubernetes-ingress-default-web-www.example.com"].(*healthcheck.HTTPHealthcheckConfiguration)
+	if !ok || www.Protocol != healthcheck.HTTP || www.Port != 80 {
+		t.Fatalf("Invalid ingress healthcheck %v", checks["kubernetes-ingress-default-web-www.example.com"])
+	}
+}
@@ -0,0 +1,82 @@
+package kv
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+const (
+	// BackendConsul reads the healthchecks from the Consul KV store
+	BackendConsul = "consul"
+	// BackendEtcd reads the healthchecks from etcd
+	BackendEtcd = "etcd"
+	// DefaultInterval the default interval between two full listings of
+	// the keys, in addition to the watch
+	DefaultInterval = healthcheck.Duration(60 * time.Second)
+)
+
+// Configuration the key/value store discovery configuration.
+// Each key under the prefix contains healthchecks definitions, using the
+// same format as the checks directory files. The keys are watched and the
+// changes are applied live.
+type Configuration struct {
+	Name     string
+	Backend  string
+	Address  string
+	Prefix   string
+	Token    string
+	Username string
+	Password string
+	Interval healthcheck.Duration `json:"interval"`
+	Labels   map[string]string    `json:"labels,omitempty"`
+	Key      string               `json:"key,omitempty"`
+	Cert     string               `json:"cert,omitempty"`
+	Cacert   string               `json:"cacert,omitempty"`
+	Insecure bool
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read KV discovery configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid KV discovery name configuration")
+	}
+	if raw.Prefix == "" {
+		return errors.New("The KV discovery prefix is missing")
+	}
+	switch raw.Backend {
+	case BackendConsul:
+		if raw.Address == "" {
+			raw.Address = "http://127.0.0.1:8500"
+		}
+	case BackendEtcd:
+		if raw.Address == "" {
+			raw.Address = "http://127.0.0.1:2379"
+		}
+		if (raw.Username == "") != (raw.Password == "") {
+			return errors.New("The KV discovery username and password should be configured together")
+		}
+	default:
+		return fmt.Errorf("Invalid KV discovery backend %s", raw.Backend)
+	}
+	if raw.Interval == 0 {
+		raw.Interval = DefaultInterval
+	}
+	if raw.Interval < healthcheck.Duration(10*time.Second) {
+		return errors.New("The KV discovery interval should be greater or equal than 10 seconds")
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
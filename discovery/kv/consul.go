@@ -0,0 +1,113 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// consulWait the maximum duration of the Consul blocking queries
+const consulWait = "5m"
+
+type consulPair struct {
+	Key   string `json:"Key"`
+	Value []byte `json:"Value"`
+}
+
+// consulBackend reads the keys from the Consul KV store. The changes are
+// detected using blocking queries.
+type consulBackend struct {
+	config       *Configuration
+	client       *http.Client
+	streamClient *http.Client
+	url          string
+}
+
+// get lists the keys under the prefix. A blocking query is sent if index
+// is not 0.
+func (b *consulBackend) get(ctx context.Context, client *http.Client, index uint64) ([]consulPair, uint64, error) {
+	params := url.Values{}
+	params.Set("recurse", "true")
+	if index != 0 {
+		params.Set("index", strconv.FormatUint(index, 10))
+		params.Set("wait", consulWait)
+	}
+	reqURL := fmt.Sprintf("%s/v1/kv/%s?%s", b.url, strings.TrimPrefix(b.config.Prefix, "/"), params.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "KV discovery: fail to create request for %s", reqURL)
+	}
+	req.Header.Set("User-Agent", "Cabourotte")
+	if b.config.Token != "" {
+		req.Header.Set("X-Consul-Token", b.config.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "KV discovery: fail to send request to %s", reqURL)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "Fail to read request body")
+	}
+	newIndex, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	// no key under the prefix
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, newIndex, nil
+	}
+	if resp.StatusCode != 200 {
+		return nil, 0, fmt.Errorf("KV discovery: request to %s failed, status %d, body %s", reqURL, resp.StatusCode, string(body))
+	}
+	var pairs []consulPair
+	if err := json.Unmarshal(body, &pairs); err != nil {
+		return nil, 0, errors.Wrapf(err, "KV discovery: fail to convert the payload from json")
+	}
+	return pairs, newIndex, nil
+}
+
+func (b *consulBackend) list(ctx context.Context) (map[string][]byte, error) {
+	pairs, _, err := b.get(ctx, b.client, 0)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]byte)
+	for _, pair := range pairs {
+		// folders have no value
+		if strings.HasSuffix(pair.Key, "/") || len(pair.Value) == 0 {
+			continue
+		}
+		result[pair.Key] = pair.Value
+	}
+	return result, nil
+}
+
+func (b *consulBackend) watch(ctx context.Context, changed func()) error {
+	_, index, err := b.get(ctx, b.client, 0)
+	if err != nil {
+		return err
+	}
+	for {
+		if index == 0 {
+			index = 1
+		}
+		_, newIndex, err := b.get(ctx, b.streamClient, index)
+		if err != nil {
+			return err
+		}
+		if newIndex != index {
+			changed()
+		}
+		// the index can go backward, for example after a snapshot restore
+		if newIndex < index {
+			newIndex = 0
+		}
+		index = newIndex
+	}
+}
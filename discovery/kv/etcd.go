@@ -0,0 +1,184 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+type etcdHeader struct {
+	Revision string `json:"revision"`
+}
+
+type etcdRangeResponse struct {
+	Header etcdHeader `json:"header"`
+	KVs    []struct {
+		Key   []byte `json:"key"`
+		Value []byte `json:"value"`
+	} `json:"kvs"`
+}
+
+type etcdWatchResponse struct {
+	Result struct {
+		Created bool              `json:"created"`
+		Events  []json.RawMessage `json:"events"`
+	} `json:"result"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// etcdBackend reads the keys from etcd using the v3 JSON gateway. The
+// changes are detected using the watch API.
+type etcdBackend struct {
+	config       *Configuration
+	client       *http.Client
+	streamClient *http.Client
+	url          string
+}
+
+// prefixEnd returns the end of the range of the keys starting by prefix
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// all the keys
+	return []byte{0}
+}
+
+// post sends a request to the etcd JSON gateway
+func (b *etcdBackend) post(ctx context.Context, client *http.Client, path string, payload interface{}, token string) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrapf(err, "KV discovery: fail to convert the payload to json")
+	}
+	reqURL := fmt.Sprintf("%s%s", b.url, path)
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, errors.Wrapf(err, "KV discovery: fail to create request for %s", reqURL)
+	}
+	req.Header.Set("User-Agent", "Cabourotte")
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "KV discovery: fail to send request to %s", reqURL)
+	}
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("KV discovery: request to %s failed, status %d, body %s", reqURL, resp.StatusCode, string(respBody))
+	}
+	return resp, nil
+}
+
+// authenticate returns an authentication token if a username is
+// configured
+func (b *etcdBackend) authenticate(ctx context.Context) (string, error) {
+	if b.config.Username == "" {
+		return "", nil
+	}
+	resp, err := b.post(ctx, b.client, "/v3/auth/authenticate", map[string]string{
+		"name":     b.config.Username,
+		"password": b.config.Password,
+	}, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var payload struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", errors.Wrapf(err, "KV discovery: fail to read the etcd authentication response")
+	}
+	return payload.Token, nil
+}
+
+// getRange lists the keys under the prefix
+func (b *etcdBackend) getRange(ctx context.Context, token string) (*etcdRangeResponse, error) {
+	resp, err := b.post(ctx, b.client, "/v3/kv/range", map[string][]byte{
+		"key":       []byte(b.config.Prefix),
+		"range_end": prefixEnd(b.config.Prefix),
+	}, token)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var payload etcdRangeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, errors.Wrapf(err, "KV discovery: fail to convert the payload from json")
+	}
+	return &payload, nil
+}
+
+func (b *etcdBackend) list(ctx context.Context) (map[string][]byte, error) {
+	token, err := b.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := b.getRange(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]byte)
+	for _, kv := range payload.KVs {
+		if len(kv.Value) == 0 {
+			continue
+		}
+		result[string(kv.Key)] = kv.Value
+	}
+	return result, nil
+}
+
+func (b *etcdBackend) watch(ctx context.Context, changed func()) error {
+	token, err := b.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+	payload, err := b.getRange(ctx, token)
+	if err != nil {
+		return err
+	}
+	revision, err := strconv.ParseInt(payload.Header.Revision, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "KV discovery: invalid etcd revision %s", payload.Header.Revision)
+	}
+	request := map[string]interface{}{
+		"create_request": map[string]interface{}{
+			"key":            []byte(b.config.Prefix),
+			"range_end":      prefixEnd(b.config.Prefix),
+			"start_revision": strconv.FormatInt(revision+1, 10),
+		},
+	}
+	resp, err := b.post(ctx, b.streamClient, "/v3/watch", request, token)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message etcdWatchResponse
+		if err := decoder.Decode(&message); err != nil {
+			return errors.Wrapf(err, "KV discovery: fail to read the etcd watch stream")
+		}
+		if message.Error != nil {
+			return fmt.Errorf("KV discovery: etcd watch error: %s", message.Error.Message)
+		}
+		if len(message.Result.Events) != 0 {
+			changed()
+		}
+	}
+}
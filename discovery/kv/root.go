@@ -0,0 +1,195 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/discovery/directory"
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/tls"
+)
+
+// backend a key/value store
+type backend interface {
+	// list returns the values of the keys under the prefix
+	list(ctx context.Context) (map[string][]byte, error)
+	// watch blocks and calls changed each time the keys under the prefix
+	// change
+	watch(ctx context.Context, changed func()) error
+}
+
+// KVDiscovery the key/value store discovery struct
+type KVDiscovery struct {
+	Logger          *zap.Logger
+	responseCounter *prom.CounterVec
+	Healthcheck     *healthcheck.Component
+	Config          *Configuration
+	backend         backend
+	trigger         chan struct{}
+	cancel          context.CancelFunc
+	t               tomb.Tomb
+	tick            *time.Ticker
+}
+
+// New creates a new key/value store discovery
+func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, counter *prom.CounterVec) (*KVDiscovery, error) {
+	tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, "", config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+		},
+		Timeout: time.Second * 10,
+	}
+	// the watch requests are long-running and are cancelled on stop
+	streamClient := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	url := strings.TrimSuffix(config.Address, "/")
+	var b backend
+	switch config.Backend {
+	case BackendConsul:
+		b = &consulBackend{config: config, client: client, streamClient: streamClient, url: url}
+	case BackendEtcd:
+		b = &etcdBackend{config: config, client: client, streamClient: streamClient, url: url}
+	default:
+		return nil, fmt.Errorf("Invalid KV discovery backend %s", config.Backend)
+	}
+	return &KVDiscovery{
+		Logger:          logger,
+		responseCounter: counter,
+		Healthcheck:     checkComponent,
+		Config:          config,
+		backend:         b,
+		trigger:         make(chan struct{}, 1),
+	}, nil
+}
+
+// reconcile reads the keys and reloads the healthchecks. The existing
+// healthchecks are kept if a key is invalid.
+func (c *KVDiscovery) reconcile() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	values, err := c.backend.list(ctx)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var checks directory.Checks
+	for _, key := range keys {
+		var keyChecks directory.Checks
+		if err := yaml.UnmarshalStrict(values[key], &keyChecks); err != nil {
+			return errors.Wrapf(err, "KV discovery: invalid healthchecks in the key %s", key)
+		}
+		checks.CommandChecks = append(checks.CommandChecks, keyChecks.CommandChecks...)
+		checks.DNSChecks = append(checks.DNSChecks, keyChecks.DNSChecks...)
+		checks.TCPChecks = append(checks.TCPChecks, keyChecks.TCPChecks...)
+		checks.HTTPChecks = append(checks.HTTPChecks, keyChecks.HTTPChecks...)
+		checks.TLSChecks = append(checks.TLSChecks, keyChecks.TLSChecks...)
+		checks.HeartbeatChecks = append(checks.HeartbeatChecks, keyChecks.HeartbeatChecks...)
+		checks.GRPCChecks = append(checks.GRPCChecks, keyChecks.GRPCChecks...)
+		checks.ProfileChecks = append(checks.ProfileChecks, keyChecks.ProfileChecks...)
+	}
+	return c.Healthcheck.ReloadForSource(
+		c.source(),
+		c.Config.Labels,
+		checks.CommandChecks,
+		checks.DNSChecks,
+		checks.TCPChecks,
+		checks.HTTPChecks,
+		checks.TLSChecks,
+		checks.HeartbeatChecks,
+		checks.GRPCChecks,
+		checks.ProfileChecks)
+}
+
+// reconcileAndCount reconciles the healthchecks and updates the counter
+func (c *KVDiscovery) reconcileAndCount() {
+	status := "success"
+	err := c.reconcile()
+	if err != nil {
+		status = "failure"
+		c.Logger.Error(fmt.Sprintf("KV discovery error: %s", err.Error()))
+	}
+	c.responseCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+	c.Healthcheck.RecordReconciliation(c.source(), err)
+}
+
+// notify triggers a reconciliation
+func (c *KVDiscovery) notify() {
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// source returns the source of the healthchecks of the discovery
+func (c *KVDiscovery) source() string {
+	return fmt.Sprintf("%s-%s", healthcheck.SourceKVDiscovery, c.Config.Name)
+}
+
+// Start starts the key/value store discovery component
+func (c *KVDiscovery) Start() error {
+	c.tick = time.NewTicker(time.Duration(c.Config.Interval))
+	c.Healthcheck.RegisterSource(c.source())
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.t.Go(func() error {
+		c.Logger.Info(fmt.Sprintf("Starting the KV healthcheck discovery %s", c.Config.Name))
+		for {
+			c.reconcileAndCount()
+			select {
+			case <-c.tick.C:
+			case <-c.trigger:
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	c.t.Go(func() error {
+		for {
+			err := c.backend.watch(ctx, c.notify)
+			select {
+			case <-c.t.Dying():
+				return nil
+			default:
+			}
+			c.Logger.Error(fmt.Sprintf("KV discovery: watch error: %s", err.Error()))
+			// the keys may have changed while the watch was down
+			c.notify()
+			select {
+			case <-time.After(5 * time.Second):
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops the key/value store discovery component
+func (c *KVDiscovery) Stop() error {
+	c.Logger.Info("Stopping the KV discovery")
+	c.tick.Stop()
+	c.t.Kill(nil)
+	c.cancel()
+	return c.t.Wait()
+}
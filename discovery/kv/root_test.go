@@ -0,0 +1,245 @@
+package kv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+const webChecks = `
+tcp-checks:
+  - name: "web"
+    target: "127.0.0.1"
+    port: 80
+    timeout: 3s
+    interval: 10s
+`
+
+const dbChecks = `
+tcp-checks:
+  - name: "db"
+    target: "127.0.0.1"
+    port: 5432
+    timeout: 3s
+    interval: 10s
+heartbeat-checks:
+  - name: "db-backup"
+    interval: 1h
+`
+
+func newCheckComponent(t *testing.T) (*healthcheck.Component, *prom.CounterVec) {
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(zap.NewExample(), make(chan *healthcheck.Result, 10), promComponent, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "kv_discovery_requests_total",
+			Help: "Count the number of KV discovery reconciliations.",
+		},
+		[]string{"status", "name"})
+	return checkComponent, counter
+}
+
+func checkNames(checkComponent *healthcheck.Component) []string {
+	var names []string
+	for _, check := range checkComponent.ListChecks() {
+		names = append(names, check.Base().Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestReconcileConsul(t *testing.T) {
+	values := map[string]string{
+		"cabourotte/web": webChecks,
+		"cabourotte/db":  dbChecks,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/kv/cabourotte/" || r.URL.Query().Get("recurse") != "true" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		pairs := []consulPair{{Key: "cabourotte/"}}
+		for k, v := range values {
+			pairs = append(pairs, consulPair{Key: k, Value: []byte(v)})
+		}
+		w.Header().Set("X-Consul-Index", "10")
+		err := json.NewEncoder(w).Encode(pairs)
+		if err != nil {
+			t.Fatalf("Error writing body:\n%v", err)
+		}
+	}))
+	defer ts.Close()
+	checkComponent, counter := newCheckComponent(t)
+	config := Configuration{
+		Name:     "test",
+		Backend:  BackendConsul,
+		Address:  ts.URL,
+		Prefix:   "cabourotte/",
+		Token:    "token",
+		Interval: DefaultInterval,
+	}
+	discovery, err := New(zap.NewExample(), &config, checkComponent, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the KV discovery component :\n%v", err)
+	}
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("KV discovery failed\n%v", err)
+	}
+	names := checkNames(checkComponent)
+	if fmt.Sprintf("%v", names) != "[db db-backup web]" {
+		t.Fatalf("Invalid healthchecks %v", names)
+	}
+	for _, check := range checkComponent.ListChecks() {
+		if check.Base().Source != "kv-discovery-test" {
+			t.Fatalf("Invalid source %s", check.Base().Source)
+		}
+	}
+	// an invalid key keeps the existing healthchecks
+	values["cabourotte/invalid"] = "tcp-checks: [{intreval: 10s}]"
+	delete(values, "cabourotte/db")
+	err = discovery.reconcile()
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if len(checkComponent.ListChecks()) != 3 {
+		t.Fatalf("The existing healthchecks should be kept")
+	}
+	delete(values, "cabourotte/invalid")
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("KV discovery failed\n%v", err)
+	}
+	names = checkNames(checkComponent)
+	if fmt.Sprintf("%v", names) != "[web]" {
+		t.Fatalf("Invalid healthchecks %v", names)
+	}
+}
+
+func TestWatchConsul(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		index := "1"
+		if r.URL.Query().Get("index") != "" {
+			index = "2"
+		}
+		w.Header().Set("X-Consul-Index", index)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+	checkComponent, counter := newCheckComponent(t)
+	config := Configuration{
+		Name:     "test",
+		Backend:  BackendConsul,
+		Address:  ts.URL,
+		Prefix:   "cabourotte",
+		Interval: DefaultInterval,
+	}
+	discovery, err := New(zap.NewExample(), &config, checkComponent, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the KV discovery component :\n%v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	changed := make(chan struct{}, 10)
+	go func() {
+		// nolint
+		discovery.backend.watch(ctx, func() { changed <- struct{}{} })
+	}()
+	select {
+	case <-changed:
+	case <-ctx.Done():
+		t.Fatalf("The change was not detected")
+	}
+}
+
+func TestReconcileAndWatchEtcd(t *testing.T) {
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/auth/authenticate":
+			fmt.Fprint(w, `{"token": "etcd-token"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "etcd-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Fatalf("Invalid body:\n%v", err)
+		}
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			if body["key"] != encode("/cabourotte/") || body["range_end"] != encode("/cabourotte0") {
+				t.Fatalf("Invalid range %v", body)
+			}
+			fmt.Fprintf(w, `{"header": {"revision": "7"}, "kvs": [{"key": "%s", "value": "%s"}, {"key": "%s", "value": "%s"}]}`,
+				encode("/cabourotte/web"), encode(webChecks), encode("/cabourotte/db"), encode(dbChecks))
+		case "/v3/watch":
+			request := body["create_request"].(map[string]interface{})
+			if request["start_revision"] != "8" {
+				t.Fatalf("Invalid watch request %v", body)
+			}
+			fmt.Fprint(w, `{"result": {"created": true}}`+"\n")
+			fmt.Fprint(w, `{"result": {"events": [{"type": "PUT"}]}}`+"\n")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	checkComponent, counter := newCheckComponent(t)
+	config := Configuration{
+		Name:     "test",
+		Backend:  BackendEtcd,
+		Address:  ts.URL,
+		Prefix:   "/cabourotte/",
+		Username: "user",
+		Password: "pass",
+		Interval: DefaultInterval,
+	}
+	discovery, err := New(zap.NewExample(), &config, checkComponent, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the KV discovery component :\n%v", err)
+	}
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("KV discovery failed\n%v", err)
+	}
+	names := checkNames(checkComponent)
+	if fmt.Sprintf("%v", names) != "[db db-backup web]" {
+		t.Fatalf("Invalid healthchecks %v", names)
+	}
+	changes := 0
+	err = discovery.backend.watch(context.Background(), func() { changes++ })
+	if err == nil {
+		t.Fatalf("Was expecting an error at the end of the stream")
+	}
+	if changes != 1 {
+		t.Fatalf("Expected 1 change, got %d", changes)
+	}
+}
@@ -6,7 +6,15 @@ import (
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
+	"github.com/appclacks/cabourotte/discovery/consul"
+	"github.com/appclacks/cabourotte/discovery/docker"
+	"github.com/appclacks/cabourotte/discovery/ec2"
+	"github.com/appclacks/cabourotte/discovery/eureka"
+	"github.com/appclacks/cabourotte/discovery/filesd"
 	dhttp "github.com/appclacks/cabourotte/discovery/http"
+	"github.com/appclacks/cabourotte/discovery/kubernetes"
+	"github.com/appclacks/cabourotte/discovery/kv"
+	"github.com/appclacks/cabourotte/discovery/srv"
 	"github.com/appclacks/cabourotte/healthcheck"
 	"github.com/appclacks/cabourotte/prometheus"
 	prom "github.com/prometheus/client_golang/prometheus"
@@ -16,6 +24,14 @@ import (
 type Component struct {
 	Logger           *zap.Logger
 	HTTPDiscovery    []*dhttp.HTTPDiscovery
+	K8SDiscovery     []*kubernetes.KubernetesDiscovery
+	ConsulDiscovery  []*consul.ConsulDiscovery
+	SRVDiscovery     []*srv.SRVDiscovery
+	DockerDiscovery  []*docker.DockerDiscovery
+	EC2Discovery     []*ec2.EC2Discovery
+	EurekaDiscovery  []*eureka.EurekaDiscovery
+	KVDiscovery      []*kv.KVDiscovery
+	FileSDDiscovery  []*filesd.FileSDDiscovery
 	requestHistogram *prom.HistogramVec
 	responseCounter  *prom.CounterVec
 	Prometheus       *prometheus.Prometheus
@@ -71,6 +87,210 @@ func New(logger *zap.Logger, config Configuration, promComponent *prometheus.Pro
 		component.responseCounter = counter
 		component.requestHistogram = histo
 	}
+	if len(config.Kubernetes) != 0 {
+		counter := prom.NewCounterVec(
+			prom.CounterOpts{
+				Name: "kubernetes_discovery_requests_total",
+				Help: "Count the number of Kubernetes discovery reconciliations.",
+			},
+			[]string{"status", "name"})
+		err := promComponent.Register(counter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to register the kubernetes discovery counter")
+		}
+		names := make(map[string]bool)
+		for i := range config.Kubernetes {
+			configK8S := config.Kubernetes[i]
+			_, ok := names[configK8S.Name]
+			if ok {
+				return nil, fmt.Errorf("Kubernetes discovery names should be unique (duplicate found for %s)", configK8S.Name)
+			}
+			logger.Info(fmt.Sprintf("Enabling Kubernetes discovery %s", configK8S.Name))
+			k8sDiscovery, err := kubernetes.New(logger, &configK8S, healthcheck, counter)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Fail to create the Kubernetes discovery component")
+			}
+			names[configK8S.Name] = true
+			component.K8SDiscovery = append(component.K8SDiscovery, k8sDiscovery)
+		}
+	}
+	if len(config.Consul) != 0 {
+		counter := prom.NewCounterVec(
+			prom.CounterOpts{
+				Name: "consul_discovery_requests_total",
+				Help: "Count the number of Consul discovery reconciliations.",
+			},
+			[]string{"status", "name"})
+		err := promComponent.Register(counter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to register the consul discovery counter")
+		}
+		names := make(map[string]bool)
+		for i := range config.Consul {
+			configConsul := config.Consul[i]
+			_, ok := names[configConsul.Name]
+			if ok {
+				return nil, fmt.Errorf("Consul discovery names should be unique (duplicate found for %s)", configConsul.Name)
+			}
+			logger.Info(fmt.Sprintf("Enabling Consul discovery %s", configConsul.Name))
+			consulDiscovery, err := consul.New(logger, &configConsul, healthcheck, counter)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Fail to create the Consul discovery component")
+			}
+			names[configConsul.Name] = true
+			component.ConsulDiscovery = append(component.ConsulDiscovery, consulDiscovery)
+		}
+	}
+	if len(config.SRV) != 0 {
+		counter := prom.NewCounterVec(
+			prom.CounterOpts{
+				Name: "srv_discovery_requests_total",
+				Help: "Count the number of DNS SRV discovery reconciliations.",
+			},
+			[]string{"status", "name"})
+		err := promComponent.Register(counter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to register the srv discovery counter")
+		}
+		names := make(map[string]bool)
+		for i := range config.SRV {
+			configSRV := config.SRV[i]
+			_, ok := names[configSRV.Name]
+			if ok {
+				return nil, fmt.Errorf("DNS SRV discovery names should be unique (duplicate found for %s)", configSRV.Name)
+			}
+			logger.Info(fmt.Sprintf("Enabling DNS SRV discovery %s", configSRV.Name))
+			names[configSRV.Name] = true
+			component.SRVDiscovery = append(component.SRVDiscovery, srv.New(logger, &configSRV, healthcheck, counter))
+		}
+	}
+	if len(config.Docker) != 0 {
+		counter := prom.NewCounterVec(
+			prom.CounterOpts{
+				Name: "docker_discovery_requests_total",
+				Help: "Count the number of Docker discovery reconciliations.",
+			},
+			[]string{"status", "name"})
+		err := promComponent.Register(counter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to register the docker discovery counter")
+		}
+		names := make(map[string]bool)
+		for i := range config.Docker {
+			configDocker := config.Docker[i]
+			_, ok := names[configDocker.Name]
+			if ok {
+				return nil, fmt.Errorf("Docker discovery names should be unique (duplicate found for %s)", configDocker.Name)
+			}
+			logger.Info(fmt.Sprintf("Enabling Docker discovery %s", configDocker.Name))
+			dockerDiscovery, err := docker.New(logger, &configDocker, healthcheck, counter)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Fail to create the Docker discovery component")
+			}
+			names[configDocker.Name] = true
+			component.DockerDiscovery = append(component.DockerDiscovery, dockerDiscovery)
+		}
+	}
+	if len(config.EC2) != 0 {
+		counter := prom.NewCounterVec(
+			prom.CounterOpts{
+				Name: "ec2_discovery_requests_total",
+				Help: "Count the number of EC2 discovery reconciliations.",
+			},
+			[]string{"status", "name"})
+		err := promComponent.Register(counter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to register the ec2 discovery counter")
+		}
+		names := make(map[string]bool)
+		for i := range config.EC2 {
+			configEC2 := config.EC2[i]
+			_, ok := names[configEC2.Name]
+			if ok {
+				return nil, fmt.Errorf("EC2 discovery names should be unique (duplicate found for %s)", configEC2.Name)
+			}
+			logger.Info(fmt.Sprintf("Enabling EC2 discovery %s", configEC2.Name))
+			names[configEC2.Name] = true
+			component.EC2Discovery = append(component.EC2Discovery, ec2.New(logger, &configEC2, healthcheck, counter))
+		}
+	}
+	if len(config.Eureka) != 0 {
+		counter := prom.NewCounterVec(
+			prom.CounterOpts{
+				Name: "eureka_discovery_requests_total",
+				Help: "Count the number of Eureka discovery reconciliations.",
+			},
+			[]string{"status", "name"})
+		err := promComponent.Register(counter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to register the eureka discovery counter")
+		}
+		names := make(map[string]bool)
+		for i := range config.Eureka {
+			configEureka := config.Eureka[i]
+			_, ok := names[configEureka.Name]
+			if ok {
+				return nil, fmt.Errorf("Eureka discovery names should be unique (duplicate found for %s)", configEureka.Name)
+			}
+			logger.Info(fmt.Sprintf("Enabling Eureka discovery %s", configEureka.Name))
+			eurekaDiscovery, err := eureka.New(logger, &configEureka, healthcheck, counter)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Fail to create the Eureka discovery component")
+			}
+			names[configEureka.Name] = true
+			component.EurekaDiscovery = append(component.EurekaDiscovery, eurekaDiscovery)
+		}
+	}
+	if len(config.KV) != 0 {
+		counter := prom.NewCounterVec(
+			prom.CounterOpts{
+				Name: "kv_discovery_requests_total",
+				Help: "Count the number of KV discovery reconciliations.",
+			},
+			[]string{"status", "name"})
+		err := promComponent.Register(counter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to register the kv discovery counter")
+		}
+		names := make(map[string]bool)
+		for i := range config.KV {
+			configKV := config.KV[i]
+			_, ok := names[configKV.Name]
+			if ok {
+				return nil, fmt.Errorf("KV discovery names should be unique (duplicate found for %s)", configKV.Name)
+			}
+			logger.Info(fmt.Sprintf("Enabling KV discovery %s", configKV.Name))
+			kvDiscovery, err := kv.New(logger, &configKV, healthcheck, counter)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Fail to create the KV discovery component")
+			}
+			names[configKV.Name] = true
+			component.KVDiscovery = append(component.KVDiscovery, kvDiscovery)
+		}
+	}
+	if len(config.FileSD) != 0 {
+		counter := prom.NewCounterVec(
+			prom.CounterOpts{
+				Name: "filesd_discovery_requests_total",
+				Help: "Count the number of file_sd discovery reconciliations.",
+			},
+			[]string{"status", "name"})
+		err := promComponent.Register(counter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to register the file_sd discovery counter")
+		}
+		names := make(map[string]bool)
+		for i := range config.FileSD {
+			configFileSD := config.FileSD[i]
+			_, ok := names[configFileSD.Name]
+			if ok {
+				return nil, fmt.Errorf("file_sd discovery names should be unique (duplicate found for %s)", configFileSD.Name)
+			}
+			logger.Info(fmt.Sprintf("Enabling file_sd discovery %s", configFileSD.Name))
+			names[configFileSD.Name] = true
+			component.FileSDDiscovery = append(component.FileSDDiscovery, filesd.New(logger, &configFileSD, healthcheck, counter))
+		}
+	}
 	return component, nil
 }
 
@@ -85,6 +305,54 @@ func (c *Component) Start() error {
 			}
 		}
 	}
+	for i := range c.K8SDiscovery {
+		err := c.K8SDiscovery[i].Start()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.ConsulDiscovery {
+		err := c.ConsulDiscovery[i].Start()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.SRVDiscovery {
+		err := c.SRVDiscovery[i].Start()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.DockerDiscovery {
+		err := c.DockerDiscovery[i].Start()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.EC2Discovery {
+		err := c.EC2Discovery[i].Start()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.EurekaDiscovery {
+		err := c.EurekaDiscovery[i].Start()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.KVDiscovery {
+		err := c.KVDiscovery[i].Start()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.FileSDDiscovery {
+		err := c.FileSDDiscovery[i].Start()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -99,5 +367,53 @@ func (c *Component) Stop() error {
 			}
 		}
 	}
+	for i := range c.K8SDiscovery {
+		err := c.K8SDiscovery[i].Stop()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.ConsulDiscovery {
+		err := c.ConsulDiscovery[i].Stop()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.SRVDiscovery {
+		err := c.SRVDiscovery[i].Stop()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.DockerDiscovery {
+		err := c.DockerDiscovery[i].Stop()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.EC2Discovery {
+		err := c.EC2Discovery[i].Stop()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.EurekaDiscovery {
+		err := c.EurekaDiscovery[i].Stop()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.KVDiscovery {
+		err := c.KVDiscovery[i].Stop()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.FileSDDiscovery {
+		err := c.FileSDDiscovery[i].Stop()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,52 @@
+package srv
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// DefaultInterval the default interval between two resolutions
+const DefaultInterval = healthcheck.Duration(30 * time.Second)
+
+// Configuration the DNS SRV discovery configuration.
+// The records are resolved periodically and an healthcheck is created for
+// each target from the check template. The template target and port are
+// replaced by the SRV target and port, and the template name is used as
+// prefix for the healthchecks names.
+type Configuration struct {
+	Name      string
+	Records   []string
+	Interval  healthcheck.Duration                      `json:"interval"`
+	Labels    map[string]string                         `json:"labels,omitempty"`
+	HTTPCheck *healthcheck.HTTPHealthcheckConfiguration `json:"http-check,omitempty" yaml:"http-check,omitempty"`
+	TCPCheck  *healthcheck.TCPHealthcheckConfiguration  `json:"tcp-check,omitempty" yaml:"tcp-check,omitempty"`
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read DNS SRV discovery configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid DNS SRV discovery name configuration")
+	}
+	if len(raw.Records) == 0 {
+		return errors.New("The DNS SRV discovery records are missing")
+	}
+	if raw.Interval == 0 {
+		raw.Interval = DefaultInterval
+	}
+	if raw.Interval < healthcheck.Duration(10*time.Second) {
+		return errors.New("The DNS SRV discovery interval should be greater or equal than 10 seconds")
+	}
+	if (raw.HTTPCheck == nil) == (raw.TCPCheck == nil) {
+		return errors.New("The DNS SRV discovery needs exactly one check template (http-check or tcp-check)")
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
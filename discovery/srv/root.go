@@ -0,0 +1,140 @@
+package srv
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// SRVDiscovery the DNS SRV discovery struct
+type SRVDiscovery struct {
+	Logger          *zap.Logger
+	responseCounter *prom.CounterVec
+	Healthcheck     *healthcheck.Component
+	Config          *Configuration
+	lookupSRV       func(ctx context.Context, name string) ([]*net.SRV, error)
+	t               tomb.Tomb
+	tick            *time.Ticker
+}
+
+// New creates a new DNS SRV discovery
+func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, counter *prom.CounterVec) *SRVDiscovery {
+	return &SRVDiscovery{
+		Logger:          logger,
+		responseCounter: counter,
+		Healthcheck:     checkComponent,
+		Config:          config,
+		lookupSRV: func(ctx context.Context, name string) ([]*net.SRV, error) {
+			_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+			return records, err
+		},
+	}
+}
+
+// checkName builds the name of the healthcheck for a SRV target
+func (c *SRVDiscovery) checkName(prefix string, target string, port uint16) string {
+	if prefix == "" {
+		prefix = c.Config.Name
+	}
+	return fmt.Sprintf("%s-%s-%d", prefix, target, port)
+}
+
+// reconcile resolves the SRV records and reloads the healthchecks
+func (c *SRVDiscovery) reconcile() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var tcpChecks []healthcheck.TCPHealthcheckConfiguration
+	var httpChecks []healthcheck.HTTPHealthcheckConfiguration
+	for _, record := range c.Config.Records {
+		records, err := c.lookupSRV(ctx, record)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to resolve the SRV record %s", record)
+		}
+		for _, srv := range records {
+			target := strings.TrimSuffix(srv.Target, ".")
+			labels := map[string]string{
+				"record": record,
+			}
+			if c.Config.HTTPCheck != nil {
+				config := c.Config.HTTPCheck.DeepCopy()
+				config.Base.Name = c.checkName(config.Base.Name, target, srv.Port)
+				config.Target = target
+				config.Port = uint(srv.Port)
+				healthcheck.MergeLabels(&config.Base, labels)
+				if err := config.Validate(); err != nil {
+					return errors.Wrapf(err, "Invalid healthcheck template for the SRV record %s", record)
+				}
+				httpChecks = append(httpChecks, *config)
+			}
+			if c.Config.TCPCheck != nil {
+				config := c.Config.TCPCheck.DeepCopy()
+				config.Base.Name = c.checkName(config.Base.Name, target, srv.Port)
+				config.Target = target
+				config.Port = uint(srv.Port)
+				healthcheck.MergeLabels(&config.Base, labels)
+				if err := config.Validate(); err != nil {
+					return errors.Wrapf(err, "Invalid healthcheck template for the SRV record %s", record)
+				}
+				tcpChecks = append(tcpChecks, *config)
+			}
+		}
+	}
+	return c.Healthcheck.ReloadForSource(
+		c.source(),
+		c.Config.Labels,
+		nil,
+		nil,
+		tcpChecks,
+		httpChecks,
+		nil,
+		nil,
+		nil,
+		nil)
+}
+
+// source returns the source of the healthchecks of the discovery
+func (c *SRVDiscovery) source() string {
+	return fmt.Sprintf("%s-%s", healthcheck.SourceSRVDiscovery, c.Config.Name)
+}
+
+// Start starts the DNS SRV discovery component
+func (c *SRVDiscovery) Start() error {
+	c.tick = time.NewTicker(time.Duration(c.Config.Interval))
+	c.Healthcheck.RegisterSource(c.source())
+	c.t.Go(func() error {
+		c.Logger.Info(fmt.Sprintf("Starting the DNS SRV healthcheck discovery %s", c.Config.Name))
+		for {
+			status := "success"
+			err := c.reconcile()
+			if err != nil {
+				status = "failure"
+				c.Logger.Error(fmt.Sprintf("DNS SRV discovery error: %s", err.Error()))
+			}
+			c.responseCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+			c.Healthcheck.RecordReconciliation(c.source(), err)
+			select {
+			case <-c.tick.C:
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops the DNS SRV discovery component
+func (c *SRVDiscovery) Stop() error {
+	c.Logger.Info("Stopping the DNS SRV discovery")
+	c.tick.Stop()
+	c.t.Kill(nil)
+	return c.t.Wait()
+}
@@ -0,0 +1,98 @@
+package srv
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestReconcile(t *testing.T) {
+	records := map[string][]*net.SRV{
+		"_http._tcp.example.com": {
+			{Target: "a.example.com.", Port: 8080},
+			{Target: "b.example.com.", Port: 8081},
+		},
+	}
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), promComponent, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "srv_discovery_requests_total",
+			Help: "Count the number of DNS SRV discovery reconciliations.",
+		},
+		[]string{"status", "name"})
+	config := Configuration{
+		Name:     "test",
+		Records:  []string{"_http._tcp.example.com"},
+		Interval: DefaultInterval,
+		HTTPCheck: &healthcheck.HTTPHealthcheckConfiguration{
+			Base: healthcheck.Base{
+				Name:     "api",
+				Interval: healthcheck.Duration(10 * time.Second),
+			},
+			ValidStatus: []uint{200},
+			Path:        "/health",
+			Timeout:     healthcheck.Duration(5 * time.Second),
+		},
+	}
+	discovery := New(logger, &config, checkComponent, counter)
+	discovery.lookupSRV = func(ctx context.Context, name string) ([]*net.SRV, error) {
+		result, ok := records[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown record %s", name)
+		}
+		return result, nil
+	}
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("DNS SRV discovery failed\n%v", err)
+	}
+	checks := make(map[string]*healthcheck.HTTPHealthcheckConfiguration)
+	for _, check := range checkComponent.ListChecks() {
+		checks[check.Base().Name] = check.GetConfig().(*healthcheck.HTTPHealthcheckConfiguration)
+	}
+	if len(checks) != 2 {
+		t.Fatalf("Expected 2 configured healthchecks, got %v", checks)
+	}
+	check, ok := checks["api-a.example.com-8080"]
+	if !ok || check.Target != "a.example.com" || check.Port != 8080 || check.Path != "/health" {
+		t.Fatalf("Invalid healthcheck %v", check)
+	}
+	if check.Base.Labels["record"] != "_http._tcp.example.com" {
+		t.Fatalf("Invalid healthcheck labels %v", check.Base.Labels)
+	}
+	if config.HTTPCheck.Target != "" || config.HTTPCheck.Base.Labels != nil {
+		t.Fatalf("The template should not be modified")
+	}
+	records["_http._tcp.example.com"] = records["_http._tcp.example.com"][1:]
+	err = discovery.reconcile()
+	if err != nil {
+		t.Fatalf("DNS SRV discovery failed\n%v", err)
+	}
+	if len(checkComponent.ListChecks()) != 1 {
+		t.Fatalf("Expected 1 configured healthcheck")
+	}
+	delete(records, "_http._tcp.example.com")
+	err = discovery.reconcile()
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if len(checkComponent.ListChecks()) != 1 {
+		t.Fatalf("The healthchecks should be kept when the resolution fails")
+	}
+}
@@ -0,0 +1,188 @@
+package exporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+const (
+	// BreakerClosed the exporter receives the results
+	BreakerClosed string = "closed"
+	// BreakerOpen the exporter failed repeatedly, the results are buffered
+	// until the end of the cooldown
+	BreakerOpen string = "open"
+	// BreakerHalfOpen the cooldown ended, the next push closes the
+	// breaker if it succeeds or opens it again
+	BreakerHalfOpen string = "half-open"
+)
+
+// DefaultBreakerBufferSize the default number of results buffered by an
+// exporter whose circuit breaker is open
+const DefaultBreakerBufferSize = 1000
+
+// CircuitBreakerConfiguration the circuit breakers of the exporters
+type CircuitBreakerConfiguration struct {
+	// Threshold the number of consecutive failures of an exporter opening
+	// its circuit breaker. The circuit breakers are disabled if the
+	// threshold is 0.
+	Threshold uint
+	// Cooldown the delay during which an exporter whose circuit breaker
+	// is open is not used, the results being buffered
+	Cooldown healthcheck.Duration
+	// BufferSize the maximum number of results buffered by an exporter
+	// whose circuit breaker is open, the oldest results being dropped
+	BufferSize uint `yaml:"buffer-size"`
+}
+
+// Validate validates the circuit breakers configuration
+func (c *CircuitBreakerConfiguration) Validate() error {
+	if c.Threshold != 0 && c.Cooldown <= 0 {
+		return errors.New("The circuit breaker cooldown is required when the threshold is set")
+	}
+	return nil
+}
+
+// bufferSize returns the size of the buffers, DefaultBreakerBufferSize by
+// default
+func (c *CircuitBreakerConfiguration) bufferSize() int {
+	if c.BufferSize == 0 {
+		return DefaultBreakerBufferSize
+	}
+	return int(c.BufferSize)
+}
+
+// BreakerStatus the status of the circuit breaker of an exporter
+type BreakerStatus struct {
+	State string `json:"state"`
+	// Failures the number of consecutive failures
+	Failures uint `json:"failures"`
+	// OpenedAt the time the breaker was opened, if it is not closed
+	OpenedAt *time.Time `json:"opened-at,omitempty"`
+	// Buffered the number of results waiting for the breaker to close
+	Buffered int `json:"buffered"`
+}
+
+// ExporterStatus the status of an exporter
+type ExporterStatus struct {
+	Name    string        `json:"name"`
+	Started bool          `json:"started"`
+	Breaker BreakerStatus `json:"breaker"`
+}
+
+// breaker the circuit breaker of an exporter
+type breaker struct {
+	lock     sync.Mutex
+	state    string
+	failures uint
+	openedAt time.Time
+	buffer   []*healthcheck.Result
+}
+
+// newBreaker creates a closed circuit breaker
+func newBreaker() *breaker {
+	return &breaker{
+		state: BreakerClosed,
+	}
+}
+
+// ready returns true if the exporter can be used. An open breaker is
+// half-opened once the cooldown ended.
+func (b *breaker) ready(now time.Time, cooldown time.Duration) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.state != BreakerOpen {
+		return true
+	}
+	if now.Sub(b.openedAt) < cooldown {
+		return false
+	}
+	b.state = BreakerHalfOpen
+	return true
+}
+
+// halfOpen returns true if the breaker is half-open
+func (b *breaker) halfOpen() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.state == BreakerHalfOpen
+}
+
+// hold buffers a result, and returns the number of results dropped
+// because the buffer is full
+func (b *breaker) hold(result *healthcheck.Result, size int) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.buffer = append(b.buffer, result)
+	dropped := 0
+	if len(b.buffer) > size {
+		dropped = len(b.buffer) - size
+		b.buffer = b.buffer[dropped:]
+	}
+	return dropped
+}
+
+// drain returns the buffered results, in order, and empties the buffer
+func (b *breaker) drain() []*healthcheck.Result {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	results := b.buffer
+	b.buffer = nil
+	return results
+}
+
+// success closes the breaker
+func (b *breaker) success() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.state = BreakerClosed
+	b.failures = 0
+}
+
+// failure records a failure, and returns true if the breaker was opened.
+// A half-open breaker is opened again on failure.
+func (b *breaker) failure(now time.Time, threshold uint) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.failures++
+	if threshold == 0 || b.state == BreakerOpen {
+		return false
+	}
+	if b.state == BreakerHalfOpen || b.failures >= threshold {
+		b.state = BreakerOpen
+		b.openedAt = now
+		return true
+	}
+	return false
+}
+
+// status returns the status of the breaker
+func (b *breaker) status() BreakerStatus {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	status := BreakerStatus{
+		State:    b.state,
+		Failures: b.failures,
+		Buffered: len(b.buffer),
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// stateValue the value of the breaker state in the metrics
+func stateValue(state string) float64 {
+	switch state {
+	case BreakerOpen:
+		return 1
+	case BreakerHalfOpen:
+		return 2
+	default:
+		return 0
+	}
+}
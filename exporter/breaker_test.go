@@ -0,0 +1,159 @@
+package exporter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/maintenance"
+	"github.com/appclacks/cabourotte/memorystore"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+// failingExporter an exporter failing while fail is true
+type failingExporter struct {
+	started  bool
+	fail     bool
+	attempts int
+	pushed   []string
+}
+
+func (e *failingExporter) Start() error {
+	e.started = true
+	return nil
+}
+
+func (e *failingExporter) Stop() error {
+	e.started = false
+	return nil
+}
+
+func (e *failingExporter) Reconnect() error {
+	e.attempts++
+	if e.fail {
+		return errors.New("connection refused")
+	}
+	e.started = true
+	return nil
+}
+
+func (e *failingExporter) IsStarted() bool {
+	return e.started
+}
+
+func (e *failingExporter) Name() string {
+	return "failing"
+}
+
+func (e *failingExporter) GetConfig() interface{} {
+	return nil
+}
+
+func (e *failingExporter) Push(result *healthcheck.Result) error {
+	e.attempts++
+	if e.fail {
+		return errors.New("connection refused")
+	}
+	e.pushed = append(e.pushed, result.Name)
+	return nil
+}
+
+func TestBreaker(t *testing.T) {
+	b := newBreaker()
+	now := time.Now()
+	if b.failure(now, 2) || b.status().State != BreakerClosed {
+		t.Fatalf("The breaker should be closed after one failure")
+	}
+	if !b.failure(now, 2) || b.status().State != BreakerOpen || b.status().OpenedAt == nil {
+		t.Fatalf("The breaker should be open after two failures")
+	}
+	if b.ready(now.Add(time.Second), time.Minute) {
+		t.Fatalf("The breaker should not be ready during the cooldown")
+	}
+	if dropped := b.hold(&healthcheck.Result{Name: "a"}, 1); dropped != 0 {
+		t.Fatalf("Invalid number of dropped results %d", dropped)
+	}
+	if dropped := b.hold(&healthcheck.Result{Name: "b"}, 1); dropped != 1 {
+		t.Fatalf("Invalid number of dropped results %d", dropped)
+	}
+	if !b.ready(now.Add(time.Minute), time.Minute) || b.status().State != BreakerHalfOpen {
+		t.Fatalf("The breaker should be half-open after the cooldown")
+	}
+	if !b.failure(now.Add(time.Minute), 2) || b.status().State != BreakerOpen {
+		t.Fatalf("The half-open breaker should be opened again on failure")
+	}
+	results := b.drain()
+	if len(results) != 1 || results[0].Name != "b" || b.status().Buffered != 0 {
+		t.Fatalf("Invalid buffered results %v", results)
+	}
+	b.success()
+	if status := b.status(); status.State != BreakerClosed || status.Failures != 0 || status.OpenedAt != nil {
+		t.Fatalf("Invalid status after a success %+v", status)
+	}
+	// the breakers are disabled without threshold
+	b = newBreaker()
+	for i := 0; i < 10; i++ {
+		if b.failure(now, 0) {
+			t.Fatalf("The breaker should be disabled")
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger, 10),
+		maintenance.New(logger),
+		make(chan *healthcheck.Result, 10),
+		prom,
+		&Configuration{
+			CircuitBreaker: CircuitBreakerConfiguration{
+				Threshold: 2,
+				Cooldown:  healthcheck.Duration(100 * time.Millisecond),
+			},
+		})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	exporter := &failingExporter{fail: true}
+	err = component.Register(exporter)
+	if err != nil {
+		t.Fatalf("Fail to register the exporter :\n%v", err)
+	}
+	err = exporter.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the exporter :\n%v", err)
+	}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		component.push(&healthcheck.Result{Name: name})
+	}
+	// the first result is dropped, the breaker is opened by the second
+	// one and the results are then buffered without using the exporter
+	statuses := component.Statuses()
+	if len(statuses) != 1 || statuses[0].Breaker.State != BreakerOpen || statuses[0].Breaker.Buffered != 3 {
+		t.Fatalf("Invalid statuses %+v", statuses)
+	}
+	attempts := exporter.attempts
+	component.push(&healthcheck.Result{Name: "e"})
+	if exporter.attempts != attempts {
+		t.Fatalf("The exporter was used while the breaker is open")
+	}
+	time.Sleep(150 * time.Millisecond)
+	exporter.fail = false
+	component.push(&healthcheck.Result{Name: "f"})
+	if len(exporter.pushed) != 5 || exporter.pushed[0] != "b" || exporter.pushed[4] != "f" {
+		t.Fatalf("Invalid pushed results %v", exporter.pushed)
+	}
+	statuses = component.Statuses()
+	if statuses[0].Breaker.State != BreakerClosed || statuses[0].Breaker.Buffered != 0 || !statuses[0].Started {
+		t.Fatalf("Invalid statuses %+v", statuses)
+	}
+}
@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/ingest"
+)
+
+// CabourotteConfiguration the configuration of the exporter forwarding the
+// results to another Cabourotte node, on its ingest endpoint
+type CabourotteConfiguration struct {
+	Name     string
+	Host     string
+	Port     uint32
+	Protocol healthcheck.Protocol
+	Key      string `json:"key,omitempty"`
+	Cert     string `json:"cert,omitempty"`
+	Cacert   string `json:"cacert,omitempty"`
+	Insecure bool
+	// TransitionsOnly only forwards the results changing the state of
+	// the healthchecks
+	TransitionsOnly bool `json:"transitions-only,omitempty" yaml:"transitions-only"`
+	// Secret signs the payloads with HMAC-SHA256 if set, the secret of
+	// the ingest configuration of the other node
+	Secret string `json:"-" yaml:"secret"`
+}
+
+// CabourotteExporter forwards the results to another Cabourotte node. The
+// results are sent in the latest format to the ingest endpoint.
+type CabourotteExporter struct {
+	*HTTPExporter
+	Config *CabourotteConfiguration
+}
+
+// UnmarshalYAML parses the configuration of the cabourotte exporter from
+// YAML.
+func (c *CabourotteConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration CabourotteConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read cabourotte exporter configuration")
+	}
+	if raw.Host == "" {
+		return errors.New("Invalid host for the cabourotte exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the cabourotte exporter configuration")
+	}
+	if raw.Port == 0 {
+		return errors.New("Invalid port for the cabourotte exporter configuration")
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	*c = CabourotteConfiguration(raw)
+	return nil
+}
+
+// NewCabourotteExporter creates a new cabourotte exporter
+func NewCabourotteExporter(logger *zap.Logger, config *CabourotteConfiguration) (*CabourotteExporter, error) {
+	httpExporter, err := NewHTTPExporter(logger, &HTTPConfiguration{
+		Name:            config.Name,
+		Host:            config.Host,
+		Path:            ingest.Path,
+		Port:            config.Port,
+		Protocol:        config.Protocol,
+		Key:             config.Key,
+		Cert:            config.Cert,
+		Cacert:          config.Cacert,
+		Insecure:        config.Insecure,
+		TransitionsOnly: config.TransitionsOnly,
+		Secret:          config.Secret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &CabourotteExporter{
+		HTTPExporter: httpExporter,
+		Config:       config,
+	}, nil
+}
+
+// Start starts the cabourotte exporter
+func (c *CabourotteExporter) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the cabourotte exporter forwarding to %s", c.URL))
+	c.Started = true
+	return nil
+}
+
+// GetConfig returns the config of the exporter
+func (c *CabourotteExporter) GetConfig() interface{} {
+	return c.Config
+}
@@ -0,0 +1,73 @@
+package exporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/ingest"
+)
+
+func TestCabourotteExporter(t *testing.T) {
+	var path string
+	var results []healthcheck.Result
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, err := io.ReadAll(r.Body)
+		if err == nil {
+			err = json.Unmarshal(body, &results)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	config := &CabourotteConfiguration{
+		Name:     "regional",
+		Host:     "127.0.0.1",
+		Port:     uint32(port),
+		Protocol: healthcheck.HTTP,
+	}
+	exporter, err := NewCabourotteExporter(zap.NewExample(), config)
+	if err != nil {
+		t.Fatalf("Error creating the cabourotte exporter :\n%v", err)
+	}
+	err = exporter.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the cabourotte exporter:\n%v", err)
+	}
+	if exporter.Name() != "regional" || exporter.GetConfig() != config {
+		t.Fatalf("Invalid exporter configuration")
+	}
+	err = exporter.Push(&healthcheck.Result{
+		Name:                 "foo",
+		Success:              true,
+		HealthcheckTimestamp: time.Now().Unix(),
+		Node:                 &healthcheck.Node{Name: "edge-1"},
+		Relays:               []string{"regional-1"},
+	})
+	if err != nil {
+		t.Fatalf("Fail to push healthcheck result:\n%v", err)
+	}
+	if path != ingest.Path {
+		t.Fatalf("Invalid path %s", path)
+	}
+	if len(results) != 1 || results[0].Node == nil || results[0].Node.Name != "edge-1" || len(results[0].Relays) != 1 {
+		t.Fatalf("Invalid forwarded results %+v", results)
+	}
+}
@@ -1,7 +1,27 @@
 package exporter
 
+import (
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// DeduplicationConfiguration the deduplication of the failures
+type DeduplicationConfiguration struct {
+	// Window the identical consecutive failures of an healthcheck (same
+	// error category) are exported at most once per window, with the
+	// number of failures suppressed in the meantime. The deduplication is
+	// disabled if the window is 0.
+	Window healthcheck.Duration
+}
+
 // Configuration the main configuration for the exporter component
 type Configuration struct {
-	HTTP    []HTTPConfiguration
-	Riemann []RiemannConfiguration
+	HTTP           []HTTPConfiguration
+	Riemann        []RiemannConfiguration
+	Exec           []ExecConfiguration
+	Plugin         []PluginConfiguration
+	RemoteWrite    []RemoteWriteConfiguration `yaml:"remote-write"`
+	Zabbix         []ZabbixConfiguration
+	Cabourotte     []CabourotteConfiguration
+	Deduplication  DeduplicationConfiguration
+	CircuitBreaker CircuitBreakerConfiguration `yaml:"circuit-breaker"`
 }
@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// dedupEntry the latest exported failure of an healthcheck
+type dedupEntry struct {
+	category   string
+	last       time.Time
+	suppressed int64
+}
+
+// deduplicator suppresses the identical consecutive failures of the
+// healthchecks during the window. The first failure after the window is
+// exported as an heartbeat, with the number of failures suppressed.
+type deduplicator struct {
+	lock      sync.Mutex
+	window    time.Duration
+	entries   map[string]*dedupEntry
+	lastPurge time.Time
+}
+
+// newDeduplicator creates a new deduplicator
+func newDeduplicator(window time.Duration) *deduplicator {
+	return &deduplicator{
+		window:  window,
+		entries: make(map[string]*dedupEntry),
+	}
+}
+
+// setWindow updates the deduplication window
+func (d *deduplicator) setWindow(window time.Duration) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.window = window
+	if window == 0 {
+		d.entries = make(map[string]*dedupEntry)
+	}
+}
+
+// failureCategory returns the error class of a failure
+func failureCategory(result *healthcheck.Result) string {
+	if result.ErrorCategory != "" {
+		return result.ErrorCategory
+	}
+	return result.Message
+}
+
+// allow returns true if the result should be exported, and the number of
+// identical failures suppressed since the previous exported one.
+// The successful results and the state transitions are always exported.
+func (d *deduplicator) allow(result *healthcheck.Result, now time.Time) (bool, int64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.window == 0 {
+		return true, 0
+	}
+	d.purge(now)
+	id := result.ID()
+	if result.Success {
+		delete(d.entries, id)
+		return true, 0
+	}
+	category := failureCategory(result)
+	entry, ok := d.entries[id]
+	if !ok || entry.category != category || result.Transition() {
+		d.entries[id] = &dedupEntry{category: category, last: now}
+		return true, 0
+	}
+	if now.Sub(entry.last) < d.window {
+		entry.suppressed++
+		return false, 0
+	}
+	suppressed := entry.suppressed
+	entry.last = now
+	entry.suppressed = 0
+	return true, suppressed
+}
+
+// purge removes the entries which did not suppress any failure during the
+// last window, in order to not keep the removed healthchecks
+func (d *deduplicator) purge(now time.Time) {
+	if now.Sub(d.lastPurge) < d.window {
+		return
+	}
+	for id, entry := range d.entries {
+		if entry.suppressed == 0 && now.Sub(entry.last) >= d.window {
+			delete(d.entries, id)
+		}
+	}
+	d.lastPurge = now
+}
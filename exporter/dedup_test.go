@@ -0,0 +1,43 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestDeduplicatorAllow(t *testing.T) {
+	dedup := newDeduplicator(time.Minute)
+	start := time.Now()
+	failure := func(category string) *healthcheck.Result {
+		return &healthcheck.Result{Name: "foo", ErrorCategory: category}
+	}
+	cases := []struct {
+		result     *healthcheck.Result
+		offset     time.Duration
+		allowed    bool
+		suppressed int64
+	}{
+		{failure(healthcheck.ErrorTimeout), 0, true, 0},
+		{failure(healthcheck.ErrorTimeout), 10 * time.Second, false, 0},
+		{failure(healthcheck.ErrorTimeout), 20 * time.Second, false, 0},
+		{failure(healthcheck.ErrorTimeout), 70 * time.Second, true, 2},
+		{failure(healthcheck.ErrorTimeout), 80 * time.Second, false, 0},
+		{failure(healthcheck.ErrorDNS), 90 * time.Second, true, 0},
+		{&healthcheck.Result{Name: "foo", ErrorCategory: healthcheck.ErrorDNS, PreviousState: healthcheck.StateHealthy}, 100 * time.Second, true, 0},
+		{&healthcheck.Result{Name: "foo", Success: true}, 110 * time.Second, true, 0},
+		{failure(healthcheck.ErrorDNS), 120 * time.Second, true, 0},
+	}
+	for i, c := range cases {
+		allowed, suppressed := dedup.allow(c.result, start.Add(c.offset))
+		if allowed != c.allowed || suppressed != c.suppressed {
+			t.Fatalf("Invalid result for the case %d: %t %d", i, allowed, suppressed)
+		}
+	}
+	dedup.setWindow(0)
+	allowed, _ := dedup.allow(failure(healthcheck.ErrorDNS), start.Add(130*time.Second))
+	if !allowed {
+		t.Fatalf("The deduplication should be disabled")
+	}
+}
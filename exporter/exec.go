@@ -0,0 +1,176 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// ExecConfiguration the configuration for the exec exporter.
+// The command is started once and receives the healthchecks results
+// as JSON documents (one per line) on its standard input.
+type ExecConfiguration struct {
+	Name      string
+	Command   string
+	Arguments []string
+	// TransitionsOnly only exports the results changing the state of
+	// the healthchecks
+	TransitionsOnly bool `json:"transitions-only,omitempty" yaml:"transitions-only"`
+	// SchemaVersion the version of the results format, the latest
+	// version by default. The version 1 is the format before the
+	// versioning, without the fields added since.
+	SchemaVersion int `json:"schema-version,omitempty" yaml:"schema-version"`
+}
+
+// ExecExporter the exec exporter struct
+type ExecExporter struct {
+	Started bool
+	Logger  *zap.Logger
+	Config  *ExecConfiguration
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lock  sync.Mutex
+}
+
+// UnmarshalYAML parses the configuration of the exec exporter from YAML.
+func (c *ExecConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration ExecConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read exec exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the exec exporter configuration")
+	}
+	if raw.Command == "" {
+		return errors.New("Invalid command for the exec exporter configuration")
+	}
+	if err := healthcheck.ValidateSchemaVersion(raw.SchemaVersion); err != nil {
+		return err
+	}
+	*c = ExecConfiguration(raw)
+	return nil
+}
+
+// NewExecExporter creates a new exec exporter
+func NewExecExporter(logger *zap.Logger, config *ExecConfiguration) (*ExecExporter, error) {
+	return &ExecExporter{
+		Logger: logger,
+		Config: config,
+	}, nil
+}
+
+// startProcess starts the exporter subprocess.
+// The function is *not* thread-safe.
+func (c *ExecExporter) startProcess() error {
+	cmd := exec.Command(c.Config.Command, c.Config.Arguments...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to get the stdin of the exec exporter %s", c.Config.Name)
+	}
+	err = cmd.Start()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to start the exec exporter %s", c.Config.Name)
+	}
+	c.cmd = cmd
+	c.stdin = stdin
+	c.Started = true
+	return nil
+}
+
+// stopProcess stops the exporter subprocess. Closing stdin lets the
+// process flush and exit by itself.
+// The function is *not* thread-safe.
+func (c *ExecExporter) stopProcess() error {
+	c.Started = false
+	if c.cmd == nil {
+		return nil
+	}
+	err := c.stdin.Close()
+	if err != nil {
+		c.Logger.Error(fmt.Sprintf("Fail to close stdin for the exec exporter %s: %s", c.Config.Name, err.Error()))
+	}
+	err = c.cmd.Wait()
+	c.cmd = nil
+	if err != nil {
+		return errors.Wrapf(err, "The exec exporter %s process exited with an error", c.Config.Name)
+	}
+	return nil
+}
+
+// Start starts the exec exporter component
+func (c *ExecExporter) Start() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.Logger.Info(fmt.Sprintf("Starting the exec healthcheck exporter %s", c.Config.Name))
+	return c.startProcess()
+}
+
+// Reconnect restarts the exporter subprocess
+func (c *ExecExporter) Reconnect() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.Logger.Info(fmt.Sprintf("Exec exporter %s: restarting the process", c.Config.Name))
+	err := c.stopProcess()
+	if err != nil {
+		c.Logger.Error(err.Error())
+	}
+	return c.startProcess()
+}
+
+// Stop stops the exec exporter component
+func (c *ExecExporter) Stop() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.Logger.Info(fmt.Sprintf("Stopping the exec exporter %s", c.Config.Name))
+	return c.stopProcess()
+}
+
+// Name returns the name of the exporter
+func (c *ExecExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *ExecExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// TransitionsOnly returns true if the exporter only receives the state
+// transitions
+func (c *ExecExporter) TransitionsOnly() bool {
+	return c.Config.TransitionsOnly
+}
+
+// IsStarted returns the exporter status
+func (c *ExecExporter) IsStarted() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Started
+}
+
+// Push writes the result on the process stdin
+func (c *ExecExporter) Push(result *healthcheck.Result) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	jsonBytes, err := result.MarshalVersion(c.Config.SchemaVersion)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to convert result to json:\n%v", result)
+	}
+	jsonBytes = append(jsonBytes, '\n')
+	_, err = c.stdin.Write(jsonBytes)
+	if err != nil {
+		return errors.Wrapf(err, "Exec exporter: fail to write to the %s process", c.Config.Name)
+	}
+	return nil
+}
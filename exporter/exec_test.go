@@ -0,0 +1,70 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestExecExporter(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "results")
+	exporter, err := NewExecExporter(
+		zap.NewExample(),
+		&ExecConfiguration{
+			Name:      "foo",
+			Command:   "sh",
+			Arguments: []string{"-c", fmt.Sprintf("cat > %s", output)},
+		})
+	if err != nil {
+		t.Fatalf("Error creating the exec exporter :\n%v", err)
+	}
+	err = exporter.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the exec exporter:\n%v", err)
+	}
+	result := &healthcheck.Result{
+		Name:                 "foo",
+		Success:              true,
+		HealthcheckTimestamp: time.Now().Unix(),
+		Message:              "message",
+	}
+	err = exporter.Push(result)
+	if err != nil {
+		t.Fatalf("Fail to push healthcheck result:\n%v", err)
+	}
+	err = exporter.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the exec exporter:\n%v", err)
+	}
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Fail to read the exec exporter output:\n%v", err)
+	}
+	var received healthcheck.Result
+	err = json.Unmarshal(content, &received)
+	if err != nil {
+		t.Fatalf("Fail to read the exec exporter output:\n%v", err)
+	}
+	if received.Name != "foo" || !received.Success {
+		t.Fatalf("Invalid result received by the process: %s", string(content))
+	}
+}
+
+func TestPluginExporterInvalidPath(t *testing.T) {
+	_, err := NewPluginExporter(
+		zap.NewExample(),
+		&PluginConfiguration{
+			Name: "foo",
+			Path: "/does/not/exist.so",
+		})
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
@@ -2,16 +2,17 @@ package exporter
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
 	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/signature"
 	"github.com/appclacks/cabourotte/tls"
 )
 
@@ -27,6 +28,17 @@ type HTTPConfiguration struct {
 	Cert     string            `json:"cert,omitempty"`
 	Cacert   string            `json:"cacert,omitempty"`
 	Insecure bool
+	// TransitionsOnly only exports the results changing the state of
+	// the healthchecks
+	TransitionsOnly bool `json:"transitions-only,omitempty" yaml:"transitions-only"`
+	// Secret signs the payloads with HMAC-SHA256 if set, the signature
+	// and its timestamp being sent in the X-Cabourotte-Signature and
+	// X-Cabourotte-Timestamp headers
+	Secret string `json:"-" yaml:"secret"`
+	// SchemaVersion the version of the results format, the latest
+	// version by default. The version 1 is the format before the
+	// versioning, without the fields added since.
+	SchemaVersion int `json:"schema-version,omitempty" yaml:"schema-version"`
 }
 
 // HTTPExporter the http exporter struct
@@ -58,6 +70,9 @@ func (c *HTTPConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) err
 		(raw.Key == "" && raw.Cert == "")) {
 		return errors.New("Invalid certificates")
 	}
+	if err := healthcheck.ValidateSchemaVersion(raw.SchemaVersion); err != nil {
+		return err
+	}
 	*c = HTTPConfiguration(raw)
 	return nil
 }
@@ -133,11 +148,15 @@ func (c *HTTPExporter) GetConfig() interface{} {
 	return c.Config
 }
 
+// TransitionsOnly returns true if the exporter only receives the state
+// transitions
+func (c *HTTPExporter) TransitionsOnly() bool {
+	return c.Config.TransitionsOnly
+}
+
 // Push pushes events to the HTTP destination
 func (c *HTTPExporter) Push(result *healthcheck.Result) error {
-	var jsonBytes []byte
-	payload := []*healthcheck.Result{result}
-	jsonBytes, err := json.Marshal(payload)
+	jsonBytes, err := healthcheck.MarshalResults([]*healthcheck.Result{result}, c.Config.SchemaVersion)
 	if err != nil {
 		return errors.Wrapf(err, "Fail to convert result to json:\n%v", result)
 	}
@@ -149,6 +168,11 @@ func (c *HTTPExporter) Push(result *healthcheck.Result) error {
 	for k, v := range c.Config.Headers {
 		req.Header.Set(k, v)
 	}
+	if c.Config.Secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set(signature.TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(signature.SignatureHeader, signature.Sign(c.Config.Secret, timestamp, jsonBytes))
+	}
 	resp, err := c.Client.Do(req)
 	if err != nil {
 		return errors.Wrapf(err, "HTTP exporter: fail to send healthchecks to %s", c.URL)
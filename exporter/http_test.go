@@ -1,6 +1,7 @@
 package exporter
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -11,12 +12,20 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/signature"
 )
 
 func TestHTTPExporter(t *testing.T) {
 	count := 0
+	var verifyErr error
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		count++
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			verifyErr = err
+		} else {
+			verifyErr = signature.Verify("secret", r.Header.Get(signature.TimestampHeader), r.Header.Get(signature.SignatureHeader), body, time.Now(), time.Minute)
+		}
 		w.WriteHeader(http.StatusOK)
 	}))
 	defer ts.Close()
@@ -31,6 +40,7 @@ func TestHTTPExporter(t *testing.T) {
 			Host:     "127.0.0.1",
 			Port:     uint32(port),
 			Protocol: healthcheck.HTTP,
+			Secret:   "secret",
 		})
 	if err != nil {
 		t.Fatalf("Error creating the http exporter :\n%v", err)
@@ -55,4 +65,7 @@ func TestHTTPExporter(t *testing.T) {
 	if count != 1 {
 		t.Fatalf("The request counter is invalid")
 	}
+	if verifyErr != nil {
+		t.Fatalf("Invalid payload signature: %s", verifyErr.Error())
+	}
 }
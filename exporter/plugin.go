@@ -0,0 +1,69 @@
+package exporter
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// PluginSymbol is the symbol looked up in Go plugins. It should be of
+// type PluginConstructor.
+const PluginSymbol = "NewExporter"
+
+// PluginConstructor is the function a Go plugin should export (as
+// `NewExporter`) in order to provide an exporter.
+// It receives the exporter name and the free-form configuration from the
+// Cabourotte configuration file.
+type PluginConstructor func(logger *zap.Logger, name string, config map[string]string) (Exporter, error)
+
+// PluginConfiguration the configuration for exporters loaded from Go plugins
+type PluginConfiguration struct {
+	Name   string
+	Path   string
+	Config map[string]string
+}
+
+// UnmarshalYAML parses the configuration of the plugin exporter from YAML.
+func (c *PluginConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration PluginConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read plugin exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the plugin exporter configuration")
+	}
+	if raw.Path == "" {
+		return errors.New("Invalid path for the plugin exporter configuration")
+	}
+	*c = PluginConfiguration(raw)
+	return nil
+}
+
+// NewPluginExporter loads an exporter from a Go plugin
+func NewPluginExporter(logger *zap.Logger, config *PluginConfiguration) (Exporter, error) {
+	p, err := plugin.Open(config.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to load the exporter plugin %s", config.Path)
+	}
+	symbol, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to find the %s symbol in the exporter plugin %s", PluginSymbol, config.Path)
+	}
+	var constructor PluginConstructor
+	switch f := symbol.(type) {
+	case func(*zap.Logger, string, map[string]string) (Exporter, error):
+		constructor = f
+	case *PluginConstructor:
+		constructor = *f
+	default:
+		return nil, fmt.Errorf("The %s symbol in the exporter plugin %s has an invalid type %T", PluginSymbol, config.Path, symbol)
+	}
+	exporter, err := constructor(logger, config.Name, config.Config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to create the exporter from the plugin %s", config.Path)
+	}
+	return exporter, nil
+}
@@ -0,0 +1,280 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/tls"
+)
+
+const (
+	// RemoteWriteStateMetric the series of the healthchecks states, 1 if
+	// the healthcheck is healthy and 0 otherwise
+	RemoteWriteStateMetric = "healthcheck_up"
+	// RemoteWriteDurationMetric the series of the healthchecks
+	// executions durations in seconds
+	RemoteWriteDurationMetric = "healthcheck_last_duration_seconds"
+)
+
+// RemoteWriteConfiguration the configuration of the Prometheus
+// remote-write exporter
+type RemoteWriteConfiguration struct {
+	Name string
+	URL  string
+	// BearerToken is sent in the Authorization header if set
+	BearerToken string            `json:"-" yaml:"bearer-token"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Timeout     healthcheck.Duration
+	Key         string `json:"key,omitempty"`
+	Cert        string `json:"cert,omitempty"`
+	Cacert      string `json:"cacert,omitempty"`
+	Insecure    bool
+	// TransitionsOnly only exports the results changing the state of
+	// the healthchecks
+	TransitionsOnly bool `json:"transitions-only,omitempty" yaml:"transitions-only"`
+}
+
+// RemoteWriteExporter pushes the healthchecks states and durations to a
+// Prometheus remote-write endpoint
+type RemoteWriteExporter struct {
+	Started bool
+	Logger  *zap.Logger
+	Config  *RemoteWriteConfiguration
+	Client  *http.Client
+}
+
+// UnmarshalYAML parses the configuration of the remote-write exporter
+// from YAML.
+func (c *RemoteWriteConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration RemoteWriteConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read remote-write exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the remote-write exporter configuration")
+	}
+	if !strings.HasPrefix(raw.URL, "http://") && !strings.HasPrefix(raw.URL, "https://") {
+		return errors.New("Invalid URL for the remote-write exporter configuration")
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	if raw.Timeout == 0 {
+		raw.Timeout = healthcheck.Duration(time.Second * 5)
+	}
+	*c = RemoteWriteConfiguration(raw)
+	return nil
+}
+
+// NewRemoteWriteExporter creates a new remote-write exporter
+func NewRemoteWriteExporter(logger *zap.Logger, config *RemoteWriteConfiguration) (*RemoteWriteExporter, error) {
+	tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, "", config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	return &RemoteWriteExporter{
+		Logger: logger,
+		Config: config,
+		Client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+			Timeout: time.Duration(config.Timeout),
+		},
+	}, nil
+}
+
+// IsStarted returns the exporter status
+func (c *RemoteWriteExporter) IsStarted() bool {
+	return c.Started
+}
+
+// Start starts the remote-write exporter component
+func (c *RemoteWriteExporter) Start() error {
+	// nothing to do
+	c.Logger.Info(fmt.Sprintf("Starting the remote-write healthcheck exporter on %s", c.Config.URL))
+	c.Started = true
+	return nil
+}
+
+// Reconnect reconnects the remote-write exporter component
+func (c *RemoteWriteExporter) Reconnect() error {
+	// nothing to do
+	c.Started = true
+	return nil
+}
+
+// Stop stops the remote-write exporter component
+func (c *RemoteWriteExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the remote-write exporter %s", c.Config.Name))
+	c.Started = false
+	return nil
+}
+
+// Name returns the name of the exporter
+func (c *RemoteWriteExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *RemoteWriteExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// TransitionsOnly returns true if the exporter only receives the state
+// transitions
+func (c *RemoteWriteExporter) TransitionsOnly() bool {
+	return c.Config.TransitionsOnly
+}
+
+// Push pushes the series of the result to the remote-write endpoint
+func (c *RemoteWriteExporter) Push(result *healthcheck.Result) error {
+	payload := snappyEncode(writeRequest(result))
+	req, err := http.NewRequest("POST", c.Config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrapf(err, "Remote-write exporter: fail to create request for %s", c.Config.URL)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	for k, v := range c.Config.Headers {
+		req.Header.Set(k, v)
+	}
+	if c.Config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Config.BearerToken)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "Remote-write exporter: fail to send the series to %s", c.Config.URL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("Remote-write exporter: request failed, status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// seriesLabels returns the labels of the series of a result, sorted by
+// name as required by the remote-write protocol
+func seriesLabels(result *healthcheck.Result) [][2]string {
+	labels := make(map[string]string)
+	for k, v := range result.Labels {
+		labels[labelName(k)] = v
+	}
+	if result.Node != nil {
+		for k, v := range result.Node.MetricsLabels() {
+			labels[labelName(k)] = v
+		}
+	}
+	labels["name"] = result.Name
+	if result.Namespace != "" {
+		labels["namespace"] = result.Namespace
+	}
+	labels["source"] = result.Source
+	pairs := make([][2]string, 0, len(labels))
+	for k, v := range labels {
+		if v != "" {
+			pairs = append(pairs, [2]string{k, v})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i][0] < pairs[j][0]
+	})
+	return pairs
+}
+
+// labelName replaces the characters which are not allowed in the
+// Prometheus labels names by underscores
+func labelName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// writeRequest encodes the series of the result as a remote-write
+// WriteRequest protobuf message
+func writeRequest(result *healthcheck.Result) []byte {
+	labels := seriesLabels(result)
+	timestamp := result.HealthcheckTimestamp * 1000
+	up := 0.0
+	if result.Healthy() {
+		up = 1
+	}
+	var message []byte
+	message = appendTimeSeries(message, RemoteWriteStateMetric, labels, up, timestamp)
+	message = appendTimeSeries(message, RemoteWriteDurationMetric, labels, float64(result.Duration)/1000, timestamp)
+	return message
+}
+
+// appendTimeSeries appends a TimeSeries with one sample to a WriteRequest
+func appendTimeSeries(message []byte, name string, labels [][2]string, value float64, timestamp int64) []byte {
+	var series []byte
+	series = appendLabel(series, "__name__", name)
+	for _, label := range labels {
+		series = appendLabel(series, label[0], label[1])
+	}
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(timestamp))
+	series = protowire.AppendTag(series, 2, protowire.BytesType)
+	series = protowire.AppendBytes(series, sample)
+	message = protowire.AppendTag(message, 1, protowire.BytesType)
+	return protowire.AppendBytes(message, series)
+}
+
+// appendLabel appends a Label to a TimeSeries
+func appendLabel(series []byte, name string, value string) []byte {
+	var label []byte
+	label = protowire.AppendTag(label, 1, protowire.BytesType)
+	label = protowire.AppendString(label, name)
+	label = protowire.AppendTag(label, 2, protowire.BytesType)
+	label = protowire.AppendString(label, value)
+	series = protowire.AppendTag(series, 1, protowire.BytesType)
+	return protowire.AppendBytes(series, label)
+}
+
+// snappyEncode encodes the content in the snappy block format expected by
+// the remote-write endpoints. The content is stored as literals, without
+// compression, the payloads being small.
+func snappyEncode(content []byte) []byte {
+	encoded := binary.AppendUvarint(nil, uint64(len(content)))
+	for len(content) > 0 {
+		chunk := content
+		if len(chunk) > 65536 {
+			chunk = chunk[:65536]
+		}
+		n := len(chunk) - 1
+		switch {
+		case n < 60:
+			encoded = append(encoded, byte(n<<2))
+		case n < 256:
+			encoded = append(encoded, 60<<2, byte(n))
+		default:
+			encoded = append(encoded, 61<<2, byte(n), byte(n>>8))
+		}
+		encoded = append(encoded, chunk...)
+		content = content[len(chunk):]
+	}
+	return encoded
+}
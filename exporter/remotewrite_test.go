@@ -0,0 +1,206 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// snappyDecode decodes the literals of a snappy block
+func snappyDecode(t *testing.T, encoded []byte) []byte {
+	length, n := binary.Uvarint(encoded)
+	encoded = encoded[n:]
+	var decoded []byte
+	for len(encoded) > 0 {
+		tag := encoded[0]
+		if tag&3 != 0 {
+			t.Fatalf("Unexpected snappy copy tag %d", tag)
+		}
+		size := int(tag>>2) + 1
+		encoded = encoded[1:]
+		switch tag >> 2 {
+		case 60:
+			size = int(encoded[0]) + 1
+			encoded = encoded[1:]
+		case 61:
+			size = int(binary.LittleEndian.Uint16(encoded)) + 1
+			encoded = encoded[2:]
+		}
+		decoded = append(decoded, encoded[:size]...)
+		encoded = encoded[size:]
+	}
+	if uint64(len(decoded)) != length {
+		t.Fatalf("Invalid snappy length %d, expected %d", len(decoded), length)
+	}
+	return decoded
+}
+
+type remoteWriteSample struct {
+	labels    map[string]string
+	value     float64
+	timestamp int64
+}
+
+// decodeWriteRequest decodes the series of a WriteRequest, with one
+// sample per series
+func decodeWriteRequest(t *testing.T, message []byte) []remoteWriteSample {
+	fields := func(content []byte, f func(num protowire.Number, value []byte, number uint64)) {
+		for len(content) > 0 {
+			num, typ, n := protowire.ConsumeTag(content)
+			content = content[n:]
+			switch typ {
+			case protowire.BytesType:
+				value, n := protowire.ConsumeBytes(content)
+				f(num, value, 0)
+				content = content[n:]
+			case protowire.Fixed64Type:
+				value, n := protowire.ConsumeFixed64(content)
+				f(num, nil, value)
+				content = content[n:]
+			case protowire.VarintType:
+				value, n := protowire.ConsumeVarint(content)
+				f(num, nil, value)
+				content = content[n:]
+			default:
+				t.Fatalf("Unexpected wire type %d", typ)
+			}
+		}
+	}
+	var samples []remoteWriteSample
+	fields(message, func(_ protowire.Number, series []byte, _ uint64) {
+		sample := remoteWriteSample{labels: make(map[string]string)}
+		fields(series, func(num protowire.Number, value []byte, _ uint64) {
+			if num == 1 {
+				var name, labelValue string
+				fields(value, func(num protowire.Number, value []byte, _ uint64) {
+					if num == 1 {
+						name = string(value)
+					} else {
+						labelValue = string(value)
+					}
+				})
+				sample.labels[name] = labelValue
+				return
+			}
+			fields(value, func(num protowire.Number, _ []byte, number uint64) {
+				if num == 1 {
+					sample.value = math.Float64frombits(number)
+				} else {
+					sample.timestamp = int64(number)
+				}
+			})
+		})
+		samples = append(samples, sample)
+	})
+	return samples
+}
+
+func TestSnappyEncode(t *testing.T) {
+	for _, size := range []int{0, 10, 60, 61, 300, 70000, 200000} {
+		content := make([]byte, size)
+		for i := range content {
+			content[i] = byte(i)
+		}
+		decoded := snappyDecode(t, snappyEncode(content))
+		if len(decoded) != len(content) || (size != 0 && !reflect.DeepEqual(decoded, content)) {
+			t.Fatalf("Invalid snappy encoding for size %d", size)
+		}
+	}
+}
+
+func TestRemoteWriteExporter(t *testing.T) {
+	var samples []remoteWriteSample
+	var headers http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Fail to read the body: %s", err.Error())
+		}
+		samples = decodeWriteRequest(t, snappyDecode(t, body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	exporter, err := NewRemoteWriteExporter(
+		zap.NewExample(),
+		&RemoteWriteConfiguration{
+			Name:        "remote-write",
+			URL:         ts.URL,
+			BearerToken: "token",
+			Timeout:     healthcheck.Duration(time.Second),
+		})
+	if err != nil {
+		t.Fatalf("Error creating the remote-write exporter :\n%v", err)
+	}
+	timestamp := time.Now().Unix()
+	err = exporter.Push(&healthcheck.Result{
+		Name:                 "foo",
+		Namespace:            "prod",
+		Source:               "configuration",
+		Success:              false,
+		HealthcheckTimestamp: timestamp,
+		Duration:             1500,
+		Labels:               map[string]string{"app-name": "bar"},
+		Node:                 &healthcheck.Node{Name: "prober-1"},
+	})
+	if err != nil {
+		t.Fatalf("Fail to push healthcheck result:\n%v", err)
+	}
+	if headers.Get("Authorization") != "Bearer token" {
+		t.Fatalf("Invalid authorization header %s", headers.Get("Authorization"))
+	}
+	if headers.Get("Content-Encoding") != "snappy" {
+		t.Fatalf("Invalid content encoding %s", headers.Get("Content-Encoding"))
+	}
+	labels := map[string]string{
+		"name":      "foo",
+		"namespace": "prod",
+		"source":    "configuration",
+		"app_name":  "bar",
+		"node":      "prober-1",
+	}
+	expected := []remoteWriteSample{
+		{value: 0, timestamp: timestamp * 1000},
+		{value: 1.5, timestamp: timestamp * 1000},
+	}
+	for i, name := range []string{RemoteWriteStateMetric, RemoteWriteDurationMetric} {
+		expected[i].labels = map[string]string{"__name__": name}
+		for k, v := range labels {
+			expected[i].labels[k] = v
+		}
+	}
+	if !reflect.DeepEqual(samples, expected) {
+		t.Fatalf("Invalid samples %+v", samples)
+	}
+	err = exporter.Push(&healthcheck.Result{Name: "foo", Success: true})
+	if err != nil {
+		t.Fatalf("Fail to push healthcheck result:\n%v", err)
+	}
+	if samples[0].value != 1 {
+		t.Fatalf("The healthcheck should be up")
+	}
+}
+
+func TestLabelName(t *testing.T) {
+	cases := map[string]string{
+		"app":      "app",
+		"app-name": "app_name",
+		"1app":     "_app",
+		"app.v2":   "app_v2",
+	}
+	for in, want := range cases {
+		if got := labelName(in); got != want {
+			t.Fatalf("Invalid label name %s for %s", got, in)
+		}
+	}
+}
@@ -23,6 +23,9 @@ type RiemannConfiguration struct {
 	Cert     string `json:"cert,omitempty"`
 	Cacert   string `json:"cacert,omitempty"`
 	Insecure bool
+	// TransitionsOnly only exports the results changing the state of
+	// the healthchecks
+	TransitionsOnly bool `json:"transitions-only,omitempty" yaml:"transitions-only"`
 }
 
 // RiemannExporter the Riemann exporter struct
@@ -139,6 +142,12 @@ func (c *RiemannExporter) GetConfig() interface{} {
 	return c.Config
 }
 
+// TransitionsOnly returns true if the exporter only receives the state
+// transitions
+func (c *RiemannExporter) TransitionsOnly() bool {
+	return c.Config.TransitionsOnly
+}
+
 // IsStarted returns the exporter status
 func (c *RiemannExporter) IsStarted() bool {
 	return c.Started
@@ -147,23 +156,86 @@ func (c *RiemannExporter) IsStarted() bool {
 // Push pushes events to the desination
 func (c *RiemannExporter) Push(result *healthcheck.Result) error {
 	state := "ok"
-	if !result.Success {
-		state = "critical"
+	if result.State == healthcheck.StateUnknown || (result.Degraded && result.Healthy()) {
+		state = "warning"
+	} else if !result.Healthy() {
+		state = healthcheck.SeverityCritical
+		if result.Severity != "" {
+			state = result.Severity
+		}
 	}
 	attributes := map[string]string{
 		"healthcheck": result.Name,
 		"source":      result.Source,
+		"success":     fmt.Sprintf("%t", result.Success),
+		"severity":    result.Severity,
+	}
+	if result.Namespace != "" {
+		attributes["namespace"] = result.Namespace
+	}
+	if result.TraceID != "" {
+		attributes["trace-id"] = result.TraceID
+	}
+	if result.ErrorCategory != "" {
+		attributes["error-category"] = result.ErrorCategory
+	}
+	if result.ProbeID != "" {
+		attributes["probe-id"] = result.ProbeID
+	}
+	if result.Suppressed != 0 {
+		attributes["suppressed"] = fmt.Sprintf("%d", result.Suppressed)
+	}
+	if result.Target != "" {
+		attributes["target"] = result.Target
+	}
+	if result.ResolvedIP != "" {
+		attributes["resolved-ip"] = result.ResolvedIP
+	}
+	if result.Degraded {
+		attributes["degraded"] = "true"
+	}
+	if result.ExpectedFailure {
+		attributes["expected-failure"] = "true"
+	}
+	if result.Owner != "" {
+		attributes["owner"] = result.Owner
+	}
+	if result.Team != "" {
+		attributes["team"] = result.Team
+	}
+	if result.RunbookURL != "" {
+		attributes["runbook-url"] = result.RunbookURL
+	}
+	tags := []string{"cabourotte"}
+	if result.Shadow {
+		attributes["shadow"] = "true"
+		tags = append(tags, "shadow")
+	}
+	if result.Transition() {
+		attributes["previous-state"] = result.PreviousState
+		attributes["previous-state-duration"] = fmt.Sprintf("%d", result.PreviousStateDuration)
+	}
+	host := ""
+	if result.Node != nil {
+		host = result.Node.Name
+		for k, v := range result.Node.Attributes() {
+			attributes[k] = v
+		}
+	}
+	for k, v := range result.CheckAnnotations {
+		attributes[k] = v
 	}
 	for k, v := range result.Labels {
 		attributes[k] = v
 	}
 	event := &riemanngo.Event{
+		Host:        host,
 		Service:     "cabourotte-healthcheck",
 		Metric:      result.Duration,
 		Description: fmt.Sprintf("%s: %s", result.Summary, result.Message),
 		Time:        time.Unix(result.HealthcheckTimestamp, 0),
 		State:       state,
-		Tags:        []string{"cabourotte"},
+		Tags:        tags,
 		TTL:         time.Duration(c.Config.TTL),
 		Attributes:  attributes,
 	}
@@ -2,6 +2,8 @@ package exporter
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"sync"
 	"time"
 
@@ -11,11 +13,14 @@ import (
 	"gopkg.in/tomb.v2"
 
 	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/maintenance"
 	"github.com/appclacks/cabourotte/memorystore"
 	"github.com/appclacks/cabourotte/prometheus"
 )
 
-// Exporter the exporter interface
+// Exporter the exporter interface.
+// This interface is also the contract for exporters loaded from Go plugins
+// (see PluginConstructor), so it should stay stable.
 type Exporter interface {
 	Start() error
 	Stop() error
@@ -26,6 +31,12 @@ type Exporter interface {
 	Push(*healthcheck.Result) error
 }
 
+// Flusher can be implemented by exporters buffering results. Flush is
+// called before stopping the exporter on shutdown.
+type Flusher interface {
+	Flush() error
+}
+
 // Component the exporter component
 type Component struct {
 	Logger            *zap.Logger
@@ -33,18 +44,46 @@ type Component struct {
 	ChanResult        chan *healthcheck.Result
 	Exporters         map[string]Exporter
 	MemoryStore       *memorystore.MemoryStore
+	Maintenance       *maintenance.Component
 	exporterHistogram *prom.HistogramVec
 	chanResultGauge   *prom.GaugeVec
+	chanCapacityGauge prom.Gauge
+	droppedCounter    *prom.CounterVec
+	sloGauge          *prom.GaugeVec
+	breakerGauge      *prom.GaugeVec
+	bufferedGauge     *prom.GaugeVec
+	openedCounter     *prom.CounterVec
 	prometheus        *prometheus.Prometheus
 	gaugeTick         *time.Ticker
 	lock              sync.RWMutex
+	// exportersLock protects the exporters, which can be modified on reload
+	exportersLock sync.RWMutex
+	// states the latest state of the healthchecks, in order to annotate
+	// the results changing it
+	states *transitions
+	// groups the latest state of the healthchecks groups, in order to
+	// export the groups results when their states change
+	groups *transitions
+	// slos the latest state of the healthchecks SLO, in order to export
+	// the SLO results when they start or stop burning
+	slos *transitions
+	// dedup suppresses the identical consecutive failures
+	dedup *deduplicator
+	// registered the exporters added programmatically, kept on reload
+	registered map[string]Exporter
+	// breakers the circuit breakers of the exporters, by name
+	breakers     map[string]*breaker
+	breakersLock sync.Mutex
+	started      bool
+	// node the identity of the node, attached to the results
+	node *healthcheck.Node
 
 	t  tomb.Tomb
 	wg sync.WaitGroup
 }
 
-// New creates a new exporter component
-func New(logger *zap.Logger, store *memorystore.MemoryStore, chanResult chan *healthcheck.Result, promComponent *prometheus.Prometheus, config *Configuration) (*Component, error) {
+// newExporters creates the exporters from the configuration
+func newExporters(logger *zap.Logger, config *Configuration) (map[string]Exporter, error) {
 	exporters := make(map[string]Exporter)
 	for i := range config.HTTP {
 		httpConfig := config.HTTP[i]
@@ -62,6 +101,55 @@ func New(logger *zap.Logger, store *memorystore.MemoryStore, chanResult chan *he
 		}
 		exporters[riemannConfig.Name] = exporter
 	}
+	for i := range config.Exec {
+		execConfig := config.Exec[i]
+		exporter, err := NewExecExporter(logger, &execConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the exec exporter")
+		}
+		exporters[execConfig.Name] = exporter
+	}
+	for i := range config.Plugin {
+		pluginConfig := config.Plugin[i]
+		exporter, err := NewPluginExporter(logger, &pluginConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the plugin exporter")
+		}
+		exporters[pluginConfig.Name] = exporter
+	}
+	for i := range config.RemoteWrite {
+		remoteWriteConfig := config.RemoteWrite[i]
+		exporter, err := NewRemoteWriteExporter(logger, &remoteWriteConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the remote-write exporter")
+		}
+		exporters[remoteWriteConfig.Name] = exporter
+	}
+	for i := range config.Zabbix {
+		zabbixConfig := config.Zabbix[i]
+		exporter, err := NewZabbixExporter(logger, &zabbixConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the Zabbix exporter")
+		}
+		exporters[zabbixConfig.Name] = exporter
+	}
+	for i := range config.Cabourotte {
+		cabourotteConfig := config.Cabourotte[i]
+		exporter, err := NewCabourotteExporter(logger, &cabourotteConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the cabourotte exporter")
+		}
+		exporters[cabourotteConfig.Name] = exporter
+	}
+	return exporters, nil
+}
+
+// New creates a new exporter component
+func New(logger *zap.Logger, store *memorystore.MemoryStore, maintenanceComponent *maintenance.Component, chanResult chan *healthcheck.Result, promComponent *prometheus.Prometheus, config *Configuration) (*Component, error) {
+	exporters, err := newExporters(logger, config)
+	if err != nil {
+		return nil, err
+	}
 	buckets := []float64{
 		0.05, 0.1, 0.2, 0.4, 0.8, 1,
 		1.5, 2, 3, 5}
@@ -75,32 +163,106 @@ func New(logger *zap.Logger, store *memorystore.MemoryStore, chanResult chan *he
 		Name: "result_chan_size",
 		Help: "Size of the result channel.",
 	}, []string{})
-	err := promComponent.Register(histo)
+	capacityGauge := prom.NewGauge(prom.GaugeOpts{
+		Name: "result_chan_capacity",
+		Help: "Capacity of the result channel.",
+	})
+	capacityGauge.Set(float64(cap(chanResult)))
+	dropped := prom.NewCounterVec(prom.CounterOpts{
+		Name: "exporter_dropped_total",
+		Help: "Count the number of results not exported because the exporter failed or is disconnected.",
+	},
+		[]string{"name"})
+	err = promComponent.Register(histo)
 	if err != nil {
 		return nil, errors.Wrapf(err, "fail to register the exporter Prometheus histogram")
 	}
+	err = promComponent.Register(capacityGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the chan result capacity Prometheus gauge")
+	}
+	err = promComponent.Register(dropped)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the exporter dropped Prometheus counter")
+	}
 	err = promComponent.Register(gauge)
 	if err != nil {
 		return nil, errors.Wrapf(err, "fail to register the chan result Prometheus gauge")
 	}
+	sloGauge := prom.NewGaugeVec(prom.GaugeOpts{
+		Name: "healthcheck_slo_burn_rate",
+		Help: "Burn rate of the error budget of the healthchecks SLO on the long and short windows.",
+	},
+		[]string{"name", "namespace", "window"})
+	err = promComponent.Register(sloGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the SLO burn rate Prometheus gauge")
+	}
+	breakerGauge := prom.NewGaugeVec(prom.GaugeOpts{
+		Name: "exporter_circuit_breaker_state",
+		Help: "State of the circuit breaker of the exporters (0 closed, 1 open, 2 half-open).",
+	},
+		[]string{"name"})
+	err = promComponent.Register(breakerGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the exporter circuit breaker Prometheus gauge")
+	}
+	bufferedGauge := prom.NewGaugeVec(prom.GaugeOpts{
+		Name: "exporter_buffered_results",
+		Help: "Number of results buffered while the circuit breaker of the exporters is open.",
+	},
+		[]string{"name"})
+	err = promComponent.Register(bufferedGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the exporter buffered results Prometheus gauge")
+	}
+	openedCounter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "exporter_circuit_breaker_opened_total",
+		Help: "Count the number of times the circuit breaker of the exporters was opened.",
+	},
+		[]string{"name"})
+	err = promComponent.Register(openedCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the exporter circuit breaker Prometheus counter")
+	}
 	return &Component{
 		exporterHistogram: histo,
 		chanResultGauge:   gauge,
+		chanCapacityGauge: capacityGauge,
+		droppedCounter:    dropped,
+		sloGauge:          sloGauge,
+		breakerGauge:      breakerGauge,
+		bufferedGauge:     bufferedGauge,
+		openedCounter:     openedCounter,
 		MemoryStore:       store,
+		Maintenance:       maintenanceComponent,
 		Logger:            logger,
 		Config:            config,
 		ChanResult:        chanResult,
 		Exporters:         exporters,
 		prometheus:        promComponent,
 		gaugeTick:         time.NewTicker(time.Duration(time.Second * 10)),
+		states:            newTransitions(),
+		groups:            newTransitions(),
+		slos:              newTransitions(),
+		dedup:             newDeduplicator(time.Duration(config.Deduplication.Window)),
+		registered:        make(map[string]Exporter),
+		breakers:          make(map[string]*breaker),
 	}, nil
 }
 
+// SetNode attaches the node identity to the results. It should be called
+// before starting the component.
+func (c *Component) SetNode(node *healthcheck.Node) {
+	c.node = node
+}
+
 // Start starts the exporter component
 func (c *Component) Start() error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	c.Logger.Info("Starting the exporters")
+	c.exportersLock.Lock()
 	for _, exporter := range c.Exporters {
 		err := exporter.Start()
 		if err != nil {
@@ -108,6 +270,8 @@ func (c *Component) Start() error {
 			c.Logger.Error(fmt.Sprintf("fail to create the exporter %s: %s", exporter.Name(), err.Error()))
 		}
 	}
+	c.started = true
+	c.exportersLock.Unlock()
 	c.wg.Add(1)
 	c.t.Go(func() error {
 		for {
@@ -123,7 +287,29 @@ func (c *Component) Start() error {
 	go func() {
 		defer c.wg.Done()
 		for message := range c.ChanResult {
+			if len(message.Relays) != 0 {
+				// the results forwarded by other nodes were already
+				// processed by the node executing the healthcheck, they
+				// are only exported
+				c.push(message)
+				continue
+			}
+			if c.node != nil {
+				message.Node = c.node
+			}
+			if !message.Success && c.Maintenance.Silenced(message.ID(), message.Labels, time.Now()) {
+				message.Silenced = true
+			}
+			c.states.observe(message.ID(), message, time.Now())
 			c.MemoryStore.Add(message)
+			// the group results are exported even if the healthcheck
+			// result is not
+			if groupResult := c.groupChanged(message); groupResult != nil {
+				c.push(groupResult)
+			}
+			if sloResult := c.sloChanged(message); sloResult != nil {
+				c.push(sloResult)
+			}
 			if message.Success {
 				c.Logger.Info("Healthcheck successful",
 					zap.String("name", message.Name),
@@ -138,35 +324,41 @@ func (c *Component) Start() error {
 					zap.Int64("healthcheck-timestamp", message.HealthcheckTimestamp),
 				)
 			}
-			for k := range c.Exporters {
-				exporter := c.Exporters[k]
-				if exporter.IsStarted() {
-					start := time.Now()
-					err := exporter.Push(message)
-					duration := time.Since(start)
-					status := "success"
-					name := exporter.Name()
-					if err != nil {
-						c.Logger.Error(fmt.Sprintf("Failed to push healthchecks result for exporter %s: %s", name, err.Error()))
-						status = "failure"
-						err := exporter.Stop()
-						if err != nil {
-							// do not return error
-							// on purpose
-							c.Logger.Error(fmt.Sprintf("Fail to close the exporter %s: %s", name, err.Error()))
-						}
-					}
-					c.exporterHistogram.With(prom.Labels{"name": name, "status": status}).Observe(duration.Seconds())
-				}
-				if !exporter.IsStarted() {
-					err := exporter.Reconnect()
-					if err != nil {
-						// do not return error
-						// on purpose
-						c.Logger.Error(fmt.Sprintf("fail to reconnect the exporter %s: %s", exporter.Name(), err.Error()))
-					}
-				}
+			if message.Flapping {
+				// notifications are suppressed for flapping healthchecks
+				c.Logger.Debug("healthcheck is flapping, not exporting the result",
+					zap.String("name", message.Name))
+				continue
+			}
+			if message.Silenced {
+				c.Logger.Debug("healthcheck is in maintenance, not exporting the result",
+					zap.String("name", message.Name))
+				continue
 			}
+			if message.DependencyFailure {
+				// only the failing dependency is notified
+				c.Logger.Debug("healthcheck dependency is unhealthy, not exporting the result",
+					zap.String("name", message.Name))
+				continue
+			}
+			if message.Sampled() {
+				c.Logger.Debug("successful result sampled, not exporting the result",
+					zap.String("name", message.Name))
+				continue
+			}
+			allowed, suppressed := c.dedup.allow(message, time.Now())
+			if !allowed {
+				c.Logger.Debug("identical failure already exported, not exporting the result",
+					zap.String("name", message.Name))
+				continue
+			}
+			if suppressed != 0 {
+				// the result is also referenced by the memory store
+				heartbeat := *message
+				heartbeat.Suppressed = suppressed
+				message = &heartbeat
+			}
+			c.push(message)
 		}
 		c.Logger.Info("Exporter routine stopped")
 
@@ -175,6 +367,267 @@ func (c *Component) Start() error {
 	return nil
 }
 
+// Register adds an exporter which is not defined in the configuration,
+// for example when Cabourotte is embedded in another program. The
+// exporter is started if the component is started, and is kept when the
+// configuration is reloaded.
+func (c *Component) Register(exporter Exporter) error {
+	c.exportersLock.Lock()
+	defer c.exportersLock.Unlock()
+	name := exporter.Name()
+	if _, ok := c.Exporters[name]; ok {
+		return fmt.Errorf("An exporter named %s already exists", name)
+	}
+	if c.started {
+		err := exporter.Start()
+		if err != nil {
+			// do not return error on purpose, clients should be able to reconnect
+			c.Logger.Error(fmt.Sprintf("fail to create the exporter %s: %s", name, err.Error()))
+		}
+	}
+	c.Exporters[name] = exporter
+	c.registered[name] = exporter
+	return nil
+}
+
+// breaker returns the circuit breaker of an exporter
+func (c *Component) breaker(name string) *breaker {
+	c.breakersLock.Lock()
+	defer c.breakersLock.Unlock()
+	b, ok := c.breakers[name]
+	if !ok {
+		b = newBreaker()
+		c.breakers[name] = b
+	}
+	return b
+}
+
+// removeBreaker removes the circuit breaker of an exporter, the buffered
+// results being dropped
+func (c *Component) removeBreaker(name string) {
+	c.breakersLock.Lock()
+	defer c.breakersLock.Unlock()
+	if b, ok := c.breakers[name]; ok {
+		c.droppedCounter.With(prom.Labels{"name": name}).Add(float64(len(b.drain())))
+		delete(c.breakers, name)
+	}
+	c.breakerGauge.DeleteLabelValues(name)
+	c.bufferedGauge.DeleteLabelValues(name)
+}
+
+// hold buffers a result until the circuit breaker of the exporter closes
+func (c *Component) hold(name string, b *breaker, message *healthcheck.Result) {
+	if dropped := b.hold(message, c.Config.CircuitBreaker.bufferSize()); dropped != 0 {
+		c.droppedCounter.With(prom.Labels{"name": name}).Add(float64(dropped))
+	}
+}
+
+// send pushes a result to an exporter. The exporter is stopped if the
+// push fails.
+func (c *Component) send(exporter Exporter, message *healthcheck.Result) error {
+	name := exporter.Name()
+	if !exporter.IsStarted() {
+		return fmt.Errorf("the exporter %s is not started", name)
+	}
+	start := time.Now()
+	err := exporter.Push(message)
+	duration := time.Since(start)
+	status := "success"
+	if err != nil {
+		c.Logger.Error(fmt.Sprintf("Failed to push healthchecks result for exporter %s: %s", name, err.Error()))
+		status = "failure"
+		err := exporter.Stop()
+		if err != nil {
+			// do not return error
+			// on purpose
+			c.Logger.Error(fmt.Sprintf("Fail to close the exporter %s: %s", name, err.Error()))
+		}
+	}
+	c.exporterHistogram.With(prom.Labels{"name": name, "status": status}).Observe(duration.Seconds())
+	return err
+}
+
+// reconnect reconnects a stopped exporter
+func (c *Component) reconnect(exporter Exporter) {
+	err := exporter.Reconnect()
+	if err != nil {
+		// do not return error
+		// on purpose
+		c.Logger.Error(fmt.Sprintf("fail to reconnect the exporter %s: %s", exporter.Name(), err.Error()))
+	}
+}
+
+// push pushes a result to the exporters. The exporters failing
+// repeatedly are not used during the circuit breaker cooldown, their
+// results being buffered and pushed once the cooldown ends.
+func (c *Component) push(message *healthcheck.Result) {
+	c.exportersLock.RLock()
+	defer c.exportersLock.RUnlock()
+	config := c.Config.CircuitBreaker
+	now := time.Now()
+	for k := range c.Exporters {
+		exporter := c.Exporters[k]
+		// the exporters receiving the transitions notify the state
+		// changes, which are not notified for the shadow healthchecks
+		if transitionsOnly(exporter) && (!message.Transition() || message.Shadow) {
+			continue
+		}
+		name := exporter.Name()
+		b := c.breaker(name)
+		if !b.ready(now, time.Duration(config.Cooldown)) {
+			c.hold(name, b, message)
+			c.bufferedGauge.With(prom.Labels{"name": name}).Set(float64(b.status().Buffered))
+			continue
+		}
+		if b.halfOpen() && !exporter.IsStarted() {
+			c.reconnect(exporter)
+		}
+		results := append(b.drain(), message)
+		for i, result := range results {
+			err := c.send(exporter, result)
+			if err == nil {
+				b.success()
+				continue
+			}
+			if b.failure(now, config.Threshold) {
+				c.Logger.Warn(fmt.Sprintf("Opening the circuit breaker of the exporter %s for %s", name, time.Duration(config.Cooldown)))
+				c.openedCounter.With(prom.Labels{"name": name}).Inc()
+				// the results not exported are pushed once the
+				// cooldown ends
+				for _, r := range results[i:] {
+					c.hold(name, b, r)
+				}
+				break
+			}
+			c.droppedCounter.With(prom.Labels{"name": name}).Inc()
+		}
+		status := b.status()
+		c.breakerGauge.With(prom.Labels{"name": name}).Set(stateValue(status.State))
+		c.bufferedGauge.With(prom.Labels{"name": name}).Set(float64(status.Buffered))
+		if status.State != BreakerOpen && !exporter.IsStarted() {
+			c.reconnect(exporter)
+		}
+	}
+}
+
+// Statuses returns the status of the exporters and of their circuit
+// breakers, sorted by name
+func (c *Component) Statuses() []ExporterStatus {
+	c.exportersLock.RLock()
+	defer c.exportersLock.RUnlock()
+	result := make([]ExporterStatus, 0, len(c.Exporters))
+	for name, exporter := range c.Exporters {
+		result = append(result, ExporterStatus{
+			Name:    name,
+			Started: exporter.IsStarted(),
+			Breaker: c.breaker(name).status(),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// groupChanged returns the result of the group of the healthcheck if the
+// group state changed, nil otherwise
+func (c *Component) groupChanged(message *healthcheck.Result) *healthcheck.Result {
+	if message.Group == "" {
+		return nil
+	}
+	group, err := c.MemoryStore.Group(message.Namespace, message.Group)
+	if err != nil || group.State == healthcheck.StateUnknown {
+		return nil
+	}
+	result := group.Result()
+	result.Node = c.node
+	if !c.groups.observe(group.ID(), result, time.Now()) {
+		return nil
+	}
+	c.Logger.Info(fmt.Sprintf("The state of the group %s changed to %s", group.ID(), group.State))
+	return result
+}
+
+// sloChanged evaluates the SLO of the healthcheck, and returns the SLO
+// result if the SLO started or stopped burning, nil otherwise
+func (c *Component) sloChanged(message *healthcheck.Result) *healthcheck.Result {
+	if message.SLO == nil {
+		return nil
+	}
+	status, err := c.MemoryStore.GetSLO(message.ID(), time.Now())
+	if err != nil {
+		return nil
+	}
+	c.sloGauge.WithLabelValues(message.Name, message.Namespace, "long").Set(status.LongWindow.BurnRate)
+	c.sloGauge.WithLabelValues(message.Name, message.Namespace, "short").Set(status.ShortWindow.BurnRate)
+	result := status.Result()
+	result.Node = c.node
+	result.Shadow = message.Shadow
+	if !c.slos.observe(status.ID(), result, time.Now()) {
+		return nil
+	}
+	// a SLO which is not burning initially is not notified
+	if !status.Burning && result.PreviousState == healthcheck.StateUnknown {
+		return nil
+	}
+	if status.Burning {
+		c.Logger.Info(fmt.Sprintf("The error budget of the healthcheck %s is burning", status.ID()))
+	}
+	return result
+}
+
+// Reload reloads the exporters from a new configuration. Exporters whose
+// configuration did not change are kept, the others are stopped, created
+// or replaced.
+func (c *Component) Reload(config *Configuration) error {
+	newExporters, err := newExporters(c.Logger, config)
+	if err != nil {
+		return err
+	}
+	c.exportersLock.Lock()
+	defer c.exportersLock.Unlock()
+	for name, exporter := range c.registered {
+		if _, ok := newExporters[name]; ok {
+			return fmt.Errorf("The exporter %s conflicts with a registered exporter", name)
+		}
+		newExporters[name] = exporter
+	}
+	for name, exporter := range c.Exporters {
+		newExporter, ok := newExporters[name]
+		if ok && reflect.DeepEqual(exporter.GetConfig(), newExporter.GetConfig()) {
+			newExporters[name] = exporter
+			continue
+		}
+		if ok {
+			c.Logger.Info(fmt.Sprintf("Reload: updating the exporter %s", name))
+		} else {
+			c.Logger.Info(fmt.Sprintf("Reload: removing the exporter %s", name))
+		}
+		c.removeBreaker(name)
+		err := exporter.Stop()
+		if err != nil {
+			c.Logger.Error(fmt.Sprintf("Fail to stop the exporter %s: %s", name, err.Error()))
+		}
+	}
+	for name, exporter := range newExporters {
+		if existing, ok := c.Exporters[name]; ok && existing == exporter {
+			continue
+		}
+		if _, ok := c.Exporters[name]; !ok {
+			c.Logger.Info(fmt.Sprintf("Reload: adding the exporter %s", name))
+		}
+		err := exporter.Start()
+		if err != nil {
+			// do not return error on purpose, clients should be able to reconnect
+			c.Logger.Error(fmt.Sprintf("fail to create the exporter %s: %s", name, err.Error()))
+		}
+	}
+	c.Exporters = newExporters
+	c.dedup.setWindow(time.Duration(config.Deduplication.Window))
+	c.Config = config
+	return nil
+}
+
 // Stop the exporters
 func (c *Component) Stop() error {
 	c.Logger.Info("Stopping exporters")
@@ -187,9 +640,23 @@ func (c *Component) Stop() error {
 		return err
 	}
 	c.prometheus.Unregister(c.chanResultGauge)
+	c.prometheus.Unregister(c.chanCapacityGauge)
+	c.prometheus.Unregister(c.droppedCounter)
 	c.prometheus.Unregister(c.exporterHistogram)
+	c.prometheus.Unregister(c.sloGauge)
+	c.prometheus.Unregister(c.breakerGauge)
+	c.prometheus.Unregister(c.bufferedGauge)
+	c.prometheus.Unregister(c.openedCounter)
+	c.exportersLock.RLock()
+	defer c.exportersLock.RUnlock()
 	for k := range c.Exporters {
 		e := c.Exporters[k]
+		if flusher, ok := e.(Flusher); ok && e.IsStarted() {
+			err := flusher.Flush()
+			if err != nil {
+				c.Logger.Error(fmt.Sprintf("Fail to flush the exporter %s: %s", e.Name(), err.Error()))
+			}
+		}
 		err := e.Stop()
 		if err != nil {
 			return errors.Wrapf(err, "Fail to stop an exporter")
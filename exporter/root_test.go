@@ -1,8 +1,10 @@
 package exporter
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -12,6 +14,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/maintenance"
 	"github.com/appclacks/cabourotte/memorystore"
 	"github.com/appclacks/cabourotte/prometheus"
 )
@@ -39,7 +42,8 @@ func TestStartStop(t *testing.T) {
 	}
 	component, err := New(
 		logger,
-		memorystore.NewMemoryStore(logger),
+		memorystore.NewMemoryStore(logger, 10),
+		maintenance.New(logger),
 		chanResult,
 		prom,
 		&Configuration{
@@ -82,4 +86,259 @@ func TestStartStop(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error stopping the component :\n%v", err)
 	}
+	// the metrics are unregistered on stop, the component being recreated
+	// on reload
+	_, err = New(
+		logger,
+		memorystore.NewMemoryStore(logger, 10),
+		maintenance.New(logger),
+		make(chan *healthcheck.Result, 10),
+		prom,
+		&Configuration{})
+	if err != nil {
+		t.Fatalf("Error recreating the component :\n%v", err)
+	}
+}
+
+func TestGroupResult(t *testing.T) {
+	mutex := &sync.RWMutex{}
+	var groupResults []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var results []healthcheck.Result
+		err := json.NewDecoder(r.Body).Decode(&results)
+		if err != nil {
+			t.Errorf("Invalid body: %s", err.Error())
+		}
+		mutex.Lock()
+		for _, result := range results {
+			if result.Source == memorystore.SourceGroup {
+				groupResults = append(groupResults, result.State)
+			}
+		}
+		mutex.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	chanResult := make(chan *healthcheck.Result, 10)
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger, 10),
+		maintenance.New(logger),
+		chanResult,
+		prom,
+		&Configuration{
+			HTTP: []HTTPConfiguration{
+				{
+					Name:     "foo",
+					Port:     uint32(port),
+					Protocol: healthcheck.HTTP,
+				},
+			}})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Error starting the component :\n%v", err)
+	}
+	for _, r := range []struct {
+		name    string
+		success bool
+	}{{"a", true}, {"b", true}, {"a", true}, {"b", false}, {"b", false}} {
+		chanResult <- &healthcheck.Result{
+			Name:                 r.name,
+			Group:                "web",
+			Success:              r.success,
+			HealthcheckTimestamp: time.Now().Unix(),
+		}
+	}
+	// the transitions of the shadow healthchecks are not notified
+	chanResult <- &healthcheck.Result{
+		Name:                 "shadow",
+		State:                healthcheck.StateUnhealthy,
+		Shadow:               true,
+		HealthcheckTimestamp: time.Now().Unix(),
+	}
+	close(chanResult)
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Error stopping the component :\n%v", err)
+	}
+	mutex.RLock()
+	defer mutex.RUnlock()
+	expected := []string{healthcheck.StateHealthy, memorystore.GroupStateDegraded}
+	if !reflect.DeepEqual(groupResults, expected) {
+		t.Fatalf("Invalid group results %v", groupResults)
+	}
+}
+
+func TestTransitionsOnly(t *testing.T) {
+	mutex := &sync.RWMutex{}
+	var received []healthcheck.Result
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var results []healthcheck.Result
+		err := json.NewDecoder(r.Body).Decode(&results)
+		if err != nil {
+			t.Errorf("Invalid body: %s", err.Error())
+		}
+		mutex.Lock()
+		received = append(received, results...)
+		mutex.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	chanResult := make(chan *healthcheck.Result, 10)
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger, 10),
+		maintenance.New(logger),
+		chanResult,
+		prom,
+		&Configuration{
+			HTTP: []HTTPConfiguration{
+				{
+					Name:            "foo",
+					Port:            uint32(port),
+					Protocol:        healthcheck.HTTP,
+					TransitionsOnly: true,
+				},
+			}})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Error starting the component :\n%v", err)
+	}
+	for _, state := range []string{
+		healthcheck.StateUnknown,
+		healthcheck.StateHealthy,
+		healthcheck.StateHealthy,
+		healthcheck.StateUnhealthy,
+		healthcheck.StateUnhealthy,
+		healthcheck.StateHealthy,
+	} {
+		chanResult <- &healthcheck.Result{
+			Name:                 "a",
+			Success:              state == healthcheck.StateHealthy,
+			State:                state,
+			HealthcheckTimestamp: time.Now().Unix(),
+		}
+	}
+	close(chanResult)
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Error stopping the component :\n%v", err)
+	}
+	mutex.RLock()
+	defer mutex.RUnlock()
+	expected := [][2]string{
+		{healthcheck.StateUnknown, healthcheck.StateHealthy},
+		{healthcheck.StateHealthy, healthcheck.StateUnhealthy},
+		{healthcheck.StateUnhealthy, healthcheck.StateHealthy},
+	}
+	if len(received) != len(expected) {
+		t.Fatalf("Invalid results %v", received)
+	}
+	for i, result := range received {
+		if result.PreviousState != expected[i][0] || result.State != expected[i][1] {
+			t.Fatalf("Invalid transition %s -> %s, expected %v", result.PreviousState, result.State, expected[i])
+		}
+	}
+}
+
+func TestSLOResult(t *testing.T) {
+	mutex := &sync.RWMutex{}
+	var sloResults []healthcheck.Result
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var results []healthcheck.Result
+		err := json.NewDecoder(r.Body).Decode(&results)
+		if err != nil {
+			t.Errorf("Invalid body: %s", err.Error())
+		}
+		mutex.Lock()
+		for _, result := range results {
+			if result.Source == memorystore.SourceSLO {
+				sloResults = append(sloResults, result)
+			}
+		}
+		mutex.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	chanResult := make(chan *healthcheck.Result, 10)
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger, 10),
+		maintenance.New(logger),
+		chanResult,
+		prom,
+		&Configuration{
+			HTTP: []HTTPConfiguration{
+				{
+					Name:            "foo",
+					Port:            uint32(port),
+					Protocol:        healthcheck.HTTP,
+					TransitionsOnly: true,
+				},
+			}})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Error starting the component :\n%v", err)
+	}
+	slo := &healthcheck.SLO{Objective: 99}
+	for _, success := range []bool{true, true, true, false, false, true} {
+		chanResult <- &healthcheck.Result{
+			Name:                 "a",
+			Success:              success,
+			HealthcheckTimestamp: time.Now().Unix(),
+			SLO:                  slo,
+		}
+	}
+	close(chanResult)
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Error stopping the component :\n%v", err)
+	}
+	mutex.RLock()
+	defer mutex.RUnlock()
+	// the SLO is not notified until it burns, and is still burning after
+	// the recovery of the healthcheck
+	if len(sloResults) != 1 {
+		t.Fatalf("Invalid SLO results %v", sloResults)
+	}
+	result := sloResults[0]
+	if result.Name != "a-slo" || result.State != healthcheck.StateUnhealthy || result.PreviousState != healthcheck.StateHealthy {
+		t.Fatalf("Invalid SLO result %+v", result)
+	}
 }
@@ -0,0 +1,68 @@
+package exporter
+
+import (
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// TransitionsFilter can be implemented by exporters which should only
+// receive the results changing the state of the healthchecks, instead of
+// every result. The built-in exporters implement it through their
+// transitions-only option.
+type TransitionsFilter interface {
+	TransitionsOnly() bool
+}
+
+// transitionsOnly returns true if the exporter only receives the state
+// transitions
+func transitionsOnly(exporter Exporter) bool {
+	filter, ok := exporter.(TransitionsFilter)
+	return ok && filter.TransitionsOnly()
+}
+
+// stateEntry the latest state of an healthcheck and since when it is in
+// this state
+type stateEntry struct {
+	state string
+	since time.Time
+}
+
+// transitions tracks the states of the healthchecks in order to detect
+// their transitions
+type transitions struct {
+	states map[string]*stateEntry
+}
+
+// newTransitions creates a new transitions tracker
+func newTransitions() *transitions {
+	return &transitions{
+		states: make(map[string]*stateEntry),
+	}
+}
+
+// observe records the state of the result. If the state changed, the
+// result is annotated with the previous state and the time spent in it.
+// The first result of an healthcheck is a transition from the unknown
+// state, unless its state is also unknown.
+func (t *transitions) observe(id string, result *healthcheck.Result, now time.Time) bool {
+	state := result.CurrentState()
+	entry, ok := t.states[id]
+	if !ok {
+		t.states[id] = &stateEntry{state: state, since: now}
+		if state == healthcheck.StateUnknown {
+			return false
+		}
+		result.PreviousState = healthcheck.StateUnknown
+		result.PreviousStateDuration = 0
+		return true
+	}
+	if entry.state == state {
+		return false
+	}
+	result.PreviousState = entry.state
+	result.PreviousStateDuration = int64(now.Sub(entry.since).Seconds())
+	entry.state = state
+	entry.since = now
+	return true
+}
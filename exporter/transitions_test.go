@@ -0,0 +1,39 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestTransitionsObserve(t *testing.T) {
+	tracker := newTransitions()
+	start := time.Now()
+	cases := []struct {
+		result           *healthcheck.Result
+		offset           time.Duration
+		transition       bool
+		previous         string
+		previousDuration int64
+	}{
+		{&healthcheck.Result{Success: false}, 0, true, healthcheck.StateUnknown, 0},
+		{&healthcheck.Result{Success: false}, 10 * time.Second, false, "", 0},
+		{&healthcheck.Result{Success: true}, 30 * time.Second, true, healthcheck.StateUnhealthy, 30},
+		{&healthcheck.Result{Success: false, State: healthcheck.StateHealthy}, 40 * time.Second, false, "", 0},
+		{&healthcheck.Result{Success: false, State: healthcheck.StateUnhealthy}, 100 * time.Second, true, healthcheck.StateHealthy, 70},
+	}
+	for i, c := range cases {
+		transition := tracker.observe("foo", c.result, start.Add(c.offset))
+		if transition != c.transition {
+			t.Fatalf("Invalid transition for the case %d: %t", i, transition)
+		}
+		if c.result.PreviousState != c.previous || c.result.PreviousStateDuration != c.previousDuration {
+			t.Fatalf("Invalid previous state for the case %d: %s %d", i, c.result.PreviousState, c.result.PreviousStateDuration)
+		}
+	}
+	result := &healthcheck.Result{State: healthcheck.StateUnknown}
+	if tracker.observe("bar", result, start) || result.Transition() {
+		t.Fatalf("The unknown state should not be a transition")
+	}
+}
@@ -0,0 +1,231 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// zabbixHeader the header of the Zabbix protocol messages
+var zabbixHeader = []byte("ZBXD\x01")
+
+// ZabbixConfiguration the configuration of the Zabbix sender exporter
+type ZabbixConfiguration struct {
+	Name string
+	Host string
+	Port uint32
+	// Hostname the Zabbix host of the items, the node name by default
+	Hostname string
+	// HostLabel the healthcheck label containing the Zabbix host of the
+	// items, the hostname being used if the label is not set
+	HostLabel string `json:"host-label,omitempty" yaml:"host-label"`
+	// KeyPrefix the prefix of the items keys
+	KeyPrefix string `json:"key-prefix" yaml:"key-prefix"`
+	Timeout   healthcheck.Duration
+	// TransitionsOnly only exports the results changing the state of
+	// the healthchecks
+	TransitionsOnly bool `json:"transitions-only,omitempty" yaml:"transitions-only"`
+}
+
+// ZabbixExporter sends the healthchecks results as Zabbix trapper items
+// using the Zabbix sender protocol
+type ZabbixExporter struct {
+	Started bool
+	Logger  *zap.Logger
+	Config  *ZabbixConfiguration
+}
+
+// zabbixItem an item value sent to Zabbix
+type zabbixItem struct {
+	Host  string `json:"host"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Clock int64  `json:"clock"`
+}
+
+// zabbixRequest the sender data request
+type zabbixRequest struct {
+	Request string       `json:"request"`
+	Data    []zabbixItem `json:"data"`
+}
+
+// zabbixResponse the response of the Zabbix server
+type zabbixResponse struct {
+	Response string `json:"response"`
+	Info     string `json:"info"`
+}
+
+// UnmarshalYAML parses the configuration of the Zabbix exporter from YAML.
+func (c *ZabbixConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration ZabbixConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read Zabbix exporter configuration")
+	}
+	if raw.Host == "" {
+		return errors.New("Invalid host for the Zabbix exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the Zabbix exporter configuration")
+	}
+	if raw.Port == 0 {
+		raw.Port = 10051
+	}
+	if raw.KeyPrefix == "" {
+		raw.KeyPrefix = "cabourotte"
+	}
+	if raw.Timeout == 0 {
+		raw.Timeout = healthcheck.Duration(time.Second * 5)
+	}
+	*c = ZabbixConfiguration(raw)
+	return nil
+}
+
+// NewZabbixExporter creates a new Zabbix exporter
+func NewZabbixExporter(logger *zap.Logger, config *ZabbixConfiguration) (*ZabbixExporter, error) {
+	return &ZabbixExporter{
+		Logger: logger,
+		Config: config,
+	}, nil
+}
+
+// IsStarted returns the exporter status
+func (c *ZabbixExporter) IsStarted() bool {
+	return c.Started
+}
+
+// Start starts the Zabbix exporter component
+func (c *ZabbixExporter) Start() error {
+	// nothing to do, a connection is opened for each result
+	c.Logger.Info(fmt.Sprintf("Starting the Zabbix healthcheck exporter on %s:%d", c.Config.Host, c.Config.Port))
+	c.Started = true
+	return nil
+}
+
+// Reconnect reconnects the Zabbix exporter component
+func (c *ZabbixExporter) Reconnect() error {
+	// nothing to do
+	c.Started = true
+	return nil
+}
+
+// Stop stops the Zabbix exporter component
+func (c *ZabbixExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the Zabbix exporter %s", c.Config.Name))
+	c.Started = false
+	return nil
+}
+
+// Name returns the name of the exporter
+func (c *ZabbixExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *ZabbixExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// TransitionsOnly returns true if the exporter only receives the state
+// transitions
+func (c *ZabbixExporter) TransitionsOnly() bool {
+	return c.Config.TransitionsOnly
+}
+
+// zabbixKey returns the key of an item of the healthcheck, the
+// healthcheck ID being the key parameter
+func zabbixKey(prefix string, item string, id string) string {
+	return fmt.Sprintf("%s.%s[\"%s\"]", prefix, item, strings.ReplaceAll(id, "\"", "\\\""))
+}
+
+// items returns the items of a result: the status (1 if healthy, 0
+// otherwise), the duration in milliseconds and the message
+func (c *ZabbixExporter) items(result *healthcheck.Result) []zabbixItem {
+	host := c.Config.Hostname
+	if value, ok := result.Labels[c.Config.HostLabel]; ok && c.Config.HostLabel != "" {
+		host = value
+	} else if host == "" && result.Node != nil {
+		host = result.Node.Name
+	}
+	status := "0"
+	if result.Healthy() {
+		status = "1"
+	}
+	id := result.ID()
+	clock := result.HealthcheckTimestamp
+	return []zabbixItem{
+		{Host: host, Key: zabbixKey(c.Config.KeyPrefix, "status", id), Value: status, Clock: clock},
+		{Host: host, Key: zabbixKey(c.Config.KeyPrefix, "duration", id), Value: fmt.Sprintf("%d", result.Duration), Clock: clock},
+		{Host: host, Key: zabbixKey(c.Config.KeyPrefix, "message", id), Value: result.Message, Clock: clock},
+	}
+}
+
+// Push sends the items of the result to the Zabbix server
+func (c *ZabbixExporter) Push(result *healthcheck.Result) error {
+	payload, err := json.Marshal(zabbixRequest{Request: "sender data", Data: c.items(result)})
+	if err != nil {
+		return errors.Wrapf(err, "Fail to convert result to json:\n%v", result)
+	}
+	address := net.JoinHostPort(c.Config.Host, fmt.Sprintf("%d", c.Config.Port))
+	timeout := time.Duration(c.Config.Timeout)
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return errors.Wrapf(err, "Zabbix exporter: fail to connect to %s", address)
+	}
+	defer conn.Close()
+	err = conn.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return errors.Wrapf(err, "Zabbix exporter: fail to set the connection deadline")
+	}
+	var message bytes.Buffer
+	message.Write(zabbixHeader)
+	length := make([]byte, 8)
+	binary.LittleEndian.PutUint64(length, uint64(len(payload)))
+	message.Write(length)
+	message.Write(payload)
+	_, err = conn.Write(message.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, "Zabbix exporter: fail to send the items to %s", address)
+	}
+	header := make([]byte, len(zabbixHeader)+8)
+	_, err = io.ReadFull(conn, header)
+	if err != nil {
+		return errors.Wrapf(err, "Zabbix exporter: fail to read the response of %s", address)
+	}
+	if !bytes.Equal(header[:len(zabbixHeader)], zabbixHeader) {
+		return fmt.Errorf("Zabbix exporter: invalid response header from %s", address)
+	}
+	responseLength := binary.LittleEndian.Uint64(header[len(zabbixHeader):])
+	if responseLength > 1024*1024 {
+		return fmt.Errorf("Zabbix exporter: response too large from %s", address)
+	}
+	body := make([]byte, responseLength)
+	_, err = io.ReadFull(conn, body)
+	if err != nil {
+		return errors.Wrapf(err, "Zabbix exporter: fail to read the response of %s", address)
+	}
+	var response zabbixResponse
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return errors.Wrapf(err, "Zabbix exporter: invalid response from %s", address)
+	}
+	if response.Response != "success" {
+		return fmt.Errorf("Zabbix exporter: the server returned %s: %s", response.Response, response.Info)
+	}
+	// the items which are not configured on the server are only reported
+	// in the response information
+	if !strings.Contains(response.Info, "failed: 0;") {
+		c.Logger.Warn(fmt.Sprintf("Zabbix exporter %s: some items were not processed: %s", c.Config.Name, response.Info))
+	}
+	return nil
+}
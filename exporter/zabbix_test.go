@@ -0,0 +1,123 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// zabbixServer accepts one sender request and returns the response
+func zabbixServer(t *testing.T, response string) (net.Listener, chan zabbixRequest) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the Zabbix server: %s", err.Error())
+	}
+	requests := make(chan zabbixRequest, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		header := make([]byte, 13)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		body := make([]byte, binary.LittleEndian.Uint64(header[5:]))
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		var request zabbixRequest
+		if err := json.Unmarshal(body, &request); err == nil {
+			requests <- request
+		}
+		length := make([]byte, 8)
+		binary.LittleEndian.PutUint64(length, uint64(len(response)))
+		// nolint
+		conn.Write(append(append([]byte("ZBXD\x01"), length...), response...))
+	}()
+	return listener, requests
+}
+
+func TestZabbixExporter(t *testing.T) {
+	cases := []struct {
+		response string
+		host     string
+		err      bool
+	}{
+		{response: `{"response":"success","info":"processed: 3; failed: 0; total: 3; seconds spent: 0.000055"}`, host: "prober-1"},
+		{response: `{"response":"success","info":"processed: 3; failed: 0; total: 3; seconds spent: 0.000055"}`, host: "api"},
+		{response: `{"response":"failed","info":"invalid request"}`, host: "prober-1", err: true},
+	}
+	for _, c := range cases {
+		listener, requests := zabbixServer(t, c.response)
+		config := ZabbixConfiguration{
+			Name:      "zabbix",
+			Host:      "127.0.0.1",
+			Port:      uint32(listener.Addr().(*net.TCPAddr).Port),
+			KeyPrefix: "cabourotte",
+			Timeout:   healthcheck.Duration(time.Second),
+		}
+		labels := map[string]string{}
+		if c.host != "prober-1" {
+			config.HostLabel = "zabbix-host"
+			labels["zabbix-host"] = c.host
+		}
+		exporter, err := NewZabbixExporter(zap.NewExample(), &config)
+		if err != nil {
+			t.Fatalf("Fail to create the Zabbix exporter:\n%v", err)
+		}
+		err = exporter.Push(&healthcheck.Result{
+			Name:                 "foo",
+			Namespace:            "prod",
+			Success:              true,
+			HealthcheckTimestamp: 1000,
+			Duration:             12,
+			Message:              "success",
+			Labels:               labels,
+			Node:                 &healthcheck.Node{Name: "prober-1"},
+		})
+		listener.Close()
+		if c.err {
+			if err == nil {
+				t.Fatalf("Was expecting an error for the response %s", c.response)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Fail to push the result:\n%v", err)
+		}
+		request := <-requests
+		expected := zabbixRequest{
+			Request: "sender data",
+			Data: []zabbixItem{
+				{Host: c.host, Key: `cabourotte.status["prod/foo"]`, Value: "1", Clock: 1000},
+				{Host: c.host, Key: `cabourotte.duration["prod/foo"]`, Value: "12", Clock: 1000},
+				{Host: c.host, Key: `cabourotte.message["prod/foo"]`, Value: "success", Clock: 1000},
+			},
+		}
+		if !reflect.DeepEqual(request, expected) {
+			t.Fatalf("Invalid request %+v", request)
+		}
+	}
+}
+
+func TestZabbixConfigurationDefaults(t *testing.T) {
+	var config ZabbixConfiguration
+	err := yaml.Unmarshal([]byte("name: zabbix\nhost: 127.0.0.1"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration:\n%v", err)
+	}
+	if config.Port != 10051 || config.KeyPrefix != "cabourotte" || config.Timeout != healthcheck.Duration(5*time.Second) {
+		t.Fatalf("Invalid configuration defaults %+v", config)
+	}
+}
@@ -0,0 +1,88 @@
+package expression
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// function a function callable from the expressions
+type function struct {
+	arity int
+	call  func(args []interface{}) (interface{}, error)
+}
+
+// stringArgs returns the arguments, which should be strings
+func stringArgs(args []interface{}) ([]string, error) {
+	result := make([]string, len(args))
+	for i, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("expects strings, got a %s", typeName(arg))
+		}
+		result[i] = s
+	}
+	return result, nil
+}
+
+// stringFunction builds a function of strings
+func stringFunction(arity int, f func(args []string) interface{}) function {
+	return function{
+		arity: arity,
+		call: func(args []interface{}) (interface{}, error) {
+			s, err := stringArgs(args)
+			if err != nil {
+				return nil, err
+			}
+			return f(s), nil
+		},
+	}
+}
+
+// functions the functions callable from the expressions
+var functions = map[string]function{
+	"len": {
+		arity: 1,
+		call: func(args []interface{}) (interface{}, error) {
+			switch v := args[0].(type) {
+			case string:
+				return float64(utf8.RuneCountInString(v)), nil
+			case []interface{}:
+				return float64(len(v)), nil
+			case map[string]interface{}:
+				return float64(len(v)), nil
+			}
+			return nil, fmt.Errorf("expects a string, a list or an object, got a %s", typeName(args[0]))
+		},
+	},
+	"contains": stringFunction(2, func(args []string) interface{} {
+		return strings.Contains(args[0], args[1])
+	}),
+	"startsWith": stringFunction(2, func(args []string) interface{} {
+		return strings.HasPrefix(args[0], args[1])
+	}),
+	"endsWith": stringFunction(2, func(args []string) interface{} {
+		return strings.HasSuffix(args[0], args[1])
+	}),
+	"lower": stringFunction(1, func(args []string) interface{} {
+		return strings.ToLower(args[0])
+	}),
+	"upper": stringFunction(1, func(args []string) interface{} {
+		return strings.ToUpper(args[0])
+	}),
+	"matches": {
+		arity: 2,
+		call: func(args []interface{}) (interface{}, error) {
+			s, err := stringArgs(args)
+			if err != nil {
+				return nil, err
+			}
+			r, err := regexp.Compile(s[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %s", s[1], err.Error())
+			}
+			return r.MatchString(s[0]), nil
+		},
+	},
+}
@@ -0,0 +1,125 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenKind the kind of a token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdentifier
+	tokenOperator
+)
+
+// token a token of an expression
+type token struct {
+	kind     tokenKind
+	value    string
+	number   float64
+	position int
+}
+
+// operators the operators and the punctuation, the longest first
+var operators = []string{
+	"&&", "||", "==", "!=", "<=", ">=",
+	"<", ">", "!", "+", "-", "*", "/", "%", "(", ")", "[", "]", ",", ".",
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+// readString reads the string literal starting at the position, and
+// returns its value and the position following it
+func readString(source string, start int) (string, int, error) {
+	quote := source[start]
+	var builder strings.Builder
+	for i := start + 1; i < len(source); i++ {
+		c := source[i]
+		switch {
+		case c == quote:
+			return builder.String(), i + 1, nil
+		case c == '\\':
+			i++
+			if i == len(source) {
+				break
+			}
+			switch source[i] {
+			case 'n':
+				builder.WriteByte('\n')
+			case 't':
+				builder.WriteByte('\t')
+			case 'r':
+				builder.WriteByte('\r')
+			case '\\', '"', '\'':
+				builder.WriteByte(source[i])
+			default:
+				return "", 0, fmt.Errorf("Invalid escape sequence \\%c at position %d", source[i], i-1)
+			}
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("Unterminated string at position %d", start)
+}
+
+// tokenize splits an expression into tokens
+func tokenize(source string) ([]token, error) {
+	tokens := []token{}
+	i := 0
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isDigit(c):
+			start := i
+			for i < len(source) && (isDigit(source[i]) || source[i] == '.') {
+				i++
+			}
+			number, err := strconv.ParseFloat(source[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid number %s at position %d", source[start:i], start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: source[start:i], number: number, position: start})
+		case c == '"' || c == '\'':
+			value, end, err := readString(source, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value, position: i})
+			i = end
+		case isLetter(c):
+			start := i
+			for i < len(source) && (isLetter(source[i]) || isDigit(source[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdentifier, value: source[start:i], position: start})
+		default:
+			matched := false
+			for _, operator := range operators {
+				if strings.HasPrefix(source[i:], operator) {
+					tokens = append(tokens, token{kind: tokenOperator, value: operator, position: i})
+					i += len(operator)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("Unexpected character %q at position %d", c, i)
+			}
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, position: len(source)})
+	return tokens, nil
+}
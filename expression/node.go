@@ -0,0 +1,361 @@
+package expression
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// node a node of the tree of an expression
+type node interface {
+	eval(variables map[string]interface{}) (interface{}, error)
+}
+
+// literal a constant value
+type literal struct {
+	value interface{}
+}
+
+func (n *literal) eval(variables map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+// variable a variable, null if it has no value
+type variable struct {
+	name string
+}
+
+func (n *variable) eval(variables map[string]interface{}) (interface{}, error) {
+	return normalize(variables[n.name]), nil
+}
+
+// list a list of values
+type list struct {
+	items []node
+}
+
+func (n *list) eval(variables map[string]interface{}) (interface{}, error) {
+	result := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		value, err := item.eval(variables)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+// member the field of an object. The missing fields and the fields of null
+// are null.
+type member struct {
+	object node
+	name   string
+}
+
+func (n *member) eval(variables map[string]interface{}) (interface{}, error) {
+	object, err := n.object.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	switch o := object.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return normalize(o[n.name]), nil
+	}
+	return nil, fmt.Errorf("Cannot access the field %s of a %s", n.name, typeName(object))
+}
+
+// indexed an element of a list, or a field of an object. The elements out
+// of the list and the fields of null are null.
+type indexed struct {
+	object node
+	index  node
+}
+
+func (n *indexed) eval(variables map[string]interface{}) (interface{}, error) {
+	object, err := n.object.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	switch o := object.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		i, ok := index.(float64)
+		if !ok || i != math.Trunc(i) {
+			return nil, fmt.Errorf("Invalid list index %v", index)
+		}
+		if i < 0 || int(i) >= len(o) {
+			return nil, nil
+		}
+		return normalize(o[int(i)]), nil
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("Invalid object key %v", index)
+		}
+		return normalize(o[key]), nil
+	}
+	return nil, fmt.Errorf("Cannot index a %s", typeName(object))
+}
+
+// unary the ! and - operators
+type unary struct {
+	operator string
+	operand  node
+}
+
+func (n *unary) eval(variables map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	if n.operator == "!" {
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("The operator ! expects a bool, got a %s", typeName(value))
+		}
+		return !b, nil
+	}
+	number, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("The operator - expects a number, got a %s", typeName(value))
+	}
+	return -number, nil
+}
+
+// logical the && and || operators, the right operand being evaluated only
+// if needed
+type logical struct {
+	operator string
+	left     node
+	right    node
+}
+
+func (n *logical) eval(variables map[string]interface{}) (interface{}, error) {
+	for _, operand := range []node{n.left, n.right} {
+		value, err := operand.eval(variables)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("The operator %s expects bools, got a %s", n.operator, typeName(value))
+		}
+		if b == (n.operator == "||") {
+			return b, nil
+		}
+	}
+	return n.operator == "&&", nil
+}
+
+// binary the arithmetic, comparison and in operators
+type binary struct {
+	operator string
+	left     node
+	right    node
+}
+
+func (n *binary) eval(variables map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	switch n.operator {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(left, right)
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch n.operator {
+			case "+":
+				return l + r, nil
+			case "<":
+				return l < r, nil
+			case "<=":
+				return l <= r, nil
+			case ">":
+				return l > r, nil
+			case ">=":
+				return l >= r, nil
+			}
+		}
+	}
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("The operator %s is not supported between a %s and a %s", n.operator, typeName(left), typeName(right))
+	}
+	switch n.operator {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, fmt.Errorf("Division by zero")
+		}
+		return l / r, nil
+	case "%":
+		if r == 0 {
+			return nil, fmt.Errorf("Division by zero")
+		}
+		return math.Mod(l, r), nil
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	}
+	return nil, fmt.Errorf("Unknown operator %s", n.operator)
+}
+
+// call a function call
+type call struct {
+	name     string
+	function function
+	args     []node
+}
+
+func (n *call) eval(variables map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(variables)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	result, err := n.function.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", n.name, err.Error())
+	}
+	return result, nil
+}
+
+// match the matches function with a constant pattern
+type match struct {
+	value  node
+	regexp *regexp.Regexp
+}
+
+func (n *match) eval(variables map[string]interface{}) (interface{}, error) {
+	value, err := n.value.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("matches: expects a string, got a %s", typeName(value))
+	}
+	return n.regexp.MatchString(s), nil
+}
+
+// normalize converts the numbers to float64, and the lists and the objects
+// of strings to lists and objects of values
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []string:
+		result := make([]interface{}, len(v))
+		for i := range v {
+			result[i] = v[i]
+		}
+		return result
+	case map[string]string:
+		result := make(map[string]interface{}, len(v))
+		for k := range v {
+			result[k] = v[k]
+		}
+		return result
+	case map[string]float64:
+		result := make(map[string]interface{}, len(v))
+		for k := range v {
+			result[k] = v[k]
+		}
+		return result
+	}
+	return value
+}
+
+// equal compares two values
+func equal(left interface{}, right interface{}) bool {
+	return reflect.DeepEqual(left, right)
+}
+
+// contains returns true if the value is an element of the list, a key of
+// the object, or a substring of the string
+func contains(value interface{}, container interface{}) (bool, error) {
+	switch c := container.(type) {
+	case []interface{}:
+		for i := range c {
+			if equal(value, normalize(c[i])) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		key, ok := value.(string)
+		if !ok {
+			return false, fmt.Errorf("The operator in expects a string key, got a %s", typeName(value))
+		}
+		_, found := c[key]
+		return found, nil
+	case string:
+		s, ok := value.(string)
+		if !ok {
+			return false, fmt.Errorf("The operator in expects a string, got a %s", typeName(value))
+		}
+		return strings.Contains(c, s), nil
+	}
+	return false, fmt.Errorf("The operator in is not supported on a %s", typeName(container))
+}
+
+// typeName returns the name of the type of a value
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,280 @@
+package expression
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// parser builds the tree of an expression from its tokens, by recursive
+// descent. The precedence of the operators is, from the lowest: ||, &&,
+// the comparisons and in, + and -, *, / and %, the unary ! and -.
+type parser struct {
+	tokens    []token
+	position  int
+	variables map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.position]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.position]
+	if t.kind != tokenEOF {
+		p.position++
+	}
+	return t
+}
+
+// isOperator returns true if the next token is the operator
+func (p *parser) isOperator(operator string) bool {
+	t := p.peek()
+	return t.kind == tokenOperator && t.value == operator
+}
+
+// expect consumes the operator, or fails
+func (p *parser) expect(operator string) error {
+	t := p.next()
+	if t.kind != tokenOperator || t.value != operator {
+		return unexpected(t, operator)
+	}
+	return nil
+}
+
+// unexpected returns the error of an unexpected token
+func unexpected(t token, expected string) error {
+	if t.kind == tokenEOF {
+		return fmt.Errorf("Unexpected end of the expression, expected %s", expected)
+	}
+	return fmt.Errorf("Unexpected %s at position %d, expected %s", t.value, t.position, expected)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{operator: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{operator: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+// comparisons the comparison operators
+var comparisons = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		isComparison := t.kind == tokenOperator && comparisons[t.value]
+		isIn := t.kind == tokenIdentifier && t.value == "in"
+		if !isComparison && !isIn {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{operator: t.value, left: left, right: right}
+	}
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("+") || p.isOperator("-") {
+		operator := p.next().value
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{operator: operator, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("*") || p.isOperator("/") || p.isOperator("%") {
+		operator := p.next().value
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{operator: operator, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("!") || p.isOperator("-") {
+		operator := p.next().value
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{operator: operator, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.isOperator("."):
+			p.next()
+			t := p.next()
+			if t.kind != tokenIdentifier {
+				return nil, unexpected(t, "a field name")
+			}
+			n = &member{object: n, name: t.value}
+		case p.isOperator("["):
+			p.next()
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = &indexed{object: n, index: index}
+		default:
+			return n, nil
+		}
+	}
+}
+
+// parseList parses the expressions separated by commas until the closing
+// operator
+func (p *parser) parseList(closing string) ([]node, error) {
+	nodes := []node{}
+	if p.isOperator(closing) {
+		p.next()
+		return nodes, nil
+	}
+	for {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if p.isOperator(closing) {
+			p.next()
+			return nodes, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		return &literal{value: t.number}, nil
+	case tokenString:
+		return &literal{value: t.value}, nil
+	case tokenIdentifier:
+		switch t.value {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		case "null":
+			return &literal{value: nil}, nil
+		}
+		if p.isOperator("(") {
+			p.next()
+			return p.parseCall(t)
+		}
+		if !p.variables[t.value] {
+			return nil, fmt.Errorf("Unknown variable %s at position %d", t.value, t.position)
+		}
+		return &variable{name: t.value}, nil
+	case tokenOperator:
+		switch t.value {
+		case "(":
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &list{items: items}, nil
+		}
+	}
+	return nil, unexpected(t, "a value")
+}
+
+// parseCall parses the arguments of a function call
+func (p *parser) parseCall(name token) (node, error) {
+	f, ok := functions[name.value]
+	if !ok {
+		return nil, fmt.Errorf("Unknown function %s at position %d", name.value, name.position)
+	}
+	args, err := p.parseList(")")
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != f.arity {
+		return nil, fmt.Errorf("The function %s expects %d arguments, got %d", name.value, f.arity, len(args))
+	}
+	// the constant patterns are compiled once
+	if name.value == "matches" {
+		if pattern, ok := args[1].(*literal); ok {
+			s, ok := pattern.value.(string)
+			if !ok {
+				return nil, fmt.Errorf("The function matches expects a string pattern")
+			}
+			r, err := regexp.Compile(s)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid pattern %s: %s", s, err.Error())
+			}
+			return &match{value: args[0], regexp: r}, nil
+		}
+	}
+	return &call{name: name.value, function: f, args: args}, nil
+}
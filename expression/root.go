@@ -0,0 +1,67 @@
+package expression
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Expression a compiled expression. The expressions support:
+//   - the null, bool, number, string and list literals
+//   - the variables, the fields of the objects (a.b or a["b"]) and the
+//     elements of the lists (a[0]), the missing ones being null
+//   - the operators ||, &&, !, ==, !=, <, <=, >, >=, +, -, *, / and %
+//   - the in operator, testing an element of a list, a key of an object
+//     or a substring
+//   - the functions len, contains, startsWith, endsWith, lower, upper
+//     and matches (regular expressions)
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile compiles an expression referencing the variables
+func Compile(source string, variables []string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid expression %s", source)
+	}
+	p := &parser{
+		tokens:    tokens,
+		variables: make(map[string]bool, len(variables)),
+	}
+	for _, v := range variables {
+		p.variables[v] = true
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid expression %s", source)
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, errors.Wrapf(unexpected(t, "the end of the expression"), "Invalid expression %s", source)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate evaluates the expression with the values of the variables
+func (e *Expression) Evaluate(variables map[string]interface{}) (interface{}, error) {
+	return e.root.eval(variables)
+}
+
+// EvaluateBool evaluates an expression returning a bool
+func (e *Expression) EvaluateBool(variables map[string]interface{}) (bool, error) {
+	value, err := e.Evaluate(variables)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("The expression returned a %s instead of a bool", typeName(value))
+	}
+	return b, nil
+}
@@ -0,0 +1,109 @@
+package expression
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	var body interface{}
+	err := json.Unmarshal([]byte(`{"status": "up", "items": [{"name": "a"}, {"name": "b"}], "count": 2}`), &body)
+	if err != nil {
+		t.Fatalf("Fail to decode the body: %s", err.Error())
+	}
+	variables := map[string]interface{}{
+		"status":  200,
+		"headers": map[string]string{"content-type": "application/json"},
+		"json":    body,
+		"ips":     []string{"10.0.0.1", "10.0.0.2"},
+		"timings": map[string]float64{"connect": 1.5},
+	}
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{expression: "status == 200", expected: true},
+		{expression: "status >= 200 && status < 300", expected: true},
+		{expression: "!(status == 200) || false", expected: false},
+		{expression: "status / 100 % 2 - -1", expected: float64(1)},
+		{expression: "json.status == 'up'", expected: true},
+		{expression: `json["items"][1].name`, expected: "b"},
+		{expression: "json.items[5].name == null", expected: true},
+		{expression: "json.missing.field", expected: nil},
+		{expression: "len(json.items) == json.count", expected: true},
+		{expression: "'10.0.0.2' in ips", expected: true},
+		{expression: "'content-type' in headers", expected: true},
+		{expression: "'json' in headers['content-type']", expected: true},
+		{expression: "startsWith(headers['content-type'], 'application/')", expected: true},
+		{expression: "matches(ips[0], '^10\\\\.')", expected: true},
+		{expression: "matches(json.status, json.status)", expected: true},
+		{expression: "upper(json.status) + lower('!')", expected: "UP!"},
+		{expression: "timings.connect < 2.5", expected: true},
+		{expression: "status in [200, 201]", expected: true},
+		{expression: "false && json.missing.field > 1", expected: false},
+	}
+	for _, c := range cases {
+		e, err := Compile(c.expression, []string{"status", "headers", "json", "ips", "timings"})
+		if err != nil {
+			t.Fatalf("Fail to compile %s: %s", c.expression, err.Error())
+		}
+		result, err := e.Evaluate(variables)
+		if err != nil {
+			t.Fatalf("Fail to evaluate %s: %s", c.expression, err.Error())
+		}
+		if !equal(result, c.expected) {
+			t.Fatalf("Invalid result for %s: %v, expected %v", c.expression, result, c.expected)
+		}
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	cases := []string{
+		"",
+		"status ==",
+		"unknown == 1",
+		"foo(1)",
+		"len(1, 2)",
+		"matches(body, '[')",
+		"'unterminated",
+		"status == 200 200",
+		"(status == 200",
+		"status # 1",
+	}
+	for _, c := range cases {
+		_, err := Compile(c, []string{"status", "body"})
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", c)
+		}
+	}
+}
+
+func TestEvaluateError(t *testing.T) {
+	variables := map[string]interface{}{"status": 200, "body": "ok"}
+	cases := []string{
+		"status + body",
+		"status / 0",
+		"!status",
+		"body.field",
+		"status && true",
+		"len(status)",
+	}
+	for _, c := range cases {
+		e, err := Compile(c, []string{"status", "body"})
+		if err != nil {
+			t.Fatalf("Fail to compile %s: %s", c, err.Error())
+		}
+		_, err = e.Evaluate(variables)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", c)
+		}
+	}
+	e, err := Compile("status", []string{"status"})
+	if err != nil {
+		t.Fatalf("Fail to compile: %s", err.Error())
+	}
+	_, err = e.EvaluateBool(variables)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
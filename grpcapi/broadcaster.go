@@ -0,0 +1,120 @@
+package grpcapi
+
+import (
+	"sync"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// BroadcasterName the name of the exporter streaming the results to the
+// gRPC clients
+const BroadcasterName = "grpc-stream"
+
+// subscriber a client streaming the results
+type subscriber struct {
+	namespace string
+	name      string
+	results   chan *healthcheck.Result
+}
+
+// matches returns true if the result should be sent to the subscriber
+func (s *subscriber) matches(result *healthcheck.Result) bool {
+	if s.namespace != "" && result.Namespace != s.namespace {
+		return false
+	}
+	if s.name != "" && result.Name != s.name {
+		return false
+	}
+	return true
+}
+
+// Broadcaster an exporter sending the results to the gRPC stream clients.
+// The results are dropped for the clients not reading them fast enough,
+// the exporter never failing.
+type Broadcaster struct {
+	subscribers map[*subscriber]bool
+	started     bool
+	lock        sync.RWMutex
+}
+
+// NewBroadcaster creates a new broadcaster
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		subscribers: make(map[*subscriber]bool),
+	}
+}
+
+// subscribe registers a new stream client
+func (b *Broadcaster) subscribe(namespace string, name string, buffer uint) *subscriber {
+	s := &subscriber{
+		namespace: namespace,
+		name:      name,
+		results:   make(chan *healthcheck.Result, buffer),
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.subscribers[s] = true
+	return s
+}
+
+// unsubscribe removes a stream client
+func (b *Broadcaster) unsubscribe(s *subscriber) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.subscribers, s)
+}
+
+// Start starts the broadcaster
+func (b *Broadcaster) Start() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.started = true
+	return nil
+}
+
+// Stop stops the broadcaster
+func (b *Broadcaster) Stop() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.started = false
+	return nil
+}
+
+// Reconnect does nothing, the broadcaster has no connection
+func (b *Broadcaster) Reconnect() error {
+	return nil
+}
+
+// IsStarted returns the broadcaster status
+func (b *Broadcaster) IsStarted() bool {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.started
+}
+
+// Name returns the name of the broadcaster
+func (b *Broadcaster) Name() string {
+	return BroadcasterName
+}
+
+// GetConfig returns the config of the broadcaster
+func (b *Broadcaster) GetConfig() interface{} {
+	return nil
+}
+
+// Push sends a copy of the result to the matching stream clients
+func (b *Broadcaster) Push(result *healthcheck.Result) error {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for s := range b.subscribers {
+		if !s.matches(result) {
+			continue
+		}
+		copied := *result
+		select {
+		case s.results <- &copied:
+		default:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package grpcapi
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// Configuration the gRPC server configuration
+type Configuration struct {
+	Host   string
+	Port   uint32
+	Key    string
+	Cert   string
+	Cacert string
+	// StreamBuffer the number of results buffered for each stream client,
+	// the results being dropped for the slow clients
+	StreamBuffer uint `yaml:"stream-buffer"`
+}
+
+// defaultStreamBuffer the default number of results buffered for each
+// stream client
+const defaultStreamBuffer = 1000
+
+// UnmarshalYAML parses the configuration of the gRPC component from YAML.
+func (c *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read gRPC configuration")
+	}
+	ip := net.ParseIP(raw.Host)
+	if ip == nil {
+		return errors.New("Invalid IP address for the gRPC server")
+	}
+	if raw.Port == 0 {
+		return errors.New("Invalid Port for the gRPC server")
+	}
+	if !((raw.Key != "" && raw.Cert != "" && raw.Cacert != "") ||
+		(raw.Key == "" && raw.Cert == "" && raw.Cacert == "")) {
+		return errors.New("Invalid certificates for the gRPC server, the key, cert and cacert options should be configured together")
+	}
+	if raw.StreamBuffer == 0 {
+		raw.StreamBuffer = defaultStreamBuffer
+	}
+	*c = Configuration(raw)
+	return nil
+}
@@ -0,0 +1,80 @@
+package grpcapi
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalConfig(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Configuration
+	}{
+		{
+			in: `
+host: "127.0.0.1"
+port: 2000
+`,
+			want: Configuration{
+				Host:         "127.0.0.1",
+				Port:         2000,
+				StreamBuffer: defaultStreamBuffer,
+			},
+		},
+		{
+			in: `
+host: "127.0.0.1"
+port: 2000
+key: /tmp/foo
+cert: /tmp/bar
+cacert: /tmp/baz
+stream-buffer: 10
+`,
+			want: Configuration{
+				Host:         "127.0.0.1",
+				Port:         2000,
+				Key:          "/tmp/foo",
+				Cert:         "/tmp/bar",
+				Cacert:       "/tmp/baz",
+				StreamBuffer: 10,
+			},
+		},
+	}
+	for _, c := range cases {
+		var result Configuration
+		err := yaml.Unmarshal([]byte(c.in), &result)
+		if err != nil {
+			t.Fatalf("Unmarshal error: %s", err.Error())
+		}
+		if !reflect.DeepEqual(c.want, result) {
+			t.Fatalf("Invalid configuration: \n%v\n%v", c.want, result)
+		}
+	}
+}
+
+func TestUnmarshalConfigError(t *testing.T) {
+	cases := []string{
+		`
+host: "foo"
+port: 2000
+`,
+		`
+host: "127.0.0.1"
+`,
+		`
+host: "127.0.0.1"
+port: 2000
+key: /tmp/foo
+cert: /tmp/bar
+`,
+	}
+	for _, c := range cases {
+		var result Configuration
+		err := yaml.Unmarshal([]byte(c), &result)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", c)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/appclacks/cabourotte/grpcapi/pb"
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// toStruct converts a value to a protobuf struct using its JSON
+// representation
+func toStruct(value interface{}) (*structpb.Struct, error) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(fields)
+}
+
+// decodeConfiguration decodes the configuration of a check into an
+// healthcheck configuration, generates its name and validates it. The
+// fields of the check override the configuration ones.
+func decodeConfiguration(check *pb.Check, config interface{ Validate() error }, base *healthcheck.Base) error {
+	content, err := json.Marshal(check.GetConfiguration().AsMap())
+	if err != nil {
+		return errors.Wrap(err, "Invalid healthcheck configuration")
+	}
+	if err := json.Unmarshal(content, config); err != nil {
+		return errors.Wrap(err, "Invalid healthcheck configuration")
+	}
+	if check.Name != "" {
+		base.Name = check.Name
+	}
+	if check.Namespace != "" {
+		base.Namespace = check.Namespace
+	}
+	if check.Description != "" {
+		base.Description = check.Description
+	}
+	if len(check.Labels) != 0 {
+		base.Labels = check.Labels
+	}
+	if err := healthcheck.GenerateName(config); err != nil {
+		return errors.Wrap(err, "Invalid healthcheck configuration")
+	}
+	if err := config.Validate(); err != nil {
+		return errors.Wrap(err, "Invalid healthcheck configuration")
+	}
+	return nil
+}
+
+// newHealthcheck creates an healthcheck from a check
+func newHealthcheck(logger *zap.Logger, check *pb.Check) (healthcheck.Healthcheck, error) {
+	switch check.Type {
+	case pb.CheckType_CHECK_TYPE_DNS:
+		var config healthcheck.DNSHealthcheckConfiguration
+		if err := decodeConfiguration(check, &config, &config.Base); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewDNSHealthcheck(logger, &config), nil
+	case pb.CheckType_CHECK_TYPE_TCP:
+		var config healthcheck.TCPHealthcheckConfiguration
+		if err := decodeConfiguration(check, &config, &config.Base); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewTCPHealthcheck(logger, &config), nil
+	case pb.CheckType_CHECK_TYPE_HTTP:
+		var config healthcheck.HTTPHealthcheckConfiguration
+		if err := decodeConfiguration(check, &config, &config.Base); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewHTTPHealthcheck(logger, &config), nil
+	case pb.CheckType_CHECK_TYPE_TLS:
+		var config healthcheck.TLSHealthcheckConfiguration
+		if err := decodeConfiguration(check, &config, &config.Base); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewTLSHealthcheck(logger, &config), nil
+	case pb.CheckType_CHECK_TYPE_COMMAND:
+		var config healthcheck.CommandHealthcheckConfiguration
+		if err := decodeConfiguration(check, &config, &config.Base); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewCommandHealthcheck(logger, &config), nil
+	case pb.CheckType_CHECK_TYPE_HEARTBEAT:
+		var config healthcheck.HeartbeatHealthcheckConfiguration
+		if err := decodeConfiguration(check, &config, &config.Base); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewHeartbeatHealthcheck(logger, &config), nil
+	case pb.CheckType_CHECK_TYPE_GRPC:
+		var config healthcheck.GRPCHealthcheckConfiguration
+		if err := decodeConfiguration(check, &config, &config.Base); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewGRPCHealthcheck(logger, &config), nil
+	case pb.CheckType_CHECK_TYPE_PROFILE:
+		var config healthcheck.ProfileHealthcheckConfiguration
+		if err := decodeConfiguration(check, &config, &config.Base); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewProfileHealthcheck(logger, &config), nil
+	}
+	return nil, fmt.Errorf("Invalid healthcheck type %s", check.Type)
+}
+
+// checkType returns the type of an healthcheck
+func checkType(check healthcheck.Healthcheck) pb.CheckType {
+	switch check.(type) {
+	case *healthcheck.DNSHealthcheck:
+		return pb.CheckType_CHECK_TYPE_DNS
+	case *healthcheck.TCPHealthcheck:
+		return pb.CheckType_CHECK_TYPE_TCP
+	case *healthcheck.HTTPHealthcheck:
+		return pb.CheckType_CHECK_TYPE_HTTP
+	case *healthcheck.TLSHealthcheck:
+		return pb.CheckType_CHECK_TYPE_TLS
+	case *healthcheck.CommandHealthcheck:
+		return pb.CheckType_CHECK_TYPE_COMMAND
+	case *healthcheck.HeartbeatHealthcheck:
+		return pb.CheckType_CHECK_TYPE_HEARTBEAT
+	case *healthcheck.GRPCHealthcheck:
+		return pb.CheckType_CHECK_TYPE_GRPC
+	case *healthcheck.ProfileHealthcheck:
+		return pb.CheckType_CHECK_TYPE_PROFILE
+	}
+	return pb.CheckType_CHECK_TYPE_UNSPECIFIED
+}
+
+// sourceName returns the name of an healthcheck source, the healthchecks
+// of the configuration having an empty source
+func sourceName(source string) string {
+	if source == healthcheck.SourceConfig {
+		return "configuration"
+	}
+	return source
+}
+
+// toCheck converts an healthcheck to a check
+func toCheck(check healthcheck.Healthcheck) (*pb.Check, error) {
+	configuration, err := toStruct(check)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to convert the healthcheck %s", check.Base().Name)
+	}
+	base := check.Base()
+	return &pb.Check{
+		Type:          checkType(check),
+		Name:          base.Name,
+		Namespace:     base.Namespace,
+		Source:        sourceName(base.Source),
+		Description:   base.Description,
+		Labels:        base.Labels,
+		Configuration: configuration,
+	}, nil
+}
+
+// toResult converts an healthcheck result to a result
+func toResult(result *healthcheck.Result) (*pb.Result, error) {
+	details, err := toStruct(result)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to convert the result of the healthcheck %s", result.Name)
+	}
+	return &pb.Result{
+		Name:                 result.Name,
+		Namespace:            result.Namespace,
+		Group:                result.Group,
+		Labels:               result.Labels,
+		Success:              result.Success,
+		State:                result.State,
+		Message:              result.Message,
+		HealthcheckTimestamp: result.HealthcheckTimestamp,
+		Duration:             result.Duration,
+		Source:               result.Source,
+		Severity:             result.Severity,
+		Target:               result.Target,
+		ErrorCategory:        result.ErrorCategory,
+		Degraded:             result.Degraded,
+		Flapping:             result.Flapping,
+		Silenced:             result.Silenced,
+		Details:              details,
+	}, nil
+}
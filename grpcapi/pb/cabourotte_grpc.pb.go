@@ -0,0 +1,297 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: cabourotte.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Management_AddCheck_FullMethodName      = "/cabourotte.v1.Management/AddCheck"
+	Management_RemoveCheck_FullMethodName   = "/cabourotte.v1.Management/RemoveCheck"
+	Management_ListChecks_FullMethodName    = "/cabourotte.v1.Management/ListChecks"
+	Management_ListResults_FullMethodName   = "/cabourotte.v1.Management/ListResults"
+	Management_StreamResults_FullMethodName = "/cabourotte.v1.Management/StreamResults"
+)
+
+// ManagementClient is the client API for Management service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ManagementClient interface {
+	// AddCheck adds or replaces an healthcheck
+	AddCheck(ctx context.Context, in *AddCheckRequest, opts ...grpc.CallOption) (*AddCheckResponse, error)
+	// RemoveCheck removes an healthcheck
+	RemoveCheck(ctx context.Context, in *RemoveCheckRequest, opts ...grpc.CallOption) (*RemoveCheckResponse, error)
+	// ListChecks lists the healthchecks
+	ListChecks(ctx context.Context, in *ListChecksRequest, opts ...grpc.CallOption) (*ListChecksResponse, error)
+	// ListResults lists the latest result of each healthcheck
+	ListResults(ctx context.Context, in *ListResultsRequest, opts ...grpc.CallOption) (*ListResultsResponse, error)
+	// StreamResults streams the results of the healthchecks as they are
+	// executed
+	StreamResults(ctx context.Context, in *StreamResultsRequest, opts ...grpc.CallOption) (Management_StreamResultsClient, error)
+}
+
+type managementClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewManagementClient(cc grpc.ClientConnInterface) ManagementClient {
+	return &managementClient{cc}
+}
+
+func (c *managementClient) AddCheck(ctx context.Context, in *AddCheckRequest, opts ...grpc.CallOption) (*AddCheckResponse, error) {
+	out := new(AddCheckResponse)
+	err := c.cc.Invoke(ctx, Management_AddCheck_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) RemoveCheck(ctx context.Context, in *RemoveCheckRequest, opts ...grpc.CallOption) (*RemoveCheckResponse, error) {
+	out := new(RemoveCheckResponse)
+	err := c.cc.Invoke(ctx, Management_RemoveCheck_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) ListChecks(ctx context.Context, in *ListChecksRequest, opts ...grpc.CallOption) (*ListChecksResponse, error) {
+	out := new(ListChecksResponse)
+	err := c.cc.Invoke(ctx, Management_ListChecks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) ListResults(ctx context.Context, in *ListResultsRequest, opts ...grpc.CallOption) (*ListResultsResponse, error) {
+	out := new(ListResultsResponse)
+	err := c.cc.Invoke(ctx, Management_ListResults_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) StreamResults(ctx context.Context, in *StreamResultsRequest, opts ...grpc.CallOption) (Management_StreamResultsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Management_ServiceDesc.Streams[0], Management_StreamResults_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &managementStreamResultsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Management_StreamResultsClient interface {
+	Recv() (*Result, error)
+	grpc.ClientStream
+}
+
+type managementStreamResultsClient struct {
+	grpc.ClientStream
+}
+
+func (x *managementStreamResultsClient) Recv() (*Result, error) {
+	m := new(Result)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ManagementServer is the server API for Management service.
+// All implementations must embed UnimplementedManagementServer
+// for forward compatibility
+type ManagementServer interface {
+	// AddCheck adds or replaces an healthcheck
+	AddCheck(context.Context, *AddCheckRequest) (*AddCheckResponse, error)
+	// RemoveCheck removes an healthcheck
+	RemoveCheck(context.Context, *RemoveCheckRequest) (*RemoveCheckResponse, error)
+	// ListChecks lists the healthchecks
+	ListChecks(context.Context, *ListChecksRequest) (*ListChecksResponse, error)
+	// ListResults lists the latest result of each healthcheck
+	ListResults(context.Context, *ListResultsRequest) (*ListResultsResponse, error)
+	// StreamResults streams the results of the healthchecks as they are
+	// executed
+	StreamResults(*StreamResultsRequest, Management_StreamResultsServer) error
+	mustEmbedUnimplementedManagementServer()
+}
+
+// UnimplementedManagementServer must be embedded to have forward compatible implementations.
+type UnimplementedManagementServer struct {
+}
+
+func (UnimplementedManagementServer) AddCheck(context.Context, *AddCheckRequest) (*AddCheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddCheck not implemented")
+}
+func (UnimplementedManagementServer) RemoveCheck(context.Context, *RemoveCheckRequest) (*RemoveCheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveCheck not implemented")
+}
+func (UnimplementedManagementServer) ListChecks(context.Context, *ListChecksRequest) (*ListChecksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListChecks not implemented")
+}
+func (UnimplementedManagementServer) ListResults(context.Context, *ListResultsRequest) (*ListResultsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListResults not implemented")
+}
+func (UnimplementedManagementServer) StreamResults(*StreamResultsRequest, Management_StreamResultsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamResults not implemented")
+}
+func (UnimplementedManagementServer) mustEmbedUnimplementedManagementServer() {}
+
+// UnsafeManagementServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ManagementServer will
+// result in compilation errors.
+type UnsafeManagementServer interface {
+	mustEmbedUnimplementedManagementServer()
+}
+
+func RegisterManagementServer(s grpc.ServiceRegistrar, srv ManagementServer) {
+	s.RegisterService(&Management_ServiceDesc, srv)
+}
+
+func _Management_AddCheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).AddCheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Management_AddCheck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).AddCheck(ctx, req.(*AddCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_RemoveCheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).RemoveCheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Management_RemoveCheck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).RemoveCheck(ctx, req.(*RemoveCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_ListChecks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListChecksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).ListChecks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Management_ListChecks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).ListChecks(ctx, req.(*ListChecksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_ListResults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListResultsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).ListResults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Management_ListResults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).ListResults(ctx, req.(*ListResultsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_StreamResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamResultsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ManagementServer).StreamResults(m, &managementStreamResultsServer{stream})
+}
+
+type Management_StreamResultsServer interface {
+	Send(*Result) error
+	grpc.ServerStream
+}
+
+type managementStreamResultsServer struct {
+	grpc.ServerStream
+}
+
+func (x *managementStreamResultsServer) Send(m *Result) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Management_ServiceDesc is the grpc.ServiceDesc for Management service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Management_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cabourotte.v1.Management",
+	HandlerType: (*ManagementServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddCheck",
+			Handler:    _Management_AddCheck_Handler,
+		},
+		{
+			MethodName: "RemoveCheck",
+			Handler:    _Management_RemoveCheck_Handler,
+		},
+		{
+			MethodName: "ListChecks",
+			Handler:    _Management_ListChecks_Handler,
+		},
+		{
+			MethodName: "ListResults",
+			Handler:    _Management_ListResults_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamResults",
+			Handler:       _Management_StreamResults_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cabourotte.proto",
+}
@@ -0,0 +1,238 @@
+// Package grpcapi exposes the healthchecks management API over gRPC, the
+// service being defined in cabourotte.proto. The pb package is generated
+// with protoc-gen-go v1.30.0 and protoc-gen-go-grpc v1.3.0.
+package grpcapi
+
+//go:generate protoc --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative cabourotte.proto
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"github.com/appclacks/cabourotte/grpcapi/pb"
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/http"
+	"github.com/appclacks/cabourotte/memorystore"
+)
+
+// Component the gRPC server component, exposing the management API
+// defined in cabourotte.proto
+type Component struct {
+	pb.UnimplementedManagementServer
+	Config      *Configuration
+	Logger      *zap.Logger
+	MemoryStore *memorystore.MemoryStore
+	Server      *grpc.Server
+	healthcheck *healthcheck.Component
+	broadcaster *Broadcaster
+	tokens      []http.Token
+	done        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// New creates a new gRPC component. The broadcaster should be registered
+// in the exporter component in order to stream the results. If tokens is
+// not empty, the requests are authenticated using the API tokens of the
+// HTTP server.
+func New(logger *zap.Logger, memstore *memorystore.MemoryStore, config *Configuration, healthcheck *healthcheck.Component, broadcaster *Broadcaster, tokens []http.Token) (*Component, error) {
+	var options []grpc.ServerOption
+	if config.Cert != "" {
+		caCert, err := os.ReadFile(config.Cacert)
+		if err != nil {
+			return nil, errors.Wrap(err, "fail to read the ca certificate")
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCert)
+		cert, err := tls.LoadX509KeyPair(config.Cert, config.Key)
+		if err != nil {
+			return nil, errors.Wrap(err, "fail to build the x509 keypair")
+		}
+		tlsConfig := &tls.Config{
+			ClientCAs:    caCertPool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			Certificates: []tls.Certificate{cert},
+		}
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	component := Component{
+		Config:      config,
+		Logger:      logger,
+		MemoryStore: memstore,
+		healthcheck: healthcheck,
+		broadcaster: broadcaster,
+		tokens:      tokens,
+		done:        make(chan struct{}),
+	}
+	options = append(options,
+		grpc.UnaryInterceptor(component.unaryInterceptor),
+		grpc.StreamInterceptor(component.streamInterceptor))
+	component.Server = grpc.NewServer(options...)
+	pb.RegisterManagementServer(component.Server, &component)
+	return &component, nil
+}
+
+// Start starts the gRPC server
+func (c *Component) Start() error {
+	address := fmt.Sprintf("%s:%d", c.Config.Host, c.Config.Port)
+	c.Logger.Info(fmt.Sprintf("Starting the gRPC server component on %s", address))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return errors.Wrapf(err, "fail to listen on %s", address)
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		err := c.Server.Serve(listener)
+		if err != nil {
+			c.Logger.Error(fmt.Sprintf("gRPC server error: %s", err.Error()))
+		}
+	}()
+	return nil
+}
+
+// Stop stops the gRPC server, closing the results streams
+func (c *Component) Stop() error {
+	c.Logger.Info("Stopping the gRPC server component")
+	close(c.done)
+	c.Server.GracefulStop()
+	c.wg.Wait()
+	c.Logger.Info("gRPC server stopped")
+	return nil
+}
+
+// AddCheck adds or replaces an healthcheck managed by the API
+func (c *Component) AddCheck(ctx context.Context, request *pb.AddCheckRequest) (*pb.AddCheckResponse, error) {
+	if request.Check == nil {
+		return nil, status.Error(codes.InvalidArgument, "The healthcheck is missing")
+	}
+	check, err := newHealthcheck(c.Logger, request.Check)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	base := check.Base()
+	if base.OneOff {
+		return nil, status.Error(codes.InvalidArgument, "The one-off healthchecks are not supported by the gRPC API")
+	}
+	if !tokenAllows(ctx, base.Labels) {
+		return nil, tokenError(ctx, base.ID())
+	}
+	if existing := c.healthcheck.GetCheck(base.ID()); existing != nil && !tokenAllows(ctx, existing.Base().Labels) {
+		return nil, tokenError(ctx, base.ID())
+	}
+	if err := c.healthcheck.CheckDependencies([]healthcheck.Healthcheck{check}); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	check.SetSource(healthcheck.SourceAPI)
+	err = c.healthcheck.AddCheck(check)
+	if err != nil {
+		msg := fmt.Sprintf("Fail to add the healthcheck %s: %s", base.Name, err.Error())
+		if healthcheck.IsConflict(err) {
+			return nil, status.Error(codes.AlreadyExists, msg)
+		}
+		return nil, status.Error(codes.Internal, msg)
+	}
+	return &pb.AddCheckResponse{Name: base.Name}, nil
+}
+
+// RemoveCheck removes an healthcheck
+func (c *Component) RemoveCheck(ctx context.Context, request *pb.RemoveCheckRequest) (*pb.RemoveCheckResponse, error) {
+	id := healthcheck.ID(request.Namespace, request.Name)
+	check := c.healthcheck.GetCheck(id)
+	if check == nil {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("The healthcheck %s does not exist", id))
+	}
+	if !tokenAllows(ctx, check.Base().Labels) {
+		return nil, tokenError(ctx, id)
+	}
+	c.Logger.Info(fmt.Sprintf("Deleting healthcheck %s", id))
+	err := c.healthcheck.RemoveCheck(id)
+	if err != nil {
+		msg := fmt.Sprintf("Fail to remove the healthcheck %s: %s", id, err.Error())
+		return nil, status.Error(codes.Internal, msg)
+	}
+	return &pb.RemoveCheckResponse{}, nil
+}
+
+// ListChecks lists the healthchecks
+func (c *Component) ListChecks(ctx context.Context, request *pb.ListChecksRequest) (*pb.ListChecksResponse, error) {
+	response := &pb.ListChecksResponse{}
+	for _, check := range c.healthcheck.ListChecks() {
+		base := check.Base()
+		if request.Namespace != "" && base.Namespace != request.Namespace {
+			continue
+		}
+		if request.Source != "" && sourceName(base.Source) != request.Source {
+			continue
+		}
+		if !tokenAllows(ctx, base.Labels) {
+			continue
+		}
+		value, err := toCheck(check)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		response.Checks = append(response.Checks, value)
+	}
+	return response, nil
+}
+
+// ListResults lists the latest result of each healthcheck
+func (c *Component) ListResults(ctx context.Context, request *pb.ListResultsRequest) (*pb.ListResultsResponse, error) {
+	response := &pb.ListResultsResponse{}
+	for _, result := range c.MemoryStore.List() {
+		if request.Namespace != "" && result.Namespace != request.Namespace {
+			continue
+		}
+		if !tokenAllows(ctx, result.Labels) {
+			continue
+		}
+		value, err := toResult(&result)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		response.Results = append(response.Results, value)
+	}
+	return response, nil
+}
+
+// StreamResults streams the results of the healthchecks until the client
+// cancels the stream or the server stops
+func (c *Component) StreamResults(request *pb.StreamResultsRequest, stream pb.Management_StreamResultsServer) error {
+	buffer := c.Config.StreamBuffer
+	if buffer == 0 {
+		buffer = defaultStreamBuffer
+	}
+	s := c.broadcaster.subscribe(request.Namespace, request.Name, buffer)
+	defer c.broadcaster.unsubscribe(s)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-c.done:
+			return nil
+		case result := <-s.results:
+			if !tokenAllows(stream.Context(), result.Labels) {
+				continue
+			}
+			value, err := toResult(result)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			if err := stream.Send(value); err != nil {
+				return err
+			}
+		}
+	}
+}
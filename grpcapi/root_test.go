@@ -0,0 +1,346 @@
+package grpcapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/appclacks/cabourotte/grpcapi/pb"
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/http"
+	"github.com/appclacks/cabourotte/memorystore"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func newStruct(t *testing.T, fields map[string]interface{}) *structpb.Struct {
+	result, err := structpb.NewStruct(fields)
+	if err != nil {
+		t.Fatalf("Fail to build the struct: %s", err.Error())
+	}
+	return result
+}
+
+func TestManagement(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	memstore := memorystore.NewMemoryStore(logger, 10)
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	broadcaster := NewBroadcaster()
+	component, err := New(logger, memstore, &Configuration{Host: "127.0.0.1", Port: 2013}, checkComponent, broadcaster, nil)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	conn, err := grpc.Dial("127.0.0.1:2013", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Fail to create the client\n%v", err)
+	}
+	defer conn.Close()
+	client := pb.NewManagementClient(conn)
+	ctx := context.Background()
+
+	configCheck := healthcheck.NewTCPHealthcheck(logger, &healthcheck.TCPHealthcheckConfiguration{
+		Base: healthcheck.Base{
+			Name:     "db",
+			Interval: healthcheck.Duration(10 * time.Minute),
+		},
+		Target:  "127.0.0.1",
+		Port:    5432,
+		Timeout: healthcheck.Duration(time.Second),
+	})
+	err = checkComponent.AddCheck(configCheck)
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+
+	tcp := map[string]interface{}{
+		"target":        "127.0.0.1",
+		"port":          9999,
+		"interval":      "10m",
+		"timeout":       "1s",
+		"generate-name": true,
+	}
+	cases := []struct {
+		check *pb.Check
+		code  codes.Code
+		name  string
+	}{
+		{
+			check: &pb.Check{Type: pb.CheckType_CHECK_TYPE_TCP, Description: "foo", Configuration: newStruct(t, tcp)},
+			code:  codes.OK,
+			name:  "tcp-127.0.0.1-9999",
+		},
+		{
+			check: &pb.Check{Type: pb.CheckType_CHECK_TYPE_TCP, Name: "db", Configuration: newStruct(t, tcp)},
+			code:  codes.AlreadyExists,
+		},
+		{
+			check: &pb.Check{Type: pb.CheckType_CHECK_TYPE_UNSPECIFIED, Configuration: newStruct(t, tcp)},
+			code:  codes.InvalidArgument,
+		},
+		{
+			check: &pb.Check{Type: pb.CheckType_CHECK_TYPE_TCP, Name: "invalid", Configuration: newStruct(t, map[string]interface{}{"target": "127.0.0.1"})},
+			code:  codes.InvalidArgument,
+		},
+		{
+			check: nil,
+			code:  codes.InvalidArgument,
+		},
+	}
+	for _, c := range cases {
+		response, err := client.AddCheck(ctx, &pb.AddCheckRequest{Check: c.check})
+		if status.Code(err) != c.code {
+			t.Fatalf("Expected the code %s, got %s (%v)", c.code, status.Code(err), err)
+		}
+		if err == nil && response.Name != c.name {
+			t.Fatalf("Expected the name %s, got %s", c.name, response.Name)
+		}
+	}
+
+	list, err := client.ListChecks(ctx, &pb.ListChecksRequest{Source: healthcheck.SourceAPI})
+	if err != nil {
+		t.Fatalf("Fail to list the healthchecks\n%v", err)
+	}
+	if len(list.Checks) != 1 {
+		t.Fatalf("Expected one healthcheck, got %d", len(list.Checks))
+	}
+	check := list.Checks[0]
+	if check.Type != pb.CheckType_CHECK_TYPE_TCP || check.Name != "tcp-127.0.0.1-9999" || check.Description != "foo" || check.Source != healthcheck.SourceAPI {
+		t.Fatalf("Invalid healthcheck %v", check)
+	}
+	if check.Configuration.AsMap()["target"] != "127.0.0.1" {
+		t.Fatalf("Invalid healthcheck configuration %v", check.Configuration.AsMap())
+	}
+	list, err = client.ListChecks(ctx, &pb.ListChecksRequest{Source: "configuration"})
+	if err != nil {
+		t.Fatalf("Fail to list the healthchecks\n%v", err)
+	}
+	if len(list.Checks) != 1 || list.Checks[0].Name != "db" {
+		t.Fatalf("Invalid healthchecks %v", list.Checks)
+	}
+
+	memstore.Add(&healthcheck.Result{Name: "foo", Namespace: "a", Success: true, Message: "ok"})
+	memstore.Add(&healthcheck.Result{Name: "bar", Success: false, Message: "error"})
+	results, err := client.ListResults(ctx, &pb.ListResultsRequest{Namespace: "a"})
+	if err != nil {
+		t.Fatalf("Fail to list the results\n%v", err)
+	}
+	if len(results.Results) != 1 || results.Results[0].Name != "foo" || !results.Results[0].Success {
+		t.Fatalf("Invalid results %v", results.Results)
+	}
+	if results.Results[0].Details.AsMap()["message"] != "ok" {
+		t.Fatalf("Invalid result details %v", results.Results[0].Details.AsMap())
+	}
+
+	stream, err := client.StreamResults(ctx, &pb.StreamResultsRequest{Name: "foo"})
+	if err != nil {
+		t.Fatalf("Fail to stream the results\n%v", err)
+	}
+	for i := 0; ; i++ {
+		broadcaster.lock.RLock()
+		subscribed := len(broadcaster.subscribers) == 1
+		broadcaster.lock.RUnlock()
+		if subscribed {
+			break
+		}
+		if i == 50 {
+			t.Fatalf("The stream client is not subscribed")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	err = broadcaster.Push(&healthcheck.Result{Name: "bar", Message: "filtered"})
+	if err != nil {
+		t.Fatalf("Fail to push the result\n%v", err)
+	}
+	err = broadcaster.Push(&healthcheck.Result{Name: "foo", Message: "streamed", Duration: 12})
+	if err != nil {
+		t.Fatalf("Fail to push the result\n%v", err)
+	}
+	result, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Fail to receive the result\n%v", err)
+	}
+	if result.Name != "foo" || result.Message != "streamed" || result.Duration != 12 {
+		t.Fatalf("Invalid result %v", result)
+	}
+
+	_, err = client.RemoveCheck(ctx, &pb.RemoveCheckRequest{Name: "tcp-127.0.0.1-9999"})
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+	if checkComponent.GetCheck("tcp-127.0.0.1-9999") != nil {
+		t.Fatalf("The healthcheck was not removed")
+	}
+	_, err = client.RemoveCheck(ctx, &pb.RemoveCheckRequest{Name: "tcp-127.0.0.1-9999"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected the code %s, got %s (%v)", codes.NotFound, status.Code(err), err)
+	}
+
+	// the open streams are closed on stop
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+	_, err = stream.Recv()
+	if err == nil {
+		t.Fatalf("Was expecting the stream to be closed")
+	}
+}
+
+func TestTokens(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	memstore := memorystore.NewMemoryStore(logger, 10)
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	tokens := []http.Token{
+		{Name: "admin", Token: "admin-secret"},
+		{Name: "payments", Token: "payments-secret", Selector: map[string]string{"team": "payments"}},
+	}
+	broadcaster := NewBroadcaster()
+	component, err := New(logger, memstore, &Configuration{Host: "127.0.0.1", Port: 2015}, checkComponent, broadcaster, tokens)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	conn, err := grpc.Dial("127.0.0.1:2015", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Fail to create the client\n%v", err)
+	}
+	defer conn.Close()
+	client := pb.NewManagementClient(conn)
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+	newCheck := func(name string, team string) *pb.Check {
+		return &pb.Check{
+			Type:          pb.CheckType_CHECK_TYPE_TCP,
+			Name:          name,
+			Labels:        map[string]string{"team": team},
+			Configuration: newStruct(t, map[string]interface{}{"target": "127.0.0.1", "port": 9999, "interval": "10m", "timeout": "1s"}),
+		}
+	}
+
+	cases := []struct {
+		ctx   context.Context
+		check *pb.Check
+		code  codes.Code
+	}{
+		{ctx: context.Background(), check: newCheck("billing", "billing"), code: codes.Unauthenticated},
+		{ctx: withToken("invalid"), check: newCheck("billing", "billing"), code: codes.Unauthenticated},
+		{ctx: withToken("admin-secret"), check: newCheck("billing", "billing"), code: codes.OK},
+		{ctx: withToken("payments-secret"), check: newCheck("payments", "payments"), code: codes.OK},
+		{ctx: withToken("payments-secret"), check: newCheck("other", "billing"), code: codes.PermissionDenied},
+		// the healthcheck of another team can not be replaced
+		{ctx: withToken("payments-secret"), check: newCheck("billing", "payments"), code: codes.PermissionDenied},
+	}
+	for _, c := range cases {
+		_, err := client.AddCheck(c.ctx, &pb.AddCheckRequest{Check: c.check})
+		if status.Code(err) != c.code {
+			t.Fatalf("Expected the code %s, got %s (%v)", c.code, status.Code(err), err)
+		}
+	}
+
+	list, err := client.ListChecks(withToken("payments-secret"), &pb.ListChecksRequest{})
+	if err != nil {
+		t.Fatalf("Fail to list the healthchecks\n%v", err)
+	}
+	if len(list.Checks) != 1 || list.Checks[0].Name != "payments" {
+		t.Fatalf("Invalid healthchecks %v", list.Checks)
+	}
+	list, err = client.ListChecks(withToken("admin-secret"), &pb.ListChecksRequest{})
+	if err != nil {
+		t.Fatalf("Fail to list the healthchecks\n%v", err)
+	}
+	if len(list.Checks) != 2 {
+		t.Fatalf("Invalid healthchecks %v", list.Checks)
+	}
+
+	memstore.Add(&healthcheck.Result{Name: "billing", Labels: map[string]string{"team": "billing"}, Success: false, Message: "error"})
+	memstore.Add(&healthcheck.Result{Name: "payments", Labels: map[string]string{"team": "payments"}, Success: true, Message: "ok"})
+	results, err := client.ListResults(withToken("payments-secret"), &pb.ListResultsRequest{})
+	if err != nil {
+		t.Fatalf("Fail to list the results\n%v", err)
+	}
+	if len(results.Results) != 1 || results.Results[0].Name != "payments" {
+		t.Fatalf("Invalid results %v", results.Results)
+	}
+
+	stream, err := client.StreamResults(context.Background(), &pb.StreamResultsRequest{})
+	if err != nil {
+		t.Fatalf("Fail to stream the results\n%v", err)
+	}
+	_, err = stream.Recv()
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected the code %s, got %s (%v)", codes.Unauthenticated, status.Code(err), err)
+	}
+	stream, err = client.StreamResults(withToken("payments-secret"), &pb.StreamResultsRequest{})
+	if err != nil {
+		t.Fatalf("Fail to stream the results\n%v", err)
+	}
+	for i := 0; ; i++ {
+		broadcaster.lock.RLock()
+		subscribed := len(broadcaster.subscribers) == 1
+		broadcaster.lock.RUnlock()
+		if subscribed {
+			break
+		}
+		if i == 50 {
+			t.Fatalf("The stream client is not subscribed")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	err = broadcaster.Push(&healthcheck.Result{Name: "billing", Labels: map[string]string{"team": "billing"}, Message: "filtered"})
+	if err != nil {
+		t.Fatalf("Fail to push the result\n%v", err)
+	}
+	err = broadcaster.Push(&healthcheck.Result{Name: "payments", Labels: map[string]string{"team": "payments"}, Message: "streamed"})
+	if err != nil {
+		t.Fatalf("Fail to push the result\n%v", err)
+	}
+	result, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Fail to receive the result\n%v", err)
+	}
+	if result.Name != "payments" || result.Message != "streamed" {
+		t.Fatalf("Invalid result %v", result)
+	}
+
+	_, err = client.RemoveCheck(withToken("payments-secret"), &pb.RemoveCheckRequest{Name: "billing"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Expected the code %s, got %s (%v)", codes.PermissionDenied, status.Code(err), err)
+	}
+	_, err = client.RemoveCheck(withToken("payments-secret"), &pb.RemoveCheckRequest{Name: "payments"})
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
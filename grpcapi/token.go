@@ -0,0 +1,91 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/appclacks/cabourotte/http"
+)
+
+// tokenMetadata the metadata of the API token, sent using the Bearer
+// scheme
+const tokenMetadata = "authorization"
+
+// tokenKey the key of the request token in the context
+type tokenKey struct{}
+
+// tokenStream a server stream carrying the context of the request token
+type tokenStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream
+func (s *tokenStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate returns the context of a request, carrying its token if
+// the API tokens are configured
+func (c *Component) authenticate(ctx context.Context) (context.Context, error) {
+	if len(c.tokens) == 0 {
+		return ctx, nil
+	}
+	value := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(tokenMetadata); len(values) != 0 {
+			value = strings.TrimPrefix(values[0], "Bearer ")
+		}
+	}
+	token := http.FindToken(c.tokens, value)
+	if value == "" || token == nil {
+		c.Logger.Error("Invalid API token")
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+	return context.WithValue(ctx, tokenKey{}, token), nil
+}
+
+// unaryInterceptor authenticates the unary requests
+func (c *Component) unaryInterceptor(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := c.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, request)
+}
+
+// streamInterceptor authenticates the streaming requests
+func (c *Component) streamInterceptor(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := c.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(server, &tokenStream{ServerStream: stream, ctx: ctx})
+}
+
+// requestToken returns the token of the request, or nil if the API
+// tokens are not configured
+func requestToken(ctx context.Context) *http.Token {
+	token, _ := ctx.Value(tokenKey{}).(*http.Token)
+	return token
+}
+
+// tokenAllows returns true if the request token can manage the
+// healthchecks having these labels
+func tokenAllows(ctx context.Context, labels map[string]string) bool {
+	token := requestToken(ctx)
+	return token == nil || token.Allows(labels)
+}
+
+// tokenError the error returned when the request token can not manage an
+// healthcheck
+func tokenError(ctx context.Context, id string) error {
+	msg := fmt.Sprintf("The API token %s is not allowed to manage the healthcheck %s", requestToken(ctx).Name, id)
+	return status.Error(codes.PermissionDenied, msg)
+}
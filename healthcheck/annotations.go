@@ -0,0 +1,45 @@
+package healthcheck
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// parseAnnotation parses the template of an annotation
+func parseAnnotation(name string, value string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=zero").Parse(value)
+}
+
+// validateAnnotations validates the templates of the annotations
+func validateAnnotations(annotations map[string]string) error {
+	for name, value := range annotations {
+		if _, err := parseAnnotation(name, value); err != nil {
+			return errors.Wrapf(err, "Invalid healthcheck annotation %s", name)
+		}
+	}
+	return nil
+}
+
+// renderAnnotations renders the annotations of an healthcheck with the
+// fields of its result ({{ .Duration }} for example). The annotations
+// failing to render are kept as is.
+func renderAnnotations(annotations map[string]string, result *Result) map[string]string {
+	if len(annotations) == 0 {
+		return nil
+	}
+	rendered := make(map[string]string, len(annotations))
+	for name, value := range annotations {
+		rendered[name] = value
+		tmpl, err := parseAnnotation(name, value)
+		if err != nil {
+			continue
+		}
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, result); err == nil {
+			rendered[name] = b.String()
+		}
+	}
+	return rendered
+}
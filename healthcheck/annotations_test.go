@@ -0,0 +1,40 @@
+package healthcheck
+
+import (
+	"testing"
+)
+
+func TestRenderAnnotations(t *testing.T) {
+	result := &Result{
+		Name:     "api",
+		State:    StateUnhealthy,
+		Duration: 1200,
+		Message:  "timeout",
+		Labels:   map[string]string{"env": "prod"},
+	}
+	rendered := renderAnnotations(map[string]string{
+		"summary":     "{{ .Name }} is {{ .State }} in {{ .Labels.env }}",
+		"description": "Latency was {{ .Duration }}ms: {{ .Message }}",
+		"missing":     "{{ .Labels.region }}",
+		"invalid":     "{{ .Unknown }}",
+		"static":      "no template",
+	}, result)
+	expected := map[string]string{
+		"summary":     "api is unhealthy in prod",
+		"description": "Latency was 1200ms: timeout",
+		"missing":     "",
+		"invalid":     "{{ .Unknown }}",
+		"static":      "no template",
+	}
+	for k, v := range expected {
+		if rendered[k] != v {
+			t.Fatalf("Invalid annotation %s: %s, expected %s", k, rendered[k], v)
+		}
+	}
+	if renderAnnotations(nil, result) != nil {
+		t.Fatalf("No annotations should be rendered")
+	}
+	if err := validateAnnotations(map[string]string{"summary": "{{ .Name"}); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
@@ -0,0 +1,159 @@
+package healthcheck
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultAnomalyWindow the default number of executions in the
+	// baseline
+	DefaultAnomalyWindow = 100
+	// DefaultAnomalyDeviations the default number of standard deviations
+	// above which a duration is anomalous
+	DefaultAnomalyDeviations = 3
+	// DefaultAnomalyMinSamples the default number of executions learned
+	// before detecting anomalies
+	DefaultAnomalyMinSamples = 10
+)
+
+// Anomaly configures the detection of the anomalies of the executions
+// durations. The durations of the successful executions are learned in a
+// rolling baseline, and the executions slower than the mean of the
+// baseline plus the configured number of standard deviations are
+// reported as degraded.
+type Anomaly struct {
+	// Window the number of executions in the baseline
+	Window uint `json:"window,omitempty" yaml:"window,omitempty"`
+	// Deviations the number of standard deviations above the mean
+	Deviations float64 `json:"deviations,omitempty" yaml:"deviations,omitempty"`
+	// MinSamples the number of executions learned before detecting
+	// anomalies
+	MinSamples uint `json:"min-samples,omitempty" yaml:"min-samples,omitempty"`
+	// MinDeviation the minimum difference with the mean, in order to
+	// ignore the small variations of the very stable targets
+	MinDeviation Duration `json:"min-deviation,omitempty" yaml:"min-deviation,omitempty"`
+}
+
+// Validate validates the anomaly detection configuration
+func (a *Anomaly) Validate() error {
+	if a.Deviations < 0 || a.MinDeviation < 0 {
+		return errors.New("The anomaly deviations and min-deviation should be positive")
+	}
+	window, _, minSamples := a.parameters()
+	if minSamples < 2 || minSamples > window {
+		return errors.New("The anomaly min-samples should be between 2 and the window")
+	}
+	return nil
+}
+
+// parameters returns the window, the number of deviations and the
+// minimum number of samples, using the default values for the unset ones
+func (a *Anomaly) parameters() (uint, float64, uint) {
+	window := a.Window
+	if window == 0 {
+		window = DefaultAnomalyWindow
+	}
+	deviations := a.Deviations
+	if deviations == 0 {
+		deviations = DefaultAnomalyDeviations
+	}
+	minSamples := a.MinSamples
+	if minSamples == 0 {
+		minSamples = DefaultAnomalyMinSamples
+	}
+	return window, deviations, minSamples
+}
+
+// anomalyDetector the rolling baseline of the executions durations of an
+// healthcheck
+type anomalyDetector struct {
+	lock       sync.Mutex
+	deviations float64
+	minSamples int
+	minDelta   float64
+	// samples the durations in seconds, used as a ring buffer
+	samples []float64
+	next    int
+}
+
+// newAnomalyDetector creates an anomaly detector, or returns nil if the
+// anomaly detection is not configured
+func newAnomalyDetector(config *Anomaly) *anomalyDetector {
+	if config == nil {
+		return nil
+	}
+	window, deviations, minSamples := config.parameters()
+	return &anomalyDetector{
+		deviations: deviations,
+		minSamples: int(minSamples),
+		minDelta:   time.Duration(config.MinDeviation).Seconds(),
+		samples:    make([]float64, 0, window),
+	}
+}
+
+// baseline returns the mean and the standard deviation of the samples
+func (d *anomalyDetector) baseline() (float64, float64) {
+	var sum float64
+	for _, sample := range d.samples {
+		sum += sample
+	}
+	mean := sum / float64(len(d.samples))
+	var variance float64
+	for _, sample := range d.samples {
+		variance += (sample - mean) * (sample - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(d.samples)))
+}
+
+// observe compares the duration with the baseline, and adds it to the
+// baseline. It returns the anomaly description if the duration is
+// anomalous, or an empty string.
+func (d *anomalyDetector) observe(duration time.Duration) string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	value := duration.Seconds()
+	message := ""
+	if len(d.samples) >= d.minSamples {
+		mean, stddev := d.baseline()
+		delta := math.Max(d.deviations*stddev, d.minDelta)
+		if value > mean+delta {
+			message = fmt.Sprintf(
+				"the execution took %s, above the anomaly threshold of %s (baseline %s)",
+				duration.Truncate(time.Millisecond),
+				seconds(mean+delta).Truncate(time.Millisecond),
+				seconds(mean).Truncate(time.Millisecond))
+		}
+	}
+	// the anomalous durations are also learned, the baseline following
+	// the lasting changes of the target
+	if len(d.samples) < cap(d.samples) {
+		d.samples = append(d.samples, value)
+	} else {
+		d.samples[d.next] = value
+		d.next = (d.next + 1) % len(d.samples)
+	}
+	return message
+}
+
+// seconds converts a number of seconds to a duration
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+// apply marks the successful result as degraded if its duration is
+// anomalous. The degradations reported by the healthcheck are kept.
+func (d *anomalyDetector) apply(result *Result, duration time.Duration) {
+	if !result.Success {
+		return
+	}
+	message := d.observe(duration)
+	if message != "" && !result.Degraded {
+		result.Degraded = true
+		result.Message = message
+	}
+}
@@ -0,0 +1,82 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnomalyDetector(t *testing.T) {
+	detector := newAnomalyDetector(&Anomaly{Window: 20, MinSamples: 5, MinDeviation: Duration(5 * time.Millisecond)})
+	cases := []struct {
+		duration  time.Duration
+		success   bool
+		anomalous bool
+	}{
+		// learning the baseline
+		{duration: 100 * time.Millisecond, success: true},
+		{duration: 102 * time.Millisecond, success: true},
+		{duration: 98 * time.Millisecond, success: true},
+		{duration: 101 * time.Millisecond, success: true},
+		{duration: 99 * time.Millisecond, success: true},
+		// failures are not learned
+		{duration: 10 * time.Second, success: false},
+		{duration: 103 * time.Millisecond, success: true},
+		{duration: 500 * time.Millisecond, success: true, anomalous: true},
+		{duration: 100 * time.Millisecond, success: true},
+		// faster executions are not anomalous
+		{duration: 10 * time.Millisecond, success: true},
+	}
+	for i, c := range cases {
+		result := &Result{Success: c.success, Message: "success"}
+		detector.apply(result, c.duration)
+		if result.Degraded != c.anomalous {
+			t.Fatalf("Invalid degraded value for the case %d: %+v", i, result)
+		}
+		if c.anomalous && result.Message == "success" {
+			t.Fatalf("The anomaly message is missing for the case %d", i)
+		}
+	}
+	if len(detector.samples) != 9 {
+		t.Fatalf("Invalid number of samples %d", len(detector.samples))
+	}
+}
+
+func TestAnomalyDetectorWindow(t *testing.T) {
+	detector := newAnomalyDetector(&Anomaly{Window: 3, MinSamples: 2})
+	for i := 0; i < 10; i++ {
+		detector.observe(time.Second)
+	}
+	if len(detector.samples) != 3 {
+		t.Fatalf("Invalid number of samples %d", len(detector.samples))
+	}
+	// the baseline follows the lasting changes
+	for i := 0; i < 3; i++ {
+		detector.observe(2 * time.Second)
+	}
+	if message := detector.observe(2 * time.Second); message != "" {
+		t.Fatalf("The duration should not be anomalous: %s", message)
+	}
+}
+
+func TestAnomalyValidate(t *testing.T) {
+	cases := []struct {
+		config Anomaly
+		err    bool
+	}{
+		{config: Anomaly{}},
+		{config: Anomaly{Window: 50, Deviations: 2, MinSamples: 20}},
+		{config: Anomaly{Window: 5}, err: true},
+		{config: Anomaly{MinSamples: 1}, err: true},
+		{config: Anomaly{Deviations: -1}, err: true},
+		{config: Anomaly{MinDeviation: Duration(-time.Second)}, err: true},
+	}
+	for i, c := range cases {
+		err := c.config.Validate()
+		if c.err && err == nil {
+			t.Fatalf("Was expecting an error for the case %d", i)
+		}
+		if !c.err && err != nil {
+			t.Fatalf("Validation error for the case %d:\n%v", i, err)
+		}
+	}
+}
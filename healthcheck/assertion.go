@@ -0,0 +1,89 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/expression"
+)
+
+// the variables of the assertions, by healthcheck type. The durations and
+// the timings of the phases are in milliseconds.
+var (
+	// httpAssertionVariables the response status, headers (lower case
+	// names), body, body decoded from JSON (null if it is not JSON),
+	// duration and timings
+	httpAssertionVariables = []string{"status", "headers", "body", "json", "duration", "timings"}
+	// tcpAssertionVariables the IP and port connected to, the connection
+	// duration and the timings
+	tcpAssertionVariables = []string{"ip", "port", "duration", "timings"}
+	// dnsAssertionVariables the resolved IPs and the resolution duration
+	dnsAssertionVariables = []string{"ips", "duration"}
+)
+
+// compileAssertions compiles the assertions of an healthcheck
+func compileAssertions(assertions []string, variables []string) ([]*expression.Expression, error) {
+	result := make([]*expression.Expression, 0, len(assertions))
+	for _, assertion := range assertions {
+		e, err := expression.Compile(assertion, variables)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid healthcheck assertion")
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// verifyAssertions evaluates the assertions on the data of the probe, all
+// of them should be true
+func verifyAssertions(assertions []*expression.Expression, variables map[string]interface{}) error {
+	for _, assertion := range assertions {
+		ok, err := assertion.EvaluateBool(variables)
+		if err != nil {
+			return assertionError("Fail to evaluate the assertion %s: %s", assertion.String(), err.Error())
+		}
+		if !ok {
+			return assertionError("The assertion %s is false", assertion.String())
+		}
+	}
+	return nil
+}
+
+// milliseconds returns a duration in milliseconds
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
+}
+
+// timings returns the durations of the phases recorded in the context
+func timings(ctx context.Context) map[string]float64 {
+	if recorder := phasesFromContext(ctx); recorder != nil {
+		return phasesDurations(recorder.list())
+	}
+	return nil
+}
+
+// httpAssertionData returns the variables of the assertions of an HTTP
+// healthcheck
+func httpAssertionData(ctx context.Context, response *http.Response, body []byte, duration time.Duration) map[string]interface{} {
+	headers := make(map[string]interface{}, len(response.Header))
+	for name, values := range response.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		decoded = nil
+	}
+	return map[string]interface{}{
+		"status":   response.StatusCode,
+		"headers":  headers,
+		"body":     string(body),
+		"json":     decoded,
+		"duration": milliseconds(duration),
+		"timings":  timings(ctx),
+	}
+}
@@ -0,0 +1,103 @@
+package healthcheck
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// bandwidthChunk the maximum number of bytes read at once by the limited
+// readers
+const bandwidthChunk = 32 * 1024
+
+// bandwidthLimiter limits the bandwidth shared by the healthchecks using a
+// token bucket, the bucket containing up to one second of bandwidth
+type bandwidthLimiter struct {
+	rate   float64
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newBandwidthLimiter creates a limiter allowing rate bytes per second
+func newBandwidthLimiter(rate uint64) *bandwidthLimiter {
+	return &bandwidthLimiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes n bytes from the bucket, and returns the time to wait
+// before using them
+func (l *bandwidthLimiter) reserve(n int, now time.Time) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// wait waits until n bytes can be used, or until the context is done
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	delay := l.reserve(n, time.Now())
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limitedReader a reader limited by the bandwidth limiter
+type limitedReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *bandwidthLimiter
+}
+
+// Read reads from the underlying reader, waiting for the bandwidth to be
+// available
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > bandwidthChunk {
+		p = p[:bandwidthChunk]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// limiterKey the context key of the bandwidth limiter
+type limiterKey struct{}
+
+// withLimiter returns a context containing the bandwidth limiter
+func withLimiter(ctx context.Context, limiter *bandwidthLimiter) context.Context {
+	return context.WithValue(ctx, limiterKey{}, limiter)
+}
+
+// limitReader returns a reader limited by the bandwidth limiter of the
+// context, if any
+func limitReader(ctx context.Context, reader io.Reader) io.Reader {
+	limiter, _ := ctx.Value(limiterKey{}).(*bandwidthLimiter)
+	if limiter == nil {
+		return reader
+	}
+	return &limitedReader{ctx: ctx, reader: reader, limiter: limiter}
+}
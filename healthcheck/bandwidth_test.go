@@ -0,0 +1,53 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestBandwidthLimiterReserve(t *testing.T) {
+	limiter := newBandwidthLimiter(1000)
+	now := limiter.last
+	if delay := limiter.reserve(1000, now); delay != 0 {
+		t.Fatalf("Expected no delay, got %s", delay)
+	}
+	if delay := limiter.reserve(500, now); delay != 500*time.Millisecond {
+		t.Fatalf("Expected a 500ms delay, got %s", delay)
+	}
+	if delay := limiter.reserve(500, now.Add(time.Second)); delay != 0 {
+		t.Fatalf("Expected no delay, got %s", delay)
+	}
+	// the bucket contains at most one second of bandwidth
+	if delay := limiter.reserve(1500, now.Add(time.Hour)); delay != 500*time.Millisecond {
+		t.Fatalf("Expected a 500ms delay, got %s", delay)
+	}
+}
+
+func TestLimitReader(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 2000)
+	reader := limitReader(context.Background(), bytes.NewReader(content))
+	if _, ok := reader.(*limitedReader); ok {
+		t.Fatalf("The reader should not be limited without limiter")
+	}
+	ctx := withLimiter(context.Background(), newBandwidthLimiter(4000))
+	start := time.Now()
+	result, err := io.ReadAll(limitReader(ctx, bytes.NewReader(append(content, content...))))
+	if err != nil {
+		t.Fatalf("Fail to read:\n%v", err)
+	}
+	if len(result) != 4000 {
+		t.Fatalf("Invalid length %d", len(result))
+	}
+	ctx, cancel := context.WithTimeout(withLimiter(context.Background(), newBandwidthLimiter(1000)), 100*time.Millisecond)
+	defer cancel()
+	_, err = io.ReadAll(limitReader(ctx, bytes.NewReader(content)))
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("The reads took too long")
+	}
+}
@@ -0,0 +1,159 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// DefaultCaptureSize the default maximum size of the captured bodies
+const DefaultCaptureSize = 4096
+
+// redacted replaces the redacted values
+const redacted = "[redacted]"
+
+// sensitiveHeaders the headers whose values are always redacted
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Set-Cookie":          true,
+	"Cookie":              true,
+}
+
+// CaptureResponse captures the response of the failed executions of an
+// HTTP healthcheck in their results
+type CaptureResponse struct {
+	// MaxSize the maximum size in bytes of the captured body, the body
+	// being truncated above
+	MaxSize uint64 `json:"max-size,omitempty" yaml:"max-size,omitempty"`
+	// Redact the regular expressions of the parts of the body and of the
+	// headers values replaced by [redacted]. The authorization and cookie
+	// headers values are always redacted.
+	Redact []Regexp `json:"redact,omitempty" yaml:"redact,omitempty"`
+}
+
+// DeepCopyInto copies the receiver into out
+func (c *CaptureResponse) DeepCopyInto(out *CaptureResponse) {
+	*out = *c
+	if c.Redact != nil {
+		out.Redact = make([]Regexp, len(c.Redact))
+		copy(out.Redact, c.Redact)
+	}
+}
+
+// CapturedResponse the response of a failed HTTP healthcheck execution
+type CapturedResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	// Truncated the body exceeded the maximum size of the capture
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// redact replaces the parts of a value matching the patterns
+func redact(value string, patterns []Regexp) string {
+	for i := range patterns {
+		r := regexp.Regexp(patterns[i])
+		value = r.ReplaceAllString(value, redacted)
+	}
+	return value
+}
+
+// newCapturedResponse captures a response, truncating and redacting its
+// body and its headers
+func newCapturedResponse(config *CaptureResponse, status int, header http.Header, body []byte) *CapturedResponse {
+	maxSize := config.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultCaptureSize
+	}
+	captured := &CapturedResponse{
+		Status:  status,
+		Headers: make(map[string]string, len(header)),
+	}
+	if uint64(len(body)) > maxSize {
+		body = body[:maxSize]
+		// a truncated multi-byte character is removed
+		for len(body) > 0 && !utf8.Valid(body) {
+			body = body[:len(body)-1]
+		}
+		captured.Truncated = true
+	}
+	captured.Body = redact(string(body), config.Redact)
+	for name, values := range header {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			captured.Headers[name] = redacted
+			continue
+		}
+		captured.Headers[name] = redact(strings.Join(values, ", "), config.Redact)
+	}
+	return captured
+}
+
+// responseKey the context key of the responses recorder
+type responseKey struct{}
+
+// responseRecorder records the response of an healthcheck execution, the
+// response being only captured if the execution fails
+type responseRecorder struct {
+	lock     sync.Mutex
+	config   *CaptureResponse
+	status   int
+	header   http.Header
+	body     []byte
+	recorded bool
+}
+
+// withResponse returns a context recording the responses of the
+// executions
+func withResponse(ctx context.Context) (context.Context, *responseRecorder) {
+	recorder := &responseRecorder{}
+	return context.WithValue(ctx, responseKey{}, recorder), recorder
+}
+
+// responseRecorderFromContext returns the responses recorder of the
+// context, or nil
+func responseRecorderFromContext(ctx context.Context) *responseRecorder {
+	recorder, _ := ctx.Value(responseKey{}).(*responseRecorder)
+	return recorder
+}
+
+// recordResponse records a response if the context has a responses
+// recorder and if the capture is configured
+func recordResponse(ctx context.Context, config *CaptureResponse, response *http.Response, body []byte) {
+	if config == nil {
+		return
+	}
+	if recorder := responseRecorderFromContext(ctx); recorder != nil {
+		recorder.lock.Lock()
+		defer recorder.lock.Unlock()
+		recorder.config = config
+		recorder.status = response.StatusCode
+		recorder.header = response.Header
+		recorder.body = body
+		recorder.recorded = true
+	}
+}
+
+// reset removes the recorded response, in order to only keep the response
+// of the last attempt
+func (r *responseRecorder) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.recorded = false
+	r.header = nil
+	r.body = nil
+}
+
+// get returns the captured response, or nil. The response is truncated
+// and redacted.
+func (r *responseRecorder) get() *CapturedResponse {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.recorded {
+		return nil
+	}
+	return newCapturedResponse(r.config, r.status, r.header, r.body)
+}
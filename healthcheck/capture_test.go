@@ -0,0 +1,104 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestNewCapturedResponse(t *testing.T) {
+	config := &CaptureResponse{
+		MaxSize: 10,
+		Redact:  []Regexp{Regexp(*regexp.MustCompile("token=[^&]*"))},
+	}
+	header := http.Header{
+		"Set-Cookie": []string{"session=abc"},
+		"Location":   []string{"/login?token=abc&next=/"},
+	}
+	captured := newCapturedResponse(config, 500, header, []byte("token=secret-value"))
+	if captured.Status != 500 {
+		t.Fatalf("Invalid status %d", captured.Status)
+	}
+	if !captured.Truncated {
+		t.Fatalf("The body should be truncated")
+	}
+	if captured.Body != "[redacted]" {
+		t.Fatalf("Invalid body %s", captured.Body)
+	}
+	if captured.Headers["Set-Cookie"] != "[redacted]" {
+		t.Fatalf("Invalid Set-Cookie header %s", captured.Headers["Set-Cookie"])
+	}
+	if captured.Headers["Location"] != "/login?[redacted]&next=/" {
+		t.Fatalf("Invalid Location header %s", captured.Headers["Location"])
+	}
+	captured = newCapturedResponse(&CaptureResponse{MaxSize: 4}, 500, nil, []byte("abcé"))
+	if captured.Body != "abc" || !captured.Truncated {
+		t.Fatalf("Invalid truncated body %s", captured.Body)
+	}
+	captured = newCapturedResponse(&CaptureResponse{}, 500, nil, []byte("error"))
+	if captured.Body != "error" || captured.Truncated {
+		t.Fatalf("Invalid body %s", captured.Body)
+	}
+}
+
+func TestHTTPExecuteCaptureResponse(t *testing.T) {
+	status := http.StatusInternalServerError
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request", "password=foo")
+		w.WriteHeader(status)
+		_, err := w.Write([]byte(strings.Repeat("a", 100) + "password=foo"))
+		if err != nil {
+			t.Fatalf("Error writing :\n%v", err)
+		}
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+		Base: Base{
+			Name:   "foo",
+			OneOff: true,
+		},
+		ValidStatus: []uint{200},
+		Port:        uint(port),
+		Target:      "127.0.0.1",
+		Protocol:    HTTP,
+		Path:        "/",
+		Timeout:     Duration(time.Second * 2),
+		CaptureResponse: &CaptureResponse{
+			MaxSize: 50,
+			Redact:  []Regexp{Regexp(*regexp.MustCompile("password=[^&]*"))},
+		},
+	})
+	result := ExecuteOnce(context.Background(), h)
+	if result.Success {
+		t.Fatalf("The healthcheck should fail")
+	}
+	if result.Response == nil {
+		t.Fatalf("The response should be captured")
+	}
+	if result.Response.Status != 500 || !result.Response.Truncated || result.Response.Body != strings.Repeat("a", 50) {
+		t.Fatalf("Invalid captured response %v", result.Response)
+	}
+	if result.Response.Headers["X-Request"] != "[redacted]" {
+		t.Fatalf("Invalid captured header %s", result.Response.Headers["X-Request"])
+	}
+	status = http.StatusOK
+	result = ExecuteOnce(context.Background(), h)
+	if !result.Success {
+		t.Fatalf("healthcheck error :\n%s", result.Message)
+	}
+	if result.Response != nil {
+		t.Fatalf("The response of a successful execution should not be captured")
+	}
+}
@@ -0,0 +1,118 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrorDNS the resolution of the target failed
+	ErrorDNS = "dns-error"
+	// ErrorDNSTimeout the resolution of the target timed out
+	ErrorDNSTimeout = "dns-timeout"
+	// ErrorConnectionRefused the target refused the connection
+	ErrorConnectionRefused = "connection-refused"
+	// ErrorConnectionReset the target reset the connection
+	ErrorConnectionReset = "connection-reset"
+	// ErrorTimeout the healthcheck timed out
+	ErrorTimeout = "timeout"
+	// ErrorTLS the TLS handshake or the certificate verification failed
+	ErrorTLS = "tls-error"
+	// ErrorAssertion the target answered but the response is invalid
+	// (status code, body, IP addresses, certificate expiration...)
+	ErrorAssertion = "assertion-failed"
+	// ErrorCommand the command exited with an error
+	ErrorCommand = "command-failed"
+	// ErrorDependency a dependency of the healthcheck is unhealthy
+	ErrorDependency = "dependency-failure"
+	// ErrorLatency the target answered slower than the critical threshold
+	ErrorLatency = "latency-exceeded"
+	// ErrorPolicy the target is forbidden by the targets policy
+	ErrorPolicy = "policy-violation"
+	// ErrorHeartbeat no heartbeat was received in time
+	ErrorHeartbeat = "heartbeat-missed"
+	// ErrorNetwork another network error
+	ErrorNetwork = "network-error"
+	// ErrorUnknown the error could not be classified
+	ErrorUnknown = "unknown"
+)
+
+// categoryError an error whose category is known
+type categoryError struct {
+	category string
+	err      error
+}
+
+// Error returns the error message
+func (e *categoryError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *categoryError) Unwrap() error {
+	return e.err
+}
+
+// withCategory sets the category of an error
+func withCategory(category string, err error) error {
+	return &categoryError{category: category, err: err}
+}
+
+// assertionError returns an assertion failure
+func assertionError(format string, args ...interface{}) error {
+	return withCategory(ErrorAssertion, fmt.Errorf(format, args...))
+}
+
+// ErrorCategory classifies the error of an healthcheck execution
+func ErrorCategory(err error) string {
+	if err == nil {
+		return ""
+	}
+	var categoryErr *categoryError
+	if errors.As(err, &categoryErr) {
+		return categoryErr.category
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return ErrorDNSTimeout
+		}
+		return ErrorDNS
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrorConnectionRefused
+	}
+	if errors.Is(err, syscall.ECONNRESET) {
+		return ErrorConnectionReset
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorTimeout
+	}
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	var recordErr tls.RecordHeaderError
+	if errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &invalidErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &recordErr) ||
+		strings.Contains(err.Error(), "tls: ") {
+		return ErrorTLS
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return ErrorNetwork
+	}
+	return ErrorUnknown
+}
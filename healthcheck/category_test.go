@@ -0,0 +1,64 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+func TestErrorCategory(t *testing.T) {
+	cases := []struct {
+		err      error
+		category string
+	}{
+		{err: nil, category: ""},
+		{err: errors.New("foo"), category: ErrorUnknown},
+		{err: assertionError("invalid status %d", 500), category: ErrorAssertion},
+		{err: errors.Wrapf(assertionError("invalid body"), "wrapped"), category: ErrorAssertion},
+		{err: errors.Wrapf(&net.DNSError{Err: "no such host", Name: "foo"}, "lookup"), category: ErrorDNS},
+		{err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "i/o timeout", Name: "foo", IsTimeout: true}}, category: ErrorDNSTimeout},
+		{err: errors.Wrapf(context.DeadlineExceeded, "request"), category: ErrorTimeout},
+		{err: fmt.Errorf("request: %w", x509.UnknownAuthorityError{}), category: ErrorTLS},
+		{err: errors.New("remote error: tls: bad certificate"), category: ErrorTLS},
+		{err: &net.OpError{Op: "dial", Err: errors.New("network is unreachable")}, category: ErrorNetwork},
+		// the outermost category is used
+		{err: withCategory(ErrorTimeout, assertionError("invalid body")), category: ErrorTimeout},
+	}
+	for _, c := range cases {
+		category := ErrorCategory(c.err)
+		if category != c.category {
+			t.Fatalf("Invalid category for %v: %s, expected %s", c.err, category, c.category)
+		}
+	}
+}
+
+func TestErrorCategoryConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen: %s", err.Error())
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	h := NewTCPHealthcheck(zap.NewExample(), &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:   "foo",
+			OneOff: true,
+		},
+		Target:  "127.0.0.1",
+		Port:    uint(port),
+		Timeout: Duration(time.Second),
+	})
+	result := ExecuteOnce(context.Background(), h)
+	if result.Success {
+		t.Fatalf("The healthcheck should fail")
+	}
+	if result.ErrorCategory != ErrorConnectionRefused {
+		t.Fatalf("Invalid category %s", result.ErrorCategory)
+	}
+}
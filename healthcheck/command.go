@@ -5,7 +5,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
@@ -18,6 +23,19 @@ type CommandHealthcheckConfiguration struct {
 	Command   string   `json:"command"`
 	Arguments []string `json:"arguments"`
 	Timeout   Duration `json:"timeout"`
+	// Directory the working directory of the command
+	Directory string `json:"directory,omitempty" yaml:"directory,omitempty"`
+	// User and UserGroup the user and the group executing the command,
+	// names or IDs. The group is the user primary group by default. Linux
+	// only, the daemon should run as root.
+	User      string `json:"user,omitempty" yaml:"user,omitempty"`
+	UserGroup string `json:"user-group,omitempty" yaml:"user-group,omitempty"`
+	// MemoryLimit the maximum memory in bytes used by the command,
+	// enforced by a cgroup of the commands policy cgroup (Linux only)
+	MemoryLimit uint64 `json:"memory-limit,omitempty" yaml:"memory-limit,omitempty"`
+	// CPULimit the maximum number of CPUs used by the command, enforced
+	// by a cgroup of the commands policy cgroup (Linux only)
+	CPULimit float64 `json:"cpu-limit,omitempty" yaml:"cpu-limit,omitempty"`
 }
 
 // CommandHealthcheck defines an HTTP healthcheck
@@ -26,7 +44,84 @@ type CommandHealthcheck struct {
 	Config *CommandHealthcheckConfiguration
 	URL    string
 
-	Tick *time.Ticker
+	Tick       *time.Ticker
+	credential *commandCredential
+}
+
+// commandCredential the user and the group executing a command
+type commandCredential struct {
+	uid uint32
+	gid uint32
+}
+
+// parseID parses a user or a group ID
+func parseID(value string) (uint32, error) {
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Invalid ID %s", value)
+	}
+	return uint32(id), nil
+}
+
+// lookupUser resolves a user, by name or by ID, and returns its ID and
+// its primary group ID
+func lookupUser(value string) (uint32, uint32, error) {
+	u, err := user.Lookup(value)
+	if err != nil {
+		u, err = user.LookupId(value)
+	}
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "Fail to find the user %s", value)
+	}
+	uid, err := parseID(u.Uid)
+	if err != nil {
+		return 0, 0, err
+	}
+	gid, err := parseID(u.Gid)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uid, gid, nil
+}
+
+// lookupGroup resolves a group, by name or by ID
+func lookupGroup(value string) (uint32, error) {
+	g, err := user.LookupGroup(value)
+	if err != nil {
+		g, err = user.LookupGroupId(value)
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "Fail to find the group %s", value)
+	}
+	return parseID(g.Gid)
+}
+
+// lookupCredential resolves the user and the group executing a command,
+// nil if the command is executed by the daemon user
+func lookupCredential(userName string, groupName string) (*commandCredential, error) {
+	if userName == "" && groupName == "" {
+		return nil, nil
+	}
+	credential := &commandCredential{
+		uid: uint32(os.Getuid()),
+		gid: uint32(os.Getgid()),
+	}
+	if userName != "" {
+		uid, gid, err := lookupUser(userName)
+		if err != nil {
+			return nil, err
+		}
+		credential.uid = uid
+		credential.gid = gid
+	}
+	if groupName != "" {
+		gid, err := lookupGroup(groupName)
+		if err != nil {
+			return nil, err
+		}
+		credential.gid = gid
+	}
+	return credential, nil
 }
 
 // Validate validates the healthcheck configuration
@@ -40,7 +135,17 @@ func (config *CommandHealthcheckConfiguration) Validate() error {
 	if config.Timeout == 0 {
 		return errors.New("The healthcheck timeout is missing")
 	}
-	if !config.Base.OneOff {
+	if config.Directory != "" && !filepath.IsAbs(config.Directory) {
+		return errors.New("The healthcheck directory should be an absolute path")
+	}
+	// the minimum CPU quota of the cgroups is 1ms per 100ms period
+	if config.CPULimit < 0 || (config.CPULimit != 0 && config.CPULimit < 0.01) {
+		return errors.New("The healthcheck cpu-limit should be greater than 0.01")
+	}
+	if runtime.GOOS != "linux" && (config.User != "" || config.UserGroup != "" || config.MemoryLimit != 0 || config.CPULimit != 0) {
+		return errors.New("The healthcheck user, group and resources limits are only supported on Linux")
+	}
+	if !config.Base.OneOff && config.Base.Cron == nil {
 		if config.Base.Interval < Duration(2*time.Second) {
 			return errors.New("The healthcheck interval should be greater than 2 second")
 		}
@@ -48,11 +153,20 @@ func (config *CommandHealthcheckConfiguration) Validate() error {
 			return errors.New("The healthcheck interval should be greater than the timeout")
 		}
 	}
+	err := config.Base.ValidateBase()
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
 // Initialize the healthcheck.
 func (h *CommandHealthcheck) Initialize() error {
+	credential, err := lookupCredential(h.Config.User, h.Config.UserGroup)
+	if err != nil {
+		return err
+	}
+	h.credential = credential
 	return nil
 }
 
@@ -66,6 +180,11 @@ func (h *CommandHealthcheck) Base() Base {
 	return h.Config.Base
 }
 
+// Timeout returns the healthcheck timeout
+func (h *CommandHealthcheck) Timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
 // SetSource set the healthcheck source
 func (h *CommandHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
@@ -107,13 +226,24 @@ func (h *CommandHealthcheck) LogInfo(message string) {
 }
 
 // Execute executes an healthcheck on the given domain
-func (h *CommandHealthcheck) Execute() error {
+func (h *CommandHealthcheck) Execute(ctx context.Context) error {
 	h.LogDebug("start executing healthcheck")
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(h.Config.Timeout)*time.Second)
-	defer cancel()
+	cgroup := ""
+	if policy := commandPolicyFromContext(ctx); policy != nil {
+		if err := policy.Check(h.Config.Command); err != nil {
+			return err
+		}
+		cgroup = policy.Cgroup
+	}
 	var stdErr bytes.Buffer
 	cmd := exec.CommandContext(ctx, h.Config.Command, h.Config.Arguments...)
 	cmd.Stderr = &stdErr
+	cmd.Dir = h.Config.Directory
+	release, err := prepareCommand(cmd, h.credential, h.Config.MemoryLimit, h.Config.CPULimit, cgroup)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to prepare the command execution")
+	}
+	defer release()
 	if err := cmd.Run(); err != nil {
 		var errorMsg string
 		exitErr, isExitError := err.(*exec.ExitError)
@@ -122,6 +252,9 @@ func (h *CommandHealthcheck) Execute() error {
 		} else {
 			errorMsg = fmt.Sprintf("The command failed, stderr=%s", stdErr.String())
 		}
+		if isExitError {
+			return withCategory(ErrorCommand, errors.Wrapf(err, errorMsg))
+		}
 		return errors.Wrapf(err, errorMsg)
 	}
 
@@ -0,0 +1,78 @@
+//go:build linux
+
+package healthcheck
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cpuPeriod the period of the cgroups CPU quota, in microseconds
+const cpuPeriod = 100000
+
+// removeCgroup kills the processes left in a cgroup, and removes it
+func removeCgroup(dir string) {
+	// cgroup.kill is not available before Linux 5.14
+	_ = os.WriteFile(filepath.Join(dir, "cgroup.kill"), []byte("1"), 0)
+	// the cgroup can not be removed until its processes exited
+	for i := 0; i < 10; i++ {
+		err := os.Remove(dir)
+		if err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// prepareCommand sets the user and the group executing the command, and
+// creates the cgroup limiting its resources if needed. The command is
+// started in the cgroup. The returned function removes the cgroup once
+// the command exited.
+func prepareCommand(cmd *exec.Cmd, credential *commandCredential, memoryLimit uint64, cpuLimit float64, cgroup string) (func(), error) {
+	attr := &syscall.SysProcAttr{}
+	if credential != nil {
+		attr.Credential = &syscall.Credential{Uid: credential.uid, Gid: credential.gid}
+	}
+	cmd.SysProcAttr = attr
+	if memoryLimit == 0 && cpuLimit == 0 {
+		return func() {}, nil
+	}
+	if cgroup == "" {
+		return nil, errors.New("The resources limits require the cgroup of the commands policy")
+	}
+	dir, err := os.MkdirTemp(cgroup, "command-")
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to create the cgroup of the command")
+	}
+	limits := make(map[string]string)
+	if memoryLimit != 0 {
+		limits["memory.max"] = strconv.FormatUint(memoryLimit, 10)
+	}
+	if cpuLimit != 0 {
+		limits["cpu.max"] = fmt.Sprintf("%d %d", int64(cpuLimit*cpuPeriod), cpuPeriod)
+	}
+	for file, value := range limits {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0); err != nil {
+			removeCgroup(dir)
+			return nil, errors.Wrapf(err, "Fail to set the %s limit of the command", file)
+		}
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		removeCgroup(dir)
+		return nil, errors.Wrap(err, "Fail to open the cgroup of the command")
+	}
+	attr.UseCgroupFD = true
+	attr.CgroupFD = int(f.Fd())
+	return func() {
+		f.Close()
+		removeCgroup(dir)
+	}, nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package healthcheck
+
+import (
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// prepareCommand fails if the command user, group or resources limits
+// are set, they are only supported on Linux
+func prepareCommand(cmd *exec.Cmd, credential *commandCredential, memoryLimit uint64, cpuLimit float64, cgroup string) (func(), error) {
+	if credential != nil || memoryLimit != 0 || cpuLimit != 0 {
+		return nil, errors.New("The command user, group and resources limits are only supported on Linux")
+	}
+	return func() {}, nil
+}
@@ -1,6 +1,9 @@
 package healthcheck
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -15,7 +18,7 @@ func TestCommandExecuteSuccess(t *testing.T) {
 			Timeout: Duration(time.Second * 2),
 		},
 	}
-	err := h.Execute()
+	err := h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -30,8 +33,61 @@ func TestCommandExecuteFailure(t *testing.T) {
 			Timeout:   Duration(time.Second * 2),
 		},
 	}
-	err := h.Execute()
+	err := h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("healthcheck was expected to fail")
 	}
 }
+
+func TestCommandExecuteTimeout(t *testing.T) {
+	h := CommandHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &CommandHealthcheckConfiguration{
+			Command:   "sleep",
+			Arguments: []string{"10"},
+			Timeout:   Duration(time.Millisecond * 100),
+		},
+	}
+	start := time.Now()
+	err := ExecuteWithTimeout(context.Background(), &h)
+	if err == nil {
+		t.Fatalf("healthcheck was expected to fail")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("The command was not killed on timeout")
+	}
+}
+
+func TestCommandExecuteDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("foo"), 0600); err != nil {
+		t.Fatalf("Fail to write the file:\n%v", err)
+	}
+	h := CommandHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &CommandHealthcheckConfiguration{
+			Command:   "ls",
+			Arguments: []string{"file"},
+			Directory: dir,
+			Timeout:   Duration(time.Second * 2),
+		},
+	}
+	err := h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+}
+
+func TestCommandValidate(t *testing.T) {
+	cases := []CommandHealthcheckConfiguration{
+		{Command: "ls", Directory: "relative"},
+		{Command: "ls", CPULimit: 0.001},
+		{Command: "ls", CPULimit: -1},
+	}
+	for _, c := range cases {
+		c.Base = Base{Name: "foo", OneOff: true}
+		if err := c.Validate(); err == nil {
+			t.Fatalf("Was expecting an error for %+v", c)
+		}
+	}
+}
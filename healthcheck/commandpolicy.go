@@ -0,0 +1,106 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CommandPolicy restricts the executables of the command healthchecks,
+// and sets the cgroup in which the resources of the commands are limited
+type CommandPolicy struct {
+	// Allow the glob patterns of the allowed executables paths, all the
+	// executables being allowed if empty
+	Allow []string
+	// Cgroup the cgroup v2 directory in which a cgroup is created for each
+	// execution of the commands with resource limits. The cpu and memory
+	// controllers should be enabled in its cgroup.subtree_control.
+	Cgroup string
+}
+
+// Initialize validates the policy
+func (p *CommandPolicy) Initialize() error {
+	for _, pattern := range p.Allow {
+		if !filepath.IsAbs(pattern) {
+			return fmt.Errorf("Invalid allowed command %s, it should be an absolute path", pattern)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return errors.Wrapf(err, "Invalid allowed command %s", pattern)
+		}
+	}
+	if p.Cgroup != "" && !filepath.IsAbs(p.Cgroup) {
+		return fmt.Errorf("Invalid cgroup %s, it should be an absolute path", p.Cgroup)
+	}
+	return nil
+}
+
+// UnmarshalYAML parses the commands policy from YAML
+func (p *CommandPolicy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawPolicy CommandPolicy
+	raw := rawPolicy{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the commands policy")
+	}
+	policy := CommandPolicy(raw)
+	if err := policy.Initialize(); err != nil {
+		return err
+	}
+	*p = policy
+	return nil
+}
+
+// commandPath returns the absolute path of a command, the commands
+// without separator being looked up in the PATH
+func commandPath(command string) (string, error) {
+	if !strings.Contains(command, string(filepath.Separator)) {
+		return exec.LookPath(command)
+	}
+	return filepath.Abs(command)
+}
+
+// Check returns an error if the command is not allowed by the policy
+func (p *CommandPolicy) Check(command string) error {
+	if len(p.Allow) == 0 {
+		return nil
+	}
+	path, err := commandPath(command)
+	if err != nil {
+		return withCategory(ErrorPolicy, errors.Wrapf(err, "Fail to find the command %s", command))
+	}
+	path = filepath.Clean(path)
+	for _, pattern := range p.Allow {
+		if match, _ := filepath.Match(pattern, path); match {
+			return nil
+		}
+	}
+	return withCategory(ErrorPolicy, fmt.Errorf("the command %s is not allowed by the commands policy", path))
+}
+
+// checkCommand returns an error if the healthcheck is a command
+// healthcheck whose command is not allowed by the policy
+func (p *CommandPolicy) checkCommand(healthcheck Healthcheck) error {
+	check, ok := healthcheck.(*CommandHealthcheck)
+	if !ok {
+		return nil
+	}
+	return p.Check(check.Config.Command)
+}
+
+// commandPolicyKey the context key of the commands policy
+type commandPolicyKey struct{}
+
+// withCommandPolicy returns a context containing the commands policy
+func withCommandPolicy(ctx context.Context, policy *CommandPolicy) context.Context {
+	return context.WithValue(ctx, commandPolicyKey{}, policy)
+}
+
+// commandPolicyFromContext returns the commands policy of the context, or
+// nil
+func commandPolicyFromContext(ctx context.Context) *CommandPolicy {
+	policy, _ := ctx.Value(commandPolicyKey{}).(*CommandPolicy)
+	return policy
+}
@@ -0,0 +1,90 @@
+package healthcheck
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestCommandPolicyCheck(t *testing.T) {
+	path, err := exec.LookPath("ls")
+	if err != nil {
+		t.Fatalf("Fail to find ls:\n%v", err)
+	}
+	cases := []struct {
+		allow   []string
+		command string
+		allowed bool
+	}{
+		{allow: nil, command: "ls", allowed: true},
+		{allow: []string{filepath.Join(filepath.Dir(path), "*")}, command: "ls", allowed: true},
+		{allow: []string{path}, command: path, allowed: true},
+		{allow: []string{"/doesnotexist/*"}, command: "ls", allowed: false},
+		{allow: []string{"/doesnotexist/*"}, command: "/doesnotexist/../bin/ls", allowed: false},
+		{allow: []string{"/usr/bin/*"}, command: "doesnotexist", allowed: false},
+	}
+	for _, c := range cases {
+		policy := CommandPolicy{Allow: c.allow}
+		err := policy.Check(c.command)
+		if c.allowed && err != nil {
+			t.Fatalf("The command %s should be allowed by %v:\n%v", c.command, c.allow, err)
+		}
+		if !c.allowed {
+			if err == nil {
+				t.Fatalf("The command %s should be denied by %v", c.command, c.allow)
+			}
+			if ErrorCategory(err) != ErrorPolicy {
+				t.Fatalf("Invalid error category %s", ErrorCategory(err))
+			}
+		}
+	}
+}
+
+func TestCommandPolicyInvalid(t *testing.T) {
+	for _, config := range []string{"allow: [bin/*]", "allow: [\"/bin/[\"]", "cgroup: cabourotte"} {
+		var policy CommandPolicy
+		err := yaml.Unmarshal([]byte(config), &policy)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", config)
+		}
+	}
+}
+
+func TestCommandPolicyExecute(t *testing.T) {
+	h := CommandHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &CommandHealthcheckConfiguration{
+			Command: "ls",
+			Timeout: Duration(time.Second * 2),
+		},
+	}
+	ctx := withCommandPolicy(context.Background(), &CommandPolicy{Allow: []string{"/doesnotexist/*"}})
+	err := h.Execute(ctx)
+	if err == nil {
+		t.Fatalf("The command should be denied by the policy")
+	}
+	if ErrorCategory(err) != ErrorPolicy {
+		t.Fatalf("Invalid error category %s", ErrorCategory(err))
+	}
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	component.SetCommandPolicy(&CommandPolicy{Allow: []string{"/doesnotexist/*"}})
+	h.Config.Base = Base{Name: "denied", Interval: Duration(5 * time.Second), OneOff: true}
+	err = component.AddCheck(&h)
+	if err == nil {
+		t.Fatalf("The healthcheck should be rejected by the policy")
+	}
+}
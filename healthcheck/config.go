@@ -1,5 +1,14 @@
 package healthcheck
 
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
 const (
 	// SourceConfig the check is managed by the configuration file
 	SourceConfig string = ""
@@ -7,27 +16,322 @@ const (
 	SourceAPI string = "api"
 	// SourceHTTPDiscovery the check was created from the http discovery mechanism
 	SourceHTTPDiscovery string = "http-discovery"
+	// SourceKubernetesDiscovery the check was created from the kubernetes
+	// discovery mechanism
+	SourceKubernetesDiscovery string = "kubernetes-discovery"
+	// SourceConsulDiscovery the check was created from the consul discovery
+	// mechanism
+	SourceConsulDiscovery string = "consul-discovery"
+	// SourceSRVDiscovery the check was created from the DNS SRV discovery
+	// mechanism
+	SourceSRVDiscovery string = "srv-discovery"
+	// SourceDockerDiscovery the check was created from the docker discovery
+	// mechanism
+	SourceDockerDiscovery string = "docker-discovery"
+	// SourceEC2Discovery the check was created from the EC2 discovery
+	// mechanism
+	SourceEC2Discovery string = "ec2-discovery"
+	// SourceEurekaDiscovery the check was created from the eureka discovery
+	// mechanism
+	SourceEurekaDiscovery string = "eureka-discovery"
+	// SourceKVDiscovery the check was loaded from a key/value store
+	SourceKVDiscovery string = "kv-discovery"
+	// SourceFileSDDiscovery the check was created from the targets of
+	// Prometheus file_sd files
+	SourceFileSDDiscovery string = "filesd-discovery"
+	// SourceDirectory the check was loaded from a file of the checks
+	// directory
+	SourceDirectory string = "directory"
+)
+
+const (
+	// PriorityLow low priority healthchecks are skipped when the workers
+	// are saturated
+	PriorityLow string = "low"
+	// PriorityNormal the default priority
+	PriorityNormal string = "normal"
+	// PriorityHigh high priority healthchecks are executed first
+	PriorityHigh string = "high"
 )
 
+const (
+	// SeverityCritical the default severity
+	SeverityCritical string = "critical"
+	// SeverityWarning the severity of the healthchecks which should not
+	// page
+	SeverityWarning string = "warning"
+	// SeverityInfo the severity of the informational healthchecks
+	SeverityInfo string = "info"
+)
+
+// namespaceRegexp the valid namespaces
+var namespaceRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]*$`)
+
+// ID returns the identifier of an healthcheck. The healthchecks names are
+// unique per namespace.
+func ID(namespace string, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
 // Base shared fields between healthchecks
 type Base struct {
-	Name        string            `json:"name"`
-	Description string            `json:"description"`
-	Interval    Duration          `json:"interval"`
-	OneOff      bool              `json:"one-off"`
-	Source      string            `json:"source"`
-	Labels      map[string]string `json:"labels,omitempty"`
+	Name          string            `json:"name"`
+	Namespace     string            `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Group         string            `json:"group,omitempty" yaml:"group,omitempty"`
+	Description   string            `json:"description"`
+	Interval      Duration          `json:"interval"`
+	OneOff        bool              `json:"one-off"`
+	Source        string            `json:"source"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Jitter        Jitter            `json:"jitter"`
+	MaxRetries    uint              `json:"max-retries,omitempty" yaml:"max-retries,omitempty"`
+	RetryDelay    Duration          `json:"retry-delay,omitempty" yaml:"retry-delay,omitempty"`
+	Rise          uint              `json:"rise,omitempty"`
+	Fall          uint              `json:"fall,omitempty"`
+	FlapThreshold uint              `json:"flap-threshold,omitempty" yaml:"flap-threshold,omitempty"`
+	FlapWindow    Duration          `json:"flap-window,omitempty" yaml:"flap-window,omitempty"`
+	Backoff       *Backoff          `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+	DependsOn     []string          `json:"depends-on,omitempty" yaml:"depends-on,omitempty"`
+	Cron          *Cron             `json:"cron,omitempty" yaml:"cron,omitempty"`
+	Priority      string            `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Severity      string            `json:"severity,omitempty" yaml:"severity,omitempty"`
+	Enabled       *bool             `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	TTL           Duration          `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	Adaptive      *Adaptive         `json:"adaptive,omitempty" yaml:"adaptive,omitempty"`
+	// Shadow the results are exported, but ignored by the groups, the
+	// notifications and the SLA
+	Shadow bool `json:"shadow,omitempty" yaml:"shadow,omitempty"`
+	// SLO the service level objective, evaluated on the stored results
+	SLO *SLO `json:"slo,omitempty" yaml:"slo,omitempty"`
+	// ExpectedFailures the windows during which the failures are expected
+	ExpectedFailures []ExpectedFailures `json:"expected-failures,omitempty" yaml:"expected-failures,omitempty"`
+	// InitialDelay the delay before the first execution, for example to
+	// let the target start
+	InitialDelay Duration `json:"initial-delay,omitempty" yaml:"initial-delay,omitempty"`
+	// Spread aligns the executions on a phase of the interval derived
+	// from the healthcheck ID and the node name, the nodes probing the
+	// same target interleaving instead of probing it simultaneously
+	Spread bool `json:"spread,omitempty" yaml:"spread,omitempty"`
+	// InitialState the state of the healthcheck until the rise or fall
+	// threshold is reached, unknown by default
+	InitialState string `json:"initial-state,omitempty" yaml:"initial-state,omitempty"`
+	// Anomaly reports the successful executions as degraded if their
+	// duration deviates from the learned baseline
+	Anomaly *Anomaly `json:"anomaly,omitempty" yaml:"anomaly,omitempty"`
+	// Owner the owner of the healthcheck, for example an email address
+	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	// Team the team owning the healthcheck
+	Team string `json:"team,omitempty" yaml:"team,omitempty"`
+	// RunbookURL the URL of the runbook to follow when the healthcheck
+	// fails
+	RunbookURL string `json:"runbook-url,omitempty" yaml:"runbook-url,omitempty"`
+	// ReportEvery only exports one successful result out of every
+	// report-every, the failures and the state changes being always
+	// exported
+	ReportEvery uint `json:"report-every,omitempty" yaml:"report-every,omitempty"`
+	// ExclusionGroup the healthchecks sharing an exclusion group are never
+	// executed concurrently
+	ExclusionGroup string `json:"exclusion-group,omitempty" yaml:"exclusion-group,omitempty"`
+	// Annotations free-form descriptions added to the results, rendered
+	// as templates with the fields of the result
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	// GenerateName derives the name from the type and the target of the
+	// healthcheck when the name is not set, see GenerateName
+	GenerateName bool `json:"generate-name,omitempty" yaml:"generate-name,omitempty"`
+}
+
+// ID returns the healthcheck identifier
+func (b *Base) ID() string {
+	return ID(b.Namespace, b.Name)
+}
+
+// IsEnabled returns true if the healthcheck should be scheduled.
+// Healthchecks are enabled by default.
+func (b *Base) IsEnabled() bool {
+	return b.Enabled == nil || *b.Enabled
+}
+
+// SeverityLevel returns the healthcheck severity, critical by default
+func (b *Base) SeverityLevel() string {
+	if b.Severity == "" {
+		return SeverityCritical
+	}
+	return b.Severity
+}
+
+// priorityRank returns the rank of a priority, 0 being the highest
+func priorityRank(priority string) int {
+	switch priority {
+	case PriorityHigh:
+		return 0
+	case PriorityLow:
+		return 2
+	}
+	return 1
+}
+
+// Backoff configures the interval backoff for unhealthy healthchecks.
+// The interval is multiplied by the factor for each new failure once the
+// healthcheck is unhealthy, up to the max interval. It is reset when the
+// healthcheck recovers.
+type Backoff struct {
+	MaxInterval Duration `json:"max-interval" yaml:"max-interval"`
+	Factor      float64  `json:"factor,omitempty" yaml:"factor,omitempty"`
+}
+
+// interval computes the interval for the given number of failures
+// since the healthcheck became unhealthy
+func (b *Backoff) interval(interval time.Duration, failures uint) time.Duration {
+	factor := b.Factor
+	if factor == 0 {
+		factor = 2
+	}
+	result := float64(interval)
+	for i := uint(0); i < failures && result < float64(b.MaxInterval); i++ {
+		result = result * factor
+	}
+	if result > float64(b.MaxInterval) {
+		return time.Duration(b.MaxInterval)
+	}
+	return time.Duration(result)
+}
+
+// Adaptive configures the adaptive interval for stable healthchecks.
+// Once the healthcheck succeeded `after` consecutive times, the interval is
+// multiplied by the factor for each new success, up to the max interval.
+// The base interval is used again as soon as the healthcheck fails.
+type Adaptive struct {
+	MaxInterval Duration `json:"max-interval" yaml:"max-interval"`
+	Factor      float64  `json:"factor,omitempty" yaml:"factor,omitempty"`
+	After       uint     `json:"after,omitempty" yaml:"after,omitempty"`
+}
+
+// interval computes the interval for the given number of consecutive
+// successes
+func (a *Adaptive) interval(interval time.Duration, successes uint) time.Duration {
+	after := a.After
+	if after == 0 {
+		after = 10
+	}
+	if successes < after {
+		return interval
+	}
+	factor := a.Factor
+	if factor == 0 {
+		factor = 2
+	}
+	result := float64(interval)
+	for i := uint(0); i <= successes-after && result < float64(a.MaxInterval); i++ {
+		result = result * factor
+	}
+	if result > float64(a.MaxInterval) {
+		return time.Duration(a.MaxInterval)
+	}
+	return time.Duration(result)
+}
+
+// ValidateBase validates the fields shared between healthchecks
+func (b *Base) ValidateBase() error {
+	if err := ValidateName(b.Name); err != nil {
+		return err
+	}
+	if !namespaceRegexp.MatchString(b.Namespace) {
+		return fmt.Errorf("Invalid healthcheck namespace %s", b.Namespace)
+	}
+	if b.Cron != nil && b.Interval != 0 {
+		return errors.New("The healthcheck interval and cron options are mutually exclusive")
+	}
+	// the options related to the interval are not used with cron
+	scheduled := !b.OneOff && b.Cron == nil
+	if b.Jitter.Max(time.Duration(b.Interval)) >= time.Duration(b.Interval) && scheduled {
+		return errors.New("The healthcheck jitter should be lower than the interval")
+	}
+	if b.Spread && b.Cron != nil {
+		return errors.New("The healthcheck spread and cron options are mutually exclusive")
+	}
+	if b.Spread && b.Jitter.Max(time.Duration(b.Interval)) != 0 {
+		return errors.New("The healthcheck spread and jitter options are mutually exclusive")
+	}
+	if b.FlapThreshold != 0 && b.FlapWindow == 0 {
+		return errors.New("The healthcheck flap-window is required when flap-threshold is set")
+	}
+	for _, dependency := range b.DependsOn {
+		if dependency == b.Name {
+			return errors.New("An healthcheck cannot depend on itself")
+		}
+	}
+	if b.Backoff != nil && b.Cron == nil {
+		if b.Backoff.MaxInterval < b.Interval {
+			return errors.New("The healthcheck backoff max-interval should be greater than the interval")
+		}
+		if b.Backoff.Factor != 0 && b.Backoff.Factor < 1 {
+			return errors.New("The healthcheck backoff factor should be greater than 1")
+		}
+	}
+	if b.Adaptive != nil && b.Cron == nil {
+		if b.Adaptive.MaxInterval < b.Interval {
+			return errors.New("The healthcheck adaptive max-interval should be greater than the interval")
+		}
+		if b.Adaptive.Factor != 0 && b.Adaptive.Factor < 1 {
+			return errors.New("The healthcheck adaptive factor should be greater than 1")
+		}
+	}
+	if scheduled && Duration(b.MaxRetries)*b.RetryDelay >= b.Interval {
+		return errors.New("The healthcheck retries delays should be lower than the interval")
+	}
+	if b.Priority != "" && b.Priority != PriorityLow && b.Priority != PriorityNormal && b.Priority != PriorityHigh {
+		return fmt.Errorf("Invalid healthcheck priority %s", b.Priority)
+	}
+	if b.Severity != "" && b.Severity != SeverityCritical && b.Severity != SeverityWarning && b.Severity != SeverityInfo {
+		return fmt.Errorf("Invalid healthcheck severity %s", b.Severity)
+	}
+	if b.SLO != nil {
+		if err := b.SLO.Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range b.ExpectedFailures {
+		if err := b.ExpectedFailures[i].Validate(); err != nil {
+			return err
+		}
+	}
+	if b.InitialDelay < 0 {
+		return errors.New("The healthcheck initial-delay should be positive")
+	}
+	if b.InitialState != "" && b.InitialState != StateUnknown && b.InitialState != StateHealthy {
+		return fmt.Errorf("Invalid healthcheck initial-state %s, valid states are %s and %s", b.InitialState, StateUnknown, StateHealthy)
+	}
+	if b.Anomaly != nil {
+		if err := b.Anomaly.Validate(); err != nil {
+			return err
+		}
+	}
+	if b.RunbookURL != "" {
+		runbook, err := url.Parse(b.RunbookURL)
+		if err != nil || (runbook.Scheme != "http" && runbook.Scheme != "https") || runbook.Host == "" {
+			return fmt.Errorf("Invalid healthcheck runbook-url %s, an HTTP or HTTPS URL is expected", b.RunbookURL)
+		}
+	}
+	if err := validateAnnotations(b.Annotations); err != nil {
+		return err
+	}
+	return nil
 }
 
-// SourceChecksNames returns all checks managed by the given source
+// SourceChecksNames returns the identifiers of all checks managed by the
+// given source
 func (c *Component) SourceChecksNames(source string) map[string]bool {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	checks := make(map[string]bool)
 	for i := range c.Healthchecks {
 		wrapper := c.Healthchecks[i]
-		if wrapper.healthcheck.Base().Source == source {
-			checks[wrapper.healthcheck.Base().Name] = true
+		base := wrapper.healthcheck.Base()
+		if base.Source == source {
+			checks[base.ID()] = true
 		}
 	}
 	return checks
@@ -43,6 +347,48 @@ func (in *Base) DeepCopyInto(out *Base) {
 			(*out)[key] = val
 		}
 	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Backoff != nil {
+		in, out := &in.Backoff, &out.Backoff
+		*out = new(Backoff)
+		**out = **in
+	}
+	if in.Adaptive != nil {
+		in, out := &in.Adaptive, &out.Adaptive
+		*out = new(Adaptive)
+		**out = **in
+	}
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.SLO != nil {
+		in, out := &in.SLO, &out.SLO
+		*out = new(SLO)
+		**out = **in
+	}
+	if in.ExpectedFailures != nil {
+		in, out := &in.ExpectedFailures, &out.ExpectedFailures
+		*out = make([]ExpectedFailures, len(*in))
+		copy(*out, *in)
+	}
+	if in.Anomaly != nil {
+		in, out := &in.Anomaly, &out.Anomaly
+		*out = new(Anomaly)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Base.
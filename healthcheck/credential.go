@@ -0,0 +1,119 @@
+package healthcheck
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Credential a named credential referenced by the healthchecks, in order to
+// define the secrets once. The HTTP healthchecks use the username and the
+// password (basic authentication) or the token (bearer authentication), and
+// the HTTP and TLS healthchecks use the certificates if they do not
+// configure their own.
+type Credential struct {
+	Name     string `json:"name"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Cert     string `json:"cert,omitempty"`
+	Cacert   string `json:"cacert,omitempty"`
+}
+
+// Validate validates the credential
+func (c *Credential) Validate() error {
+	if c.Name == "" {
+		return errors.New("The credential name is missing")
+	}
+	if c.Username == "" && c.Password != "" {
+		return fmt.Errorf("The credential %s has a password but no username", c.Name)
+	}
+	if c.Username != "" && c.Token != "" {
+		return fmt.Errorf("The credential %s should define a username or a token, not both", c.Name)
+	}
+	if (c.Key == "") != (c.Cert == "") {
+		return fmt.Errorf("The credential %s should define both the key and the cert", c.Name)
+	}
+	if c.Username == "" && c.Token == "" && c.Cert == "" && c.Cacert == "" {
+		return fmt.Errorf("The credential %s is empty", c.Name)
+	}
+	return nil
+}
+
+// Credentials the named credentials
+type Credentials []Credential
+
+// Validate validates the credentials, their names being unique
+func (c Credentials) Validate() error {
+	names := make(map[string]bool)
+	for i := range c {
+		if err := c[i].Validate(); err != nil {
+			return err
+		}
+		if names[c[i].Name] {
+			return fmt.Errorf("The credential %s is defined several times", c[i].Name)
+		}
+		names[c[i].Name] = true
+	}
+	return nil
+}
+
+// Get returns the credential, or nil if it does not exist
+func (c Credentials) Get(name string) *Credential {
+	for i := range c {
+		if c[i].Name == name {
+			credential := c[i]
+			return &credential
+		}
+	}
+	return nil
+}
+
+// CredentialName returns the name of the credential referenced by the
+// healthcheck, or an empty string
+func CredentialName(check Healthcheck) string {
+	switch h := check.(type) {
+	case *HTTPHealthcheck:
+		return h.Config.Credential
+	case *TLSHealthcheck:
+		return h.Config.Credential
+	}
+	return ""
+}
+
+// Resolve sets the credential referenced by the healthcheck. An error is
+// returned if the credential does not exist.
+func (c Credentials) Resolve(check Healthcheck) error {
+	name := CredentialName(check)
+	if name == "" {
+		return nil
+	}
+	credential := c.Get(name)
+	if credential == nil {
+		return fmt.Errorf("The credential %s of the healthcheck %s does not exist", name, check.Base().Name)
+	}
+	switch h := check.(type) {
+	case *HTTPHealthcheck:
+		h.Config.credential = credential
+	case *TLSHealthcheck:
+		h.Config.credential = credential
+	}
+	return nil
+}
+
+// certificates returns the key, the certificate and the CA certificate of
+// an healthcheck, the ones of the credential being used if the healthcheck
+// does not configure its own
+func (c *Credential) certificates(key string, cert string, cacert string) (string, string, string) {
+	if c == nil {
+		return key, cert, cacert
+	}
+	if key == "" && cert == "" {
+		key, cert = c.Key, c.Cert
+	}
+	if cacert == "" {
+		cacert = c.Cacert
+	}
+	return key, cert, cacert
+}
@@ -0,0 +1,157 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestCredentialsValidate(t *testing.T) {
+	cases := []struct {
+		credentials Credentials
+		valid       bool
+	}{
+		{credentials: Credentials{{Name: "api", Username: "foo", Password: "bar"}}, valid: true},
+		{credentials: Credentials{{Name: "api", Token: "foo"}, {Name: "mtls", Key: "/tmp/key", Cert: "/tmp/cert"}}, valid: true},
+		{credentials: Credentials{{Name: "ca", Cacert: "/tmp/ca"}}, valid: true},
+		{credentials: Credentials{{Username: "foo"}}, valid: false},
+		{credentials: Credentials{{Name: "api"}}, valid: false},
+		{credentials: Credentials{{Name: "api", Password: "bar"}}, valid: false},
+		{credentials: Credentials{{Name: "api", Username: "foo", Token: "bar"}}, valid: false},
+		{credentials: Credentials{{Name: "mtls", Key: "/tmp/key"}}, valid: false},
+		{credentials: Credentials{{Name: "api", Token: "foo"}, {Name: "api", Token: "bar"}}, valid: false},
+	}
+	for i, c := range cases {
+		err := c.credentials.Validate()
+		if c.valid && err != nil {
+			t.Fatalf("Unexpected error for the case %d:\n%v", i, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Was expecting an error for the case %d", i)
+		}
+	}
+}
+
+func TestHTTPExecuteCredential(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if (ok && username == "foo" && password == "bar") || r.Header.Get("Authorization") == "Bearer token" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	credentials := Credentials{
+		{Name: "basic", Username: "foo", Password: "bar"},
+		{Name: "bearer", Token: "token"},
+		{Name: "invalid", Username: "foo", Password: "invalid"},
+	}
+	cases := []struct {
+		credential string
+		success    bool
+	}{
+		{credential: "basic", success: true},
+		{credential: "bearer", success: true},
+		{credential: "invalid", success: false},
+		{credential: "", success: false},
+	}
+	for _, c := range cases {
+		h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Minute),
+			},
+			ValidStatus: []uint{200},
+			Port:        uint(port),
+			Target:      "127.0.0.1",
+			Protocol:    HTTP,
+			Path:        "/",
+			Timeout:     Duration(time.Second * 2),
+			Credential:  c.credential,
+		})
+		err := credentials.Resolve(h)
+		if err != nil {
+			t.Fatalf("Fail to resolve the credential %s:\n%v", c.credential, err)
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		err = h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("Unexpected error for the credential %s:\n%v", c.credential, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for the credential %s", c.credential)
+		}
+	}
+	h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+		Base:       Base{Name: "foo"},
+		Credential: "unknown",
+	})
+	if err := credentials.Resolve(h); err == nil {
+		t.Fatalf("Was expecting an error for an unknown credential")
+	}
+}
+
+func TestComponentCredential(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	newCheck := func() *HTTPHealthcheck {
+		return NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Minute),
+			},
+			ValidStatus: []uint{200},
+			Port:        9999,
+			Target:      "127.0.0.1",
+			Protocol:    HTTP,
+			Timeout:     Duration(time.Second),
+			Credential:  "api",
+		})
+	}
+	err = component.AddCheck(newCheck())
+	if err == nil {
+		t.Fatalf("Was expecting an error for an unknown credential")
+	}
+	component.SetCredentials(Credentials{{Name: "api", Token: "foo"}})
+	err = component.AddCheck(newCheck())
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	// the healthcheck is replaced when its credential is modified
+	component.SetCredentials(Credentials{{Name: "api", Token: "bar"}})
+	err = component.AddCheck(newCheck())
+	if err != nil {
+		t.Fatalf("Fail to update the healthcheck\n%v", err)
+	}
+	check := component.GetCheck("foo").(*HTTPHealthcheck)
+	if check.Config.credential.Token != "bar" {
+		t.Fatalf("The credential of the healthcheck was not updated: %s", check.Config.credential.Token)
+	}
+	err = component.RemoveCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Cron is a standard cron expression (minute, hour, day of month, month,
+// day of week). The expression can be prefixed by `CRON_TZ=<timezone>` in
+// order to be evaluated in a specific timezone (UTC by default).
+type Cron struct {
+	expression string
+	location   *time.Location
+	minute     uint64
+	hour       uint64
+	dom        uint64
+	month      uint64
+	dow        uint64
+	// true if the day of month or the day of week field does not start
+	// with `*`, a stepped `*/N` field being unrestricted as in standard
+	// cron
+	domRestricted bool
+	dowRestricted bool
+}
+
+type cronField struct {
+	name string
+	min  uint
+	max  uint
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// parseCronField parses a cron field (`*`, `1,2`, `1-5`, `*/10`, `1-30/2`)
+// into a bitmask
+func parseCronField(value string, field cronField) (uint64, error) {
+	var result uint64
+	for _, part := range strings.Split(value, ",") {
+		step := uint64(1)
+		rangePart := part
+		if i := strings.Index(part, "/"); i != -1 {
+			s, err := strconv.ParseUint(part[i+1:], 10, 8)
+			if err != nil || s == 0 {
+				return 0, fmt.Errorf("Invalid step in the cron %s field: %s", field.name, part)
+			}
+			step = s
+			rangePart = part[:i]
+		}
+		start, end := uint64(field.min), uint64(field.max)
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			s, err := strconv.ParseUint(bounds[0], 10, 8)
+			if err != nil {
+				return 0, fmt.Errorf("Invalid value in the cron %s field: %s", field.name, part)
+			}
+			start = s
+			if len(bounds) == 2 {
+				e, err := strconv.ParseUint(bounds[1], 10, 8)
+				if err != nil {
+					return 0, fmt.Errorf("Invalid value in the cron %s field: %s", field.name, part)
+				}
+				end = e
+			} else if step == 1 {
+				end = start
+			}
+		}
+		if start < uint64(field.min) || end > uint64(field.max) || start > end {
+			return 0, fmt.Errorf("Out of range value in the cron %s field: %s", field.name, part)
+		}
+		for i := start; i <= end; i += step {
+			result |= 1 << i
+		}
+	}
+	return result, nil
+}
+
+// ParseCron parses a cron expression
+func ParseCron(expression string) (*Cron, error) {
+	cron := &Cron{
+		expression: expression,
+		location:   time.UTC,
+	}
+	expr := strings.TrimSpace(expression)
+	if strings.HasPrefix(expr, "CRON_TZ=") {
+		i := strings.Index(expr, " ")
+		if i == -1 {
+			return nil, fmt.Errorf("Invalid cron expression %s", expression)
+		}
+		location, err := time.LoadLocation(expr[len("CRON_TZ="):i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid timezone in cron expression %s", expression)
+		}
+		cron.location = location
+		expr = strings.TrimSpace(expr[i:])
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("Invalid cron expression %s: 5 fields expected", expression)
+	}
+	masks := make([]uint64, len(fields))
+	for i := range fields {
+		mask, err := parseCronField(fields[i], cronFields[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid cron expression %s", expression)
+		}
+		masks[i] = mask
+	}
+	cron.minute, cron.hour, cron.dom, cron.month, cron.dow = masks[0], masks[1], masks[2], masks[3], masks[4]
+	// 7 is also sunday
+	if cron.dow&(1<<7) != 0 {
+		cron.dow |= 1
+	}
+	cron.domRestricted = !strings.HasPrefix(fields[2], "*")
+	cron.dowRestricted = !strings.HasPrefix(fields[4], "*")
+	// expressions like `0 0 30 2 *` are valid fields but never match
+	if cron.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("Invalid cron expression %s: it never matches", expression)
+	}
+	return cron, nil
+}
+
+// dayMatches verifies if the day matches the expression. As in standard
+// cron, if both the day of month and the day of week are restricted, the
+// day matches if one of them matches.
+func (c *Cron) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domRestricted && c.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// Next returns the next activation time strictly after the given time,
+// or the zero time if the expression has no activation during the next
+// five years
+func (c *Cron) Next(from time.Time) time.Time {
+	t := from.In(c.location).Truncate(time.Minute).Add(time.Minute)
+	// five years is enough to find a match for any valid expression
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.location)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// cronRetryDelay the delay before looking again for the next activation
+// of a cron expression without activation
+const cronRetryDelay = 24 * time.Hour
+
+// delay returns the delay between now and the next activation strictly
+// after the given time. The expressions without activation are never
+// due, the activation being looked for again after cronRetryDelay.
+func (c *Cron) delay(now time.Time, from time.Time) time.Duration {
+	next := c.Next(from)
+	if next.IsZero() {
+		return cronRetryDelay
+	}
+	return next.Sub(now)
+}
+
+// String returns the cron expression
+func (c *Cron) String() string {
+	return c.expression
+}
+
+// UnmarshalYAML read a cron expression from yaml
+func (c *Cron) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw string
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the cron expression")
+	}
+	return c.UnmarshalText([]byte(raw))
+}
+
+// UnmarshalText unmarshal a cron expression
+func (c *Cron) UnmarshalText(text []byte) error {
+	cron, err := ParseCron(unQuote(text))
+	if err != nil {
+		return err
+	}
+	*c = *cron
+	return nil
+}
+
+// UnmarshalJSON unmarshal to json a cron expression
+func (c *Cron) UnmarshalJSON(text []byte) error {
+	return c.UnmarshalText(text)
+}
+
+// MarshalJSON marshal to json a cron expression
+func (c *Cron) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.expression)
+}
@@ -0,0 +1,121 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2023, 6, 1, 10, 30, 20, 0, time.UTC)
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+	cases := []struct {
+		expression string
+		want       time.Time
+	}{
+		{expression: "* * * * *", want: time.Date(2023, 6, 1, 10, 31, 0, 0, time.UTC)},
+		{expression: "*/15 * * * *", want: time.Date(2023, 6, 1, 10, 45, 0, 0, time.UTC)},
+		{expression: "0 2 * * *", want: time.Date(2023, 6, 2, 2, 0, 0, 0, time.UTC)},
+		{expression: "0 9-17 * * 1-5", want: time.Date(2023, 6, 1, 11, 0, 0, 0, time.UTC)},
+		{expression: "0 0 1 1 *", want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{expression: "30 8 * * 0", want: time.Date(2023, 6, 4, 8, 30, 0, 0, time.UTC)},
+		{expression: "30 8 * * 7", want: time.Date(2023, 6, 4, 8, 30, 0, 0, time.UTC)},
+		{expression: "0 0 15 * 1", want: time.Date(2023, 6, 5, 0, 0, 0, 0, time.UTC)},
+		// the stepped fields are unrestricted, both days should match
+		{expression: "0 0 */2 * 1", want: time.Date(2023, 6, 5, 0, 0, 0, 0, time.UTC)},
+		{expression: "0 0 1 * */2", want: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{expression: "0 0 29 2 *", want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{expression: "CRON_TZ=Europe/Paris 0 13 * * *", want: time.Date(2023, 6, 1, 13, 0, 0, 0, paris)},
+	}
+	for _, c := range cases {
+		cron, err := ParseCron(c.expression)
+		if err != nil {
+			t.Fatalf("Fail to parse cron expression %s: %v", c.expression, err)
+		}
+		next := cron.Next(from)
+		if !next.Equal(c.want) {
+			t.Fatalf("Invalid next time for %s: %s (expected %s)", c.expression, next, c.want)
+		}
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	cases := []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"*/0 * * * *",
+		"5-2 * * * *",
+		"CRON_TZ=Nowhere/Foo * * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+		"0 0 31 4,6,9,11 *",
+	}
+	for _, c := range cases {
+		_, err := ParseCron(c)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", c)
+		}
+	}
+}
+
+func TestCronUnmarshal(t *testing.T) {
+	var config TCPHealthcheckConfiguration
+	err := yaml.Unmarshal([]byte(`
+name: foo
+target: 127.0.0.1
+port: 80
+timeout: 5s
+cron: "0 2 * * *"
+`), &config)
+	if err != nil {
+		t.Fatalf("Unmarshal yaml error:\n%v", err)
+	}
+	if config.Base.Cron == nil || config.Base.Cron.String() != "0 2 * * *" {
+		t.Fatalf("Invalid cron expression")
+	}
+	err = config.Validate()
+	if err != nil {
+		t.Fatalf("The configuration should be valid: %v", err)
+	}
+	config.Base.Interval = Duration(10 * time.Second)
+	err = config.Validate()
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestCronWithoutActivation(t *testing.T) {
+	// the expression is rejected by ParseCron, the wrapper should not
+	// schedule it anyway
+	cron := &Cron{
+		expression: "0 0 30 2 *",
+		location:   time.UTC,
+		minute:     1,
+		hour:       1,
+		dom:        1 << 30,
+		month:      1 << 2,
+		dow:        1<<7 - 1,
+	}
+	if next := cron.Next(time.Now()); !next.IsZero() {
+		t.Fatalf("Unexpected activation %s", next)
+	}
+	wrapper := NewWrapper(&testHealthcheck{
+		config: &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name: "foo",
+				Cron: cron,
+			},
+		},
+	})
+	if delay := wrapper.nextDelay(); delay != cronRetryDelay {
+		t.Fatalf("Invalid delay %s", delay)
+	}
+	if delay := wrapper.initialDelay(); delay != cronRetryDelay {
+		t.Fatalf("Invalid initial delay %s", delay)
+	}
+}
@@ -0,0 +1,60 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// degradedKey the context key of the degradation recorder
+type degradedKey struct{}
+
+// degradation records why a successful healthcheck execution is degraded,
+// for example because the target is slow
+type degradation struct {
+	lock    sync.Mutex
+	message string
+}
+
+// withDegradation returns a context recording the degradation of the
+// executions
+func withDegradation(ctx context.Context) (context.Context, *degradation) {
+	recorder := &degradation{}
+	return context.WithValue(ctx, degradedKey{}, recorder), recorder
+}
+
+// degradationFromContext returns the degradation recorder of the context,
+// or nil
+func degradationFromContext(ctx context.Context) *degradation {
+	recorder, _ := ctx.Value(degradedKey{}).(*degradation)
+	return recorder
+}
+
+// markDegraded records that the execution is degraded, if the context
+// records the degradation
+func markDegraded(ctx context.Context, format string, args ...interface{}) {
+	if recorder := degradationFromContext(ctx); recorder != nil {
+		recorder.lock.Lock()
+		defer recorder.lock.Unlock()
+		recorder.message = fmt.Sprintf(format, args...)
+	}
+}
+
+// reset removes the degradation, in order to only keep the degradation of
+// the last attempt
+func (d *degradation) reset() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.message = ""
+}
+
+// apply marks the result as degraded if the execution was successful but
+// degraded
+func (d *degradation) apply(result *Result) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.message != "" && result.Success {
+		result.Degraded = true
+		result.Message = d.message
+	}
+}
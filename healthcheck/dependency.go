@@ -0,0 +1,73 @@
+package healthcheck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dependencyIDs returns the identifiers of the dependencies of an
+// healthcheck, the dependencies being healthchecks of the same namespace
+func dependencyIDs(base Base) []string {
+	result := make([]string, 0, len(base.DependsOn))
+	for _, dependency := range base.DependsOn {
+		result = append(result, ID(base.Namespace, dependency))
+	}
+	return result
+}
+
+// dependencyCycle returns the identifiers of a depends-on cycle going
+// through the start identifier, or nil. The graph maps the healthchecks
+// identifiers to the identifiers of their dependencies.
+func dependencyCycle(graph map[string][]string, start string) []string {
+	visited := make(map[string]bool)
+	var walk func(id string, path []string) []string
+	walk = func(id string, path []string) []string {
+		for _, dependency := range graph[id] {
+			if dependency == start {
+				return append(path, dependency)
+			}
+			if visited[dependency] {
+				continue
+			}
+			visited[dependency] = true
+			if cycle := walk(dependency, append(path, dependency)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return walk(start, []string{start})
+}
+
+// checkDependencies returns an error if the healthchecks dependencies
+// form a cycle, the healthchecks in a cycle being skipped forever once
+// unhealthy. The healthchecks replace the registered healthchecks with
+// the same identifier, and the removed healthchecks are ignored. The lock
+// should be held.
+func (c *Component) checkDependencies(checks []Healthcheck, removed map[string]bool) error {
+	graph := make(map[string][]string)
+	for id, wrapper := range c.Healthchecks {
+		if !removed[id] {
+			graph[id] = dependencyIDs(wrapper.healthcheck.Base())
+		}
+	}
+	for _, check := range checks {
+		base := check.Base()
+		graph[base.ID()] = dependencyIDs(base)
+	}
+	for _, check := range checks {
+		base := check.Base()
+		if cycle := dependencyCycle(graph, base.ID()); cycle != nil {
+			return fmt.Errorf("The dependencies of the healthcheck %s form a cycle: %s", base.ID(), strings.Join(cycle, " -> "))
+		}
+	}
+	return nil
+}
+
+// CheckDependencies returns an error if the dependencies of the
+// healthchecks form a cycle with the registered healthchecks
+func (c *Component) CheckDependencies(checks []Healthcheck) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.checkDependencies(checks, nil)
+}
@@ -11,6 +11,8 @@ import (
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/expression"
 )
 
 // DNSHealthcheckConfiguration defines a DNS healthcheck configuration
@@ -19,6 +21,9 @@ type DNSHealthcheckConfiguration struct {
 	Timeout     Duration `json:"timeout"`
 	ExpectedIPs []IP     `json:"expected-ips,omitempty" yaml:"expected-ips,omitempty"`
 	Domain      string   `json:"domain"`
+	// Assertions the expressions evaluated on the resolution, which
+	// should all be true
+	Assertions []string `json:"assertions,omitempty" yaml:"assertions,omitempty"`
 }
 
 // DNSHealthcheck defines an HTTP healthcheck
@@ -27,7 +32,8 @@ type DNSHealthcheck struct {
 	Config *DNSHealthcheckConfiguration
 	URL    string
 
-	Tick *time.Ticker
+	Tick       *time.Ticker
+	assertions []*expression.Expression
 }
 
 // Validate validates the healthcheck configuration
@@ -41,7 +47,10 @@ func (config *DNSHealthcheckConfiguration) Validate() error {
 	if config.Timeout == 0 {
 		return errors.New("The healthcheck timeout is missing")
 	}
-	if !config.Base.OneOff {
+	if _, err := compileAssertions(config.Assertions, dnsAssertionVariables); err != nil {
+		return err
+	}
+	if !config.Base.OneOff && config.Base.Cron == nil {
 		if config.Base.Interval < Duration(2*time.Second) {
 			return errors.New("The healthcheck interval should be greater than 2 second")
 		}
@@ -49,11 +58,20 @@ func (config *DNSHealthcheckConfiguration) Validate() error {
 			return errors.New("The healthcheck interval should be greater than the timeout")
 		}
 	}
+	err := config.Base.ValidateBase()
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
 // Initialize the healthcheck.
 func (h *DNSHealthcheck) Initialize() error {
+	assertions, err := compileAssertions(h.Config.Assertions, dnsAssertionVariables)
+	if err != nil {
+		return err
+	}
+	h.assertions = assertions
 	return nil
 }
 
@@ -67,6 +85,11 @@ func (h *DNSHealthcheck) Base() Base {
 	return h.Config.Base
 }
 
+// Timeout returns the healthcheck timeout
+func (h *DNSHealthcheck) Timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
 // SetSource set the healthcheck source
 func (h *DNSHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
@@ -130,14 +153,12 @@ func verifyIPs(expectedIPs []IP, lookupIPs []net.IP) error {
 		for _, ip := range lookupIPs {
 			lookup = append(lookup, ip.String())
 		}
-		return fmt.Errorf("The IP addresses %s were not found. The DNS result was %s ", strings.Join(l, ", "), strings.Join(lookup, ", "))
+		return assertionError("The IP addresses %s were not found. The DNS result was %s ", strings.Join(l, ", "), strings.Join(lookup, ", "))
 	}
 	return nil
 }
 
-func (h *DNSHealthcheck) lookupIP() ([]net.IP, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(h.Config.Timeout))
-	defer cancel()
+func (h *DNSHealthcheck) lookupIP(ctx context.Context) ([]net.IP, error) {
 	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, h.Config.Domain)
 	if err != nil {
 		return nil, err
@@ -150,16 +171,28 @@ func (h *DNSHealthcheck) lookupIP() ([]net.IP, error) {
 }
 
 // Execute executes an healthcheck on the given domain
-func (h *DNSHealthcheck) Execute() error {
+func (h *DNSHealthcheck) Execute(ctx context.Context) error {
 	h.LogDebug("start executing healthcheck")
-	ips, err := h.lookupIP()
+	start := time.Now()
+	ips, err := h.lookupIP(ctx)
 	if err != nil {
-		return errors.Wrapf(err, "Fail to lookup IP for domain")
+		return withCategory(ErrorDNS, errors.Wrapf(err, "Fail to lookup IP for domain"))
 	}
+	duration := time.Since(start)
 	err = verifyIPs(h.Config.ExpectedIPs, ips)
 	if err != nil {
 		return err
 	}
+	if len(h.assertions) != 0 {
+		addresses := make([]string, len(ips))
+		for i, ip := range ips {
+			addresses[i] = ip.String()
+		}
+		return verifyAssertions(h.assertions, map[string]interface{}{
+			"ips":      addresses,
+			"duration": milliseconds(duration),
+		})
+	}
 	return nil
 }
 
@@ -191,6 +224,11 @@ func (h *DNSHealthcheckConfiguration) DeepCopyInto(out *DNSHealthcheckConfigurat
 			}
 		}
 	}
+	if h.Assertions != nil {
+		h, out := &h.Assertions, &out.Assertions
+		*out = make([]string, len(*h))
+		copy(*out, *h)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSHealthcheckConfiguration.
@@ -1,6 +1,7 @@
 package healthcheck
 
 import (
+	"context"
 	"net"
 	"testing"
 	"time"
@@ -20,7 +21,7 @@ func TestDNSExecuteSuccess(t *testing.T) {
 		},
 	}
 
-	err := h.Execute()
+	err := h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -35,7 +36,7 @@ func TestDNSExecuteFailure(t *testing.T) {
 		},
 	}
 
-	err := h.Execute()
+	err := h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("Was expecting an error: the domain does not exist")
 	}
@@ -61,7 +62,7 @@ func TestDNSStartStop(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
 	}
-	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{})
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
 	if err != nil {
 		t.Fatalf("Fail to create the component\n%v", err)
 	}
@@ -0,0 +1,204 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// EncodingText the payloads are sent as is
+	EncodingText = "text"
+	// EncodingHex the payloads are hex-encoded, the spaces being ignored
+	EncodingHex = "hex"
+
+	// FramingNone the payloads are not framed
+	FramingNone = "none"
+	// FramingUint8 the payloads are prefixed by their length on one byte
+	FramingUint8 = "uint8"
+	// FramingUint16BE the payloads are prefixed by their length on two
+	// bytes, big endian
+	FramingUint16BE = "uint16be"
+	// FramingUint16LE the payloads are prefixed by their length on two
+	// bytes, little endian
+	FramingUint16LE = "uint16le"
+	// FramingUint32BE the payloads are prefixed by their length on four
+	// bytes, big endian
+	FramingUint32BE = "uint32be"
+	// FramingUint32LE the payloads are prefixed by their length on four
+	// bytes, little endian
+	FramingUint32LE = "uint32le"
+)
+
+// maxFrameSize the maximum size of the frames read from the targets
+const maxFrameSize = 1024 * 1024
+
+// Exchange a payload sent to the target, and the beginning of the response
+// expected. The payload is not sent if empty, and the response is not read
+// if no response is expected.
+type Exchange struct {
+	Send   string `json:"send,omitempty" yaml:"send,omitempty"`
+	Expect string `json:"expect,omitempty" yaml:"expect,omitempty"`
+}
+
+// framePrefix returns the size and the byte order of the length prefix of
+// the framing, the size being 0 if the payloads are not framed
+func framePrefix(framing string) (int, binary.ByteOrder, error) {
+	switch framing {
+	case "", FramingNone:
+		return 0, nil, nil
+	case FramingUint8:
+		return 1, binary.BigEndian, nil
+	case FramingUint16BE:
+		return 2, binary.BigEndian, nil
+	case FramingUint16LE:
+		return 2, binary.LittleEndian, nil
+	case FramingUint32BE:
+		return 4, binary.BigEndian, nil
+	case FramingUint32LE:
+		return 4, binary.LittleEndian, nil
+	}
+	return 0, nil, fmt.Errorf("Invalid framing %s", framing)
+}
+
+// decodePayload decodes a payload of an exchange
+func decodePayload(payload string, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", EncodingText:
+		return []byte(payload), nil
+	case EncodingHex:
+		decoded, err := hex.DecodeString(strings.Join(strings.Fields(payload), ""))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid hex payload %s", payload)
+		}
+		return decoded, nil
+	}
+	return nil, fmt.Errorf("Invalid encoding %s", encoding)
+}
+
+// formatPayload formats a payload for the error messages
+func formatPayload(payload []byte, encoding string) string {
+	if encoding == EncodingHex {
+		return hex.EncodeToString(payload)
+	}
+	return fmt.Sprintf("%q", payload)
+}
+
+// validateExchanges validates the exchanges, their encoding and their
+// framing
+func validateExchanges(exchanges []Exchange, encoding string, framing string) error {
+	size, _, err := framePrefix(framing)
+	if err != nil {
+		return err
+	}
+	for _, exchange := range exchanges {
+		if exchange.Send == "" && exchange.Expect == "" {
+			return errors.New("The exchanges should send a payload or expect a response")
+		}
+		send, err := decodePayload(exchange.Send, encoding)
+		if err != nil {
+			return err
+		}
+		if _, err := decodePayload(exchange.Expect, encoding); err != nil {
+			return err
+		}
+		if size != 0 && uint64(len(send)) >= uint64(1)<<(8*size) {
+			return fmt.Errorf("The payload %s is too large for the framing %s", exchange.Send, framing)
+		}
+	}
+	return nil
+}
+
+// writeFrame writes the payload, prefixed by its length if framed
+func writeFrame(w io.Writer, payload []byte, size int, order binary.ByteOrder) error {
+	message := make([]byte, size, size+len(payload))
+	switch size {
+	case 1:
+		message[0] = byte(len(payload))
+	case 2:
+		order.PutUint16(message, uint16(len(payload)))
+	case 4:
+		order.PutUint32(message, uint32(len(payload)))
+	}
+	_, err := w.Write(append(message, payload...))
+	return err
+}
+
+// readFrame reads a frame prefixed by its length
+func readFrame(r io.Reader, size int, order binary.ByteOrder) ([]byte, error) {
+	prefix := make([]byte, size)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, err
+	}
+	var length uint64
+	switch size {
+	case 1:
+		length = uint64(prefix[0])
+	case 2:
+		length = uint64(order.Uint16(prefix))
+	case 4:
+		length = uint64(order.Uint32(prefix))
+	}
+	if length > maxFrameSize {
+		return nil, fmt.Errorf("frame of %d bytes larger than the limit of %d bytes", length, maxFrameSize)
+	}
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// exchange sends the payloads of the exchanges to the target, and verifies
+// the responses. Without framing, the number of bytes read is the size of
+// the expected response.
+func exchange(ctx context.Context, conn net.Conn, exchanges []Exchange, encoding string, framing string) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return errors.Wrapf(err, "Fail to set the connection deadline")
+		}
+	}
+	size, order, err := framePrefix(framing)
+	if err != nil {
+		return err
+	}
+	for i, e := range exchanges {
+		send, err := decodePayload(e.Send, encoding)
+		if err != nil {
+			return err
+		}
+		expect, err := decodePayload(e.Expect, encoding)
+		if err != nil {
+			return err
+		}
+		if len(send) != 0 {
+			if err := writeFrame(conn, send, size, order); err != nil {
+				return errors.Wrapf(err, "Fail to send the payload of the exchange %d", i+1)
+			}
+		}
+		if len(expect) == 0 {
+			continue
+		}
+		var response []byte
+		if size != 0 {
+			response, err = readFrame(conn, size, order)
+		} else {
+			response = make([]byte, len(expect))
+			_, err = io.ReadFull(conn, response)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Fail to read the response of the exchange %d", i+1)
+		}
+		if !bytes.HasPrefix(response, expect) {
+			return assertionError("Invalid response for the exchange %d: expected %s, got %s", i+1, formatPayload(expect, encoding), formatPayload(response, encoding))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package healthcheck
+
+import (
+	"sync"
+)
+
+// exclusionGroup the state of an exclusion group: the healthcheck
+// currently executed, and the healthchecks waiting for it
+type exclusionGroup struct {
+	running *Wrapper
+	waiting []*Wrapper
+}
+
+// exclusionGroups serializes the executions of the healthchecks sharing an
+// exclusion group. The healthchecks executed while their group is busy are
+// deferred until the end of the running execution.
+type exclusionGroups struct {
+	lock   sync.Mutex
+	groups map[string]*exclusionGroup
+}
+
+// newExclusionGroups creates the exclusion groups
+func newExclusionGroups() *exclusionGroups {
+	return &exclusionGroups{
+		groups: make(map[string]*exclusionGroup),
+	}
+}
+
+// acquire returns true if the wrapper can be executed. Otherwise, the
+// wrapper is added to the waiting list of the group.
+func (e *exclusionGroups) acquire(name string, w *Wrapper) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	group, ok := e.groups[name]
+	if !ok {
+		e.groups[name] = &exclusionGroup{running: w}
+		return true
+	}
+	if group.running == nil || group.running == w {
+		group.running = w
+		return true
+	}
+	for _, waiting := range group.waiting {
+		if waiting == w {
+			return false
+		}
+	}
+	group.waiting = append(group.waiting, w)
+	return false
+}
+
+// release releases the group held by the wrapper, and returns the
+// wrappers waiting for it. The group is removed once it is idle.
+func (e *exclusionGroups) release(name string, w *Wrapper) []*Wrapper {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	group, ok := e.groups[name]
+	if !ok || group.running != w {
+		return nil
+	}
+	waiting := group.waiting
+	delete(e.groups, name)
+	return waiting
+}
@@ -0,0 +1,54 @@
+package healthcheck
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ExpectedFailures is a recurring window during which the failures of an
+// healthcheck are expected, for example because the service is stopped
+// during a nightly backup. The window starts at each activation of the
+// cron expression and lasts for the duration. The failures during the
+// window are downgraded to the info severity.
+type ExpectedFailures struct {
+	Cron     *Cron    `json:"cron"`
+	Duration Duration `json:"duration"`
+	Reason   string   `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// Validate validates the expected failures window
+func (e *ExpectedFailures) Validate() error {
+	if e.Cron == nil {
+		return errors.New("The expected failures window cron is missing")
+	}
+	if e.Duration <= 0 {
+		return errors.New("The expected failures window duration should be positive")
+	}
+	return nil
+}
+
+// Active returns true if the window is active at the given time
+func (e *ExpectedFailures) Active(now time.Time) bool {
+	duration := time.Duration(e.Duration)
+	// the activations are at the minute precision
+	t := e.Cron.Next(now.Add(-duration - time.Minute))
+	for !t.IsZero() && !t.After(now) {
+		if now.Before(t.Add(duration)) {
+			return true
+		}
+		t = e.Cron.Next(t)
+	}
+	return false
+}
+
+// expectedFailures returns the expected failures window active at the
+// given time, or nil
+func (b *Base) expectedFailures(now time.Time) *ExpectedFailures {
+	for i := range b.ExpectedFailures {
+		if b.ExpectedFailures[i].Active(now) {
+			return &b.ExpectedFailures[i]
+		}
+	}
+	return nil
+}
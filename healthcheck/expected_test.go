@@ -0,0 +1,74 @@
+package healthcheck
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestExpectedFailuresActive(t *testing.T) {
+	cron, err := ParseCron("0 2 * * *")
+	if err != nil {
+		t.Fatalf("Fail to parse the cron expression: %v", err)
+	}
+	window := ExpectedFailures{
+		Cron:     cron,
+		Duration: Duration(30 * time.Minute),
+	}
+	cases := []struct {
+		now    time.Time
+		active bool
+	}{
+		{now: time.Date(2023, 6, 1, 1, 59, 59, 0, time.UTC), active: false},
+		{now: time.Date(2023, 6, 1, 2, 0, 0, 0, time.UTC), active: true},
+		{now: time.Date(2023, 6, 1, 2, 15, 0, 0, time.UTC), active: true},
+		{now: time.Date(2023, 6, 1, 2, 29, 59, 0, time.UTC), active: true},
+		{now: time.Date(2023, 6, 1, 2, 30, 0, 0, time.UTC), active: false},
+		{now: time.Date(2023, 6, 1, 14, 0, 0, 0, time.UTC), active: false},
+	}
+	for _, c := range cases {
+		if window.Active(c.now) != c.active {
+			t.Fatalf("Invalid window status at %s, expected %t", c.now, c.active)
+		}
+	}
+}
+
+func TestExpectedFailuresResult(t *testing.T) {
+	cron, err := ParseCron("* * * * *")
+	if err != nil {
+		t.Fatalf("Fail to parse the cron expression: %v", err)
+	}
+	config := TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(10 * time.Second),
+			ExpectedFailures: []ExpectedFailures{
+				{Cron: cron, Duration: Duration(2 * time.Minute), Reason: "backup"},
+			},
+		},
+		Target:  "127.0.0.1",
+		Port:    22,
+		Timeout: Duration(3 * time.Second),
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %s", err.Error())
+	}
+	healthcheck := NewTCPHealthcheck(zap.NewExample(), &config)
+	result := NewResult(healthcheck, 0, errors.New("connection refused"))
+	if !result.ExpectedFailure || result.Severity != SeverityInfo {
+		t.Fatalf("The failure should be expected: %+v", result)
+	}
+	if result.Message != "connection refused (expected failure: backup)" {
+		t.Fatalf("Invalid message %s", result.Message)
+	}
+	result = NewResult(healthcheck, 0, nil)
+	if result.ExpectedFailure || result.Severity != SeverityCritical {
+		t.Fatalf("The success should not be an expected failure: %+v", result)
+	}
+	config.Base.ExpectedFailures[0].Duration = 0
+	if err := config.Validate(); err == nil {
+		t.Fatalf("Was expecting an error for the window duration")
+	}
+}
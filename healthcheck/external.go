@@ -0,0 +1,104 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ExternalSource the source of the results pushed by third-party scripts
+const ExternalSource = "external"
+
+// ExternalResult the result of a check executed by a third-party script,
+// pushed to the external results API
+type ExternalResult struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Success   bool   `json:"success"`
+	Message   string `json:"message,omitempty"`
+	// Duration the duration of the check in milliseconds
+	Duration int64             `json:"duration,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Severity string            `json:"severity,omitempty"`
+	// Timestamp the unix timestamp of the check, the reception time by
+	// default
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// ID returns the identifier of the external result
+func (e *ExternalResult) ID() string {
+	return ID(e.Namespace, e.Name)
+}
+
+// Validate validates the external result
+func (e *ExternalResult) Validate() error {
+	if e.Name == "" {
+		return errors.New("The result name is missing")
+	}
+	if err := ValidateName(e.Name); err != nil {
+		return err
+	}
+	if !namespaceRegexp.MatchString(e.Namespace) {
+		return fmt.Errorf("Invalid result namespace %s", e.Namespace)
+	}
+	if e.Duration < 0 {
+		return errors.New("The result duration should be positive")
+	}
+	if e.Timestamp < 0 {
+		return errors.New("The result timestamp should be positive")
+	}
+	if e.Severity != "" && e.Severity != SeverityCritical && e.Severity != SeverityWarning && e.Severity != SeverityInfo {
+		return fmt.Errorf("Invalid result severity %s", e.Severity)
+	}
+	return nil
+}
+
+// Result converts the external result to an healthcheck result
+func (e *ExternalResult) Result(now time.Time) *Result {
+	result := &Result{
+		Name:                 e.Name,
+		Namespace:            e.Namespace,
+		Summary:              fmt.Sprintf("external check %s", e.Name),
+		Labels:               e.Labels,
+		Success:              e.Success,
+		HealthcheckTimestamp: now.Unix(),
+		Message:              e.Message,
+		Duration:             e.Duration,
+		Source:               ExternalSource,
+		Severity:             e.Severity,
+	}
+	if e.Timestamp != 0 {
+		result.HealthcheckTimestamp = e.Timestamp
+	}
+	if result.Severity == "" {
+		result.Severity = SeverityCritical
+	}
+	if result.Message == "" {
+		result.Message = "success"
+		if !e.Success {
+			result.Message = "failure"
+		}
+	}
+	return result
+}
+
+// AddExternalResult sends the result of a check executed by a third-party
+// script to the exporters, like the results of the healthchecks. The
+// results can not replace the results of the healthchecks of this
+// component.
+func (c *Component) AddExternalResult(ctx context.Context, external *ExternalResult) error {
+	if err := external.Validate(); err != nil {
+		return err
+	}
+	if c.GetCheck(external.ID()) != nil {
+		return fmt.Errorf("The result %s conflicts with an existing healthcheck", external.ID())
+	}
+	select {
+	case c.ChanResult <- external.Result(time.Now()):
+		return nil
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "Fail to add the result %s", external.ID())
+	}
+}
@@ -0,0 +1,34 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExternalResult(t *testing.T) {
+	invalid := []ExternalResult{
+		{},
+		{Name: "raid", Namespace: "a/b"},
+		{Name: "raid", Duration: -1},
+		{Name: "raid", Severity: "urgent"},
+	}
+	for i, external := range invalid {
+		if err := external.Validate(); err == nil {
+			t.Fatalf("Was expecting an error for the case %d", i)
+		}
+	}
+	now := time.Now()
+	external := ExternalResult{Name: "raid", Namespace: "storage", Labels: map[string]string{"host": "db-1"}}
+	if err := external.Validate(); err != nil {
+		t.Fatalf("Invalid external result: %s", err.Error())
+	}
+	result := external.Result(now)
+	if result.ID() != "storage/raid" || result.Success || result.Message != "failure" || result.Source != ExternalSource || result.Severity != SeverityCritical || result.HealthcheckTimestamp != now.Unix() || result.Labels["host"] != "db-1" {
+		t.Fatalf("Invalid result %+v", result)
+	}
+	external = ExternalResult{Name: "raid", Success: true, Message: "ok", Severity: SeverityInfo, Timestamp: 1000}
+	result = external.Result(now)
+	if !result.Success || result.Message != "ok" || result.Severity != SeverityInfo || result.HealthcheckTimestamp != 1000 {
+		t.Fatalf("Invalid result %+v", result)
+	}
+}
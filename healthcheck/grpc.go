@@ -0,0 +1,317 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/appclacks/cabourotte/tls"
+)
+
+// GRPCHealthcheckConfiguration defines a gRPC healthcheck configuration.
+// The healthcheck uses the gRPC health protocol, or invokes a unary method
+// whose descriptor is retrieved using the server reflection.
+type GRPCHealthcheckConfiguration struct {
+	Base `json:",inline" yaml:",inline"`
+	// can be an IP or a domain
+	Target  string   `json:"target"`
+	Port    uint     `json:"port"`
+	Timeout Duration `json:"timeout"`
+	// TLS connects to the target using TLS
+	TLS        bool   `json:"tls"`
+	Key        string `json:"key,omitempty"`
+	Cert       string `json:"cert,omitempty"`
+	Cacert     string `json:"cacert,omitempty"`
+	ServerName string `json:"server-name,omitempty" yaml:"server-name"`
+	Insecure   bool   `json:"insecure,omitempty" yaml:"insecure,omitempty"`
+	// Metadata the metadata sent with the requests
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	// Service the service checked using the health protocol, the whole
+	// server by default
+	Service string `json:"service,omitempty" yaml:"service,omitempty"`
+	// Method the unary method invoked instead of the health protocol
+	// (package.Service/Method)
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+	// Request the JSON encoded request of the method, {} by default
+	Request string `json:"request,omitempty" yaml:"request,omitempty"`
+	// ValidCodes the names of the status codes of the method considered
+	// successful (OK, NOT_FOUND...), OK by default
+	ValidCodes []string `json:"valid-codes,omitempty" yaml:"valid-codes,omitempty"`
+	// ResponseFields the expected values of fields of the JSON encoded
+	// response, by path (items.0.name for example)
+	ResponseFields map[string]string `json:"response-fields,omitempty" yaml:"response-fields,omitempty"`
+}
+
+// parseCode parses the name of a gRPC status code
+func parseCode(name string) (codes.Code, error) {
+	var code codes.Code
+	if err := code.UnmarshalJSON([]byte(strconv.Quote(name))); err != nil {
+		return code, fmt.Errorf("Invalid gRPC status code %s", name)
+	}
+	return code, nil
+}
+
+// splitMethod splits a method into its service and its name
+func splitMethod(method string) (string, string, error) {
+	parts := strings.Split(strings.TrimPrefix(method, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("Invalid gRPC method %s, it should be package.Service/Method", method)
+	}
+	return parts[0], parts[1], nil
+}
+
+// Validate validates the healthcheck configuration
+func (config *GRPCHealthcheckConfiguration) Validate() error {
+	if config.Base.Name == "" {
+		return errors.New("The healthcheck name is missing")
+	}
+	if config.Target == "" {
+		return errors.New("The healthcheck target is missing")
+	}
+	if config.Port == 0 {
+		return errors.New("The healthcheck port is missing")
+	}
+	if config.Timeout == 0 {
+		return errors.New("The healthcheck timeout is missing")
+	}
+	if !((config.Key != "" && config.Cert != "") ||
+		(config.Key == "" && config.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	if !config.TLS && (config.Key != "" || config.Cacert != "" || config.ServerName != "" || config.Insecure) {
+		return errors.New("The healthcheck TLS options require tls")
+	}
+	if config.Method == "" {
+		if config.Request != "" || len(config.ValidCodes) != 0 || len(config.ResponseFields) != 0 {
+			return errors.New("The healthcheck request, valid-codes and response-fields require a method")
+		}
+	} else {
+		if config.Service != "" {
+			return errors.New("The healthcheck service and method are mutually exclusive")
+		}
+		if _, _, err := splitMethod(config.Method); err != nil {
+			return err
+		}
+		if config.Request != "" && !json.Valid([]byte(config.Request)) {
+			return errors.New("The healthcheck request should be valid JSON")
+		}
+		for _, name := range config.ValidCodes {
+			if _, err := parseCode(name); err != nil {
+				return err
+			}
+		}
+	}
+	if !config.Base.OneOff && config.Base.Cron == nil {
+		if config.Base.Interval < Duration(2*time.Second) {
+			return errors.New("The healthcheck interval should be greater than 2 second")
+		}
+		if config.Base.Interval < config.Timeout {
+			return errors.New("The healthcheck interval should be greater than the timeout")
+		}
+	}
+	err := config.Base.ValidateBase()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GRPCHealthcheck defines a gRPC healthcheck
+type GRPCHealthcheck struct {
+	Logger *zap.Logger
+	Config *GRPCHealthcheckConfiguration
+	URL    string
+
+	Tick        *time.Ticker
+	credentials credentials.TransportCredentials
+	validCodes  map[codes.Code]bool
+}
+
+// buildURL build the target URL for the gRPC healthcheck, depending of
+// its configuration
+func (h *GRPCHealthcheck) buildURL() {
+	h.URL = net.JoinHostPort(h.Config.Target, fmt.Sprintf("%d", h.Config.Port))
+}
+
+// Summary returns an healthcheck summary
+func (h *GRPCHealthcheck) Summary() string {
+	summary := ""
+	if h.Config.Base.Description != "" {
+		summary = fmt.Sprintf("gRPC healthcheck %s on %s:%d", h.Config.Base.Description, h.Config.Target, h.Config.Port)
+	} else {
+		summary = fmt.Sprintf("gRPC healthcheck on %s:%d", h.Config.Target, h.Config.Port)
+	}
+	if h.Config.Method != "" {
+		summary = summary + fmt.Sprintf(" invoking %s", h.Config.Method)
+	}
+	return summary
+}
+
+// Initialize the healthcheck.
+func (h *GRPCHealthcheck) Initialize() error {
+	h.buildURL()
+	h.credentials = insecure.NewCredentials()
+	if h.Config.TLS {
+		tlsConfig, err := tls.GetTLSConfig(h.Config.Key, h.Config.Cert, h.Config.Cacert, h.Config.ServerName, h.Config.Insecure)
+		if err != nil {
+			return err
+		}
+		h.credentials = credentials.NewTLS(tlsConfig)
+	}
+	h.validCodes = map[codes.Code]bool{codes.OK: true}
+	if len(h.Config.ValidCodes) != 0 {
+		h.validCodes = make(map[codes.Code]bool)
+		for _, name := range h.Config.ValidCodes {
+			code, err := parseCode(name)
+			if err != nil {
+				return err
+			}
+			h.validCodes[code] = true
+		}
+	}
+	return nil
+}
+
+// GetConfig get the config
+func (h *GRPCHealthcheck) GetConfig() interface{} {
+	return h.Config
+}
+
+// Base get the base configuration
+func (h *GRPCHealthcheck) Base() Base {
+	return h.Config.Base
+}
+
+// Timeout returns the healthcheck timeout
+func (h *GRPCHealthcheck) Timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
+// SetSource set the healthcheck source
+func (h *GRPCHealthcheck) SetSource(source string) {
+	h.Config.Base.Source = source
+}
+
+// LogError logs an error with context
+func (h *GRPCHealthcheck) LogError(err error, message string) {
+	h.Logger.Error(err.Error(),
+		zap.String("extra", message),
+		zap.String("target", h.Config.Target),
+		zap.Uint("port", h.Config.Port),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogDebug logs a message with context
+func (h *GRPCHealthcheck) LogDebug(message string) {
+	h.Logger.Debug(message,
+		zap.String("target", h.Config.Target),
+		zap.Uint("port", h.Config.Port),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogInfo logs a message with context
+func (h *GRPCHealthcheck) LogInfo(message string) {
+	h.Logger.Info(message,
+		zap.String("target", h.Config.Target),
+		zap.Uint("port", h.Config.Port),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// Execute executes an healthcheck on the given target
+func (h *GRPCHealthcheck) Execute(ctx context.Context) error {
+	h.LogDebug("start executing healthcheck")
+	dialer := net.Dialer{}
+	applyPolicy(ctx, &dialer)
+	connectStart := time.Now()
+	conn, err := grpc.DialContext(ctx, h.URL,
+		grpc.WithTransportCredentials(h.credentials),
+		grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", address)
+		}),
+		grpc.WithBlock(),
+		grpc.WithReturnConnectionError(),
+		grpc.FailOnNonTempDialError(true))
+	if err != nil {
+		return errors.Wrapf(err, "gRPC connection failed on %s", h.URL)
+	}
+	defer conn.Close()
+	recordPhase(ctx, PhaseConnect, connectStart, time.Now())
+	for k, v := range h.Config.Metadata {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+	}
+	if h.Config.Method != "" {
+		return h.invoke(ctx, conn)
+	}
+	response, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{
+		Service: h.Config.Service,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "gRPC health check failed on %s", h.URL)
+	}
+	if response.Status != healthpb.HealthCheckResponse_SERVING {
+		return assertionError("The gRPC health check returned the status %s on %s", response.Status.String(), h.URL)
+	}
+	return nil
+}
+
+// NewGRPCHealthcheck creates a gRPC healthcheck from a logger and a
+// configuration
+func NewGRPCHealthcheck(logger *zap.Logger, config *GRPCHealthcheckConfiguration) *GRPCHealthcheck {
+	return &GRPCHealthcheck{
+		Logger: logger,
+		Config: config,
+	}
+}
+
+// MarshalJSON marshal to json a gRPC healthcheck
+func (h *GRPCHealthcheck) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Config)
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPCHealthcheckConfiguration) DeepCopyInto(out *GRPCHealthcheckConfiguration) {
+	*out = *in
+	in.Base.DeepCopyInto(&out.Base)
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ValidCodes != nil {
+		in, out := &in.ValidCodes, &out.ValidCodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResponseFields != nil {
+		in, out := &in.ResponseFields, &out.ResponseFields
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCHealthcheckConfiguration.
+func (in *GRPCHealthcheckConfiguration) DeepCopy() *GRPCHealthcheckConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPCHealthcheckConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
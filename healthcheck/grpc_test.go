@@ -0,0 +1,165 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// grpcServer starts a gRPC server exposing the health service and the
+// server reflection
+func grpcServer(t *testing.T) (*health.Server, uint) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen: %s", err.Error())
+	}
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		t.Fatalf("Invalid port: %s", err.Error())
+	}
+	return healthServer, uint(p)
+}
+
+func TestGRPCExecute(t *testing.T) {
+	healthServer, port := grpcServer(t)
+	healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_NOT_SERVING)
+	cases := []struct {
+		config  GRPCHealthcheckConfiguration
+		success bool
+	}{
+		{config: GRPCHealthcheckConfiguration{}, success: true},
+		{config: GRPCHealthcheckConfiguration{Service: "orders"}, success: false},
+		{config: GRPCHealthcheckConfiguration{Service: "unknown"}, success: false},
+		{
+			config: GRPCHealthcheckConfiguration{
+				Method:         "grpc.health.v1.Health/Check",
+				ResponseFields: map[string]string{"status": "SERVING"},
+			},
+			success: true,
+		},
+		{
+			config: GRPCHealthcheckConfiguration{
+				Method:         "grpc.health.v1.Health/Check",
+				Request:        `{"service": "orders"}`,
+				ResponseFields: map[string]string{"status": "SERVING"},
+			},
+			success: false,
+		},
+		{
+			config: GRPCHealthcheckConfiguration{
+				Method:         "grpc.health.v1.Health/Check",
+				Request:        `{"service": "orders"}`,
+				ResponseFields: map[string]string{"missing.field": "1"},
+			},
+			success: false,
+		},
+		{
+			config: GRPCHealthcheckConfiguration{
+				Method:  "grpc.health.v1.Health/Check",
+				Request: `{"service": "unknown"}`,
+			},
+			success: false,
+		},
+		{
+			config: GRPCHealthcheckConfiguration{
+				Method:     "grpc.health.v1.Health/Check",
+				Request:    `{"service": "unknown"}`,
+				ValidCodes: []string{"NOT_FOUND"},
+			},
+			success: true,
+		},
+		{
+			config:  GRPCHealthcheckConfiguration{Method: "grpc.health.v1.Health/Watch"},
+			success: false,
+		},
+		{
+			config:  GRPCHealthcheckConfiguration{Method: "grpc.health.v1.Health/Missing"},
+			success: false,
+		},
+	}
+	for i, c := range cases {
+		config := c.config
+		config.Base = Base{Name: "foo", OneOff: true}
+		config.Target = "127.0.0.1"
+		config.Port = port
+		config.Timeout = Duration(2 * time.Second)
+		if err := config.Validate(); err != nil {
+			t.Fatalf("Invalid configuration for the case %d: %s", i, err.Error())
+		}
+		h := NewGRPCHealthcheck(zap.NewExample(), &config)
+		if err := h.Initialize(); err != nil {
+			t.Fatalf("Fail to initialize the healthcheck for the case %d: %s", i, err.Error())
+		}
+		err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for the case %d: %s", i, err.Error())
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for the case %d", i)
+		}
+	}
+}
+
+func TestGRPCValidate(t *testing.T) {
+	cases := []GRPCHealthcheckConfiguration{
+		{Port: 9090},
+		{Target: "127.0.0.1"},
+		{Target: "127.0.0.1", Port: 9090, Insecure: true},
+		{Target: "127.0.0.1", Port: 9090, Request: "{}"},
+		{Target: "127.0.0.1", Port: 9090, Method: "Check"},
+		{Target: "127.0.0.1", Port: 9090, Method: "grpc.health.v1.Health/Check", Service: "orders"},
+		{Target: "127.0.0.1", Port: 9090, Method: "grpc.health.v1.Health/Check", Request: "{"},
+		{Target: "127.0.0.1", Port: 9090, Method: "grpc.health.v1.Health/Check", ValidCodes: []string{"BROKEN"}},
+	}
+	for i, config := range cases {
+		config.Base = Base{Name: "foo", OneOff: true}
+		config.Timeout = Duration(2 * time.Second)
+		if err := config.Validate(); err == nil {
+			t.Fatalf("Was expecting an error for the case %d", i)
+		}
+	}
+}
+
+func TestResponseField(t *testing.T) {
+	response := map[string]interface{}{
+		"status": "SERVING",
+		"count":  float64(2),
+		"items":  []interface{}{map[string]interface{}{"name": "a", "ready": true}},
+	}
+	cases := []struct {
+		path     string
+		expected string
+		found    bool
+	}{
+		{path: "status", expected: "SERVING", found: true},
+		{path: "count", expected: "2", found: true},
+		{path: "items.0.name", expected: "a", found: true},
+		{path: "items.0.ready", expected: "true", found: true},
+		{path: "items.1.name", found: false},
+		{path: "status.name", found: false},
+		{path: "missing", found: false},
+	}
+	for _, c := range cases {
+		value, found := responseField(response, c.path)
+		if found != c.found || value != c.expected {
+			t.Fatalf("Invalid value %s (found %t) for %s", value, found, c.path)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// reflectionClient retrieves the descriptors of the services using the
+// server reflection
+type reflectionClient struct {
+	stream rpb.ServerReflection_ServerReflectionInfoClient
+	files  map[string]*descriptorpb.FileDescriptorProto
+}
+
+// request sends a reflection request, and stores the files descriptors of
+// the response
+func (r *reflectionClient) request(request *rpb.ServerReflectionRequest) error {
+	if err := r.stream.Send(request); err != nil {
+		return err
+	}
+	response, err := r.stream.Recv()
+	if err != nil {
+		return err
+	}
+	if e := response.GetErrorResponse(); e != nil {
+		return fmt.Errorf("%s", e.ErrorMessage)
+	}
+	descriptors := response.GetFileDescriptorResponse()
+	if descriptors == nil {
+		return errors.New("the server reflection returned no file descriptor")
+	}
+	for _, content := range descriptors.FileDescriptorProto {
+		file := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(content, file); err != nil {
+			return errors.Wrap(err, "Invalid file descriptor")
+		}
+		r.files[file.GetName()] = file
+	}
+	return nil
+}
+
+// dependencies retrieves the missing dependencies of the files, the well
+// known types being taken from the local registry
+func (r *reflectionClient) dependencies() error {
+	for {
+		missing := ""
+		for _, file := range r.files {
+			for _, dependency := range file.Dependency {
+				if _, ok := r.files[dependency]; !ok {
+					missing = dependency
+				}
+			}
+		}
+		if missing == "" {
+			return nil
+		}
+		if local, err := protoregistry.GlobalFiles.FindFileByPath(missing); err == nil {
+			r.files[missing] = protodesc.ToFileDescriptorProto(local)
+			continue
+		}
+		err := r.request(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: missing},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Fail to retrieve the file %s", missing)
+		}
+		if _, ok := r.files[missing]; !ok {
+			return fmt.Errorf("The server reflection did not return the file %s", missing)
+		}
+	}
+}
+
+// resolveMethod retrieves the descriptor of a unary method using the
+// server reflection
+func resolveMethod(ctx context.Context, conn *grpc.ClientConn, method string) (protoreflect.MethodDescriptor, error) {
+	serviceName, methodName, err := splitMethod(method)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to call the server reflection")
+	}
+	client := &reflectionClient{
+		stream: stream,
+		files:  make(map[string]*descriptorpb.FileDescriptorProto),
+	}
+	err = client.request(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: serviceName},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to retrieve the service %s", serviceName)
+	}
+	if err := client.dependencies(); err != nil {
+		return nil, err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, file := range client.files {
+		set.File = append(set.File, file)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid file descriptors")
+	}
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to find the service %s", serviceName)
+	}
+	service, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", serviceName)
+	}
+	result := service.Methods().ByName(protoreflect.Name(methodName))
+	if result == nil {
+		return nil, fmt.Errorf("The service %s has no method %s", serviceName, methodName)
+	}
+	if result.IsStreamingClient() || result.IsStreamingServer() {
+		return nil, fmt.Errorf("The method %s is not unary", method)
+	}
+	return result, nil
+}
+
+// responseField returns the value of a field of a JSON decoded response,
+// by path. The strings are returned as is, the other values JSON encoded.
+func responseField(response interface{}, path string) (string, bool) {
+	value := response
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			field, ok := v[key]
+			if !ok {
+				return "", false
+			}
+			value = field
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return "", false
+			}
+			value = v[index]
+		default:
+			return "", false
+		}
+	}
+	if s, ok := value.(string); ok {
+		return s, true
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	return string(encoded), true
+}
+
+// verifyResponseFields verifies the expected fields of a response, the
+// fields being named as in the proto files
+func verifyResponseFields(response proto.Message, expected map[string]string) error {
+	content, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(response)
+	if err != nil {
+		return errors.Wrap(err, "Fail to encode the response")
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(content, &decoded); err != nil {
+		return errors.Wrap(err, "Fail to decode the response")
+	}
+	for path, value := range expected {
+		actual, ok := responseField(decoded, path)
+		if !ok {
+			return assertionError("The response field %s is missing", path)
+		}
+		if actual != value {
+			return assertionError("The response field %s is %s instead of %s", path, actual, value)
+		}
+	}
+	return nil
+}
+
+// invoke invokes the method of the healthcheck, and verifies its status
+// code and its response
+func (h *GRPCHealthcheck) invoke(ctx context.Context, conn *grpc.ClientConn) error {
+	method, err := resolveMethod(ctx, conn, h.Config.Method)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to resolve the gRPC method %s on %s", h.Config.Method, h.URL)
+	}
+	request := dynamicpb.NewMessage(method.Input())
+	if h.Config.Request != "" {
+		if err := protojson.Unmarshal([]byte(h.Config.Request), request); err != nil {
+			return errors.Wrapf(err, "Invalid request for the gRPC method %s", h.Config.Method)
+		}
+	}
+	response := dynamicpb.NewMessage(method.Output())
+	fullName := fmt.Sprintf("/%s/%s", method.Parent().FullName(), method.Name())
+	err = conn.Invoke(ctx, fullName, request, response)
+	code := status.Code(err)
+	if !h.validCodes[code] {
+		if err == nil {
+			return assertionError("The gRPC method %s returned the status %s on %s", h.Config.Method, code.String(), h.URL)
+		}
+		err = errors.Wrapf(err, "The gRPC method %s returned the status %s on %s", h.Config.Method, code.String(), h.URL)
+		// the unavailable and deadline exceeded statuses are classified
+		// as the transport errors they wrap
+		if code == codes.Unavailable || code == codes.DeadlineExceeded {
+			return err
+		}
+		return withCategory(ErrorAssertion, err)
+	}
+	if err != nil || len(h.Config.ResponseFields) == 0 {
+		return nil
+	}
+	return verifyResponseFields(response, h.Config.ResponseFields)
+}
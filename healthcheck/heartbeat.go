@@ -0,0 +1,172 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// HeartbeatHealthcheckConfiguration defines a heartbeat healthcheck
+// configuration. The healthcheck is passive: it fails if no heartbeat was
+// received during the interval and the grace period.
+type HeartbeatHealthcheckConfiguration struct {
+	Base `json:",inline" yaml:",inline"`
+	// Grace the delay added to the interval before considering the
+	// heartbeat as missed
+	Grace Duration `json:"grace,omitempty" yaml:"grace,omitempty"`
+}
+
+// heartbeat the time of the last heartbeat of a healthcheck
+type heartbeat struct {
+	lock sync.RWMutex
+	last time.Time
+}
+
+// HeartbeatHealthcheck defines a heartbeat (dead man's switch)
+// healthcheck, the heartbeats being sent by external systems, for example
+// a cron job, to /heartbeat/:name
+type HeartbeatHealthcheck struct {
+	Logger *zap.Logger
+	Config *HeartbeatHealthcheckConfiguration
+
+	heartbeat *heartbeat
+}
+
+// Validate validates the healthcheck configuration
+func (config *HeartbeatHealthcheckConfiguration) Validate() error {
+	if config.Base.Name == "" {
+		return errors.New("The healthcheck name is missing")
+	}
+	if config.Base.OneOff || config.Base.Cron != nil {
+		return errors.New("The heartbeat healthchecks cannot be one-off or use cron")
+	}
+	if config.Base.Interval < Duration(time.Second) {
+		return errors.New("The healthcheck interval should be greater than 1 second")
+	}
+	if config.Grace < 0 {
+		return errors.New("The healthcheck grace should be positive")
+	}
+	err := config.Base.ValidateBase()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Initialize the healthcheck. The first heartbeat is expected one
+// interval after the registration of the healthcheck.
+func (h *HeartbeatHealthcheck) Initialize() error {
+	if h.heartbeat == nil {
+		h.heartbeat = &heartbeat{last: time.Now()}
+	}
+	return nil
+}
+
+// GetConfig get the config
+func (h *HeartbeatHealthcheck) GetConfig() interface{} {
+	return h.Config
+}
+
+// Base get the base configuration
+func (h *HeartbeatHealthcheck) Base() Base {
+	return h.Config.Base
+}
+
+// Timeout returns the healthcheck timeout
+func (h *HeartbeatHealthcheck) Timeout() time.Duration {
+	return time.Second
+}
+
+// SetSource set the healthcheck source
+func (h *HeartbeatHealthcheck) SetSource(source string) {
+	h.Config.Base.Source = source
+}
+
+// Summary returns an healthcheck summary
+func (h *HeartbeatHealthcheck) Summary() string {
+	if h.Config.Base.Description != "" {
+		return fmt.Sprintf("%s, heartbeat every %s", h.Config.Base.Description, time.Duration(h.Config.Base.Interval))
+	}
+	return fmt.Sprintf("heartbeat every %s", time.Duration(h.Config.Base.Interval))
+}
+
+// LogError logs an error with context
+func (h *HeartbeatHealthcheck) LogError(err error, message string) {
+	h.Logger.Error(err.Error(),
+		zap.String("extra", message),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogDebug logs a message with context
+func (h *HeartbeatHealthcheck) LogDebug(message string) {
+	h.Logger.Debug(message,
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogInfo logs a message with context
+func (h *HeartbeatHealthcheck) LogInfo(message string) {
+	h.Logger.Info(message,
+		zap.String("name", h.Config.Base.Name))
+}
+
+// Ping records a heartbeat
+func (h *HeartbeatHealthcheck) Ping(now time.Time) {
+	h.heartbeat.lock.Lock()
+	defer h.heartbeat.lock.Unlock()
+	h.heartbeat.last = now
+}
+
+// LastHeartbeat returns the time of the last heartbeat, or of the
+// registration of the healthcheck if no heartbeat was received
+func (h *HeartbeatHealthcheck) LastHeartbeat() time.Time {
+	h.heartbeat.lock.RLock()
+	defer h.heartbeat.lock.RUnlock()
+	return h.heartbeat.last
+}
+
+// Execute verifies that a heartbeat was received during the interval and
+// the grace period
+func (h *HeartbeatHealthcheck) Execute(ctx context.Context) error {
+	h.LogDebug("start executing healthcheck")
+	last := h.LastHeartbeat()
+	deadline := time.Duration(h.Config.Base.Interval + h.Config.Grace)
+	if elapsed := time.Since(last); elapsed > deadline {
+		return withCategory(ErrorHeartbeat, fmt.Errorf("No heartbeat received since %s (%s ago)", last.Format(time.RFC3339), elapsed.Truncate(time.Second)))
+	}
+	return nil
+}
+
+// NewHeartbeatHealthcheck creates a heartbeat healthcheck from a logger
+// and a configuration
+func NewHeartbeatHealthcheck(logger *zap.Logger, config *HeartbeatHealthcheckConfiguration) *HeartbeatHealthcheck {
+	return &HeartbeatHealthcheck{
+		Logger: logger,
+		Config: config,
+	}
+}
+
+// MarshalJSON marshal to json a heartbeat healthcheck
+func (h *HeartbeatHealthcheck) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Config)
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HeartbeatHealthcheckConfiguration) DeepCopyInto(out *HeartbeatHealthcheckConfiguration) {
+	*out = *in
+	in.Base.DeepCopyInto(&out.Base)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeartbeatHealthcheckConfiguration.
+func (in *HeartbeatHealthcheckConfiguration) DeepCopy() *HeartbeatHealthcheckConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(HeartbeatHealthcheckConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
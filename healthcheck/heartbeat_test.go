@@ -0,0 +1,70 @@
+package healthcheck
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestHeartbeatExecute(t *testing.T) {
+	h := NewHeartbeatHealthcheck(zap.NewExample(), &HeartbeatHealthcheckConfiguration{
+		Base: Base{
+			Name:     "backup",
+			Interval: Duration(time.Minute),
+		},
+		Grace: Duration(10 * time.Second),
+	})
+	err := h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	cases := []struct {
+		last time.Duration
+		err  bool
+	}{
+		{last: 0},
+		{last: 65 * time.Second},
+		{last: 75 * time.Second, err: true},
+		{last: time.Hour, err: true},
+	}
+	for _, c := range cases {
+		h.Ping(time.Now().Add(-c.last))
+		err := h.Execute(context.Background())
+		if c.err {
+			if err == nil {
+				t.Fatalf("Was expecting an error for a heartbeat %s ago", c.last)
+			}
+			if ErrorCategory(err) != ErrorHeartbeat {
+				t.Fatalf("Invalid error category %s", ErrorCategory(err))
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Heartbeat healthcheck error for a heartbeat %s ago:\n%v", c.last, err)
+		}
+	}
+}
+
+func TestHeartbeatValidate(t *testing.T) {
+	cases := []struct {
+		config HeartbeatHealthcheckConfiguration
+		err    bool
+	}{
+		{config: HeartbeatHealthcheckConfiguration{Base: Base{Name: "foo", Interval: Duration(time.Minute)}}},
+		{config: HeartbeatHealthcheckConfiguration{Base: Base{Interval: Duration(time.Minute)}}, err: true},
+		{config: HeartbeatHealthcheckConfiguration{Base: Base{Name: "foo"}}, err: true},
+		{config: HeartbeatHealthcheckConfiguration{Base: Base{Name: "foo", Interval: Duration(time.Minute), OneOff: true}}, err: true},
+		{config: HeartbeatHealthcheckConfiguration{Base: Base{Name: "foo", Interval: Duration(time.Minute)}, Grace: Duration(-time.Second)}, err: true},
+	}
+	for i, c := range cases {
+		err := c.config.Validate()
+		if c.err && err == nil {
+			t.Fatalf("Was expecting an error for the case %d", i)
+		}
+		if !c.err && err != nil {
+			t.Fatalf("Validation error for the case %d:\n%v", i, err)
+		}
+	}
+}
@@ -9,41 +9,93 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"regexp"
 	"time"
 
+	"github.com/appclacks/cabourotte/expression"
 	"github.com/appclacks/cabourotte/tls"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
-
-	"gopkg.in/tomb.v2"
 )
 
 // HTTPHealthcheckConfiguration defines an HTTP healthcheck configuration
 type HTTPHealthcheckConfiguration struct {
 	Base        `json:",inline" yaml:",inline"`
-	ValidStatus []uint `json:"valid-status" yaml:"valid-status"`
+	ValidStatus StatusCodes `json:"valid-status" yaml:"valid-status"`
 	// can be an IP or a domain
-	Target     string            `json:"target"`
-	Host       string            `json:"host,omitempty"`
-	Method     string            `json:"method"`
-	Port       uint              `json:"port"`
-	Redirect   bool              `json:"redirect"`
-	Body       string            `json:"body,omitempty"`
-	Query      map[string]string `json:"query,omitempty"`
-	Headers    map[string]string `json:"headers,omitempty"`
-	Protocol   Protocol          `json:"protocol"`
-	Path       string            `json:"path,omitempty"`
-	SourceIP   IP                `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
-	BodyRegexp []Regexp          `json:"body-regexp,omitempty" yaml:"body-regexp,omitempty"`
-	Insecure   bool              `json:"insecure"`
-	ServerName string            `json:"server-name"`
-	Timeout    Duration          `json:"timeout"`
-	Key        string            `json:"key,omitempty"`
-	Cert       string            `json:"cert,omitempty"`
-	Cacert     string            `json:"cacert,omitempty"`
+	Target string `json:"target"`
+	// Targets executes the healthcheck on each target, instead of Target
+	Targets  []string          `json:"targets,omitempty" yaml:"targets,omitempty"`
+	Host     string            `json:"host,omitempty"`
+	Method   string            `json:"method"`
+	Port     uint              `json:"port"`
+	Redirect bool              `json:"redirect"`
+	Body     string            `json:"body,omitempty"`
+	Query    map[string]string `json:"query,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	// UserAgent the User-Agent header of the requests, Cabourotte by
+	// default
+	UserAgent string   `json:"user-agent,omitempty" yaml:"user-agent,omitempty"`
+	Protocol  Protocol `json:"protocol"`
+	Path      string   `json:"path,omitempty"`
+	SourceIP  IP       `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
+	// IPVersion restricts the healthcheck to the IPv4 (4) or IPv6 (6)
+	// addresses of the target
+	IPVersion uint `json:"ip-version,omitempty" yaml:"ip-version,omitempty"`
+	// Paths executes the healthcheck simultaneously on each network path
+	Paths      []Path   `json:"paths,omitempty" yaml:"paths,omitempty"`
+	BodyRegexp []Regexp `json:"body-regexp,omitempty" yaml:"body-regexp,omitempty"`
+	// Assertions the expressions evaluated on the response, which should
+	// all be true
+	Assertions []string `json:"assertions,omitempty" yaml:"assertions,omitempty"`
+	Insecure   bool     `json:"insecure"`
+	ServerName string   `json:"server-name"`
+	Timeout    Duration `json:"timeout"`
+	Key        string   `json:"key,omitempty"`
+	Cert       string   `json:"cert,omitempty"`
+	Cacert     string   `json:"cacert,omitempty"`
+	// DisableDNSCache resolves the target without the DNS cache
+	DisableDNSCache bool `json:"disable-dns-cache,omitempty" yaml:"disable-dns-cache,omitempty"`
+	// Resolution when the target is resolved: on each execution
+	// (execution), once (registration), or cached during the
+	// resolution-ttl (cache). By default, the target is resolved on each
+	// new connection.
+	Resolution    string   `json:"resolution,omitempty" yaml:"resolution,omitempty"`
+	ResolutionTTL Duration `json:"resolution-ttl,omitempty" yaml:"resolution-ttl,omitempty"`
+	// DNSTimeout the timeout of the resolution of the target, in order
+	// to distinguish a slow resolver from a slow target
+	DNSTimeout Duration `json:"dns-timeout,omitempty" yaml:"dns-timeout,omitempty"`
+	// MaxBodySize the maximum size in bytes of the response body,
+	// DefaultMaxBodySize if not set. The healthcheck fails if the body is
+	// larger.
+	MaxBodySize uint64 `json:"max-body-size,omitempty" yaml:"max-body-size,omitempty"`
+	// CaptureResponse captures the response status, headers and body in
+	// the results of the failed executions
+	CaptureResponse *CaptureResponse `json:"capture-response,omitempty" yaml:"capture-response,omitempty"`
+	// Proxy the proxy used to reach the target, an HTTP, HTTPS or SOCKS5 proxy URL, or direct to
+	// ignore the global proxy
+	Proxy string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// NetworkNamespace the Linux network namespace in which the
+	// connections are opened, a name of /var/run/netns or a path
+	NetworkNamespace string `json:"network-namespace,omitempty" yaml:"network-namespace,omitempty"`
+	// VRF the VRF or the network interface the connections are bound to
+	VRF string `json:"vrf,omitempty" yaml:"vrf,omitempty"`
+	// AllowedMethods the methods which should be listed in the Allow
+	// header of the response, usually to an OPTIONS request
+	AllowedMethods []string `json:"allowed-methods,omitempty" yaml:"allowed-methods,omitempty"`
+	// Preflight verifies the response to a CORS preflight request, the
+	// method being OPTIONS by default
+	Preflight *Preflight `json:"preflight,omitempty" yaml:"preflight,omitempty"`
+	// Credential the name of the credential used to authenticate
+	Credential string `json:"credential,omitempty" yaml:"credential,omitempty"`
+
+	credential *Credential
 }
 
+// DefaultMaxBodySize the default maximum size of the HTTP responses bodies
+const DefaultMaxBodySize = 10 * 1024 * 1024
+
 // Validate validates the healthcheck configuration
 func (config *HTTPHealthcheckConfiguration) Validate() error {
 	if config.Base.Name == "" {
@@ -52,8 +104,14 @@ func (config *HTTPHealthcheckConfiguration) Validate() error {
 	if len(config.ValidStatus) == 0 {
 		return errors.New("At least one valid status code should be provided")
 	}
-	if config.Target == "" {
-		return errors.New("The healthcheck target is missing")
+	if err := validateTargets(config.Target, config.Targets); err != nil {
+		return err
+	}
+	if err := validatePaths(config.Paths, config.Targets, config.SourceIP, config.IPVersion); err != nil {
+		return err
+	}
+	if err := validateResolution(config.Resolution, config.ResolutionTTL); err != nil {
+		return err
 	}
 	if config.Port == 0 {
 		return errors.New("The healthcheck port is missing")
@@ -61,14 +119,33 @@ func (config *HTTPHealthcheckConfiguration) Validate() error {
 	if config.Timeout == 0 {
 		return errors.New("The healthcheck timeout is missing")
 	}
+	if err := validateDNSTimeout(config.DNSTimeout, config.Timeout); err != nil {
+		return err
+	}
+	if err := validateProxy(config.Proxy, "http", "https", "socks5"); err != nil {
+		return err
+	}
+	if err := validateNetwork(config.NetworkNamespace, config.VRF); err != nil {
+		return err
+	}
+	if config.Preflight != nil {
+		if err := config.Preflight.Validate(); err != nil {
+			return err
+		}
+	}
+	if _, err := compileAssertions(config.Assertions, httpAssertionVariables); err != nil {
+		return err
+	}
 	if config.Method != "" {
-		if config.Method != "GET" && config.Method != "POST" && config.Method != "PUT" && config.Method != "HEAD" && config.Method != "DELETE" {
+		if config.Method != "GET" && config.Method != "POST" && config.Method != "PUT" && config.Method != "HEAD" && config.Method != "DELETE" && config.Method != "OPTIONS" {
 			return errors.New(fmt.Sprintf("The healthcheck method is invalid: %s", config.Method))
 		}
+	} else if config.Preflight != nil {
+		config.Method = "OPTIONS"
 	} else {
 		config.Method = "GET"
 	}
-	if !config.Base.OneOff {
+	if !config.Base.OneOff && config.Base.Cron == nil {
 		if config.Base.Interval < Duration(2*time.Second) {
 			return errors.New("The healthcheck interval should be greater than 2 second")
 		}
@@ -76,6 +153,10 @@ func (config *HTTPHealthcheckConfiguration) Validate() error {
 			return errors.New("The healthcheck interval should be greater than the timeout")
 		}
 	}
+	err := config.Base.ValidateBase()
+	if err != nil {
+		return err
+	}
 	if !((config.Key != "" && config.Cert != "") ||
 		(config.Key == "" && config.Cert == "")) {
 		return errors.New("Invalid certificates")
@@ -89,9 +170,10 @@ type HTTPHealthcheck struct {
 	Config *HTTPHealthcheckConfiguration
 	URL    string
 
-	Tick      *time.Ticker
-	t         tomb.Tomb
-	transport *http.Transport
+	Tick       *time.Ticker
+	transport  *http.Transport
+	cache      *addressCache
+	assertions []*expression.Expression
 }
 
 // buildURL build the target URL for the HTTP healthcheck, depending of its
@@ -112,10 +194,10 @@ func (h *HTTPHealthcheck) buildURL() {
 func (h *HTTPHealthcheck) Summary() string {
 	summary := ""
 	if h.Config.Base.Description != "" {
-		summary = fmt.Sprintf("HTTP healthcheck %s on %s:%d", h.Config.Base.Description, h.Config.Target, h.Config.Port)
+		summary = fmt.Sprintf("HTTP healthcheck %s on %s:%d", h.Config.Base.Description, displayTargets(h.Config.Target, h.Config.Targets), h.Config.Port)
 
 	} else {
-		summary = fmt.Sprintf("HTTP healthcheck on %s:%d", h.Config.Target, h.Config.Port)
+		summary = fmt.Sprintf("HTTP healthcheck on %s:%d", displayTargets(h.Config.Target, h.Config.Targets), h.Config.Port)
 	}
 
 	return summary
@@ -136,13 +218,34 @@ func (h *HTTPHealthcheck) Initialize() error {
 			LocalAddr: addr,
 		}
 	}
-	tlsConfig, err := tls.GetTLSConfig(h.Config.Key, h.Config.Cert, h.Config.Cacert, h.Config.ServerName, h.Config.Insecure)
+	key, cert, cacert := h.Config.credential.certificates(h.Config.Key, h.Config.Cert, h.Config.Cacert)
+	tlsConfig, err := tls.GetTLSConfig(key, cert, cacert, h.Config.ServerName, h.Config.Insecure)
+	if err != nil {
+		return err
+	}
+	assertions, err := compileAssertions(h.Config.Assertions, httpAssertionVariables)
 	if err != nil {
 		return err
 	}
+	h.assertions = assertions
+	cache := newAddressCache(h.Config.Resolution, h.Config.ResolutionTTL)
+	h.cache = cache
 	h.transport = &http.Transport{
-		DialContext:     dialer.DialContext,
+		DialContext: func(ctx context.Context, _ string, address string) (net.Conn, error) {
+			dialer := dialer
+			applyPolicy(ctx, &dialer)
+			d := applyNetwork(&dialer, h.Config.NetworkNamespace, h.Config.VRF)
+			return dial(ctx, d, network(h.Config.IPVersion), address, !h.Config.DisableDNSCache, cache, time.Duration(h.Config.DNSTimeout))
+		},
+		// the proxy is selected on each request, the global proxy being
+		// in the request context
+		Proxy: func(req *http.Request) (*url.URL, error) {
+			return proxyURL(req.Context(), h.Config.Proxy, req.URL.Scheme, req.URL.Hostname())
+		},
 		TLSClientConfig: tlsConfig,
+		// the connections are not reused in order to resolve the target
+		// on each execution
+		DisableKeepAlives: h.Config.Resolution == ResolutionExecution,
 	}
 	return nil
 }
@@ -157,6 +260,11 @@ func (h *HTTPHealthcheck) Base() Base {
 	return h.Config.Base
 }
 
+// Timeout returns the healthcheck timeout
+func (h *HTTPHealthcheck) Timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
 // SetSource set the healthcheck source
 func (h *HTTPHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
@@ -165,12 +273,7 @@ func (h *HTTPHealthcheck) SetSource(source string) {
 // isSuccessful verifies if a healthcheck result is considered valid
 // depending of the healthcheck configuration
 func (h *HTTPHealthcheck) isSuccessful(response *http.Response) bool {
-	for _, s := range h.Config.ValidStatus {
-		if uint(response.StatusCode) == s {
-			return true
-		}
-	}
-	return false
+	return h.Config.ValidStatus.Contains(uint(response.StatusCode))
 }
 
 // LogError logs an error with context
@@ -199,18 +302,34 @@ func (h *HTTPHealthcheck) LogInfo(message string) {
 }
 
 // Execute executes an healthcheck on the given target
-func (h *HTTPHealthcheck) Execute() error {
+func (h *HTTPHealthcheck) Execute(ctx context.Context) error {
 	h.LogDebug("start executing healthcheck")
-	ctx := h.t.Context(context.TODO())
 	body := bytes.NewBuffer([]byte(h.Config.Body))
 	req, err := http.NewRequest(h.Config.Method, h.URL, body)
 	if err != nil {
 		return errors.Wrapf(err, "fail to initialize HTTP request")
 	}
-	req.Header.Set("User-Agent", "Cabourotte")
+	userAgent := DefaultUserAgent
+	if h.Config.UserAgent != "" {
+		userAgent = h.Config.UserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if probeID := probeIDFromContext(ctx); probeID != "" {
+		req.Header.Set(ProbeIDHeader, probeID)
+	}
+	if credential := h.Config.credential; credential != nil {
+		if credential.Username != "" {
+			req.SetBasicAuth(credential.Username, credential.Password)
+		} else if credential.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+credential.Token)
+		}
+	}
 	for k, v := range h.Config.Headers {
 		req.Header.Set(k, v)
 	}
+	if h.Config.Preflight != nil {
+		h.Config.Preflight.apply(req)
+	}
 	redirect := http.ErrUseLastResponse
 	if h.Config.Redirect {
 		redirect = nil
@@ -224,9 +343,7 @@ func (h *HTTPHealthcheck) Execute() error {
 			return redirect
 		},
 	}
-	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
-	defer cancel()
-	req = req.WithContext(timeoutCtx)
+	req = req.WithContext(addressTrace(httpTrace(ctx)))
 	if len(h.Config.Query) != 0 {
 		q := req.URL.Query()
 		for k, v := range h.Config.Query {
@@ -234,15 +351,31 @@ func (h *HTTPHealthcheck) Execute() error {
 		}
 		req.URL.RawQuery = q.Encode()
 	}
+	start := time.Now()
 	response, err := client.Do(req)
 	if err != nil {
 		return errors.Wrapf(err, "HTTP request failed")
 	}
 	defer response.Body.Close()
-	responseBody, err := io.ReadAll(response.Body)
+	if response.TLS != nil {
+		recordTLS(ctx, *response.TLS)
+	}
+	bodyStart := time.Now()
+	maxBodySize := h.Config.MaxBodySize
+	if maxBodySize == 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+	// one more byte is read to detect the bodies exceeding the limit
+	reader := limitReader(ctx, io.LimitReader(response.Body, int64(maxBodySize)+1))
+	responseBody, err := io.ReadAll(reader)
 	if err != nil {
 		return errors.Wrapf(err, "Fail to read request body")
 	}
+	recordResponse(ctx, h.Config.CaptureResponse, response, responseBody)
+	recordPhase(ctx, PhaseBody, bodyStart, time.Now())
+	if uint64(len(responseBody)) > maxBodySize {
+		return assertionError("the response body exceeds the maximum size of %d bytes", maxBodySize)
+	}
 	responseBodyStr := string(responseBody)
 	maxMessageSize := 1000
 	message := responseBodyStr
@@ -252,15 +385,27 @@ func (h *HTTPHealthcheck) Execute() error {
 	if !h.isSuccessful(response) {
 
 		errorMsg := fmt.Sprintf("HTTP request failed: (status %d) => %s", response.StatusCode, html.EscapeString(message))
-		err = errors.New(errorMsg)
-		return err
+		return withCategory(ErrorAssertion, errors.New(errorMsg))
+	}
+	if len(h.Config.AllowedMethods) != 0 {
+		if err := verifyAllowedMethods(response.Header, h.Config.AllowedMethods); err != nil {
+			return err
+		}
+	}
+	if h.Config.Preflight != nil {
+		if err := h.Config.Preflight.verify(response.Header); err != nil {
+			return err
+		}
 	}
 	for _, regex := range h.Config.BodyRegexp {
 		r := regexp.Regexp(regex)
 		if !r.MatchString(responseBodyStr) {
-			return fmt.Errorf("healthcheck body does not match regex %s: %s", r.String(), message)
+			return assertionError("healthcheck body does not match regex %s: %s", r.String(), message)
 		}
 	}
+	if len(h.assertions) != 0 {
+		return verifyAssertions(h.assertions, httpAssertionData(ctx, response, responseBody, time.Since(start)))
+	}
 	return nil
 }
 
@@ -283,7 +428,7 @@ func (in *HTTPHealthcheckConfiguration) DeepCopyInto(out *HTTPHealthcheckConfigu
 	in.Base.DeepCopyInto(&out.Base)
 	if in.ValidStatus != nil {
 		in, out := &in.ValidStatus, &out.ValidStatus
-		*out = make([]uint, len(*in))
+		*out = make(StatusCodes, len(*in))
 		copy(*out, *in)
 	}
 	if in.Headers != nil {
@@ -298,6 +443,23 @@ func (in *HTTPHealthcheckConfiguration) DeepCopyInto(out *HTTPHealthcheckConfigu
 		*out = make(IP, len(*in))
 		copy(*out, *in)
 	}
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]Path, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Assertions != nil {
+		in, out := &in.Assertions, &out.Assertions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.BodyRegexp != nil {
 		in, out := &in.BodyRegexp, &out.BodyRegexp
 		*out = make([]Regexp, len(*in))
@@ -305,6 +467,30 @@ func (in *HTTPHealthcheckConfiguration) DeepCopyInto(out *HTTPHealthcheckConfigu
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CaptureResponse != nil {
+		in, out := &in.CaptureResponse, &out.CaptureResponse
+		*out = new(CaptureResponse)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AllowedMethods != nil {
+		in, out := &in.AllowedMethods, &out.AllowedMethods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Preflight != nil {
+		in, out := &in.Preflight, &out.Preflight
+		*out = new(Preflight)
+		**out = **in
+		if (*in).RequestHeaders != nil {
+			(*out).RequestHeaders = make([]string, len((*in).RequestHeaders))
+			copy((*out).RequestHeaders, (*in).RequestHeaders)
+		}
+	}
+	if in.credential != nil {
+		in, out := &in.credential, &out.credential
+		*out = new(Credential)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPHealthcheckConfiguration.
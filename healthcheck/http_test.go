@@ -1,6 +1,7 @@
 package healthcheck
 
 import (
+	"context"
 	"io"
 	"net"
 	"net/http"
@@ -104,7 +105,7 @@ func TestHTTPExecuteGetSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -154,7 +155,7 @@ func TestHTTPExecuteRegexpSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -198,7 +199,7 @@ func TestHTTPExecuteRegexpFailure(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("Was expecting an error")
 	}
@@ -240,7 +241,7 @@ func TestHTTPv6ExecuteSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -279,7 +280,7 @@ func TestHTTPExecuteFailure(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("Was expecting an error")
 	}
@@ -351,7 +352,7 @@ func TestHTTPStartStop(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
 	}
-	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{})
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
 	if err != nil {
 		t.Fatalf("Fail to create the component\n%v", err)
 	}
@@ -407,7 +408,7 @@ func TestHTTPExecuteSourceIP(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -470,7 +471,7 @@ func TestHTTPExecutePostSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -546,7 +547,7 @@ func TestHTTPExecuteQueryParam(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -560,3 +561,324 @@ func TestHTTPExecuteQueryParam(t *testing.T) {
 		t.Fatal("Invalid body")
 	}
 }
+
+func TestHTTPExecutePhases(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := HTTPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &HTTPHealthcheckConfiguration{
+			ValidStatus: []uint{200},
+			Port:        uint(port),
+			Target:      "127.0.0.1",
+			Protocol:    HTTP,
+			Path:        "/",
+			Timeout:     Duration(time.Second * 2),
+		},
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	ctx, recorder := withPhases(context.Background())
+	err = h.Execute(ctx)
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	names := make(map[string]bool)
+	for _, phase := range recorder.list() {
+		if phase.End.Before(phase.Start) {
+			t.Fatalf("Invalid phase %s", phase.Name)
+		}
+		names[phase.Name] = true
+	}
+	for _, name := range []string{PhaseConnect, PhaseFirstByte, PhaseBody} {
+		if !names[name] {
+			t.Fatalf("The phase %s was not recorded: %v", name, names)
+		}
+	}
+}
+
+func TestHTTPExecuteProbeID(t *testing.T) {
+	var userAgent, probeID string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		probeID = r.Header.Get(ProbeIDHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+		Base: Base{
+			Name:   "foo",
+			OneOff: true,
+		},
+		ValidStatus: []uint{200},
+		Port:        uint(port),
+		Target:      "127.0.0.1",
+		Protocol:    HTTP,
+		Path:        "/",
+		UserAgent:   "my-agent",
+		Timeout:     Duration(time.Second * 2),
+	})
+	result := ExecuteOnce(context.Background(), h)
+	if !result.Success {
+		t.Fatalf("healthcheck error :\n%s", result.Message)
+	}
+	if userAgent != "my-agent" {
+		t.Fatalf("Invalid User-Agent %s", userAgent)
+	}
+	if probeID == "" || probeID != result.ProbeID {
+		t.Fatalf("Invalid probe ID %s, expected %s", probeID, result.ProbeID)
+	}
+	other := ExecuteOnce(context.Background(), h)
+	if other.ProbeID == result.ProbeID {
+		t.Fatalf("The probe ID should be unique for each execution")
+	}
+}
+
+func TestHTTPExecuteMaxBodySize(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(strings.Repeat("a", 100)))
+		if err != nil {
+			t.Fatalf("Error writing :\n%v", err)
+		}
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		maxBodySize uint64
+		success     bool
+	}{
+		{maxBodySize: 0, success: true},
+		{maxBodySize: 100, success: true},
+		{maxBodySize: 99, success: false},
+	}
+	for _, c := range cases {
+		h := HTTPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &HTTPHealthcheckConfiguration{
+				ValidStatus: []uint{200},
+				Port:        uint(port),
+				Target:      "127.0.0.1",
+				Protocol:    HTTP,
+				Path:        "/",
+				Timeout:     Duration(time.Second * 2),
+				MaxBodySize: c.maxBodySize,
+			},
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		err = h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("Unexpected error for the max body size %d:\n%v", c.maxBodySize, err)
+		}
+		if !c.success {
+			if err == nil {
+				t.Fatalf("Was expecting an error for the max body size %d", c.maxBodySize)
+			}
+			if ErrorCategory(err) != ErrorAssertion {
+				t.Fatalf("Invalid error category %s", ErrorCategory(err))
+			}
+		}
+	}
+}
+
+func TestHTTPExecuteResolvedIP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+		Base: Base{
+			Name: "foo",
+		},
+		ValidStatus: []uint{200},
+		Port:        uint(port),
+		Target:      "127.0.0.1",
+		Protocol:    HTTP,
+		Timeout:     Duration(time.Second * 2),
+	})
+	result := ExecuteOnce(context.Background(), h)
+	if !result.Success {
+		t.Fatalf("The healthcheck should be successful: %s", result.Message)
+	}
+	if result.ResolvedIP != "127.0.0.1" {
+		t.Fatalf("Invalid resolved IP %s", result.ResolvedIP)
+	}
+}
+
+func TestHTTPExecutePreflight(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Allow", "GET, POST, OPTIONS")
+		if origin := r.Header.Get("Origin"); origin == "https://app.example.com" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		} else if origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		w.Header().Set("Access-Control-Allow-Headers", "content-type")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		allowedMethods []string
+		preflight      *Preflight
+		success        bool
+	}{
+		{allowedMethods: []string{"GET", "options"}, success: true},
+		{allowedMethods: []string{"DELETE"}, success: false},
+		{preflight: &Preflight{Origin: "https://app.example.com", RequestMethod: "POST", RequestHeaders: []string{"Content-Type"}, AllowCredentials: true}, success: true},
+		{preflight: &Preflight{Origin: "https://other.example.com", RequestMethod: "GET"}, success: true},
+		{preflight: &Preflight{Origin: "https://other.example.com", AllowCredentials: true}, success: false},
+		{preflight: &Preflight{Origin: "https://app.example.com", RequestMethod: "PUT"}, success: false},
+		{preflight: &Preflight{Origin: "https://app.example.com", RequestHeaders: []string{"Content-Type", "Authorization"}}, success: false},
+	}
+	for i, c := range cases {
+		config := &HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Minute),
+			},
+			ValidStatus:    []uint{204},
+			Port:           uint(port),
+			Target:         "127.0.0.1",
+			Protocol:       HTTP,
+			Path:           "/",
+			Timeout:        Duration(time.Second * 2),
+			AllowedMethods: c.allowedMethods,
+			Preflight:      c.preflight,
+		}
+		if c.preflight == nil {
+			config.Method = http.MethodOptions
+		}
+		err := config.Validate()
+		if err != nil {
+			t.Fatalf("Validation error for the case %d:\n%v", i, err)
+		}
+		if config.Method != http.MethodOptions {
+			t.Fatalf("Invalid method %s for the case %d", config.Method, i)
+		}
+		h := NewHTTPHealthcheck(zap.NewExample(), config)
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		err = h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("Unexpected error for the case %d:\n%v", i, err)
+		}
+		if !c.success {
+			if err == nil {
+				t.Fatalf("Was expecting an error for the case %d", i)
+			}
+			if ErrorCategory(err) != ErrorAssertion {
+				t.Fatalf("Invalid error category %s", ErrorCategory(err))
+			}
+		}
+	}
+}
+
+func TestHTTPExecuteAssertions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(`{"status": "up", "replicas": [1, 2, 3]}`))
+		if err != nil {
+			t.Fatalf("Error writing :\n%v", err)
+		}
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		assertions []string
+		success    bool
+	}{
+		{assertions: []string{"status == 200", "json.status == 'up' && len(json.replicas) >= 2"}, success: true},
+		{assertions: []string{"headers['content-type'] == 'application/json'", "contains(body, 'replicas')"}, success: true},
+		{assertions: []string{"duration >= 0"}, success: true},
+		{assertions: []string{"status == 200", "json.status == 'down'"}, success: false},
+		{assertions: []string{"json.status > 1"}, success: false},
+	}
+	for _, c := range cases {
+		h := HTTPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &HTTPHealthcheckConfiguration{
+				Base: Base{
+					Name:     "foo",
+					Interval: Duration(time.Second * 10),
+				},
+				ValidStatus: []uint{200},
+				Port:        uint(port),
+				Target:      "127.0.0.1",
+				Protocol:    HTTP,
+				Path:        "/",
+				Timeout:     Duration(time.Second * 2),
+				Assertions:  c.assertions,
+			},
+		}
+		err = h.Config.Validate()
+		if err != nil {
+			t.Fatalf("Fail to validate the healthcheck: %s", err.Error())
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		err = h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %v:\n%v", c.assertions, err)
+		}
+		if !c.success && (err == nil || ErrorCategory(err) != ErrorAssertion) {
+			t.Fatalf("Was expecting an assertion error for %v, got %v", c.assertions, err)
+		}
+	}
+	h := HTTPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(time.Second * 10),
+		},
+		ValidStatus: []uint{200},
+		Port:        uint(port),
+		Target:      "127.0.0.1",
+		Timeout:     Duration(time.Second * 2),
+		Assertions:  []string{"ips == null"},
+	}
+	if err := h.Validate(); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
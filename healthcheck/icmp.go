@@ -0,0 +1,208 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// icmpPayload the payload of the ICMP echo requests
+var icmpPayload = []byte("cabourotte")
+
+// icmpSequence the sequence number of the last ICMP echo request, shared
+// by the healthchecks in order to match the replies of concurrent
+// executions
+var icmpSequence uint32
+
+// icmpConfiguration the configuration of the ICMP echo executed by a
+// profile healthcheck
+type icmpConfiguration struct {
+	Base       `json:",inline"`
+	Target     string   `json:"target"`
+	Timeout    Duration `json:"timeout"`
+	IPVersion  uint     `json:"ip-version,omitempty"`
+	Privileged bool     `json:"privileged,omitempty"`
+}
+
+// icmpHealthcheck sends an ICMP echo request to the target and waits for
+// the reply. The unprivileged mode uses datagram sockets, which should be
+// allowed by the net.ipv4.ping_group_range sysctl, and the privileged
+// mode uses raw sockets.
+type icmpHealthcheck struct {
+	Logger *zap.Logger
+	Config *icmpConfiguration
+}
+
+// Summary returns an healthcheck summary
+func (h *icmpHealthcheck) Summary() string {
+	return fmt.Sprintf("ICMP healthcheck on %s", h.Config.Target)
+}
+
+// Initialize the healthcheck.
+func (h *icmpHealthcheck) Initialize() error {
+	return nil
+}
+
+// GetConfig get the config
+func (h *icmpHealthcheck) GetConfig() interface{} {
+	return h.Config
+}
+
+// Base get the base configuration
+func (h *icmpHealthcheck) Base() Base {
+	return h.Config.Base
+}
+
+// Timeout returns the healthcheck timeout
+func (h *icmpHealthcheck) Timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
+// SetSource set the healthcheck source
+func (h *icmpHealthcheck) SetSource(source string) {
+	h.Config.Base.Source = source
+}
+
+// LogError logs an error with context
+func (h *icmpHealthcheck) LogError(err error, message string) {
+	h.Logger.Error(err.Error(),
+		zap.String("extra", message),
+		zap.String("target", h.Config.Target),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogDebug logs a message with context
+func (h *icmpHealthcheck) LogDebug(message string) {
+	h.Logger.Debug(message,
+		zap.String("target", h.Config.Target),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogInfo logs a message with context
+func (h *icmpHealthcheck) LogInfo(message string) {
+	h.Logger.Info(message,
+		zap.String("target", h.Config.Target),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// resolve returns the IP address of the target, using the resolver of the
+// context if any
+func (h *icmpHealthcheck) resolve(ctx context.Context) (net.IP, error) {
+	if ip := net.ParseIP(h.Config.Target); ip != nil {
+		return ip, nil
+	}
+	resolver := resolverFromContext(ctx)
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	start := time.Now()
+	addrs, err := resolver.LookupIPAddr(ctx, h.Config.Target)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to resolve %s", h.Config.Target)
+	}
+	recordPhase(ctx, PhaseDNS, start, time.Now())
+	for _, addr := range addrs {
+		ipv4 := addr.IP.To4() != nil
+		if (h.Config.IPVersion == 4 && !ipv4) || (h.Config.IPVersion == 6 && ipv4) {
+			continue
+		}
+		return addr.IP, nil
+	}
+	return nil, fmt.Errorf("no suitable address found for %s", h.Config.Target)
+}
+
+// Execute executes an healthcheck on the given target
+func (h *icmpHealthcheck) Execute(ctx context.Context) error {
+	h.LogDebug("start executing healthcheck")
+	ip, err := h.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	if policy, _ := ctx.Value(policyKey{}).(*TargetPolicy); policy != nil {
+		if err := policy.Check(ip); err != nil {
+			return err
+		}
+	}
+	network := "udp4"
+	address := "0.0.0.0"
+	protocol := 1
+	var requestType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if ip.To4() == nil {
+		network = "udp6"
+		address = "::"
+		protocol = 58
+		requestType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	if h.Config.Privileged {
+		if ip.To4() == nil {
+			network = "ip6:ipv6-icmp"
+		} else {
+			network = "ip4:icmp"
+		}
+	}
+	conn, err := icmp.ListenPacket(network, address)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to open the ICMP socket")
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return errors.Wrapf(err, "Fail to set the ICMP socket deadline")
+		}
+	}
+	// the identifier is replaced by the kernel in unprivileged mode
+	id := os.Getpid() & 0xffff
+	seq := int(atomic.AddUint32(&icmpSequence, 1) & 0xffff)
+	request, err := (&icmp.Message{
+		Type: requestType,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: icmpPayload},
+	}).Marshal(nil)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to build the ICMP echo request")
+	}
+	var destination net.Addr = &net.UDPAddr{IP: ip}
+	if h.Config.Privileged {
+		destination = &net.IPAddr{IP: ip}
+	}
+	start := time.Now()
+	if _, err := conn.WriteTo(request, destination); err != nil {
+		return errors.Wrapf(err, "ICMP echo request failed on %s", ip)
+	}
+	buffer := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return errors.Wrapf(err, "ICMP echo failed on %s", ip)
+		}
+		message, err := icmp.ParseMessage(protocol, buffer[:n])
+		if err != nil || message.Type != replyType {
+			continue
+		}
+		echo, ok := message.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq || !bytes.Equal(echo.Data, icmpPayload) {
+			continue
+		}
+		if h.Config.Privileged && echo.ID != id {
+			continue
+		}
+		recordPhase(ctx, PhaseConnect, start, time.Now())
+		recordAddress(ctx, peer)
+		return nil
+	}
+}
+
+// MarshalJSON marshal to json an ICMP healthcheck
+func (h *icmpHealthcheck) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Config)
+}
@@ -0,0 +1,157 @@
+package healthcheck
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MaxNameLength the maximum length of the healthchecks names
+const MaxNameLength = 253
+
+// nameRegexp the valid healthchecks names: letters, digits, '_', '.', ':'
+// and '-', starting with a letter or a digit
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.:-]*$`)
+
+// invalidNameRegexp the characters not allowed in the healthchecks names
+var invalidNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_.:-]+`)
+
+// ValidateName validates an healthcheck name. The names contain letters,
+// digits, '_', '.', ':' and '-', start with a letter or a digit and are at
+// most MaxNameLength characters long.
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("The healthcheck name is missing")
+	}
+	if len(name) > MaxNameLength {
+		return fmt.Errorf("The healthcheck name %s is too long, the maximum length is %d", name, MaxNameLength)
+	}
+	if !nameRegexp.MatchString(name) {
+		return fmt.Errorf("Invalid healthcheck name %s, it should only contain letters, digits, '_', '.', ':' and '-', and start with a letter or a digit", name)
+	}
+	return nil
+}
+
+// NormalizeName converts a string to a valid healthcheck name. The invalid
+// characters are replaced by '-', and the names too long are truncated and
+// suffixed by a hash of the whole string in order to stay unique.
+func NormalizeName(name string) string {
+	result := invalidNameRegexp.ReplaceAllString(name, "-")
+	result = strings.TrimLeft(result, "_.:-")
+	if len(result) > MaxNameLength {
+		hash := fnv.New32a()
+		hash.Write([]byte(name))
+		suffix := fmt.Sprintf("-%08x", hash.Sum32())
+		result = strings.TrimRight(result[:MaxNameLength-len(suffix)], "_.:-") + suffix
+	}
+	return result
+}
+
+// generatedName returns the name derived from the type, the target and
+// the port of an healthcheck, or an empty string without target
+func generatedName(checkType string, target string, targets []string, port uint) string {
+	if target == "" && len(targets) == 0 {
+		return ""
+	}
+	if len(targets) != 0 {
+		target = strings.Join(targets, "-")
+	}
+	name := fmt.Sprintf("%s-%s", checkType, target)
+	if port != 0 {
+		name = fmt.Sprintf("%s-%d", name, port)
+	}
+	return NormalizeName(name)
+}
+
+// GenerateName sets the name of an healthcheck configuration whose
+// generate-name option is enabled. The name is derived from the type, the
+// target and the port of the healthcheck, for example tcp-10.0.0.1-22. The
+// explicit names are kept.
+func GenerateName(config interface{}) error {
+	var base *Base
+	var name string
+	switch c := config.(type) {
+	case *DNSHealthcheckConfiguration:
+		base = &c.Base
+		name = generatedName("dns", c.Domain, nil, 0)
+	case *TCPHealthcheckConfiguration:
+		base = &c.Base
+		name = generatedName("tcp", c.Target, c.Targets, c.Port)
+	case *HTTPHealthcheckConfiguration:
+		base = &c.Base
+		name = generatedName("http", c.Target, c.Targets, c.Port)
+	case *TLSHealthcheckConfiguration:
+		base = &c.Base
+		name = generatedName("tls", c.Target, c.Targets, c.Port)
+	case *GRPCHealthcheckConfiguration:
+		base = &c.Base
+		name = generatedName("grpc", c.Target, nil, c.Port)
+	case *ProfileHealthcheckConfiguration:
+		base = &c.Base
+		name = generatedName("profile", c.Target, nil, c.Port)
+	case *CommandHealthcheckConfiguration:
+		if c.Base.GenerateName && c.Base.Name == "" {
+			return errors.New("The generate-name option is not supported by the command healthchecks")
+		}
+		return nil
+	case *HeartbeatHealthcheckConfiguration:
+		if c.Base.GenerateName && c.Base.Name == "" {
+			return errors.New("The generate-name option is not supported by the heartbeat healthchecks")
+		}
+		return nil
+	default:
+		return fmt.Errorf("Unknown healthcheck configuration %T", config)
+	}
+	if !base.GenerateName || base.Name != "" {
+		return nil
+	}
+	if name == "" {
+		return errors.New("The healthcheck target is required to generate its name")
+	}
+	base.Name = name
+	return nil
+}
+
+// conflictError the name of an healthcheck is already used by an
+// healthcheck of another source
+type conflictError struct {
+	id     string
+	source string
+}
+
+// Error returns the error message
+func (e *conflictError) Error() string {
+	return fmt.Sprintf("The healthcheck %s already exists and is managed by the source %s", e.id, sourceName(e.source))
+}
+
+// IsConflict returns true if the error is caused by an healthcheck name
+// already used by another source
+func IsConflict(err error) bool {
+	var conflictErr *conflictError
+	return errors.As(err, &conflictErr)
+}
+
+// CheckConflict returns an error if the identifier is used by an
+// healthcheck not managed by the API, the API not being allowed to
+// replace the healthchecks of the configuration or of the discovery
+// mechanisms.
+func (c *Component) CheckConflict(id string) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.checkConflict(id, SourceAPI)
+}
+
+// checkConflict returns an error if the identifier is used by an
+// healthcheck of another source, a source not being allowed to replace
+// the healthchecks of the others. The lock should be held.
+func (c *Component) checkConflict(id string, source string) error {
+	if current, ok := c.Healthchecks[id]; ok {
+		if currentSource := current.healthcheck.Base().Source; currentSource != source {
+			return &conflictError{id: id, source: currentSource}
+		}
+	}
+	return nil
+}
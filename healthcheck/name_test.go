@@ -0,0 +1,160 @@
+package healthcheck
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestValidateName(t *testing.T) {
+	cases := []struct {
+		name  string
+		valid bool
+	}{
+		{name: "foo", valid: true},
+		{name: "consul-web-node1-web.1", valid: true},
+		{name: "eureka-app-host:app:8080", valid: true},
+		{name: "0_foo", valid: true},
+		{name: strings.Repeat("a", MaxNameLength), valid: true},
+		{name: "", valid: false},
+		{name: "-foo", valid: false},
+		{name: "foo bar", valid: false},
+		{name: "foo/bar", valid: false},
+		{name: "héhé", valid: false},
+		{name: strings.Repeat("a", MaxNameLength+1), valid: false},
+	}
+	for _, c := range cases {
+		err := ValidateName(c.name)
+		if c.valid && err != nil {
+			t.Fatalf("Unexpected error for %s:\n%v", c.name, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Was expecting an error for %s", c.name)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{name: "foo", expected: "foo"},
+		{name: "-foo bar/baz", expected: "foo-bar-baz"},
+		{name: "web server-api", expected: "web-server-api"},
+	}
+	for _, c := range cases {
+		if result := NormalizeName(c.name); result != c.expected {
+			t.Fatalf("Invalid name %s for %s, expected %s", result, c.name, c.expected)
+		}
+	}
+	long := NormalizeName(strings.Repeat("a", MaxNameLength) + "1")
+	other := NormalizeName(strings.Repeat("a", MaxNameLength) + "2")
+	if len(long) != MaxNameLength || long == other {
+		t.Fatalf("Invalid truncated names %s and %s", long, other)
+	}
+	if err := ValidateName(long); err != nil {
+		t.Fatalf("Invalid truncated name\n%v", err)
+	}
+}
+
+func TestGenerateName(t *testing.T) {
+	base := Base{GenerateName: true, Interval: Duration(10 * time.Second)}
+	tcp := TCPHealthcheckConfiguration{Base: base, Target: "10.0.0.1", Port: 22, Timeout: Duration(time.Second)}
+	if err := GenerateName(&tcp); err != nil {
+		t.Fatalf("Fail to generate the name\n%v", err)
+	}
+	if tcp.Base.Name != "tcp-10.0.0.1-22" {
+		t.Fatalf("Invalid generated name %s", tcp.Base.Name)
+	}
+	if err := tcp.Validate(); err != nil {
+		t.Fatalf("Invalid configuration\n%v", err)
+	}
+	http := HTTPHealthcheckConfiguration{Base: base, Targets: []string{"a.example.com", "b.example.com"}, Port: 443}
+	if err := GenerateName(&http); err != nil {
+		t.Fatalf("Fail to generate the name\n%v", err)
+	}
+	if http.Base.Name != "http-a.example.com-b.example.com-443" {
+		t.Fatalf("Invalid generated name %s", http.Base.Name)
+	}
+	dns := DNSHealthcheckConfiguration{Base: base, Domain: "example.com"}
+	dns.Base.Name = "resolver"
+	if err := GenerateName(&dns); err != nil {
+		t.Fatalf("Fail to generate the name\n%v", err)
+	}
+	if dns.Base.Name != "resolver" {
+		t.Fatalf("The explicit name was replaced by %s", dns.Base.Name)
+	}
+	command := CommandHealthcheckConfiguration{Base: base, Command: "true"}
+	if err := GenerateName(&command); err == nil {
+		t.Fatalf("Was expecting an error for the command healthcheck")
+	}
+	grpc := GRPCHealthcheckConfiguration{Base: base, Port: 9090}
+	if err := GenerateName(&grpc); err == nil {
+		t.Fatalf("Was expecting an error for the healthcheck without target")
+	}
+}
+
+func TestCheckConflict(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	tcp := func(port uint) []TCPHealthcheckConfiguration {
+		return []TCPHealthcheckConfiguration{
+			{
+				Base: Base{
+					Name:     "db",
+					Interval: Duration(time.Minute),
+				},
+				Target:  "127.0.0.1",
+				Port:    port,
+				Timeout: Duration(time.Second),
+			},
+		}
+	}
+	err = component.ReloadForSource("directory-a.yaml", nil, nil, nil, tcp(5432), nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Fail to reload the healthchecks\n%v", err)
+	}
+	// a source can not replace the healthchecks of another source
+	err = component.ReloadForSource("directory-b.yaml", nil, nil, nil, tcp(5433), nil, nil, nil, nil, nil)
+	if !IsConflict(err) {
+		t.Fatalf("Was expecting a conflict error, got %v", err)
+	}
+	if component.GetCheck("db").Base().Source != "directory-a.yaml" {
+		t.Fatalf("The healthcheck was replaced")
+	}
+	err = component.ReloadForSource(SourceConfig, nil, nil, nil, tcp(5433), nil, nil, nil, nil, nil)
+	if !IsConflict(err) {
+		t.Fatalf("Was expecting a conflict error, got %v", err)
+	}
+	if err := component.CheckConflict("db"); !IsConflict(err) {
+		t.Fatalf("Was expecting a conflict error, got %v", err)
+	}
+	// the healthchecks of the source can be updated
+	err = component.ReloadForSource("directory-a.yaml", nil, nil, nil, tcp(5433), nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Fail to reload the healthchecks\n%v", err)
+	}
+	err = component.ReloadForSource("directory-a.yaml", nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Fail to reload the healthchecks\n%v", err)
+	}
+	err = component.ReloadForSource("directory-b.yaml", nil, nil, nil, tcp(5433), nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Fail to reload the healthchecks\n%v", err)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// NetworkNamespaceDirectory the directory of the named network namespaces
+const NetworkNamespaceDirectory = "/var/run/netns"
+
+// contextDialer opens the connections of the healthchecks
+type contextDialer interface {
+	DialContext(ctx context.Context, network string, address string) (net.Conn, error)
+}
+
+// validateNetwork validates the network namespace and the VRF of an
+// healthcheck
+func validateNetwork(namespace string, vrf string) error {
+	if namespace == "" && vrf == "" {
+		return nil
+	}
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("The healthcheck network-namespace and vrf are only supported on Linux")
+	}
+	if namespace != "" && !filepath.IsAbs(namespace) {
+		if strings.Contains(namespace, "/") || namespace == "." || namespace == ".." {
+			return fmt.Errorf("Invalid healthcheck network-namespace %s, it should be a name or an absolute path", namespace)
+		}
+	}
+	// the interfaces names are limited to 15 characters
+	if vrf != "" && (len(vrf) > 15 || strings.ContainsAny(vrf, "/ \t")) {
+		return fmt.Errorf("Invalid healthcheck vrf %s", vrf)
+	}
+	return nil
+}
+
+// namespacePath returns the path of a network namespace, the named
+// namespaces being in NetworkNamespaceDirectory
+func namespacePath(namespace string) string {
+	if filepath.IsAbs(namespace) {
+		return namespace
+	}
+	return filepath.Join(NetworkNamespaceDirectory, namespace)
+}
+
+// namespaceDialer opens the connections in a network namespace. The
+// targets are resolved in the network namespace of the daemon.
+type namespaceDialer struct {
+	dialer    *net.Dialer
+	namespace string
+}
+
+// DialContext resolves the address, and connects to its IP addresses in
+// the network namespace
+func (d *namespaceDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return dialInNamespace(ctx, d.dialer, namespacePath(d.namespace), network, address)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	var firstErr error
+	for _, addr := range addrs {
+		ipv4 := addr.IP.To4() != nil
+		if (network == "tcp4" && !ipv4) || (network == "tcp6" && ipv4) {
+			continue
+		}
+		conn, err := dialInNamespace(ctx, d.dialer, namespacePath(d.namespace), network, net.JoinHostPort(addr.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = &net.OpError{Op: "dial", Net: network, Err: &net.AddrError{Err: "no suitable address found", Addr: host}}
+	}
+	return nil, firstErr
+}
+
+// applyNetwork binds the dialer to the VRF, and returns the dialer opening
+// the connections in the network namespace. It should be called after
+// applyPolicy, the target policy being kept.
+func applyNetwork(dialer *net.Dialer, namespace string, vrf string) contextDialer {
+	if vrf != "" {
+		dialer.Control = bindToDevice(vrf, dialer.Control)
+	}
+	if namespace != "" {
+		return &namespaceDialer{dialer: dialer, namespace: namespace}
+	}
+	return dialer
+}
@@ -0,0 +1,83 @@
+//go:build linux
+
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// bindToDevice returns a dialer control binding the sockets to the
+// network interface or the VRF, then calling the next control
+func bindToDevice(device string, next func(string, string, syscall.RawConn) error) func(string, string, syscall.RawConn) error {
+	return func(network string, address string, c syscall.RawConn) error {
+		var bindErr error
+		err := c.Control(func(fd uintptr) {
+			bindErr = unix.BindToDevice(int(fd), device)
+		})
+		if err != nil {
+			return err
+		}
+		if bindErr != nil {
+			return errors.Wrapf(bindErr, "Fail to bind the connection to %s", device)
+		}
+		if next != nil {
+			return next(network, address, c)
+		}
+		return nil
+	}
+}
+
+// dialInNamespace connects to the address in the network namespace. The
+// socket is created by a goroutine locked on a thread moved to the
+// namespace, the thread being terminated if its namespace can not be
+// restored.
+func dialInNamespace(ctx context.Context, dialer *net.Dialer, path string, network string, address string) (net.Conn, error) {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		runtime.LockOSThread()
+		current, err := os.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", unix.Gettid()))
+		if err != nil {
+			runtime.UnlockOSThread()
+			ch <- result{err: errors.Wrap(err, "Fail to open the current network namespace")}
+			return
+		}
+		defer current.Close()
+		target, err := os.Open(path)
+		if err != nil {
+			runtime.UnlockOSThread()
+			ch <- result{err: errors.Wrapf(err, "Fail to open the network namespace %s", path)}
+			return
+		}
+		defer target.Close()
+		if err := unix.Setns(int(target.Fd()), unix.CLONE_NEWNET); err != nil {
+			runtime.UnlockOSThread()
+			ch <- result{err: errors.Wrapf(err, "Fail to enter the network namespace %s", path)}
+			return
+		}
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err := unix.Setns(int(current.Fd()), unix.CLONE_NEWNET); err != nil {
+			// the thread stays locked, and is terminated with the goroutine
+			if conn != nil {
+				conn.Close()
+			}
+			ch <- result{err: errors.Wrap(err, "Fail to restore the network namespace")}
+			return
+		}
+		runtime.UnlockOSThread()
+		ch <- result{conn: conn, err: err}
+	}()
+	r := <-ch
+	return r.conn, r.err
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// errNetworkUnsupported the network namespaces and the VRFs are only
+// supported on Linux
+var errNetworkUnsupported = errors.New("The network namespaces and the VRFs are only supported on Linux")
+
+// bindToDevice returns a dialer control failing, the sockets can not be
+// bound to a device
+func bindToDevice(device string, next func(string, string, syscall.RawConn) error) func(string, string, syscall.RawConn) error {
+	return func(network string, address string, c syscall.RawConn) error {
+		return errNetworkUnsupported
+	}
+}
+
+// dialInNamespace fails, the network namespaces being unsupported
+func dialInNamespace(ctx context.Context, dialer *net.Dialer, path string, network string, address string) (net.Conn, error) {
+	return nil, errNetworkUnsupported
+}
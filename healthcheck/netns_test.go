@@ -0,0 +1,105 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestValidateNetwork(t *testing.T) {
+	cases := []struct {
+		namespace string
+		vrf       string
+		valid     bool
+	}{
+		{valid: true},
+		{namespace: "wg0", valid: true},
+		{namespace: "/proc/1/ns/net", vrf: "vrf-blue", valid: true},
+		{namespace: "foo/bar", valid: false},
+		{namespace: "..", valid: false},
+		{vrf: "averyveryverylongname", valid: false},
+		{vrf: "vrf blue", valid: false},
+	}
+	for _, c := range cases {
+		err := validateNetwork(c.namespace, c.vrf)
+		if runtime.GOOS != "linux" && (c.namespace != "" || c.vrf != "") {
+			if err == nil {
+				t.Fatalf("The network namespaces should not be supported on %s", runtime.GOOS)
+			}
+			continue
+		}
+		if c.valid && err != nil {
+			t.Fatalf("Unexpected error for %s %s:\n%v", c.namespace, c.vrf, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Was expecting an error for %s %s", c.namespace, c.vrf)
+		}
+	}
+}
+
+func TestTCPHealthcheckNetwork(t *testing.T) {
+	if runtime.GOOS != "linux" || os.Geteuid() != 0 {
+		t.Skip("the network namespaces and the VRFs require Linux and the root user")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the listener: %s", err.Error())
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+	cases := []struct {
+		namespace string
+		vrf       string
+		success   bool
+	}{
+		// the network namespace of the daemon
+		{namespace: "/proc/self/ns/net", success: true},
+		{namespace: "/proc/self/ns/net", vrf: "lo", success: true},
+		{vrf: "lo", success: true},
+		{vrf: "unknown0", success: false},
+		{namespace: "cabourotte-unknown", success: false},
+	}
+	for _, c := range cases {
+		h := NewTCPHealthcheck(zap.NewExample(), &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Minute),
+			},
+			Target:           "localhost",
+			Port:             uint(port),
+			Timeout:          Duration(time.Second),
+			IPVersion:        4,
+			NetworkNamespace: c.namespace,
+			VRF:              c.vrf,
+		})
+		err := h.Config.Validate()
+		if err != nil {
+			t.Fatalf("Validation error :\n%v", err)
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		err = h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("Unexpected error for %s %s:\n%v", c.namespace, c.vrf, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %s %s", c.namespace, c.vrf)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package healthcheck
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// nodeLabelRegexp the valid node labels names, which are also used in the
+// metrics labels
+var nodeLabelRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Node the identity of the Cabourotte node executing the healthchecks,
+// attached to the exported results and metrics
+type Node struct {
+	Name   string            `json:"name"`
+	Region string            `json:"region,omitempty"`
+	Zone   string            `json:"zone,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// UnmarshalYAML parses the node configuration from YAML. The name
+// defaults to the hostname.
+func (node *Node) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawNode Node
+	raw := rawNode{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the node configuration")
+	}
+	if raw.Name == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return errors.Wrap(err, "Fail to get the hostname, the node name should be configured")
+		}
+		raw.Name = hostname
+	}
+	for key := range raw.Labels {
+		if !nodeLabelRegexp.MatchString(key) {
+			return fmt.Errorf("Invalid node label %s, the labels should match %s", key, nodeLabelRegexp.String())
+		}
+	}
+	*node = Node(raw)
+	return nil
+}
+
+// MetricsLabels returns the labels added to all the metrics. The labels
+// are prefixed by node in order to not conflict with the healthchecks
+// labels.
+func (node *Node) MetricsLabels() map[string]string {
+	labels := map[string]string{"node": node.Name}
+	if node.Region != "" {
+		labels["node_region"] = node.Region
+	}
+	if node.Zone != "" {
+		labels["node_zone"] = node.Zone
+	}
+	for key, value := range node.Labels {
+		labels["node_"+key] = value
+	}
+	return labels
+}
+
+// Attributes returns the node metadata as attributes, for the exporters
+// not supporting nested fields
+func (node *Node) Attributes() map[string]string {
+	attributes := map[string]string{"node": node.Name}
+	if node.Region != "" {
+		attributes["node-region"] = node.Region
+	}
+	if node.Zone != "" {
+		attributes["node-zone"] = node.Zone
+	}
+	for key, value := range node.Labels {
+		attributes["node-"+key] = value
+	}
+	return attributes
+}
@@ -0,0 +1,137 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// decodeOverride decodes the overridden configuration of an healthcheck
+// and validates it
+func decodeOverride(content []byte, config interface{ Validate() error }) error {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return errors.Wrap(err, "Invalid overrides")
+	}
+	if err := config.Validate(); err != nil {
+		return errors.Wrap(err, "Invalid healthcheck configuration")
+	}
+	return nil
+}
+
+// Override returns a copy of the healthcheck whose configuration fields
+// are replaced by the overrides, for example to execute the healthcheck
+// once on another target. The overrides use the JSON format of the
+// configuration. The name and the namespace cannot be overridden.
+func Override(check Healthcheck, overrides map[string]interface{}) (Healthcheck, error) {
+	content, err := json.Marshal(check.GetConfig())
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to serialize the healthcheck configuration")
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, errors.Wrap(err, "Fail to read the healthcheck configuration")
+	}
+	for key, value := range overrides {
+		if key == "name" || key == "namespace" {
+			return nil, fmt.Errorf("The healthcheck %s cannot be overridden", key)
+		}
+		fields[key] = value
+	}
+	content, err = json.Marshal(fields)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to serialize the overridden configuration")
+	}
+	switch h := check.(type) {
+	case *CommandHealthcheck:
+		var config CommandHealthcheckConfiguration
+		if err := decodeOverride(content, &config); err != nil {
+			return nil, err
+		}
+		return NewCommandHealthcheck(h.Logger, &config), nil
+	case *DNSHealthcheck:
+		var config DNSHealthcheckConfiguration
+		if err := decodeOverride(content, &config); err != nil {
+			return nil, err
+		}
+		return NewDNSHealthcheck(h.Logger, &config), nil
+	case *TCPHealthcheck:
+		var config TCPHealthcheckConfiguration
+		if err := decodeOverride(content, &config); err != nil {
+			return nil, err
+		}
+		return NewTCPHealthcheck(h.Logger, &config), nil
+	case *HTTPHealthcheck:
+		var config HTTPHealthcheckConfiguration
+		if err := decodeOverride(content, &config); err != nil {
+			return nil, err
+		}
+		return NewHTTPHealthcheck(h.Logger, &config), nil
+	case *TLSHealthcheck:
+		var config TLSHealthcheckConfiguration
+		if err := decodeOverride(content, &config); err != nil {
+			return nil, err
+		}
+		return NewTLSHealthcheck(h.Logger, &config), nil
+	case *HeartbeatHealthcheck:
+		var config HeartbeatHealthcheckConfiguration
+		if err := decodeOverride(content, &config); err != nil {
+			return nil, err
+		}
+		return NewHeartbeatHealthcheck(h.Logger, &config), nil
+	case *GRPCHealthcheck:
+		var config GRPCHealthcheckConfiguration
+		if err := decodeOverride(content, &config); err != nil {
+			return nil, err
+		}
+		return NewGRPCHealthcheck(h.Logger, &config), nil
+	case *ProfileHealthcheck:
+		var config ProfileHealthcheckConfiguration
+		if err := decodeOverride(content, &config); err != nil {
+			return nil, err
+		}
+		return NewProfileHealthcheck(h.Logger, &config), nil
+	}
+	return nil, fmt.Errorf("The healthcheck %s cannot be overridden", check.Base().Name)
+}
+
+// ExecuteOnce executes an healthcheck once, outside of the scheduler,
+// with the credentials, the target policy, the proxy, the DNS cache and
+// the bandwidth limit of the component. The healthcheck is not registered
+// and its result is not exported.
+func (c *Component) ExecuteOnce(ctx context.Context, check Healthcheck) (*Result, error) {
+	if err := c.ResolveCredential(check); err != nil {
+		return nil, err
+	}
+	if policy := c.getPolicy(); policy != nil {
+		if err := policy.checkTarget(check); err != nil {
+			return nil, errors.Wrapf(err, "Invalid target for the healthcheck %s", check.Base().Name)
+		}
+		for _, member := range members(check) {
+			if err := policy.checkTarget(member.healthcheck); err != nil {
+				return nil, errors.Wrapf(err, "Invalid target for the healthcheck %s", check.Base().Name)
+			}
+		}
+		ctx = withPolicy(ctx, policy)
+	}
+	if policy := c.getCommandPolicy(); policy != nil {
+		if err := policy.checkCommand(check); err != nil {
+			return nil, errors.Wrapf(err, "Invalid command for the healthcheck %s", check.Base().Name)
+		}
+		ctx = withCommandPolicy(ctx, policy)
+	}
+	if proxy := c.getProxy(); proxy != nil {
+		ctx = withProxy(ctx, proxy)
+	}
+	if limiter := c.getLimiter(); limiter != nil {
+		ctx = withLimiter(ctx, limiter)
+	}
+	if resolver := c.getResolver(); resolver != nil {
+		ctx = withResolver(ctx, resolver)
+	}
+	return ExecuteOnce(ctx, check), nil
+}
@@ -0,0 +1,46 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestOverride(t *testing.T) {
+	config := TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(10 * time.Second),
+			Labels:   map[string]string{"env": "prod"},
+		},
+		Target:  "127.0.0.1",
+		Port:    22,
+		Timeout: Duration(3 * time.Second),
+	}
+	check := NewTCPHealthcheck(zap.NewExample(), &config)
+	overridden, err := Override(check, map[string]interface{}{"target": "127.0.0.2", "timeout": "1s"})
+	if err != nil {
+		t.Fatalf("Fail to override the healthcheck: %s", err.Error())
+	}
+	result := overridden.GetConfig().(*TCPHealthcheckConfiguration)
+	if result.Target != "127.0.0.2" || result.Timeout != Duration(time.Second) {
+		t.Fatalf("The fields were not overridden: %+v", result)
+	}
+	if result.Port != 22 || result.Base.Name != "foo" || result.Base.Labels["env"] != "prod" {
+		t.Fatalf("The other fields should be kept: %+v", result)
+	}
+	if config.Target != "127.0.0.1" {
+		t.Fatalf("The original healthcheck was modified")
+	}
+	for _, overrides := range []map[string]interface{}{
+		{"name": "bar"},
+		{"namespace": "bar"},
+		{"unknown": "bar"},
+		{"port": 0},
+	} {
+		if _, err := Override(check, overrides); err == nil {
+			t.Fatalf("Was expecting an error for the overrides %v", overrides)
+		}
+	}
+}
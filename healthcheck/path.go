@@ -0,0 +1,105 @@
+package healthcheck
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// PathLabel the label containing the path of the results of multi-path
+// healthchecks
+const PathLabel = "path"
+
+// pathRegexp the valid paths names
+var pathRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Path a network path used to execute an healthcheck. Multi-path
+// healthchecks are executed simultaneously on all their paths, in order to
+// distinguish a target failure from a path failure on multi-homed nodes.
+type Path struct {
+	Name string `json:"name"`
+	// SourceIP the source IP of the path
+	SourceIP IP `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
+	// IPVersion restricts the path to the IPv4 (4) or IPv6 (6) addresses
+	// of the target
+	IPVersion uint `json:"ip-version,omitempty" yaml:"ip-version,omitempty"`
+}
+
+// PathName returns the name of the healthcheck executed on a path of a
+// multi-path healthcheck
+func PathName(name string, path string) string {
+	return fmt.Sprintf("%s-%s", name, path)
+}
+
+// apply returns the base configuration of the healthcheck executed on the
+// path, labelled with the path name
+func (p *Path) apply(base Base) Base {
+	labels := make(map[string]string, len(base.Labels)+1)
+	for k, v := range base.Labels {
+		labels[k] = v
+	}
+	labels[PathLabel] = p.Name
+	base.Name = PathName(base.Name, p.Name)
+	base.Labels = labels
+	return base
+}
+
+// validateIPVersion validates an IP version
+func validateIPVersion(version uint) error {
+	if version != 0 && version != 4 && version != 6 {
+		return fmt.Errorf("Invalid IP version %d, it should be 4 or 6", version)
+	}
+	return nil
+}
+
+// network returns the network dialed for the IP version
+func network(version uint) string {
+	switch version {
+	case 4:
+		return "tcp4"
+	case 6:
+		return "tcp6"
+	}
+	return "tcp"
+}
+
+// validatePaths validates the paths of an healthcheck
+func validatePaths(paths []Path, targets []string, sourceIP IP, ipVersion uint) error {
+	if err := validateIPVersion(ipVersion); err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	if len(targets) != 0 {
+		return errors.New("The healthcheck targets and paths are mutually exclusive")
+	}
+	if sourceIP != nil || ipVersion != 0 {
+		return errors.New("The healthcheck source-ip and ip-version should be set on the paths")
+	}
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if !pathRegexp.MatchString(path.Name) {
+			return fmt.Errorf("Invalid path name '%s'", path.Name)
+		}
+		if seen[path.Name] {
+			return fmt.Errorf("The healthcheck path %s is duplicated", path.Name)
+		}
+		seen[path.Name] = true
+		if err := validateIPVersion(path.IPVersion); err != nil {
+			return errors.Wrapf(err, "Invalid path %s", path.Name)
+		}
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Path) DeepCopyInto(out *Path) {
+	*out = *in
+	if in.SourceIP != nil {
+		in, out := &in.SourceIP, &out.SourceIP
+		*out = make(IP, len(*in))
+		copy(*out, *in)
+	}
+}
@@ -0,0 +1,111 @@
+package healthcheck
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestValidatePaths(t *testing.T) {
+	cases := []struct {
+		paths     []Path
+		targets   []string
+		sourceIP  IP
+		ipVersion uint
+		valid     bool
+	}{
+		{valid: true},
+		{ipVersion: 6, valid: true},
+		{ipVersion: 5, valid: false},
+		{paths: []Path{{Name: "v4", IPVersion: 4}, {Name: "v6", IPVersion: 6}}, valid: true},
+		{paths: []Path{{Name: "eth0", SourceIP: IP(net.ParseIP("10.0.0.1"))}}, valid: true},
+		{paths: []Path{{Name: "v4"}, {Name: "v4"}}, valid: false},
+		{paths: []Path{{Name: ""}}, valid: false},
+		{paths: []Path{{Name: "a b"}}, valid: false},
+		{paths: []Path{{Name: "v4", IPVersion: 3}}, valid: false},
+		{paths: []Path{{Name: "v4"}}, targets: []string{"127.0.0.1"}, valid: false},
+		{paths: []Path{{Name: "v4"}}, sourceIP: IP(net.ParseIP("10.0.0.1")), valid: false},
+		{paths: []Path{{Name: "v4"}}, ipVersion: 4, valid: false},
+	}
+	for i, c := range cases {
+		err := validatePaths(c.paths, c.targets, c.sourceIP, c.ipVersion)
+		if c.valid && err != nil {
+			t.Fatalf("Unexpected error for the case %d:\n%v", i, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Was expecting an error for the case %d", i)
+		}
+	}
+}
+
+func TestExecutePaths(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	config := &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(5 * time.Second),
+			Labels:   map[string]string{"team": "a"},
+		},
+		Target:  "127.0.0.1",
+		Paths:   []Path{{Name: "v4", IPVersion: 4}, {Name: "v6", IPVersion: 6}},
+		Port:    uint(port),
+		Timeout: Duration(2 * time.Second),
+	}
+	wrapper := NewWrapper(NewTCPHealthcheck(logger, config))
+	err = wrapper.initialize()
+	if err != nil {
+		t.Fatalf("Fail to initialize the healthcheck\n%v", err)
+	}
+	results := component.executeTargets(wrapper)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	if results[0].Name != "foo-v4" || results[0].Labels[PathLabel] != "v4" || results[0].Labels["team"] != "a" || !results[0].Success {
+		t.Fatalf("Invalid path result %+v", results[0])
+	}
+	if results[1].Name != "foo-v6" || results[1].Labels[PathLabel] != "v6" || results[1].Success {
+		t.Fatalf("Invalid path result %+v", results[1])
+	}
+	if _, ok := config.Base.Labels[PathLabel]; ok {
+		t.Fatalf("The labels of the healthcheck should not be modified")
+	}
+	// the target is healthy if it is reachable on a path
+	if !results[2].Success {
+		t.Fatalf("Invalid aggregated result %+v", results[2])
+	}
+
+	config.Paths = []Path{{Name: "v6", IPVersion: 6}}
+	wrapper = NewWrapper(NewTCPHealthcheck(logger, config))
+	err = wrapper.initialize()
+	if err != nil {
+		t.Fatalf("Fail to initialize the healthcheck\n%v", err)
+	}
+	results = component.executeTargets(wrapper)
+	if results[1].Success || !strings.Contains(results[1].Message, "failed on all the paths (v6)") {
+		t.Fatalf("Invalid aggregated result %+v", results[1])
+	}
+}
@@ -0,0 +1,128 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Snapshot contains the configuration of healthchecks, used to persist
+// the healthchecks added through the API
+type Snapshot struct {
+	CommandChecks []CommandHealthcheckConfiguration `json:"command-checks"`
+	DNSChecks     []DNSHealthcheckConfiguration     `json:"dns-checks"`
+	TCPChecks     []TCPHealthcheckConfiguration     `json:"tcp-checks"`
+	HTTPChecks    []HTTPHealthcheckConfiguration    `json:"http-checks"`
+	TLSChecks     []TLSHealthcheckConfiguration     `json:"tls-checks"`
+	// HeartbeatChecks the heartbeat healthchecks
+	HeartbeatChecks []HeartbeatHealthcheckConfiguration `json:"heartbeat-checks,omitempty"`
+	// GRPCChecks the gRPC healthchecks
+	GRPCChecks []GRPCHealthcheckConfiguration `json:"grpc-checks,omitempty"`
+	// ProfileChecks the profile healthchecks
+	ProfileChecks []ProfileHealthcheckConfiguration `json:"profile-checks,omitempty"`
+}
+
+// snapshot builds a snapshot of the healthchecks managed by the given source
+func (c *Component) snapshot(source string) Snapshot {
+	snapshot := Snapshot{}
+	for _, check := range c.ListChecks() {
+		if check.Base().Source != source {
+			continue
+		}
+		switch config := check.GetConfig().(type) {
+		case *CommandHealthcheckConfiguration:
+			snapshot.CommandChecks = append(snapshot.CommandChecks, *config)
+		case *DNSHealthcheckConfiguration:
+			snapshot.DNSChecks = append(snapshot.DNSChecks, *config)
+		case *TCPHealthcheckConfiguration:
+			snapshot.TCPChecks = append(snapshot.TCPChecks, *config)
+		case *HTTPHealthcheckConfiguration:
+			snapshot.HTTPChecks = append(snapshot.HTTPChecks, *config)
+		case *TLSHealthcheckConfiguration:
+			snapshot.TLSChecks = append(snapshot.TLSChecks, *config)
+		case *HeartbeatHealthcheckConfiguration:
+			snapshot.HeartbeatChecks = append(snapshot.HeartbeatChecks, *config)
+		case *GRPCHealthcheckConfiguration:
+			snapshot.GRPCChecks = append(snapshot.GRPCChecks, *config)
+		case *ProfileHealthcheckConfiguration:
+			snapshot.ProfileChecks = append(snapshot.ProfileChecks, *config)
+		}
+	}
+	return snapshot
+}
+
+// persist writes the healthchecks added through the API in the
+// persistence file, if configured
+func (c *Component) persist() error {
+	c.persistLock.Lock()
+	defer c.persistLock.Unlock()
+	if c.persistencePath == "" {
+		return nil
+	}
+	content, err := json.Marshal(c.snapshot(SourceAPI))
+	if err != nil {
+		return errors.Wrap(err, "Fail to serialize the healthchecks")
+	}
+	// the file is replaced atomically
+	tmpFile, err := os.CreateTemp(filepath.Dir(c.persistencePath), ".cabourotte-checks-*")
+	if err != nil {
+		return errors.Wrap(err, "Fail to create the healthchecks persistence file")
+	}
+	_, err = tmpFile.Write(content)
+	if err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return errors.Wrap(err, "Fail to write the healthchecks persistence file")
+	}
+	err = tmpFile.Close()
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return errors.Wrap(err, "Fail to write the healthchecks persistence file")
+	}
+	err = os.Rename(tmpFile.Name(), c.persistencePath)
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return errors.Wrap(err, "Fail to write the healthchecks persistence file")
+	}
+	return nil
+}
+
+// EnablePersistence restores the healthchecks persisted in the given file,
+// and persists the healthchecks added or removed through the API from now on.
+// Restored healthchecks keep the API source, and are not impacted by
+// configuration reloads.
+func (c *Component) EnablePersistence(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "Fail to read the healthchecks persistence file %s", path)
+	}
+	if err == nil {
+		var snapshot Snapshot
+		err = json.Unmarshal(content, &snapshot)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to read the healthchecks persistence file %s", path)
+		}
+		c.Logger.Info(fmt.Sprintf("Restoring healthchecks from %s", path))
+		err = c.ReloadForSource(
+			SourceAPI,
+			nil,
+			snapshot.CommandChecks,
+			snapshot.DNSChecks,
+			snapshot.TCPChecks,
+			snapshot.HTTPChecks,
+			snapshot.TLSChecks,
+			snapshot.HeartbeatChecks,
+			snapshot.GRPCChecks,
+			snapshot.ProfileChecks)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to restore the healthchecks from %s", path)
+		}
+	}
+	c.persistLock.Lock()
+	c.persistencePath = path
+	c.persistLock.Unlock()
+	return nil
+}
@@ -0,0 +1,156 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+const (
+	// PhaseDNS the DNS resolution phase
+	PhaseDNS = "dns"
+	// PhaseConnect the connection phase
+	PhaseConnect = "connect"
+	// PhaseTLS the TLS handshake phase
+	PhaseTLS = "tls"
+	// PhaseFirstByte the phase between the request being sent and the
+	// first byte of the response
+	PhaseFirstByte = "first-byte"
+	// PhaseBody the response body read phase
+	PhaseBody = "body"
+)
+
+// Phases the phases names, in execution order
+var Phases = []string{PhaseDNS, PhaseConnect, PhaseTLS, PhaseFirstByte, PhaseBody}
+
+// Phase a phase of an healthcheck execution
+type Phase struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+// phasesKey the context key of the phases recorder
+type phasesKey struct{}
+
+// phases records the phases of an healthcheck execution
+type phases struct {
+	lock   sync.Mutex
+	phases []Phase
+}
+
+// withPhases returns a context recording the phases of the executions
+func withPhases(ctx context.Context) (context.Context, *phases) {
+	recorder := &phases{}
+	return context.WithValue(ctx, phasesKey{}, recorder), recorder
+}
+
+// phasesFromContext returns the phases recorder of the context, or nil
+func phasesFromContext(ctx context.Context) *phases {
+	recorder, _ := ctx.Value(phasesKey{}).(*phases)
+	return recorder
+}
+
+// add records a phase
+func (p *phases) add(name string, start time.Time, end time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.phases = append(p.phases, Phase{Name: name, Start: start, End: end})
+}
+
+// reset removes the recorded phases, in order to only keep the phases of
+// the last attempt
+func (p *phases) reset() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.phases = nil
+}
+
+// list returns the recorded phases
+func (p *phases) list() []Phase {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	result := make([]Phase, len(p.phases))
+	copy(result, p.phases)
+	return result
+}
+
+// phasesDurations returns the durations in milliseconds of the phases.
+// The durations of the phases with the same name are added.
+func phasesDurations(phases []Phase) map[string]float64 {
+	if len(phases) == 0 {
+		return nil
+	}
+	result := make(map[string]float64, len(phases))
+	for _, phase := range phases {
+		result[phase.Name] += float64(phase.End.Sub(phase.Start).Microseconds()) / 1000
+	}
+	return result
+}
+
+// recordPhase records a phase if the context has a phases recorder
+func recordPhase(ctx context.Context, name string, start time.Time, end time.Time) {
+	if recorder := phasesFromContext(ctx); recorder != nil {
+		recorder.add(name, start, end)
+	}
+}
+
+// httpTrace returns a context recording the phases of an HTTP request if
+// the context has a phases recorder
+func httpTrace(ctx context.Context) context.Context {
+	recorder := phasesFromContext(ctx)
+	if recorder == nil {
+		return ctx
+	}
+	// the connections to several addresses can be attempted concurrently
+	var lock sync.Mutex
+	var dnsStart, tlsStart, wroteRequest time.Time
+	connectStart := make(map[string]time.Time)
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			lock.Lock()
+			defer lock.Unlock()
+			dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			lock.Lock()
+			defer lock.Unlock()
+			recorder.add(PhaseDNS, dnsStart, time.Now())
+		},
+		ConnectStart: func(network, addr string) {
+			lock.Lock()
+			defer lock.Unlock()
+			connectStart[network+addr] = time.Now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			if err == nil {
+				recorder.add(PhaseConnect, connectStart[network+addr], time.Now())
+			}
+		},
+		TLSHandshakeStart: func() {
+			lock.Lock()
+			defer lock.Unlock()
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			recorder.add(PhaseTLS, tlsStart, time.Now())
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			lock.Lock()
+			defer lock.Unlock()
+			wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			lock.Lock()
+			defer lock.Unlock()
+			recorder.add(PhaseFirstByte, wroteRequest, time.Now())
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace)
+}
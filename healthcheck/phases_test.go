@@ -0,0 +1,37 @@
+package healthcheck
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPhasesDurations(t *testing.T) {
+	if phasesDurations(nil) != nil {
+		t.Fatalf("The durations should be nil without phases")
+	}
+	start := time.Now()
+	durations := phasesDurations([]Phase{
+		{Name: PhaseConnect, Start: start, End: start.Add(1500 * time.Microsecond)},
+		{Name: PhaseTLS, Start: start, End: start.Add(2 * time.Millisecond)},
+		{Name: PhaseConnect, Start: start, End: start.Add(time.Millisecond)},
+	})
+	if len(durations) != 2 || durations[PhaseConnect] != 2.5 || durations[PhaseTLS] != 2 {
+		t.Fatalf("Invalid durations: %v", durations)
+	}
+}
+
+func TestRecordPhase(t *testing.T) {
+	start := time.Now()
+	// no recorder in the context
+	recordPhase(context.Background(), PhaseDNS, start, start)
+	ctx, recorder := withPhases(context.Background())
+	recordPhase(ctx, PhaseDNS, start, start.Add(time.Millisecond))
+	if len(recorder.list()) != 1 || recorder.list()[0].Name != PhaseDNS {
+		t.Fatalf("Invalid phases: %v", recorder.list())
+	}
+	recorder.reset()
+	if len(recorder.list()) != 0 {
+		t.Fatalf("The phases should be removed: %v", recorder.list())
+	}
+}
@@ -0,0 +1,165 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// linkLocalNetworks the link-local networks, including the cloud
+// providers metadata services
+var linkLocalNetworks = []string{
+	"169.254.0.0/16",
+	"fe80::/10",
+	"fd00:ec2::254/128",
+}
+
+// TargetPolicy restricts the addresses the healthcheck targets can resolve
+// to. The denied networks take precedence over the allowed ones.
+type TargetPolicy struct {
+	// Allow the allowed networks, all the addresses being allowed if empty
+	Allow []string
+	// Deny the denied networks
+	Deny []string
+	// DenyLinkLocal denies the link-local addresses, including the cloud
+	// providers metadata services
+	DenyLinkLocal bool `yaml:"deny-link-local"`
+
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// parseNetwork parses a CIDR or an IP address
+func parseNetwork(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("Invalid network %s", value)
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid network %s", value)
+	}
+	return network, nil
+}
+
+// parseNetworks parses a list of CIDRs or IP addresses
+func parseNetworks(values []string) ([]*net.IPNet, error) {
+	result := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		network, err := parseNetwork(value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, network)
+	}
+	return result, nil
+}
+
+// Initialize parses the networks of the policy
+func (p *TargetPolicy) Initialize() error {
+	allow, err := parseNetworks(p.Allow)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid allowed networks")
+	}
+	deny, err := parseNetworks(p.Deny)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid denied networks")
+	}
+	if p.DenyLinkLocal {
+		linkLocal, err := parseNetworks(linkLocalNetworks)
+		if err != nil {
+			return err
+		}
+		deny = append(deny, linkLocal...)
+	}
+	p.allow = allow
+	p.deny = deny
+	return nil
+}
+
+// UnmarshalYAML parses the targets policy from YAML
+func (p *TargetPolicy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawPolicy TargetPolicy
+	raw := rawPolicy{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the targets policy")
+	}
+	policy := TargetPolicy(raw)
+	if err := policy.Initialize(); err != nil {
+		return err
+	}
+	*p = policy
+	return nil
+}
+
+// Check returns an error if the IP address is forbidden by the policy
+func (p *TargetPolicy) Check(ip net.IP) error {
+	for _, network := range p.deny {
+		if network.Contains(ip) {
+			return withCategory(ErrorPolicy, fmt.Errorf("the address %s is denied by the targets policy (%s)", ip, network))
+		}
+	}
+	if len(p.allow) == 0 {
+		return nil
+	}
+	for _, network := range p.allow {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+	return withCategory(ErrorPolicy, fmt.Errorf("the address %s is not allowed by the targets policy", ip))
+}
+
+// control verifies the addresses effectively dialed, once the targets are
+// resolved
+func (p *TargetPolicy) control(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid address %s", address)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("Invalid address %s", address)
+	}
+	return p.Check(ip)
+}
+
+// checkTarget returns an error if the target of the healthcheck is an IP
+// address forbidden by the policy. The hostnames are verified on each
+// execution, once resolved.
+func (p *TargetPolicy) checkTarget(healthcheck Healthcheck) error {
+	ip := net.ParseIP(strings.Trim(target(healthcheck), "[]"))
+	if ip == nil {
+		return nil
+	}
+	return p.Check(ip)
+}
+
+// policyKey the context key of the targets policy
+type policyKey struct{}
+
+// withPolicy returns a context containing the targets policy
+func withPolicy(ctx context.Context, policy *TargetPolicy) context.Context {
+	return context.WithValue(ctx, policyKey{}, policy)
+}
+
+// applyPolicy configures the dialer to verify the dialed addresses against
+// the targets policy of the context, if any
+func applyPolicy(ctx context.Context, dialer *net.Dialer) {
+	policy, _ := ctx.Value(policyKey{}).(*TargetPolicy)
+	if policy != nil {
+		dialer.Control = policy.control
+	}
+}
@@ -0,0 +1,124 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+func TestTargetPolicyCheck(t *testing.T) {
+	cases := []struct {
+		config  string
+		ip      string
+		allowed bool
+	}{
+		{config: "deny-link-local: false", ip: "169.254.169.254", allowed: true},
+		{config: "deny-link-local: true", ip: "169.254.169.254", allowed: false},
+		{config: "deny-link-local: true", ip: "fe80::1", allowed: false},
+		{config: "deny-link-local: true", ip: "10.0.0.1", allowed: true},
+		{config: "allow: [10.0.0.0/8]", ip: "10.0.0.1", allowed: true},
+		{config: "allow: [10.0.0.0/8]", ip: "192.168.0.1", allowed: false},
+		{config: "allow: [192.168.0.1]", ip: "192.168.0.1", allowed: true},
+		{config: "allow: [10.0.0.0/8]\ndeny: [10.0.0.0/24]", ip: "10.0.0.1", allowed: false},
+		{config: "allow: [10.0.0.0/8]\ndeny: [10.0.0.0/24]", ip: "10.0.1.1", allowed: true},
+		{config: "deny: [\"::1\"]", ip: "::1", allowed: false},
+	}
+	for _, c := range cases {
+		var policy TargetPolicy
+		err := yaml.Unmarshal([]byte(c.config), &policy)
+		if err != nil {
+			t.Fatalf("Fail to parse the policy %s:\n%v", c.config, err)
+		}
+		err = policy.Check(net.ParseIP(c.ip))
+		if c.allowed && err != nil {
+			t.Fatalf("The address %s should be allowed by %s:\n%v", c.ip, c.config, err)
+		}
+		if !c.allowed {
+			if err == nil {
+				t.Fatalf("The address %s should be denied by %s", c.ip, c.config)
+			}
+			if ErrorCategory(err) != ErrorPolicy {
+				t.Fatalf("Invalid error category %s", ErrorCategory(err))
+			}
+		}
+	}
+}
+
+func TestTargetPolicyInvalid(t *testing.T) {
+	for _, config := range []string{"allow: [foo]", "deny: [10.0.0.0/33]"} {
+		var policy TargetPolicy
+		err := yaml.Unmarshal([]byte(config), &policy)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", config)
+		}
+	}
+}
+
+func TestTargetPolicyExecute(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	policy := &TargetPolicy{Deny: []string{"127.0.0.0/8"}}
+	err = policy.Initialize()
+	if err != nil {
+		t.Fatalf("Fail to initialize the policy:\n%v", err)
+	}
+	h := HTTPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &HTTPHealthcheckConfiguration{
+			ValidStatus: []uint{200},
+			Port:        uint(port),
+			Target:      "localhost",
+			Protocol:    HTTP,
+			Path:        "/",
+			Timeout:     Duration(time.Second * 2),
+		},
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	// a new transport in order to not reuse the connection
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	err = h.Execute(withPolicy(context.Background(), policy))
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if ErrorCategory(err) != ErrorPolicy {
+		t.Fatalf("Invalid error category %s: %v", ErrorCategory(err), err)
+	}
+	// the IP targets are rejected on registration
+	tcp := NewTCPHealthcheck(zap.NewExample(), &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(time.Second * 5),
+		},
+		Target:  "127.0.0.1",
+		Port:    uint(port),
+		Timeout: Duration(time.Second * 2),
+	})
+	if err := policy.checkTarget(tcp); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
@@ -0,0 +1,113 @@
+package healthcheck
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Preflight configures the verification of the CORS preflight responses.
+// The request is sent with the Origin, Access-Control-Request-Method and
+// Access-Control-Request-Headers headers, and the response should allow
+// them.
+type Preflight struct {
+	// Origin the origin of the request
+	Origin string `json:"origin"`
+	// RequestMethod the method of the actual request
+	RequestMethod string `json:"request-method,omitempty" yaml:"request-method,omitempty"`
+	// RequestHeaders the headers of the actual request
+	RequestHeaders []string `json:"request-headers,omitempty" yaml:"request-headers,omitempty"`
+	// AllowCredentials the response should allow the credentials
+	AllowCredentials bool `json:"allow-credentials,omitempty" yaml:"allow-credentials,omitempty"`
+}
+
+// Validate validates the preflight configuration
+func (p *Preflight) Validate() error {
+	if p.Origin == "" {
+		return errors.New("The healthcheck preflight origin is missing")
+	}
+	for _, header := range p.RequestHeaders {
+		if header == "" || strings.ContainsAny(header, ", ") {
+			return fmt.Errorf("Invalid healthcheck preflight request header %q", header)
+		}
+	}
+	return nil
+}
+
+// apply adds the preflight headers to the request
+func (p *Preflight) apply(req *http.Request) {
+	req.Header.Set("Origin", p.Origin)
+	if p.RequestMethod != "" {
+		req.Header.Set("Access-Control-Request-Method", p.RequestMethod)
+	}
+	if len(p.RequestHeaders) != 0 {
+		req.Header.Set("Access-Control-Request-Headers", strings.Join(p.RequestHeaders, ","))
+	}
+}
+
+// headerValues returns the values of a comma-separated list header
+func headerValues(header http.Header, name string) []string {
+	var values []string
+	for _, value := range header.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// missingValues returns the expected values which are not in the list
+// header. The values are case-insensitive, and the wildcard allows all the
+// values if accepted.
+func missingValues(header http.Header, name string, expected []string, wildcard bool) []string {
+	values := headerValues(header, name)
+	var missing []string
+	for _, e := range expected {
+		found := false
+		for _, value := range values {
+			if strings.EqualFold(value, e) || (wildcard && value == "*") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, e)
+		}
+	}
+	return missing
+}
+
+// verify verifies that the response allows the preflight request
+func (p *Preflight) verify(header http.Header) error {
+	origin := header.Get("Access-Control-Allow-Origin")
+	// the wildcards are not allowed for the requests with credentials
+	wildcard := !p.AllowCredentials
+	if origin != p.Origin && !(wildcard && origin == "*") {
+		return assertionError("the origin %s is not allowed by the preflight response (Access-Control-Allow-Origin: %q)", p.Origin, origin)
+	}
+	if p.AllowCredentials && header.Get("Access-Control-Allow-Credentials") != "true" {
+		return assertionError("the credentials are not allowed by the preflight response")
+	}
+	if p.RequestMethod != "" {
+		if missing := missingValues(header, "Access-Control-Allow-Methods", []string{p.RequestMethod}, wildcard); len(missing) != 0 {
+			return assertionError("the method %s is not allowed by the preflight response (Access-Control-Allow-Methods: %q)", p.RequestMethod, header.Get("Access-Control-Allow-Methods"))
+		}
+	}
+	if missing := missingValues(header, "Access-Control-Allow-Headers", p.RequestHeaders, wildcard); len(missing) != 0 {
+		return assertionError("the headers %s are not allowed by the preflight response (Access-Control-Allow-Headers: %q)", strings.Join(missing, ", "), header.Get("Access-Control-Allow-Headers"))
+	}
+	return nil
+}
+
+// verifyAllowedMethods verifies that the methods are listed in the Allow
+// header of the response
+func verifyAllowedMethods(header http.Header, methods []string) error {
+	if missing := missingValues(header, "Allow", methods, false); len(missing) != 0 {
+		return assertionError("the methods %s are not allowed by the response (Allow: %q)", strings.Join(missing, ", "), header.Get("Allow"))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+const (
+	// DefaultUserAgent the default User-Agent of the HTTP healthchecks
+	DefaultUserAgent = "Cabourotte"
+	// ProbeIDHeader the header containing the ID of the execution sent
+	// by the HTTP healthchecks
+	ProbeIDHeader = "X-Cabourotte-Probe-ID"
+)
+
+// probeIDKey the context key of the execution ID
+type probeIDKey struct{}
+
+// newProbeID generates a random execution ID (UUID v4)
+func newProbeID() string {
+	var b [16]byte
+	// crypto/rand never returns an error on supported platforms
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// withProbeID returns a context containing a new execution ID, the
+// retries of the execution sharing the same ID
+func withProbeID(ctx context.Context) (context.Context, string) {
+	probeID := newProbeID()
+	return context.WithValue(ctx, probeIDKey{}, probeID), probeID
+}
+
+// probeIDFromContext returns the execution ID of the context, or an empty
+// string
+func probeIDFromContext(ctx context.Context) string {
+	probeID, _ := ctx.Value(probeIDKey{}).(string)
+	return probeID
+}
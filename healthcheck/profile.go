@@ -0,0 +1,307 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// ProfileICMP sends an ICMP echo request to the target
+	ProfileICMP = "icmp"
+	// ProfileTCP opens a TCP connection to the target
+	ProfileTCP = "tcp"
+	// ProfileHTTP sends an HTTP GET request to the target
+	ProfileHTTP = "http"
+)
+
+// ProtocolLabel the label containing the protocol of the results of the
+// profile healthchecks
+const ProtocolLabel = "protocol"
+
+// ProfileHealthcheckConfiguration defines a profile healthcheck
+// configuration. The healthcheck executes simultaneously an ICMP echo, a
+// TCP connection and an HTTP GET request on the same target, and fails if
+// one of the protocols fails.
+type ProfileHealthcheckConfiguration struct {
+	Base `json:",inline" yaml:",inline"`
+	// can be an IP or a domain
+	Target  string   `json:"target"`
+	Timeout Duration `json:"timeout"`
+	// Protocols the protocols checked on the target, icmp, tcp and http by
+	// default. The field is always marshalled, the clients guessing the
+	// healthchecks types from their fields.
+	Protocols []string `json:"protocols" yaml:"protocols,omitempty"`
+	// Port the port of the TCP connection
+	Port uint `json:"port,omitempty"`
+	// IPVersion restricts the healthcheck to the IPv4 (4) or IPv6 (6)
+	// addresses of the target
+	IPVersion uint `json:"ip-version,omitempty" yaml:"ip-version,omitempty"`
+	// Privileged sends the ICMP echo requests using raw sockets instead of
+	// datagram sockets
+	Privileged bool `json:"privileged,omitempty" yaml:"privileged,omitempty"`
+	// HTTPProtocol the protocol of the HTTP request, http by default
+	HTTPProtocol Protocol `json:"http-protocol,omitempty" yaml:"http-protocol,omitempty"`
+	// HTTPPort the port of the HTTP request, 80 or 443 by default
+	HTTPPort uint `json:"http-port,omitempty" yaml:"http-port,omitempty"`
+	// HTTPPath the path of the HTTP request, / by default
+	HTTPPath string `json:"http-path,omitempty" yaml:"http-path,omitempty"`
+	// ValidStatus the valid status codes of the HTTP request, 200 by
+	// default
+	ValidStatus StatusCodes `json:"valid-status,omitempty" yaml:"valid-status,omitempty"`
+	Insecure    bool        `json:"insecure,omitempty" yaml:"insecure,omitempty"`
+}
+
+// Validate validates the healthcheck configuration
+func (config *ProfileHealthcheckConfiguration) Validate() error {
+	if config.Base.Name == "" {
+		return errors.New("The healthcheck name is missing")
+	}
+	if config.Target == "" {
+		return errors.New("The healthcheck target is missing")
+	}
+	if config.Timeout == 0 {
+		return errors.New("The healthcheck timeout is missing")
+	}
+	if err := validateIPVersion(config.IPVersion); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, protocol := range config.protocols() {
+		if protocol != ProfileICMP && protocol != ProfileTCP && protocol != ProfileHTTP {
+			return fmt.Errorf("Invalid healthcheck protocol %s, it should be icmp, tcp or http", protocol)
+		}
+		if seen[protocol] {
+			return fmt.Errorf("The healthcheck protocol %s is duplicated", protocol)
+		}
+		seen[protocol] = true
+	}
+	if seen[ProfileTCP] && config.Port == 0 {
+		return errors.New("The healthcheck port is missing")
+	}
+	if !config.Base.OneOff && config.Base.Cron == nil {
+		if config.Base.Interval < Duration(2*time.Second) {
+			return errors.New("The healthcheck interval should be greater than 2 second")
+		}
+		if config.Base.Interval < config.Timeout {
+			return errors.New("The healthcheck interval should be greater than the timeout")
+		}
+	}
+	err := config.Base.ValidateBase()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// protocols returns the protocols checked on the target, all by default
+func (config *ProfileHealthcheckConfiguration) protocols() []string {
+	if len(config.Protocols) == 0 {
+		return []string{ProfileICMP, ProfileTCP, ProfileHTTP}
+	}
+	return config.Protocols
+}
+
+// http returns the configuration of the HTTP request, with the default
+// protocol, port, path and valid status codes
+func (config *ProfileHealthcheckConfiguration) http(base Base) *HTTPHealthcheckConfiguration {
+	result := &HTTPHealthcheckConfiguration{
+		Base:        base,
+		ValidStatus: config.ValidStatus,
+		Target:      config.Target,
+		Method:      "GET",
+		Port:        config.HTTPPort,
+		Protocol:    config.HTTPProtocol,
+		Path:        config.HTTPPath,
+		IPVersion:   config.IPVersion,
+		Insecure:    config.Insecure,
+		Timeout:     config.Timeout,
+	}
+	if result.Protocol == 0 {
+		result.Protocol = HTTP
+	}
+	if result.Port == 0 {
+		result.Port = 80
+		if result.Protocol == HTTPS {
+			result.Port = 443
+		}
+	}
+	if result.Path == "" {
+		result.Path = "/"
+	}
+	if len(result.ValidStatus) == 0 {
+		result.ValidStatus = StatusCodes{200}
+	}
+	return result
+}
+
+// ProfileHealthcheck defines a profile healthcheck
+type ProfileHealthcheck struct {
+	Logger *zap.Logger
+	Config *ProfileHealthcheckConfiguration
+}
+
+// ProtocolName returns the name of the healthcheck executed on a protocol
+// of a profile healthcheck
+func ProtocolName(name string, protocol string) string {
+	return fmt.Sprintf("%s-%s", name, protocol)
+}
+
+// protocolBase returns the base configuration of the healthcheck executed
+// on the protocol, labelled with the protocol name
+func protocolBase(base Base, protocol string) Base {
+	labels := make(map[string]string, len(base.Labels)+1)
+	for k, v := range base.Labels {
+		labels[k] = v
+	}
+	labels[ProtocolLabel] = protocol
+	base.Name = ProtocolName(base.Name, protocol)
+	base.Labels = labels
+	return base
+}
+
+// members returns the healthchecks executed on each protocol
+func (h *ProfileHealthcheck) members() []*member {
+	var result []*member
+	for _, protocol := range h.Config.protocols() {
+		base := protocolBase(h.Config.Base, protocol)
+		var check Healthcheck
+		switch protocol {
+		case ProfileICMP:
+			check = &icmpHealthcheck{
+				Logger: h.Logger,
+				Config: &icmpConfiguration{
+					Base:       base,
+					Target:     h.Config.Target,
+					Timeout:    h.Config.Timeout,
+					IPVersion:  h.Config.IPVersion,
+					Privileged: h.Config.Privileged,
+				},
+			}
+		case ProfileTCP:
+			check = NewTCPHealthcheck(h.Logger, &TCPHealthcheckConfiguration{
+				Base:      base,
+				Target:    h.Config.Target,
+				Port:      h.Config.Port,
+				IPVersion: h.Config.IPVersion,
+				Timeout:   h.Config.Timeout,
+			})
+		case ProfileHTTP:
+			check = NewHTTPHealthcheck(h.Logger, h.Config.http(base))
+		default:
+			continue
+		}
+		result = append(result, &member{target: h.Config.Target, protocol: protocol, healthcheck: check})
+	}
+	return result
+}
+
+// Summary returns an healthcheck summary
+func (h *ProfileHealthcheck) Summary() string {
+	summary := ""
+	if h.Config.Base.Description != "" {
+		summary = fmt.Sprintf("profile healthcheck %s on %s", h.Config.Base.Description, h.Config.Target)
+	} else {
+		summary = fmt.Sprintf("profile healthcheck on %s", h.Config.Target)
+	}
+	return summary + fmt.Sprintf(" (%s)", strings.Join(h.Config.protocols(), ", "))
+}
+
+// Initialize the healthcheck. The healthchecks of the protocols are
+// initialized as members.
+func (h *ProfileHealthcheck) Initialize() error {
+	return nil
+}
+
+// GetConfig get the config
+func (h *ProfileHealthcheck) GetConfig() interface{} {
+	return h.Config
+}
+
+// Base get the base configuration
+func (h *ProfileHealthcheck) Base() Base {
+	return h.Config.Base
+}
+
+// Timeout returns the healthcheck timeout
+func (h *ProfileHealthcheck) Timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
+// SetSource set the healthcheck source
+func (h *ProfileHealthcheck) SetSource(source string) {
+	h.Config.Base.Source = source
+}
+
+// LogError logs an error with context
+func (h *ProfileHealthcheck) LogError(err error, message string) {
+	h.Logger.Error(err.Error(),
+		zap.String("extra", message),
+		zap.String("target", h.Config.Target),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogDebug logs a message with context
+func (h *ProfileHealthcheck) LogDebug(message string) {
+	h.Logger.Debug(message,
+		zap.String("target", h.Config.Target),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogInfo logs a message with context
+func (h *ProfileHealthcheck) LogInfo(message string) {
+	h.Logger.Info(message,
+		zap.String("target", h.Config.Target),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// Execute executes the healthcheck on all the protocols concurrently
+func (h *ProfileHealthcheck) Execute(ctx context.Context) error {
+	h.LogDebug("start executing healthcheck")
+	return executeMembers(ctx, h.members())
+}
+
+// NewProfileHealthcheck creates a profile healthcheck from a logger and a
+// configuration
+func NewProfileHealthcheck(logger *zap.Logger, config *ProfileHealthcheckConfiguration) *ProfileHealthcheck {
+	return &ProfileHealthcheck{
+		Logger: logger,
+		Config: config,
+	}
+}
+
+// MarshalJSON marshal to json a profile healthcheck
+func (h *ProfileHealthcheck) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Config)
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfileHealthcheckConfiguration) DeepCopyInto(out *ProfileHealthcheckConfiguration) {
+	*out = *in
+	in.Base.DeepCopyInto(&out.Base)
+	if in.Protocols != nil {
+		in, out := &in.Protocols, &out.Protocols
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ValidStatus != nil {
+		in, out := &in.ValidStatus, &out.ValidStatus
+		*out = make(StatusCodes, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileHealthcheckConfiguration.
+func (in *ProfileHealthcheckConfiguration) DeepCopy() *ProfileHealthcheckConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfileHealthcheckConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
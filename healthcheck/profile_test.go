@@ -0,0 +1,143 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestProfileValidate(t *testing.T) {
+	base := Base{Name: "foo", Interval: Duration(5 * time.Second)}
+	cases := []struct {
+		config ProfileHealthcheckConfiguration
+		valid  bool
+	}{
+		{config: ProfileHealthcheckConfiguration{Base: base, Target: "127.0.0.1", Port: 22, Timeout: Duration(time.Second)}, valid: true},
+		{config: ProfileHealthcheckConfiguration{Base: base, Target: "127.0.0.1", Protocols: []string{"icmp", "http"}, Timeout: Duration(time.Second)}, valid: true},
+		{config: ProfileHealthcheckConfiguration{Base: base, Target: "127.0.0.1", Timeout: Duration(time.Second)}, valid: false},
+		{config: ProfileHealthcheckConfiguration{Base: base, Port: 22, Timeout: Duration(time.Second)}, valid: false},
+		{config: ProfileHealthcheckConfiguration{Base: base, Target: "127.0.0.1", Port: 22}, valid: false},
+		{config: ProfileHealthcheckConfiguration{Base: base, Target: "127.0.0.1", Protocols: []string{"udp"}, Timeout: Duration(time.Second)}, valid: false},
+		{config: ProfileHealthcheckConfiguration{Base: base, Target: "127.0.0.1", Protocols: []string{"icmp", "icmp"}, Timeout: Duration(time.Second)}, valid: false},
+		{config: ProfileHealthcheckConfiguration{Base: base, Target: "127.0.0.1", Port: 22, IPVersion: 5, Timeout: Duration(time.Second)}, valid: false},
+	}
+	for i, c := range cases {
+		err := c.config.Validate()
+		if c.valid && err != nil {
+			t.Fatalf("Unexpected error for the case %d:\n%v", i, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Was expecting an error for the case %d", i)
+		}
+	}
+}
+
+func TestExecuteProfile(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	config := &ProfileHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(5 * time.Second),
+			Labels:   map[string]string{"team": "infra"},
+		},
+		Target:    "127.0.0.1",
+		Protocols: []string{ProfileTCP, ProfileHTTP},
+		Port:      uint(port),
+		HTTPPort:  uint(port),
+		HTTPPath:  "/health",
+		Timeout:   Duration(2 * time.Second),
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Invalid configuration\n%v", err)
+	}
+	wrapper := NewWrapper(NewProfileHealthcheck(logger, config))
+	err = wrapper.initialize()
+	if err != nil {
+		t.Fatalf("Fail to initialize the healthcheck\n%v", err)
+	}
+	results := component.executeTargets(wrapper)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for i, protocol := range config.Protocols {
+		result := results[i]
+		if result.Name != "foo-"+protocol || !result.Success || result.Labels[ProtocolLabel] != protocol || result.Labels["team"] != "infra" {
+			t.Fatalf("Invalid %s result %+v", protocol, result)
+		}
+	}
+	if results[2].Name != "foo" || !results[2].Success {
+		t.Fatalf("Invalid combined result %+v", results[2])
+	}
+	err = ExecuteWithTimeout(context.Background(), NewProfileHealthcheck(logger, config))
+	if err != nil {
+		t.Fatalf("Unexpected error\n%v", err)
+	}
+
+	config.HTTPPath = "/"
+	wrapper = NewWrapper(NewProfileHealthcheck(logger, config))
+	err = wrapper.initialize()
+	if err != nil {
+		t.Fatalf("Fail to initialize the healthcheck\n%v", err)
+	}
+	results = component.executeTargets(wrapper)
+	if !results[0].Success || results[1].Success {
+		t.Fatalf("Invalid protocols results %+v %+v", results[0], results[1])
+	}
+	if results[2].Success || !strings.Contains(results[2].Message, "1/2 protocols (http)") {
+		t.Fatalf("Invalid combined result %+v", results[2])
+	}
+	err = wrapper.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the wrapper\n%v", err)
+	}
+}
+
+func TestExecuteICMP(t *testing.T) {
+	conn, err := net.ListenPacket("ip4:icmp", "127.0.0.1")
+	if err != nil {
+		t.Skipf("ICMP raw sockets not allowed: %v", err)
+	}
+	conn.Close()
+	h := &icmpHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &icmpConfiguration{
+			Base:       Base{Name: "foo"},
+			Target:     "127.0.0.1",
+			Timeout:    Duration(2 * time.Second),
+			Privileged: true,
+		},
+	}
+	err = ExecuteWithTimeout(context.Background(), h)
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+}
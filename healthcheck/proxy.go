@@ -0,0 +1,329 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ProxyDirect the proxy of the healthchecks reaching their target
+// directly, ignoring the global proxy
+const ProxyDirect = "direct"
+
+// Proxy the proxies used by the healthchecks to reach their targets,
+// unless the healthchecks configure their own proxy
+type Proxy struct {
+	// HTTP the proxy of the HTTP healthchecks
+	HTTP string
+	// HTTPS the proxy of the HTTPS healthchecks
+	HTTPS string
+	// SOCKS the SOCKS5 proxy of the TCP and TLS healthchecks, also used by
+	// the HTTP healthchecks if no HTTP or HTTPS proxy is configured
+	SOCKS string
+	// NoProxy the targets reached directly: IP addresses, networks, and
+	// domains including their subdomains
+	NoProxy []string `yaml:"no-proxy"`
+
+	http            *url.URL
+	https           *url.URL
+	socks           *url.URL
+	noProxyNetworks []*net.IPNet
+	noProxyDomains  []string
+}
+
+// parseProxy parses a proxy URL, verifying its scheme
+func parseProxy(value string, schemes ...string) (*url.URL, error) {
+	proxy, err := url.Parse(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid proxy %s", value)
+	}
+	if proxy.Host == "" {
+		return nil, fmt.Errorf("Invalid proxy %s, the host is missing", value)
+	}
+	for _, scheme := range schemes {
+		if proxy.Scheme == scheme {
+			return proxy, nil
+		}
+	}
+	return nil, fmt.Errorf("Invalid proxy %s, the supported schemes are %s", value, strings.Join(schemes, ", "))
+}
+
+// validateProxy validates the proxy of an healthcheck
+func validateProxy(value string, schemes ...string) error {
+	if value == "" || value == ProxyDirect {
+		return nil
+	}
+	_, err := parseProxy(value, schemes...)
+	return err
+}
+
+// Initialize parses the proxies and the targets reached directly
+func (p *Proxy) Initialize() error {
+	var err error
+	if p.HTTP != "" {
+		if p.http, err = parseProxy(p.HTTP, "http", "https", "socks5"); err != nil {
+			return err
+		}
+	}
+	if p.HTTPS != "" {
+		if p.https, err = parseProxy(p.HTTPS, "http", "https", "socks5"); err != nil {
+			return err
+		}
+	}
+	if p.SOCKS != "" {
+		if p.socks, err = parseProxy(p.SOCKS, "socks5"); err != nil {
+			return err
+		}
+	}
+	p.noProxyNetworks = nil
+	p.noProxyDomains = nil
+	for _, value := range p.NoProxy {
+		if network, err := parseNetwork(value); err == nil {
+			p.noProxyNetworks = append(p.noProxyNetworks, network)
+			continue
+		}
+		domain := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(value, "*"), "."))
+		if domain == "" {
+			return fmt.Errorf("Invalid no-proxy value %s", value)
+		}
+		p.noProxyDomains = append(p.noProxyDomains, domain)
+	}
+	return nil
+}
+
+// UnmarshalYAML parses the proxy configuration from YAML
+func (p *Proxy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawProxy Proxy
+	raw := rawProxy{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the proxy configuration")
+	}
+	proxy := Proxy(raw)
+	if err := proxy.Initialize(); err != nil {
+		return err
+	}
+	*p = proxy
+	return nil
+}
+
+// bypass returns true if the host should be reached directly
+func (p *Proxy) bypass(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		for _, network := range p.noProxyNetworks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, domain := range p.noProxyDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// forScheme returns the proxy used to reach the host using the scheme
+// (http, https or tcp), or nil if the host is reached directly
+func (p *Proxy) forScheme(scheme string, host string) *url.URL {
+	if p.bypass(host) {
+		return nil
+	}
+	switch {
+	case scheme == "http" && p.http != nil:
+		return p.http
+	case scheme == "https" && p.https != nil:
+		return p.https
+	}
+	return p.socks
+}
+
+// proxyKey the context key of the global proxy
+type proxyKey struct{}
+
+// withProxy returns a context containing the global proxy
+func withProxy(ctx context.Context, proxy *Proxy) context.Context {
+	return context.WithValue(ctx, proxyKey{}, proxy)
+}
+
+// proxyURL returns the proxy used by an healthcheck to reach the host,
+// or nil if the host is reached directly. The proxy of the healthcheck
+// takes precedence over the global proxy of the context.
+func proxyURL(ctx context.Context, override string, scheme string, host string) (*url.URL, error) {
+	if override == ProxyDirect {
+		return nil, nil
+	}
+	if override != "" {
+		return url.Parse(override)
+	}
+	proxy, _ := ctx.Value(proxyKey{}).(*Proxy)
+	if proxy == nil {
+		return nil, nil
+	}
+	return proxy.forScheme(scheme, host), nil
+}
+
+// the SOCKS5 protocol constants (RFC 1928 and RFC 1929)
+const (
+	socksVersion      = 5
+	socksNoAuth       = 0
+	socksPasswordAuth = 2
+	socksNoMethod     = 0xff
+	socksConnect      = 1
+	socksIPv4         = 1
+	socksDomain       = 3
+	socksIPv6         = 4
+)
+
+// socksReplies the SOCKS5 errors
+var socksReplies = map[byte]string{
+	1: "general failure",
+	2: "connection not allowed by ruleset",
+	3: "network unreachable",
+	4: "host unreachable",
+	5: "connection refused",
+	6: "TTL expired",
+	7: "command not supported",
+	8: "address type not supported",
+}
+
+// dialSOCKS connects to the address through a SOCKS5 proxy. The target is
+// resolved by the proxy.
+func dialSOCKS(ctx context.Context, dialer contextDialer, proxy *url.URL, address string) (net.Conn, error) {
+	proxyAddress := proxy.Host
+	if proxy.Port() == "" {
+		proxyAddress = net.JoinHostPort(proxy.Hostname(), "1080")
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to connect to the SOCKS proxy %s", proxyAddress)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if err := socksHandshake(conn, proxy.User, address); err != nil {
+		conn.Close()
+		return nil, errors.Wrapf(err, "SOCKS proxy %s", proxyAddress)
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// socksHandshake authenticates on the SOCKS5 proxy and requests the
+// connection to the address
+func socksHandshake(conn net.Conn, user *url.Userinfo, address string) error {
+	host, portValue, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid address %s", address)
+	}
+	port, err := strconv.ParseUint(portValue, 10, 16)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid port %s", portValue)
+	}
+	methods := []byte{socksNoAuth}
+	if user != nil {
+		methods = append(methods, socksPasswordAuth)
+	}
+	if _, err := conn.Write(append([]byte{socksVersion, byte(len(methods))}, methods...)); err != nil {
+		return errors.Wrap(err, "fail to send the authentication methods")
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return errors.Wrap(err, "fail to read the authentication method")
+	}
+	if reply[0] != socksVersion {
+		return fmt.Errorf("invalid SOCKS version %d", reply[0])
+	}
+	switch reply[1] {
+	case socksNoAuth:
+	case socksPasswordAuth:
+		if user == nil {
+			return errors.New("the proxy requires an authentication")
+		}
+		username := user.Username()
+		password, _ := user.Password()
+		if len(username) > 255 || len(password) > 255 {
+			return errors.New("the proxy username and password should be shorter than 256 characters")
+		}
+		request := []byte{1, byte(len(username))}
+		request = append(request, username...)
+		request = append(request, byte(len(password)))
+		request = append(request, password...)
+		if _, err := conn.Write(request); err != nil {
+			return errors.Wrap(err, "fail to send the credentials")
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return errors.Wrap(err, "fail to read the authentication status")
+		}
+		if reply[1] != 0 {
+			return errors.New("authentication failed")
+		}
+	case socksNoMethod:
+		return errors.New("no acceptable authentication method")
+	default:
+		return fmt.Errorf("unsupported authentication method %d", reply[1])
+	}
+	request := []byte{socksVersion, socksConnect, 0}
+	if ip := net.ParseIP(host); ip != nil {
+		if ipv4 := ip.To4(); ipv4 != nil {
+			request = append(append(request, socksIPv4), ipv4...)
+		} else {
+			request = append(append(request, socksIPv6), ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("the host %s is too long", host)
+		}
+		request = append(append(request, socksDomain, byte(len(host))), host...)
+	}
+	request = append(request, byte(port>>8), byte(port))
+	if _, err := conn.Write(request); err != nil {
+		return errors.Wrap(err, "fail to send the connection request")
+	}
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return errors.Wrap(err, "fail to read the connection reply")
+	}
+	if header[1] != 0 {
+		message, ok := socksReplies[header[1]]
+		if !ok {
+			message = fmt.Sprintf("error %d", header[1])
+		}
+		return fmt.Errorf("connection to %s failed: %s", address, message)
+	}
+	// the address bound by the proxy is ignored
+	var length int
+	switch header[3] {
+	case socksIPv4:
+		length = net.IPv4len
+	case socksIPv6:
+		length = net.IPv6len
+	case socksDomain:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return errors.Wrap(err, "fail to read the connection reply")
+		}
+		length = int(size[0])
+	default:
+		return fmt.Errorf("invalid address type %d", header[3])
+	}
+	if _, err := io.ReadFull(conn, make([]byte, length+2)); err != nil {
+		return errors.Wrap(err, "fail to read the connection reply")
+	}
+	return nil
+}
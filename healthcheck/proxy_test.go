@@ -0,0 +1,235 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+func TestProxyForScheme(t *testing.T) {
+	config := `
+http: "http://proxy:3128"
+socks: "socks5://proxy:1080"
+no-proxy: ["10.0.0.0/8", "192.168.1.1", ".internal.example.com"]
+`
+	var proxy Proxy
+	err := yaml.Unmarshal([]byte(config), &proxy)
+	if err != nil {
+		t.Fatalf("Fail to parse the proxy:\n%v", err)
+	}
+	cases := []struct {
+		scheme string
+		host   string
+		proxy  string
+	}{
+		{scheme: "http", host: "example.com", proxy: "http://proxy:3128"},
+		{scheme: "https", host: "example.com", proxy: "socks5://proxy:1080"},
+		{scheme: "tcp", host: "example.com", proxy: "socks5://proxy:1080"},
+		{scheme: "http", host: "10.1.2.3"},
+		{scheme: "tcp", host: "192.168.1.1"},
+		{scheme: "tcp", host: "192.168.1.2", proxy: "socks5://proxy:1080"},
+		{scheme: "http", host: "internal.example.com"},
+		{scheme: "http", host: "api.INTERNAL.example.com."},
+		{scheme: "http", host: "notinternal.example.com", proxy: "http://proxy:3128"},
+	}
+	for _, c := range cases {
+		result := proxy.forScheme(c.scheme, c.host)
+		if c.proxy == "" {
+			if result != nil {
+				t.Fatalf("The host %s should be reached directly, got %s", c.host, result)
+			}
+			continue
+		}
+		if result == nil || result.String() != c.proxy {
+			t.Fatalf("Invalid proxy for %s %s: %v", c.scheme, c.host, result)
+		}
+	}
+	ctx := withProxy(context.Background(), &proxy)
+	result, err := proxyURL(ctx, ProxyDirect, "http", "example.com")
+	if err != nil || result != nil {
+		t.Fatalf("The direct healthchecks should ignore the global proxy: %v %v", result, err)
+	}
+	result, err = proxyURL(ctx, "http://other:8080", "http", "10.0.0.1")
+	if err != nil || result.String() != "http://other:8080" {
+		t.Fatalf("The proxy of the healthcheck should be used: %v %v", result, err)
+	}
+}
+
+func TestProxyInvalid(t *testing.T) {
+	for _, config := range []string{"http: \"ftp://proxy\"", "socks: \"http://proxy:3128\"", "https: \"proxy:3128\"", "no-proxy: [\".\"]"} {
+		var proxy Proxy
+		err := yaml.Unmarshal([]byte(config), &proxy)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", config)
+		}
+	}
+}
+
+// socksServer accepts SOCKS5 connections authenticated with user/pass, and
+// replies to the connection requests with the reply code. The requested
+// addresses are sent to the channel.
+func socksServer(t *testing.T, reply byte) (net.Listener, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the SOCKS server: %s", err.Error())
+	}
+	addresses := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				header := make([]byte, 2)
+				if _, err := io.ReadFull(conn, header); err != nil {
+					return
+				}
+				if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+					return
+				}
+				if _, err := conn.Write([]byte{socksVersion, socksPasswordAuth}); err != nil {
+					return
+				}
+				credentials := make([]byte, 2)
+				if _, err := io.ReadFull(conn, credentials); err != nil {
+					return
+				}
+				username := make([]byte, credentials[1]+1)
+				if _, err := io.ReadFull(conn, username); err != nil {
+					return
+				}
+				password := make([]byte, username[len(username)-1])
+				if _, err := io.ReadFull(conn, password); err != nil {
+					return
+				}
+				if string(username[:len(username)-1]) != "user" || string(password) != "pass" {
+					// nolint
+					conn.Write([]byte{1, 1})
+					return
+				}
+				if _, err := conn.Write([]byte{1, 0}); err != nil {
+					return
+				}
+				request := make([]byte, 5)
+				if _, err := io.ReadFull(conn, request); err != nil {
+					return
+				}
+				host := make([]byte, request[4]+2)
+				if _, err := io.ReadFull(conn, host); err != nil {
+					return
+				}
+				port := int(host[len(host)-2])<<8 | int(host[len(host)-1])
+				addresses <- net.JoinHostPort(string(host[:len(host)-2]), strconv.Itoa(port))
+				// nolint
+				conn.Write([]byte{socksVersion, reply, 0, socksIPv4, 127, 0, 0, 1, 0, 0})
+			}(conn)
+		}
+	}()
+	return listener, addresses
+}
+
+func TestTCPHealthcheckSOCKSProxy(t *testing.T) {
+	cases := []struct {
+		reply       byte
+		credentials string
+		err         string
+	}{
+		{reply: 0, credentials: "user:pass"},
+		{reply: 5, credentials: "user:pass", err: "connection refused"},
+		{reply: 0, credentials: "user:invalid", err: "authentication failed"},
+	}
+	for _, c := range cases {
+		listener, addresses := socksServer(t, c.reply)
+		h := NewTCPHealthcheck(zap.NewExample(), &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Minute),
+			},
+			Target:  "db.example.invalid",
+			Port:    5432,
+			Timeout: Duration(time.Second),
+		})
+		err := h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		proxy := Proxy{SOCKS: fmt.Sprintf("socks5://%s@%s", c.credentials, listener.Addr().String())}
+		err = proxy.Initialize()
+		if err != nil {
+			t.Fatalf("Fail to initialize the proxy:\n%v", err)
+		}
+		err = h.Execute(withProxy(context.Background(), &proxy))
+		listener.Close()
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("Was expecting the error %s, got %v", c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Healthcheck error :\n%v", err)
+		}
+		if address := <-addresses; address != "db.example.invalid:5432" {
+			t.Fatalf("Invalid address requested to the proxy: %s", address)
+		}
+	}
+}
+
+func TestHTTPHealthcheckProxy(t *testing.T) {
+	var requested string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the proxies receive the absolute URLs
+		requested = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	proxy := Proxy{HTTP: ts.URL}
+	err := proxy.Initialize()
+	if err != nil {
+		t.Fatalf("Fail to initialize the proxy:\n%v", err)
+	}
+	for _, override := range []string{"", ProxyDirect} {
+		h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Minute),
+			},
+			ValidStatus: []uint{200},
+			Target:      "api.example.invalid",
+			Port:        80,
+			Protocol:    HTTP,
+			Path:        "/health",
+			Timeout:     Duration(time.Second),
+			Proxy:       override,
+		})
+		err := h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		err = h.Execute(withProxy(context.Background(), &proxy))
+		if override == ProxyDirect {
+			if err == nil {
+				t.Fatalf("The direct healthcheck should not use the proxy")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Healthcheck error :\n%v", err)
+		}
+		if requested != "http://api.example.invalid:80/health" {
+			t.Fatalf("Invalid URL requested to the proxy: %s", requested)
+		}
+	}
+}
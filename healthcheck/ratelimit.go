@@ -0,0 +1,141 @@
+package healthcheck
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RateLimit limits the number of executions per second against each
+// target host, whatever the number of healthchecks targeting it. The
+// executions exceeding the limit are deferred.
+type RateLimit struct {
+	// Rate the maximum number of executions per second per target
+	Rate float64 `yaml:"rate"`
+	// Burst the number of executions allowed at once, the rate rounded up
+	// by default
+	Burst uint `yaml:"burst"`
+}
+
+// UnmarshalYAML parses the rate limit from YAML
+func (r *RateLimit) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawRateLimit RateLimit
+	raw := rawRateLimit{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the target rate limit")
+	}
+	if raw.Rate <= 0 {
+		return errors.New("The target rate limit rate should be positive")
+	}
+	if raw.Burst == 0 {
+		raw.Burst = uint(math.Ceil(raw.Rate))
+	}
+	*r = RateLimit(raw)
+	return nil
+}
+
+// targetBucket the token bucket of a target
+type targetBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// targetLimiter limits the executions per target host using a token
+// bucket per host
+type targetLimiter struct {
+	rate      float64
+	burst     float64
+	lock      sync.Mutex
+	buckets   map[string]*targetBucket
+	lastPurge time.Time
+}
+
+// newTargetLimiter creates a limiter from the rate limit configuration
+func newTargetLimiter(limit RateLimit) *targetLimiter {
+	burst := float64(limit.Burst)
+	if burst == 0 {
+		burst = math.Ceil(limit.Rate)
+	}
+	return &targetLimiter{
+		rate:    limit.Rate,
+		burst:   burst,
+		buckets: make(map[string]*targetBucket),
+	}
+}
+
+// refill adds the tokens accumulated since the last update of the bucket
+func (l *targetLimiter) refill(bucket *targetBucket, now time.Time) {
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+}
+
+// take takes the tokens of the executions against the hosts if they are
+// all available, and returns 0. Otherwise, no token is taken and the time
+// to wait before retrying is returned.
+func (l *targetLimiter) take(hosts map[string]int, now time.Time) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.purge(now)
+	var wait float64
+	for host, n := range hosts {
+		bucket, ok := l.buckets[host]
+		if !ok {
+			bucket = &targetBucket{tokens: l.burst, last: now}
+			l.buckets[host] = bucket
+		}
+		l.refill(bucket, now)
+		needed := math.Min(float64(n), l.burst)
+		if bucket.tokens < needed {
+			wait = math.Max(wait, (needed-bucket.tokens)/l.rate)
+		}
+	}
+	if wait > 0 {
+		return time.Duration(math.Ceil(wait * float64(time.Second)))
+	}
+	for host, n := range hosts {
+		l.buckets[host].tokens -= math.Min(float64(n), l.burst)
+	}
+	return 0
+}
+
+// purge removes the full buckets once per minute, in order to not keep
+// the targets of the removed healthchecks
+func (l *targetLimiter) purge(now time.Time) {
+	if now.Sub(l.lastPurge) < time.Minute {
+		return
+	}
+	for host, bucket := range l.buckets {
+		l.refill(bucket, now)
+		if bucket.tokens >= l.burst {
+			delete(l.buckets, host)
+		}
+	}
+	l.lastPurge = now
+}
+
+// targetHost returns the host targeted by an healthcheck, or an empty
+// string
+func targetHost(healthcheck Healthcheck) string {
+	return strings.ToLower(strings.Trim(target(healthcheck), "[]"))
+}
+
+// targetHosts returns the number of executions against each host for an
+// execution of the wrapper
+func targetHosts(w *Wrapper) map[string]int {
+	hosts := make(map[string]int)
+	if len(w.members) == 0 {
+		if host := targetHost(w.healthcheck); host != "" {
+			hosts[host]++
+		}
+		return hosts
+	}
+	for _, member := range w.members {
+		if host := targetHost(member.healthcheck); host != "" {
+			hosts[host]++
+		}
+	}
+	return hosts
+}
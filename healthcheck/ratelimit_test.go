@@ -0,0 +1,92 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestRateLimitUnmarshal(t *testing.T) {
+	cases := []struct {
+		config string
+		limit  RateLimit
+		err    bool
+	}{
+		{config: "rate: 2", limit: RateLimit{Rate: 2, Burst: 2}},
+		{config: "rate: 0.5", limit: RateLimit{Rate: 0.5, Burst: 1}},
+		{config: "rate: 2\nburst: 5", limit: RateLimit{Rate: 2, Burst: 5}},
+		{config: "burst: 5", err: true},
+		{config: "rate: -1", err: true},
+	}
+	for _, c := range cases {
+		var limit RateLimit
+		err := yaml.Unmarshal([]byte(c.config), &limit)
+		if c.err {
+			if err == nil {
+				t.Fatalf("Was expecting an error for the rate limit %s", c.config)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Fail to parse the rate limit %s:\n%v", c.config, err)
+		}
+		if limit != c.limit {
+			t.Fatalf("Invalid rate limit for %s: %+v", c.config, limit)
+		}
+	}
+}
+
+func TestTargetLimiterTake(t *testing.T) {
+	limiter := newTargetLimiter(RateLimit{Rate: 2, Burst: 2})
+	now := time.Now()
+	hosts := map[string]int{"a.example.com": 1}
+	for i := 0; i < 2; i++ {
+		if delay := limiter.take(hosts, now); delay != 0 {
+			t.Fatalf("Execution %d should not be deferred: %s", i, delay)
+		}
+	}
+	delay := limiter.take(hosts, now)
+	if delay != 500*time.Millisecond {
+		t.Fatalf("Invalid delay %s", delay)
+	}
+	// other targets are not limited
+	if delay := limiter.take(map[string]int{"b.example.com": 1}, now); delay != 0 {
+		t.Fatalf("The other target should not be deferred: %s", delay)
+	}
+	// no token is taken if one of the targets is limited
+	if delay := limiter.take(map[string]int{"a.example.com": 1, "c.example.com": 2}, now); delay == 0 {
+		t.Fatalf("The execution should be deferred")
+	}
+	if delay := limiter.take(map[string]int{"c.example.com": 2}, now); delay != 0 {
+		t.Fatalf("The tokens of c.example.com should be available: %s", delay)
+	}
+	if delay := limiter.take(hosts, now.Add(delay)); delay != 0 {
+		t.Fatalf("The execution should not be deferred after the delay: %s", delay)
+	}
+}
+
+func TestTargetLimiterPurge(t *testing.T) {
+	limiter := newTargetLimiter(RateLimit{Rate: 1, Burst: 1})
+	now := time.Now()
+	limiter.take(map[string]int{"a.example.com": 1}, now)
+	limiter.take(map[string]int{"b.example.com": 1}, now.Add(2*time.Minute))
+	if _, ok := limiter.buckets["a.example.com"]; ok {
+		t.Fatalf("The full bucket should be purged")
+	}
+	if _, ok := limiter.buckets["b.example.com"]; !ok {
+		t.Fatalf("The used bucket should be kept")
+	}
+}
+
+func TestTargetHosts(t *testing.T) {
+	check := NewTCPHealthcheck(nil, &TCPHealthcheckConfiguration{
+		Base:   Base{Name: "foo"},
+		Target: "Example.COM",
+		Port:   80,
+	})
+	hosts := targetHosts(NewWrapper(check))
+	if len(hosts) != 1 || hosts["example.com"] != 1 {
+		t.Fatalf("Invalid hosts %v", hosts)
+	}
+}
@@ -0,0 +1,161 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ResolutionExecution the target is resolved on each execution
+	ResolutionExecution = "execution"
+	// ResolutionRegistration the target is resolved once, on the first
+	// execution after the registration of the healthcheck
+	ResolutionRegistration = "registration"
+	// ResolutionCache the target addresses are cached during the
+	// resolution TTL
+	ResolutionCache = "cache"
+)
+
+// validateResolution validates the resolution strategy of the target
+func validateResolution(resolution string, ttl Duration) error {
+	switch resolution {
+	case "", ResolutionExecution, ResolutionRegistration:
+		if ttl != 0 {
+			return fmt.Errorf("The healthcheck resolution-ttl can only be used with the %s resolution", ResolutionCache)
+		}
+	case ResolutionCache:
+		if ttl <= 0 {
+			return fmt.Errorf("The healthcheck resolution-ttl should be positive with the %s resolution", ResolutionCache)
+		}
+	default:
+		return fmt.Errorf("Invalid healthcheck resolution %s, valid resolutions are %s, %s and %s", resolution, ResolutionExecution, ResolutionRegistration, ResolutionCache)
+	}
+	return nil
+}
+
+// validateDNSTimeout validates the timeout of the resolution of the
+// target, which should be lower than the healthcheck timeout
+func validateDNSTimeout(dnsTimeout Duration, timeout Duration) error {
+	if dnsTimeout < 0 {
+		return errors.New("The healthcheck dns-timeout should be positive")
+	}
+	if dnsTimeout != 0 && dnsTimeout >= timeout {
+		return errors.New("The healthcheck dns-timeout should be lower than the timeout")
+	}
+	return nil
+}
+
+// addressCache caches the addresses of the target of an healthcheck
+type addressCache struct {
+	// ttl the cache duration, the addresses being kept forever if 0
+	ttl       time.Duration
+	lock      sync.Mutex
+	addrs     []net.IPAddr
+	expiresAt time.Time
+}
+
+// newAddressCache returns the addresses cache of the resolution strategy,
+// or nil if the target should be resolved on each execution
+func newAddressCache(resolution string, ttl Duration) *addressCache {
+	switch resolution {
+	case ResolutionRegistration:
+		return &addressCache{}
+	case ResolutionCache:
+		return &addressCache{ttl: time.Duration(ttl)}
+	}
+	return nil
+}
+
+// lookup returns the cached addresses of the host, resolving it with the
+// resolver if the cache is empty or expired. It also returns true if the
+// host was resolved.
+func (c *addressCache) lookup(ctx context.Context, resolver Resolver, host string) ([]net.IPAddr, bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now()
+	if len(c.addrs) != 0 && (c.ttl == 0 || now.Before(c.expiresAt)) {
+		return c.addrs, false, nil
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, true, err
+	}
+	c.addrs = addrs
+	c.expiresAt = now.Add(c.ttl)
+	return addrs, true, nil
+}
+
+// addressKey the context key of the address recorder
+type addressKey struct{}
+
+// addressRecorder records the IP address connected by an healthcheck
+// execution
+type addressRecorder struct {
+	lock    sync.Mutex
+	address string
+}
+
+// withAddress returns a context recording the addresses connected by the
+// executions
+func withAddress(ctx context.Context) (context.Context, *addressRecorder) {
+	recorder := &addressRecorder{}
+	return context.WithValue(ctx, addressKey{}, recorder), recorder
+}
+
+// addressRecorderFromContext returns the address recorder of the context,
+// or nil
+func addressRecorderFromContext(ctx context.Context) *addressRecorder {
+	recorder, _ := ctx.Value(addressKey{}).(*addressRecorder)
+	return recorder
+}
+
+// recordAddress records the IP address of the remote address if the
+// context has an address recorder
+func recordAddress(ctx context.Context, address net.Addr) {
+	recorder := addressRecorderFromContext(ctx)
+	if recorder == nil || address == nil {
+		return
+	}
+	ip := address.String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	recorder.address = ip
+}
+
+// addressTrace returns a context recording the address of the
+// connections used by the HTTP requests, including the reused ones, if
+// the context has an address recorder
+func addressTrace(ctx context.Context) context.Context {
+	if addressRecorderFromContext(ctx) == nil {
+		return ctx
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			recordAddress(ctx, info.Conn.RemoteAddr())
+		},
+	})
+}
+
+// reset removes the recorded address, in order to only keep the address
+// of the last attempt
+func (r *addressRecorder) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.address = ""
+}
+
+// get returns the recorded address
+func (r *addressRecorder) get() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.address
+}
@@ -0,0 +1,92 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Resolver resolves the targets of the healthchecks
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// resolverKey the context key of the resolver
+type resolverKey struct{}
+
+// withResolver returns a context containing the resolver
+func withResolver(ctx context.Context, resolver Resolver) context.Context {
+	return context.WithValue(ctx, resolverKey{}, resolver)
+}
+
+// resolverFromContext returns the resolver of the context, or nil
+func resolverFromContext(ctx context.Context) Resolver {
+	resolver, _ := ctx.Value(resolverKey{}).(Resolver)
+	return resolver
+}
+
+// dial connects to the address. The host is resolved using the resolver
+// of the context if cached is true, by the dialer otherwise. The
+// addresses are read from the addresses cache if not nil. The resolution
+// fails with a dns-timeout error after dnsTimeout if not 0.
+func dial(ctx context.Context, dialer contextDialer, network string, address string, cached bool, cache *addressCache, dnsTimeout time.Duration) (net.Conn, error) {
+	resolver := resolverFromContext(ctx)
+	if !cached {
+		resolver = nil
+	}
+	host, port, err := net.SplitHostPort(address)
+	if (cache == nil && resolver == nil && dnsTimeout == 0) || err != nil || net.ParseIP(host) != nil {
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err == nil {
+			recordAddress(ctx, conn.RemoteAddr())
+		}
+		return conn, err
+	}
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	start := time.Now()
+	lookupCtx := ctx
+	if dnsTimeout != 0 {
+		var cancel context.CancelFunc
+		lookupCtx, cancel = context.WithTimeout(ctx, dnsTimeout)
+		defer cancel()
+	}
+	var addrs []net.IPAddr
+	resolved := true
+	if cache != nil {
+		addrs, resolved, err = cache.lookup(lookupCtx, resolver, host)
+	} else {
+		addrs, err = resolver.LookupIPAddr(lookupCtx, host)
+	}
+	if err != nil {
+		if lookupCtx.Err() != nil && ctx.Err() == nil {
+			// the DNS timeout expired, not the healthcheck timeout
+			err = withCategory(ErrorDNSTimeout, fmt.Errorf("the resolution of %s timed out after %s", host, dnsTimeout))
+		}
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	if resolved {
+		recordPhase(ctx, PhaseDNS, start, time.Now())
+	}
+	var firstErr error
+	for _, addr := range addrs {
+		ipv4 := addr.IP.To4() != nil
+		if (network == "tcp4" && !ipv4) || (network == "tcp6" && ipv4) {
+			continue
+		}
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+		if err == nil {
+			recordAddress(ctx, conn.RemoteAddr())
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = &net.OpError{Op: "dial", Net: network, Err: &net.AddrError{Err: "no suitable address found", Addr: host}}
+	}
+	return nil, firstErr
+}
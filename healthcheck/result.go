@@ -1,19 +1,90 @@
 package healthcheck
 
 import (
+	"fmt"
+	"reflect"
 	"time"
 )
 
 // Result represents the result of an healthcheck
 type Result struct {
+	// SchemaVersion the version of the results format, only set by the
+	// exporters
+	SchemaVersion        int               `json:"schema-version,omitempty"`
 	Name                 string            `json:"name"`
+	Namespace            string            `json:"namespace,omitempty"`
+	Group                string            `json:"group,omitempty"`
 	Summary              interface{}       `json:"summary"`
 	Labels               map[string]string `json:"labels,omitempty"`
 	Success              bool              `json:"success"`
+	State                string            `json:"state,omitempty"`
+	Flapping             bool              `json:"flapping,omitempty"`
+	DependencyFailure    bool              `json:"dependency-failure,omitempty"`
+	Silenced             bool              `json:"silenced,omitempty"`
 	HealthcheckTimestamp int64             `json:"healthcheck-timestamp"`
 	Message              string            `json:"message"`
 	Duration             int64             `json:"duration"`
 	Source               string            `json:"source"`
+	Severity             string            `json:"severity,omitempty"`
+	TraceID              string            `json:"trace-id,omitempty"`
+	// ProbeID the ID of the execution, sent by the HTTP healthchecks in
+	// the X-Cabourotte-Probe-ID header
+	ProbeID string `json:"probe-id,omitempty"`
+	// ErrorCategory the category of the failure (dns-error, timeout...)
+	ErrorCategory string `json:"error-category,omitempty"`
+	// Phases the durations in milliseconds of the execution phases
+	Phases map[string]float64 `json:"phases,omitempty"`
+	// PreviousState the state of the healthcheck before this result, only
+	// set if the result changed the state
+	PreviousState string `json:"previous-state,omitempty"`
+	// PreviousStateDuration the time in seconds spent in the previous state
+	PreviousStateDuration int64 `json:"previous-state-duration,omitempty"`
+	// Suppressed the number of identical failures not exported since the
+	// previous exported result
+	Suppressed int64 `json:"suppressed,omitempty"`
+	// Annotations the names of the annotations documenting a known
+	// incident at the time of the result
+	Annotations []string `json:"annotations,omitempty"`
+	// Node the node which executed the healthcheck, if the node identity
+	// is configured
+	Node *Node `json:"node,omitempty"`
+	// Shadow the result of a shadow healthcheck, which does not affect
+	// the health
+	Shadow bool `json:"shadow,omitempty"`
+	// Target the target of the result, for the results of the targets of
+	// multi-target healthchecks
+	Target string `json:"target,omitempty"`
+	// SLO the service level objective of the healthcheck
+	SLO *SLO `json:"slo,omitempty"`
+	// Degraded the execution is successful but degraded, for example
+	// because the target is slow
+	Degraded bool `json:"degraded,omitempty"`
+	// TLS the details of the TLS connection negotiated with the target
+	TLS *TLSInfo `json:"tls,omitempty"`
+	// ExpectedFailure the failure happened during an expected failures
+	// window, its severity is downgraded to info
+	ExpectedFailure bool `json:"expected-failure,omitempty"`
+	// ResolvedIP the IP address connected by the healthcheck
+	ResolvedIP string `json:"resolved-ip,omitempty"`
+	// Owner the owner of the healthcheck
+	Owner string `json:"owner,omitempty"`
+	// Team the team owning the healthcheck
+	Team string `json:"team,omitempty"`
+	// RunbookURL the URL of the runbook of the healthcheck
+	RunbookURL string `json:"runbook-url,omitempty"`
+	// Relays the nodes which received the result forwarded by another
+	// node, in order, the node executing the healthcheck being Node
+	Relays []string `json:"relays,omitempty"`
+	// Response the response of a failed HTTP healthcheck, if the
+	// capture is configured
+	Response *CapturedResponse `json:"response,omitempty"`
+	// CheckAnnotations the annotations of the healthcheck, rendered with
+	// the fields of the result
+	CheckAnnotations map[string]string `json:"check-annotations,omitempty"`
+
+	// sampled the result is not exported because of the report-every
+	// option of the healthcheck
+	sampled bool
 }
 
 // Equals implements Equals for Result
@@ -21,12 +92,30 @@ func (r Result) Equals(v Result) bool {
 	if r.Name != v.Name {
 		return false
 	}
+	if r.Namespace != v.Namespace {
+		return false
+	}
+	if r.Group != v.Group {
+		return false
+	}
 	if r.Summary != v.Summary {
 		return false
 	}
 	if r.Success != v.Success {
 		return false
 	}
+	if r.State != v.State {
+		return false
+	}
+	if r.Flapping != v.Flapping {
+		return false
+	}
+	if r.DependencyFailure != v.DependencyFailure {
+		return false
+	}
+	if r.Silenced != v.Silenced {
+		return false
+	}
 	if r.HealthcheckTimestamp != v.HealthcheckTimestamp {
 		return false
 	}
@@ -39,6 +128,57 @@ func (r Result) Equals(v Result) bool {
 	if r.Source != v.Source {
 		return false
 	}
+	if r.Severity != v.Severity {
+		return false
+	}
+	if r.TraceID != v.TraceID {
+		return false
+	}
+	if r.ProbeID != v.ProbeID {
+		return false
+	}
+	if r.ErrorCategory != v.ErrorCategory {
+		return false
+	}
+	if r.PreviousState != v.PreviousState {
+		return false
+	}
+	if r.PreviousStateDuration != v.PreviousStateDuration {
+		return false
+	}
+	if r.Suppressed != v.Suppressed {
+		return false
+	}
+	if r.Shadow != v.Shadow {
+		return false
+	}
+	if r.Target != v.Target {
+		return false
+	}
+	if !reflect.DeepEqual(r.SLO, v.SLO) {
+		return false
+	}
+	if r.Degraded != v.Degraded {
+		return false
+	}
+	if !reflect.DeepEqual(r.TLS, v.TLS) {
+		return false
+	}
+	if !reflect.DeepEqual(r.Response, v.Response) {
+		return false
+	}
+	if r.ExpectedFailure != v.ExpectedFailure {
+		return false
+	}
+	if r.ResolvedIP != v.ResolvedIP {
+		return false
+	}
+	if r.Owner != v.Owner || r.Team != v.Team || r.RunbookURL != v.RunbookURL {
+		return false
+	}
+	if (r.Node == nil) != (v.Node == nil) || (r.Node != nil && !reflect.DeepEqual(*r.Node, *v.Node)) {
+		return false
+	}
 	if len(r.Labels) != len(v.Labels) {
 		return false
 	}
@@ -47,27 +187,114 @@ func (r Result) Equals(v Result) bool {
 			return false
 		}
 	}
+	if len(r.CheckAnnotations) != len(v.CheckAnnotations) {
+		return false
+	}
+	for k, value := range r.CheckAnnotations {
+		if value != v.CheckAnnotations[k] {
+			return false
+		}
+	}
+	if len(r.Annotations) != len(v.Annotations) {
+		return false
+	}
+	for i, value := range r.Annotations {
+		if value != v.Annotations[i] {
+			return false
+		}
+	}
+	if len(r.Relays) != len(v.Relays) {
+		return false
+	}
+	for i, value := range r.Relays {
+		if value != v.Relays[i] {
+			return false
+		}
+	}
+	if len(r.Phases) != len(v.Phases) {
+		return false
+	}
+	for k, value := range r.Phases {
+		if value != v.Phases[k] {
+			return false
+		}
+	}
 	return true
 }
 
+// ID returns the identifier of the healthcheck of the result
+func (r Result) ID() string {
+	return ID(r.Namespace, r.Name)
+}
+
+// Healthy returns true if the healthcheck state is healthy.
+// The raw result is used if the state was not computed.
+func (r Result) Healthy() bool {
+	if r.State == "" {
+		return r.Success
+	}
+	return r.State == StateHealthy
+}
+
+// CurrentState returns the state of the healthcheck. The state is derived
+// from the raw result if it was not computed.
+func (r Result) CurrentState() string {
+	if r.State != "" {
+		return r.State
+	}
+	if r.Success {
+		return StateHealthy
+	}
+	return StateUnhealthy
+}
+
+// Sampled returns true if the successful result should not be exported
+// because of the report-every option of the healthcheck
+func (r Result) Sampled() bool {
+	return r.sampled
+}
+
+// Transition returns true if the result changed the state of the
+// healthcheck
+func (r Result) Transition() bool {
+	return r.PreviousState != ""
+}
+
 // NewResult build a a new result for an healthcheck
 func NewResult(healthcheck Healthcheck, duration int64, err error) *Result {
 	now := time.Now()
+	base := healthcheck.Base()
 	source := "configuration"
 	if healthcheck.Base().Source != "" {
 		source = healthcheck.Base().Source
 	}
 	result := Result{
 		Name:                 healthcheck.Base().Name,
+		Namespace:            base.Namespace,
+		Group:                base.Group,
 		Summary:              healthcheck.Summary(),
 		Labels:               healthcheck.Base().Labels,
 		HealthcheckTimestamp: now.Unix(),
 		Duration:             duration,
 		Source:               source,
+		Severity:             base.SeverityLevel(),
+		Shadow:               base.Shadow,
+		SLO:                  base.SLO,
+		Owner:                base.Owner,
+		Team:                 base.Team,
+		RunbookURL:           base.RunbookURL,
 	}
 	if err != nil {
 		result.Success = false
 		result.Message = err.Error()
+		result.ErrorCategory = ErrorCategory(err)
+		if window := base.expectedFailures(now); window != nil {
+			result.ExpectedFailure = true
+			result.Severity = SeverityInfo
+			if window.Reason != "" {
+				result.Message = fmt.Sprintf("%s (expected failure: %s)", result.Message, window.Reason)
+			}
+		}
 	} else {
 		result.Success = true
 		result.Message = "success"
@@ -1,8 +1,9 @@
 package healthcheck
 
 import (
+	"context"
 	"fmt"
-	"math/rand"
+	"os"
 	"reflect"
 	"sort"
 	"sync"
@@ -11,8 +12,10 @@ import (
 	"github.com/pkg/errors"
 	prom "github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
 
 	"github.com/appclacks/cabourotte/prometheus"
+	"github.com/appclacks/cabourotte/tracing"
 )
 
 // HealthcheckConfiguration is the interface for the healthcheck configuration
@@ -25,7 +28,8 @@ type Healthcheck interface {
 	Initialize() error
 	GetConfig() interface{}
 	Summary() string
-	Execute() error
+	Timeout() time.Duration
+	Execute(ctx context.Context) error
 	LogDebug(message string)
 	LogInfo(message string)
 	Base() Base
@@ -33,78 +37,384 @@ type Healthcheck interface {
 	LogError(err error, message string)
 }
 
+// ExecuteWithTimeout executes an healthcheck with a context bounded by the
+// healthcheck timeout. The function returns a timeout error as soon as the
+// timeout is reached, even if the healthcheck ignores the context.
+// Multi-target and multi-path healthchecks are executed on all their
+// members.
+func ExecuteWithTimeout(ctx context.Context, healthcheck Healthcheck) error {
+	if members := members(healthcheck); len(members) != 0 {
+		return executeMembers(ctx, members)
+	}
+	timeout := healthcheck.Timeout()
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() {
+		result <- healthcheck.Execute(timeoutCtx)
+	}()
+	select {
+	case err := <-result:
+		if err != nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+			return withCategory(ErrorTimeout, errors.Wrapf(err, "healthcheck timed out after %s", timeout))
+		}
+		return err
+	case <-timeoutCtx.Done():
+		if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+			return withCategory(ErrorTimeout, fmt.Errorf("healthcheck timed out after %s", timeout))
+		}
+		return errors.Wrap(timeoutCtx.Err(), "healthcheck cancelled")
+	}
+}
+
+// ExecuteOnce initializes and executes an healthcheck once, retrying it on
+// failure if configured, and returns its result
+func ExecuteOnce(ctx context.Context, healthcheck Healthcheck) *Result {
+	base := healthcheck.Base()
+	err := healthcheck.Initialize()
+	if err != nil {
+		return NewResult(healthcheck, 0, errors.Wrapf(err, "Fail to initialize the healthcheck"))
+	}
+	ctx, recorder := withPhases(ctx)
+	ctx, degraded := withDegradation(ctx)
+	ctx, tlsInfo := withTLSInfo(ctx)
+	ctx, address := withAddress(ctx)
+	ctx, response := withResponse(ctx)
+	ctx, probeID := withProbeID(ctx)
+	var duration time.Duration
+	for attempt := uint(0); attempt <= base.MaxRetries; attempt++ {
+		if attempt != 0 {
+			select {
+			case <-time.After(time.Duration(base.RetryDelay)):
+			case <-ctx.Done():
+				return NewResult(healthcheck, duration.Milliseconds(), err)
+			}
+		}
+		recorder.reset()
+		degraded.reset()
+		tlsInfo.reset()
+		address.reset()
+		response.reset()
+		start := time.Now()
+		err = ExecuteWithTimeout(ctx, healthcheck)
+		duration = time.Since(start)
+		if err == nil {
+			break
+		}
+	}
+	result := NewResult(healthcheck, duration.Milliseconds(), err)
+	degraded.apply(result)
+	result.Phases = phasesDurations(recorder.list())
+	result.ProbeID = probeID
+	result.TLS = tlsInfo.get()
+	result.ResolvedIP = address.get()
+	if !result.Success {
+		result.Response = response.get()
+	}
+	result.CheckAnnotations = renderAnnotations(base.Annotations, result)
+	return result
+}
+
 // Component is the component which will manage healthchecks
 type Component struct {
 	Logger             *zap.Logger
 	Healthchecks       map[string]*Wrapper
 	resultHistogram    *prom.HistogramVec
+	phaseHistogram     *prom.HistogramVec
 	resultCounter      *prom.CounterVec
+	errorCounter       *prom.CounterVec
+	flappingGauge      *prom.GaugeVec
+	registeredGauge    *prom.GaugeVec
+	scheduler          *scheduler
 	lock               sync.RWMutex
 	healthchecksLabels []string
 
+	// states are stored separately from the healthchecks in order to be
+	// read by running healthchecks without acquiring the component lock
+	states     map[string]*stateMachine
+	statesLock sync.RWMutex
+
+	persistencePath string
+	persistLock     sync.Mutex
+
+	// sourcesLock serializes the replacements of the sources healthchecks
+	sourcesLock sync.Mutex
+
+	// owns returns true if the healthcheck should be executed by this
+	// instance (clustering mode)
+	owns     func(name string) bool
+	ownsLock sync.RWMutex
+
+	// tracer exports the executions spans if tracing is enabled
+	tracer     *tracing.Tracer
+	tracerLock sync.RWMutex
+
+	// resolver resolves the targets if the DNS cache is enabled
+	resolver     Resolver
+	resolverLock sync.RWMutex
+
+	// limiter limits the bandwidth used to read the HTTP responses
+	limiter     *bandwidthLimiter
+	limiterLock sync.RWMutex
+
+	// policy restricts the addresses of the targets
+	policy     *TargetPolicy
+	policyLock sync.RWMutex
+	// commandPolicy restricts the commands, and limits their resources
+	commandPolicy     *CommandPolicy
+	commandPolicyLock sync.RWMutex
+
+	// node the name of the node, used to spread the executions. The
+	// hostname by default.
+	node     string
+	nodeLock sync.RWMutex
+
+	// proxy the global proxy of the healthcheck targets
+	proxy     *Proxy
+	proxyLock sync.RWMutex
+	// credentials the named credentials referenced by the healthchecks
+	credentials     Credentials
+	credentialsLock sync.RWMutex
+
+	// rateLimiter limits the executions per target host
+	rateLimiter     *targetLimiter
+	rateLimiterLock sync.RWMutex
+	deferredCounter *prom.CounterVec
+	// exclusionGroups serializes the executions of the healthchecks
+	// sharing an exclusion group
+	exclusionGroups *exclusionGroups
+	// sourceStatuses the statuses of the sources of healthchecks
+	sourceStatuses     map[string]*SourceStatus
+	sourceStatusLock   sync.Mutex
+	sourceCounter      *prom.CounterVec
+	sourceSuccessGauge *prom.GaugeVec
+
+	expireTick *time.Ticker
+	t          tomb.Tomb
+
 	ChanResult chan *Result
 }
 
+// execute executes an healthcheck and computes its result
+func (c *Component) execute(w *Wrapper) *Result {
+	base := w.healthcheck.Base()
+	if dependency := c.unhealthyDependency(base); dependency != "" {
+		w.healthcheck.LogDebug(fmt.Sprintf("dependency %s is unhealthy, skipping the execution", dependency))
+		result := NewResult(
+			w.healthcheck,
+			0,
+			withCategory(ErrorDependency, fmt.Errorf("dependency failure: the healthcheck %s is unhealthy", dependency)))
+		result.DependencyFailure = true
+		result.State = w.state.current()
+		return result
+	}
+	ctx, recorder := withPhases(w.ctx)
+	ctx, degraded := withDegradation(ctx)
+	ctx, tlsInfo := withTLSInfo(ctx)
+	ctx, address := withAddress(ctx)
+	ctx, response := withResponse(ctx)
+	ctx, probeID := withProbeID(ctx)
+	if limiter := c.getLimiter(); limiter != nil {
+		ctx = withLimiter(ctx, limiter)
+	}
+	if policy := c.getPolicy(); policy != nil {
+		ctx = withPolicy(ctx, policy)
+	}
+	if policy := c.getCommandPolicy(); policy != nil {
+		ctx = withCommandPolicy(ctx, policy)
+	}
+	if proxy := c.getProxy(); proxy != nil {
+		ctx = withProxy(ctx, proxy)
+	}
+	if resolver := c.getResolver(); resolver != nil {
+		ctx = withResolver(ctx, resolver)
+	}
+	start := time.Now()
+	duration, err := w.execute(ctx)
+	result := NewResult(
+		w.healthcheck,
+		duration.Milliseconds(),
+		err)
+	degraded.apply(result)
+	if w.anomaly != nil {
+		w.anomaly.apply(result, duration)
+	}
+	executionPhases := recorder.list()
+	result.Phases = phasesDurations(executionPhases)
+	result.ProbeID = probeID
+	result.TLS = tlsInfo.get()
+	result.ResolvedIP = address.get()
+	if !result.Success {
+		result.Response = response.get()
+	}
+	if tracer := c.getTracer(); tracer != nil && tracer.Sampled() {
+		span := executionSpan(w.healthcheck, result, start, time.Now(), executionPhases)
+		tracer.Export(span)
+		result.TraceID = span.TraceIDString()
+	}
+	c.record(w, result, duration)
+	for _, phase := range executionPhases {
+		phaseLabels := map[string]string{
+			"name":      base.Name,
+			"namespace": base.Namespace,
+			"phase":     phase.Name,
+		}
+		for _, k := range c.healthchecksLabels {
+			phaseLabels[k] = result.Labels[k]
+		}
+		c.phaseHistogram.With(prom.Labels(phaseLabels)).Observe(phase.End.Sub(phase.Start).Seconds())
+	}
+	return result
+}
+
+// record updates the state of the healthcheck and the metrics from the
+// result of an execution
+func (c *Component) record(w *Wrapper, result *Result, duration time.Duration) {
+	base := w.healthcheck.Base()
+	now := time.Now()
+	previous := w.state.current()
+	result.State = w.state.update(result.Success, now)
+	result.Flapping = w.state.flapping(now)
+	if w.sampler != nil {
+		w.sampler.sample(result, previous)
+	}
+	status := "failure"
+	if result.Success {
+		status = "success"
+	}
+	histoLabels := map[string]string{
+		"name":      base.Name,
+		"namespace": base.Namespace,
+		"severity":  result.Severity,
+	}
+	for _, k := range c.healthchecksLabels {
+		histoLabels[k] = result.Labels[k]
+	}
+	c.resultHistogram.With(prom.Labels(histoLabels)).Observe(duration.Seconds())
+	counterLabels := map[string]string{
+		"name":      base.Name,
+		"namespace": base.Namespace,
+		"status":    status,
+		"severity":  result.Severity,
+	}
+	for _, k := range c.healthchecksLabels {
+		counterLabels[k] = result.Labels[k]
+	}
+	c.resultCounter.With(prom.Labels(counterLabels)).Inc()
+	if !result.Success {
+		errorLabels := map[string]string{
+			"name":      base.Name,
+			"namespace": base.Namespace,
+			"category":  result.ErrorCategory,
+		}
+		for _, k := range c.healthchecksLabels {
+			errorLabels[k] = result.Labels[k]
+		}
+		c.errorCounter.With(prom.Labels(errorLabels)).Inc()
+	}
+	flapping := 0.0
+	if result.Flapping {
+		flapping = 1
+	}
+	c.flappingGauge.With(prom.Labels(histoLabels)).Set(flapping)
+}
+
 // Start an healthcheck wrapper
 func (c *Component) startWrapper(w *Wrapper) {
+	base := w.healthcheck.Base()
+	if !base.IsEnabled() {
+		w.healthcheck.LogInfo("Healthcheck disabled, not scheduling it")
+		return
+	}
 	w.healthcheck.LogInfo("Starting healthcheck")
-	w.Tick = time.NewTicker(time.Duration(w.healthcheck.Base().Interval))
-	w.t.Go(func() error {
-		wait := rand.Intn(4000)
-		time.Sleep(time.Duration(wait) * time.Millisecond)
-		for {
-			start := time.Now()
-			err := w.healthcheck.Execute()
-			duration := time.Since(start)
-			result := NewResult(
-				w.healthcheck,
-				duration.Milliseconds(),
-				err)
-			status := "failure"
-			if result.Success {
-				status = "success"
-			}
-			histoLabels := map[string]string{
-				"name": w.healthcheck.Base().Name,
-			}
-			for _, k := range c.healthchecksLabels {
-				histoLabels[k] = result.Labels[k]
-			}
-			c.resultHistogram.With(prom.Labels(histoLabels)).Observe(duration.Seconds())
-			counterLabels := map[string]string{
-				"name":   w.healthcheck.Base().Name,
-				"status": status,
+	w.scheduler = c.scheduler
+	w.node = c.getNode()
+	c.scheduler.schedule(w, time.Now().Add(w.initialDelay()))
+}
+
+// runWrapper executes an healthcheck wrapper and schedules its next
+// execution. It is called by the scheduler workers.
+func (c *Component) runWrapper(w *Wrapper) {
+	start := time.Now()
+	base := w.healthcheck.Base()
+	if c.ownsCheck(base.ID()) {
+		if base.ExclusionGroup != "" {
+			if !c.exclusionGroups.acquire(base.ExclusionGroup, w) {
+				// the execution is rescheduled once the group is released
+				w.healthcheck.LogDebug(fmt.Sprintf("exclusion group %s busy, deferring the execution", base.ExclusionGroup))
+				return
 			}
-			for _, k := range c.healthchecksLabels {
-				counterLabels[k] = result.Labels[k]
+			defer c.releaseExclusionGroup(base.ExclusionGroup, w)
+		}
+		if limiter := c.getRateLimiter(); limiter != nil {
+			if delay := limiter.take(targetHosts(w), start); delay > 0 {
+				w.healthcheck.LogDebug(fmt.Sprintf("target rate limit exceeded, deferring the execution by %s", delay))
+				c.deferredCounter.With(prom.Labels{"name": base.Name, "namespace": base.Namespace}).Inc()
+				c.scheduler.schedule(w, start.Add(delay))
+				return
 			}
-			c.resultCounter.With(prom.Labels(counterLabels)).Inc()
-			c.ChanResult <- result
+		}
+		var results []*Result
+		if len(w.members) == 0 {
+			results = []*Result{c.execute(w)}
+		} else {
+			results = c.executeTargets(w)
+		}
+		for _, result := range results {
+			// the annotations are rendered once the state is known
+			result.CheckAnnotations = renderAnnotations(base.Annotations, result)
 			select {
-			case <-w.Tick.C:
-				continue
-			case <-w.t.Dying():
-				return nil
+			case c.ChanResult <- result:
+			case <-w.done:
+				return
 			}
 		}
-	})
+	} else {
+		w.healthcheck.LogDebug("healthcheck owned by another cluster node, skipping the execution")
+	}
+	delay := w.nextDelay()
+	next := time.Now().Add(delay)
+	if w.healthcheck.Base().Cron == nil {
+		// the execution time is removed from the delay in order to keep
+		// a stable frequency
+		next = start.Add(delay)
+	}
+	c.scheduler.schedule(w, w.align(next, start))
+}
+
+// releaseExclusionGroup releases the exclusion group held by the wrapper,
+// and reschedules the healthchecks waiting for it
+func (c *Component) releaseExclusionGroup(group string, w *Wrapper) {
+	now := time.Now()
+	for _, waiting := range c.exclusionGroups.release(group, w) {
+		c.scheduler.schedule(waiting, now)
+	}
 }
 
 // New creates a new Healthcheck component
-func New(logger *zap.Logger, chanResult chan *Result, promComponent *prometheus.Prometheus, healthchecksLabels []string) (*Component, error) {
+func New(logger *zap.Logger, chanResult chan *Result, promComponent *prometheus.Prometheus, healthchecksLabels []string, concurrency ConcurrencyConfiguration) (*Component, error) {
 	buckets := []float64{
 		0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 0.75, 1,
 		2.5, 5, 7.5, 10}
-	histoLabels := []string{"name"}
+	histoLabels := []string{"name", "namespace", "severity"}
 	histoLabels = append(histoLabels, healthchecksLabels...)
-	histo := prom.NewHistogramVec(prom.HistogramOpts{
+	histo := prom.NewHistogramVec(promComponent.DurationHistogramOpts(prom.HistogramOpts{
 		Name:    "healthcheck_duration_seconds",
 		Help:    "Time to execute a healthcheck.",
 		Buckets: buckets,
-	},
+	}),
 		histoLabels,
 	)
-	counterLabels := []string{"name", "status"}
+	phaseLabels := []string{"name", "namespace", "phase"}
+	phaseLabels = append(phaseLabels, healthchecksLabels...)
+	phaseHisto := prom.NewHistogramVec(promComponent.DurationHistogramOpts(prom.HistogramOpts{
+		Name:    "healthcheck_phase_duration_seconds",
+		Help:    "Time spent in each phase (dns, connect, tls, first-byte, body) of a healthcheck execution.",
+		Buckets: buckets,
+	}),
+		phaseLabels,
+	)
+	counterLabels := []string{"name", "namespace", "status", "severity"}
 	counterLabels = append(counterLabels, healthchecksLabels...)
 	counter := prom.NewCounterVec(
 		prom.CounterOpts{
@@ -112,39 +422,372 @@ func New(logger *zap.Logger, chanResult chan *Result, promComponent *prometheus.
 			Help: "Count the number of healthchecks executions.",
 		},
 		counterLabels)
+	errorLabels := []string{"name", "namespace", "category"}
+	errorLabels = append(errorLabels, healthchecksLabels...)
+	errorCounter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "healthcheck_errors_total",
+			Help: "Count the number of healthchecks failures by category (dns-error, connection-refused, timeout, tls-error, assertion-failed...).",
+		},
+		errorLabels)
+	flappingGauge := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Name: "healthcheck_flapping",
+			Help: "1 if the healthcheck is flapping, 0 otherwise.",
+		},
+		histoLabels)
+	queueGauge := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Name: "healthcheck_queue_depth",
+			Help: "Number of healthchecks waiting for a worker.",
+		},
+		[]string{"type"})
+	registeredGauge := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Name: "healthcheck_registered",
+			Help: "Number of healthchecks registered on the node.",
+		},
+		[]string{"type", "source"})
+	deferredCounter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "healthcheck_deferred_total",
+			Help: "Count the number of healthchecks executions deferred because of the target rate limit.",
+		},
+		[]string{"name", "namespace"})
+	shedCounter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "healthcheck_shed_total",
+			Help: "Count the number of low priority healthchecks executions skipped because the workers are saturated.",
+		},
+		[]string{"type"})
+	sourceCounter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "healthcheck_source_reconciliations_total",
+			Help: "Count the number of reconciliations of the healthchecks sources.",
+		},
+		[]string{"source", "status"})
+	sourceSuccessGauge := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Name: "healthcheck_source_last_success_timestamp_seconds",
+			Help: "Timestamp of the last successful reconciliation of the healthchecks sources.",
+		},
+		[]string{"source"})
 
 	err := promComponent.Register(histo)
 	if err != nil {
 		return nil, errors.Wrapf(err, "fail to register the healthcheck results Prometheus histogram")
 	}
+	err = promComponent.Register(phaseHisto)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck phases Prometheus histogram")
+	}
 	err = promComponent.Register(counter)
 	if err != nil {
 		return nil, errors.Wrapf(err, "fail to register the healthcheck results Prometheus counter")
 	}
+	err = promComponent.Register(errorCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck errors Prometheus counter")
+	}
+	err = promComponent.Register(flappingGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck flapping Prometheus gauge")
+	}
+	err = promComponent.Register(queueGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck queue depth Prometheus gauge")
+	}
+	err = promComponent.Register(registeredGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck registered Prometheus gauge")
+	}
+	err = promComponent.Register(shedCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck shed Prometheus counter")
+	}
+	err = promComponent.Register(deferredCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck deferred Prometheus counter")
+	}
+	err = promComponent.Register(sourceCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck source reconciliations Prometheus counter")
+	}
+	err = promComponent.Register(sourceSuccessGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck source last success Prometheus gauge")
+	}
+	// the node name is only used to spread the executions, an empty
+	// name is still valid
+	hostname, _ := os.Hostname()
 	component := Component{
+		node:               hostname,
 		resultCounter:      counter,
+		errorCounter:       errorCounter,
 		resultHistogram:    histo,
+		phaseHistogram:     phaseHisto,
+		flappingGauge:      flappingGauge,
+		registeredGauge:    registeredGauge,
+		deferredCounter:    deferredCounter,
+		exclusionGroups:    newExclusionGroups(),
+		sourceStatuses:     make(map[string]*SourceStatus),
+		sourceCounter:      sourceCounter,
+		sourceSuccessGauge: sourceSuccessGauge,
 		Logger:             logger,
 		Healthchecks:       make(map[string]*Wrapper),
+		states:             make(map[string]*stateMachine),
 		ChanResult:         chanResult,
 		healthchecksLabels: healthchecksLabels,
 	}
+	component.scheduler = newScheduler(logger, concurrency, queueGauge, shedCounter, component.runWrapper)
 
 	return &component, nil
 }
 
+// SetNode sets the identity of the node, whose name is used to spread
+// the executions of the healthchecks started afterwards
+func (c *Component) SetNode(node *Node) {
+	if node == nil {
+		return
+	}
+	c.nodeLock.Lock()
+	defer c.nodeLock.Unlock()
+	c.node = node.Name
+}
+
+// getNode returns the name of the node
+func (c *Component) getNode() string {
+	c.nodeLock.RLock()
+	defer c.nodeLock.RUnlock()
+	return c.node
+}
+
+// SetOwnership configures the function used to know if an healthcheck
+// should be executed by this instance. Healthchecks not owned are still
+// scheduled, in order to be taken over if the ownership changes.
+func (c *Component) SetOwnership(owns func(name string) bool) {
+	c.ownsLock.Lock()
+	defer c.ownsLock.Unlock()
+	c.owns = owns
+}
+
+// SetTracer enables the tracing of the healthchecks executions
+func (c *Component) SetTracer(tracer *tracing.Tracer) {
+	c.tracerLock.Lock()
+	defer c.tracerLock.Unlock()
+	c.tracer = tracer
+}
+
+// getTracer returns the tracer, nil if the tracing is disabled
+func (c *Component) getTracer() *tracing.Tracer {
+	c.tracerLock.RLock()
+	defer c.tracerLock.RUnlock()
+	return c.tracer
+}
+
+// SetResolver enables the resolution of the healthchecks targets using
+// the resolver
+func (c *Component) SetResolver(resolver Resolver) {
+	c.resolverLock.Lock()
+	defer c.resolverLock.Unlock()
+	c.resolver = resolver
+}
+
+// getResolver returns the resolver, nil if the DNS cache is disabled
+func (c *Component) getResolver() Resolver {
+	c.resolverLock.RLock()
+	defer c.resolverLock.RUnlock()
+	return c.resolver
+}
+
+// SetBandwidthLimit limits the bandwidth in bytes per second used by all
+// the HTTP healthchecks to read the responses. The bandwidth is not
+// limited if the limit is 0.
+func (c *Component) SetBandwidthLimit(limit uint64) {
+	c.limiterLock.Lock()
+	defer c.limiterLock.Unlock()
+	if limit == 0 {
+		c.limiter = nil
+		return
+	}
+	if c.limiter != nil && c.limiter.rate == float64(limit) {
+		return
+	}
+	c.limiter = newBandwidthLimiter(limit)
+}
+
+// getLimiter returns the bandwidth limiter, nil if the bandwidth is not
+// limited
+func (c *Component) getLimiter() *bandwidthLimiter {
+	c.limiterLock.RLock()
+	defer c.limiterLock.RUnlock()
+	return c.limiter
+}
+
+// SetTargetPolicy restricts the addresses of the healthchecks targets. The
+// targets are not restricted if the policy is nil.
+func (c *Component) SetTargetPolicy(policy *TargetPolicy) {
+	c.policyLock.Lock()
+	defer c.policyLock.Unlock()
+	c.policy = policy
+}
+
+// getPolicy returns the targets policy, nil if the targets are not
+// restricted
+func (c *Component) getPolicy() *TargetPolicy {
+	c.policyLock.RLock()
+	defer c.policyLock.RUnlock()
+	return c.policy
+}
+
+// SetCommandPolicy restricts the commands of the command healthchecks, and
+// sets the cgroup limiting their resources. The commands are not
+// restricted if the policy is nil.
+func (c *Component) SetCommandPolicy(policy *CommandPolicy) {
+	c.commandPolicyLock.Lock()
+	defer c.commandPolicyLock.Unlock()
+	c.commandPolicy = policy
+}
+
+// getCommandPolicy returns the commands policy, or nil
+func (c *Component) getCommandPolicy() *CommandPolicy {
+	c.commandPolicyLock.RLock()
+	defer c.commandPolicyLock.RUnlock()
+	return c.commandPolicy
+}
+
+// ownsCheck returns true if the healthcheck should be executed by this
+// instance
+func (c *Component) ownsCheck(name string) bool {
+	c.ownsLock.RLock()
+	defer c.ownsLock.RUnlock()
+	return c.owns == nil || c.owns(name)
+}
+
+// SetProxy sets the proxy used by the healthchecks which do not configure
+// their own proxy. The targets are reached directly if the proxy is nil.
+func (c *Component) SetProxy(proxy *Proxy) {
+	c.proxyLock.Lock()
+	defer c.proxyLock.Unlock()
+	c.proxy = proxy
+}
+
+// getProxy returns the global proxy, or nil
+func (c *Component) getProxy() *Proxy {
+	c.proxyLock.RLock()
+	defer c.proxyLock.RUnlock()
+	return c.proxy
+}
+
+// SetCredentials sets the named credentials referenced by the healthchecks.
+// The credentials are resolved when the healthchecks are added.
+func (c *Component) SetCredentials(credentials Credentials) {
+	c.credentialsLock.Lock()
+	defer c.credentialsLock.Unlock()
+	c.credentials = credentials
+}
+
+// ResolveCredential sets the credential referenced by the healthcheck
+func (c *Component) ResolveCredential(check Healthcheck) error {
+	c.credentialsLock.RLock()
+	defer c.credentialsLock.RUnlock()
+	return c.credentials.Resolve(check)
+}
+
+// SetTargetRateLimit limits the number of executions per second against
+// each target host. The executions are not limited if the limit is nil.
+func (c *Component) SetTargetRateLimit(limit *RateLimit) {
+	c.rateLimiterLock.Lock()
+	defer c.rateLimiterLock.Unlock()
+	if limit == nil {
+		c.rateLimiter = nil
+		return
+	}
+	limiter := newTargetLimiter(*limit)
+	// the buckets are kept if the limit is not modified
+	if current := c.rateLimiter; current != nil && current.rate == limiter.rate && current.burst == limiter.burst {
+		return
+	}
+	c.rateLimiter = limiter
+}
+
+// getRateLimiter returns the target rate limiter, or nil
+func (c *Component) getRateLimiter() *targetLimiter {
+	c.rateLimiterLock.RLock()
+	defer c.rateLimiterLock.RUnlock()
+	return c.rateLimiter
+}
+
 // Start start the healthcheck component
 func (c *Component) Start() error {
 	c.Logger.Info("Starting the healthcheck component")
-	// nothing to do
+	c.scheduler.start()
+	tick := time.NewTicker(5 * time.Second)
+	c.expireTick = tick
+	c.t.Go(func() error {
+		for {
+			select {
+			case <-tick.C:
+				err := c.expireChecks(time.Now())
+				if err != nil {
+					c.Logger.Error(err.Error())
+				}
+				c.updateRegisteredGauge()
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
 	return nil
 }
 
+// Alive returns true if the healthchecks scheduler is running and
+// responsive
+func (c *Component) Alive(timeout time.Duration) bool {
+	return c.scheduler.alive(timeout)
+}
+
 // Stop stop the healthcheck component, stopping all healthchecks being executed.
 func (c *Component) Stop() error {
+	return c.Shutdown(0)
+}
+
+// Shutdown stops the healthcheck component gracefully: healthchecks are not
+// scheduled anymore, and the executions in progress have up to timeout to
+// complete and send their results before being cancelled.
+func (c *Component) Shutdown(timeout time.Duration) error {
+	// the expiration routine acquires the component lock, it should be
+	// stopped first
+	if c.expireTick != nil {
+		c.expireTick.Stop()
+		c.expireTick = nil
+		c.t.Kill(nil)
+		err := c.t.Wait()
+		if err != nil {
+			return errors.Wrap(err, "Fail to stop the healthchecks expiration")
+		}
+	}
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	c.Logger.Info("Stopping the healthcheck component")
+	for i := range c.Healthchecks {
+		c.Healthchecks[i].unschedule()
+	}
+	if timeout > 0 {
+		drained := make(chan struct{})
+		go func() {
+			for i := range c.Healthchecks {
+				c.Healthchecks[i].wg.Wait()
+			}
+			close(drained)
+		}()
+		select {
+		case <-drained:
+			c.Logger.Info("All in-flight healthchecks completed")
+		case <-time.After(timeout):
+			c.Logger.Info("Timeout waiting for in-flight healthchecks, cancelling them")
+		}
+	}
 	for i := range c.Healthchecks {
 		wrapper := c.Healthchecks[i]
 		wrapper.healthcheck.LogDebug("stopping healthcheck")
@@ -155,6 +798,10 @@ func (c *Component) Stop() error {
 		}
 	}
 	c.Logger.Info("All healthchecks stopped")
+	err := c.scheduler.stop()
+	if err != nil {
+		return errors.Wrap(err, "Fail to stop the healthchecks scheduler")
+	}
 	return nil
 }
 
@@ -163,55 +810,207 @@ func (c *Component) Stop() error {
 func (c *Component) removeCheck(identifier string) error {
 	if existingWrapper, ok := c.Healthchecks[identifier]; ok {
 		existingWrapper.healthcheck.LogInfo("Stopping healthcheck")
-		c.resultHistogram.DeletePartialMatch(prom.Labels{"name": identifier})
-		c.resultCounter.DeletePartialMatch(prom.Labels{"name": identifier})
+		base := existingWrapper.healthcheck.Base()
+		labels := prom.Labels{"name": base.Name, "namespace": base.Namespace}
+		c.resultHistogram.DeletePartialMatch(labels)
+		c.resultCounter.DeletePartialMatch(labels)
+		c.flappingGauge.DeletePartialMatch(labels)
+		c.phaseHistogram.DeletePartialMatch(labels)
+		c.errorCounter.DeletePartialMatch(labels)
+		c.deferredCounter.DeletePartialMatch(labels)
 		err := existingWrapper.Stop()
 		if err != nil {
-			return errors.Wrapf(err, "Fail to stop healthcheck %s", existingWrapper.healthcheck.Base().Name)
+			return errors.Wrapf(err, "Fail to stop healthcheck %s", identifier)
 		}
 		delete(c.Healthchecks, identifier)
+		c.statesLock.Lock()
+		delete(c.states, identifier)
+		c.statesLock.Unlock()
 		existingWrapper.healthcheck.LogInfo("Healthcheck stopped")
 	}
 	return nil
 }
 
-// AddCheck add an healthcheck to the component and starts it.
+// AddCheck add an healthcheck to the component and starts it. An error
+// is returned if its dependencies form a cycle.
 func (c *Component) AddCheck(check Healthcheck) error {
+	c.lock.RLock()
+	err := c.checkDependencies([]Healthcheck{check}, nil)
+	c.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+	return c.registerCheck(check)
+}
+
+// registerCheck add an healthcheck to the component and starts it, the
+// healthchecks added through the API being persisted. The dependencies
+// should be already checked.
+func (c *Component) registerCheck(check Healthcheck) error {
+	err := c.addCheck(check)
+	if err != nil {
+		return err
+	}
+	if check.Base().Source == SourceAPI {
+		return c.persist()
+	}
+	return nil
+}
+
+// checkExpiration returns the expiration time of an healthcheck. Only
+// healthchecks added through the API with a TTL expire.
+func checkExpiration(base Base) time.Time {
+	if base.TTL == 0 || base.Source != SourceAPI {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(base.TTL))
+}
+
+// updateRegisteredGauge updates the number of registered healthchecks by
+// type and source
+func (c *Component) updateRegisteredGauge() {
+	c.lock.RLock()
+	counts := make(map[[2]string]int)
+	for _, wrapper := range c.Healthchecks {
+		source := wrapper.healthcheck.Base().Source
+		if source == SourceConfig {
+			source = "configuration"
+		}
+		counts[[2]string{checkType(wrapper.healthcheck), source}]++
+	}
+	c.lock.RUnlock()
+	c.registeredGauge.Reset()
+	for key, count := range counts {
+		c.registeredGauge.With(prom.Labels{"type": key[0], "source": key[1]}).Set(float64(count))
+	}
+}
+
+// expireChecks removes the healthchecks whose TTL elapsed without being
+// refreshed
+func (c *Component) expireChecks(now time.Time) error {
+	c.lock.Lock()
+	var expired []string
+	for name, wrapper := range c.Healthchecks {
+		if !wrapper.expiresAt.IsZero() && now.After(wrapper.expiresAt) {
+			c.Logger.Info(fmt.Sprintf("Healthcheck %s expired, removing it", name))
+			err := c.removeCheck(name)
+			if err != nil {
+				c.lock.Unlock()
+				return errors.Wrapf(err, "Fail to remove the expired healthcheck %s", name)
+			}
+			expired = append(expired, name)
+		}
+	}
+	c.lock.Unlock()
+	if len(expired) != 0 {
+		return c.persist()
+	}
+	return nil
+}
+
+// addCheck add an healthcheck to the component and starts it, without
+// persisting it.
+func (c *Component) addCheck(check Healthcheck) error {
+	// the credential is resolved first, the healthchecks being updated
+	// when their credential is modified
+	if err := c.ResolveCredential(check); err != nil {
+		return err
+	}
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	if currentCheck, ok := c.Healthchecks[check.Base().Name]; ok {
+	base := check.Base()
+	if err := c.checkConflict(base.ID(), base.Source); err != nil {
+		return err
+	}
+	if currentCheck, ok := c.Healthchecks[base.ID()]; ok {
 		if reflect.DeepEqual(currentCheck.healthcheck.GetConfig(), check.GetConfig()) {
 			currentCheck.healthcheck.LogDebug("trying to replace existing healthcheck with the same config: do nothing")
+			currentCheck.expiresAt = checkExpiration(check.Base())
 			return nil
 		}
 	}
+	if current, ok := c.Healthchecks[base.ID()]; ok {
+		// the heartbeats are kept when the healthcheck is updated
+		if previous, ok := current.healthcheck.(*HeartbeatHealthcheck); ok {
+			if heartbeatCheck, ok := check.(*HeartbeatHealthcheck); ok {
+				heartbeatCheck.heartbeat = previous.heartbeat
+			}
+		}
+	}
 	wrapper := NewWrapper(check)
+	if policy := c.getPolicy(); policy != nil {
+		if err := policy.checkTarget(check); err != nil {
+			return errors.Wrapf(err, "Invalid target for the healthcheck %s", base.Name)
+		}
+		for _, member := range wrapper.members {
+			if err := policy.checkTarget(member.wrapper.healthcheck); err != nil {
+				return errors.Wrapf(err, "Invalid target for the healthcheck %s", base.Name)
+			}
+		}
+	}
+	if policy := c.getCommandPolicy(); policy != nil {
+		if err := policy.checkCommand(check); err != nil {
+			return errors.Wrapf(err, "Invalid command for the healthcheck %s", base.Name)
+		}
+	}
+	wrapper.expiresAt = checkExpiration(check.Base())
 	wrapper.healthcheck.LogInfo("Adding healthcheck")
-	err := wrapper.healthcheck.Initialize()
+	err := wrapper.initialize()
 	if err != nil {
 		return errors.Wrapf(err, "Fail to initialize healthcheck %s", wrapper.healthcheck.Base().Name)
 	}
 
 	// verifies if the healthcheck already exists, and removes it if needed.
 	// Updating an healthcheck is removing the old one and adding the new one.
-	err = c.removeCheck(wrapper.healthcheck.Base().Name)
+	err = c.removeCheck(base.ID())
 	if err != nil {
-		return errors.Wrapf(err, "Fail to stop existing healthcheck %s", wrapper.healthcheck.Base().Name)
+		return errors.Wrapf(err, "Fail to stop existing healthcheck %s", base.ID())
 	}
+	c.statesLock.Lock()
+	c.states[base.ID()] = wrapper.state
+	c.statesLock.Unlock()
 	c.startWrapper(wrapper)
-	c.Healthchecks[wrapper.healthcheck.Base().Name] = wrapper
+	c.Healthchecks[base.ID()] = wrapper
 	return nil
 }
 
-// RemoveCheck Removes an healthcheck
-func (c *Component) RemoveCheck(name string) error {
+// unhealthyDependency returns the name of the first unhealthy healthcheck
+// the given healthcheck depends on, or an empty string. The dependencies
+// are healthchecks of the same namespace.
+func (c *Component) unhealthyDependency(base Base) string {
+	if len(base.DependsOn) == 0 {
+		return ""
+	}
+	c.statesLock.RLock()
+	defer c.statesLock.RUnlock()
+	for _, dependency := range base.DependsOn {
+		if state, ok := c.states[ID(base.Namespace, dependency)]; ok {
+			if state.current() == StateUnhealthy {
+				return dependency
+			}
+		}
+	}
+	return ""
+}
+
+// RemoveCheck Removes an healthcheck by identifier
+func (c *Component) RemoveCheck(id string) error {
+	check := c.GetCheck(id)
 	c.lock.Lock()
-	defer c.lock.Unlock()
-	c.Logger.Info(fmt.Sprintf("Removing healthcheck %s", name))
-	return c.removeCheck(name)
+	c.Logger.Info(fmt.Sprintf("Removing healthcheck %s", id))
+	err := c.removeCheck(id)
+	c.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	if check != nil && check.Base().Source == SourceAPI {
+		return c.persist()
+	}
+	return nil
 }
 
-// ListChecks returns the healthchecks currently configured, sorted by name
+// ListChecks returns the healthchecks currently configured, sorted by
+// identifier
 func (c *Component) ListChecks() []Healthcheck {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
@@ -221,16 +1020,18 @@ func (c *Component) ListChecks() []Healthcheck {
 		result = append(result, wrapper.healthcheck)
 	}
 	sort.Slice(result, func(i, j int) bool {
-		return result[i].Base().Name < result[j].Base().Name
+		baseI := result[i].Base()
+		baseJ := result[j].Base()
+		return baseI.ID() < baseJ.ID()
 	})
 	return result
 }
 
-// GetCheck returns a check if it exists, otherwise an error.
-func (c *Component) GetCheck(name string) Healthcheck {
+// GetCheck returns a check by identifier if it exists, otherwise nil.
+func (c *Component) GetCheck(id string) Healthcheck {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
-	if existingWrapper, ok := c.Healthchecks[name]; ok {
+	if existingWrapper, ok := c.Healthchecks[id]; ok {
 		return existingWrapper.healthcheck
 	}
 	return nil
@@ -278,83 +1079,138 @@ func (c *Component) ReloadForSource(
 	dns []DNSHealthcheckConfiguration,
 	tcp []TCPHealthcheckConfiguration,
 	http []HTTPHealthcheckConfiguration,
-	tls []TLSHealthcheckConfiguration) error {
+	tls []TLSHealthcheckConfiguration,
+	heartbeat []HeartbeatHealthcheckConfiguration,
+	grpc []GRPCHealthcheckConfiguration,
+	profile []ProfileHealthcheckConfiguration) error {
 
 	oldChecks := c.SourceChecksNames(source)
 	newChecks := make(map[string]bool)
+	checks := []Healthcheck{}
 	for i := range command {
 		config := &command[i]
 		MergeLabels(&config.Base, commonLabels)
 		config.Base.Source = source
-		newChecks[config.Base.Name] = true
-		err := config.Validate()
-		if err != nil {
+		if err := GenerateName(config); err != nil {
 			return err
 		}
-		newCheck := NewCommandHealthcheck(c.Logger, config)
-		err = c.AddCheck(newCheck)
+		newChecks[config.Base.ID()] = true
+		err := config.Validate()
 		if err != nil {
-			return errors.Wrapf(err, "Fail to add healthcheck %s", newCheck.Base().Name)
+			return err
 		}
+		checks = append(checks, NewCommandHealthcheck(c.Logger, config))
 	}
 	for i := range dns {
 		config := &dns[i]
 		MergeLabels(&config.Base, commonLabels)
 		config.Base.Source = source
-		newChecks[config.Base.Name] = true
-		err := config.Validate()
-		if err != nil {
+		if err := GenerateName(config); err != nil {
 			return err
 		}
-		newCheck := NewDNSHealthcheck(c.Logger, config)
-		err = c.AddCheck(newCheck)
+		newChecks[config.Base.ID()] = true
+		err := config.Validate()
 		if err != nil {
-			return errors.Wrapf(err, "Fail to add healthcheck %s", newCheck.Base().Name)
+			return err
 		}
+		checks = append(checks, NewDNSHealthcheck(c.Logger, config))
 	}
 	for i := range http {
 		config := &http[i]
 		MergeLabels(&config.Base, commonLabels)
 		config.Base.Source = source
-		newChecks[config.Base.Name] = true
-		err := config.Validate()
-		if err != nil {
+		if err := GenerateName(config); err != nil {
 			return err
 		}
-		newCheck := NewHTTPHealthcheck(c.Logger, config)
-		err = c.AddCheck(newCheck)
+		newChecks[config.Base.ID()] = true
+		err := config.Validate()
 		if err != nil {
-			return errors.Wrapf(err, "Fail to add healthcheck %s", newCheck.Base().Name)
+			return err
 		}
+		checks = append(checks, NewHTTPHealthcheck(c.Logger, config))
 	}
 	for i := range tcp {
 		config := &tcp[i]
 		MergeLabels(&config.Base, commonLabels)
 		config.Base.Source = source
-		newChecks[config.Base.Name] = true
-		err := config.Validate()
-		if err != nil {
+		if err := GenerateName(config); err != nil {
 			return err
 		}
-		newCheck := NewTCPHealthcheck(c.Logger, config)
-		err = c.AddCheck(newCheck)
+		newChecks[config.Base.ID()] = true
+		err := config.Validate()
 		if err != nil {
-			return errors.Wrapf(err, "Fail to add healthcheck %s", newCheck.Base().Name)
+			return err
 		}
+		checks = append(checks, NewTCPHealthcheck(c.Logger, config))
 	}
 	for i := range tls {
 		config := &tls[i]
 		MergeLabels(&config.Base, commonLabels)
 		config.Base.Source = source
-		newChecks[config.Base.Name] = true
+		if err := GenerateName(config); err != nil {
+			return err
+		}
+		newChecks[config.Base.ID()] = true
 		err := config.Validate()
 		if err != nil {
 			return err
 		}
-		newCheck := NewTLSHealthcheck(c.Logger, config)
-		err = c.AddCheck(newCheck)
+		checks = append(checks, NewTLSHealthcheck(c.Logger, config))
+	}
+	for i := range heartbeat {
+		config := &heartbeat[i]
+		MergeLabels(&config.Base, commonLabels)
+		config.Base.Source = source
+		if err := GenerateName(config); err != nil {
+			return err
+		}
+		newChecks[config.Base.ID()] = true
+		err := config.Validate()
+		if err != nil {
+			return err
+		}
+		checks = append(checks, NewHeartbeatHealthcheck(c.Logger, config))
+	}
+	for i := range grpc {
+		config := &grpc[i]
+		MergeLabels(&config.Base, commonLabels)
+		config.Base.Source = source
+		if err := GenerateName(config); err != nil {
+			return err
+		}
+		newChecks[config.Base.ID()] = true
+		err := config.Validate()
+		if err != nil {
+			return err
+		}
+		checks = append(checks, NewGRPCHealthcheck(c.Logger, config))
+	}
+	for i := range profile {
+		config := &profile[i]
+		MergeLabels(&config.Base, commonLabels)
+		config.Base.Source = source
+		if err := GenerateName(config); err != nil {
+			return err
+		}
+		newChecks[config.Base.ID()] = true
+		err := config.Validate()
+		if err != nil {
+			return err
+		}
+		checks = append(checks, NewProfileHealthcheck(c.Logger, config))
+	}
+	// the healthchecks of the source are replaced, the dependencies are
+	// checked against the new healthchecks only
+	c.lock.RLock()
+	err := c.checkDependencies(checks, oldChecks)
+	c.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+	for _, check := range checks {
+		err := c.registerCheck(check)
 		if err != nil {
-			return errors.Wrapf(err, "Fail to add healthcheck %s", newCheck.Base().Name)
+			return errors.Wrapf(err, "Fail to add healthcheck %s", check.Base().Name)
 		}
 	}
 	return c.RemoveNonConfiguredHealthchecks(oldChecks, newChecks)
@@ -1,6 +1,10 @@
 package healthcheck
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,7 +18,7 @@ func TestStartStop(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
 	}
-	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{})
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
 	if err != nil {
 		t.Fatalf("Fail to create the component\n%v", err)
 	}
@@ -34,7 +38,7 @@ func TestAddRemoveCheck(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
 	}
-	component, err := New(logger, make(chan *Result, 10), prom, []string{})
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
 	if err != nil {
 		t.Fatalf("Fail to create the component\n%v", err)
 	}
@@ -120,7 +124,7 @@ func TestGetCheck(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
 	}
-	component, err := New(logger, make(chan *Result, 10), prom, []string{})
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
 	if err != nil {
 		t.Fatalf("Fail to create the component\n%v", err)
 	}
@@ -194,3 +198,615 @@ func TestMergeLabels(t *testing.T) {
 	}
 
 }
+
+func TestExecuteDependencyFailure(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	parent := newStateMachine(1, 1)
+	component.states["parent"] = parent
+	executed := false
+	wrapper := NewWrapper(&testHealthcheck{
+		config: &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:      "child",
+				Interval:  Duration(10 * time.Second),
+				DependsOn: []string{"parent"},
+			},
+		},
+		execute: func() error {
+			executed = true
+			return nil
+		},
+	})
+	result := component.execute(wrapper)
+	if !executed || !result.Success || result.DependencyFailure {
+		t.Fatalf("The healthcheck should be executed when its dependency is not unhealthy")
+	}
+	parent.update(false, time.Now())
+	executed = false
+	result = component.execute(wrapper)
+	if executed {
+		t.Fatalf("The healthcheck should not be executed when its dependency is unhealthy")
+	}
+	if result.Success || !result.DependencyFailure {
+		t.Fatalf("The result should be a dependency failure")
+	}
+}
+
+func TestExecuteWithTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	check := &testHealthcheck{
+		config: &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(10 * time.Second),
+			},
+		},
+		timeout: 50 * time.Millisecond,
+		execute: func() error {
+			<-block
+			return nil
+		},
+	}
+	start := time.Now()
+	err := ExecuteWithTimeout(context.Background(), check)
+	if err == nil {
+		t.Fatalf("Was expecting a timeout error")
+	}
+	if !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Invalid error message: %s", err.Error())
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("The healthcheck was not cancelled on timeout")
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	logger := zap.NewExample()
+	path := filepath.Join(t.TempDir(), "checks.json")
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.EnablePersistence(path)
+	if err != nil {
+		t.Fatalf("Fail to enable persistence\n%v", err)
+	}
+	for _, source := range []string{SourceAPI, SourceConfig} {
+		check := NewTCPHealthcheck(
+			logger,
+			&TCPHealthcheckConfiguration{
+				Base: Base{
+					Name:     fmt.Sprintf("foo-%s", source),
+					Interval: Duration(time.Second * 5),
+					Source:   source,
+				},
+				Target:  "127.0.0.1",
+				Port:    9000,
+				Timeout: Duration(time.Second * 3),
+			},
+		)
+		err = component.AddCheck(check)
+		if err != nil {
+			t.Fatalf("Fail to add the healthcheck\n%v", err)
+		}
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+
+	prom, err = prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	restored, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = restored.EnablePersistence(path)
+	if err != nil {
+		t.Fatalf("Fail to enable persistence\n%v", err)
+	}
+	checks := restored.ListChecks()
+	if len(checks) != 1 || checks[0].Base().Name != "foo-api" || checks[0].Base().Source != SourceAPI {
+		t.Fatalf("Only the API healthcheck should be restored: %v", checks)
+	}
+	err = restored.RemoveCheck("foo-api")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+	snapshot := restored.snapshot(SourceAPI)
+	if len(snapshot.TCPChecks) != 0 {
+		t.Fatalf("The healthcheck should be removed from the snapshot")
+	}
+	err = restored.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestShutdownDrain(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *Result, 10)
+	component, err := New(zap.NewExample(), chanResult, prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	running := make(chan struct{})
+	wrapper := NewWrapper(&testHealthcheck{
+		config: &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(10 * time.Second),
+			},
+		},
+		execute: func() error {
+			close(running)
+			time.Sleep(200 * time.Millisecond)
+			return nil
+		},
+	})
+	component.Healthchecks["foo"] = wrapper
+	wrapper.scheduler = component.scheduler
+	component.scheduler.schedule(wrapper, time.Now())
+	<-running
+	err = component.Shutdown(5 * time.Second)
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+	select {
+	case result := <-chanResult:
+		if !result.Success {
+			t.Fatalf("The in-flight healthcheck should complete successfully")
+		}
+	default:
+		t.Fatalf("The in-flight healthcheck result was lost")
+	}
+}
+
+func TestDisabledCheck(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	enabled := false
+	check := NewTCPHealthcheck(
+		logger,
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 5),
+				Enabled:  &enabled,
+			},
+			Target:  "127.0.0.1",
+			Port:    9000,
+			Timeout: Duration(time.Second * 3),
+		},
+	)
+	err = component.AddCheck(check)
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	if len(component.ListChecks()) != 1 {
+		t.Fatalf("The disabled healthcheck should be listed")
+	}
+	if component.Healthchecks["foo"].index != -1 {
+		t.Fatalf("The disabled healthcheck should not be scheduled")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestExpireChecks(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	newCheck := func(name string, source string) Healthcheck {
+		return NewTCPHealthcheck(
+			logger,
+			&TCPHealthcheckConfiguration{
+				Base: Base{
+					Name:     name,
+					Interval: Duration(time.Second * 5),
+					Source:   source,
+					TTL:      Duration(time.Minute),
+				},
+				Target:  "127.0.0.1",
+				Port:    9000,
+				Timeout: Duration(time.Second * 3),
+			},
+		)
+	}
+	err = component.AddCheck(newCheck("api", SourceAPI))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	err = component.AddCheck(newCheck("config", SourceConfig))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	err = component.expireChecks(time.Now().Add(30 * time.Second))
+	if err != nil {
+		t.Fatalf("Fail to expire healthchecks\n%v", err)
+	}
+	if len(component.ListChecks()) != 2 {
+		t.Fatalf("No healthcheck should be expired yet")
+	}
+	// refreshing the healthcheck
+	time.Sleep(10 * time.Millisecond)
+	expiresAt := component.Healthchecks["api"].expiresAt
+	err = component.AddCheck(newCheck("api", SourceAPI))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	if !component.Healthchecks["api"].expiresAt.After(expiresAt) {
+		t.Fatalf("The healthcheck expiration should be refreshed")
+	}
+	err = component.expireChecks(time.Now().Add(2 * time.Minute))
+	if err != nil {
+		t.Fatalf("Fail to expire healthchecks\n%v", err)
+	}
+	checks := component.ListChecks()
+	if len(checks) != 1 || checks[0].Base().Name != "config" {
+		t.Fatalf("Only the API healthcheck should be expired")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	cases := []struct {
+		severity string
+		expected string
+		valid    bool
+	}{
+		{severity: "", expected: SeverityCritical, valid: true},
+		{severity: SeverityCritical, expected: SeverityCritical, valid: true},
+		{severity: SeverityWarning, expected: SeverityWarning, valid: true},
+		{severity: SeverityInfo, expected: SeverityInfo, valid: true},
+		{severity: "page", valid: false},
+	}
+	for _, c := range cases {
+		config := TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(10 * time.Second),
+				Severity: c.severity,
+			},
+			Target:  "127.0.0.1",
+			Port:    22,
+			Timeout: Duration(3 * time.Second),
+		}
+		err := config.Validate()
+		if c.valid && err != nil {
+			t.Fatalf("Invalid severity %s: %s", c.severity, err.Error())
+		}
+		if !c.valid {
+			if err == nil {
+				t.Fatalf("Was expecting an error for the severity %s", c.severity)
+			}
+			continue
+		}
+		result := NewResult(NewTCPHealthcheck(zap.NewExample(), &config), 0, nil)
+		if result.Severity != c.expected {
+			t.Fatalf("Invalid result severity %s, expected %s", result.Severity, c.expected)
+		}
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	cases := []struct {
+		runbook string
+		valid   bool
+	}{
+		{runbook: "", valid: true},
+		{runbook: "https://wiki.example.com/runbooks/foo", valid: true},
+		{runbook: "http://wiki/foo", valid: true},
+		{runbook: "javascript:alert(1)", valid: false},
+		{runbook: "/runbooks/foo", valid: false},
+	}
+	for _, c := range cases {
+		config := TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:       "foo",
+				Interval:   Duration(10 * time.Second),
+				Owner:      "alice@example.com",
+				Team:       "infra",
+				RunbookURL: c.runbook,
+			},
+			Target:  "127.0.0.1",
+			Port:    22,
+			Timeout: Duration(3 * time.Second),
+		}
+		err := config.Validate()
+		if c.valid && err != nil {
+			t.Fatalf("Invalid runbook-url %s: %s", c.runbook, err.Error())
+		}
+		if !c.valid {
+			if err == nil {
+				t.Fatalf("Was expecting an error for the runbook-url %s", c.runbook)
+			}
+			continue
+		}
+		result := NewResult(NewTCPHealthcheck(zap.NewExample(), &config), 0, nil)
+		if result.Owner != "alice@example.com" || result.Team != "infra" || result.RunbookURL != c.runbook {
+			t.Fatalf("Invalid result ownership %+v", result)
+		}
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	newConfig := func(namespace string) TCPHealthcheckConfiguration {
+		return TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:      "foo",
+				Namespace: namespace,
+				Interval:  Duration(10 * time.Minute),
+			},
+			Target:  "127.0.0.1",
+			Port:    9000,
+			Timeout: Duration(time.Second * 3),
+		}
+	}
+	err = component.ReloadForSource(SourceConfig, nil, nil, nil, []TCPHealthcheckConfiguration{newConfig(""), newConfig("team-a")}, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Fail to add the healthchecks\n%v", err)
+	}
+	err = component.ReloadForSource(SourceAPI, nil, nil, nil, []TCPHealthcheckConfiguration{newConfig("team-b")}, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Fail to add the healthchecks\n%v", err)
+	}
+	var ids []string
+	for _, check := range component.ListChecks() {
+		base := check.Base()
+		ids = append(ids, base.ID())
+	}
+	if fmt.Sprintf("%v", ids) != "[foo team-a/foo team-b/foo]" {
+		t.Fatalf("Invalid healthchecks %v", ids)
+	}
+	result := NewResult(component.GetCheck("team-a/foo"), 0, nil)
+	if result.Namespace != "team-a" || result.ID() != "team-a/foo" {
+		t.Fatalf("Invalid result namespace %s", result.Namespace)
+	}
+	// the dependencies are resolved in the healthcheck namespace
+	component.states["team-a/foo"].update(false, time.Now())
+	child := NewWrapper(&testHealthcheck{
+		config: &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:      "child",
+				Namespace: "team-b",
+				Interval:  Duration(10 * time.Second),
+				DependsOn: []string{"foo"},
+			},
+		},
+		execute: func() error {
+			return nil
+		},
+	})
+	result = component.execute(child)
+	if result.DependencyFailure || result.Namespace != "team-b" {
+		t.Fatalf("The dependency should be resolved in the healthcheck namespace")
+	}
+	err = component.RemoveCheck("team-a/foo")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+	if component.GetCheck("foo") == nil || component.GetCheck("team-a/foo") != nil {
+		t.Fatalf("Invalid healthchecks after removal")
+	}
+	config := newConfig("team/a")
+	if config.Validate() == nil {
+		t.Fatalf("Was expecting an error for the namespace")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestRegisteredGauge(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	for i, source := range []string{SourceConfig, SourceAPI, SourceAPI} {
+		healthcheck := NewTCPHealthcheck(
+			logger,
+			&TCPHealthcheckConfiguration{
+				Base: Base{
+					Name:     fmt.Sprintf("foo%d", i),
+					Interval: Duration(time.Second * 5),
+					Source:   source,
+				},
+				Target:  "127.0.0.1",
+				Port:    9000,
+				Timeout: Duration(time.Second * 3),
+			},
+		)
+		err = component.AddCheck(healthcheck)
+		if err != nil {
+			t.Fatalf("Fail to add the healthcheck\n%v", err)
+		}
+	}
+	component.updateRegisteredGauge()
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics\n%v", err)
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "healthcheck_registered" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			values[labels["type"]+"/"+labels["source"]] = metric.GetGauge().GetValue()
+		}
+	}
+	if len(values) != 2 || values["tcp/configuration"] != 1 || values["tcp/api"] != 2 {
+		t.Fatalf("Invalid registered healthchecks metrics: %v", values)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestRemoveCheckMetrics(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	check := NewTCPHealthcheck(
+		logger,
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Minute),
+			},
+			Target:  "127.0.0.1",
+			Port:    9000,
+			Timeout: Duration(time.Second),
+		},
+	)
+	err = component.AddCheck(check)
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	component.phaseHistogram.WithLabelValues("foo", "", "connect").Observe(0.1)
+	component.errorCounter.WithLabelValues("foo", "", "timeout").Inc()
+	component.deferredCounter.WithLabelValues("foo", "").Inc()
+	err = component.RemoveCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics\n%v", err)
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == "foo" {
+					t.Fatalf("The metric %s of the removed healthcheck was not deleted", family.GetName())
+				}
+			}
+		}
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	tcp := func(name string, dependencies ...string) TCPHealthcheckConfiguration {
+		return TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:      name,
+				Interval:  Duration(10 * time.Minute),
+				DependsOn: dependencies,
+			},
+			Target:  "127.0.0.1",
+			Port:    9000,
+			Timeout: Duration(time.Second),
+		}
+	}
+	err = component.ReloadForSource(SourceConfig, nil, nil, nil, []TCPHealthcheckConfiguration{tcp("a", "b"), tcp("b")}, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Fail to reload the healthchecks\n%v", err)
+	}
+	// the dependencies are inverted, the previous ones being replaced
+	err = component.ReloadForSource(SourceConfig, nil, nil, nil, []TCPHealthcheckConfiguration{tcp("a"), tcp("b", "a")}, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Fail to reload the healthchecks\n%v", err)
+	}
+	err = component.ReloadForSource(SourceConfig, nil, nil, nil, []TCPHealthcheckConfiguration{tcp("a", "b"), tcp("b", "a")}, nil, nil, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "form a cycle") {
+		t.Fatalf("Was expecting a cycle error, got %v", err)
+	}
+	config := tcp("c", "b")
+	config.Base.Source = SourceAPI
+	err = component.AddCheck(NewTCPHealthcheck(logger, &config))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	selfConfig := tcp("d", "d")
+	err = component.AddCheck(NewTCPHealthcheck(logger, &selfConfig))
+	if err == nil {
+		t.Fatalf("Was expecting a cycle error")
+	}
+	err = component.ReloadForSource(SourceConfig, nil, nil, nil, []TCPHealthcheckConfiguration{tcp("a", "c"), tcp("b", "a")}, nil, nil, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "a -> c -> b -> a") {
+		t.Fatalf("Was expecting a cycle error, got %v", err)
+	}
+	if len(component.ListChecks()) != 3 {
+		t.Fatalf("Invalid number of healthchecks %d", len(component.ListChecks()))
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
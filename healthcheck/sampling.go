@@ -0,0 +1,40 @@
+package healthcheck
+
+import (
+	"sync"
+)
+
+// sampler samples the successful results of an healthcheck, only one
+// successful result out of every being exported
+type sampler struct {
+	lock  sync.Mutex
+	every uint
+	count uint
+}
+
+// newSampler creates a sampler, or returns nil if all the results are
+// exported
+func newSampler(every uint) *sampler {
+	if every <= 1 {
+		return nil
+	}
+	return &sampler{every: every}
+}
+
+// sample marks the result as sampled if it should not be exported. The
+// failures, the degraded results and the state changes are always
+// exported.
+func (s *sampler) sample(result *Result, previous string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !result.Success || result.Degraded || result.State != previous {
+		s.count = 0
+		return
+	}
+	s.count++
+	if s.count < s.every {
+		result.sampled = true
+		return
+	}
+	s.count = 0
+}
@@ -0,0 +1,38 @@
+package healthcheck
+
+import (
+	"testing"
+)
+
+func TestSampler(t *testing.T) {
+	s := newSampler(3)
+	cases := []struct {
+		success  bool
+		state    string
+		previous string
+		degraded bool
+		sampled  bool
+	}{
+		{success: true, state: StateHealthy, previous: StateUnknown},
+		{success: true, state: StateHealthy, previous: StateHealthy, sampled: true},
+		{success: true, state: StateHealthy, previous: StateHealthy, sampled: true},
+		{success: true, state: StateHealthy, previous: StateHealthy},
+		{success: true, state: StateHealthy, previous: StateHealthy, sampled: true},
+		{success: false, state: StateHealthy, previous: StateHealthy},
+		{success: true, state: StateHealthy, previous: StateHealthy, sampled: true},
+		{success: true, state: StateHealthy, previous: StateHealthy, degraded: true},
+		{success: true, state: StateHealthy, previous: StateHealthy, sampled: true},
+		{success: true, state: StateHealthy, previous: StateHealthy, sampled: true},
+		{success: true, state: StateHealthy, previous: StateHealthy},
+	}
+	for i, c := range cases {
+		result := &Result{Success: c.success, State: c.state, Degraded: c.degraded}
+		s.sample(result, c.previous)
+		if result.Sampled() != c.sampled {
+			t.Fatalf("Invalid sampling for the result %d", i)
+		}
+	}
+	if newSampler(0) != nil || newSampler(1) != nil {
+		t.Fatalf("All the results should be exported")
+	}
+}
@@ -0,0 +1,352 @@
+package healthcheck
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+)
+
+// DefaultConcurrency the default maximum number of healthchecks executed
+// concurrently
+const DefaultConcurrency = 500
+
+// ConcurrencyConfiguration limits the number of healthchecks executed
+// concurrently, globally and per healthcheck type (command, dns, http,
+// tcp, tls).
+// When more than ShedQueueDepth healthchecks of a type are waiting for a
+// worker, low priority healthchecks of this type are skipped.
+type ConcurrencyConfiguration struct {
+	Global         uint            `yaml:"global"`
+	PerType        map[string]uint `yaml:"per-type"`
+	ShedQueueDepth uint            `yaml:"shed-queue-depth"`
+}
+
+// checkType returns the type of an healthcheck
+func checkType(healthcheck Healthcheck) string {
+	switch healthcheck.(type) {
+	case *CommandHealthcheck:
+		return "command"
+	case *DNSHealthcheck:
+		return "dns"
+	case *HTTPHealthcheck:
+		return "http"
+	case *TCPHealthcheck:
+		return "tcp"
+	case *TLSHealthcheck:
+		return "tls"
+	case *HeartbeatHealthcheck:
+		return "heartbeat"
+	case *GRPCHealthcheck:
+		return "grpc"
+	case *ProfileHealthcheck:
+		return "profile"
+	}
+	return "other"
+}
+
+// wrapperHeap is a min-heap of wrappers ordered by their next execution
+type wrapperHeap []*Wrapper
+
+func (h wrapperHeap) Len() int           { return len(h) }
+func (h wrapperHeap) Less(i, j int) bool { return h[i].next.Before(h[j].next) }
+func (h wrapperHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *wrapperHeap) Push(x interface{}) {
+	w := x.(*Wrapper)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+
+func (h *wrapperHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*h = old[:n-1]
+	return w
+}
+
+// jobQueue is an unbounded queue of wrappers waiting to be executed.
+// Wrappers are ordered by priority, then by arrival.
+type jobQueue struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	items  [3][]*Wrapper
+	size   int
+	closed bool
+}
+
+func newJobQueue() *jobQueue {
+	q := &jobQueue{}
+	q.cond = sync.NewCond(&q.lock)
+	return q
+}
+
+func (q *jobQueue) push(w *Wrapper) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	rank := priorityRank(w.healthcheck.Base().Priority)
+	q.items[rank] = append(q.items[rank], w)
+	q.size++
+	q.cond.Signal()
+}
+
+// len returns the number of wrappers in the queue
+func (q *jobQueue) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.size
+}
+
+// pop blocks until a wrapper is available. It returns nil if the queue
+// is closed.
+func (q *jobQueue) pop() *Wrapper {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for q.size == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil
+	}
+	for rank := range q.items {
+		if len(q.items[rank]) != 0 {
+			w := q.items[rank][0]
+			q.items[rank][0] = nil
+			q.items[rank] = q.items[rank][1:]
+			q.size--
+			return w
+		}
+	}
+	return nil
+}
+
+// close closes the queue and returns the wrappers which were not executed
+func (q *jobQueue) close() []*Wrapper {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+	var items []*Wrapper
+	for rank := range q.items {
+		items = append(items, q.items[rank]...)
+		q.items[rank] = nil
+	}
+	q.size = 0
+	return items
+}
+
+// scheduler executes the healthcheck wrappers when they are due, using a
+// bounded pool of workers.
+// Each healthcheck type has its own queue and workers in order to apply
+// the per-type limits, and all workers share the global limit.
+type scheduler struct {
+	logger     *zap.Logger
+	config     ConcurrencyConfiguration
+	run        func(w *Wrapper)
+	queueDepth *prom.GaugeVec
+	shed       *prom.CounterVec
+
+	lock    sync.Mutex
+	heap    wrapperHeap
+	queues  map[string]*jobQueue
+	global  chan struct{}
+	wakeup  chan struct{}
+	ping    chan chan struct{}
+	started bool
+	t       tomb.Tomb
+}
+
+func newScheduler(logger *zap.Logger, config ConcurrencyConfiguration, queueDepth *prom.GaugeVec, shed *prom.CounterVec, run func(w *Wrapper)) *scheduler {
+	if config.Global == 0 {
+		config.Global = DefaultConcurrency
+	}
+	return &scheduler{
+		logger:     logger,
+		config:     config,
+		run:        run,
+		queueDepth: queueDepth,
+		shed:       shed,
+		queues:     make(map[string]*jobQueue),
+		global:     make(chan struct{}, config.Global),
+		wakeup:     make(chan struct{}, 1),
+		ping:       make(chan chan struct{}),
+	}
+}
+
+// start starts the scheduling loop
+func (s *scheduler) start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.started = true
+	s.t.Go(s.loop)
+}
+
+// stop stops the scheduling loop and the workers
+func (s *scheduler) stop() error {
+	s.lock.Lock()
+	if !s.started {
+		s.lock.Unlock()
+		return nil
+	}
+	s.started = false
+	for hcType, queue := range s.queues {
+		for _, w := range queue.close() {
+			s.queueDepth.With(prom.Labels{"type": hcType}).Dec()
+			w.wg.Done()
+		}
+	}
+	s.queues = make(map[string]*jobQueue)
+	s.lock.Unlock()
+	s.t.Kill(nil)
+	return s.t.Wait()
+}
+
+func (s *scheduler) loop() error {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		s.lock.Lock()
+		now := time.Now()
+		for s.heap.Len() > 0 && !s.heap[0].next.After(now) {
+			w := heap.Pop(&s.heap).(*Wrapper)
+			s.dispatch(w)
+		}
+		wait := time.Hour
+		if s.heap.Len() > 0 {
+			wait = s.heap[0].next.Sub(now)
+		}
+		s.lock.Unlock()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-s.wakeup:
+		case reply := <-s.ping:
+			close(reply)
+		case <-s.t.Dying():
+			return nil
+		}
+	}
+}
+
+// alive returns true if the scheduling loop answers before the timeout,
+// the loop acquiring the scheduler lock on each iteration
+func (s *scheduler) alive(timeout time.Duration) bool {
+	reply := make(chan struct{})
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case s.ping <- reply:
+	case <-timer.C:
+		return false
+	}
+	select {
+	case <-reply:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// dispatch pushes a wrapper to the queue of its type, creating the queue
+// and its workers if needed.
+// The function is *not* thread-safe.
+func (s *scheduler) dispatch(w *Wrapper) {
+	hcType := checkType(w.healthcheck)
+	queue, ok := s.queues[hcType]
+	if !ok {
+		queue = newJobQueue()
+		s.queues[hcType] = queue
+		workers := s.config.Global
+		if limit, ok := s.config.PerType[hcType]; ok && limit != 0 && limit < workers {
+			workers = limit
+		}
+		for i := uint(0); i < workers; i++ {
+			s.t.Go(func() error {
+				s.worker(hcType, queue)
+				return nil
+			})
+		}
+	}
+	if s.config.ShedQueueDepth != 0 && w.healthcheck.Base().Priority == PriorityLow && uint(queue.len()) >= s.config.ShedQueueDepth {
+		// the workers are saturated, the execution is skipped
+		w.healthcheck.LogDebug("workers are saturated, skipping the low priority healthcheck")
+		s.shed.With(prom.Labels{"type": hcType}).Inc()
+		now := time.Now()
+		w.next = w.align(now.Add(w.nextDelay()), now)
+		heap.Push(&s.heap, w)
+		return
+	}
+	w.wg.Add(1)
+	s.queueDepth.With(prom.Labels{"type": hcType}).Inc()
+	queue.push(w)
+}
+
+func (s *scheduler) worker(hcType string, queue *jobQueue) {
+	for {
+		w := queue.pop()
+		if w == nil {
+			return
+		}
+		s.queueDepth.With(prom.Labels{"type": hcType}).Dec()
+		select {
+		case s.global <- struct{}{}:
+		case <-w.done:
+			w.wg.Done()
+			continue
+		case <-s.t.Dying():
+			w.wg.Done()
+			return
+		}
+		select {
+		case <-w.done:
+		default:
+			s.run(w)
+		}
+		<-s.global
+		w.wg.Done()
+	}
+}
+
+// schedule schedules the next execution of a wrapper
+func (s *scheduler) schedule(w *Wrapper, next time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if w.stopped {
+		return
+	}
+	w.next = next
+	heap.Push(&s.heap, w)
+	if w.index == 0 {
+		select {
+		case s.wakeup <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// remove removes a wrapper from the scheduler. The wrapper will not be
+// scheduled anymore.
+func (s *scheduler) remove(w *Wrapper) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	w.stopped = true
+	if w.index >= 0 && w.index < s.heap.Len() && s.heap[w.index] == w {
+		heap.Remove(&s.heap, w.index)
+	}
+}
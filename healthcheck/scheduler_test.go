@@ -0,0 +1,260 @@
+package healthcheck
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestSchedulerConcurrency(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *Result, 100)
+	component, err := New(
+		zap.NewExample(),
+		chanResult,
+		prom,
+		[]string{},
+		ConcurrencyConfiguration{
+			Global:  10,
+			PerType: map[string]uint{"other": 2},
+		})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	var lock sync.Mutex
+	running := 0
+	maxRunning := 0
+	for i := 0; i < 6; i++ {
+		check := &testHealthcheck{
+			config: &TCPHealthcheckConfiguration{
+				Base: Base{
+					Name:     fmt.Sprintf("check-%d", i),
+					Interval: Duration(10 * time.Second),
+				},
+			},
+			execute: func() error {
+				lock.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				lock.Unlock()
+				time.Sleep(50 * time.Millisecond)
+				lock.Lock()
+				running--
+				lock.Unlock()
+				return nil
+			},
+		}
+		wrapper := NewWrapper(check)
+		component.scheduler.schedule(wrapper, time.Now())
+	}
+	for i := 0; i < 6; i++ {
+		select {
+		case <-chanResult:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timeout waiting for the healthchecks results")
+		}
+	}
+	if maxRunning != 2 {
+		t.Fatalf("Invalid number of concurrent executions: %d", maxRunning)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestJobQueuePriority(t *testing.T) {
+	queue := newJobQueue()
+	priorities := []string{PriorityLow, "", PriorityHigh, PriorityNormal, PriorityHigh}
+	for i, priority := range priorities {
+		queue.push(NewWrapper(&testHealthcheck{
+			config: &TCPHealthcheckConfiguration{
+				Base: Base{
+					Name:     fmt.Sprintf("check-%d", i),
+					Priority: priority,
+				},
+			},
+		}))
+	}
+	expected := []string{"check-2", "check-4", "check-1", "check-3", "check-0"}
+	for _, name := range expected {
+		w := queue.pop()
+		if w.healthcheck.Base().Name != name {
+			t.Fatalf("Invalid queue order: got %s, expected %s", w.healthcheck.Base().Name, name)
+		}
+	}
+	if queue.len() != 0 {
+		t.Fatalf("The queue should be empty")
+	}
+}
+
+func TestSchedulerLoadShedding(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		zap.NewExample(),
+		make(chan *Result, 10),
+		prom,
+		[]string{},
+		ConcurrencyConfiguration{Global: 1, ShedQueueDepth: 1})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	block := make(chan struct{})
+	newWrapper := func(name string, priority string) *Wrapper {
+		return NewWrapper(&testHealthcheck{
+			config: &TCPHealthcheckConfiguration{
+				Base: Base{
+					Name:     name,
+					Interval: Duration(10 * time.Second),
+					Priority: priority,
+				},
+			},
+			execute: func() error {
+				<-block
+				return nil
+			},
+		})
+	}
+	s := component.scheduler
+	normal1 := newWrapper("normal-1", PriorityNormal)
+	normal2 := newWrapper("normal-2", PriorityNormal)
+	low := newWrapper("low", PriorityLow)
+	// the only worker is busy with the first healthcheck, the second
+	// one is waiting in the queue
+	s.lock.Lock()
+	s.dispatch(normal1)
+	s.dispatch(normal2)
+	s.dispatch(low)
+	s.lock.Unlock()
+	if low.index == -1 || !low.next.After(time.Now()) {
+		t.Fatalf("The low priority healthcheck should be skipped and rescheduled")
+	}
+	close(block)
+	for _, w := range []*Wrapper{normal1, normal2, low} {
+		err = w.Stop()
+		if err != nil {
+			t.Fatalf("Fail to stop the healthcheck\n%v", err)
+		}
+	}
+}
+
+func TestSchedulerAlive(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	if component.Alive(10 * time.Millisecond) {
+		t.Fatalf("The scheduler is not started")
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	if !component.Alive(time.Second) {
+		t.Fatalf("The scheduler should be alive")
+	}
+	// a wedged scheduling loop does not answer
+	component.scheduler.lock.Lock()
+	select {
+	case component.scheduler.wakeup <- struct{}{}:
+	default:
+	}
+	if component.Alive(50 * time.Millisecond) {
+		t.Fatalf("The scheduler should not be alive")
+	}
+	component.scheduler.lock.Unlock()
+	if !component.Alive(time.Second) {
+		t.Fatalf("The scheduler should be alive")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestSchedulerExclusionGroup(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *Result, 100)
+	component, err := New(
+		zap.NewExample(),
+		chanResult,
+		prom,
+		[]string{},
+		ConcurrencyConfiguration{
+			Global: 10,
+		})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	var lock sync.Mutex
+	running := 0
+	maxRunning := 0
+	for i := 0; i < 4; i++ {
+		check := &testHealthcheck{
+			config: &TCPHealthcheckConfiguration{
+				Base: Base{
+					Name:           fmt.Sprintf("check-%d", i),
+					Interval:       Duration(10 * time.Second),
+					ExclusionGroup: "admin",
+				},
+			},
+			execute: func() error {
+				lock.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				lock.Unlock()
+				time.Sleep(50 * time.Millisecond)
+				lock.Lock()
+				running--
+				lock.Unlock()
+				return nil
+			},
+		}
+		wrapper := NewWrapper(check)
+		component.scheduler.schedule(wrapper, time.Now())
+	}
+	for i := 0; i < 4; i++ {
+		select {
+		case <-chanResult:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timeout waiting for the healthchecks results")
+		}
+	}
+	if maxRunning != 1 {
+		t.Fatalf("Invalid number of concurrent executions: %d", maxRunning)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package healthcheck
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SchemaV1 the format of the results before the schema versioning,
+	// without the schema-version field. Its fields are frozen.
+	SchemaV1 = 1
+	// SchemaV2 the format of the results described by ResultSchema
+	SchemaV2 = 2
+	// SchemaVersion the latest version of the results format
+	SchemaVersion = SchemaV2
+)
+
+// ResultSchema the JSON schema of the results, in the latest version
+//
+//go:embed result.schema.json
+var ResultSchema []byte
+
+// schemaV1Fields the fields of the results in the version 1 of the
+// format. The fields added later are not exported in this version.
+var schemaV1Fields = map[string]bool{
+	"name":                    true,
+	"namespace":               true,
+	"group":                   true,
+	"summary":                 true,
+	"labels":                  true,
+	"success":                 true,
+	"state":                   true,
+	"flapping":                true,
+	"dependency-failure":      true,
+	"silenced":                true,
+	"healthcheck-timestamp":   true,
+	"message":                 true,
+	"duration":                true,
+	"source":                  true,
+	"severity":                true,
+	"trace-id":                true,
+	"probe-id":                true,
+	"error-category":          true,
+	"phases":                  true,
+	"previous-state":          true,
+	"previous-state-duration": true,
+	"suppressed":              true,
+	"annotations":             true,
+	"node":                    true,
+	"shadow":                  true,
+	"target":                  true,
+	"slo":                     true,
+	"degraded":                true,
+	"tls":                     true,
+	"expected-failure":        true,
+	"resolved-ip":             true,
+}
+
+// ValidateSchemaVersion validates a results format version, 0 being the
+// latest version
+func ValidateSchemaVersion(version int) error {
+	if version < 0 || version > SchemaVersion {
+		return fmt.Errorf("Invalid results schema version %d, valid versions are %d to %d", version, SchemaV1, SchemaVersion)
+	}
+	return nil
+}
+
+// MarshalVersion encodes the result in JSON using a format version, 0
+// being the latest version
+func (r *Result) MarshalVersion(version int) ([]byte, error) {
+	result := *r
+	if version != SchemaV1 {
+		result.SchemaVersion = SchemaVersion
+		return json.Marshal(&result)
+	}
+	result.SchemaVersion = 0
+	content, err := json.Marshal(&result)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, errors.Wrap(err, "Fail to read the result fields")
+	}
+	for field := range fields {
+		if !schemaV1Fields[field] {
+			delete(fields, field)
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// MarshalResults encodes the results in a JSON array using a format
+// version, 0 being the latest version
+func MarshalResults(results []*Result, version int) ([]byte, error) {
+	encoded := make([]json.RawMessage, 0, len(results))
+	for _, result := range results {
+		content, err := result.MarshalVersion(version)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, content)
+	}
+	return json.Marshal(encoded)
+}
@@ -0,0 +1,72 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResultMarshalVersion(t *testing.T) {
+	result := Result{
+		Name:       "foo",
+		Success:    true,
+		Message:    "success",
+		Source:     "configuration",
+		ResolvedIP: "10.0.0.1",
+	}
+	cases := []struct {
+		version int
+		schema  interface{}
+	}{
+		{version: 0, schema: float64(SchemaVersion)},
+		{version: SchemaV2, schema: float64(SchemaV2)},
+		{version: SchemaV1, schema: nil},
+	}
+	for _, c := range cases {
+		content, err := result.MarshalVersion(c.version)
+		if err != nil {
+			t.Fatalf("Fail to marshal the result in the version %d:\n%v", c.version, err)
+		}
+		fields := make(map[string]interface{})
+		if err := json.Unmarshal(content, &fields); err != nil {
+			t.Fatalf("Fail to read the result:\n%v", err)
+		}
+		if fields["schema-version"] != c.schema {
+			t.Fatalf("Invalid schema version %v for the version %d", fields["schema-version"], c.version)
+		}
+		if fields["name"] != "foo" || fields["resolved-ip"] != "10.0.0.1" {
+			t.Fatalf("Invalid fields %v for the version %d", fields, c.version)
+		}
+	}
+	if result.SchemaVersion != 0 {
+		t.Fatalf("The result should not be modified")
+	}
+	if err := ValidateSchemaVersion(SchemaVersion + 1); err == nil {
+		t.Fatalf("Was expecting an error for an unknown schema version")
+	}
+}
+
+func TestResultSchemaFields(t *testing.T) {
+	var schema struct {
+		Properties map[string]interface{} `json:"properties"`
+	}
+	if err := json.Unmarshal(ResultSchema, &schema); err != nil {
+		t.Fatalf("Invalid results schema:\n%v", err)
+	}
+	resultType := reflect.TypeOf(Result{})
+	exported := 0
+	for i := 0; i < resultType.NumField(); i++ {
+		if !resultType.Field(i).IsExported() {
+			continue
+		}
+		exported++
+		field := strings.Split(resultType.Field(i).Tag.Get("json"), ",")[0]
+		if _, ok := schema.Properties[field]; !ok {
+			t.Fatalf("The result field %s is not documented in the schema", field)
+		}
+	}
+	if len(schema.Properties) != exported {
+		t.Fatalf("The schema documents fields which do not exist")
+	}
+}
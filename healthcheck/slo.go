@@ -0,0 +1,84 @@
+package healthcheck
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultSLOLongWindow the default long window of the burn rate alerts
+	DefaultSLOLongWindow = time.Hour
+	// DefaultSLOShortWindow the default short window of the burn rate
+	// alerts
+	DefaultSLOShortWindow = 5 * time.Minute
+	// DefaultSLOBurnRate the default burn rate threshold, consuming 2% of
+	// a 30 days error budget in one hour
+	DefaultSLOBurnRate = 14.4
+)
+
+// SLO the service level objective of an healthcheck. An execution is
+// good if the healthcheck is healthy, and if it is faster than the latency
+// objective when set. The SLO is burning when the burn rates of the error
+// budget on both the long and the short windows exceed the threshold.
+type SLO struct {
+	// Objective the percentage of good executions, for example 99.9
+	Objective float64 `json:"objective"`
+	// Latency the executions slower than the latency are bad
+	Latency     Duration `json:"latency,omitempty" yaml:"latency,omitempty"`
+	LongWindow  Duration `json:"long-window,omitempty" yaml:"long-window,omitempty"`
+	ShortWindow Duration `json:"short-window,omitempty" yaml:"short-window,omitempty"`
+	// BurnRate the burn rate threshold
+	BurnRate float64 `json:"burn-rate,omitempty" yaml:"burn-rate,omitempty"`
+}
+
+// Validate validates the SLO
+func (s *SLO) Validate() error {
+	if s.Objective <= 0 || s.Objective >= 100 {
+		return errors.New("The SLO objective should be between 0 and 100 (excluded)")
+	}
+	if s.Latency < 0 || s.LongWindow < 0 || s.ShortWindow < 0 || s.BurnRate < 0 {
+		return errors.New("The SLO latency, windows and burn rate should be positive")
+	}
+	long, short := s.Windows()
+	if short > long {
+		return errors.New("The SLO short window should be lower than the long window")
+	}
+	return nil
+}
+
+// Windows returns the long and the short windows of the burn rate alerts
+func (s *SLO) Windows() (time.Duration, time.Duration) {
+	long := time.Duration(s.LongWindow)
+	if long == 0 {
+		long = DefaultSLOLongWindow
+	}
+	short := time.Duration(s.ShortWindow)
+	if short == 0 {
+		short = DefaultSLOShortWindow
+	}
+	return long, short
+}
+
+// Threshold returns the burn rate threshold
+func (s *SLO) Threshold() float64 {
+	if s.BurnRate == 0 {
+		return DefaultSLOBurnRate
+	}
+	return s.BurnRate
+}
+
+// Good returns true if the result is a good execution. The silenced
+// failures are good executions.
+func (s *SLO) Good(result Result) bool {
+	if !result.Healthy() && !result.Silenced {
+		return false
+	}
+	return s.Latency == 0 || time.Duration(result.Duration)*time.Millisecond <= time.Duration(s.Latency)
+}
+
+// Burn returns the burn rate of the error budget for a ratio of bad
+// executions
+func (s *SLO) Burn(errorRatio float64) float64 {
+	return errorRatio / (1 - s.Objective/100)
+}
@@ -0,0 +1,51 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSLOValidate(t *testing.T) {
+	cases := []struct {
+		slo   SLO
+		valid bool
+	}{
+		{slo: SLO{Objective: 99.9}, valid: true},
+		{slo: SLO{Objective: 99, Latency: Duration(time.Second), LongWindow: Duration(6 * time.Hour), ShortWindow: Duration(30 * time.Minute), BurnRate: 6}, valid: true},
+		{slo: SLO{}, valid: false},
+		{slo: SLO{Objective: 100}, valid: false},
+		{slo: SLO{Objective: 99, BurnRate: -1}, valid: false},
+		{slo: SLO{Objective: 99, ShortWindow: Duration(2 * time.Hour)}, valid: false},
+	}
+	for i, c := range cases {
+		err := c.slo.Validate()
+		if c.valid && err != nil {
+			t.Fatalf("Unexpected error for the case %d:\n%v", i, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Was expecting an error for the case %d", i)
+		}
+	}
+}
+
+func TestSLOGood(t *testing.T) {
+	slo := SLO{Objective: 99, Latency: Duration(100 * time.Millisecond)}
+	cases := []struct {
+		result Result
+		good   bool
+	}{
+		{result: Result{Success: true, Duration: 50}, good: true},
+		{result: Result{Success: true, Duration: 150}, good: false},
+		{result: Result{Success: false, Duration: 50}, good: false},
+		{result: Result{Success: false, Silenced: true, Duration: 50}, good: true},
+		{result: Result{Success: false, State: StateHealthy, Duration: 50}, good: true},
+	}
+	for i, c := range cases {
+		if slo.Good(c.result) != c.good {
+			t.Fatalf("Invalid result for the case %d", i)
+		}
+	}
+	if burn := slo.Burn(0.01); burn < 0.99 || burn > 1.01 {
+		t.Fatalf("Invalid burn rate %f", burn)
+	}
+}
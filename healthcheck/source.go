@@ -0,0 +1,155 @@
+package healthcheck
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// reservedSources the sources managed by Cabourotte. The discovery and
+// directory sources are suffixed by the name of the mechanism.
+var reservedSources = []string{
+	SourceAPI,
+	SourceHTTPDiscovery,
+	SourceKubernetesDiscovery,
+	SourceConsulDiscovery,
+	SourceSRVDiscovery,
+	SourceDockerDiscovery,
+	SourceEC2Discovery,
+	SourceEurekaDiscovery,
+	SourceKVDiscovery,
+	SourceFileSDDiscovery,
+	SourceDirectory,
+}
+
+// sourceRegexp the valid names of the external sources
+var sourceRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ValidateExternalSource validates the source of healthchecks managed by
+// an external controller. The sources managed by Cabourotte are reserved.
+func ValidateExternalSource(source string) error {
+	if !sourceRegexp.MatchString(source) {
+		return fmt.Errorf("Invalid source %s, it should only contain alphanumeric characters, dots, dashes and underscores", source)
+	}
+	for _, reserved := range reservedSources {
+		if source == reserved || strings.HasPrefix(source, reserved+"-") {
+			return fmt.Errorf("The source %s is reserved", source)
+		}
+	}
+	return nil
+}
+
+// Reconciliation the changes done when replacing the healthchecks of a
+// source
+type Reconciliation struct {
+	Added     []string `json:"added"`
+	Updated   []string `json:"updated"`
+	Removed   []string `json:"removed"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// ReplaceSourceChecks replaces the healthchecks managed by the source by
+// the given healthchecks: the new healthchecks are added, the modified ones
+// updated and the others removed. If namespace is not empty, only the
+// healthchecks of the source in this namespace are replaced.
+// The healthchecks are validated and initialized before any change, and an
+// healthcheck managed by another source can not be replaced. The
+// reconciliation is recorded in the status of the source.
+func (c *Component) ReplaceSourceChecks(source string, namespace string, checks []Healthcheck) (*Reconciliation, error) {
+	c.sourcesLock.Lock()
+	defer c.sourcesLock.Unlock()
+	result, err := c.replaceSourceChecks(source, namespace, checks)
+	c.RecordReconciliation(source, err)
+	return result, err
+}
+
+// replaceSourceChecks replaces the healthchecks managed by the source
+func (c *Component) replaceSourceChecks(source string, namespace string, checks []Healthcheck) (*Reconciliation, error) {
+	result := &Reconciliation{
+		Added:     []string{},
+		Updated:   []string{},
+		Removed:   []string{},
+		Unchanged: []string{},
+	}
+	newChecks := make(map[string]Healthcheck)
+	for _, check := range checks {
+		check.SetSource(source)
+		if err := c.ResolveCredential(check); err != nil {
+			return nil, err
+		}
+		base := check.Base()
+		if namespace != "" && base.Namespace != namespace {
+			return nil, fmt.Errorf("The namespace %s of the healthcheck %s does not match the namespace %s", base.Namespace, base.Name, namespace)
+		}
+		if _, ok := newChecks[base.ID()]; ok {
+			return nil, fmt.Errorf("The healthcheck %s is defined several times", base.ID())
+		}
+		newChecks[base.ID()] = check
+	}
+	changed := []Healthcheck{}
+	c.lock.RLock()
+	for id, check := range newChecks {
+		wrapper, ok := c.Healthchecks[id]
+		if !ok {
+			result.Added = append(result.Added, id)
+			changed = append(changed, check)
+			continue
+		}
+		if current := wrapper.healthcheck.Base().Source; current != source {
+			c.lock.RUnlock()
+			if current == SourceConfig {
+				current = "configuration"
+			}
+			return nil, fmt.Errorf("The healthcheck %s is managed by the source %s", id, current)
+		}
+		if reflect.DeepEqual(wrapper.healthcheck.GetConfig(), check.GetConfig()) {
+			result.Unchanged = append(result.Unchanged, id)
+			continue
+		}
+		result.Updated = append(result.Updated, id)
+		changed = append(changed, check)
+	}
+	sourceChecks := make(map[string]bool)
+	for id, wrapper := range c.Healthchecks {
+		base := wrapper.healthcheck.Base()
+		if base.Source != source || (namespace != "" && base.Namespace != namespace) {
+			continue
+		}
+		sourceChecks[id] = true
+		if _, ok := newChecks[id]; !ok {
+			result.Removed = append(result.Removed, id)
+		}
+	}
+	err := c.checkDependencies(checks, sourceChecks)
+	c.lock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	for _, check := range changed {
+		err := check.Initialize()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to initialize healthcheck %s", check.Base().Name)
+		}
+	}
+	for _, check := range changed {
+		err := c.addCheck(check)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to add healthcheck %s", check.Base().Name)
+		}
+	}
+	for _, id := range result.Removed {
+		err := c.RemoveCheck(id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to remove healthcheck %s", id)
+		}
+	}
+	for _, ids := range [][]string{result.Added, result.Updated, result.Removed, result.Unchanged} {
+		sort.Strings(ids)
+	}
+	c.Logger.Info(fmt.Sprintf("Source %s reconciled: %d added, %d updated, %d removed", source, len(result.Added), len(result.Updated), len(result.Removed)))
+	return result, nil
+}
@@ -0,0 +1,128 @@
+package healthcheck
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestValidateExternalSource(t *testing.T) {
+	cases := []struct {
+		source string
+		valid  bool
+	}{
+		{"controller", true},
+		{"team-a.controller_1", true},
+		{"", false},
+		{"-controller", false},
+		{"my/controller", false},
+		{SourceAPI, false},
+		{"consul-discovery-foo", false},
+		{"directory-checks", false},
+		{"directory2", true},
+	}
+	for _, c := range cases {
+		err := ValidateExternalSource(c.source)
+		if (err == nil) != c.valid {
+			t.Fatalf("Invalid validation for the source %s: %v", c.source, err)
+		}
+	}
+}
+
+func TestReplaceSourceChecks(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	newCheck := func(name string, namespace string, port uint) Healthcheck {
+		return NewTCPHealthcheck(logger, &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:      name,
+				Namespace: namespace,
+				Interval:  Duration(10 * time.Minute),
+			},
+			Target:  "127.0.0.1",
+			Port:    port,
+			Timeout: Duration(time.Second * 3),
+		})
+	}
+	err = component.ReloadForSource(SourceConfig, nil, nil, nil, []TCPHealthcheckConfiguration{
+		*newCheck("config", "", 9000).GetConfig().(*TCPHealthcheckConfiguration),
+	}, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Fail to add the healthchecks\n%v", err)
+	}
+	result, err := component.ReplaceSourceChecks("controller", "", []Healthcheck{
+		newCheck("a", "", 9000),
+		newCheck("b", "", 9000),
+		newCheck("c", "team", 9000),
+	})
+	if err != nil {
+		t.Fatalf("Fail to replace the healthchecks\n%v", err)
+	}
+	if fmt.Sprintf("%v", result.Added) != "[a b team/c]" {
+		t.Fatalf("Invalid reconciliation %+v", result)
+	}
+	result, err = component.ReplaceSourceChecks("controller", "", []Healthcheck{
+		newCheck("a", "", 9000),
+		newCheck("b", "", 9001),
+		newCheck("d", "", 9000),
+	})
+	if err != nil {
+		t.Fatalf("Fail to replace the healthchecks\n%v", err)
+	}
+	expected := &Reconciliation{
+		Added:     []string{"d"},
+		Updated:   []string{"b"},
+		Removed:   []string{"team/c"},
+		Unchanged: []string{"a"},
+	}
+	if fmt.Sprintf("%v", result) != fmt.Sprintf("%v", expected) {
+		t.Fatalf("Invalid reconciliation %+v", result)
+	}
+	if check := component.GetCheck("b"); check == nil || check.Base().Source != "controller" {
+		t.Fatalf("The healthcheck should be managed by the source")
+	}
+	// the healthchecks of other namespaces are kept
+	result, err = component.ReplaceSourceChecks("controller", "team", []Healthcheck{})
+	if err != nil {
+		t.Fatalf("Fail to replace the healthchecks\n%v", err)
+	}
+	if len(result.Removed) != 0 || component.GetCheck("a") == nil {
+		t.Fatalf("Invalid reconciliation %+v", result)
+	}
+	// nothing is modified on error
+	for _, checks := range [][]Healthcheck{
+		{newCheck("e", "", 9000), newCheck("config", "", 9000)},
+		{newCheck("e", "", 9000), newCheck("e", "", 9000)},
+		{newCheck("e", "other", 9000)},
+	} {
+		namespace := ""
+		if checks[0].Base().Namespace == "other" {
+			namespace = "team"
+		}
+		_, err = component.ReplaceSourceChecks("controller", namespace, checks)
+		if err == nil {
+			t.Fatalf("Was expecting an error")
+		}
+		if component.GetCheck("e") != nil || component.GetCheck("other/e") != nil {
+			t.Fatalf("The healthchecks should not be modified")
+		}
+	}
+	if check := component.GetCheck("config"); check == nil || check.Base().Source != SourceConfig {
+		t.Fatalf("The configuration healthcheck should not be replaced")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
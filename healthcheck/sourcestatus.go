@@ -0,0 +1,117 @@
+package healthcheck
+
+import (
+	"sort"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// SourceStatus the status of a source of healthchecks: the configuration,
+// the API, a discovery mechanism, a file of the checks directory or an
+// external controller
+type SourceStatus struct {
+	Source string `json:"source"`
+	// Checks the number of healthchecks managed by the source
+	Checks int `json:"checks"`
+	// Reconciliations the number of reconciliations since the start
+	Reconciliations uint64 `json:"reconciliations"`
+	// Failures the number of failed reconciliations since the start
+	Failures uint64 `json:"failures"`
+	// LastReconciliation the time of the last reconciliation, successful
+	// or not
+	LastReconciliation *time.Time `json:"last-reconciliation,omitempty"`
+	// LastSuccess the time of the last successful reconciliation
+	LastSuccess *time.Time `json:"last-success,omitempty"`
+	// LastError the error of the last reconciliation, empty if it was
+	// successful
+	LastError string `json:"last-error,omitempty"`
+}
+
+// sourceName returns the name of a source in the statuses and the
+// metrics
+func sourceName(source string) string {
+	if source == SourceConfig {
+		return "configuration"
+	}
+	return source
+}
+
+// RegisterSource registers a source, listed before its first
+// reconciliation
+func (c *Component) RegisterSource(source string) {
+	c.sourceStatusLock.Lock()
+	defer c.sourceStatusLock.Unlock()
+	if _, ok := c.sourceStatuses[source]; !ok {
+		c.sourceStatuses[source] = &SourceStatus{Source: sourceName(source)}
+	}
+}
+
+// RemoveSource removes the status of a source which does not exist
+// anymore
+func (c *Component) RemoveSource(source string) {
+	c.sourceStatusLock.Lock()
+	defer c.sourceStatusLock.Unlock()
+	delete(c.sourceStatuses, source)
+	c.sourceSuccessGauge.DeleteLabelValues(sourceName(source))
+}
+
+// RecordReconciliation records the result of a reconciliation of the
+// healthchecks of a source, err being nil if it was successful
+func (c *Component) RecordReconciliation(source string, err error) {
+	c.sourceStatusLock.Lock()
+	defer c.sourceStatusLock.Unlock()
+	status, ok := c.sourceStatuses[source]
+	if !ok {
+		status = &SourceStatus{Source: sourceName(source)}
+		c.sourceStatuses[source] = status
+	}
+	now := time.Now()
+	status.Reconciliations++
+	status.LastReconciliation = &now
+	result := "success"
+	if err != nil {
+		result = "failure"
+		status.Failures++
+		status.LastError = err.Error()
+	} else {
+		status.LastSuccess = &now
+		status.LastError = ""
+		c.sourceSuccessGauge.With(prom.Labels{"source": status.Source}).Set(float64(now.Unix()))
+	}
+	c.sourceCounter.With(prom.Labels{"source": status.Source, "status": result}).Inc()
+}
+
+// ListSources returns the statuses of the registered sources and of the
+// sources of the healthchecks, sorted by source. If filter is not nil,
+// only the healthchecks matching it are counted and the sources without
+// such healthchecks are omitted.
+func (c *Component) ListSources(filter func(Healthcheck) bool) []SourceStatus {
+	checks := make(map[string]int)
+	c.lock.RLock()
+	for _, wrapper := range c.Healthchecks {
+		if filter == nil || filter(wrapper.healthcheck) {
+			checks[wrapper.healthcheck.Base().Source]++
+		}
+	}
+	c.lock.RUnlock()
+	c.sourceStatusLock.Lock()
+	defer c.sourceStatusLock.Unlock()
+	result := make([]SourceStatus, 0, len(c.sourceStatuses))
+	for source, status := range c.sourceStatuses {
+		if filter != nil && checks[source] == 0 {
+			continue
+		}
+		s := *status
+		s.Checks = checks[source]
+		delete(checks, source)
+		result = append(result, s)
+	}
+	for source, count := range checks {
+		result = append(result, SourceStatus{Source: sourceName(source), Checks: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Source < result[j].Source
+	})
+	return result
+}
@@ -0,0 +1,83 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestSourceStatus(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	newCheck := func(name string) Healthcheck {
+		return NewTCPHealthcheck(zap.NewExample(), &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     name,
+				Interval: Duration(time.Minute),
+			},
+			Target:  "127.0.0.1",
+			Port:    22,
+			Timeout: Duration(time.Second),
+		})
+	}
+	component.RegisterSource("consul-discovery-foo")
+	_, err = component.ReplaceSourceChecks("controller", "", []Healthcheck{newCheck("foo"), newCheck("bar")})
+	if err != nil {
+		t.Fatalf("Fail to replace the healthchecks\n%v", err)
+	}
+	_, err = component.ReplaceSourceChecks("controller", "", []Healthcheck{newCheck("foo"), newCheck("foo")})
+	if err == nil {
+		t.Fatalf("Was expecting an error for the duplicated healthchecks")
+	}
+	err = component.AddCheck(newCheck("baz"))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	component.RecordReconciliation(SourceConfig, errors.New("invalid configuration"))
+	sources := component.ListSources(nil)
+	if len(sources) != 3 {
+		t.Fatalf("Invalid sources %+v", sources)
+	}
+	config := sources[0]
+	if config.Source != "configuration" || config.Checks != 1 || config.Failures != 1 || config.LastError != "invalid configuration" || config.LastSuccess != nil {
+		t.Fatalf("Invalid configuration source %+v", config)
+	}
+	consul := sources[1]
+	if consul.Source != "consul-discovery-foo" || consul.Checks != 0 || consul.Reconciliations != 0 || consul.LastReconciliation != nil {
+		t.Fatalf("Invalid registered source %+v", consul)
+	}
+	controller := sources[2]
+	if controller.Source != "controller" || controller.Checks != 2 || controller.Reconciliations != 2 || controller.Failures != 1 || controller.LastError == "" || controller.LastSuccess == nil {
+		t.Fatalf("Invalid controller source %+v", controller)
+	}
+	// the sources without healthchecks matching the filter are omitted
+	sources = component.ListSources(func(check Healthcheck) bool {
+		return check.Base().Name == "foo"
+	})
+	if len(sources) != 1 || sources[0].Source != "controller" || sources[0].Checks != 1 {
+		t.Fatalf("Invalid filtered sources %+v", sources)
+	}
+	_, err = component.ReplaceSourceChecks("controller", "", []Healthcheck{})
+	if err != nil {
+		t.Fatalf("Fail to replace the healthchecks\n%v", err)
+	}
+	component.RemoveSource("controller")
+	sources = component.ListSources(nil)
+	if len(sources) != 2 || sources[1].Source != "consul-discovery-foo" {
+		t.Fatalf("The source was not removed: %+v", sources)
+	}
+	err = component.RemoveCheck("baz")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+}
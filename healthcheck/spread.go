@@ -0,0 +1,25 @@
+package healthcheck
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// spreadPhase returns the phase of the executions of an healthcheck
+// within its interval. It is derived from the healthcheck ID and the node
+// name, each node of a fleet executing the healthcheck at a different
+// time.
+func spreadPhase(id string, node string, interval time.Duration) time.Duration {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	h.Write([]byte{0})
+	h.Write([]byte(node))
+	return time.Duration(h.Sum64() % uint64(interval))
+}
+
+// nextSlot returns the first time at or after t whose offset within the
+// interval, since the Unix epoch, is the phase
+func nextSlot(t time.Time, interval time.Duration, phase time.Duration) time.Time {
+	offset := time.Duration(t.UnixNano() % int64(interval))
+	return t.Add((phase - offset + interval) % interval)
+}
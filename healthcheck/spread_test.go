@@ -0,0 +1,104 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSpreadPhase(t *testing.T) {
+	interval := 30 * time.Second
+	phases := make(map[time.Duration]bool)
+	for _, node := range []string{"node-1", "node-2", "node-3", "node-4"} {
+		phase := spreadPhase("foo", node, interval)
+		if phase < 0 || phase >= interval {
+			t.Fatalf("Invalid phase %s", phase)
+		}
+		if phase != spreadPhase("foo", node, interval) {
+			t.Fatalf("The phase should be deterministic")
+		}
+		phases[phase] = true
+	}
+	if len(phases) != 4 {
+		t.Fatalf("The nodes should have different phases: %v", phases)
+	}
+}
+
+func TestNextSlot(t *testing.T) {
+	interval := 10 * time.Second
+	cases := []struct {
+		t        time.Time
+		phase    time.Duration
+		expected time.Time
+	}{
+		{t: time.Unix(100, 0), phase: 3 * time.Second, expected: time.Unix(103, 0)},
+		{t: time.Unix(103, 0), phase: 3 * time.Second, expected: time.Unix(103, 0)},
+		{t: time.Unix(104, 0), phase: 3 * time.Second, expected: time.Unix(113, 0)},
+		{t: time.Unix(109, 500), phase: 0, expected: time.Unix(110, 0)},
+	}
+	for _, c := range cases {
+		if result := nextSlot(c.t, interval, c.phase); !result.Equal(c.expected) {
+			t.Fatalf("Invalid slot %s for %s, expected %s", result, c.t, c.expected)
+		}
+	}
+}
+
+func TestWrapperSpread(t *testing.T) {
+	interval := 10 * time.Second
+	wrapper := NewWrapper(NewTCPHealthcheck(
+		zap.NewExample(),
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(interval),
+				Spread:   true,
+			},
+		},
+	))
+	wrapper.node = "node-1"
+	phase, ok := wrapper.spreadPhase()
+	if !ok {
+		t.Fatalf("The executions should be spread")
+	}
+	before := time.Now()
+	delay := wrapper.initialDelay()
+	if delay < 0 || delay >= interval {
+		t.Fatalf("Invalid initial delay %s", delay)
+	}
+	// the first execution is on the phase, up to the time elapsed while
+	// computing the delay
+	if drift := (phase - time.Duration(before.Add(delay).UnixNano()%int64(interval)) + interval) % interval; drift > time.Second {
+		t.Fatalf("Invalid initial delay %s for the phase %s", delay, phase)
+	}
+	// the execution started late, the next one stays on the phase
+	start := nextSlot(time.Now(), interval, phase).Add(200 * time.Millisecond)
+	next := wrapper.align(start.Add(wrapper.nextDelay()), start)
+	if next.Sub(start) != interval-200*time.Millisecond {
+		t.Fatalf("Invalid next execution %s after %s", next, start)
+	}
+	if offset := time.Duration(next.UnixNano() % int64(interval)); offset != phase {
+		t.Fatalf("Invalid offset %s, expected %s", offset, phase)
+	}
+	// the next execution is always after the previous one
+	if next := wrapper.align(start.Add(time.Second), start); !next.After(start) {
+		t.Fatalf("Invalid next execution %s after %s", next, start)
+	}
+	wrapper.node = "node-2"
+	other, _ := wrapper.spreadPhase()
+	if other == phase {
+		t.Fatalf("The nodes should have different phases")
+	}
+}
+
+func TestSpreadValidate(t *testing.T) {
+	cases := []Base{
+		{Name: "foo", Interval: Duration(10 * time.Second), Spread: true, Jitter: Jitter{Percentage: 10}},
+		{Name: "foo", Spread: true, Cron: &Cron{}},
+	}
+	for _, c := range cases {
+		if err := c.ValidateBase(); err == nil {
+			t.Fatalf("Was expecting an error for %+v", c)
+		}
+	}
+}
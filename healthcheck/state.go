@@ -0,0 +1,132 @@
+package healthcheck
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// StateUnknown the healthcheck state is not known yet
+	StateUnknown string = "unknown"
+	// StateHealthy the healthcheck is healthy
+	StateHealthy string = "healthy"
+	// StateUnhealthy the healthcheck is unhealthy
+	StateUnhealthy string = "unhealthy"
+)
+
+// stateMachine derives the healthcheck state from the raw results, using
+// the rise and fall thresholds.
+// The state becomes healthy after `rise` consecutive successes, and
+// unhealthy after `fall` consecutive failures.
+// The state machine also tracks the state transitions in order to detect
+// flapping healthchecks: an healthcheck is flapping when its state changed
+// at least `flapThreshold` times during `flapWindow`.
+type stateMachine struct {
+	rise          uint
+	fall          uint
+	state         string
+	successes     uint
+	failures      uint
+	flapThreshold uint
+	flapWindow    time.Duration
+	transitions   []time.Time
+	lock          sync.RWMutex
+}
+
+func newStateMachine(rise uint, fall uint) *stateMachine {
+	if rise == 0 {
+		rise = 1
+	}
+	if fall == 0 {
+		fall = 1
+	}
+	return &stateMachine{
+		rise:  rise,
+		fall:  fall,
+		state: StateUnknown,
+	}
+}
+
+// withFlapDetection enables flap detection on the state machine
+func (s *stateMachine) withFlapDetection(threshold uint, window time.Duration) *stateMachine {
+	s.flapThreshold = threshold
+	s.flapWindow = window
+	return s
+}
+
+// withInitialState sets the state of the state machine until the rise or
+// fall threshold is reached
+func (s *stateMachine) withInitialState(state string) *stateMachine {
+	if state != "" {
+		s.state = state
+	}
+	return s
+}
+
+// flapping returns true if the healthcheck is flapping
+func (s *stateMachine) flapping(now time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.flapThreshold == 0 {
+		return false
+	}
+	limit := now.Add(-s.flapWindow)
+	i := 0
+	for i < len(s.transitions) && s.transitions[i].Before(limit) {
+		i++
+	}
+	s.transitions = s.transitions[i:]
+	return uint(len(s.transitions)) >= s.flapThreshold
+}
+
+// current returns the current state
+func (s *stateMachine) current() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.state
+}
+
+// unhealthyFailures returns the number of consecutive failures since the
+// healthcheck became unhealthy
+func (s *stateMachine) unhealthyFailures() uint {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.state != StateUnhealthy || s.failures < s.fall {
+		return 0
+	}
+	return s.failures - s.fall
+}
+
+// consecutiveSuccesses returns the number of consecutive successes
+func (s *stateMachine) consecutiveSuccesses() uint {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.successes
+}
+
+// update updates the state machine with a new result and returns the
+// current state
+func (s *stateMachine) update(success bool, now time.Time) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	previous := s.state
+	defer func() {
+		if s.flapThreshold != 0 && previous != StateUnknown && previous != s.state {
+			s.transitions = append(s.transitions, now)
+		}
+	}()
+	if success {
+		s.failures = 0
+		s.successes++
+		if s.successes >= s.rise {
+			s.state = StateHealthy
+		}
+	} else {
+		s.successes = 0
+		s.failures++
+		if s.failures >= s.fall {
+			s.state = StateUnhealthy
+		}
+	}
+	return s.state
+}
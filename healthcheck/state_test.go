@@ -0,0 +1,70 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStateMachine(t *testing.T) {
+	s := newStateMachine(2, 3)
+	steps := []struct {
+		success bool
+		want    string
+	}{
+		{success: true, want: StateUnknown},
+		{success: true, want: StateHealthy},
+		{success: false, want: StateHealthy},
+		{success: false, want: StateHealthy},
+		{success: true, want: StateHealthy},
+		{success: false, want: StateHealthy},
+		{success: false, want: StateHealthy},
+		{success: false, want: StateUnhealthy},
+		{success: true, want: StateUnhealthy},
+		{success: true, want: StateHealthy},
+	}
+	for i, step := range steps {
+		state := s.update(step.success, time.Now())
+		if state != step.want {
+			t.Fatalf("Invalid state at step %d: %s (expected %s)", i, state, step.want)
+		}
+	}
+	s = newStateMachine(0, 0)
+	if s.update(false, time.Now()) != StateUnhealthy {
+		t.Fatalf("The state should be unhealthy")
+	}
+}
+
+func TestStateMachineInitialState(t *testing.T) {
+	s := newStateMachine(1, 2).withInitialState(StateHealthy)
+	if s.current() != StateHealthy {
+		t.Fatalf("The state should be healthy")
+	}
+	if s.update(false, time.Now()) != StateHealthy {
+		t.Fatalf("The state should be healthy until the fall threshold")
+	}
+	if s.update(false, time.Now()) != StateUnhealthy {
+		t.Fatalf("The state should be unhealthy")
+	}
+	s = newStateMachine(1, 2).withInitialState("")
+	if s.current() != StateUnknown {
+		t.Fatalf("The state should be unknown by default")
+	}
+}
+
+func TestStateMachineFlapping(t *testing.T) {
+	s := newStateMachine(1, 1).withFlapDetection(3, time.Minute)
+	now := time.Now()
+	s.update(true, now)
+	for i := 0; i < 3; i++ {
+		if s.flapping(now) {
+			t.Fatalf("The healthcheck should not be flapping at step %d", i)
+		}
+		s.update(i%2 == 1, now.Add(time.Duration(i)*time.Second))
+	}
+	if !s.flapping(now.Add(3 * time.Second)) {
+		t.Fatalf("The healthcheck should be flapping")
+	}
+	if s.flapping(now.Add(2 * time.Minute)) {
+		t.Fatalf("The healthcheck should not be flapping anymore")
+	}
+}
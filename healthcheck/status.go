@@ -0,0 +1,147 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// StatusCodes the valid status codes of an HTTP healthcheck. The codes are
+// configured as a list of codes and ranges, for example [200-299, 301].
+type StatusCodes []uint
+
+// parseStatus parses a status code or a range of status codes
+func parseStatus(value string) ([]uint, error) {
+	value = strings.TrimSpace(value)
+	bounds := strings.SplitN(value, "-", 2)
+	parsed := make([]uint, len(bounds))
+	for i, bound := range bounds {
+		code, err := strconv.ParseUint(strings.TrimSpace(bound), 10, 16)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("Invalid status code %s", value)
+		}
+		parsed[i] = uint(code)
+	}
+	if len(parsed) == 1 {
+		return parsed, nil
+	}
+	if parsed[0] > parsed[1] {
+		return nil, fmt.Errorf("Invalid status code range %s", value)
+	}
+	result := make([]uint, 0, parsed[1]-parsed[0]+1)
+	for code := parsed[0]; code <= parsed[1]; code++ {
+		result = append(result, code)
+	}
+	return result, nil
+}
+
+// ParseStatusCodes parses a list of status codes and ranges
+func ParseStatusCodes(values []string) (StatusCodes, error) {
+	var result StatusCodes
+	for _, value := range values {
+		codes, err := parseStatus(value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, codes...)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	deduplicated := result[:0]
+	for i, code := range result {
+		if i == 0 || code != result[i-1] {
+			deduplicated = append(deduplicated, code)
+		}
+	}
+	return deduplicated, nil
+}
+
+// Contains returns true if the status code is valid
+func (s StatusCodes) Contains(code uint) bool {
+	for _, c := range s {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// values returns the status codes as a list of codes and ranges, the
+// ranges being used for at least 3 consecutive codes
+func (s StatusCodes) values() []interface{} {
+	sorted := append(StatusCodes{}, s...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	result := []interface{}{}
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if j-i >= 2 {
+			result = append(result, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		} else {
+			for k := i; k <= j; k++ {
+				result = append(result, sorted[k])
+			}
+		}
+		i = j + 1
+	}
+	return result
+}
+
+// UnmarshalYAML reads the status codes from YAML
+func (s *StatusCodes) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw []string
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the valid status codes")
+	}
+	codes, err := ParseStatusCodes(raw)
+	if err != nil {
+		return err
+	}
+	*s = codes
+	return nil
+}
+
+// MarshalYAML marshals the status codes to YAML
+func (s StatusCodes) MarshalYAML() (interface{}, error) {
+	return s.values(), nil
+}
+
+// UnmarshalJSON reads the status codes from JSON, the codes being numbers
+// or strings
+func (s *StatusCodes) UnmarshalJSON(data []byte) error {
+	var raw []interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return errors.Wrap(err, "Unable to read the valid status codes")
+	}
+	values := make([]string, len(raw))
+	for i, value := range raw {
+		switch v := value.(type) {
+		case float64:
+			values[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case string:
+			values[i] = v
+		default:
+			return fmt.Errorf("Invalid status code %v", value)
+		}
+	}
+	codes, err := ParseStatusCodes(values)
+	if err != nil {
+		return err
+	}
+	*s = codes
+	return nil
+}
+
+// MarshalJSON marshals the status codes to JSON
+func (s StatusCodes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.values())
+}
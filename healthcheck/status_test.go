@@ -0,0 +1,73 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseStatusCodes(t *testing.T) {
+	cases := []struct {
+		in       []string
+		expected StatusCodes
+		err      bool
+	}{
+		{in: []string{"200"}, expected: StatusCodes{200}},
+		{in: []string{"201", "200", "200"}, expected: StatusCodes{200, 201}},
+		{in: []string{"200-203", " 301 ", "401"}, expected: StatusCodes{200, 201, 202, 203, 301, 401}},
+		{in: []string{"204-204"}, expected: StatusCodes{204}},
+		{in: []string{"foo"}, err: true},
+		{in: []string{"299-200"}, err: true},
+		{in: []string{"200-"}, err: true},
+		{in: []string{"99"}, err: true},
+		{in: []string{"200-600"}, err: true},
+	}
+	for _, c := range cases {
+		result, err := ParseStatusCodes(c.in)
+		if (err != nil) != c.err {
+			t.Fatalf("Invalid error for %v: %v", c.in, err)
+		}
+		if !c.err && !reflect.DeepEqual(result, c.expected) {
+			t.Fatalf("Invalid status codes for %v: %v", c.in, result)
+		}
+	}
+}
+
+func TestStatusCodesEncoding(t *testing.T) {
+	var config HTTPHealthcheckConfiguration
+	err := yaml.Unmarshal([]byte("valid-status: [200-299, 301, 401]"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the status codes\n%v", err)
+	}
+	if len(config.ValidStatus) != 102 || !config.ValidStatus.Contains(250) || config.ValidStatus.Contains(300) {
+		t.Fatalf("Invalid status codes %v", config.ValidStatus)
+	}
+	content, err := json.Marshal(config.ValidStatus)
+	if err != nil {
+		t.Fatalf("Fail to marshal the status codes\n%v", err)
+	}
+	if string(content) != `["200-299",301,401]` {
+		t.Fatalf("Invalid json %s", string(content))
+	}
+	var decoded StatusCodes
+	err = json.Unmarshal([]byte(`[200, 201, "300-302"]`), &decoded)
+	if err != nil {
+		t.Fatalf("Fail to parse the status codes\n%v", err)
+	}
+	if !reflect.DeepEqual(decoded, StatusCodes{200, 201, 300, 301, 302}) {
+		t.Fatalf("Invalid status codes %v", decoded)
+	}
+	content, err = json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("Fail to marshal the status codes\n%v", err)
+	}
+	if string(content) != `[200,201,"300-302"]` {
+		t.Fatalf("Invalid json %s", string(content))
+	}
+	err = json.Unmarshal([]byte(`[true]`), &decoded)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
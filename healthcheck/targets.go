@@ -0,0 +1,258 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// validateTargets validates the target or the targets of an healthcheck
+func validateTargets(target string, targets []string) error {
+	if target == "" && len(targets) == 0 {
+		return errors.New("The healthcheck target is missing")
+	}
+	if target != "" && len(targets) != 0 {
+		return errors.New("The healthcheck target and targets are mutually exclusive")
+	}
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		if t == "" {
+			return errors.New("The healthcheck targets should not be empty")
+		}
+		if seen[t] {
+			return fmt.Errorf("The healthcheck target %s is duplicated", t)
+		}
+		seen[t] = true
+	}
+	return nil
+}
+
+// displayTargets returns the target, or the list of the targets of a
+// multi-target healthcheck
+func displayTargets(target string, targets []string) string {
+	if len(targets) == 0 {
+		return target
+	}
+	return "{" + strings.Join(targets, ",") + "}"
+}
+
+// TargetName returns the name of the healthcheck executed on a target of a
+// multi-target healthcheck
+func TargetName(name string, target string) string {
+	return fmt.Sprintf("%s-%s", name, target)
+}
+
+// target returns the target of a TCP, HTTP, TLS, gRPC, ICMP or profile
+// healthcheck
+func target(healthcheck Healthcheck) string {
+	switch h := healthcheck.(type) {
+	case *HTTPHealthcheck:
+		return h.Config.Target
+	case *TCPHealthcheck:
+		return h.Config.Target
+	case *TLSHealthcheck:
+		return h.Config.Target
+	case *GRPCHealthcheck:
+		return h.Config.Target
+	case *icmpHealthcheck:
+		return h.Config.Target
+	case *ProfileHealthcheck:
+		return h.Config.Target
+	}
+	return ""
+}
+
+// member an healthcheck executed on a target of a multi-target
+// healthcheck, on a path of a multi-path healthcheck, or on a protocol of
+// a profile healthcheck
+type member struct {
+	target      string
+	path        string
+	protocol    string
+	healthcheck Healthcheck
+}
+
+// name returns the target, the path or the protocol of the member
+func (m *member) name() string {
+	if m.protocol != "" {
+		return m.protocol
+	}
+	if m.path != "" {
+		return m.path
+	}
+	return m.target
+}
+
+// members returns the healthchecks executed on each target of a
+// multi-target healthcheck, on each path of a multi-path healthcheck or
+// on each protocol of a profile healthcheck, nil otherwise
+func members(healthcheck Healthcheck) []*member {
+	var result []*member
+	switch h := healthcheck.(type) {
+	case *TCPHealthcheck:
+		for _, target := range h.Config.Targets {
+			config := *h.Config
+			config.Target = target
+			config.Targets = nil
+			config.Base.Name = TargetName(h.Config.Base.Name, target)
+			result = append(result, &member{target: target, healthcheck: NewTCPHealthcheck(h.Logger, &config)})
+		}
+		for _, path := range h.Config.Paths {
+			config := *h.Config
+			config.Paths = nil
+			config.SourceIP = path.SourceIP
+			config.IPVersion = path.IPVersion
+			config.Base = path.apply(h.Config.Base)
+			result = append(result, &member{path: path.Name, healthcheck: NewTCPHealthcheck(h.Logger, &config)})
+		}
+	case *HTTPHealthcheck:
+		for _, target := range h.Config.Targets {
+			config := *h.Config
+			config.Target = target
+			config.Targets = nil
+			config.Base.Name = TargetName(h.Config.Base.Name, target)
+			result = append(result, &member{target: target, healthcheck: NewHTTPHealthcheck(h.Logger, &config)})
+		}
+		for _, path := range h.Config.Paths {
+			config := *h.Config
+			config.Paths = nil
+			config.SourceIP = path.SourceIP
+			config.IPVersion = path.IPVersion
+			config.Base = path.apply(h.Config.Base)
+			result = append(result, &member{path: path.Name, healthcheck: NewHTTPHealthcheck(h.Logger, &config)})
+		}
+	case *TLSHealthcheck:
+		for _, target := range h.Config.Targets {
+			config := *h.Config
+			config.Target = target
+			config.Targets = nil
+			config.Base.Name = TargetName(h.Config.Base.Name, target)
+			result = append(result, &member{target: target, healthcheck: NewTLSHealthcheck(h.Logger, &config)})
+		}
+		for _, path := range h.Config.Paths {
+			config := *h.Config
+			config.Paths = nil
+			config.SourceIP = path.SourceIP
+			config.IPVersion = path.IPVersion
+			config.Base = path.apply(h.Config.Base)
+			result = append(result, &member{path: path.Name, healthcheck: NewTLSHealthcheck(h.Logger, &config)})
+		}
+	case *ProfileHealthcheck:
+		result = h.members()
+	}
+	return result
+}
+
+// aggregate computes the error of a multi-target, multi-path or profile
+// healthcheck from the errors of its members. A multi-target healthcheck
+// fails if it fails on a target, a multi-path healthcheck if it fails on
+// all the paths, and a profile healthcheck if it fails on a protocol.
+func aggregate(members []*member, errs []error) error {
+	var failed []string
+	var first error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if first == nil {
+			first = err
+		}
+		failed = append(failed, members[i].name())
+	}
+	if first == nil {
+		return nil
+	}
+	if members[0].protocol != "" {
+		return withCategory(ErrorCategory(first), fmt.Errorf("the healthcheck failed on %d/%d protocols (%s): %s", len(failed), len(members), strings.Join(failed, ", "), first.Error()))
+	}
+	if members[0].path != "" {
+		if len(failed) != len(members) {
+			return nil
+		}
+		return withCategory(ErrorCategory(first), fmt.Errorf("the healthcheck failed on all the paths (%s): %s", strings.Join(failed, ", "), first.Error()))
+	}
+	return withCategory(ErrorCategory(first), fmt.Errorf("the healthcheck failed on %d/%d targets (%s): %s", len(failed), len(members), strings.Join(failed, ", "), first.Error()))
+}
+
+// executeMembers initializes and executes the members of a multi-target
+// or multi-path healthcheck concurrently
+func executeMembers(ctx context.Context, members []*member) error {
+	errs := make([]error, len(members))
+	var wg sync.WaitGroup
+	for i, m := range members {
+		wg.Add(1)
+		go func(i int, check Healthcheck) {
+			defer wg.Done()
+			if err := check.Initialize(); err != nil {
+				errs[i] = errors.Wrapf(err, "Fail to initialize the healthcheck")
+				return
+			}
+			errs[i] = ExecuteWithTimeout(ctx, check)
+		}(i, m.healthcheck)
+	}
+	wg.Wait()
+	return aggregate(members, errs)
+}
+
+// executeTargets executes a multi-target or multi-path healthcheck on all
+// its members concurrently. One result is returned per member, followed by
+// the aggregated result of the healthcheck.
+func (c *Component) executeTargets(w *Wrapper) []*Result {
+	results := make([]*Result, len(w.members)+1)
+	var wg sync.WaitGroup
+	// the targets of an healthcheck in an exclusion group are executed
+	// one after the other
+	sequential := w.healthcheck.Base().ExclusionGroup != ""
+	for i, m := range w.members {
+		wg.Add(1)
+		execute := func(i int, m *memberWrapper) {
+			defer wg.Done()
+			result := c.execute(m.wrapper)
+			result.Target = m.target
+			results[i] = result
+		}
+		if sequential {
+			execute(i, m)
+		} else {
+			go execute(i, m)
+		}
+	}
+	wg.Wait()
+	var duration int64
+	var degraded []string
+	dependencyFailure := true
+	members := make([]*member, len(w.members))
+	errs := make([]error, len(w.members))
+	for i, result := range results[:len(w.members)] {
+		members[i] = &w.members[i].member
+		if result.Duration > duration {
+			duration = result.Duration
+		}
+		if !result.DependencyFailure {
+			dependencyFailure = false
+		}
+		if !result.Success {
+			errs[i] = withCategory(result.ErrorCategory, errors.New(result.Message))
+		}
+		if result.Degraded {
+			degraded = append(degraded, w.members[i].name())
+		}
+	}
+	result := NewResult(w.healthcheck, duration, aggregate(members, errs))
+	if result.Success && len(degraded) != 0 {
+		result.Degraded = true
+		result.Message = fmt.Sprintf("the healthcheck is degraded on %s", strings.Join(degraded, ", "))
+	}
+	if dependencyFailure {
+		result.DependencyFailure = true
+		result.State = w.state.current()
+	} else {
+		c.record(w, result, time.Duration(duration)*time.Millisecond)
+	}
+	results[len(w.members)] = result
+	return results
+}
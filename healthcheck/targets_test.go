@@ -0,0 +1,141 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestValidateTargets(t *testing.T) {
+	cases := []struct {
+		target  string
+		targets []string
+		valid   bool
+	}{
+		{target: "127.0.0.1", valid: true},
+		{targets: []string{"127.0.0.1", "127.0.0.2"}, valid: true},
+		{valid: false},
+		{target: "127.0.0.1", targets: []string{"127.0.0.2"}, valid: false},
+		{targets: []string{"127.0.0.1", ""}, valid: false},
+		{targets: []string{"127.0.0.1", "127.0.0.1"}, valid: false},
+	}
+	for _, c := range cases {
+		err := validateTargets(c.target, c.targets)
+		if c.valid && err != nil {
+			t.Fatalf("Unexpected error for %s %v:\n%v", c.target, c.targets, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Was expecting an error for %s %v", c.target, c.targets)
+		}
+	}
+}
+
+func TestExecuteTargets(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	// a closed port
+	listener, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Fatalf("Fail to listen:\n%v", err)
+	}
+	listener.Close()
+
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	config := &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(5 * time.Second),
+		},
+		Targets: []string{"127.0.0.1"},
+		Port:    uint(port),
+		Timeout: Duration(2 * time.Second),
+	}
+	wrapper := NewWrapper(NewTCPHealthcheck(logger, config))
+	err = wrapper.initialize()
+	if err != nil {
+		t.Fatalf("Fail to initialize the healthcheck\n%v", err)
+	}
+	results := component.executeTargets(wrapper)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Name != "foo-127.0.0.1" || results[0].Target != "127.0.0.1" || !results[0].Success {
+		t.Fatalf("Invalid target result %+v", results[0])
+	}
+	if results[1].Name != "foo" || results[1].Target != "" || !results[1].Success {
+		t.Fatalf("Invalid aggregated result %+v", results[1])
+	}
+	err = ExecuteWithTimeout(context.Background(), NewTCPHealthcheck(logger, config))
+	if err != nil {
+		t.Fatalf("Unexpected error\n%v", err)
+	}
+
+	_, closedPort, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Invalid address:\n%v", err)
+	}
+	config = &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "bar",
+			Interval: Duration(5 * time.Second),
+		},
+		Targets: []string{"127.0.0.1", "127.0.0.2"},
+		Port:    uint(port),
+		Timeout: Duration(2 * time.Second),
+	}
+	wrapper = NewWrapper(NewTCPHealthcheck(logger, config))
+	wrapper.members[1].wrapper.healthcheck.(*TCPHealthcheck).Config.Port, err = parsePort(closedPort)
+	if err != nil {
+		t.Fatalf("Invalid port:\n%v", err)
+	}
+	err = wrapper.initialize()
+	if err != nil {
+		t.Fatalf("Fail to initialize the healthcheck\n%v", err)
+	}
+	results = component.executeTargets(wrapper)
+	if !results[0].Success || results[1].Success || results[1].Target != "127.0.0.2" {
+		t.Fatalf("Invalid targets results %+v %+v", results[0], results[1])
+	}
+	if results[2].Success || results[2].ErrorCategory != ErrorConnectionRefused {
+		t.Fatalf("Invalid aggregated result %+v", results[2])
+	}
+	if !strings.Contains(results[2].Message, "1/2 targets (127.0.0.2)") {
+		t.Fatalf("Invalid message %s", results[2].Message)
+	}
+	if results[2].State != StateUnhealthy {
+		t.Fatalf("Invalid state %s", results[2].State)
+	}
+	err = wrapper.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the wrapper\n%v", err)
+	}
+}
+
+// parsePort parses a port number
+func parsePort(value string) (uint, error) {
+	port, err := strconv.ParseUint(value, 10, 16)
+	return uint(port), err
+}
@@ -10,18 +10,60 @@ import (
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
-	"gopkg.in/tomb.v2"
+	"github.com/appclacks/cabourotte/expression"
 )
 
 // TCPHealthcheckConfiguration defines a TCP healthcheck configuration
 type TCPHealthcheckConfiguration struct {
 	Base `json:",inline" yaml:",inline"`
 	// can be an IP or a domain
-	Target     string   `json:"target"`
-	Port       uint     `json:"port"`
-	SourceIP   IP       `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
+	Target string `json:"target"`
+	// Targets executes the healthcheck on each target, instead of Target
+	Targets  []string `json:"targets,omitempty" yaml:"targets,omitempty"`
+	Port     uint     `json:"port"`
+	SourceIP IP       `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
+	// IPVersion restricts the healthcheck to the IPv4 (4) or IPv6 (6)
+	// addresses of the target
+	IPVersion uint `json:"ip-version,omitempty" yaml:"ip-version,omitempty"`
+	// Paths executes the healthcheck simultaneously on each network path
+	Paths      []Path   `json:"paths,omitempty" yaml:"paths,omitempty"`
 	Timeout    Duration `json:"timeout"`
 	ShouldFail bool     `json:"should-fail" yaml:"should-fail"`
+	// DisableDNSCache resolves the target without the DNS cache
+	DisableDNSCache bool `json:"disable-dns-cache,omitempty" yaml:"disable-dns-cache,omitempty"`
+	// Resolution when the target is resolved: on each execution
+	// (execution), once (registration), or cached during the
+	// resolution-ttl (cache). By default, the target is resolved on each
+	// new connection.
+	Resolution    string   `json:"resolution,omitempty" yaml:"resolution,omitempty"`
+	ResolutionTTL Duration `json:"resolution-ttl,omitempty" yaml:"resolution-ttl,omitempty"`
+	// DNSTimeout the timeout of the resolution of the target, in order
+	// to distinguish a slow resolver from a slow target
+	DNSTimeout Duration `json:"dns-timeout,omitempty" yaml:"dns-timeout,omitempty"`
+	// WarningThreshold the result is degraded if the connection is slower
+	WarningThreshold Duration `json:"warning-threshold,omitempty" yaml:"warning-threshold,omitempty"`
+	// CriticalThreshold the healthcheck fails if the connection is slower
+	CriticalThreshold Duration `json:"critical-threshold,omitempty" yaml:"critical-threshold,omitempty"`
+	// Proxy the proxy used to reach the target, a SOCKS5 proxy URL, or direct to
+	// ignore the global proxy
+	Proxy string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// NetworkNamespace the Linux network namespace in which the
+	// connections are opened, a name of /var/run/netns or a path
+	NetworkNamespace string `json:"network-namespace,omitempty" yaml:"network-namespace,omitempty"`
+	// VRF the VRF or the network interface the connections are bound to
+	VRF string `json:"vrf,omitempty" yaml:"vrf,omitempty"`
+	// Exchanges the payloads sent to the target once connected, and the
+	// responses expected
+	Exchanges []Exchange `json:"exchanges,omitempty" yaml:"exchanges,omitempty"`
+	// Encoding the encoding of the exchanges payloads: text (default) or
+	// hex
+	Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+	// Framing the length prefix of the exchanges payloads: none
+	// (default), uint8, uint16be, uint16le, uint32be or uint32le
+	Framing string `json:"framing,omitempty" yaml:"framing,omitempty"`
+	// Assertions the expressions evaluated on the connection, which
+	// should all be true
+	Assertions []string `json:"assertions,omitempty" yaml:"assertions,omitempty"`
 }
 
 // Validate validates the healthcheck configuration
@@ -29,8 +71,14 @@ func (config *TCPHealthcheckConfiguration) Validate() error {
 	if config.Base.Name == "" {
 		return errors.New("The healthcheck name is missing")
 	}
-	if config.Target == "" {
-		return errors.New("The healthcheck target is missing")
+	if err := validateTargets(config.Target, config.Targets); err != nil {
+		return err
+	}
+	if err := validatePaths(config.Paths, config.Targets, config.SourceIP, config.IPVersion); err != nil {
+		return err
+	}
+	if err := validateResolution(config.Resolution, config.ResolutionTTL); err != nil {
+		return err
 	}
 	if config.Port == 0 {
 		return errors.New("The healthcheck port is missing")
@@ -38,7 +86,40 @@ func (config *TCPHealthcheckConfiguration) Validate() error {
 	if config.Timeout == 0 {
 		return errors.New("The healthcheck timeout is missing")
 	}
-	if !config.Base.OneOff {
+	if err := validateDNSTimeout(config.DNSTimeout, config.Timeout); err != nil {
+		return err
+	}
+	if config.WarningThreshold < 0 || config.CriticalThreshold < 0 {
+		return errors.New("The healthcheck thresholds should be positive")
+	}
+	if config.WarningThreshold != 0 && config.CriticalThreshold != 0 && config.WarningThreshold > config.CriticalThreshold {
+		return errors.New("The healthcheck warning-threshold should be lower than the critical-threshold")
+	}
+	if config.WarningThreshold >= config.Timeout || config.CriticalThreshold >= config.Timeout {
+		return errors.New("The healthcheck thresholds should be lower than the timeout")
+	}
+	if config.ShouldFail && (config.WarningThreshold != 0 || config.CriticalThreshold != 0) {
+		return errors.New("The healthcheck thresholds cannot be used with should-fail")
+	}
+	if err := validateProxy(config.Proxy, "socks5"); err != nil {
+		return err
+	}
+	if err := validateNetwork(config.NetworkNamespace, config.VRF); err != nil {
+		return err
+	}
+	if err := validateExchanges(config.Exchanges, config.Encoding, config.Framing); err != nil {
+		return err
+	}
+	if config.ShouldFail && len(config.Exchanges) != 0 {
+		return errors.New("The healthcheck exchanges cannot be used with should-fail")
+	}
+	if _, err := compileAssertions(config.Assertions, tcpAssertionVariables); err != nil {
+		return err
+	}
+	if config.ShouldFail && len(config.Assertions) != 0 {
+		return errors.New("The healthcheck assertions cannot be used with should-fail")
+	}
+	if !config.Base.OneOff && config.Base.Cron == nil {
 		if config.Base.Interval < Duration(2*time.Second) {
 			return errors.New("The healthcheck interval should be greater than 2 second")
 		}
@@ -46,6 +127,10 @@ func (config *TCPHealthcheckConfiguration) Validate() error {
 			return errors.New("The healthcheck interval should be greater than the timeout")
 		}
 	}
+	err := config.Base.ValidateBase()
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -55,8 +140,9 @@ type TCPHealthcheck struct {
 	Config *TCPHealthcheckConfiguration
 	URL    string
 
-	Tick *time.Ticker
-	t    tomb.Tomb
+	Tick       *time.Ticker
+	cache      *addressCache
+	assertions []*expression.Expression
 }
 
 // buildURL build the target URL for the TCP healthcheck, depending of its
@@ -69,10 +155,10 @@ func (h *TCPHealthcheck) buildURL() {
 func (h *TCPHealthcheck) Summary() string {
 	summary := ""
 	if h.Config.Base.Description != "" {
-		summary = fmt.Sprintf("TCP healthcheck %s on %s:%d", h.Config.Base.Description, h.Config.Target, h.Config.Port)
+		summary = fmt.Sprintf("TCP healthcheck %s on %s:%d", h.Config.Base.Description, displayTargets(h.Config.Target, h.Config.Targets), h.Config.Port)
 
 	} else {
-		summary = fmt.Sprintf("TCP healthcheck on %s:%d", h.Config.Target, h.Config.Port)
+		summary = fmt.Sprintf("TCP healthcheck on %s:%d", displayTargets(h.Config.Target, h.Config.Targets), h.Config.Port)
 	}
 
 	if h.Config.ShouldFail {
@@ -85,6 +171,12 @@ func (h *TCPHealthcheck) Summary() string {
 // Initialize the healthcheck.
 func (h *TCPHealthcheck) Initialize() error {
 	h.buildURL()
+	assertions, err := compileAssertions(h.Config.Assertions, tcpAssertionVariables)
+	if err != nil {
+		return err
+	}
+	h.assertions = assertions
+	h.cache = newAddressCache(h.Config.Resolution, h.Config.ResolutionTTL)
 	return nil
 }
 
@@ -98,6 +190,11 @@ func (h *TCPHealthcheck) Base() Base {
 	return h.Config.Base
 }
 
+// Timeout returns the healthcheck timeout
+func (h *TCPHealthcheck) Timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
 // SetSource set the healthcheck source
 func (h *TCPHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
@@ -129,9 +226,8 @@ func (h *TCPHealthcheck) LogInfo(message string) {
 }
 
 // Execute executes an healthcheck on the given target
-func (h *TCPHealthcheck) Execute() error {
+func (h *TCPHealthcheck) Execute(ctx context.Context) error {
 	h.LogDebug("start executing healthcheck")
-	ctx := h.t.Context(context.TODO())
 	dialer := net.Dialer{}
 	if h.Config.SourceIP != nil {
 		srcIP := net.IP(h.Config.SourceIP).String()
@@ -143,19 +239,55 @@ func (h *TCPHealthcheck) Execute() error {
 			LocalAddr: addr,
 		}
 	}
-	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
-	defer cancel()
-	conn, err := dialer.DialContext(timeoutCtx, "tcp", h.URL)
+	applyPolicy(ctx, &dialer)
+	d := applyNetwork(&dialer, h.Config.NetworkNamespace, h.Config.VRF)
+	// the connection phase includes the resolution of the target
+	connectStart := time.Now()
+	proxy, err := proxyURL(ctx, h.Config.Proxy, "tcp", h.Config.Target)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid proxy")
+	}
+	var conn net.Conn
+	if proxy != nil {
+		conn, err = dialSOCKS(ctx, d, proxy, h.URL)
+	} else {
+		conn, err = dial(ctx, d, network(h.Config.IPVersion), h.URL, !h.Config.DisableDNSCache, h.cache, time.Duration(h.Config.DNSTimeout))
+	}
+	connectEnd := time.Now()
+	if err == nil {
+		recordPhase(ctx, PhaseConnect, connectStart, connectEnd)
+	}
 	if h.Config.ShouldFail {
 		if err == nil {
 			defer conn.Close()
-			return fmt.Errorf("TCP check is successful on %s but an error was expected", h.URL)
+			return assertionError("TCP check is successful on %s but an error was expected", h.URL)
 		}
 	} else {
 		if err != nil {
 			return errors.Wrapf(err, "TCP connection failed on %s", h.URL)
 		}
 		defer conn.Close()
+		latency := connectEnd.Sub(connectStart)
+		if h.Config.CriticalThreshold != 0 && latency > time.Duration(h.Config.CriticalThreshold) {
+			return withCategory(ErrorLatency, fmt.Errorf("TCP connection on %s took %s, above the critical threshold of %s", h.URL, latency, time.Duration(h.Config.CriticalThreshold)))
+		}
+		if h.Config.WarningThreshold != 0 && latency > time.Duration(h.Config.WarningThreshold) {
+			markDegraded(ctx, "TCP connection on %s took %s, above the warning threshold of %s", h.URL, latency, time.Duration(h.Config.WarningThreshold))
+		}
+		if len(h.Config.Exchanges) != 0 {
+			if err := exchange(ctx, conn, h.Config.Exchanges, h.Config.Encoding, h.Config.Framing); err != nil {
+				return errors.Wrapf(err, "TCP exchange failed on %s", h.URL)
+			}
+		}
+		if len(h.assertions) != 0 {
+			ip, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+			return verifyAssertions(h.assertions, map[string]interface{}{
+				"ip":       ip,
+				"port":     h.Config.Port,
+				"duration": milliseconds(latency),
+				"timings":  timings(ctx),
+			})
+		}
 	}
 	return nil
 }
@@ -182,6 +314,28 @@ func (in *TCPHealthcheckConfiguration) DeepCopyInto(out *TCPHealthcheckConfigura
 		*out = make(IP, len(*in))
 		copy(*out, *in)
 	}
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]Path, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Exchanges != nil {
+		in, out := &in.Exchanges, &out.Exchanges
+		*out = make([]Exchange, len(*in))
+		copy(*out, *in)
+	}
+	if in.Assertions != nil {
+		in, out := &in.Assertions, &out.Assertions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPHealthcheckConfiguration.
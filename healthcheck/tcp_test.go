@@ -1,6 +1,9 @@
 package healthcheck
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -47,7 +50,7 @@ func TestTCPExecuteSuccess(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -73,7 +76,7 @@ func TestTCPExecuteSuccessSourceIP(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -106,7 +109,7 @@ func TestTCPv6ExecuteSuccess(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -133,7 +136,7 @@ func TestTCPStartStop(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
 	}
-	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{})
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{}, ConcurrencyConfiguration{})
 	if err != nil {
 		t.Fatalf("Fail to create the component\n%v", err)
 	}
@@ -155,8 +158,359 @@ func TestTCPExecuteSuccessShoulddFail(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err := h.Execute()
+	err := h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
 }
+
+// fakeResolver resolves all the hosts to 127.0.0.1
+type fakeResolver struct {
+	lookups int
+}
+
+func (r *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.lookups++
+	if host == "unknown.example.com" {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return []net.IPAddr{{IP: net.IPv4(127, 0, 0, 1)}}, nil
+}
+
+func TestTCPExecuteResolver(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	resolver := &fakeResolver{}
+	ctx := withResolver(context.Background(), resolver)
+	cases := []struct {
+		target   string
+		disabled bool
+		lookups  int
+		category string
+	}{
+		{"cabourotte.example.com", false, 1, ""},
+		{"127.0.0.1", false, 1, ""},
+		{"unknown.example.com", false, 2, ErrorDNS},
+		{"localhost", true, 2, ""},
+	}
+	for _, c := range cases {
+		h := TCPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &TCPHealthcheckConfiguration{
+				Port:            uint(port),
+				Target:          c.target,
+				Timeout:         Duration(time.Second * 2),
+				DisableDNSCache: c.disabled,
+			},
+		}
+		h.buildURL()
+		err = h.Execute(ctx)
+		if ErrorCategory(err) != c.category {
+			t.Fatalf("Invalid error for %s: %v", c.target, err)
+		}
+		if resolver.lookups != c.lookups {
+			t.Fatalf("Invalid number of lookups for %s: %d", c.target, resolver.lookups)
+		}
+	}
+}
+
+// slowResolver blocks until the context is done
+type slowResolver struct{}
+
+func (r *slowResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestTCPExecuteDNSTimeout(t *testing.T) {
+	ctx := withResolver(context.Background(), &slowResolver{})
+	h := TCPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &TCPHealthcheckConfiguration{
+			Port:       9000,
+			Target:     "cabourotte.example.com",
+			Timeout:    Duration(time.Second * 2),
+			DNSTimeout: Duration(100 * time.Millisecond),
+		},
+	}
+	h.buildURL()
+	start := time.Now()
+	err := h.Execute(ctx)
+	if ErrorCategory(err) != ErrorDNSTimeout {
+		t.Fatalf("Invalid error: %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("The DNS timeout was not applied")
+	}
+	for _, c := range []struct {
+		dnsTimeout Duration
+		timeout    Duration
+	}{
+		{dnsTimeout: Duration(-time.Second), timeout: Duration(time.Second)},
+		{dnsTimeout: Duration(time.Second), timeout: Duration(time.Second)},
+	} {
+		if err := validateDNSTimeout(c.dnsTimeout, c.timeout); err == nil {
+			t.Fatalf("Was expecting an error for the dns-timeout %s", time.Duration(c.dnsTimeout))
+		}
+	}
+}
+
+func TestTCPExecuteThresholds(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		warning  Duration
+		critical Duration
+		success  bool
+		degraded bool
+	}{
+		{success: true},
+		{warning: Duration(time.Second), critical: Duration(time.Second), success: true},
+		{warning: Duration(time.Nanosecond), critical: Duration(time.Second), success: true, degraded: true},
+		{warning: Duration(time.Nanosecond), critical: Duration(time.Nanosecond), success: false},
+	}
+	for i, c := range cases {
+		h := NewTCPHealthcheck(zap.NewExample(), &TCPHealthcheckConfiguration{
+			Port:              uint(port),
+			Target:            "127.0.0.1",
+			Timeout:           Duration(time.Second * 2),
+			WarningThreshold:  c.warning,
+			CriticalThreshold: c.critical,
+		})
+		result := ExecuteOnce(context.Background(), h)
+		if result.Success != c.success || result.Degraded != c.degraded {
+			t.Fatalf("Invalid result for the case %d: %+v", i, result)
+		}
+		if !c.success && result.ErrorCategory != ErrorLatency {
+			t.Fatalf("Invalid error category %s", result.ErrorCategory)
+		}
+		if _, ok := result.Phases[PhaseConnect]; c.success && !ok {
+			t.Fatalf("The connection latency is missing")
+		}
+	}
+}
+
+func TestTCPExecuteResolution(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		resolution string
+		ttl        Duration
+		lookups    int
+	}{
+		{resolution: "", lookups: 3},
+		{resolution: ResolutionExecution, lookups: 3},
+		{resolution: ResolutionRegistration, lookups: 1},
+		{resolution: ResolutionCache, ttl: Duration(time.Hour), lookups: 1},
+	}
+	for _, c := range cases {
+		resolver := &fakeResolver{}
+		ctx := withResolver(context.Background(), resolver)
+		h := NewTCPHealthcheck(zap.NewExample(), &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name: "foo",
+			},
+			Port:          uint(port),
+			Target:        "cabourotte.example.com",
+			Timeout:       Duration(time.Second * 2),
+			Resolution:    c.resolution,
+			ResolutionTTL: c.ttl,
+		})
+		err := h.Initialize()
+		if err != nil {
+			t.Fatalf("Fail to initialize the healthcheck: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			ctx, address := withAddress(ctx)
+			err = h.Execute(ctx)
+			if err != nil {
+				t.Fatalf("Fail to execute the healthcheck with the %s resolution: %v", c.resolution, err)
+			}
+			if address.get() != "127.0.0.1" {
+				t.Fatalf("Invalid resolved IP %s", address.get())
+			}
+		}
+		if resolver.lookups != c.lookups {
+			t.Fatalf("Invalid number of lookups for the %s resolution: %d", c.resolution, resolver.lookups)
+		}
+	}
+	for _, c := range []struct {
+		resolution string
+		ttl        Duration
+	}{
+		{resolution: "foo"},
+		{resolution: ResolutionCache},
+		{resolution: ResolutionRegistration, ttl: Duration(time.Second)},
+	} {
+		if err := validateResolution(c.resolution, c.ttl); err == nil {
+			t.Fatalf("Was expecting an error for the %s resolution", c.resolution)
+		}
+	}
+}
+
+func TestTCPExecuteExchanges(t *testing.T) {
+	// echo server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the server: %s", err.Error())
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				// nolint
+				io.Copy(conn, conn)
+			}(conn)
+		}
+	}()
+	cases := []struct {
+		exchanges []Exchange
+		encoding  string
+		framing   string
+		err       bool
+	}{
+		{exchanges: []Exchange{{Send: "PING", Expect: "PING"}}},
+		{exchanges: []Exchange{{Send: "PING", Expect: "PONG"}}, err: true},
+		{exchanges: []Exchange{{Send: "HELLO"}, {Send: "PING", Expect: "HELLOPING"}}},
+		{exchanges: []Exchange{{Send: "01 00 2a", Expect: "0100"}}, encoding: EncodingHex, framing: FramingUint16BE},
+		{exchanges: []Exchange{{Send: "01 00 2a", Expect: "02"}}, encoding: EncodingHex, framing: FramingUint32LE, err: true},
+		{exchanges: []Exchange{{Send: "ping", Expect: "ping"}, {Send: "pong", Expect: "pong"}}, framing: FramingUint8},
+	}
+	for i, c := range cases {
+		config := &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Minute),
+			},
+			Target:    "127.0.0.1",
+			Port:      uint(listener.Addr().(*net.TCPAddr).Port),
+			Timeout:   Duration(time.Second),
+			Exchanges: c.exchanges,
+			Encoding:  c.encoding,
+			Framing:   c.framing,
+		}
+		err := config.Validate()
+		if err != nil {
+			t.Fatalf("Invalid configuration for the case %d:\n%v", i, err)
+		}
+		h := NewTCPHealthcheck(zap.NewExample(), config)
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err = h.Execute(ctx)
+		cancel()
+		if c.err {
+			if err == nil || ErrorCategory(err) != ErrorAssertion {
+				t.Fatalf("Was expecting an assertion error for the case %d, got %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("healthcheck error for the case %d:\n%v", i, err)
+		}
+	}
+}
+
+func TestTCPValidateExchanges(t *testing.T) {
+	cases := []struct {
+		exchanges  []Exchange
+		encoding   string
+		framing    string
+		shouldFail bool
+	}{
+		{exchanges: []Exchange{{}}},
+		{exchanges: []Exchange{{Send: "zz"}}, encoding: EncodingHex},
+		{exchanges: []Exchange{{Send: "ping"}}, encoding: "base64"},
+		{exchanges: []Exchange{{Send: "ping"}}, framing: "varint"},
+		{exchanges: []Exchange{{Send: strings.Repeat("a", 256)}}, framing: FramingUint8},
+		{exchanges: []Exchange{{Send: "ping"}}, shouldFail: true},
+	}
+	for i, c := range cases {
+		config := TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Minute),
+			},
+			Target:     "127.0.0.1",
+			Port:       22,
+			Timeout:    Duration(time.Second),
+			ShouldFail: c.shouldFail,
+			Exchanges:  c.exchanges,
+			Encoding:   c.encoding,
+			Framing:    c.framing,
+		}
+		if err := config.Validate(); err == nil {
+			t.Fatalf("Was expecting an error for the case %d", i)
+		}
+	}
+}
+
+func TestTCPExecuteAssertions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		assertion string
+		success   bool
+	}{
+		{assertion: "ip == '127.0.0.1'", success: true},
+		{assertion: fmt.Sprintf("port == %d && duration < 2000", port), success: true},
+		{assertion: "startsWith(ip, '10.')", success: false},
+	}
+	for _, c := range cases {
+		h := TCPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &TCPHealthcheckConfiguration{
+				Port:       uint(port),
+				Target:     "127.0.0.1",
+				Timeout:    Duration(time.Second * 2),
+				Assertions: []string{c.assertion},
+			},
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		err = h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %s:\n%v", c.assertion, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %s", c.assertion)
+		}
+	}
+}
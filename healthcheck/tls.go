@@ -11,16 +11,22 @@ import (
 	"github.com/appclacks/cabourotte/tls"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
-	"gopkg.in/tomb.v2"
 )
 
 // TLSHealthcheckConfiguration defines a TLS healthcheck configuration
 type TLSHealthcheckConfiguration struct {
 	Base `json:",inline" yaml:",inline"`
 	// can be an IP or a domain
-	Target          string   `json:"target"`
-	Port            uint     `json:"port"`
-	SourceIP        IP       `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
+	Target string `json:"target"`
+	// Targets executes the healthcheck on each target, instead of Target
+	Targets  []string `json:"targets,omitempty" yaml:"targets,omitempty"`
+	Port     uint     `json:"port"`
+	SourceIP IP       `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
+	// IPVersion restricts the healthcheck to the IPv4 (4) or IPv6 (6)
+	// addresses of the target
+	IPVersion uint `json:"ip-version,omitempty" yaml:"ip-version,omitempty"`
+	// Paths executes the healthcheck simultaneously on each network path
+	Paths           []Path   `json:"paths,omitempty" yaml:"paths,omitempty"`
 	Timeout         Duration `json:"timeout"`
 	Key             string   `json:"key,omitempty"`
 	Cert            string   `json:"cert,omitempty"`
@@ -28,6 +34,13 @@ type TLSHealthcheckConfiguration struct {
 	ServerName      string   `json:"server-name,omitempty" yaml:"server-name"`
 	Insecure        bool     `json:"insecure"`
 	ExpirationDelay Duration `json:"expiration-delay" yaml:"expiration-delay"`
+	// Proxy the proxy used to reach the target, a SOCKS5 proxy URL, or direct to
+	// ignore the global proxy
+	Proxy string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// Credential the name of the credential containing the certificates
+	Credential string `json:"credential,omitempty" yaml:"credential,omitempty"`
+
+	credential *Credential
 }
 
 // TLSHealthcheck defines a TLS healthcheck
@@ -38,7 +51,6 @@ type TLSHealthcheck struct {
 	TLSConfig *cryptotls.Config
 
 	Tick *time.Ticker
-	t    tomb.Tomb
 }
 
 // Validate validates the healthcheck configuration
@@ -46,8 +58,11 @@ func (config *TLSHealthcheckConfiguration) Validate() error {
 	if config.Base.Name == "" {
 		return errors.New("The healthcheck name is missing")
 	}
-	if config.Target == "" {
-		return errors.New("The healthcheck target is missing")
+	if err := validateTargets(config.Target, config.Targets); err != nil {
+		return err
+	}
+	if err := validatePaths(config.Paths, config.Targets, config.SourceIP, config.IPVersion); err != nil {
+		return err
 	}
 	if config.Port == 0 {
 		return errors.New("The healthcheck port is missing")
@@ -55,7 +70,10 @@ func (config *TLSHealthcheckConfiguration) Validate() error {
 	if config.Timeout == 0 {
 		return errors.New("The healthcheck timeout is missing")
 	}
-	if !config.Base.OneOff {
+	if err := validateProxy(config.Proxy, "socks5"); err != nil {
+		return err
+	}
+	if !config.Base.OneOff && config.Base.Cron == nil {
 		if config.Base.Interval < Duration(2*time.Second) {
 			return errors.New("The healthcheck interval should be greater than 2 second")
 		}
@@ -63,6 +81,10 @@ func (config *TLSHealthcheckConfiguration) Validate() error {
 			return errors.New("The healthcheck interval should be greater than the timeout")
 		}
 	}
+	err := config.Base.ValidateBase()
+	if err != nil {
+		return err
+	}
 	if !((config.Key != "" && config.Cert != "") ||
 		(config.Key == "" && config.Cert == "")) {
 		return errors.New("Invalid certificates")
@@ -75,6 +97,11 @@ func (h *TLSHealthcheck) Base() Base {
 	return h.Config.Base
 }
 
+// Timeout returns the healthcheck timeout
+func (h *TLSHealthcheck) Timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
 // SetSource set the healthcheck source
 func (h *TLSHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
@@ -84,10 +111,10 @@ func (h *TLSHealthcheck) SetSource(source string) {
 func (h *TLSHealthcheck) Summary() string {
 	summary := ""
 	if h.Config.Base.Description != "" {
-		summary = fmt.Sprintf("TLS healthcheck %s on %s:%d", h.Config.Base.Description, h.Config.Target, h.Config.Port)
+		summary = fmt.Sprintf("TLS healthcheck %s on %s:%d", h.Config.Base.Description, displayTargets(h.Config.Target, h.Config.Targets), h.Config.Port)
 
 	} else {
-		summary = fmt.Sprintf("TLS healthcheck on %s:%d", h.Config.Target, h.Config.Port)
+		summary = fmt.Sprintf("TLS healthcheck on %s:%d", displayTargets(h.Config.Target, h.Config.Targets), h.Config.Port)
 	}
 
 	return summary
@@ -102,7 +129,8 @@ func (h *TLSHealthcheck) buildURL() {
 // Initialize the healthcheck.
 func (h *TLSHealthcheck) Initialize() error {
 	h.buildURL()
-	tlsConfig, err := tls.GetTLSConfig(h.Config.Key, h.Config.Cert, h.Config.Cacert, h.Config.ServerName, h.Config.Insecure)
+	key, cert, cacert := h.Config.credential.certificates(h.Config.Key, h.Config.Cert, h.Config.Cacert)
+	tlsConfig, err := tls.GetTLSConfig(key, cert, cacert, h.Config.ServerName, h.Config.Insecure)
 	if err != nil {
 		return err
 	}
@@ -141,10 +169,9 @@ func (h *TLSHealthcheck) LogInfo(message string) {
 }
 
 // Execute executes an healthcheck on the given target
-func (h *TLSHealthcheck) Execute() error {
+func (h *TLSHealthcheck) Execute(ctx context.Context) error {
 	h.LogDebug("start executing healthcheck")
 	dialer := net.Dialer{}
-	ctx := h.t.Context(context.TODO())
 	if h.Config.SourceIP != nil {
 		srcIP := net.IP(h.Config.SourceIP).String()
 		addr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:0", srcIP))
@@ -153,25 +180,39 @@ func (h *TLSHealthcheck) Execute() error {
 		}
 		dialer = net.Dialer{
 			LocalAddr: addr,
-			Timeout:   time.Duration(h.Config.Timeout),
 		}
 	}
-
-	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
-	defer cancel()
-	conn, err := dialer.DialContext(timeoutCtx, "tcp", h.URL)
+	applyPolicy(ctx, &dialer)
+	// the connection phase includes the resolution of the target
+	connectStart := time.Now()
+	proxy, err := proxyURL(ctx, h.Config.Proxy, "tcp", h.Config.Target)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid proxy")
+	}
+	var conn net.Conn
+	if proxy != nil {
+		conn, err = dialSOCKS(ctx, &dialer, proxy, h.URL)
+	} else {
+		conn, err = dialer.DialContext(ctx, network(h.Config.IPVersion), h.URL)
+	}
+	if err == nil {
+		recordPhase(ctx, PhaseConnect, connectStart, time.Now())
+	}
 	if err != nil {
 		return errors.Wrapf(err, "TLS connection failed on %s", h.URL)
 	}
 	defer conn.Close()
 	tlsConn := cryptotls.Client(conn, h.TLSConfig)
 	defer tlsConn.Close()
-	err = tlsConn.Handshake()
+	handshakeStart := time.Now()
+	err = tlsConn.HandshakeContext(ctx)
 	if err != nil {
-		return errors.Wrapf(err, "TLS handshake failed on %s", h.URL)
+		return withCategory(ErrorTLS, errors.Wrapf(err, "TLS handshake failed on %s", h.URL))
 	}
+	recordPhase(ctx, PhaseTLS, handshakeStart, time.Now())
+	state := tlsConn.ConnectionState()
+	recordTLS(ctx, state)
 	if h.Config.ExpirationDelay != 0 {
-		state := tlsConn.ConnectionState()
 		expirationTime := time.Time{}
 		for _, cert := range state.PeerCertificates {
 			if (expirationTime.IsZero() || cert.NotAfter.Before(expirationTime)) && !cert.NotAfter.IsZero() {
@@ -180,7 +221,7 @@ func (h *TLSHealthcheck) Execute() error {
 		}
 		expirationTimeLimit := time.Now().Add(time.Duration(h.Config.ExpirationDelay))
 		if expirationTime.Before(expirationTimeLimit) {
-			return fmt.Errorf("The certificate for %s will expire at %s", h.URL, expirationTime.String())
+			return assertionError("The certificate for %s will expire at %s", h.URL, expirationTime.String())
 		}
 	}
 
@@ -209,6 +250,23 @@ func (in *TLSHealthcheckConfiguration) DeepCopyInto(out *TLSHealthcheckConfigura
 		*out = make(IP, len(*in))
 		copy(*out, *in)
 	}
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]Path, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.credential != nil {
+		in, out := &in.credential, &out.credential
+		*out = new(Credential)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSHealthcheckConfiguration.
@@ -1,6 +1,7 @@
 package healthcheck
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -44,7 +45,7 @@ func TestTLSExecuteError(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("Was expecting an error")
 	}
@@ -60,8 +61,49 @@ func TestTLSExecuteErrorNoTarget(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err := h.Execute()
+	err := h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("Was expecting an error")
 	}
 }
+
+func TestTLSExecuteTLSInfo(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := NewTLSHealthcheck(zap.NewExample(), &TLSHealthcheckConfiguration{
+		Base: Base{
+			Name: "foo",
+		},
+		Port:     uint(port),
+		Target:   "127.0.0.1",
+		Timeout:  Duration(time.Second * 2),
+		Insecure: true,
+	})
+	result := ExecuteOnce(context.Background(), h)
+	if !result.Success {
+		t.Fatalf("The healthcheck should be successful: %s", result.Message)
+	}
+	if result.TLS == nil {
+		t.Fatalf("The TLS details are missing")
+	}
+	if result.TLS.Version == "" || result.TLS.CipherSuite == "" {
+		t.Fatalf("Invalid TLS details %+v", result.TLS)
+	}
+	if len(result.TLS.Certificates) != 1 {
+		t.Fatalf("Invalid certificates %+v", result.TLS.Certificates)
+	}
+	cert := result.TLS.Certificates[0]
+	if cert.Subject == "" || cert.Issuer == "" || cert.NotAfter.IsZero() {
+		t.Fatalf("Invalid certificate %+v", cert)
+	}
+	if len(cert.DNSNames) == 0 || len(cert.IPAddresses) == 0 || cert.IPAddresses[0] != "127.0.0.1" {
+		t.Fatalf("Invalid certificate SANs %+v", cert)
+	}
+}
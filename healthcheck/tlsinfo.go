@@ -0,0 +1,113 @@
+package healthcheck
+
+import (
+	"context"
+	cryptotls "crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CertificateInfo the details of a certificate presented by the target
+type CertificateInfo struct {
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	DNSNames    []string  `json:"dns-names,omitempty"`
+	IPAddresses []string  `json:"ip-addresses,omitempty"`
+	NotAfter    time.Time `json:"not-after"`
+}
+
+// TLSInfo the details of the TLS connection negotiated with the target
+type TLSInfo struct {
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipher-suite"`
+	// Certificates the certificates chain presented by the target, the
+	// leaf certificate first
+	Certificates []CertificateInfo `json:"certificates,omitempty"`
+}
+
+// tlsVersions the names of the TLS versions
+var tlsVersions = map[uint16]string{
+	cryptotls.VersionTLS10: "TLS 1.0",
+	cryptotls.VersionTLS11: "TLS 1.1",
+	cryptotls.VersionTLS12: "TLS 1.2",
+	cryptotls.VersionTLS13: "TLS 1.3",
+}
+
+// tlsVersionName returns the name of a TLS version
+func tlsVersionName(version uint16) string {
+	if name, ok := tlsVersions[version]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04X", version)
+}
+
+// newTLSInfo builds the TLS details from the state of a connection
+func newTLSInfo(state cryptotls.ConnectionState) *TLSInfo {
+	info := &TLSInfo{
+		Version:     tlsVersionName(state.Version),
+		CipherSuite: cryptotls.CipherSuiteName(state.CipherSuite),
+	}
+	for _, cert := range state.PeerCertificates {
+		certInfo := CertificateInfo{
+			Subject:  cert.Subject.String(),
+			Issuer:   cert.Issuer.String(),
+			DNSNames: cert.DNSNames,
+			NotAfter: cert.NotAfter.UTC(),
+		}
+		for _, ip := range cert.IPAddresses {
+			certInfo.IPAddresses = append(certInfo.IPAddresses, ip.String())
+		}
+		info.Certificates = append(info.Certificates, certInfo)
+	}
+	return info
+}
+
+// tlsInfoKey the context key of the TLS details recorder
+type tlsInfoKey struct{}
+
+// tlsRecorder records the details of the TLS connection of an healthcheck
+// execution
+type tlsRecorder struct {
+	lock sync.Mutex
+	info *TLSInfo
+}
+
+// withTLSInfo returns a context recording the TLS details of the
+// executions
+func withTLSInfo(ctx context.Context) (context.Context, *tlsRecorder) {
+	recorder := &tlsRecorder{}
+	return context.WithValue(ctx, tlsInfoKey{}, recorder), recorder
+}
+
+// tlsRecorderFromContext returns the TLS details recorder of the context,
+// or nil
+func tlsRecorderFromContext(ctx context.Context) *tlsRecorder {
+	recorder, _ := ctx.Value(tlsInfoKey{}).(*tlsRecorder)
+	return recorder
+}
+
+// recordTLS records the state of a TLS connection if the context has a
+// TLS details recorder
+func recordTLS(ctx context.Context, state cryptotls.ConnectionState) {
+	if recorder := tlsRecorderFromContext(ctx); recorder != nil {
+		recorder.lock.Lock()
+		defer recorder.lock.Unlock()
+		recorder.info = newTLSInfo(state)
+	}
+}
+
+// reset removes the TLS details, in order to only keep the details of the
+// last attempt
+func (r *tlsRecorder) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.info = nil
+}
+
+// get returns the recorded TLS details, or nil
+func (r *tlsRecorder) get() *TLSInfo {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.info
+}
@@ -0,0 +1,36 @@
+package healthcheck
+
+import (
+	"time"
+
+	"github.com/appclacks/cabourotte/tracing"
+)
+
+// executionSpan builds the span of an healthcheck execution, the phases
+// of the execution being its children
+func executionSpan(healthcheck Healthcheck, result *Result, start time.Time, end time.Time, phases []Phase) *tracing.Span {
+	base := healthcheck.Base()
+	span := tracing.NewSpan("healthcheck "+base.Name, start, end)
+	span.Attributes["healthcheck.name"] = base.Name
+	span.Attributes["healthcheck.summary"] = healthcheck.Summary()
+	if result.ProbeID != "" {
+		span.Attributes["healthcheck.probe-id"] = result.ProbeID
+	}
+	if base.Namespace != "" {
+		span.Attributes["healthcheck.namespace"] = base.Namespace
+	}
+	if base.Source != "" {
+		span.Attributes["healthcheck.source"] = base.Source
+	}
+	for k, v := range base.Labels {
+		span.Attributes["healthcheck.label."+k] = v
+	}
+	if !result.Success {
+		span.Error = result.Message
+		span.Attributes["healthcheck.error-category"] = result.ErrorCategory
+	}
+	for _, phase := range phases {
+		span.Child(phase.Name, phase.Start, phase.End)
+	}
+	return span
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -208,3 +209,70 @@ func (i *IP) MarshalJSON() ([]byte, error) {
 	ip := net.IP(*i)
 	return json.Marshal(ip.String())
 }
+
+// Jitter is a random delay added to or removed from an healthcheck interval.
+// It can be absolute (`2s`) or a percentage of the interval (`10%`).
+type Jitter struct {
+	Absolute   Duration
+	Percentage float64
+}
+
+func (j *Jitter) parse(s string) error {
+	if strings.HasSuffix(s, "%") {
+		percentage, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil {
+			return errors.Wrapf(err, "%s is not a valid jitter percentage", s)
+		}
+		if percentage < 0 || percentage >= 100 {
+			return fmt.Errorf("The jitter percentage should be between 0 and 100 (got %s)", s)
+		}
+		*j = Jitter{Percentage: percentage}
+		return nil
+	}
+	dur, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.Wrapf(err, "%s is not a valid jitter", s)
+	}
+	*j = Jitter{Absolute: Duration(dur)}
+	return nil
+}
+
+// UnmarshalYAML read a jitter from yaml
+func (j *Jitter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw string
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the healthcheck jitter")
+	}
+	return j.parse(raw)
+}
+
+// UnmarshalText unmarshal a jitter
+func (j *Jitter) UnmarshalText(text []byte) error {
+	return j.parse(unQuote(text))
+}
+
+// UnmarshalJSON unmarshal to json a jitter
+func (j *Jitter) UnmarshalJSON(text []byte) error {
+	return j.UnmarshalText(text)
+}
+
+// String returns the jitter string representation
+func (j Jitter) String() string {
+	if j.Percentage != 0 {
+		return fmt.Sprintf("%s%%", strconv.FormatFloat(j.Percentage, 'f', -1, 64))
+	}
+	return time.Duration(j.Absolute).String()
+}
+
+// MarshalJSON marshal to json a jitter
+func (j Jitter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.String())
+}
+
+// Max returns the maximum jitter for the given interval
+func (j Jitter) Max(interval time.Duration) time.Duration {
+	if j.Percentage != 0 {
+		return time.Duration(float64(interval) * j.Percentage / 100)
+	}
+	return time.Duration(j.Absolute)
+}
@@ -1,33 +1,226 @@
 package healthcheck
 
 import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
-	"gopkg.in/tomb.v2"
+	"github.com/pkg/errors"
 )
 
 // Wrapper Wrap an healthcheck
 type Wrapper struct {
 	healthcheck Healthcheck
-	Tick        *time.Ticker
-	t           tomb.Tomb
+	state       *stateMachine
+	// expiresAt is the expiration time of healthchecks added through the
+	// API with a TTL, protected by the component lock
+	expiresAt time.Time
+
+	// scheduling fields, protected by the scheduler lock
+	scheduler *scheduler
+	next      time.Time
+	index     int
+	stopped   bool
+	// node the name of the node executing the healthcheck, used to
+	// spread the executions
+	node string
+
+	// done is closed and ctx cancelled when the wrapper is stopped,
+	// and wg tracks the executions in progress
+	done     chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	// members the healthchecks executed on each target of a multi-target
+	// healthcheck, or on each path of a multi-path healthcheck
+	members []*memberWrapper
+
+	// anomaly the baseline of the executions durations, nil if the
+	// anomaly detection is disabled
+	anomaly *anomalyDetector
+	// sampler samples the successful results, nil if all the results are
+	// exported
+	sampler *sampler
+}
+
+// memberWrapper wraps a member of a multi-target or multi-path
+// healthcheck
+type memberWrapper struct {
+	member
+	wrapper *Wrapper
 }
 
 // NewWrapper creates a new wrapper struct
 func NewWrapper(healthcheck Healthcheck) *Wrapper {
-	return &Wrapper{
+	base := healthcheck.Base()
+	ctx, cancel := context.WithCancel(context.Background())
+	wrapper := &Wrapper{
 		healthcheck: healthcheck,
+		state:       newStateMachine(base.Rise, base.Fall).withInitialState(base.InitialState).withFlapDetection(base.FlapThreshold, time.Duration(base.FlapWindow)),
+		index:       -1,
+		done:        make(chan struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+		anomaly:     newAnomalyDetector(base.Anomaly),
+		sampler:     newSampler(base.ReportEvery),
+	}
+	for _, m := range members(healthcheck) {
+		wrapper.members = append(wrapper.members, &memberWrapper{
+			member:  *m,
+			wrapper: NewWrapper(m.healthcheck),
+		})
 	}
+	return wrapper
 }
 
-// Stop an Healthcheck wrapper
-func (w *Wrapper) Stop() error {
-	w.Tick.Stop()
-	w.t.Kill(nil)
-	err := w.t.Wait()
-	if err != nil {
+// initialize initializes the healthcheck, and the healthchecks of its
+// members
+func (w *Wrapper) initialize() error {
+	if err := w.healthcheck.Initialize(); err != nil {
 		return err
 	}
+	for _, member := range w.members {
+		if err := member.wrapper.healthcheck.Initialize(); err != nil {
+			return errors.Wrapf(err, "Fail to initialize the healthcheck on %s", member.name())
+		}
+	}
 	return nil
+}
+
+// initialDelay computes the delay before the first execution. The
+// executions are spread over a few seconds if no initial delay is
+// configured.
+func (w *Wrapper) initialDelay() time.Duration {
+	base := w.healthcheck.Base()
+	delay := time.Duration(base.InitialDelay)
+	if base.Cron != nil {
+		now := time.Now()
+		return base.Cron.delay(now, now.Add(delay))
+	}
+	if phase, ok := w.spreadPhase(); ok {
+		now := time.Now()
+		return nextSlot(now.Add(delay), time.Duration(base.Interval), phase).Sub(now)
+	}
+	if delay == 0 {
+		delay = time.Duration(rand.Intn(4000)) * time.Millisecond
+	}
+	return delay
+}
+
+// spreadPhase returns the phase of the executions within the interval,
+// and false if the executions are not spread
+func (w *Wrapper) spreadPhase() (time.Duration, bool) {
+	base := w.healthcheck.Base()
+	if !base.Spread || base.Cron != nil || base.Interval <= 0 {
+		return 0, false
+	}
+	return spreadPhase(base.ID(), w.node, time.Duration(base.Interval)), true
+}
 
+// align aligns the next execution on the phase of the healthcheck if its
+// executions are spread. The closest aligned time is used, in order to
+// keep the interval, the backoff and the adaptive interval.
+func (w *Wrapper) align(next time.Time, previous time.Time) time.Time {
+	phase, ok := w.spreadPhase()
+	if !ok {
+		return next
+	}
+	interval := time.Duration(w.healthcheck.Base().Interval)
+	aligned := nextSlot(next.Add(-interval/2), interval, phase)
+	if !aligned.After(previous) {
+		aligned = aligned.Add(interval)
+	}
+	return aligned
+}
+
+// nextDelay returns the delay before the next healthcheck execution,
+// applying the backoff and the jitter on the healthcheck interval.
+// For healthchecks using a cron expression, the delay is the time
+// until the next activation.
+func (w *Wrapper) nextDelay() time.Duration {
+	base := w.healthcheck.Base()
+	if base.Cron != nil {
+		now := time.Now()
+		return base.Cron.delay(now, now)
+	}
+	interval := time.Duration(base.Interval)
+	if base.Backoff != nil && w.state.current() == StateUnhealthy {
+		interval = base.Backoff.interval(interval, w.state.unhealthyFailures())
+	}
+	if base.Adaptive != nil && w.state.current() != StateUnhealthy {
+		interval = base.Adaptive.interval(interval, w.state.consecutiveSuccesses())
+	}
+	maxJitter := base.Jitter.Max(interval)
+	if maxJitter > 0 {
+		interval += time.Duration(rand.Int63n(int64(2*maxJitter)+1)) - maxJitter
+	}
+	return interval
+}
+
+// execute executes the healthcheck, retrying it on failure if configured.
+// It returns the duration of the last attempt. The context should be
+// derived from the wrapper context.
+func (w *Wrapper) execute(ctx context.Context) (time.Duration, error) {
+	base := w.healthcheck.Base()
+	var err error
+	var duration time.Duration
+	for attempt := uint(0); attempt <= base.MaxRetries; attempt++ {
+		if attempt != 0 {
+			w.healthcheck.LogDebug(fmt.Sprintf("healthcheck failed, retrying (attempt %d/%d)", attempt, base.MaxRetries))
+			select {
+			case <-time.After(time.Duration(base.RetryDelay)):
+			case <-w.done:
+				return duration, err
+			}
+		}
+		if recorder := phasesFromContext(ctx); recorder != nil {
+			recorder.reset()
+		}
+		if recorder := degradationFromContext(ctx); recorder != nil {
+			recorder.reset()
+		}
+		if recorder := tlsRecorderFromContext(ctx); recorder != nil {
+			recorder.reset()
+		}
+		if recorder := addressRecorderFromContext(ctx); recorder != nil {
+			recorder.reset()
+		}
+		if recorder := responseRecorderFromContext(ctx); recorder != nil {
+			recorder.reset()
+		}
+		start := time.Now()
+		err = ExecuteWithTimeout(ctx, w.healthcheck)
+		duration = time.Since(start)
+		if err == nil {
+			return duration, nil
+		}
+	}
+	return duration, err
+}
+
+// unschedule removes the wrapper from the scheduler, the execution in
+// progress is not cancelled
+func (w *Wrapper) unschedule() {
+	if w.scheduler != nil {
+		w.scheduler.remove(w)
+	}
+}
+
+// Stop an Healthcheck wrapper
+func (w *Wrapper) Stop() error {
+	w.unschedule()
+	w.stopOnce.Do(func() {
+		close(w.done)
+		w.cancel()
+		for _, member := range w.members {
+			member.wrapper.Stop()
+		}
+	})
+	// waits for the execution in progress
+	w.wg.Wait()
+	return nil
 }
@@ -0,0 +1,222 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+func TestJitterUnmarshal(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Jitter
+	}{
+		{in: "2s", want: Jitter{Absolute: Duration(2 * time.Second)}},
+		{in: "10%", want: Jitter{Percentage: 10}},
+		{in: "2.5%", want: Jitter{Percentage: 2.5}},
+	}
+	for _, c := range cases {
+		var result Jitter
+		if err := yaml.Unmarshal([]byte(c.in), &result); err != nil {
+			t.Fatalf("Unmarshal yaml error:\n%v", err)
+		}
+		if result != c.want {
+			t.Fatalf("Invalid jitter for %s: %v", c.in, result)
+		}
+		if result.String() != c.in {
+			t.Fatalf("Invalid jitter string representation for %s: %s", c.in, result.String())
+		}
+	}
+	for _, c := range []string{"foo", "120%", "-1%"} {
+		var result Jitter
+		if err := yaml.Unmarshal([]byte(c), &result); err == nil {
+			t.Fatalf("Was expecting an error for %s", c)
+		}
+	}
+}
+
+func TestWrapperNextDelay(t *testing.T) {
+	interval := 10 * time.Second
+	wrapper := NewWrapper(NewTCPHealthcheck(
+		zap.NewExample(),
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(interval),
+				Jitter:   Jitter{Percentage: 10},
+			},
+		},
+	))
+	for i := 0; i < 100; i++ {
+		delay := wrapper.nextDelay()
+		if delay < 9*time.Second || delay > 11*time.Second {
+			t.Fatalf("Invalid delay %s", delay)
+		}
+	}
+}
+
+func TestWrapperInitialDelay(t *testing.T) {
+	wrapper := NewWrapper(NewTCPHealthcheck(
+		zap.NewExample(),
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:         "foo",
+				Interval:     Duration(10 * time.Second),
+				InitialDelay: Duration(time.Minute),
+				InitialState: StateHealthy,
+			},
+		},
+	))
+	if delay := wrapper.initialDelay(); delay != time.Minute {
+		t.Fatalf("Invalid initial delay %s", delay)
+	}
+	if wrapper.state.current() != StateHealthy {
+		t.Fatalf("Invalid initial state %s", wrapper.state.current())
+	}
+	wrapper = NewWrapper(NewTCPHealthcheck(
+		zap.NewExample(),
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(10 * time.Second),
+			},
+		},
+	))
+	if delay := wrapper.initialDelay(); delay >= 4*time.Second {
+		t.Fatalf("Invalid initial delay %s", delay)
+	}
+}
+
+// testHealthcheck an healthcheck whose execution can be controlled by tests
+type testHealthcheck struct {
+	config  *TCPHealthcheckConfiguration
+	timeout time.Duration
+	execute func() error
+}
+
+func (h *testHealthcheck) Initialize() error      { return nil }
+func (h *testHealthcheck) GetConfig() interface{} { return h.config }
+func (h *testHealthcheck) Summary() string        { return "test healthcheck" }
+func (h *testHealthcheck) Timeout() time.Duration {
+	if h.timeout == 0 {
+		return time.Second
+	}
+	return h.timeout
+}
+func (h *testHealthcheck) Execute(ctx context.Context) error {
+	return h.execute()
+}
+func (h *testHealthcheck) LogDebug(message string) {}
+func (h *testHealthcheck) LogInfo(message string)  {}
+func (h *testHealthcheck) Base() Base              { return h.config.Base }
+func (h *testHealthcheck) SetSource(source string) { h.config.Base.Source = source }
+func (h *testHealthcheck) LogError(err error, message string) {
+}
+
+func TestWrapperRetries(t *testing.T) {
+	count := 0
+	check := &testHealthcheck{
+		config: &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:       "foo",
+				Interval:   Duration(10 * time.Second),
+				MaxRetries: 2,
+				RetryDelay: Duration(10 * time.Millisecond),
+			},
+		},
+		execute: func() error {
+			count++
+			if count < 3 {
+				return errors.New("failure")
+			}
+			return nil
+		},
+	}
+	wrapper := NewWrapper(check)
+	_, err := wrapper.execute(wrapper.ctx)
+	if err != nil {
+		t.Fatalf("The healthcheck should be successful after retries: %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("Invalid number of executions: %d", count)
+	}
+	count = -10
+	_, err = wrapper.execute(wrapper.ctx)
+	if err == nil {
+		t.Fatalf("The healthcheck should fail")
+	}
+	if count != -7 {
+		t.Fatalf("Invalid number of executions: %d", count)
+	}
+}
+
+func TestWrapperBackoff(t *testing.T) {
+	wrapper := NewWrapper(NewTCPHealthcheck(
+		zap.NewExample(),
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(10 * time.Second),
+				Fall:     2,
+				Backoff: &Backoff{
+					MaxInterval: Duration(time.Minute),
+				},
+			},
+		},
+	))
+	expected := []time.Duration{
+		10 * time.Second,
+		10 * time.Second,
+		20 * time.Second,
+		40 * time.Second,
+		time.Minute,
+		time.Minute,
+	}
+	for i, e := range expected {
+		wrapper.state.update(false, time.Now())
+		if wrapper.nextDelay() != e {
+			t.Fatalf("Invalid delay at step %d: %s (expected %s)", i, wrapper.nextDelay(), e)
+		}
+	}
+	wrapper.state.update(true, time.Now())
+	if wrapper.nextDelay() != 10*time.Second {
+		t.Fatalf("The delay should be reset on recovery: %s", wrapper.nextDelay())
+	}
+}
+
+func TestWrapperAdaptive(t *testing.T) {
+	wrapper := NewWrapper(NewTCPHealthcheck(
+		zap.NewExample(),
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(10 * time.Second),
+				Adaptive: &Adaptive{
+					MaxInterval: Duration(time.Minute),
+					After:       2,
+				},
+			},
+		},
+	))
+	expected := []time.Duration{
+		10 * time.Second,
+		20 * time.Second,
+		40 * time.Second,
+		time.Minute,
+		time.Minute,
+	}
+	for i, e := range expected {
+		wrapper.state.update(true, time.Now())
+		if wrapper.nextDelay() != e {
+			t.Fatalf("Invalid delay at step %d: %s (expected %s)", i, wrapper.nextDelay(), e)
+		}
+	}
+	wrapper.state.update(false, time.Now())
+	if wrapper.nextDelay() != 10*time.Second {
+		t.Fatalf("The delay should be reset on failure: %s", wrapper.nextDelay())
+	}
+}
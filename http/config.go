@@ -5,6 +5,7 @@ import (
 	"net"
 
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 
 	"github.com/appclacks/cabourotte/healthcheck"
 )
@@ -26,6 +27,9 @@ type Configuration struct {
 	BasicAuth             BasicAuth `yaml:"basic-auth"`
 	AllowedCN             []string  `yaml:"allowed-cn"`
 	Cacert                string
+	// Tokens the API tokens authenticating the requests, exclusive with
+	// the basic auth
+	Tokens []Token
 }
 
 // UnmarshalYAML parses the configuration of the http component from YAML.
@@ -53,17 +57,82 @@ func (c *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		(raw.BasicAuth.Username != "" && raw.BasicAuth.Password == "") {
 		return errors.New("Invalid Basic Auth configuration")
 	}
+	if raw.BasicAuth.Username != "" && len(raw.Tokens) != 0 {
+		return errors.New("The basic-auth and tokens options are exclusive")
+	}
+	if err := validateTokens(raw.Tokens); err != nil {
+		return err
+	}
 	*c = Configuration(raw)
 	return nil
 }
 
 // BulkPayload the paylaod for bulk requests fo healthchecks
 type BulkPayload struct {
-	DNSChecks     []healthcheck.DNSHealthcheckConfiguration     `json:"dns-checks"`
-	CommandChecks []healthcheck.CommandHealthcheckConfiguration `json:"command-checks"`
-	TCPChecks     []healthcheck.TCPHealthcheckConfiguration     `json:"tcp-checks"`
-	HTTPChecks    []healthcheck.HTTPHealthcheckConfiguration    `json:"http-checks"`
-	TLSChecks     []healthcheck.TLSHealthcheckConfiguration     `json:"tls-checks"`
+	DNSChecks       []healthcheck.DNSHealthcheckConfiguration       `json:"dns-checks"`
+	CommandChecks   []healthcheck.CommandHealthcheckConfiguration   `json:"command-checks"`
+	TCPChecks       []healthcheck.TCPHealthcheckConfiguration       `json:"tcp-checks"`
+	HTTPChecks      []healthcheck.HTTPHealthcheckConfiguration      `json:"http-checks"`
+	TLSChecks       []healthcheck.TLSHealthcheckConfiguration       `json:"tls-checks"`
+	HeartbeatChecks []healthcheck.HeartbeatHealthcheckConfiguration `json:"heartbeat-checks"`
+	GRPCChecks      []healthcheck.GRPCHealthcheckConfiguration      `json:"grpc-checks"`
+	ProfileChecks   []healthcheck.ProfileHealthcheckConfiguration   `json:"profile-checks"`
+}
+
+// namespaceError the error returned when an healthcheck namespace does not
+// match the request namespace
+func namespaceError(base *healthcheck.Base, namespace string) error {
+	return fmt.Errorf("The namespace %s of the healthcheck %s does not match the request namespace %s", base.Namespace, base.Name, namespace)
+}
+
+// SetNamespace sets the namespace of the healthchecks. All the healthchecks
+// of a bulk request belong to the request namespace.
+func (p *BulkPayload) SetNamespace(namespace string) error {
+	bases := []*healthcheck.Base{}
+	for i := range p.DNSChecks {
+		bases = append(bases, &p.DNSChecks[i].Base)
+	}
+	for i := range p.CommandChecks {
+		bases = append(bases, &p.CommandChecks[i].Base)
+	}
+	for i := range p.TCPChecks {
+		bases = append(bases, &p.TCPChecks[i].Base)
+	}
+	for i := range p.HTTPChecks {
+		bases = append(bases, &p.HTTPChecks[i].Base)
+	}
+	for i := range p.TLSChecks {
+		bases = append(bases, &p.TLSChecks[i].Base)
+	}
+	for i := range p.HeartbeatChecks {
+		bases = append(bases, &p.HeartbeatChecks[i].Base)
+	}
+	for i := range p.GRPCChecks {
+		bases = append(bases, &p.GRPCChecks[i].Base)
+	}
+	for i := range p.ProfileChecks {
+		bases = append(bases, &p.ProfileChecks[i].Base)
+	}
+	for _, base := range bases {
+		if base.Namespace == "" {
+			base.Namespace = namespace
+		}
+		if base.Namespace != namespace {
+			return namespaceError(base, namespace)
+		}
+	}
+	return nil
+}
+
+// GenerateNames sets the names of the healthchecks whose generate-name
+// option is enabled
+func (p *BulkPayload) GenerateNames() error {
+	for _, check := range p.Healthchecks(nil) {
+		if err := healthcheck.GenerateName(check.GetConfig()); err != nil {
+			return errors.Wrapf(err, "Invalid healthcheck configuration")
+		}
+	}
+	return nil
 }
 
 // Validate validates the payload for bulk requests
@@ -119,5 +188,62 @@ func (p *BulkPayload) Validate() error {
 			return errors.New(msg)
 		}
 	}
+	for _, config := range p.HeartbeatChecks {
+		err := config.Validate()
+		if err != nil {
+			msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+			return errors.New(msg)
+		}
+	}
+	for _, config := range p.GRPCChecks {
+		err := config.Validate()
+		if config.Base.OneOff {
+			return errors.New(oneOffErrorMsg)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+			return errors.New(msg)
+		}
+	}
+	for _, config := range p.ProfileChecks {
+		err := config.Validate()
+		if config.Base.OneOff {
+			return errors.New(oneOffErrorMsg)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+			return errors.New(msg)
+		}
+	}
 	return nil
 }
+
+// Healthchecks creates the healthchecks of the payload
+func (p *BulkPayload) Healthchecks(logger *zap.Logger) []healthcheck.Healthcheck {
+	result := []healthcheck.Healthcheck{}
+	for i := range p.DNSChecks {
+		result = append(result, healthcheck.NewDNSHealthcheck(logger, &p.DNSChecks[i]))
+	}
+	for i := range p.TCPChecks {
+		result = append(result, healthcheck.NewTCPHealthcheck(logger, &p.TCPChecks[i]))
+	}
+	for i := range p.HTTPChecks {
+		result = append(result, healthcheck.NewHTTPHealthcheck(logger, &p.HTTPChecks[i]))
+	}
+	for i := range p.TLSChecks {
+		result = append(result, healthcheck.NewTLSHealthcheck(logger, &p.TLSChecks[i]))
+	}
+	for i := range p.CommandChecks {
+		result = append(result, healthcheck.NewCommandHealthcheck(logger, &p.CommandChecks[i]))
+	}
+	for i := range p.HeartbeatChecks {
+		result = append(result, healthcheck.NewHeartbeatHealthcheck(logger, &p.HeartbeatChecks[i]))
+	}
+	for i := range p.GRPCChecks {
+		result = append(result, healthcheck.NewGRPCHealthcheck(logger, &p.GRPCChecks[i]))
+	}
+	for i := range p.ProfileChecks {
+		result = append(result, healthcheck.NewProfileHealthcheck(logger, &p.ProfileChecks[i]))
+	}
+	return result
+}
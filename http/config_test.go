@@ -119,6 +119,34 @@ host: "127.0.0.1"
 port: 2000
 basic-auth:
   username: "foo"
+`},
+		{
+			in: `
+host: "127.0.0.1"
+port: 2000
+basic-auth:
+  username: "foo"
+  password: "bar"
+tokens:
+  - name: "admin"
+    token: "secret"
+`},
+		{
+			in: `
+host: "127.0.0.1"
+port: 2000
+tokens:
+  - name: "admin"
+`},
+		{
+			in: `
+host: "127.0.0.1"
+port: 2000
+tokens:
+  - name: "admin"
+    token: "secret"
+  - name: "payments"
+    token: "secret"
 `},
 	}
 	for _, c := range cases {
@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"crypto/subtle"
 	"embed"
+	"encoding/json"
 	"fmt"
+	htmltemplate "html/template"
+	"io"
 	"io/fs"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"text/template"
@@ -17,6 +21,10 @@ import (
 	"github.com/labstack/echo/middleware"
 
 	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/ingest"
+	"github.com/appclacks/cabourotte/maintenance"
+	"github.com/appclacks/cabourotte/memorystore"
+	"github.com/appclacks/cabourotte/signature"
 	"github.com/mcorbin/corbierror"
 )
 
@@ -31,6 +39,106 @@ func newResponse(msg string) *BasicResponse {
 	}
 }
 
+// namespaceParam the query parameter scoping the requests to a namespace
+const namespaceParam = "namespace"
+
+// agentHeader the header identifying the agents pushing results to the
+// aggregator without node identity
+const agentHeader = "X-Cabourotte-Agent"
+
+// setNamespace sets the namespace of an healthcheck from the request
+// namespace
+func setNamespace(ec echo.Context, base *healthcheck.Base) error {
+	namespace := ec.QueryParam(namespaceParam)
+	if namespace == "" {
+		return nil
+	}
+	if base.Namespace == "" {
+		base.Namespace = namespace
+	}
+	if base.Namespace != namespace {
+		return corbierror.New(namespaceError(base, namespace).Error(), corbierror.BadRequest, true)
+	}
+	return nil
+}
+
+// requestID returns the identifier of the healthcheck targeted by the
+// request
+func requestID(ec echo.Context) string {
+	return healthcheck.ID(ec.QueryParam(namespaceParam), ec.Param("name"))
+}
+
+// inNamespace returns true if the request is not scoped to a namespace or
+// if the namespace matches the request namespace
+func inNamespace(ec echo.Context, namespace string) bool {
+	requested := ec.QueryParam(namespaceParam)
+	return requested == "" || requested == namespace
+}
+
+// parseTime parses a time from an unix timestamp or a RFC3339 date
+func parseTime(value string) (time.Time, error) {
+	if timestamp, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(timestamp, 0), nil
+	}
+	result, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid time %s, it should be an unix timestamp or a RFC3339 date", value)
+	}
+	return result, nil
+}
+
+// seriesRange returns the time range of a series request from the from,
+// to and step query parameters. The default range is the last hour with
+// a step of one minute.
+func seriesRange(ec echo.Context) (time.Time, time.Time, time.Duration, error) {
+	to := time.Now()
+	step := time.Minute
+	var err error
+	if value := ec.QueryParam("to"); value != "" {
+		to, err = parseTime(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, 0, err
+		}
+	}
+	from := to.Add(-time.Hour)
+	if value := ec.QueryParam("from"); value != "" {
+		from, err = parseTime(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, 0, err
+		}
+	}
+	if value := ec.QueryParam("step"); value != "" {
+		step, err = time.ParseDuration(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("Invalid step %s", value)
+		}
+	}
+	return from, to, step, memorystore.ValidateRange(from, to, step)
+}
+
+// slaWindow returns the window of a SLA request, 30 days by default
+func slaWindow(ec echo.Context) (time.Duration, error) {
+	value := ec.QueryParam("window")
+	if value == "" {
+		return 30 * 24 * time.Hour, nil
+	}
+	return memorystore.ParseWindow(value)
+}
+
+// failuresLimit returns the number of failures requested with the limit
+// query parameter, 10 by default
+func failuresLimit(ec echo.Context) (int, error) {
+	value := ec.QueryParam("limit")
+	if value == "" {
+		return 10, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 || limit > memorystore.MaxFailures {
+		return 0, fmt.Errorf("Invalid limit %s, it should be between 1 and %d", value, memorystore.MaxFailures)
+	}
+	return limit, nil
+}
+
 // addCheck adds a periodic healthcheck to the healthcheck component.
 func (c *Component) addCheck(ec echo.Context, check healthcheck.Healthcheck) error {
 	check.SetSource(healthcheck.SourceAPI)
@@ -45,37 +153,74 @@ func (c *Component) addCheck(ec echo.Context, check healthcheck.Healthcheck) err
 var embededFiles embed.FS
 
 // oneOff executes an one-off healthcheck and returns its result
-func (c *Component) oneOff(ec echo.Context, healthcheck healthcheck.Healthcheck) error {
-	c.Logger.Info(fmt.Sprintf("Executing one-off healthcheck %s", healthcheck.Base().Name))
-	err := healthcheck.Initialize()
+func (c *Component) oneOff(ec echo.Context, check healthcheck.Healthcheck) error {
+	c.Logger.Info(fmt.Sprintf("Executing one-off healthcheck %s", check.Base().Name))
+	err := c.healthcheck.ResolveCredential(check)
+	if err != nil {
+		msg := fmt.Sprintf("Fail to resolve the credential of the one off healthcheck %s: %s", check.Base().Name, err.Error())
+		return corbierror.New(msg, corbierror.BadRequest, true)
+	}
+	err = check.Initialize()
 	if err != nil {
-		msg := fmt.Sprintf("Fail to initialize one off healthcheck %s: %s", healthcheck.Base().Name, err.Error())
+		msg := fmt.Sprintf("Fail to initialize one off healthcheck %s: %s", check.Base().Name, err.Error())
 		return corbierror.New(msg, corbierror.Internal, true)
 	}
-	err = healthcheck.Execute()
+	err = healthcheck.ExecuteWithTimeout(ec.Request().Context(), check)
 	if err != nil {
-		msg := fmt.Sprintf("Execution of one off healthcheck %s failed: %s", healthcheck.Base().Name, err.Error())
+		msg := fmt.Sprintf("Execution of one off healthcheck %s failed: %s", check.Base().Name, err.Error())
 		c.Logger.Error(msg)
 		return corbierror.New(msg, corbierror.Internal, true)
 	}
-	msg := fmt.Sprintf("One-off healthcheck %s successfully executed", healthcheck.Base().Name)
+	msg := fmt.Sprintf("One-off healthcheck %s successfully executed", check.Base().Name)
 	c.Logger.Info(msg)
 	return ec.JSON(http.StatusCreated, newResponse(msg))
 }
 
-func (c *Component) addCheckError(ec echo.Context, healthcheck healthcheck.Healthcheck, err error) error {
-	msg := fmt.Sprintf("Fail to start the healthcheck %s: %s", healthcheck.Base().Name, err.Error())
+// addCheckError returns the error of an healthcheck creation, a conflict
+// if the name is already used by another source
+func (c *Component) addCheckError(ec echo.Context, check healthcheck.Healthcheck, err error) error {
+	if healthcheck.IsConflict(err) {
+		msg := fmt.Sprintf("Fail to add the healthcheck %s: %s", check.Base().Name, err.Error())
+		return corbierror.New(msg, corbierror.Conflict, true)
+	}
+	msg := fmt.Sprintf("Fail to start the healthcheck %s: %s", check.Base().Name, err.Error())
 	return corbierror.New(msg, corbierror.Internal, true)
 }
 
+// checkConflicts returns a conflict if the healthchecks of a bulk request
+// share a name, or if a name is used by an healthcheck not managed by the
+// API
+func (c *Component) checkConflicts(checks []healthcheck.Healthcheck) error {
+	seen := make(map[string]bool)
+	for _, check := range checks {
+		base := check.Base()
+		id := base.ID()
+		if seen[id] {
+			msg := fmt.Sprintf("The healthcheck %s is defined several times in the request", id)
+			return corbierror.New(msg, corbierror.Conflict, true)
+		}
+		seen[id] = true
+		if err := c.healthcheck.CheckConflict(id); err != nil {
+			return corbierror.New(err.Error(), corbierror.Conflict, true)
+		}
+	}
+	return nil
+}
+
 // handleCheck handles new healthchecks requests
-func (c *Component) handleCheck(ec echo.Context, healthcheck healthcheck.Healthcheck) error {
-	if healthcheck.Base().OneOff {
-		return c.oneOff(ec, healthcheck)
+func (c *Component) handleCheck(ec echo.Context, check healthcheck.Healthcheck) error {
+	if err := c.checkToken(ec, check.Base()); err != nil {
+		return err
+	}
+	if check.Base().OneOff {
+		return c.oneOff(ec, check)
+	}
+	if err := c.healthcheck.CheckDependencies([]healthcheck.Healthcheck{check}); err != nil {
+		return corbierror.New(err.Error(), corbierror.BadRequest, true)
 	}
-	err := c.addCheck(ec, healthcheck)
+	err := c.addCheck(ec, check)
 	if err != nil {
-		return c.addCheckError(ec, healthcheck, err)
+		return c.addCheckError(ec, check, err)
 	}
 	return ec.JSON(http.StatusCreated, newResponse("Healthcheck successfully added"))
 }
@@ -97,6 +242,9 @@ func (c *Component) handlers() {
 			return true, nil
 		}))
 	}
+	if len(c.Config.Tokens) != 0 {
+		c.Server.Use(c.tokenMiddleware)
+	}
 	echo.NotFoundHandler = func(ec echo.Context) error {
 		return corbierror.New("Not found", corbierror.NotFound, true)
 	}
@@ -108,6 +256,13 @@ func (c *Component) handlers() {
 				msg := fmt.Sprintf("Fail to create the dns healthcheck. Invalid JSON: %s", err.Error())
 				return corbierror.New(msg, corbierror.BadRequest, true)
 			}
+			if err := setNamespace(ec, &config.Base); err != nil {
+				return err
+			}
+			if err := healthcheck.GenerateName(&config); err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
 			err := config.Validate()
 			if err != nil {
 				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
@@ -123,6 +278,13 @@ func (c *Component) handlers() {
 				msg := fmt.Sprintf("Fail to create the TCP healthcheck. Invalid JSON: %s", err.Error())
 				return corbierror.New(msg, corbierror.BadRequest, true)
 			}
+			if err := setNamespace(ec, &config.Base); err != nil {
+				return err
+			}
+			if err := healthcheck.GenerateName(&config); err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
 			err := config.Validate()
 			if err != nil {
 				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
@@ -138,6 +300,13 @@ func (c *Component) handlers() {
 				msg := fmt.Sprintf("Fail to create the TLS healthcheck. Invalid JSON: %s", err.Error())
 				return corbierror.New(msg, corbierror.BadRequest, true)
 			}
+			if err := setNamespace(ec, &config.Base); err != nil {
+				return err
+			}
+			if err := healthcheck.GenerateName(&config); err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
 			err := config.Validate()
 			if err != nil {
 				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
@@ -153,6 +322,13 @@ func (c *Component) handlers() {
 				msg := fmt.Sprintf("Fail to create the HTTP healthcheck. Invalid JSON: %s", err.Error())
 				return corbierror.New(msg, corbierror.BadRequest, true)
 			}
+			if err := setNamespace(ec, &config.Base); err != nil {
+				return err
+			}
+			if err := healthcheck.GenerateName(&config); err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
 			err := config.Validate()
 			if err != nil {
 				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
@@ -168,6 +344,13 @@ func (c *Component) handlers() {
 				msg := fmt.Sprintf("Fail to create the Command healthcheck. Invalid JSON: %s", err.Error())
 				return corbierror.New(msg, corbierror.BadRequest, true)
 			}
+			if err := setNamespace(ec, &config.Base); err != nil {
+				return err
+			}
+			if err := healthcheck.GenerateName(&config); err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
 			err := config.Validate()
 			if err != nil {
 				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
@@ -177,21 +360,116 @@ func (c *Component) handlers() {
 			return c.handleCheck(ec, healthcheck)
 		})
 
+		c.Server.POST("/healthcheck/grpc", func(ec echo.Context) error {
+			var config healthcheck.GRPCHealthcheckConfiguration
+			if err := ec.Bind(&config); err != nil {
+				msg := fmt.Sprintf("Fail to create the gRPC healthcheck. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			if err := setNamespace(ec, &config.Base); err != nil {
+				return err
+			}
+			if err := healthcheck.GenerateName(&config); err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			err := config.Validate()
+			if err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			healthcheck := healthcheck.NewGRPCHealthcheck(c.Logger, &config)
+			return c.handleCheck(ec, healthcheck)
+		})
+
+		c.Server.POST("/healthcheck/profile", func(ec echo.Context) error {
+			var config healthcheck.ProfileHealthcheckConfiguration
+			if err := ec.Bind(&config); err != nil {
+				msg := fmt.Sprintf("Fail to create the profile healthcheck. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			if err := setNamespace(ec, &config.Base); err != nil {
+				return err
+			}
+			if err := healthcheck.GenerateName(&config); err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			err := config.Validate()
+			if err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			healthcheck := healthcheck.NewProfileHealthcheck(c.Logger, &config)
+			return c.handleCheck(ec, healthcheck)
+		})
+
+		c.Server.POST("/healthcheck/heartbeat", func(ec echo.Context) error {
+			var config healthcheck.HeartbeatHealthcheckConfiguration
+			if err := ec.Bind(&config); err != nil {
+				msg := fmt.Sprintf("Fail to create the heartbeat healthcheck. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			if err := setNamespace(ec, &config.Base); err != nil {
+				return err
+			}
+			if err := healthcheck.GenerateName(&config); err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			err := config.Validate()
+			if err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			healthcheck := healthcheck.NewHeartbeatHealthcheck(c.Logger, &config)
+			return c.handleCheck(ec, healthcheck)
+		})
+
 		c.Server.POST("/healthcheck/bulk", func(ec echo.Context) error {
 			bulkLock.Lock()
 			defer bulkLock.Unlock()
 			var payload BulkPayload
+			namespace := ec.QueryParam(namespaceParam)
 			newChecks := make(map[string]bool)
-			oldChecks := c.healthcheck.SourceChecksNames(healthcheck.SourceAPI)
+			// only the healthchecks of the request namespace, and
+			// managed by the request token, are replaced
+			oldChecks := make(map[string]bool)
+			for _, check := range c.healthcheck.ListChecks() {
+				base := check.Base()
+				if base.Source == healthcheck.SourceAPI && base.Namespace == namespace && tokenAllows(ec, base.Labels) {
+					oldChecks[base.ID()] = true
+				}
+			}
 			if err := ec.Bind(&payload); err != nil {
 				msg := fmt.Sprintf("Fail to add healthchecks. Invalid JSON: %s", err.Error())
 				return corbierror.New(msg, corbierror.BadRequest, true)
 			}
-			err := payload.Validate()
+			err := payload.SetNamespace(namespace)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
+			err = payload.GenerateNames()
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
+			err = payload.Validate()
 			if err != nil {
 				msg := fmt.Sprintf("Fail to validate healthchecks configuration: %s", err.Error())
 				return corbierror.New(msg, corbierror.BadRequest, true)
 			}
+			checks := payload.Healthchecks(c.Logger)
+			for _, check := range checks {
+				if err := c.checkToken(ec, check.Base()); err != nil {
+					return err
+				}
+			}
+			if err := c.checkConflicts(checks); err != nil {
+				return err
+			}
+			if err := c.healthcheck.CheckDependencies(checks); err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
 			for i := range payload.HTTPChecks {
 				config := payload.HTTPChecks[i]
 				healthcheck := healthcheck.NewHTTPHealthcheck(c.Logger, &config)
@@ -199,7 +477,7 @@ func (c *Component) handlers() {
 				if err != nil {
 					return c.addCheckError(ec, healthcheck, err)
 				}
-				newChecks[config.Base.Name] = true
+				newChecks[config.Base.ID()] = true
 			}
 			for i := range payload.TCPChecks {
 				config := payload.TCPChecks[i]
@@ -208,7 +486,7 @@ func (c *Component) handlers() {
 				if err != nil {
 					return c.addCheckError(ec, healthcheck, err)
 				}
-				newChecks[config.Base.Name] = true
+				newChecks[config.Base.ID()] = true
 			}
 			for i := range payload.DNSChecks {
 				config := payload.DNSChecks[i]
@@ -217,7 +495,7 @@ func (c *Component) handlers() {
 				if err != nil {
 					return c.addCheckError(ec, healthcheck, err)
 				}
-				newChecks[config.Base.Name] = true
+				newChecks[config.Base.ID()] = true
 			}
 			for i := range payload.TLSChecks {
 				config := payload.TLSChecks[i]
@@ -226,7 +504,7 @@ func (c *Component) handlers() {
 				if err != nil {
 					return c.addCheckError(ec, healthcheck, err)
 				}
-				newChecks[config.Base.Name] = true
+				newChecks[config.Base.ID()] = true
 			}
 			for i := range payload.CommandChecks {
 				config := payload.CommandChecks[i]
@@ -235,7 +513,25 @@ func (c *Component) handlers() {
 				if err != nil {
 					return c.addCheckError(ec, healthcheck, err)
 				}
-				newChecks[config.Base.Name] = true
+				newChecks[config.Base.ID()] = true
+			}
+			for i := range payload.GRPCChecks {
+				config := payload.GRPCChecks[i]
+				healthcheck := healthcheck.NewGRPCHealthcheck(c.Logger, &config)
+				err := c.addCheck(ec, healthcheck)
+				if err != nil {
+					return c.addCheckError(ec, healthcheck, err)
+				}
+				newChecks[config.Base.ID()] = true
+			}
+			for i := range payload.ProfileChecks {
+				config := payload.ProfileChecks[i]
+				healthcheck := healthcheck.NewProfileHealthcheck(c.Logger, &config)
+				err := c.addCheck(ec, healthcheck)
+				if err != nil {
+					return c.addCheckError(ec, healthcheck, err)
+				}
+				newChecks[config.Base.ID()] = true
 			}
 			err = c.healthcheck.RemoveNonConfiguredHealthchecks(oldChecks, newChecks)
 			if err != nil {
@@ -244,20 +540,109 @@ func (c *Component) handlers() {
 			return ec.JSON(http.StatusCreated, newResponse("Healthchecks successfully added"))
 		})
 
+		// the healthchecks managed by a source are replaced by the
+		// healthchecks of the request
+		c.Server.PUT("/healthcheck/source/:source", func(ec echo.Context) error {
+			source := ec.Param("source")
+			err := healthcheck.ValidateExternalSource(source)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
+			var payload BulkPayload
+			if err := ec.Bind(&payload); err != nil {
+				msg := fmt.Sprintf("Fail to replace the healthchecks. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			namespace := ec.QueryParam(namespaceParam)
+			if namespace != "" {
+				err = payload.SetNamespace(namespace)
+				if err != nil {
+					return corbierror.New(err.Error(), corbierror.BadRequest, true)
+				}
+			}
+			err = payload.GenerateNames()
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
+			err = payload.Validate()
+			if err != nil {
+				msg := fmt.Sprintf("Fail to validate healthchecks configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			result, err := c.healthcheck.ReplaceSourceChecks(source, namespace, payload.Healthchecks(c.Logger))
+			if err != nil {
+				msg := fmt.Sprintf("Fail to replace the healthchecks of the source %s: %s", source, err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			return ec.JSON(http.StatusOK, result)
+		})
+
 		c.Server.GET("/healthcheck", func(ec echo.Context) error {
-			return ec.JSON(http.StatusOK, c.healthcheck.ListChecks())
+			checks := []healthcheck.Healthcheck{}
+			source, filterSource := ec.QueryParams()["source"]
+			for _, check := range c.healthcheck.ListChecks() {
+				if filterSource && check.Base().Source != source[0] {
+					continue
+				}
+				if inNamespace(ec, check.Base().Namespace) && tokenAllows(ec, check.Base().Labels) {
+					checks = append(checks, check)
+				}
+			}
+			return ec.JSON(http.StatusOK, checks)
 		})
 		c.Server.GET("/healthcheck/:name", func(ec echo.Context) error {
-			name := ec.Param("name")
-			healthcheck := c.healthcheck.GetCheck(name)
-			if healthcheck == nil {
+			healthcheck := c.healthcheck.GetCheck(requestID(ec))
+			if healthcheck == nil || !tokenAllows(ec, healthcheck.Base().Labels) {
 				return corbierror.New("Healthcheck not found", corbierror.NotFound, true)
 			}
 			return ec.JSON(http.StatusOK, healthcheck)
 		})
+		c.Server.GET("/healthcheck/:name/failures", func(ec echo.Context) error {
+			limit, err := failuresLimit(ec)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
+			if err := c.checkResultToken(ec, requestID(ec)); err != nil {
+				return err
+			}
+			result, err := c.MemoryStore.GetFailures(requestID(ec), limit)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			return ec.JSON(http.StatusOK, result)
+		})
+		c.Server.POST("/healthcheck/:name/execute", func(ec echo.Context) error {
+			check := c.healthcheck.GetCheck(requestID(ec))
+			if check == nil {
+				return corbierror.New("Healthcheck not found", corbierror.NotFound, true)
+			}
+			if !tokenAllows(ec, check.Base().Labels) {
+				return tokenError(ec, requestID(ec))
+			}
+			overrides := make(map[string]interface{})
+			if ec.Request().ContentLength != 0 {
+				if err := ec.Bind(&overrides); err != nil {
+					msg := fmt.Sprintf("Fail to execute the healthcheck. Invalid JSON: %s", err.Error())
+					return corbierror.New(msg, corbierror.BadRequest, true)
+				}
+			}
+			check, err := healthcheck.Override(check, overrides)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
+			c.Logger.Info(fmt.Sprintf("Executing healthcheck %s on demand", requestID(ec)))
+			result, err := c.healthcheck.ExecuteOnce(ec.Request().Context(), check)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
+			return ec.JSON(http.StatusOK, result)
+		})
 
 		c.Server.DELETE("/healthcheck/:name", func(ec echo.Context) error {
-			name := ec.Param("name")
+			name := requestID(ec)
+			if check := c.healthcheck.GetCheck(name); check != nil && !tokenAllows(ec, check.Base().Labels) {
+				return tokenError(ec, name)
+			}
 			c.Logger.Info(fmt.Sprintf("Deleting healthcheck %s", name))
 			err := c.healthcheck.RemoveCheck(name)
 			if err != nil {
@@ -266,20 +651,263 @@ func (c *Component) handlers() {
 			}
 			return ec.JSON(http.StatusOK, newResponse(fmt.Sprintf("Successfully deleted healthcheck %s", name)))
 		})
+
+		c.Server.GET("/source", func(ec echo.Context) error {
+			// the scoped tokens only see the sources of their healthchecks
+			var filter func(healthcheck.Healthcheck) bool
+			if requestToken(ec) != nil {
+				filter = func(check healthcheck.Healthcheck) bool {
+					return tokenAllows(ec, check.Base().Labels)
+				}
+			}
+			return ec.JSON(http.StatusOK, c.healthcheck.ListSources(filter))
+		})
+
+		c.Server.GET("/maintenance", func(ec echo.Context) error {
+			return ec.JSON(http.StatusOK, c.maintenance.List())
+		})
+
+		c.Server.POST("/maintenance", func(ec echo.Context) error {
+			var window maintenance.Window
+			if err := ec.Bind(&window); err != nil {
+				msg := fmt.Sprintf("Fail to create the maintenance window. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			window.Source = maintenance.SourceAPI
+			err := c.maintenance.Add(&window)
+			if err != nil {
+				msg := fmt.Sprintf("Invalid maintenance window: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			return ec.JSON(http.StatusCreated, newResponse("Maintenance window successfully added"))
+		})
+
+		c.Server.DELETE("/maintenance/:name", func(ec echo.Context) error {
+			name := ec.Param("name")
+			c.maintenance.Remove(name)
+			return ec.JSON(http.StatusOK, newResponse(fmt.Sprintf("Successfully deleted maintenance window %s", name)))
+		})
 	}
 	if !c.Config.DisableResultAPI {
 		c.Server.GET("/result", func(ec echo.Context) error {
-			return ec.JSON(http.StatusOK, c.MemoryStore.List())
+			results := []healthcheck.Result{}
+			for _, result := range c.MemoryStore.List() {
+				if inNamespace(ec, result.Namespace) && tokenAllows(ec, result.Labels) {
+					results = append(results, result)
+				}
+			}
+			return ec.JSON(http.StatusOK, results)
+		})
+		c.Server.POST("/result/external", func(ec echo.Context) error {
+			var external healthcheck.ExternalResult
+			if err := ec.Bind(&external); err != nil {
+				msg := fmt.Sprintf("Fail to add the external result. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			if namespace := ec.QueryParam(namespaceParam); namespace != "" {
+				if external.Namespace == "" {
+					external.Namespace = namespace
+				}
+				if external.Namespace != namespace {
+					msg := fmt.Sprintf("The namespace %s of the result %s does not match the request namespace %s", external.Namespace, external.Name, namespace)
+					return corbierror.New(msg, corbierror.BadRequest, true)
+				}
+			}
+			if err := external.Validate(); err != nil {
+				msg := fmt.Sprintf("Invalid external result: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			if !tokenAllows(ec, external.Labels) {
+				return tokenError(ec, external.ID())
+			}
+			if c.healthcheck.GetCheck(external.ID()) != nil {
+				msg := fmt.Sprintf("The external result %s conflicts with an existing healthcheck", external.ID())
+				return corbierror.New(msg, corbierror.Conflict, true)
+			}
+			if err := c.healthcheck.AddExternalResult(ec.Request().Context(), &external); err != nil {
+				msg := fmt.Sprintf("Fail to add the external result: %s", err.Error())
+				return corbierror.New(msg, corbierror.Internal, true)
+			}
+			return ec.JSON(http.StatusCreated, newResponse(fmt.Sprintf("External result %s added", external.ID())))
 		})
 		c.Server.GET("/result/:name", func(ec echo.Context) error {
-			name := ec.Param("name")
-			result, err := c.MemoryStore.Get(name)
+			if err := c.checkResultToken(ec, requestID(ec)); err != nil {
+				return err
+			}
+			result, err := c.MemoryStore.Get(requestID(ec))
 			if err != nil {
 				return corbierror.New(err.Error(), corbierror.NotFound, true)
 			}
 			return ec.JSON(http.StatusOK, result)
 
 		})
+		c.Server.GET("/result/:name/latency", func(ec echo.Context) error {
+			if err := c.checkResultToken(ec, requestID(ec)); err != nil {
+				return err
+			}
+			result, err := c.MemoryStore.GetLatency(requestID(ec))
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			return ec.JSON(http.StatusOK, result)
+		})
+		c.Server.GET("/result/series", func(ec echo.Context) error {
+			from, to, step, err := seriesRange(ec)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
+			series, err := c.MemoryStore.ListSeries(from, to, step)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
+			result := []memorystore.Series{}
+			for _, s := range series {
+				if inNamespace(ec, s.Namespace) && c.resultAllows(ec, healthcheck.ID(s.Namespace, s.Name)) {
+					result = append(result, s)
+				}
+			}
+			return ec.JSON(http.StatusOK, result)
+		})
+		c.Server.GET("/result/schema", func(ec echo.Context) error {
+			return ec.Blob(http.StatusOK, "application/schema+json", healthcheck.ResultSchema)
+		})
+		c.Server.GET("/result/:name/series", func(ec echo.Context) error {
+			from, to, step, err := seriesRange(ec)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
+			if err := c.checkResultToken(ec, requestID(ec)); err != nil {
+				return err
+			}
+			result, err := c.MemoryStore.GetSeries(requestID(ec), from, to, step)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			return ec.JSON(http.StatusOK, result)
+		})
+		c.Server.GET("/result/:name/history", func(ec echo.Context) error {
+			if err := c.checkResultToken(ec, requestID(ec)); err != nil {
+				return err
+			}
+			result, err := c.MemoryStore.GetHistory(requestID(ec))
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			return ec.JSON(http.StatusOK, result)
+		})
+		c.Server.GET("/sla", func(ec echo.Context) error {
+			window, err := slaWindow(ec)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
+			label := ec.QueryParam("label")
+			if label == "" {
+				return corbierror.New("The label query parameter is missing", corbierror.BadRequest, true)
+			}
+			result := c.MemoryStore.AggregateSLA(label, window, func(r healthcheck.Result) bool {
+				return inNamespace(ec, r.Namespace) && tokenAllows(ec, r.Labels)
+			})
+			return ec.JSON(http.StatusOK, result)
+		})
+		c.Server.GET("/sla/:name", func(ec echo.Context) error {
+			window, err := slaWindow(ec)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
+			if err := c.checkResultToken(ec, requestID(ec)); err != nil {
+				return err
+			}
+			result, err := c.MemoryStore.GetSLA(requestID(ec), window)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			return ec.JSON(http.StatusOK, result)
+		})
+		c.Server.GET("/slo", func(ec echo.Context) error {
+			result := []memorystore.SLOStatus{}
+			for _, status := range c.MemoryStore.ListSLO(time.Now()) {
+				if inNamespace(ec, status.Namespace) && c.resultAllows(ec, status.ID()) {
+					result = append(result, status)
+				}
+			}
+			return ec.JSON(http.StatusOK, result)
+		})
+		c.Server.GET("/slo/:name", func(ec echo.Context) error {
+			if err := c.checkResultToken(ec, requestID(ec)); err != nil {
+				return err
+			}
+			result, err := c.MemoryStore.GetSLO(requestID(ec), time.Now())
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			return ec.JSON(http.StatusOK, result)
+		})
+		c.Server.GET("/annotation", func(ec echo.Context) error {
+			check := ec.QueryParam("healthcheck")
+			group := ec.QueryParam("group")
+			annotations := c.MemoryStore.ListAnnotations(func(a memorystore.Annotation) bool {
+				return (check == "" || a.Healthcheck == check) && (group == "" || a.Group == group)
+			})
+			result := []memorystore.Annotation{}
+			for _, annotation := range annotations {
+				if c.checkAnnotationToken(ec, annotation) == nil {
+					result = append(result, annotation)
+				}
+			}
+			return ec.JSON(http.StatusOK, result)
+		})
+		c.Server.GET("/annotation/:name", func(ec echo.Context) error {
+			result, err := c.MemoryStore.GetAnnotation(ec.Param("name"))
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			if err := c.checkAnnotationToken(ec, result); err != nil {
+				return err
+			}
+			return ec.JSON(http.StatusOK, result)
+		})
+		c.Server.POST("/annotation", func(ec echo.Context) error {
+			var annotation memorystore.Annotation
+			if err := ec.Bind(&annotation); err != nil {
+				msg := fmt.Sprintf("Fail to create the annotation. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			err := annotation.Validate()
+			if err != nil {
+				msg := fmt.Sprintf("Invalid annotation: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			err = c.MemoryStore.AddAnnotation(&annotation)
+			if err != nil {
+				return corbierror.Wrap(err, "Internal error", corbierror.Internal, true)
+			}
+			return ec.JSON(http.StatusCreated, newResponse("Annotation successfully added"))
+		})
+		c.Server.DELETE("/annotation/:name", func(ec echo.Context) error {
+			name := ec.Param("name")
+			err := c.MemoryStore.RemoveAnnotation(name)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			return ec.JSON(http.StatusOK, newResponse(fmt.Sprintf("Successfully deleted annotation %s", name)))
+		})
+		// the status is 503 if the group is unhealthy. The state is cached
+		// for the health staleness, the endpoint being polled by the load
+		// balancers.
+		c.Server.GET("/health/group/:name", func(ec echo.Context) error {
+			if err := c.checkGroupToken(ec, requestID(ec)); err != nil {
+				return err
+			}
+			group, err := c.MemoryStore.CachedGroup(ec.QueryParam(namespaceParam), ec.Param("name"))
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			status := http.StatusOK
+			if group.State == healthcheck.StateUnhealthy {
+				status = http.StatusServiceUnavailable
+			}
+			return ec.JSON(status, group)
+		})
 		c.Server.GET("/frontend", func(ec echo.Context) error {
 			err := ec.Redirect(http.StatusFound, "/frontend/index.html")
 			return err
@@ -313,6 +941,13 @@ func (c *Component) handlers() {
 						return x == reflect.ValueOf(a).Len()-1
 					},
 					"mod": func(i, j int) int { return i % j },
+					"annotation": func(name string) string {
+						annotation, err := c.MemoryStore.GetAnnotation(name)
+						if err != nil {
+							return name
+						}
+						return annotation.Text
+					},
 					"formatts": func(ts int64) string {
 						tm := time.Unix(ts, 0)
 						return tm.Format("2006/01/02 15:04:05")
@@ -336,6 +971,119 @@ func (c *Component) handlers() {
 		})
 	}
 
+	if c.ingester != nil {
+		c.Server.POST(ingest.Path, func(ec echo.Context) error {
+			payload, err := io.ReadAll(ec.Request().Body)
+			if err != nil {
+				msg := fmt.Sprintf("Fail to read the results: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			header := ec.Request().Header
+			err = c.ingester.Verify(header.Get(signature.TimestampHeader), header.Get(signature.SignatureHeader), payload, time.Now())
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.Unauthorized, true)
+			}
+			var results []healthcheck.Result
+			if err := json.Unmarshal(payload, &results); err != nil {
+				msg := fmt.Sprintf("Invalid results: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			ingested, err := c.ingester.Ingest(ec.Request().Context(), results)
+			if err != nil {
+				msg := fmt.Sprintf("Fail to ingest the results: %s", err.Error())
+				return corbierror.New(msg, corbierror.Internal, true)
+			}
+			return ec.JSON(http.StatusOK, newResponse(fmt.Sprintf("%d results ingested, %d rejected", ingested, len(results)-ingested)))
+		})
+	}
+
+	if c.exporter != nil {
+		c.Server.GET("/exporter", func(ec echo.Context) error {
+			return ec.JSON(http.StatusOK, c.exporter.Statuses())
+		})
+	}
+
+	if c.aggregator != nil {
+		c.Server.POST("/aggregator/results", func(ec echo.Context) error {
+			payload, err := io.ReadAll(ec.Request().Body)
+			if err != nil {
+				msg := fmt.Sprintf("Fail to read the results: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			header := ec.Request().Header
+			err = c.aggregator.Verify(header.Get(signature.TimestampHeader), header.Get(signature.SignatureHeader), payload, time.Now())
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.Unauthorized, true)
+			}
+			var results []healthcheck.Result
+			if err := json.Unmarshal(payload, &results); err != nil {
+				msg := fmt.Sprintf("Invalid results: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			// agents without node identity are identified by their address
+			agent := ec.Request().Header.Get(agentHeader)
+			if agent == "" {
+				agent = ec.RealIP()
+			}
+			c.aggregator.Add(agent, results, time.Now())
+			return ec.JSON(http.StatusOK, newResponse("Results successfully added"))
+		})
+		c.Server.GET("/aggregator/agents", func(ec echo.Context) error {
+			return ec.JSON(http.StatusOK, c.aggregator.Agents(time.Now()))
+		})
+		c.Server.GET("/aggregator/results", func(ec echo.Context) error {
+			return ec.JSON(http.StatusOK, c.aggregator.Results(ec.QueryParam("agent"), ec.QueryParam("healthcheck")))
+		})
+		c.Server.GET("/aggregator/health", func(ec echo.Context) error {
+			return ec.JSON(http.StatusOK, c.aggregator.Health(time.Now()))
+		})
+		c.Server.GET("/aggregator/dashboard", func(ec echo.Context) error {
+			content, err := fs.ReadFile(fsys, "aggregator.html")
+			if err != nil {
+				return corbierror.Wrap(err, "Internal error", corbierror.Internal, true)
+			}
+			// the agents names and results are not trusted, the
+			// dashboard is escaped
+			tmpl, err := htmltemplate.New("aggregator").Funcs(htmltemplate.FuncMap{
+				"formattime": func(t time.Time) string {
+					return t.Format("2006/01/02 15:04:05")
+				},
+			}).Parse(string(content))
+			if err != nil {
+				return corbierror.Wrap(err, "Internal error", corbierror.Internal, true)
+			}
+			now := time.Now()
+			var tmplBytes bytes.Buffer
+			err = tmpl.Execute(&tmplBytes, map[string]interface{}{
+				"Agents": c.aggregator.Agents(now),
+				"Health": c.aggregator.Health(now),
+			})
+			if err != nil {
+				return corbierror.Wrap(err, "Internal error", corbierror.Internal, true)
+			}
+			return ec.HTML(http.StatusOK, tmplBytes.String())
+		})
+	}
+
+	// the heartbeats are sent by external systems, the endpoint is
+	// available even if the healthcheck API is disabled
+	c.Server.POST("/heartbeat/:name", func(ec echo.Context) error {
+		id := requestID(ec)
+		check := c.healthcheck.GetCheck(id)
+		if check == nil {
+			return corbierror.New(fmt.Sprintf("Healthcheck %s not found", id), corbierror.NotFound, true)
+		}
+		if !tokenAllows(ec, check.Base().Labels) {
+			return tokenError(ec, id)
+		}
+		heartbeatCheck, ok := check.(*healthcheck.HeartbeatHealthcheck)
+		if !ok {
+			return corbierror.New(fmt.Sprintf("The healthcheck %s is not a heartbeat healthcheck", id), corbierror.BadRequest, true)
+		}
+		heartbeatCheck.Ping(time.Now())
+		return ec.JSON(http.StatusOK, newResponse(fmt.Sprintf("Heartbeat received for %s", id)))
+	})
+
 	c.Server.GET("/health", func(ec echo.Context) error {
 		return ec.JSON(http.StatusOK, "ok")
 	})
@@ -3,6 +3,8 @@ package http
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,12 +12,17 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/appclacks/cabourotte/aggregator"
 	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/ingest"
+	"github.com/appclacks/cabourotte/maintenance"
 	"github.com/appclacks/cabourotte/memorystore"
 	"github.com/appclacks/cabourotte/prometheus"
+	"github.com/appclacks/cabourotte/signature"
 )
 
 func TestHandlers(t *testing.T) {
@@ -24,12 +31,12 @@ func TestHandlers(t *testing.T) {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
 	}
 	logger := zap.NewExample()
-	memstore := memorystore.NewMemoryStore(logger)
-	healthcheck, err := healthcheck.New(zap.NewExample(), make(chan *healthcheck.Result, 10), prom, []string{})
+	memstore := memorystore.NewMemoryStore(logger, 10)
+	healthcheck, err := healthcheck.New(zap.NewExample(), make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
 	if err != nil {
 		t.Fatalf("Fail to create the healthcheck component\n%v", err)
 	}
-	component, err := New(logger, memstore, prom, &Configuration{Host: "127.0.0.1", Port: 2001}, healthcheck)
+	component, err := New(logger, memstore, prom, &Configuration{Host: "127.0.0.1", Port: 2001}, healthcheck, maintenance.New(logger))
 	if err != nil {
 		t.Fatalf("Fail to create the component\n%v", err)
 	}
@@ -128,6 +135,22 @@ func TestHandlers(t *testing.T) {
 	if !strings.Contains(body, `not found`) {
 		t.Fatalf("Invalid body\n")
 	}
+	// get the results schema
+	resp, err = http.Get("http://127.0.0.1:2001/result/schema")
+	if err != nil {
+		t.Fatalf("Fail to get the results schema\n%v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Was expecting a 200 response, got %d", resp.StatusCode)
+	}
+	bodyBytes, err = io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Fail to read the body\n%v", err)
+	}
+	if !strings.Contains(string(bodyBytes), `"schema-version"`) {
+		t.Fatalf("Invalid body\n")
+	}
 	// delete everything
 	checks := []string{"foo", "bar", "baz", "tls-check"}
 	for _, c := range checks {
@@ -172,11 +195,11 @@ func TestOneOffCheck(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
 	}
-	healthcheck, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	healthcheck, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
 	if err != nil {
 		t.Fatalf("Fail to create the healthcheck component\n%v", err)
 	}
-	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, healthcheck)
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger, 10), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, healthcheck, maintenance.New(logger))
 	if err != nil {
 		t.Fatalf("Fail to create the component\n%v", err)
 	}
@@ -232,11 +255,11 @@ func TestBulkEndpoint(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
 	}
-	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
 	if err != nil {
 		t.Fatalf("Fail to create the healthcheck component\n%v", err)
 	}
-	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, checkComponent)
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger, 10), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, checkComponent, maintenance.New(logger))
 	if err != nil {
 		t.Fatalf("Fail to create the component\n%v", err)
 	}
@@ -297,8 +320,8 @@ func TestBasicAuth(t *testing.T) {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
 	}
 	logger := zap.NewExample()
-	memstore := memorystore.NewMemoryStore(logger)
-	healthcheck, err := healthcheck.New(zap.NewExample(), make(chan *healthcheck.Result, 10), prom, []string{})
+	memstore := memorystore.NewMemoryStore(logger, 10)
+	healthcheck, err := healthcheck.New(zap.NewExample(), make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
 	if err != nil {
 		t.Fatalf("Fail to create the healthcheck component\n%v", err)
 	}
@@ -312,7 +335,8 @@ func TestBasicAuth(t *testing.T) {
 				Username: "foobar",
 				Password: "mypassword",
 			}},
-		healthcheck)
+		healthcheck,
+		maintenance.New(logger))
 	if err != nil {
 		t.Fatalf("Fail to create the component\n%v", err)
 	}
@@ -341,3 +365,953 @@ func TestBasicAuth(t *testing.T) {
 		t.Fatalf("Expected 200, got status %d", resp.StatusCode)
 	}
 }
+
+func TestNamespaces(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(zap.NewExample(), make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(logger, memorystore.NewMemoryStore(logger, 10), prom, &Configuration{Host: "127.0.0.1", Port: 2002}, checkComponent, maintenance.New(logger))
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	client := &http.Client{}
+	request := func(method string, path string, body string) int {
+		req, err := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:2002%s", path), bytes.NewBuffer([]byte(body)))
+		if err != nil {
+			t.Fatalf("Fail to build the HTTP request\n%v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	check := `{"name":"foo","interval":"10m","target":"127.0.0.1","port":9999,"timeout":"10s"}`
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{method: "POST", path: "/healthcheck/tcp", body: check, status: http.StatusCreated},
+		{method: "POST", path: "/healthcheck/tcp?namespace=team-a", body: check, status: http.StatusCreated},
+		{method: "POST", path: "/healthcheck/bulk?namespace=team-b", body: fmt.Sprintf(`{"tcp-checks": [%s]}`, check), status: http.StatusCreated},
+		{method: "POST", path: "/healthcheck/tcp?namespace=team-a", body: `{"name":"bar","namespace":"team-b","interval":"10m","target":"127.0.0.1","port":9999,"timeout":"10s"}`, status: http.StatusBadRequest},
+		{method: "POST", path: "/healthcheck/tcp", body: `{"name":"bar","namespace":"team/b","interval":"10m","target":"127.0.0.1","port":9999,"timeout":"10s"}`, status: http.StatusBadRequest},
+		{method: "GET", path: "/healthcheck/foo?namespace=team-a", status: http.StatusOK},
+		{method: "GET", path: "/healthcheck/foo?namespace=team-c", status: http.StatusNotFound},
+	}
+	for _, c := range cases {
+		status := request(c.method, c.path, c.body)
+		if status != c.status {
+			t.Fatalf("%s %s: expected status %d, got %d", c.method, c.path, c.status, status)
+		}
+	}
+	if len(checkComponent.Healthchecks) != 3 {
+		t.Fatalf("Healthchecks were not successfully created: %d", len(checkComponent.Healthchecks))
+	}
+	// the bulk request only replaces the healthchecks of its namespace
+	status := request("POST", "/healthcheck/bulk?namespace=team-b", `{}`)
+	if status != http.StatusCreated {
+		t.Fatalf("HTTP request failed, status %d", status)
+	}
+	if checkComponent.GetCheck("team-b/foo") != nil {
+		t.Fatalf("The healthcheck was not removed")
+	}
+	if checkComponent.GetCheck("foo") == nil || checkComponent.GetCheck("team-a/foo") == nil {
+		t.Fatalf("The healthchecks of the other namespaces were removed")
+	}
+	resp, err := http.Get("http://127.0.0.1:2002/healthcheck?namespace=team-a")
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Fail to read the body\n%v", err)
+	}
+	if strings.Count(string(bodyBytes), `"name":"foo"`) != 1 || !strings.Contains(string(bodyBytes), `"namespace":"team-a"`) {
+		t.Fatalf("Invalid body %s", string(bodyBytes))
+	}
+	status = request("DELETE", "/healthcheck/foo?namespace=team-a", "")
+	if status != http.StatusOK {
+		t.Fatalf("HTTP request failed, status %d", status)
+	}
+	if checkComponent.GetCheck("team-a/foo") != nil || checkComponent.GetCheck("foo") == nil {
+		t.Fatalf("Invalid healthchecks after deletion")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestSeriesEndpoint(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	memstore := memorystore.NewMemoryStore(logger, 10)
+	checkComponent, err := healthcheck.New(zap.NewExample(), make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(logger, memstore, prom, &Configuration{Host: "127.0.0.1", Port: 2003}, checkComponent, maintenance.New(logger))
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	memstore.Add(&healthcheck.Result{
+		Name:                 "foo",
+		Success:              true,
+		Duration:             10,
+		HealthcheckTimestamp: 1010,
+	})
+	memstore.Add(&healthcheck.Result{
+		Name:                 "baz",
+		Success:              false,
+		Duration:             20,
+		HealthcheckTimestamp: 1020,
+		Message:              "connection refused",
+		ErrorCategory:        healthcheck.ErrorConnectionRefused,
+	})
+	cases := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{
+			path:   "/result/foo/series?from=1000&to=1120&step=1m",
+			status: http.StatusOK,
+			body:   `"points":[{"timestamp":1000,"executions":1,"success-ratio":1,"latency-avg":10,"latency-max":10}]`,
+		},
+		{
+			path:   "/result/series?from=1970-01-01T00:16:40Z&to=1120&step=1m",
+			status: http.StatusOK,
+			body:   `"name":"foo"`,
+		},
+		{
+			path:   "/result/foo/series?from=1000&to=1120&step=foo",
+			status: http.StatusBadRequest,
+			body:   "Invalid step",
+		},
+		{
+			path:   "/result/bar/series?from=1000&to=1120",
+			status: http.StatusNotFound,
+			body:   "not found",
+		},
+		{
+			path:   "/sla/foo?window=7d",
+			status: http.StatusOK,
+			body:   `"name":"foo"`,
+		},
+		{
+			path:   "/sla/foo?window=foo",
+			status: http.StatusBadRequest,
+			body:   "Invalid window",
+		},
+		{
+			path:   "/sla?window=7d",
+			status: http.StatusBadRequest,
+			body:   "label",
+		},
+		{
+			path:   "/slo",
+			status: http.StatusOK,
+			body:   `[]`,
+		},
+		{
+			path:   "/slo/foo",
+			status: http.StatusNotFound,
+			body:   "has no SLO",
+		},
+		{
+			path:   "/healthcheck/baz/failures?limit=5",
+			status: http.StatusOK,
+			body:   `[{"timestamp":1020,"duration":20,"error-category":"connection-refused","message":"connection refused"}]`,
+		},
+		{
+			path:   "/healthcheck/foo/failures",
+			status: http.StatusOK,
+			body:   `[]`,
+		},
+		{
+			path:   "/healthcheck/baz/failures?limit=0",
+			status: http.StatusBadRequest,
+			body:   "Invalid limit",
+		},
+		{
+			path:   "/annotation?healthcheck=foo",
+			status: http.StatusOK,
+			body:   `[]`,
+		},
+		{
+			path:   "/annotation/foo",
+			status: http.StatusNotFound,
+			body:   "not found",
+		},
+		{
+			path:   "/healthcheck/bar/failures",
+			status: http.StatusNotFound,
+			body:   "not found",
+		},
+	}
+	for _, c := range cases {
+		resp, err := http.Get("http://127.0.0.1:2003" + c.path)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Fail to read the body\n%v", err)
+		}
+		if resp.StatusCode != c.status {
+			t.Fatalf("Invalid status %d for %s: %s", resp.StatusCode, c.path, string(bodyBytes))
+		}
+		if !strings.Contains(string(bodyBytes), c.body) {
+			t.Fatalf("Invalid body for %s: %s", c.path, string(bodyBytes))
+		}
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestAggregatorEndpoints(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(logger,
+		memorystore.NewMemoryStore(logger, 10),
+		prom,
+		&Configuration{Host: "127.0.0.1", Port: 2004},
+		checkComponent,
+		maintenance.New(logger))
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	component.SetAggregator(aggregator.New(logger, &aggregator.Configuration{
+		AgentTimeout: healthcheck.Duration(time.Minute),
+		Retention:    healthcheck.Duration(time.Hour),
+	}))
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	payload := fmt.Sprintf(`[{"name":"foo","success":false,"healthcheck-timestamp":%d,"message":"<b>down</b>"}]`, time.Now().Unix())
+	req, err := http.NewRequest("POST", "http://127.0.0.1:2004/aggregator/results", strings.NewReader(payload))
+	if err != nil {
+		t.Fatalf("Fail to build the request\n%v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(agentHeader, "<script>agent-1</script>")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected 200, got status %d", resp.StatusCode)
+	}
+	cases := []struct {
+		path     string
+		contains string
+	}{
+		{"/aggregator/agents", `"unhealthy":1`},
+		{"/aggregator/results?healthcheck=foo", `"node":{"name":`},
+		{"/aggregator/results?agent=other", `[]`},
+		{"/aggregator/health", `"state":"unhealthy"`},
+		{"/aggregator/dashboard", `&lt;script&gt;agent-1&lt;/script&gt;`},
+	}
+	for _, c := range cases {
+		resp, err := http.Get("http://127.0.0.1:2004" + c.path)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Fail to read the body\n%v", err)
+		}
+		if resp.StatusCode != 200 || !strings.Contains(string(body), c.contains) {
+			t.Fatalf("Invalid response for %s: %d %s", c.path, resp.StatusCode, string(body))
+		}
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestExecuteEndpoint(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	healthcheckComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(logger, memorystore.NewMemoryStore(logger, 10), prom, &Configuration{Host: "127.0.0.1", Port: 2005}, healthcheckComponent, maintenance.New(logger))
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	count := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	// the registered healthcheck targets a closed port
+	err = healthcheckComponent.AddCheck(healthcheck.NewHTTPHealthcheck(logger, &healthcheck.HTTPHealthcheckConfiguration{
+		Base: healthcheck.Base{
+			Name:     "foo",
+			Interval: healthcheck.Duration(10 * time.Minute),
+		},
+		Target:      "127.0.0.1",
+		Port:        1,
+		Protocol:    healthcheck.HTTP,
+		ValidStatus: []uint{200},
+		Timeout:     healthcheck.Duration(time.Second),
+	}))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	cases := []struct {
+		path   string
+		body   string
+		status int
+		match  string
+	}{
+		{path: "/healthcheck/foo/execute", body: fmt.Sprintf(`{"port":%d}`, port), status: http.StatusOK, match: `"success":true`},
+		{path: "/healthcheck/foo/execute", body: `{"name":"bar"}`, status: http.StatusBadRequest, match: "cannot be overridden"},
+		{path: "/healthcheck/foo/execute", body: `{"unknown":true}`, status: http.StatusBadRequest, match: "Invalid overrides"},
+		{path: "/healthcheck/foo/execute", body: `{"port":0}`, status: http.StatusBadRequest, match: "Invalid healthcheck configuration"},
+		{path: "/healthcheck/bar/execute", body: `{}`, status: http.StatusNotFound, match: "not found"},
+	}
+	client := &http.Client{}
+	for _, c := range cases {
+		req, err := http.NewRequest("POST", "http://127.0.0.1:2005"+c.path, bytes.NewBuffer([]byte(c.body)))
+		if err != nil {
+			t.Fatalf("Fail to build the HTTP request\n%v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Fail to read the body\n%v", err)
+		}
+		if resp.StatusCode != c.status {
+			t.Fatalf("Invalid status %d for %s %s: %s", resp.StatusCode, c.path, c.body, string(bodyBytes))
+		}
+		if !strings.Contains(string(bodyBytes), c.match) {
+			t.Fatalf("Invalid body for %s %s: %s", c.path, c.body, string(bodyBytes))
+		}
+	}
+	if count != 1 {
+		t.Fatalf("The overridden target was not reached: %d", count)
+	}
+	// the registered healthcheck is not modified
+	check := healthcheckComponent.GetCheck("foo")
+	if check.GetConfig().(*healthcheck.HTTPHealthcheckConfiguration).Port != 1 {
+		t.Fatalf("The registered healthcheck was modified")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+	err = healthcheckComponent.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the healthcheck component\n%v", err)
+	}
+}
+
+func TestAggregatorSignature(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(logger,
+		memorystore.NewMemoryStore(logger, 10),
+		prom,
+		&Configuration{Host: "127.0.0.1", Port: 2006},
+		checkComponent,
+		maintenance.New(logger))
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	component.SetAggregator(aggregator.New(logger, &aggregator.Configuration{
+		AgentTimeout:       healthcheck.Duration(time.Minute),
+		Retention:          healthcheck.Duration(time.Hour),
+		Secret:             "secret",
+		SignatureTolerance: healthcheck.Duration(time.Minute),
+	}))
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	now := time.Now().Unix()
+	payload := []byte(fmt.Sprintf(`[{"name":"foo","success":true,"healthcheck-timestamp":%d,"message":"ok"}]`, now))
+	cases := []struct {
+		timestamp int64
+		secret    string
+		status    int
+	}{
+		{timestamp: now, secret: "secret", status: http.StatusOK},
+		{timestamp: now, secret: "other", status: http.StatusUnauthorized},
+		{timestamp: now - 3600, secret: "secret", status: http.StatusUnauthorized},
+		{status: http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest("POST", "http://127.0.0.1:2006/aggregator/results", bytes.NewReader(payload))
+		if err != nil {
+			t.Fatalf("Fail to build the request\n%v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.secret != "" {
+			req.Header.Set(signature.TimestampHeader, strconv.FormatInt(c.timestamp, 10))
+			req.Header.Set(signature.SignatureHeader, signature.Sign(c.secret, c.timestamp, payload))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Fatalf("Expected status %d, got %d", c.status, resp.StatusCode)
+		}
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestIngestEndpoint(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	chanResult := make(chan *healthcheck.Result, 10)
+	checkComponent, err := healthcheck.New(logger, chanResult, prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(logger,
+		memorystore.NewMemoryStore(logger, 10),
+		prom,
+		&Configuration{Host: "127.0.0.1", Port: 2009},
+		checkComponent,
+		maintenance.New(logger))
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	component.SetIngester(ingest.New(logger, &ingest.Configuration{
+		Secret:             "secret",
+		SignatureTolerance: healthcheck.Duration(time.Minute),
+		MaxHops:            ingest.DefaultMaxHops,
+	}, &healthcheck.Node{Name: "regional-1"}, chanResult))
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	now := time.Now().Unix()
+	payload := []byte(fmt.Sprintf(`[{"name":"foo","success":true,"healthcheck-timestamp":%d,"message":"ok","node":{"name":"edge-1"}},{"name":"bar","success":true,"healthcheck-timestamp":%d,"message":"ok","node":{"name":"regional-1"}}]`, now, now))
+	cases := []struct {
+		secret string
+		status int
+	}{
+		{secret: "secret", status: http.StatusOK},
+		{secret: "other", status: http.StatusUnauthorized},
+		{status: http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest("POST", "http://127.0.0.1:2009/ingest", bytes.NewReader(payload))
+		if err != nil {
+			t.Fatalf("Fail to build the request\n%v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.secret != "" {
+			req.Header.Set(signature.TimestampHeader, strconv.FormatInt(now, 10))
+			req.Header.Set(signature.SignatureHeader, signature.Sign(c.secret, now, payload))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Fatalf("Expected status %d, got %d", c.status, resp.StatusCode)
+		}
+	}
+	// the result executed by this node is rejected
+	if len(chanResult) != 1 {
+		t.Fatalf("Invalid number of ingested results: %d", len(chanResult))
+	}
+	result := <-chanResult
+	if result.Name != "foo" || result.Node.Name != "edge-1" || len(result.Relays) != 1 || result.Relays[0] != "regional-1" {
+		t.Fatalf("Invalid ingested result %+v", result)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestHeartbeatEndpoint(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	healthcheckComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(logger, memorystore.NewMemoryStore(logger, 10), prom, &Configuration{Host: "127.0.0.1", Port: 2007}, healthcheckComponent, maintenance.New(logger))
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	client := &http.Client{}
+	requests := []struct {
+		path   string
+		body   string
+		status int
+	}{
+		{path: "/healthcheck/heartbeat", body: `{"name":"backup","interval":"10m","grace":"1m"}`, status: http.StatusCreated},
+		{path: "/healthcheck/tcp", body: `{"name":"ssh","target":"127.0.0.1","port":22,"timeout":"1s","interval":"10m"}`, status: http.StatusCreated},
+		{path: "/heartbeat/backup", status: http.StatusOK},
+		{path: "/heartbeat/ssh", status: http.StatusBadRequest},
+		{path: "/heartbeat/unknown", status: http.StatusNotFound},
+	}
+	for _, r := range requests {
+		req, err := http.NewRequest("POST", "http://127.0.0.1:2007"+r.path, bytes.NewBuffer([]byte(r.body)))
+		if err != nil {
+			t.Fatalf("Fail to build the HTTP request\n%v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != r.status {
+			t.Fatalf("Invalid status %d for %s", resp.StatusCode, r.path)
+		}
+	}
+	check, ok := healthcheckComponent.GetCheck("backup").(*healthcheck.HeartbeatHealthcheck)
+	if !ok {
+		t.Fatalf("The heartbeat healthcheck was not added")
+	}
+	if time.Since(check.LastHeartbeat()) > time.Minute {
+		t.Fatalf("The heartbeat was not received")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+	err = healthcheckComponent.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the healthcheck component\n%v", err)
+	}
+}
+
+func TestExternalResultEndpoint(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *healthcheck.Result, 10)
+	healthcheckComponent, err := healthcheck.New(logger, chanResult, prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(logger, memorystore.NewMemoryStore(logger, 10), prom, &Configuration{Host: "127.0.0.1", Port: 2011}, healthcheckComponent, maintenance.New(logger))
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	client := &http.Client{}
+	requests := []struct {
+		path   string
+		body   string
+		status int
+	}{
+		{path: "/healthcheck/heartbeat", body: `{"name":"backup","interval":"10m","grace":"1m"}`, status: http.StatusCreated},
+		{path: "/result/external", body: `{"name":"raid","success":false,"message":"degraded array","duration":120,"labels":{"host":"db-1"}}`, status: http.StatusCreated},
+		{path: "/result/external?namespace=storage", body: `{"name":"raid","success":true}`, status: http.StatusCreated},
+		{path: "/result/external?namespace=storage", body: `{"name":"raid","namespace":"backup","success":true}`, status: http.StatusBadRequest},
+		{path: "/result/external", body: `{"name":"backup","success":true}`, status: http.StatusConflict},
+		{path: "/result/external", body: `{"success":true}`, status: http.StatusBadRequest},
+		{path: "/result/external", body: `{"name":"raid","success":true,"severity":"urgent"}`, status: http.StatusBadRequest},
+	}
+	for _, r := range requests {
+		req, err := http.NewRequest("POST", "http://127.0.0.1:2011"+r.path, bytes.NewBuffer([]byte(r.body)))
+		if err != nil {
+			t.Fatalf("Fail to build the HTTP request\n%v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != r.status {
+			t.Fatalf("Invalid status %d for %s", resp.StatusCode, r.body)
+		}
+	}
+	// the results of the heartbeat healthcheck are ignored
+	results := []*healthcheck.Result{}
+	for len(chanResult) != 0 {
+		if result := <-chanResult; result.Source == healthcheck.ExternalSource {
+			results = append(results, result)
+		}
+	}
+	if len(results) != 2 {
+		t.Fatalf("Invalid number of external results: %d", len(results))
+	}
+	result := results[0]
+	if result.Name != "raid" || result.Success || result.Message != "degraded array" || result.Duration != 120 || result.Labels["host"] != "db-1" || result.Source != healthcheck.ExternalSource {
+		t.Fatalf("Invalid external result %+v", result)
+	}
+	result = results[1]
+	if result.ID() != "storage/raid" || !result.Success || result.Message != "success" {
+		t.Fatalf("Invalid external result %+v", result)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+	err = healthcheckComponent.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the healthcheck component\n%v", err)
+	}
+}
+
+func TestSourceEndpoint(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	healthcheckComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(logger, memorystore.NewMemoryStore(logger, 10), prom, &Configuration{Host: "127.0.0.1", Port: 2008}, healthcheckComponent, maintenance.New(logger))
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	healthcheckComponent.RecordReconciliation("http-discovery-foo", errors.New("connection refused"))
+	resp, err := http.Get("http://127.0.0.1:2008/source")
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Invalid status %d", resp.StatusCode)
+	}
+	var sources []healthcheck.SourceStatus
+	err = json.NewDecoder(resp.Body).Decode(&sources)
+	if err != nil {
+		t.Fatalf("Fail to read the sources\n%v", err)
+	}
+	if len(sources) != 1 || sources[0].Source != "http-discovery-foo" || sources[0].LastError != "connection refused" || sources[0].Failures != 1 {
+		t.Fatalf("Invalid sources %+v", sources)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	healthcheckComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	config := &Configuration{
+		Host: "127.0.0.1",
+		Port: 2010,
+		Tokens: []Token{
+			{Name: "admin", Token: "admin-secret"},
+			{Name: "payments", Token: "payments-secret", Selector: map[string]string{"team": "payments"}},
+		},
+	}
+	memstore := memorystore.NewMemoryStore(logger, 10)
+	now := time.Now()
+	slo := &healthcheck.SLO{Objective: 99}
+	memstore.Add(&healthcheck.Result{Name: "billing", Group: "billing", Labels: map[string]string{"team": "billing"}, Success: false, Message: "error", SLO: slo, HealthcheckTimestamp: now.Unix()})
+	memstore.Add(&healthcheck.Result{Name: "payments", Group: "payments", Labels: map[string]string{"team": "payments"}, Success: false, Message: "error", SLO: slo, HealthcheckTimestamp: now.Unix()})
+	for _, annotation := range []memorystore.Annotation{
+		{Name: "billing-incident", Healthcheck: "billing", Text: "incident", Start: now},
+		{Name: "payments-incident", Healthcheck: "payments", Text: "incident", Start: now},
+		{Name: "billing-release", Group: "billing", Text: "release", Start: now},
+	} {
+		annotation := annotation
+		if err := memstore.AddAnnotation(&annotation); err != nil {
+			t.Fatalf("Fail to add the annotation\n%v", err)
+		}
+	}
+	component, err := New(logger, memstore, prom, config, healthcheckComponent, maintenance.New(logger))
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	component.SetIngester(ingest.New(logger, &ingest.Configuration{
+		Secret:             "ingest-secret",
+		SignatureTolerance: healthcheck.Duration(time.Minute),
+		MaxHops:            ingest.DefaultMaxHops,
+	}, &healthcheck.Node{Name: "regional-1"}, make(chan *healthcheck.Result, 10)))
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	client := &http.Client{}
+	requests := []struct {
+		method string
+		path   string
+		token  string
+		body   string
+		status int
+		// hidden a content which should not be in the response
+		hidden string
+	}{
+		{method: "GET", path: "/healthcheck", status: http.StatusUnauthorized},
+		// the liveness probes do not require a token
+		{method: "GET", path: "/health", status: http.StatusOK},
+		{method: "GET", path: "/healthz", status: http.StatusOK},
+		{method: "GET", path: "/result/billing", token: "payments-secret", status: http.StatusForbidden},
+		{method: "GET", path: "/result/payments", token: "payments-secret", status: http.StatusOK},
+		{method: "GET", path: "/healthcheck/billing/failures", token: "payments-secret", status: http.StatusForbidden},
+		{method: "GET", path: "/healthcheck/payments/failures", token: "payments-secret", status: http.StatusOK},
+		{method: "GET", path: "/result/billing/history", token: "payments-secret", status: http.StatusForbidden},
+		{method: "GET", path: "/result/billing", token: "admin-secret", status: http.StatusOK},
+		{method: "GET", path: "/healthcheck", token: "invalid", status: http.StatusUnauthorized},
+		{method: "POST", path: "/healthcheck/heartbeat", token: "admin-secret", body: `{"name":"billing","interval":"10m","grace":"1m","labels":{"team":"billing"}}`, status: http.StatusCreated},
+		{method: "POST", path: "/healthcheck/heartbeat", token: "payments-secret", body: `{"name":"payments","interval":"10m","grace":"1m","labels":{"team":"payments"}}`, status: http.StatusCreated},
+		{method: "POST", path: "/healthcheck/heartbeat", token: "payments-secret", body: `{"name":"other","interval":"10m","grace":"1m","labels":{"team":"billing"}}`, status: http.StatusForbidden},
+		// the healthcheck of another team can not be replaced
+		{method: "POST", path: "/healthcheck/heartbeat", token: "payments-secret", body: `{"name":"billing","interval":"10m","grace":"1m","labels":{"team":"payments"}}`, status: http.StatusForbidden},
+		{method: "GET", path: "/healthcheck/billing", token: "payments-secret", status: http.StatusNotFound},
+		{method: "GET", path: "/healthcheck/payments", token: "payments-secret", status: http.StatusOK},
+		{method: "POST", path: "/heartbeat/billing", token: "payments-secret", status: http.StatusForbidden},
+		{method: "POST", path: "/heartbeat/payments", token: "payments-secret", status: http.StatusOK},
+		{method: "GET", path: "/result/series", token: "payments-secret", status: http.StatusOK, hidden: "billing"},
+		{method: "GET", path: "/sla?label=team", token: "payments-secret", status: http.StatusOK, hidden: "billing"},
+		{method: "GET", path: "/sla/billing", token: "payments-secret", status: http.StatusForbidden},
+		{method: "GET", path: "/sla/payments", token: "payments-secret", status: http.StatusOK},
+		{method: "GET", path: "/slo", token: "payments-secret", status: http.StatusOK, hidden: "billing"},
+		{method: "GET", path: "/slo/billing", token: "payments-secret", status: http.StatusForbidden},
+		{method: "GET", path: "/slo/payments", token: "payments-secret", status: http.StatusOK},
+		{method: "GET", path: "/annotation", token: "payments-secret", status: http.StatusOK, hidden: "billing"},
+		{method: "GET", path: "/annotation/billing-incident", token: "payments-secret", status: http.StatusForbidden},
+		{method: "GET", path: "/annotation/billing-release", token: "payments-secret", status: http.StatusForbidden},
+		{method: "GET", path: "/annotation/payments-incident", token: "payments-secret", status: http.StatusOK},
+		{method: "GET", path: "/health/group/billing", token: "payments-secret", status: http.StatusForbidden},
+		{method: "GET", path: "/health/group/payments", token: "payments-secret", status: http.StatusServiceUnavailable},
+		{method: "GET", path: "/source", token: "payments-secret", status: http.StatusOK, hidden: `"checks":2`},
+		{method: "GET", path: "/source", token: "admin-secret", status: http.StatusOK, hidden: `"checks":1`},
+		{method: "DELETE", path: "/healthcheck/billing", token: "payments-secret", status: http.StatusForbidden},
+		{method: "POST", path: "/maintenance", token: "payments-secret", body: `{"name":"window","selector":{"team":"billing"}}`, status: http.StatusForbidden},
+		// the bulk requests only replace the healthchecks of the token
+		{method: "POST", path: "/healthcheck/bulk", token: "payments-secret", body: `{}`, status: http.StatusCreated},
+		{method: "GET", path: "/healthcheck/billing", token: "admin-secret", status: http.StatusOK},
+		{method: "GET", path: "/healthcheck/payments", token: "admin-secret", status: http.StatusNotFound},
+		{method: "DELETE", path: "/healthcheck/billing", token: "admin-secret", status: http.StatusOK},
+	}
+	for _, r := range requests {
+		req, err := http.NewRequest(r.method, "http://127.0.0.1:2010"+r.path, bytes.NewBuffer([]byte(r.body)))
+		if err != nil {
+			t.Fatalf("Fail to build the HTTP request\n%v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if r.token != "" {
+			req.Header.Set("Authorization", "Bearer "+r.token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Fail to read the response\n%v", err)
+		}
+		if resp.StatusCode != r.status {
+			t.Fatalf("Invalid status %d for %s %s", resp.StatusCode, r.method, r.path)
+		}
+		if r.hidden != "" && strings.Contains(string(body), r.hidden) {
+			t.Fatalf("The response of %s %s should not contain %s: %s", r.method, r.path, r.hidden, string(body))
+		}
+	}
+	// the signed results are ingested without token
+	timestamp := now.Unix()
+	payload := []byte(fmt.Sprintf(`[{"name":"foo","success":true,"healthcheck-timestamp":%d,"message":"ok","node":{"name":"edge-1"}}]`, timestamp))
+	req, err := http.NewRequest("POST", "http://127.0.0.1:2010"+ingest.Path, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("Fail to build the HTTP request\n%v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(signature.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(signature.SignatureHeader, signature.Sign("ingest-secret", timestamp, payload))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Invalid status %d for the signed results", resp.StatusCode)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+	err = healthcheckComponent.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the healthcheck component\n%v", err)
+	}
+}
+
+func TestCheckNamesConflicts(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	healthcheckComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	configured := []healthcheck.TCPHealthcheckConfiguration{
+		{
+			Base:    healthcheck.Base{Name: "db", Interval: healthcheck.Duration(10 * time.Minute)},
+			Target:  "127.0.0.1",
+			Port:    5432,
+			Timeout: healthcheck.Duration(time.Second),
+		},
+	}
+	err = healthcheckComponent.ReloadForSource(healthcheck.SourceConfig, nil, nil, nil, configured, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Fail to add the configured healthcheck\n%v", err)
+	}
+	component, err := New(logger, memorystore.NewMemoryStore(logger, 10), prom, &Configuration{Host: "127.0.0.1", Port: 2012}, healthcheckComponent, maintenance.New(logger))
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	client := &http.Client{}
+	requests := []struct {
+		path    string
+		body    string
+		status  int
+		message string
+	}{
+		{path: "/healthcheck/tcp", body: `{"name":"db","target":"127.0.0.1","port":5433,"interval":"10m","timeout":"1s"}`, status: http.StatusConflict, message: "Fail to add the healthcheck db: The healthcheck db already exists and is managed by the source configuration"},
+		{path: "/healthcheck/tcp", body: `{"name":"my db","target":"127.0.0.1","port":5433,"interval":"10m","timeout":"1s"}`, status: http.StatusBadRequest},
+		{path: "/healthcheck/tcp", body: `{"generate-name":true,"target":"127.0.0.1","port":5433,"interval":"10m","timeout":"1s"}`, status: http.StatusCreated},
+		{path: "/healthcheck/tcp", body: `{"generate-name":true,"target":"127.0.0.1","port":5433,"interval":"10m","timeout":"1s"}`, status: http.StatusCreated},
+		{path: "/healthcheck/command", body: `{"generate-name":true,"command":"true","interval":"10m","timeout":"1s"}`, status: http.StatusBadRequest},
+		{path: "/healthcheck/bulk", body: `{"tcp-checks":[{"generate-name":true,"target":"127.0.0.1","port":22,"interval":"10m","timeout":"1s"},{"name":"tcp-127.0.0.1-22","target":"127.0.0.1","port":22,"interval":"10m","timeout":"1s"}]}`, status: http.StatusConflict},
+		{path: "/healthcheck/bulk", body: `{"tcp-checks":[{"name":"db","target":"127.0.0.1","port":22,"interval":"10m","timeout":"1s"}]}`, status: http.StatusConflict, message: "The healthcheck db already exists and is managed by the source configuration"},
+	}
+	for _, r := range requests {
+		req, err := http.NewRequest("POST", "http://127.0.0.1:2012"+r.path, bytes.NewBuffer([]byte(r.body)))
+		if err != nil {
+			t.Fatalf("Fail to build the HTTP request\n%v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		var response BasicResponse
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Fail to read the response\n%v", err)
+		}
+		if resp.StatusCode != r.status {
+			t.Fatalf("Invalid status %d for %s", resp.StatusCode, r.body)
+		}
+		if r.message != "" && (len(response.Messages) != 1 || response.Messages[0] != r.message) {
+			t.Fatalf("Invalid message for %s: %v", r.body, response.Messages)
+		}
+	}
+	if healthcheckComponent.GetCheck("tcp-127.0.0.1-5433") == nil {
+		t.Fatalf("The healthcheck with a generated name was not added")
+	}
+	check := healthcheckComponent.GetCheck("db")
+	if check == nil || check.Base().Source != healthcheck.SourceConfig {
+		t.Fatalf("The configured healthcheck was replaced")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+	err = healthcheckComponent.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the healthcheck component\n%v", err)
+	}
+}
@@ -15,7 +15,11 @@ import (
 	prom "github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
+	"github.com/appclacks/cabourotte/aggregator"
+	"github.com/appclacks/cabourotte/exporter"
 	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/ingest"
+	"github.com/appclacks/cabourotte/maintenance"
 	"github.com/appclacks/cabourotte/memorystore"
 	"github.com/appclacks/cabourotte/prometheus"
 )
@@ -26,6 +30,10 @@ type Component struct {
 	Config           *Configuration
 	Logger           *zap.Logger
 	healthcheck      *healthcheck.Component
+	maintenance      *maintenance.Component
+	aggregator       *aggregator.Aggregator
+	ingester         *ingest.Ingester
+	exporter         *exporter.Component
 	Server           *echo.Echo
 	Prometheus       *prometheus.Prometheus
 	requestHistogram *prom.HistogramVec
@@ -34,7 +42,7 @@ type Component struct {
 }
 
 // New creates a new HTTP component
-func New(logger *zap.Logger, memstore *memorystore.MemoryStore, promComponent *prometheus.Prometheus, config *Configuration, healthcheck *healthcheck.Component) (*Component, error) {
+func New(logger *zap.Logger, memstore *memorystore.MemoryStore, promComponent *prometheus.Prometheus, config *Configuration, healthcheck *healthcheck.Component, maintenance *maintenance.Component) (*Component, error) {
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
@@ -98,6 +106,7 @@ func New(logger *zap.Logger, memstore *memorystore.MemoryStore, promComponent *p
 		Server:           e,
 		Logger:           logger,
 		healthcheck:      healthcheck,
+		maintenance:      maintenance,
 		Prometheus:       promComponent,
 		requestHistogram: reqHistogram,
 		responseCounter:  respCounter,
@@ -105,6 +114,25 @@ func New(logger *zap.Logger, memstore *memorystore.MemoryStore, promComponent *p
 	return &component, nil
 }
 
+// SetAggregator enables the aggregator API, receiving the results of the
+// agents. It should be called before starting the server.
+func (c *Component) SetAggregator(a *aggregator.Aggregator) {
+	c.aggregator = a
+}
+
+// SetIngester enables the ingest API, receiving the results forwarded by
+// other nodes. It should be called before starting the server.
+func (c *Component) SetIngester(i *ingest.Ingester) {
+	c.ingester = i
+}
+
+// SetExporter enables the exporters API, returning the exporters and their
+// circuit breakers states. It should be called before starting the
+// server.
+func (c *Component) SetExporter(e *exporter.Component) {
+	c.exporter = e
+}
+
 // Start starts the http server
 func (c *Component) Start() error {
 	address := fmt.Sprintf("%s:%d", c.Config.Host, c.Config.Port)
@@ -12,6 +12,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/maintenance"
 	"github.com/appclacks/cabourotte/memorystore"
 	"github.com/appclacks/cabourotte/prometheus"
 )
@@ -22,11 +23,11 @@ func TestStartStop(t *testing.T) {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
 	}
 	logger := zap.NewExample()
-	healthcheck, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	healthcheck, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
 	if err != nil {
 		t.Fatalf("Fail to create the healthcheck component\n%v", err)
 	}
-	component, err := New(logger, memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2000}, healthcheck)
+	component, err := New(logger, memorystore.NewMemoryStore(logger, 10), prom, &Configuration{Host: "127.0.0.1", Port: 2000}, healthcheck, maintenance.New(logger))
 	if err != nil {
 		t.Fatalf("Fail to create the component\n%v", err)
 	}
@@ -53,12 +54,12 @@ func TestStartStopTLS(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
 	}
-	healthcheck, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	healthcheck, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{}, healthcheck.ConcurrencyConfiguration{})
 	if err != nil {
 		t.Fatalf("Fail to create the healthcheck component\n%v", err)
 	}
 	component, err := New(
-		logger, memorystore.NewMemoryStore(logger),
+		logger, memorystore.NewMemoryStore(logger, 10),
 		prom,
 		&Configuration{
 			Host:   "127.0.0.1",
@@ -68,6 +69,7 @@ func TestStartStopTLS(t *testing.T) {
 			Cacert: "../test/cert.pem",
 		},
 		healthcheck,
+		maintenance.New(logger),
 	)
 	if err != nil {
 		t.Fatalf("Fail to create the component\n%v", err)
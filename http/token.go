@@ -0,0 +1,226 @@
+package http
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/mcorbin/corbierror"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/ingest"
+	"github.com/appclacks/cabourotte/memorystore"
+)
+
+// tokenContextKey the key of the request token in the echo context
+const tokenContextKey = "token"
+
+// Token an API token, sent in the Authorization header using the Bearer
+// scheme
+type Token struct {
+	// Name identifies the token in the logs
+	Name string
+	// Token the secret value of the token
+	Token string
+	// Selector restricts the token to the healthchecks having all these
+	// labels. The tokens without selector manage all the healthchecks.
+	Selector map[string]string
+}
+
+// validateTokens validates the API tokens
+func validateTokens(tokens []Token) error {
+	names := make(map[string]bool)
+	values := make(map[string]bool)
+	for _, token := range tokens {
+		if token.Name == "" || token.Token == "" {
+			return fmt.Errorf("The API tokens should have a name and a token")
+		}
+		if names[token.Name] {
+			return fmt.Errorf("The API token %s is defined twice", token.Name)
+		}
+		if values[token.Token] {
+			return fmt.Errorf("The API token %s reuses the value of another token", token.Name)
+		}
+		names[token.Name] = true
+		values[token.Token] = true
+	}
+	return nil
+}
+
+// Allows returns true if the labels match the token selector
+func (t *Token) Allows(labels map[string]string) bool {
+	for k, v := range t.Selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// scoped returns true if the token is restricted by a selector
+func (t *Token) scoped() bool {
+	return len(t.Selector) != 0
+}
+
+// scopedRoutes the routes available to the tokens restricted by a
+// selector, in addition to the GET routes. The handlers verify the
+// healthchecks labels.
+var scopedRoutes = map[string]bool{
+	"POST /healthcheck/dns":           true,
+	"POST /healthcheck/tcp":           true,
+	"POST /healthcheck/tls":           true,
+	"POST /healthcheck/http":          true,
+	"POST /healthcheck/command":       true,
+	"POST /healthcheck/grpc":          true,
+	"POST /healthcheck/profile":       true,
+	"POST /healthcheck/heartbeat":     true,
+	"POST /healthcheck/bulk":          true,
+	"POST /healthcheck/:name/execute": true,
+	"DELETE /healthcheck/:name":       true,
+	"POST /heartbeat/:name":           true,
+	"POST /result/external":           true,
+}
+
+// publicRoutes the routes not requiring an API token, probed by the
+// cluster peers and the orchestrators
+var publicRoutes = map[string]bool{
+	"GET /health":  true,
+	"GET /healthz": true,
+}
+
+// public returns true if the route does not require an API token. The
+// ingest route authenticates the forwarding nodes with the signatures of
+// the results if a secret is configured.
+func (c *Component) public(route string) bool {
+	if publicRoutes[route] {
+		return true
+	}
+	return route == "POST "+ingest.Path && c.ingester != nil && c.ingester.Signed()
+}
+
+// FindToken returns the token matching the value, or nil
+func FindToken(tokens []Token, value string) *Token {
+	var result *Token
+	for i := range tokens {
+		// all the tokens are compared to not leak the matching one
+		if subtle.ConstantTimeCompare([]byte(value), []byte(tokens[i].Token)) == 1 {
+			result = &tokens[i]
+		}
+	}
+	return result
+}
+
+// tokenMiddleware authenticates the requests using the API tokens, the
+// tokens restricted by a selector only reaching the scoped routes
+func (c *Component) tokenMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ec echo.Context) error {
+		if c.public(ec.Request().Method + " " + ec.Path()) {
+			return next(ec)
+		}
+		value := strings.TrimPrefix(ec.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		token := FindToken(c.Config.Tokens, value)
+		if value == "" || token == nil {
+			c.Logger.Error("Invalid API token")
+			return corbierror.New("Unauthorized", corbierror.Unauthorized, true)
+		}
+		route := ec.Request().Method + " " + ec.Path()
+		if token.scoped() && ec.Request().Method != "GET" && !scopedRoutes[route] {
+			msg := fmt.Sprintf("The API token %s is restricted to the healthchecks matching its selector", token.Name)
+			return corbierror.New(msg, corbierror.Forbidden, true)
+		}
+		ec.Set(tokenContextKey, token)
+		return next(ec)
+	}
+}
+
+// requestToken returns the token of the request, or nil if the API
+// tokens are not configured
+func requestToken(ec echo.Context) *Token {
+	token, _ := ec.Get(tokenContextKey).(*Token)
+	return token
+}
+
+// tokenAllows returns true if the request token can manage the
+// healthchecks having these labels
+func tokenAllows(ec echo.Context, labels map[string]string) bool {
+	token := requestToken(ec)
+	return token == nil || token.Allows(labels)
+}
+
+// tokenError the error returned when the request token can not manage an
+// healthcheck
+func tokenError(ec echo.Context, id string) error {
+	msg := fmt.Sprintf("The API token %s is not allowed to manage the healthcheck %s", requestToken(ec).Name, id)
+	return corbierror.New(msg, corbierror.Forbidden, true)
+}
+
+// resultAllows returns true if the request token can read the results of
+// an healthcheck, using the labels of its latest result or of the
+// healthcheck
+func (c *Component) resultAllows(ec echo.Context, id string) bool {
+	if requestToken(ec) == nil {
+		return true
+	}
+	labels := map[string]string{}
+	if result, err := c.MemoryStore.Get(id); err == nil {
+		labels = result.Labels
+	} else if check := c.healthcheck.GetCheck(id); check != nil {
+		labels = check.Base().Labels
+	}
+	return tokenAllows(ec, labels)
+}
+
+// checkResultToken verifies that the request token can read the results
+// of an healthcheck
+func (c *Component) checkResultToken(ec echo.Context, id string) error {
+	if !c.resultAllows(ec, id) {
+		return tokenError(ec, id)
+	}
+	return nil
+}
+
+// groupAllows returns true if the request token can read the results of
+// all the healthchecks of a group
+func (c *Component) groupAllows(ec echo.Context, id string) bool {
+	if requestToken(ec) == nil {
+		return true
+	}
+	for _, result := range c.MemoryStore.List() {
+		if result.Group != "" && healthcheck.ID(result.Namespace, result.Group) == id && !tokenAllows(ec, result.Labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkGroupToken verifies that the request token can read the state of
+// a group
+func (c *Component) checkGroupToken(ec echo.Context, id string) error {
+	if !c.groupAllows(ec, id) {
+		msg := fmt.Sprintf("The API token %s is not allowed to read the group %s", requestToken(ec).Name, id)
+		return corbierror.New(msg, corbierror.Forbidden, true)
+	}
+	return nil
+}
+
+// checkAnnotationToken verifies that the request token can read the
+// healthcheck or the group referenced by an annotation
+func (c *Component) checkAnnotationToken(ec echo.Context, annotation memorystore.Annotation) error {
+	if annotation.Healthcheck != "" {
+		return c.checkResultToken(ec, annotation.Healthcheck)
+	}
+	return c.checkGroupToken(ec, annotation.Group)
+}
+
+// checkToken verifies that the request token can manage an healthcheck,
+// and the healthcheck it replaces if any
+func (c *Component) checkToken(ec echo.Context, base healthcheck.Base) error {
+	if !tokenAllows(ec, base.Labels) {
+		return tokenError(ec, base.ID())
+	}
+	if existing := c.healthcheck.GetCheck(base.ID()); existing != nil && !tokenAllows(ec, existing.Base().Labels) {
+		return tokenError(ec, base.ID())
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package ingest
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// DefaultMaxHops the default maximum number of nodes a result can be
+// forwarded through
+const DefaultMaxHops = 8
+
+// DefaultSignatureTolerance the default maximum difference between the
+// timestamp of a signed payload and the current time
+const DefaultSignatureTolerance = healthcheck.Duration(5 * time.Minute)
+
+// Configuration the configuration of the ingestion of the results
+// forwarded by other Cabourotte nodes
+type Configuration struct {
+	// Secret the secret of the HMAC signatures of the forwarded results.
+	// The unsigned results are rejected if set.
+	Secret string
+	// SignatureTolerance the signed results whose timestamp differs from
+	// the current time by more than the tolerance are rejected as replays
+	SignatureTolerance healthcheck.Duration `yaml:"signature-tolerance"`
+	// MaxHops the results already forwarded through this number of nodes
+	// are rejected
+	MaxHops uint `yaml:"max-hops"`
+}
+
+// UnmarshalYAML parses the ingestion configuration from YAML
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the ingest configuration")
+	}
+	if raw.SignatureTolerance == 0 {
+		raw.SignatureTolerance = DefaultSignatureTolerance
+	}
+	if raw.SignatureTolerance < 0 {
+		return errors.New("The ingest signature tolerance should be positive")
+	}
+	if raw.MaxHops == 0 {
+		raw.MaxHops = DefaultMaxHops
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
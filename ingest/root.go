@@ -0,0 +1,99 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/signature"
+)
+
+// Path the path of the endpoint receiving the forwarded results
+const Path = "/ingest"
+
+// Ingester receives the results forwarded by other Cabourotte nodes, and
+// sends them to the exporters of this node. The node and the source of the
+// results are preserved, this node being added to their relays.
+type Ingester struct {
+	logger     *zap.Logger
+	config     *Configuration
+	node       *healthcheck.Node
+	chanResult chan *healthcheck.Result
+}
+
+// New creates a new ingester. The node identity is required to detect the
+// forwarding loops.
+func New(logger *zap.Logger, config *Configuration, node *healthcheck.Node, chanResult chan *healthcheck.Result) *Ingester {
+	return &Ingester{
+		logger:     logger,
+		config:     config,
+		node:       node,
+		chanResult: chanResult,
+	}
+}
+
+// Signed returns true if the forwarded results should be signed
+func (i *Ingester) Signed() bool {
+	return i.config.Secret != ""
+}
+
+// Verify verifies the signature of the forwarded results, if a secret is
+// configured
+func (i *Ingester) Verify(timestamp string, sig string, payload []byte, now time.Time) error {
+	if i.config.Secret == "" {
+		return nil
+	}
+	return signature.Verify(i.config.Secret, timestamp, sig, payload, now, time.Duration(i.config.SignatureTolerance))
+}
+
+// check returns an error if the result should not be ingested: results
+// without node, already forwarded through this node, or forwarded through
+// too many nodes
+func (i *Ingester) check(result *healthcheck.Result) error {
+	if result.Node == nil || result.Node.Name == "" {
+		return fmt.Errorf("the result of the healthcheck %s has no node", result.ID())
+	}
+	if result.Node.Name == i.node.Name {
+		return fmt.Errorf("the result of the healthcheck %s was executed by this node (forwarding loop)", result.ID())
+	}
+	for _, relay := range result.Relays {
+		if relay == i.node.Name {
+			return fmt.Errorf("the result of the healthcheck %s was already forwarded through this node (forwarding loop)", result.ID())
+		}
+	}
+	if uint(len(result.Relays)) >= i.config.MaxHops {
+		return fmt.Errorf("the result of the healthcheck %s was forwarded through more than %d nodes", result.ID(), i.config.MaxHops)
+	}
+	return nil
+}
+
+// Ingest sends the forwarded results to the exporters, and returns the
+// number of results ingested. The rejected results are logged and
+// skipped.
+func (i *Ingester) Ingest(ctx context.Context, results []healthcheck.Result) (int, error) {
+	ingested := 0
+	for j := range results {
+		result := results[j]
+		if err := i.check(&result); err != nil {
+			i.logger.Warn(fmt.Sprintf("Rejecting a forwarded result: %s", err.Error()),
+				zap.String("node", i.node.Name),
+				zap.Strings("relays", result.Relays))
+			continue
+		}
+		relays := make([]string, 0, len(result.Relays)+1)
+		relays = append(relays, result.Relays...)
+		result.Relays = append(relays, i.node.Name)
+		// the format version of the sender is not kept
+		result.SchemaVersion = 0
+		select {
+		case i.chanResult <- &result:
+			ingested++
+		case <-ctx.Done():
+			return ingested, ctx.Err()
+		}
+	}
+	return ingested, nil
+}
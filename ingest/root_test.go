@@ -0,0 +1,61 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestUnmarshalConfiguration(t *testing.T) {
+	var config Configuration
+	err := yaml.Unmarshal([]byte("secret: foo"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	if config.Secret != "foo" || config.MaxHops != DefaultMaxHops || config.SignatureTolerance != DefaultSignatureTolerance {
+		t.Fatalf("Invalid configuration %+v", config)
+	}
+	err = yaml.Unmarshal([]byte("signature-tolerance: -1m"), &config)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestIngest(t *testing.T) {
+	chanResult := make(chan *healthcheck.Result, 10)
+	ingester := New(zap.NewExample(), &Configuration{MaxHops: 2}, &healthcheck.Node{Name: "regional-1"}, chanResult)
+	results := []healthcheck.Result{
+		{Name: "foo", Success: true, Source: "configuration", Node: &healthcheck.Node{Name: "edge-1"}},
+		{Name: "bar", Success: true, Node: &healthcheck.Node{Name: "edge-2"}, Relays: []string{"site-1"}},
+		// no node
+		{Name: "baz", Success: true},
+		// executed by this node
+		{Name: "baz", Success: true, Node: &healthcheck.Node{Name: "regional-1"}},
+		// already forwarded through this node
+		{Name: "baz", Success: true, Node: &healthcheck.Node{Name: "edge-1"}, Relays: []string{"regional-1", "global-1"}},
+		// too many hops
+		{Name: "baz", Success: true, Node: &healthcheck.Node{Name: "edge-1"}, Relays: []string{"site-1", "site-2"}},
+	}
+	ingested, err := ingester.Ingest(context.Background(), results)
+	if err != nil {
+		t.Fatalf("Fail to ingest the results\n%v", err)
+	}
+	if ingested != 2 || len(chanResult) != 2 {
+		t.Fatalf("Invalid number of results ingested: %d", ingested)
+	}
+	foo := <-chanResult
+	if foo.Name != "foo" || foo.Node.Name != "edge-1" || foo.Source != "configuration" || len(foo.Relays) != 1 || foo.Relays[0] != "regional-1" {
+		t.Fatalf("Invalid result %+v", foo)
+	}
+	bar := <-chanResult
+	if bar.Name != "bar" || len(bar.Relays) != 2 || bar.Relays[0] != "site-1" || bar.Relays[1] != "regional-1" {
+		t.Fatalf("Invalid result %+v", bar)
+	}
+	if len(results[1].Relays) != 1 {
+		t.Fatalf("The ingested results should not be modified")
+	}
+}
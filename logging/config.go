@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// EncodingJSON the JSON log encoding
+	EncodingJSON = "json"
+	// EncodingConsole the human readable log encoding
+	EncodingConsole = "console"
+
+	// OutputStdout writes the logs on stdout
+	OutputStdout = "stdout"
+	// OutputStderr writes the logs on stderr
+	OutputStderr = "stderr"
+	// OutputFile writes the logs to a file
+	OutputFile = "file"
+)
+
+// Components the components whose log level can be configured
+var Components = []string{"http", "healthcheck", "exporter", "discovery", "cluster", "tracing", "resolver", "aggregator"}
+
+// FileConfiguration the log file configuration
+type FileConfiguration struct {
+	Path string
+	// MaxSize the size in megabytes above which the file is rotated
+	MaxSize uint `yaml:"max-size"`
+	// MaxBackups the number of rotated files kept
+	MaxBackups uint `yaml:"max-backups"`
+}
+
+// SamplingConfiguration the sampling of the logs of the healthchecks
+// executions
+type SamplingConfiguration struct {
+	// Interval the debug and error logs with the same message are emitted
+	// at most once per interval for each healthcheck. The sampling is
+	// disabled if the interval is 0.
+	Interval time.Duration
+}
+
+// Configuration the logging configuration
+type Configuration struct {
+	Level    string
+	Encoding string
+	Output   string
+	File     FileConfiguration
+	// Components the log level of each component, overriding the
+	// global level
+	Components map[string]string
+	Sampling   SamplingConfiguration
+}
+
+// parseLevel parses a log level
+func parseLevel(level string) (zapcore.Level, error) {
+	var result zapcore.Level
+	if err := result.UnmarshalText([]byte(level)); err != nil {
+		return result, fmt.Errorf("Invalid log level %s", level)
+	}
+	return result, nil
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the logging configuration")
+	}
+	if raw.Level == "" {
+		raw.Level = "info"
+	}
+	if _, err := parseLevel(raw.Level); err != nil {
+		return err
+	}
+	if raw.Encoding == "" {
+		raw.Encoding = EncodingJSON
+	}
+	if raw.Encoding != EncodingJSON && raw.Encoding != EncodingConsole {
+		return fmt.Errorf("Invalid log encoding %s, valid encodings are %s and %s", raw.Encoding, EncodingJSON, EncodingConsole)
+	}
+	if raw.Output == "" {
+		raw.Output = OutputStdout
+	}
+	switch raw.Output {
+	case OutputStdout, OutputStderr:
+	case OutputFile:
+		if raw.File.Path == "" {
+			return errors.New("The log file path is missing")
+		}
+		if raw.File.MaxSize == 0 {
+			raw.File.MaxSize = 100
+		}
+	default:
+		return fmt.Errorf("Invalid log output %s, valid outputs are %s, %s and %s", raw.Output, OutputStdout, OutputStderr, OutputFile)
+	}
+	for component, level := range raw.Components {
+		if !validComponent(component) {
+			return fmt.Errorf("Invalid log component %s, valid components are %s", component, strings.Join(Components, ", "))
+		}
+		if _, err := parseLevel(level); err != nil {
+			return errors.Wrapf(err, "Invalid log level for the component %s", component)
+		}
+	}
+	if raw.Sampling.Interval < 0 {
+		return errors.New("The log sampling interval should be positive")
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
+
+// validComponent returns true if the log level of the component can be
+// configured
+func validComponent(component string) bool {
+	for _, c := range Components {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelCore filters the logs depending on the level of the component
+// which emitted them, the component being the name of the logger
+type levelCore struct {
+	zapcore.Core
+	level      zapcore.Level
+	components map[string]zapcore.Level
+}
+
+// enabled returns true if the entry should be logged
+func (c *levelCore) enabled(entry zapcore.Entry) bool {
+	level := c.level
+	component := strings.SplitN(entry.LoggerName, ".", 2)[0]
+	if componentLevel, ok := c.components[component]; ok {
+		level = componentLevel
+	}
+	return level.Enabled(entry.Level)
+}
+
+// With adds fields to the core
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{
+		Core:       c.Core.With(fields),
+		level:      c.level,
+		components: c.components,
+	}
+}
+
+// Check checks if the entry should be logged
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabled(entry) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
+
+// newCore creates the logging core writing to the given writer
+func newCore(config *Configuration, debug bool, writer zapcore.WriteSyncer) (zapcore.Core, error) {
+	level, err := parseLevel(config.Level)
+	if err != nil {
+		return nil, err
+	}
+	if debug {
+		level = zapcore.DebugLevel
+	}
+	// the underlying core accepts the lowest configured level, the
+	// filtering is done by the level core
+	minLevel := level
+	components := make(map[string]zapcore.Level)
+	for component, componentLevel := range config.Components {
+		l, err := parseLevel(componentLevel)
+		if err != nil {
+			return nil, err
+		}
+		components[component] = l
+		if l < minLevel {
+			minLevel = l
+		}
+	}
+	var encoder zapcore.Encoder
+	if config.Encoding == EncodingConsole {
+		encoderConfig := zap.NewDevelopmentEncoderConfig()
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	} else {
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
+	var core zapcore.Core = &levelCore{
+		Core:       zapcore.NewCore(encoder, writer, minLevel),
+		level:      level,
+		components: components,
+	}
+	if config.Sampling.Interval > 0 {
+		core = &samplingCore{
+			Core:    core,
+			sampler: newSampler(config.Sampling.Interval),
+		}
+	}
+	return core, nil
+}
+
+// New creates a logger from the logging configuration. All the logs are
+// emitted at the debug level if debug is true, the components levels
+// being still applied. The returned function closes the log file.
+func New(config *Configuration, debug bool) (*zap.Logger, func() error, error) {
+	if config.Level == "" {
+		config.Level = "info"
+	}
+	var writer zapcore.WriteSyncer
+	closeFn := func() error { return nil }
+	switch config.Output {
+	case OutputStderr:
+		writer = zapcore.Lock(os.Stderr)
+	case OutputFile:
+		file, err := newRotatingFile(config.File.Path, int64(config.File.MaxSize)*1024*1024, config.File.MaxBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+		writer = file
+		closeFn = file.Close
+	default:
+		writer = zapcore.Lock(os.Stdout)
+	}
+	core, err := newCore(config, debug, writer)
+	if err != nil {
+		closeFn()
+		return nil, nil, err
+	}
+	return zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)), closeFn, nil
+}
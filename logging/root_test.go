@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfiguration(t *testing.T) {
+	cases := []struct {
+		in    string
+		valid bool
+	}{
+		{in: "level: debug", valid: true},
+		{in: "encoding: console\noutput: stderr", valid: true},
+		{in: "output: file\nfile:\n  path: /tmp/cabourotte.log", valid: true},
+		{in: "components:\n  http: debug\n  exporter: error", valid: true},
+		{in: "sampling:\n  interval: 1m", valid: true},
+		{in: "level: verbose", valid: false},
+		{in: "encoding: xml", valid: false},
+		{in: "output: syslog", valid: false},
+		{in: "output: file", valid: false},
+		{in: "components:\n  unknown: debug", valid: false},
+		{in: "components:\n  http: verbose", valid: false},
+		{in: "sampling:\n  interval: -1m", valid: false},
+	}
+	for _, c := range cases {
+		var config Configuration
+		err := yaml.Unmarshal([]byte(c.in), &config)
+		if c.valid && err != nil {
+			t.Fatalf("The configuration %s should be valid: %s", c.in, err.Error())
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("The configuration %s should be invalid", c.in)
+		}
+	}
+}
+
+func TestComponentsLevels(t *testing.T) {
+	var buffer bytes.Buffer
+	config := Configuration{
+		Level:      "info",
+		Encoding:   EncodingJSON,
+		Components: map[string]string{"http": "debug", "exporter": "error"},
+	}
+	core, err := newCore(&config, false, zapcore.AddSync(&buffer))
+	if err != nil {
+		t.Fatalf("Fail to create the logging core: %s", err.Error())
+	}
+	logger := zap.New(core)
+	logger.Debug("root debug")
+	logger.Info("root info")
+	logger.Named("http").Debug("http debug")
+	logger.Named("http").With(zap.String("foo", "bar")).Named("server").Debug("http server debug")
+	logger.Named("exporter").Warn("exporter warn")
+	logger.Named("exporter").Error("exporter error")
+	logger.Named("healthcheck").Debug("healthcheck debug")
+	output := buffer.String()
+	for _, expected := range []string{"root info", "http debug", "http server debug", "exporter error"} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("The log %s is missing: %s", expected, output)
+		}
+	}
+	for _, unexpected := range []string{"root debug", "exporter warn", "healthcheck debug"} {
+		if strings.Contains(output, unexpected) {
+			t.Fatalf("The log %s should be filtered: %s", unexpected, output)
+		}
+	}
+}
+
+func TestSampling(t *testing.T) {
+	var buffer bytes.Buffer
+	config := Configuration{
+		Level:    "debug",
+		Encoding: EncodingJSON,
+		Sampling: SamplingConfiguration{Interval: time.Hour},
+	}
+	core, err := newCore(&config, false, zapcore.AddSync(&buffer))
+	if err != nil {
+		t.Fatalf("Fail to create the logging core: %s", err.Error())
+	}
+	logger := zap.New(core)
+	for i := 0; i < 3; i++ {
+		logger.Error("healthcheck failed", zap.String("name", "foo"))
+		logger.Error("healthcheck failed", zap.String("name", "bar"))
+		logger.Info("Adding healthcheck", zap.String("name", "foo"))
+		logger.Error("not an healthcheck log")
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	count := func(s string) int {
+		result := 0
+		for _, line := range lines {
+			if strings.Contains(line, s) {
+				result++
+			}
+		}
+		return result
+	}
+	if count(`"name":"foo"`) != 4 || count(`"name":"bar"`) != 1 || count("not an healthcheck log") != 3 {
+		t.Fatalf("Invalid sampling: %s", buffer.String())
+	}
+
+	s := newSampler(time.Minute)
+	now := time.Now()
+	s.allow("key", now)
+	s.allow("key", now.Add(time.Second))
+	s.allow("key", now.Add(2*time.Second))
+	allowed, suppressed := s.allow("key", now.Add(2*time.Minute))
+	if !allowed || suppressed != 2 {
+		t.Fatalf("Invalid sampler result: %t %d", allowed, suppressed)
+	}
+}
@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// rotatingFile a log file rotated when its size exceeds the maximum size.
+// The rotated files are suffixed by their index, .1 being the most recent.
+type rotatingFile struct {
+	lock       sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups uint
+	file       *os.File
+	size       int64
+}
+
+// newRotatingFile opens a rotating log file
+func newRotatingFile(path string, maxSize int64, maxBackups uint) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the log file in append mode
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to open the log file %s", r.path)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return errors.Wrapf(err, "Fail to read the log file %s", r.path)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// rotate renames the current file and the backups, and opens a new file
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return errors.Wrapf(err, "Fail to close the log file %s", r.path)
+	}
+	if r.maxBackups == 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "Fail to remove the log file %s", r.path)
+		}
+		return r.open()
+	}
+	for i := r.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "Fail to rotate the log file %s", r.path)
+		}
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		return errors.Wrapf(err, "Fail to rotate the log file %s", r.path)
+	}
+	return r.open()
+}
+
+// Write writes to the log file, rotating it if needed
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Sync flushes the log file
+func (r *rotatingFile) Sync() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.file.Sync()
+}
+
+// Close closes the log file
+func (r *rotatingFile) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.file.Close()
+}
@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cabourotte.log")
+	file, err := newRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("Fail to open the log file: %s", err.Error())
+	}
+	defer file.Close()
+	for _, line := range []string{"line-1\n", "line-2\n", "line-3\n", "line-4\n"} {
+		_, err := file.Write([]byte(line))
+		if err != nil {
+			t.Fatalf("Fail to write the log file: %s", err.Error())
+		}
+	}
+	expected := map[string]string{
+		path:        "line-4\n",
+		path + ".1": "line-3\n",
+		path + ".2": "line-2\n",
+	}
+	for p, content := range expected {
+		result, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatalf("Fail to read %s: %s", p, err.Error())
+		}
+		if string(result) != content {
+			t.Fatalf("Invalid content for %s: %s", p, string(result))
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("Only two backups should be kept")
+	}
+}
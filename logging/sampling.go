@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// samplerEntry the state of a sampled log
+type samplerEntry struct {
+	last       time.Time
+	suppressed uint64
+}
+
+// sampler emits at most one log per key during the interval
+type sampler struct {
+	interval  time.Duration
+	lock      sync.Mutex
+	entries   map[string]*samplerEntry
+	lastPurge time.Time
+}
+
+// newSampler creates a new sampler
+func newSampler(interval time.Duration) *sampler {
+	return &sampler{
+		interval: interval,
+		entries:  make(map[string]*samplerEntry),
+	}
+}
+
+// allow returns true if the log should be emitted, and the number of logs
+// suppressed since the previous one
+func (s *sampler) allow(key string, now time.Time) (bool, uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.purge(now)
+	entry, ok := s.entries[key]
+	if !ok {
+		s.entries[key] = &samplerEntry{last: now}
+		return true, 0
+	}
+	if now.Sub(entry.last) < s.interval {
+		entry.suppressed++
+		return false, 0
+	}
+	suppressed := entry.suppressed
+	entry.last = now
+	entry.suppressed = 0
+	return true, suppressed
+}
+
+// purge removes the entries which did not suppress any log during the
+// last interval, in order to not keep the removed healthchecks
+func (s *sampler) purge(now time.Time) {
+	if now.Sub(s.lastPurge) < s.interval {
+		return
+	}
+	for key, entry := range s.entries {
+		if entry.suppressed == 0 && now.Sub(entry.last) >= s.interval {
+			delete(s.entries, key)
+		}
+	}
+	s.lastPurge = now
+}
+
+// samplingCore samples the debug and error logs of the healthcheck
+// executions: the logs with the same message for the same healthcheck
+// (the name field) are emitted at most once per interval, with the
+// number of logs suppressed in the meantime.
+type samplingCore struct {
+	zapcore.Core
+	sampler *sampler
+}
+
+// With adds fields to the core
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{
+		Core:    c.Core.With(fields),
+		sampler: c.sampler,
+	}
+}
+
+// Check checks if the entry should be logged
+func (c *samplingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level != zapcore.DebugLevel && entry.Level != zapcore.ErrorLevel {
+		return c.Core.Check(entry, checked)
+	}
+	if c.Core.Check(entry, nil) == nil {
+		return checked
+	}
+	return checked.AddCore(entry, c)
+}
+
+// Write samples the entries containing an healthcheck name
+func (c *samplingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	key := ""
+	for _, field := range fields {
+		if (field.Key == "name" || field.Key == "namespace") && field.Type == zapcore.StringType {
+			key += "/" + field.String
+		}
+	}
+	if key == "" {
+		return c.Core.Write(entry, fields)
+	}
+	allowed, suppressed := c.sampler.allow(entry.Level.String()+entry.Message+key, entry.Time)
+	if !allowed {
+		return nil
+	}
+	if suppressed != 0 {
+		fields = append(fields[:len(fields):len(fields)], zap.Uint64("suppressed", suppressed))
+	}
+	return c.Core.Write(entry, fields)
+}
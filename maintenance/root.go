@@ -0,0 +1,180 @@
+package maintenance
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+const (
+	// SourceConfig the window is managed by the configuration file
+	SourceConfig string = ""
+	// SourceAPI the window is managed by the API
+	SourceAPI string = "api"
+)
+
+// Window is a maintenance window. During a maintenance window, the
+// healthchecks matching the window still run but their failures are
+// silenced and not sent to the exporters.
+// A window matches an healthcheck if the healthcheck name is in the
+// Healthchecks list, or if all labels from the Selector match the
+// healthcheck labels. The namespaced healthchecks are referenced as
+// namespace/name.
+type Window struct {
+	Name         string               `json:"name"`
+	Description  string               `json:"description,omitempty"`
+	Start        time.Time            `json:"start"`
+	Duration     healthcheck.Duration `json:"duration"`
+	Selector     map[string]string    `json:"selector,omitempty"`
+	Healthchecks []string             `json:"healthchecks,omitempty"`
+	Source       string               `json:"source"`
+}
+
+// Validate validates the maintenance window
+func (w *Window) Validate() error {
+	if w.Name == "" {
+		return errors.New("The maintenance window name is missing")
+	}
+	if w.Start.IsZero() {
+		return errors.New("The maintenance window start is missing")
+	}
+	if w.Duration <= 0 {
+		return errors.New("The maintenance window duration should be positive")
+	}
+	if len(w.Selector) == 0 && len(w.Healthchecks) == 0 {
+		return errors.New("The maintenance window should have a selector or a list of healthchecks")
+	}
+	return nil
+}
+
+// End returns the end of the maintenance window
+func (w *Window) End() time.Time {
+	return w.Start.Add(time.Duration(w.Duration))
+}
+
+// Active returns true if the window is active at the given time
+func (w *Window) Active(now time.Time) bool {
+	return !now.Before(w.Start) && now.Before(w.End())
+}
+
+// Matches returns true if the window matches the healthcheck
+func (w *Window) Matches(name string, labels map[string]string) bool {
+	for _, n := range w.Healthchecks {
+		if n == name {
+			return true
+		}
+	}
+	if len(w.Selector) == 0 {
+		return false
+	}
+	for k, v := range w.Selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Component manages the maintenance windows
+type Component struct {
+	Logger  *zap.Logger
+	windows map[string]*Window
+	lock    sync.RWMutex
+}
+
+// New creates a new maintenance component
+func New(logger *zap.Logger) *Component {
+	return &Component{
+		Logger:  logger,
+		windows: make(map[string]*Window),
+	}
+}
+
+// purge removes the expired windows.
+// The function is *not* thread-safe.
+func (c *Component) purge(now time.Time) {
+	for name, window := range c.windows {
+		if !now.Before(window.End()) {
+			c.Logger.Info(fmt.Sprintf("Maintenance window %s expired", name))
+			delete(c.windows, name)
+		}
+	}
+}
+
+// Add adds or replaces a maintenance window
+func (c *Component) Add(window *Window) error {
+	err := window.Validate()
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.Logger.Info(fmt.Sprintf("Adding maintenance window %s", window.Name))
+	c.windows[window.Name] = window
+	return nil
+}
+
+// Remove removes a maintenance window
+func (c *Component) Remove(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.Logger.Info(fmt.Sprintf("Removing maintenance window %s", name))
+	delete(c.windows, name)
+}
+
+// List returns the maintenance windows, sorted by name
+func (c *Component) List() []Window {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.purge(time.Now())
+	result := make([]Window, 0, len(c.windows))
+	for _, window := range c.windows {
+		result = append(result, *window)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Silenced returns true if an active maintenance window matches the healthcheck
+func (c *Component) Silenced(name string, labels map[string]string, now time.Time) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for _, window := range c.windows {
+		if window.Active(now) && window.Matches(name, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReloadConfiguration replaces the maintenance windows managed by the
+// configuration file
+func (c *Component) ReloadConfiguration(windows []Window) error {
+	for i := range windows {
+		err := windows[i].Validate()
+		if err != nil {
+			return errors.Wrapf(err, "Invalid maintenance window %s", windows[i].Name)
+		}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for name, window := range c.windows {
+		if window.Source == SourceConfig {
+			delete(c.windows, name)
+		}
+	}
+	for i := range windows {
+		window := windows[i]
+		window.Source = SourceConfig
+		c.windows[window.Name] = &window
+	}
+	return nil
+}
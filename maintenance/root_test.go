@@ -0,0 +1,66 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestSilenced(t *testing.T) {
+	component := New(zap.NewExample())
+	now := time.Now()
+	err := component.Add(&Window{
+		Name:     "foo",
+		Start:    now.Add(-time.Minute),
+		Duration: healthcheck.Duration(time.Hour),
+		Selector: map[string]string{"env": "prod"},
+		Source:   SourceAPI,
+	})
+	if err != nil {
+		t.Fatalf("Fail to add the maintenance window\n%v", err)
+	}
+	err = component.ReloadConfiguration([]Window{
+		{
+			Name:         "bar",
+			Start:        now.Add(time.Hour),
+			Duration:     healthcheck.Duration(time.Hour),
+			Healthchecks: []string{"check"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Fail to reload the maintenance windows\n%v", err)
+	}
+	if len(component.List()) != 2 {
+		t.Fatalf("Invalid number of maintenance windows")
+	}
+	if !component.Silenced("a", map[string]string{"env": "prod", "foo": "bar"}, now) {
+		t.Fatalf("The healthcheck should be silenced")
+	}
+	if component.Silenced("a", map[string]string{"env": "staging"}, now) {
+		t.Fatalf("The healthcheck should not be silenced")
+	}
+	if component.Silenced("check", nil, now) {
+		t.Fatalf("The window is not active yet")
+	}
+	if !component.Silenced("check", nil, now.Add(90*time.Minute)) {
+		t.Fatalf("The healthcheck should be silenced")
+	}
+	err = component.ReloadConfiguration([]Window{})
+	if err != nil {
+		t.Fatalf("Fail to reload the maintenance windows\n%v", err)
+	}
+	if len(component.List()) != 1 {
+		t.Fatalf("The configuration window should be removed")
+	}
+	component.Remove("foo")
+	if len(component.List()) != 0 {
+		t.Fatalf("The window should be removed")
+	}
+	err = component.Add(&Window{Name: "invalid"})
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
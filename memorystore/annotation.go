@@ -0,0 +1,213 @@
+package memorystore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// annotationsFile the file persisting the annotations in the results
+// persistence directory
+const annotationsFile = "annotations.json"
+
+// Annotation documents a known incident of an healthcheck or of a group
+// of healthchecks. The healthchecks and groups are referenced by their
+// identifiers (namespace/name for the namespaced ones). An annotation
+// without end is in progress.
+type Annotation struct {
+	Name        string     `json:"name"`
+	Healthcheck string     `json:"healthcheck,omitempty"`
+	Group       string     `json:"group,omitempty"`
+	Text        string     `json:"text"`
+	URL         string     `json:"url,omitempty"`
+	Start       time.Time  `json:"start"`
+	End         *time.Time `json:"end,omitempty"`
+}
+
+// Validate validates the annotation
+func (a *Annotation) Validate() error {
+	if a.Name == "" {
+		return errors.New("The annotation name is missing")
+	}
+	if a.Text == "" {
+		return errors.New("The annotation text is missing")
+	}
+	if (a.Healthcheck == "") == (a.Group == "") {
+		return errors.New("The annotation should reference either an healthcheck or a group")
+	}
+	if a.Start.IsZero() {
+		return errors.New("The annotation start is missing")
+	}
+	if a.End != nil && !a.End.After(a.Start) {
+		return errors.New("The annotation end should be after its start")
+	}
+	return nil
+}
+
+// Overlaps returns true if the annotation overlaps the time range
+func (a *Annotation) Overlaps(from time.Time, to time.Time) bool {
+	if !a.Start.Before(to) {
+		return false
+	}
+	return a.End == nil || a.End.After(from)
+}
+
+// Matches returns true if the annotation references the healthcheck of
+// the result, or its group
+func (a *Annotation) Matches(result *healthcheck.Result) bool {
+	if a.Healthcheck != "" {
+		return a.Healthcheck == result.ID()
+	}
+	return result.Group != "" && a.Group == healthcheck.ID(result.Namespace, result.Group)
+}
+
+// annotationsFor returns the names of the annotations of the result
+// active at its timestamp.
+// The function is *not* thread-safe.
+func (m *MemoryStore) annotationsFor(result *healthcheck.Result) []string {
+	timestamp := time.Unix(result.HealthcheckTimestamp, 0)
+	var names []string
+	for _, annotation := range m.annotations {
+		if annotation.Matches(result) && annotation.Overlaps(timestamp, timestamp.Add(time.Second)) {
+			names = append(names, annotation.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AddAnnotation adds or replaces an annotation
+func (m *MemoryStore) AddAnnotation(annotation *Annotation) error {
+	err := annotation.Validate()
+	if err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.Logger.Info(fmt.Sprintf("Adding annotation %s", annotation.Name))
+	m.annotations[annotation.Name] = annotation
+	return m.persistAnnotations()
+}
+
+// RemoveAnnotation removes an annotation
+func (m *MemoryStore) RemoveAnnotation(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.annotations[name]; !ok {
+		return fmt.Errorf("Annotation %s not found", name)
+	}
+	m.Logger.Info(fmt.Sprintf("Removing annotation %s", name))
+	delete(m.annotations, name)
+	return m.persistAnnotations()
+}
+
+// ListAnnotations returns the annotations for which the filter returns
+// true, sorted by start
+func (m *MemoryStore) ListAnnotations(filter func(Annotation) bool) []Annotation {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	result := []Annotation{}
+	for _, annotation := range m.annotations {
+		if filter == nil || filter(*annotation) {
+			result = append(result, *annotation)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Start.Equal(result[j].Start) {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result
+}
+
+// GetAnnotation returns an annotation
+func (m *MemoryStore) GetAnnotation(name string) (Annotation, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	annotation, ok := m.annotations[name]
+	if !ok {
+		return Annotation{}, fmt.Errorf("Annotation %s not found", name)
+	}
+	return *annotation, nil
+}
+
+// rangeAnnotations returns the annotations of the healthchecks and groups
+// overlapping the time range
+func (m *MemoryStore) rangeAnnotations(checks map[string]bool, groups map[string]bool, from time.Time, to time.Time) []Annotation {
+	return m.ListAnnotations(func(a Annotation) bool {
+		if !a.Overlaps(from, to) {
+			return false
+		}
+		return (a.Healthcheck != "" && checks[a.Healthcheck]) || (a.Group != "" && groups[a.Group])
+	})
+}
+
+// persistAnnotations writes the annotations in the results persistence
+// directory if the persistence is enabled.
+// The function is *not* thread-safe.
+func (m *MemoryStore) persistAnnotations() error {
+	if m.persistence == nil {
+		return nil
+	}
+	annotations := make([]*Annotation, 0, len(m.annotations))
+	for _, annotation := range m.annotations {
+		annotations = append(annotations, annotation)
+	}
+	content, err := json.Marshal(annotations)
+	if err != nil {
+		return errors.Wrap(err, "Fail to serialize the annotations")
+	}
+	// the file is replaced atomically
+	path := filepath.Join(m.persistence.directory, annotationsFile)
+	tmpFile, err := os.CreateTemp(m.persistence.directory, ".cabourotte-annotations-*")
+	if err != nil {
+		return errors.Wrap(err, "Fail to create the annotations file")
+	}
+	_, err = tmpFile.Write(content)
+	if err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return errors.Wrap(err, "Fail to write the annotations file")
+	}
+	err = tmpFile.Close()
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return errors.Wrap(err, "Fail to write the annotations file")
+	}
+	err = os.Rename(tmpFile.Name(), path)
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return errors.Wrap(err, "Fail to write the annotations file")
+	}
+	return nil
+}
+
+// loadAnnotations reads the annotations persisted in the directory
+func loadAnnotations(directory string) (map[string]*Annotation, error) {
+	result := make(map[string]*Annotation)
+	path := filepath.Join(directory, annotationsFile)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return nil, errors.Wrapf(err, "Fail to read the annotations file %s", path)
+	}
+	var annotations []*Annotation
+	err = json.Unmarshal(content, &annotations)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the annotations file %s", path)
+	}
+	for _, annotation := range annotations {
+		result[annotation.Name] = annotation
+	}
+	return result, nil
+}
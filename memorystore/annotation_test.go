@@ -0,0 +1,115 @@
+package memorystore
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestAnnotationValidate(t *testing.T) {
+	now := time.Now()
+	before := now.Add(-time.Hour)
+	cases := []struct {
+		annotation Annotation
+		valid      bool
+	}{
+		{Annotation{Name: "a", Text: "incident", Healthcheck: "foo", Start: now}, true},
+		{Annotation{Name: "a", Text: "incident", Group: "web", Start: now}, true},
+		{Annotation{Text: "incident", Healthcheck: "foo", Start: now}, false},
+		{Annotation{Name: "a", Healthcheck: "foo", Start: now}, false},
+		{Annotation{Name: "a", Text: "incident", Start: now}, false},
+		{Annotation{Name: "a", Text: "incident", Healthcheck: "foo", Group: "web", Start: now}, false},
+		{Annotation{Name: "a", Text: "incident", Healthcheck: "foo"}, false},
+		{Annotation{Name: "a", Text: "incident", Healthcheck: "foo", Start: now, End: &before}, false},
+	}
+	for i, c := range cases {
+		err := c.annotation.Validate()
+		if (err == nil) != c.valid {
+			t.Fatalf("Invalid validation for the case %d: %v", i, err)
+		}
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	dir := t.TempDir()
+	store := NewMemoryStore(zap.NewExample(), 10)
+	err := store.EnablePersistence(&PersistenceConfiguration{
+		Directory: dir,
+		Retention: DefaultRetention,
+	})
+	if err != nil {
+		t.Fatalf("Fail to enable the persistence: %v", err)
+	}
+	store.Start()
+	now := time.Now()
+	end := now.Add(-30 * time.Minute)
+	annotations := []*Annotation{
+		{Name: "outage", Healthcheck: "foo", Text: "database outage", URL: "https://tickets/1", Start: now.Add(-time.Hour), End: &end},
+		{Name: "network", Group: "web", Text: "network issue", Start: now.Add(-10 * time.Minute)},
+	}
+	for _, annotation := range annotations {
+		err := store.AddAnnotation(annotation)
+		if err != nil {
+			t.Fatalf("Fail to add the annotation: %v", err)
+		}
+	}
+	result := &healthcheck.Result{Name: "foo", Group: "web", HealthcheckTimestamp: now.Add(-45 * time.Minute).Unix()}
+	store.Add(result)
+	if len(result.Annotations) != 1 || result.Annotations[0] != "outage" {
+		t.Fatalf("Invalid annotations %v", result.Annotations)
+	}
+	result = &healthcheck.Result{Name: "foo", Group: "web", Success: true, HealthcheckTimestamp: now.Unix()}
+	store.Add(result)
+	if len(result.Annotations) != 1 || result.Annotations[0] != "network" {
+		t.Fatalf("Invalid annotations %v", result.Annotations)
+	}
+	sla, err := store.GetSLA("foo", 2*time.Hour)
+	if err != nil {
+		t.Fatalf("Fail to get the SLA: %v", err)
+	}
+	if len(sla.Annotations) != 2 || sla.Annotations[0].Name != "outage" {
+		t.Fatalf("Invalid SLA annotations %+v", sla.Annotations)
+	}
+	sla, err = store.GetSLA("foo", 20*time.Minute)
+	if err != nil {
+		t.Fatalf("Fail to get the SLA: %v", err)
+	}
+	if len(sla.Annotations) != 1 || sla.Annotations[0].Name != "network" {
+		t.Fatalf("Invalid SLA annotations %+v", sla.Annotations)
+	}
+	err = store.RemoveAnnotation("network")
+	if err != nil {
+		t.Fatalf("Fail to remove the annotation: %v", err)
+	}
+	err = store.RemoveAnnotation("network")
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	err = store.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the store: %v", err)
+	}
+
+	// the annotations survive a restart
+	store = NewMemoryStore(zap.NewExample(), 10)
+	err = store.EnablePersistence(&PersistenceConfiguration{
+		Directory: dir,
+		Retention: DefaultRetention,
+	})
+	if err != nil {
+		t.Fatalf("Fail to enable the persistence: %v", err)
+	}
+	annotation, err := store.GetAnnotation("outage")
+	if err != nil {
+		t.Fatalf("Fail to get the annotation: %v", err)
+	}
+	if annotation.URL != "https://tickets/1" || annotation.End == nil || !annotation.End.Equal(end) {
+		t.Fatalf("Invalid annotation %+v", annotation)
+	}
+	if len(store.ListAnnotations(nil)) != 1 {
+		t.Fatalf("Invalid number of annotations")
+	}
+}
@@ -0,0 +1,79 @@
+package memorystore
+
+import (
+	"fmt"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// MaxFailures the maximum number of failures returned for an healthcheck
+const MaxFailures = 1000
+
+// Failure a failed execution of an healthcheck
+type Failure struct {
+	Timestamp     int64  `json:"timestamp"`
+	Duration      int64  `json:"duration"`
+	ErrorCategory string `json:"error-category,omitempty"`
+	Message       string `json:"message"`
+	ProbeID       string `json:"probe-id,omitempty"`
+	TraceID       string `json:"trace-id,omitempty"`
+}
+
+// failed returns true if the result is a failure
+func failed(result healthcheck.Result) bool {
+	return !result.Success
+}
+
+// filterResults returns the results for which keep returns true
+func filterResults(results []healthcheck.Result, keep func(healthcheck.Result) bool) []healthcheck.Result {
+	filtered := []healthcheck.Result{}
+	for _, result := range results {
+		if keep(result) {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// GetFailures returns the n most recent failures of an healthcheck, from
+// the most recent to the oldest one. They are read from the persisted
+// results if the persistence is enabled, from the history otherwise.
+func (m *MemoryStore) GetFailures(id string, n int) ([]Failure, error) {
+	m.lock.RLock()
+	var history []healthcheck.Result
+	h, found := m.History[id]
+	if found {
+		history = h.list()
+	}
+	m.lock.RUnlock()
+	results := filterResults(history, failed)
+	if m.persistence != nil {
+		persisted, err := m.persistence.last(id, n, failed)
+		if err != nil {
+			return nil, err
+		}
+		if len(persisted) != 0 {
+			found = true
+			results = persisted
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("Result not found for healthcheck %s", id)
+	}
+	if len(results) > n {
+		results = results[len(results)-n:]
+	}
+	failures := make([]Failure, 0, len(results))
+	for i := len(results) - 1; i >= 0; i-- {
+		result := results[i]
+		failures = append(failures, Failure{
+			Timestamp:     result.HealthcheckTimestamp,
+			Duration:      result.Duration,
+			ErrorCategory: result.ErrorCategory,
+			Message:       result.Message,
+			ProbeID:       result.ProbeID,
+			TraceID:       result.TraceID,
+		})
+	}
+	return failures, nil
+}
@@ -0,0 +1,108 @@
+package memorystore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+const (
+	// GroupStateDegraded some healthchecks of the group are unhealthy
+	GroupStateDegraded = "degraded"
+	// SourceGroup the source of the group results
+	SourceGroup = "group"
+)
+
+// Group the rolled-up state of the healthchecks of a group.
+// The group is healthy if all its healthchecks are healthy, unhealthy if
+// all of them are unhealthy, and degraded otherwise. The healthchecks
+// whose state is unknown are ignored, and the silenced healthchecks are
+// considered healthy.
+type Group struct {
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace,omitempty"`
+	State     string   `json:"state"`
+	Healthy   []string `json:"healthy"`
+	Unhealthy []string `json:"unhealthy"`
+	Unknown   []string `json:"unknown"`
+}
+
+// ID returns the group identifier
+func (g *Group) ID() string {
+	return healthcheck.ID(g.Namespace, g.Name)
+}
+
+// Result builds the synthetic result of the group
+func (g *Group) Result() *healthcheck.Result {
+	severity := healthcheck.SeverityCritical
+	if g.State == GroupStateDegraded {
+		severity = healthcheck.SeverityWarning
+	}
+	message := fmt.Sprintf("%d/%d healthchecks healthy", len(g.Healthy), len(g.Healthy)+len(g.Unhealthy))
+	if len(g.Unhealthy) != 0 {
+		message = fmt.Sprintf("%s, unhealthy: %s", message, strings.Join(g.Unhealthy, ", "))
+	}
+	return &healthcheck.Result{
+		Name:                 g.Name,
+		Namespace:            g.Namespace,
+		Group:                g.Name,
+		Summary:              fmt.Sprintf("group %s", g.Name),
+		Labels:               map[string]string{"group": g.Name},
+		Success:              g.State == healthcheck.StateHealthy,
+		State:                g.State,
+		HealthcheckTimestamp: time.Now().Unix(),
+		Message:              message,
+		Source:               SourceGroup,
+		Severity:             severity,
+	}
+}
+
+// Group returns the state of a group, computed from the latest results of
+// its healthchecks using the index of the groups
+func (m *MemoryStore) Group(namespace string, name string) (Group, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	group := Group{
+		Name:      name,
+		Namespace: namespace,
+		Healthy:   []string{},
+		Unhealthy: []string{},
+		Unknown:   []string{},
+	}
+	found := false
+	for id := range m.groups[healthcheck.ID(namespace, name)] {
+		result := m.Results[id]
+		if result.Shadow {
+			continue
+		}
+		found = true
+		switch {
+		case result.Silenced || result.Healthy():
+			group.Healthy = append(group.Healthy, result.Name)
+		case result.State == healthcheck.StateUnknown:
+			group.Unknown = append(group.Unknown, result.Name)
+		default:
+			group.Unhealthy = append(group.Unhealthy, result.Name)
+		}
+	}
+	if !found {
+		return Group{}, fmt.Errorf("No result found for the group %s", healthcheck.ID(namespace, name))
+	}
+	sort.Strings(group.Healthy)
+	sort.Strings(group.Unhealthy)
+	sort.Strings(group.Unknown)
+	switch {
+	case len(group.Healthy) == 0 && len(group.Unhealthy) == 0:
+		group.State = healthcheck.StateUnknown
+	case len(group.Unhealthy) == 0:
+		group.State = healthcheck.StateHealthy
+	case len(group.Healthy) == 0:
+		group.State = healthcheck.StateUnhealthy
+	default:
+		group.State = GroupStateDegraded
+	}
+	return group, nil
+}
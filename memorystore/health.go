@@ -0,0 +1,95 @@
+package memorystore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// healthCache caches the states of the groups, the health endpoints being
+// polled by the load balancers
+type healthCache struct {
+	lock      sync.Mutex
+	staleness time.Duration
+	groups    map[string]cachedGroup
+}
+
+// cachedGroup the state of a group and its computation time
+type cachedGroup struct {
+	group    Group
+	err      error
+	computed time.Time
+}
+
+// newHealthCache creates a new cache, disabled until a staleness is set
+func newHealthCache() *healthCache {
+	return &healthCache{
+		groups: make(map[string]cachedGroup),
+	}
+}
+
+// index adds a result to the index of its group. It should be called
+// with the lock held.
+func (m *MemoryStore) index(id string, result *healthcheck.Result) {
+	if result.Group == "" {
+		return
+	}
+	groupID := healthcheck.ID(result.Namespace, result.Group)
+	members, ok := m.groups[groupID]
+	if !ok {
+		members = make(map[string]bool)
+		m.groups[groupID] = members
+	}
+	members[id] = true
+}
+
+// unindex removes a result from the index of its group. It should be
+// called with the lock held.
+func (m *MemoryStore) unindex(id string, result *healthcheck.Result) {
+	if result.Group == "" {
+		return
+	}
+	groupID := healthcheck.ID(result.Namespace, result.Group)
+	members, ok := m.groups[groupID]
+	if !ok {
+		return
+	}
+	delete(members, id)
+	if len(members) == 0 {
+		delete(m.groups, groupID)
+	}
+}
+
+// SetHealthStaleness sets the maximum age of the groups states returned
+// by CachedGroup, the states being computed on each call if 0
+func (m *MemoryStore) SetHealthStaleness(staleness time.Duration) {
+	m.health.lock.Lock()
+	defer m.health.lock.Unlock()
+	m.health.staleness = staleness
+	m.health.groups = make(map[string]cachedGroup)
+}
+
+// CachedGroup returns the state of a group, computed at most the health
+// staleness ago
+func (m *MemoryStore) CachedGroup(namespace string, name string) (Group, error) {
+	m.health.lock.Lock()
+	defer m.health.lock.Unlock()
+	if m.health.staleness == 0 {
+		return m.Group(namespace, name)
+	}
+	id := healthcheck.ID(namespace, name)
+	now := time.Now()
+	if cached, ok := m.health.groups[id]; ok && now.Sub(cached.computed) < m.health.staleness {
+		return cached.group, cached.err
+	}
+	group, err := m.Group(namespace, name)
+	m.health.groups[id] = cachedGroup{group: group, err: err, computed: now}
+	// the states of the removed groups expire with the staleness
+	for key, cached := range m.health.groups {
+		if now.Sub(cached.computed) >= m.health.staleness {
+			delete(m.health.groups, key)
+		}
+	}
+	return group, err
+}
@@ -0,0 +1,71 @@
+package memorystore
+
+import (
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// history is a ring buffer containing the latest results of an healthcheck
+type history struct {
+	results []*healthcheck.Result
+	next    int
+	full    bool
+}
+
+func newHistory(size uint) *history {
+	return &history{
+		results: make([]*healthcheck.Result, size),
+	}
+}
+
+// add adds a result to the buffer, replacing the oldest one if the
+// buffer is full
+func (h *history) add(result *healthcheck.Result) {
+	h.results[h.next] = result
+	h.next = (h.next + 1) % len(h.results)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// ordered returns the results, from the oldest to the most recent one
+func (h *history) ordered() []*healthcheck.Result {
+	if !h.full {
+		return append([]*healthcheck.Result{}, h.results[:h.next]...)
+	}
+	result := make([]*healthcheck.Result, 0, len(h.results))
+	for i := 0; i < len(h.results); i++ {
+		result = append(result, h.results[(h.next+i)%len(h.results)])
+	}
+	return result
+}
+
+// list returns the results, from the oldest to the most recent one
+func (h *history) list() []healthcheck.Result {
+	ordered := h.ordered()
+	result := make([]healthcheck.Result, 0, len(ordered))
+	for _, r := range ordered {
+		result = append(result, *r)
+	}
+	return result
+}
+
+// resize changes the size of the buffer, keeping the most recent
+// results, and returns the number of results dropped
+func (h *history) resize(size int) int {
+	if size == len(h.results) {
+		return 0
+	}
+	ordered := h.ordered()
+	dropped := 0
+	if len(ordered) > size {
+		dropped = len(ordered) - size
+		ordered = ordered[dropped:]
+	}
+	h.results = make([]*healthcheck.Result, size)
+	h.next = 0
+	h.full = false
+	for _, r := range ordered {
+		h.add(r)
+	}
+	return dropped
+}
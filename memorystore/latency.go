@@ -0,0 +1,92 @@
+package memorystore
+
+import (
+	"sort"
+)
+
+// DefaultLatencyWindow the default number of durations used to compute
+// the latency percentiles of an healthcheck
+const DefaultLatencyWindow = 100
+
+// Latency the latency percentiles of an healthcheck, in milliseconds
+type Latency struct {
+	P50     int64 `json:"p50"`
+	P95     int64 `json:"p95"`
+	P99     int64 `json:"p99"`
+	Samples int   `json:"samples"`
+}
+
+// latencyWindow is a ring buffer containing the latest durations of an
+// healthcheck
+type latencyWindow struct {
+	durations []int64
+	next      int
+	full      bool
+}
+
+func newLatencyWindow(size int) *latencyWindow {
+	return &latencyWindow{
+		durations: make([]int64, size),
+	}
+}
+
+func (l *latencyWindow) add(duration int64) {
+	l.durations[l.next] = duration
+	l.next = (l.next + 1) % len(l.durations)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// percentile returns the percentile of sorted values, using the nearest
+// rank method
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// resize changes the size of the window, keeping the most recent
+// durations
+func (l *latencyWindow) resize(size int) {
+	if size == len(l.durations) {
+		return
+	}
+	var ordered []int64
+	if l.full {
+		ordered = append(ordered, l.durations[l.next:]...)
+	}
+	ordered = append(ordered, l.durations[:l.next]...)
+	if len(ordered) > size {
+		ordered = ordered[len(ordered)-size:]
+	}
+	l.durations = make([]int64, size)
+	l.next = 0
+	l.full = false
+	for _, duration := range ordered {
+		l.add(duration)
+	}
+}
+
+func (l *latencyWindow) latency() Latency {
+	size := l.next
+	if l.full {
+		size = len(l.durations)
+	}
+	if size == 0 {
+		return Latency{}
+	}
+	sorted := make([]int64, size)
+	copy(sorted, l.durations[:size])
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	return Latency{
+		P50:     percentile(sorted, 50),
+		P95:     percentile(sorted, 95),
+		P99:     percentile(sorted, 99),
+		Samples: size,
+	}
+}
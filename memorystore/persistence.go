@@ -0,0 +1,259 @@
+package memorystore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// DefaultRetention the default retention of the persisted results
+const DefaultRetention = healthcheck.Duration(7 * 24 * time.Hour)
+
+// resultsFile the name of the results database in the persistence
+// directory
+const resultsFile = "results.db"
+
+// PersistenceConfiguration the configuration of the results persistence.
+// The results are stored in a bbolt database in the directory, the results
+// of the days (UTC) older than the retention being removed.
+type PersistenceConfiguration struct {
+	Directory string
+	Retention healthcheck.Duration
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *PersistenceConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration PersistenceConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the results persistence configuration")
+	}
+	if raw.Directory == "" {
+		return errors.New("The results persistence directory is missing")
+	}
+	if raw.Retention < 0 {
+		return errors.New("The results retention should be positive")
+	}
+	if raw.Retention == 0 {
+		raw.Retention = DefaultRetention
+	}
+	*configuration = PersistenceConfiguration(raw)
+	return nil
+}
+
+// resultStore persists the results in a bbolt database, with a bucket per
+// healthcheck. The results are keyed by their timestamp followed by a
+// sequence number, the results of a time range being read with a seek.
+type resultStore struct {
+	directory string
+	retention time.Duration
+	db        *bolt.DB
+}
+
+// newResultStore opens the results database, creating its directory if
+// needed
+func newResultStore(config *PersistenceConfiguration) (*resultStore, error) {
+	err := os.MkdirAll(config.Directory, 0750)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to create the results directory %s", config.Directory)
+	}
+	path := filepath.Join(config.Directory, resultsFile)
+	db, err := bolt.Open(path, 0640, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to open the results database %s", path)
+	}
+	return &resultStore{
+		directory: config.Directory,
+		retention: time.Duration(config.Retention),
+		db:        db,
+	}, nil
+}
+
+// resultKey returns the key of a result from its timestamp and its
+// sequence number. Keys are ordered by timestamp.
+func resultKey(timestamp int64, sequence uint64) []byte {
+	if timestamp < 0 {
+		timestamp = 0
+	}
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(timestamp))
+	binary.BigEndian.PutUint64(key[8:], sequence)
+	return key
+}
+
+// append writes a result in the bucket of its healthcheck
+func (s *resultStore) append(result *healthcheck.Result) error {
+	content, err := json.Marshal(result)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to convert the result to json")
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(result.ID()))
+		if err != nil {
+			return err
+		}
+		sequence, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		return bucket.Put(resultKey(result.HealthcheckTimestamp, sequence), content)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Fail to write the result")
+	}
+	return nil
+}
+
+// purge removes the results of the days older than the retention, and
+// the buckets of the healthchecks without results
+func (s *resultStore) purge(now time.Time) error {
+	limit := resultKey(now.Add(-s.retention).UTC().Truncate(24*time.Hour).Unix(), 0)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var empty [][]byte
+		err := tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			var expired [][]byte
+			cursor := bucket.Cursor()
+			key, _ := cursor.First()
+			for ; key != nil && bytes.Compare(key, limit) < 0; key, _ = cursor.Next() {
+				expired = append(expired, append([]byte{}, key...))
+			}
+			if key == nil {
+				empty = append(empty, append([]byte{}, name...))
+				return nil
+			}
+			for _, key := range expired {
+				if err := bucket.Delete(key); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range empty {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Fail to remove the expired results")
+	}
+	return nil
+}
+
+// decode decodes a persisted result
+func decode(value []byte) (healthcheck.Result, error) {
+	var result healthcheck.Result
+	if err := json.Unmarshal(value, &result); err != nil {
+		return result, errors.Wrapf(err, "Fail to read the persisted result")
+	}
+	return result, nil
+}
+
+// read returns the results of an healthcheck between from (included) and
+// to (excluded), from the oldest to the most recent one
+func (s *resultStore) read(id string, from time.Time, to time.Time) ([]healthcheck.Result, error) {
+	results := []healthcheck.Result{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(id))
+		if bucket == nil {
+			return nil
+		}
+		end := resultKey(to.Unix(), 0)
+		cursor := bucket.Cursor()
+		for key, value := cursor.Seek(resultKey(from.Unix(), 0)); key != nil && bytes.Compare(key, end) < 0; key, value = cursor.Next() {
+			result, err := decode(value)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// window returns the results of healthchecks between from (included) and
+// to (excluded), read in a single transaction
+func (s *resultStore) window(ids []string, from time.Time, to time.Time) (map[string]windowResults, error) {
+	windows := make(map[string]windowResults)
+	start := resultKey(from.Unix(), 0)
+	end := resultKey(to.Unix(), 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		for _, id := range ids {
+			bucket := tx.Bucket([]byte(id))
+			if bucket == nil {
+				continue
+			}
+			cursor := bucket.Cursor()
+			first, _ := cursor.First()
+			w := windowResults{
+				results:  []healthcheck.Result{},
+				complete: first != nil && bytes.Compare(first, start) < 0,
+			}
+			for key, value := cursor.Seek(start); key != nil && bytes.Compare(key, end) < 0; key, value = cursor.Next() {
+				result, err := decode(value)
+				if err != nil {
+					return err
+				}
+				w.results = append(w.results, result)
+			}
+			windows[id] = w
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+// last returns the n most recent results of an healthcheck, from the
+// oldest to the most recent one. Only the results for which keep returns
+// true are returned if keep is not nil.
+func (s *resultStore) last(id string, n int, keep func(healthcheck.Result) bool) ([]healthcheck.Result, error) {
+	results := []healthcheck.Result{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(id))
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		for key, value := cursor.Last(); key != nil && len(results) < n; key, value = cursor.Prev() {
+			result, err := decode(value)
+			if err != nil {
+				return err
+			}
+			if keep == nil || keep(result) {
+				results = append(results, result)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+		results[i], results[j] = results[j], results[i]
+	}
+	return results, nil
+}
+
+// close closes the results database
+func (s *resultStore) close() error {
+	return s.db.Close()
+}
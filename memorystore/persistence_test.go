@@ -0,0 +1,114 @@
+package memorystore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestPersistenceConfiguration(t *testing.T) {
+	var config PersistenceConfiguration
+	err := yaml.Unmarshal([]byte(`directory: "/tmp/results"`), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration: %v", err)
+	}
+	if config.Retention != DefaultRetention {
+		t.Fatalf("Invalid default retention: %v", config.Retention)
+	}
+	err = yaml.Unmarshal([]byte(`retention: 24h`), &config)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistenceConfiguration{
+		Directory: dir,
+		Retention: healthcheck.Duration(48 * time.Hour),
+	}
+	now := time.Now()
+	store := NewMemoryStore(zap.NewExample(), 3)
+	err := store.EnablePersistence(config)
+	if err != nil {
+		t.Fatalf("Fail to enable the persistence: %v", err)
+	}
+	store.Start()
+	if _, err := os.Stat(filepath.Join(dir, resultsFile)); err != nil {
+		t.Fatalf("The results database should be created: %v", err)
+	}
+	// expired results, removed on startup
+	for _, name := range []string{"foo", "expired"} {
+		store.Add(&healthcheck.Result{
+			Name:                 name,
+			Success:              true,
+			HealthcheckTimestamp: now.Add(-72 * time.Hour).Unix(),
+		})
+	}
+	for i := 0; i < 5; i++ {
+		store.Add(&healthcheck.Result{
+			Name:                 "foo",
+			Success:              i != 0,
+			Duration:             10,
+			HealthcheckTimestamp: now.Add(time.Duration(i-30) * time.Hour).Unix(),
+		})
+	}
+	store.Add(&healthcheck.Result{
+		Name:                 "bar",
+		Success:              true,
+		HealthcheckTimestamp: now.Unix(),
+	})
+	err = store.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the store: %v", err)
+	}
+
+	// the history survives a restart
+	store = NewMemoryStore(zap.NewExample(), 3)
+	err = store.EnablePersistence(config)
+	if err != nil {
+		t.Fatalf("Fail to enable the persistence: %v", err)
+	}
+	store.Start()
+	history, err := store.GetHistory("foo")
+	if err != nil {
+		t.Fatalf("Fail to get the history: %v", err)
+	}
+	if len(history) != 3 || history[0].HealthcheckTimestamp != now.Add(-28*time.Hour).Unix() {
+		t.Fatalf("Invalid history: %+v", history)
+	}
+	series, err := store.GetSeries("foo", now.Add(-80*time.Hour), now, time.Hour)
+	if err != nil {
+		t.Fatalf("Fail to get the series: %v", err)
+	}
+	executions := 0
+	for _, point := range series.Points {
+		executions += point.Executions
+	}
+	if series.Name != "foo" || executions != 5 {
+		t.Fatalf("Invalid series: %+v", series)
+	}
+	failures, err := store.GetFailures("foo", 10)
+	if err != nil {
+		t.Fatalf("Fail to get the failures: %v", err)
+	}
+	if len(failures) != 1 || failures[0].Timestamp != now.Add(-30*time.Hour).Unix() {
+		t.Fatalf("Invalid failures: %+v", failures)
+	}
+	for _, name := range []string{"baz", "expired"} {
+		_, err = store.GetHistory(name)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", name)
+		}
+	}
+	err = store.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the store: %v", err)
+	}
+}
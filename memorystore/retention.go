@@ -0,0 +1,148 @@
+package memorystore
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+// DefaultMaxAge the default maximum age of the results kept in memory
+const DefaultMaxAge = healthcheck.Duration(120 * time.Second)
+
+// DefaultGCInterval the default interval between two removals of the
+// stale results
+const DefaultGCInterval = healthcheck.Duration(30 * time.Second)
+
+const (
+	// EvictionMaxAge the results of an healthcheck were removed because
+	// the healthcheck was not executed during the maximum age (the
+	// healthcheck was deleted for example)
+	EvictionMaxAge = "max-age"
+	// EvictionMaxEntries the oldest results of an healthcheck were
+	// removed because the maximum number of entries was lowered
+	EvictionMaxEntries = "max-entries"
+)
+
+// RetentionConfiguration the retention of the results kept in memory. The
+// stale results are removed periodically by a background loop.
+type RetentionConfiguration struct {
+	// MaxAge the results of the healthchecks not registered anymore and
+	// not executed during this duration are removed, DefaultMaxAge by
+	// default
+	MaxAge healthcheck.Duration `yaml:"max-age"`
+	// MaxEntries the maximum number of results kept per healthcheck, in
+	// its history and to compute its latency percentiles. The history
+	// size and the latency window are not limited if 0.
+	MaxEntries uint `yaml:"max-entries"`
+	// Interval the interval between two removals of the stale results,
+	// DefaultGCInterval by default
+	Interval healthcheck.Duration
+}
+
+// Validate validates the retention configuration
+func (c *RetentionConfiguration) Validate() error {
+	if c.MaxAge < 0 {
+		return errors.New("The results retention max-age should be positive")
+	}
+	if c.Interval < 0 {
+		return errors.New("The results retention interval should be positive")
+	}
+	return nil
+}
+
+// maxAge returns the maximum age of the results, DefaultMaxAge by default
+func (c *RetentionConfiguration) maxAge() time.Duration {
+	if c.MaxAge == 0 {
+		return time.Duration(DefaultMaxAge)
+	}
+	return time.Duration(c.MaxAge)
+}
+
+// interval returns the interval of the removals of the stale results,
+// DefaultGCInterval by default
+func (c *RetentionConfiguration) interval() time.Duration {
+	if c.Interval == 0 {
+		return time.Duration(DefaultGCInterval)
+	}
+	return time.Duration(c.Interval)
+}
+
+// limit returns the size bounded by the maximum number of entries
+func (c *RetentionConfiguration) limit(size int) int {
+	if c.MaxEntries != 0 && int(c.MaxEntries) < size {
+		return int(c.MaxEntries)
+	}
+	return size
+}
+
+// storeMetrics the metrics of the memory store
+type storeMetrics struct {
+	evictions    *prom.CounterVec
+	healthchecks prom.Gauge
+}
+
+// EnableMetrics registers the metrics of the memory store
+func (m *MemoryStore) EnableMetrics(promComponent *prometheus.Prometheus) error {
+	metrics := &storeMetrics{
+		evictions: prom.NewCounterVec(
+			prom.CounterOpts{
+				Name: "memorystore_evictions_total",
+				Help: "Count the results removed from the memory store, by reason",
+			},
+			[]string{"reason"}),
+		healthchecks: prom.NewGauge(
+			prom.GaugeOpts{
+				Name: "memorystore_healthchecks",
+				Help: "Number of healthchecks whose results are kept in the memory store",
+			}),
+	}
+	if err := promComponent.Register(metrics.evictions); err != nil {
+		return errors.Wrapf(err, "Fail to register the memory store metrics")
+	}
+	if err := promComponent.Register(metrics.healthchecks); err != nil {
+		return errors.Wrapf(err, "Fail to register the memory store metrics")
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.metrics = metrics
+	m.metrics.healthchecks.Set(float64(len(m.Results)))
+	return nil
+}
+
+// evicted records evicted results. The lock should be held.
+func (m *MemoryStore) evicted(reason string, count int) {
+	if m.metrics != nil && count != 0 {
+		m.metrics.evictions.With(prom.Labels{"reason": reason}).Add(float64(count))
+	}
+}
+
+// SetRetention sets the retention of the results. The history and the
+// latency window of the healthchecks are shrunk if needed, and the
+// interval of the background removal loop is updated.
+func (m *MemoryStore) SetRetention(config RetentionConfiguration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.retention = config
+	m.TTL = config.maxAge()
+	historySize := m.historySize()
+	for _, h := range m.History {
+		m.evicted(EvictionMaxEntries, h.resize(historySize))
+	}
+	latencySize := m.retention.limit(DefaultLatencyWindow)
+	for _, l := range m.Latencies {
+		l.resize(latencySize)
+	}
+	if m.Tick != nil {
+		m.Tick.Reset(config.interval())
+	}
+}
+
+// historySize returns the number of results kept in the history of each
+// healthcheck. The lock should be held.
+func (m *MemoryStore) historySize() int {
+	return m.retention.limit(int(m.HistorySize))
+}
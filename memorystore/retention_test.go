@@ -0,0 +1,106 @@
+package memorystore
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+// metricValue returns the value of a counter or a gauge of the registry
+func metricValue(t *testing.T, prom *prometheus.Prometheus, name string, label string) float64 {
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics\n%v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			if label != "" && (len(metric.GetLabel()) == 0 || metric.GetLabel()[0].GetValue() != label) {
+				continue
+			}
+			if metric.GetCounter() != nil {
+				return metric.GetCounter().GetValue()
+			}
+			return metric.GetGauge().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestRetention(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	store := NewMemoryStore(zap.NewExample(), 10)
+	err = store.EnableMetrics(prom)
+	if err != nil {
+		t.Fatalf("Fail to enable the metrics\n%v", err)
+	}
+	store.SetRetention(RetentionConfiguration{MaxAge: healthcheck.Duration(time.Hour), MaxEntries: 5})
+	now := time.Now()
+	for i := 0; i < 8; i++ {
+		store.Add(&healthcheck.Result{Name: "foo", Success: true, HealthcheckTimestamp: now.Unix(), Duration: int64(i)})
+		store.Add(&healthcheck.Result{Name: "bar", Success: true, HealthcheckTimestamp: now.Add(-2 * time.Hour).Unix(), Duration: int64(i)})
+	}
+	history, err := store.GetHistory("foo")
+	if err != nil {
+		t.Fatalf("Fail to get the history\n%v", err)
+	}
+	if len(history) != 5 || history[0].Duration != 3 {
+		t.Fatalf("Invalid history %+v", history)
+	}
+	latency, err := store.GetLatency("foo")
+	if err != nil {
+		t.Fatalf("Fail to get the latency\n%v", err)
+	}
+	if latency.Samples != 5 {
+		t.Fatalf("Invalid latency %+v", latency)
+	}
+	if value := metricValue(t, prom, "memorystore_healthchecks", ""); value != 2 {
+		t.Fatalf("Invalid number of healthchecks %f", value)
+	}
+	store.Purge()
+	if len(store.List()) != 1 {
+		t.Fatalf("The stale results were not removed: %+v", store.List())
+	}
+	if value := metricValue(t, prom, "memorystore_evictions_total", EvictionMaxAge); value != 5 {
+		t.Fatalf("Invalid max-age evictions %f", value)
+	}
+	if value := metricValue(t, prom, "memorystore_healthchecks", ""); value != 1 {
+		t.Fatalf("Invalid number of healthchecks %f", value)
+	}
+
+	store.SetRetention(RetentionConfiguration{MaxAge: healthcheck.Duration(time.Hour), MaxEntries: 2})
+	history, err = store.GetHistory("foo")
+	if err != nil {
+		t.Fatalf("Fail to get the history\n%v", err)
+	}
+	if len(history) != 2 || history[0].Duration != 6 || history[1].Duration != 7 {
+		t.Fatalf("Invalid history %+v", history)
+	}
+	if value := metricValue(t, prom, "memorystore_evictions_total", EvictionMaxEntries); value != 3 {
+		t.Fatalf("Invalid max-entries evictions %f", value)
+	}
+	store.Add(&healthcheck.Result{Name: "foo", Success: true, HealthcheckTimestamp: now.Unix(), Duration: 8})
+	history, err = store.GetHistory("foo")
+	if err != nil {
+		t.Fatalf("Fail to get the history\n%v", err)
+	}
+	if len(history) != 2 || history[0].Duration != 7 || history[1].Duration != 8 {
+		t.Fatalf("Invalid history %+v", history)
+	}
+	latency, err = store.GetLatency("foo")
+	if err != nil {
+		t.Fatalf("Fail to get the latency\n%v", err)
+	}
+	if latency.Samples != 2 || latency.P99 != 8 {
+		t.Fatalf("Invalid latency %+v", latency)
+	}
+}
@@ -12,31 +12,69 @@ import (
 	"github.com/appclacks/cabourotte/healthcheck"
 )
 
+// DefaultHistorySize the default number of results kept per healthcheck
+const DefaultHistorySize = 10
+
 // MemoryStore A store containing the latest healthchecks results
 type MemoryStore struct {
-	TTL     time.Duration
-	Logger  *zap.Logger
-	Results map[string]*healthcheck.Result
-	Tick    *time.Ticker
+	TTL         time.Duration
+	Logger      *zap.Logger
+	Results     map[string]*healthcheck.Result
+	History     map[string]*history
+	HistorySize uint
+	Latencies   map[string]*latencyWindow
+	Tick        *time.Ticker
+
+	// persistence persists the results if enabled
+	persistence *resultStore
+	// annotations the known incidents, by name
+	annotations map[string]*Annotation
+	// groups the identifiers of the results of each group, by group
+	// identifier
+	groups map[string]map[string]bool
+	// health the cached states of the groups served by the health
+	// endpoints
+	health *healthCache
+	// retention the retention of the results
+	retention RetentionConfiguration
+	// metrics the metrics of the store, nil if disabled
+	metrics *storeMetrics
+	// slos the SLO counters of the healthcheck having a SLO
+	slos map[string]*sloCounters
+	// registered returns true if the healthcheck is registered, its
+	// results being kept whatever their age
+	registered func(id string) bool
 
 	t    tomb.Tomb
 	lock sync.RWMutex
 }
 
 // NewMemoryStore creates a new memory store
-func NewMemoryStore(logger *zap.Logger) *MemoryStore {
+// The store keeps the last historySize results for each healthcheck.
+func NewMemoryStore(logger *zap.Logger, historySize uint) *MemoryStore {
+	if historySize == 0 {
+		historySize = DefaultHistorySize
+	}
 	return &MemoryStore{
-		Logger:  logger,
-		TTL:     time.Second * 120,
-		Results: make(map[string]*healthcheck.Result),
+		Logger:      logger,
+		TTL:         time.Duration(DefaultMaxAge),
+		Results:     make(map[string]*healthcheck.Result),
+		History:     make(map[string]*history),
+		HistorySize: historySize,
+		Latencies:   make(map[string]*latencyWindow),
+		annotations: make(map[string]*Annotation),
+		slos:        make(map[string]*sloCounters),
+		groups:      make(map[string]map[string]bool),
+		health:      newHealthCache(),
 	}
 }
 
-// Start starts the memory store
+// Start starts the memory store, and the periodic removal of the stale
+// results
 func (m *MemoryStore) Start() {
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	m.Tick = time.NewTicker(time.Second * 30)
+	m.Tick = time.NewTicker(m.retention.interval())
 	m.t.Go(func() error {
 		for {
 			select {
@@ -57,28 +95,119 @@ func (m *MemoryStore) Stop() error {
 	if err != nil {
 		return err
 	}
+	if m.persistence != nil {
+		return m.persistence.close()
+	}
+	return nil
+}
+
+// EnablePersistence persists the results on disk, the history and series
+// of the healthchecks being read from the persisted results
+func (m *MemoryStore) EnablePersistence(config *PersistenceConfiguration) error {
+	persistence, err := newResultStore(config)
+	if err != nil {
+		return err
+	}
+	err = persistence.purge(time.Now())
+	if err != nil {
+		return err
+	}
+	annotations, err := loadAnnotations(config.Directory)
+	if err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.persistence = persistence
+	for name, annotation := range annotations {
+		m.annotations[name] = annotation
+	}
 	return nil
 }
 
 // Add a new Result to the store
 func (m *MemoryStore) Add(result *healthcheck.Result) {
+	m.lock.RLock()
+	result.Annotations = m.annotationsFor(result)
+	m.lock.RUnlock()
+	if m.persistence != nil {
+		if err := m.persistence.append(result); err != nil {
+			m.Logger.Error(err.Error())
+		}
+	}
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	m.Results[result.Name] = result
+	id := result.ID()
+	if previous, ok := m.Results[id]; ok {
+		m.unindex(id, previous)
+	}
+	m.Results[id] = result
+	m.index(id, result)
+	if m.metrics != nil {
+		m.metrics.healthchecks.Set(float64(len(m.Results)))
+	}
+	m.recordSLO(id, result)
+	h, ok := m.History[id]
+	if !ok {
+		h = newHistory(uint(m.historySize()))
+		m.History[id] = h
+	}
+	h.add(result)
+	// results which were not executed do not have a duration
+	if !result.DependencyFailure {
+		l, ok := m.Latencies[id]
+		if !ok {
+			l = newLatencyWindow(m.retention.limit(DefaultLatencyWindow))
+			m.Latencies[id] = l
+		}
+		l.add(result.Duration)
+	}
+}
+
+// SetRegistered configures the function used to know if an healthcheck is
+// still registered. The results of the registered healthchecks are not
+// purged, their interval being possibly longer than the maximum age (cron
+// expressions, backoff...).
+func (m *MemoryStore) SetRegistered(registered func(id string) bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.registered = registered
 }
 
-// Purge the expired results
+// Purge the results of the healthchecks not registered anymore and not
+// executed during the maximum age
 func (m *MemoryStore) Purge() {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	now := time.Now()
-	for i := range m.Results {
-		result := m.Results[i]
+	for id := range m.Results {
+		result := m.Results[id]
+		if m.registered != nil && m.registered(id) {
+			continue
+		}
 		checkTimestamp := time.Unix(result.HealthcheckTimestamp, 0)
 		if now.After(checkTimestamp.Add(m.TTL)) {
 			m.Logger.Info("expire healthcheck",
-				zap.String("name", result.Name))
-			delete(m.Results, result.Name)
+				zap.String("name", result.Name),
+				zap.String("namespace", result.Namespace))
+			m.unindex(id, result)
+			evicted := 1
+			if h, ok := m.History[id]; ok {
+				evicted = len(h.ordered())
+			}
+			m.evicted(EvictionMaxAge, evicted)
+			delete(m.Results, id)
+			delete(m.History, id)
+			delete(m.Latencies, id)
+			delete(m.slos, id)
+		}
+	}
+	if m.metrics != nil {
+		m.metrics.healthchecks.Set(float64(len(m.Results)))
+	}
+	if m.persistence != nil {
+		if err := m.persistence.purge(now); err != nil {
+			m.Logger.Error(err.Error())
 		}
 	}
 }
@@ -93,17 +222,51 @@ func (m *MemoryStore) List() []healthcheck.Result {
 		result = append(result, *value)
 	}
 	sort.Slice(result, func(i, j int) bool {
-		return result[i].Name < result[j].Name
+		return result[i].ID() < result[j].ID()
 	})
 	return result
 }
 
-// Get returns the current value for a healthcheck
-func (m *MemoryStore) Get(name string) (healthcheck.Result, error) {
+// Get returns the current value for a healthcheck, by identifier
+func (m *MemoryStore) Get(id string) (healthcheck.Result, error) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
-	if result, ok := m.Results[name]; ok {
+	if result, ok := m.Results[id]; ok {
 		return *result, nil
 	}
-	return healthcheck.Result{}, fmt.Errorf("Result not found for healthcheck %s", name)
+	return healthcheck.Result{}, fmt.Errorf("Result not found for healthcheck %s", id)
+}
+
+// GetLatency returns the latency percentiles of an healthcheck, computed on
+// its latest executions
+func (m *MemoryStore) GetLatency(id string) (Latency, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if l, ok := m.Latencies[id]; ok {
+		return l.latency(), nil
+	}
+	return Latency{}, fmt.Errorf("Result not found for healthcheck %s", id)
+}
+
+// GetHistory returns the latest results for a healthcheck, from the
+// oldest to the most recent one. The persisted results are used if the
+// healthcheck results are not in memory anymore.
+func (m *MemoryStore) GetHistory(id string) ([]healthcheck.Result, error) {
+	m.lock.RLock()
+	if h, ok := m.History[id]; ok {
+		defer m.lock.RUnlock()
+		return h.list(), nil
+	}
+	size := m.historySize()
+	m.lock.RUnlock()
+	if m.persistence != nil {
+		results, err := m.persistence.last(id, size, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) != 0 {
+			return results, nil
+		}
+	}
+	return nil, fmt.Errorf("Result not found for healthcheck %s", id)
 }
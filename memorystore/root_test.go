@@ -10,7 +10,7 @@ import (
 )
 
 func TestMemoryExporter(t *testing.T) {
-	store := NewMemoryStore(zap.NewExample())
+	store := NewMemoryStore(zap.NewExample(), 10)
 	ts := time.Now()
 	result := &healthcheck.Result{
 		Name:                 "foo",
@@ -47,3 +47,192 @@ func TestMemoryExporter(t *testing.T) {
 		t.Fatalf("Invalid result list size: %d", len(resultList))
 	}
 }
+
+func TestPurgeRegistered(t *testing.T) {
+	store := NewMemoryStore(zap.NewExample(), 10)
+	store.SetRegistered(func(id string) bool {
+		return id == "cron"
+	})
+	ts := time.Now().Add(-time.Hour)
+	for _, name := range []string{"cron", "deleted"} {
+		store.Add(&healthcheck.Result{
+			Name:                 name,
+			Success:              true,
+			HealthcheckTimestamp: ts.Unix(),
+		})
+	}
+	store.Purge()
+	resultList := store.List()
+	if len(resultList) != 1 || resultList[0].Name != "cron" {
+		t.Fatalf("Invalid results after the purge: %+v", resultList)
+	}
+}
+
+func TestMemoryStoreHistory(t *testing.T) {
+	store := NewMemoryStore(zap.NewExample(), 3)
+	for i := 0; i < 5; i++ {
+		store.Add(&healthcheck.Result{
+			Name:                 "foo",
+			Success:              i%2 == 0,
+			HealthcheckTimestamp: int64(i),
+		})
+	}
+	history, err := store.GetHistory("foo")
+	if err != nil {
+		t.Fatalf("Fail to get the history: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("Invalid history size: %d", len(history))
+	}
+	for i, result := range history {
+		if result.HealthcheckTimestamp != int64(i+2) {
+			t.Fatalf("Invalid history order: %d", result.HealthcheckTimestamp)
+		}
+	}
+	_, err = store.GetHistory("bar")
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestMemoryStoreLatency(t *testing.T) {
+	store := NewMemoryStore(zap.NewExample(), 10)
+	for i := 1; i <= 100; i++ {
+		store.Add(&healthcheck.Result{
+			Name:                 "foo",
+			Success:              true,
+			HealthcheckTimestamp: time.Now().Unix(),
+			Duration:             int64(i),
+		})
+	}
+	latency, err := store.GetLatency("foo")
+	if err != nil {
+		t.Fatalf("Fail to get the latency: %v", err)
+	}
+	expected := Latency{P50: 50, P95: 95, P99: 99, Samples: 100}
+	if latency != expected {
+		t.Fatalf("Invalid latency: %+v", latency)
+	}
+	_, err = store.GetLatency("bar")
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestGroup(t *testing.T) {
+	store := NewMemoryStore(zap.NewExample(), 10)
+	add := func(name string, namespace string, group string, state string) {
+		store.Add(&healthcheck.Result{
+			Name:                 name,
+			Namespace:            namespace,
+			Group:                group,
+			State:                state,
+			Success:              state == healthcheck.StateHealthy,
+			HealthcheckTimestamp: time.Now().Unix(),
+		})
+	}
+	_, err := store.Group("", "web")
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	add("a", "", "web", healthcheck.StateUnknown)
+	add("other", "team-a", "web", healthcheck.StateUnhealthy)
+	cases := []struct {
+		name     string
+		state    string
+		expected string
+	}{
+		{name: "a", state: healthcheck.StateUnknown, expected: healthcheck.StateUnknown},
+		{name: "a", state: healthcheck.StateHealthy, expected: healthcheck.StateHealthy},
+		{name: "b", state: healthcheck.StateUnhealthy, expected: GroupStateDegraded},
+		{name: "a", state: healthcheck.StateUnhealthy, expected: healthcheck.StateUnhealthy},
+	}
+	for _, c := range cases {
+		add(c.name, "", "web", c.state)
+		group, err := store.Group("", "web")
+		if err != nil {
+			t.Fatalf("Fail to get the group: %s", err.Error())
+		}
+		if group.State != c.expected {
+			t.Fatalf("Invalid group state %s, expected %s", group.State, c.expected)
+		}
+	}
+	// the shadow healthchecks do not affect the group
+	store.Add(&healthcheck.Result{
+		Name:                 "shadow",
+		Group:                "web",
+		State:                healthcheck.StateHealthy,
+		Success:              true,
+		Shadow:               true,
+		HealthcheckTimestamp: time.Now().Unix(),
+	})
+	group, err := store.Group("", "web")
+	if err != nil {
+		t.Fatalf("Fail to get the group: %s", err.Error())
+	}
+	if group.State != healthcheck.StateUnhealthy || len(group.Healthy) != 0 {
+		t.Fatalf("Invalid group %+v", group)
+	}
+	group, err = store.Group("team-a", "web")
+	if err != nil {
+		t.Fatalf("Fail to get the group: %s", err.Error())
+	}
+	result := group.Result()
+	if result.Success || result.Severity != healthcheck.SeverityCritical || result.Message != "0/1 healthchecks healthy, unhealthy: other" {
+		t.Fatalf("Invalid group result %+v", result)
+	}
+}
+
+func TestCachedGroup(t *testing.T) {
+	store := NewMemoryStore(zap.NewExample(), 10)
+	add := func(name string, group string, state string) {
+		store.Add(&healthcheck.Result{
+			Name:                 name,
+			Group:                group,
+			State:                state,
+			Success:              state == healthcheck.StateHealthy,
+			HealthcheckTimestamp: time.Now().Unix(),
+		})
+	}
+	add("a", "web", healthcheck.StateHealthy)
+	store.SetHealthStaleness(time.Hour)
+	group, err := store.CachedGroup("", "web")
+	if err != nil {
+		t.Fatalf("Fail to get the group: %s", err.Error())
+	}
+	if group.State != healthcheck.StateHealthy {
+		t.Fatalf("Invalid group state %s", group.State)
+	}
+	// the cached state is returned until it is stale
+	add("a", "web", healthcheck.StateUnhealthy)
+	group, err = store.CachedGroup("", "web")
+	if err != nil {
+		t.Fatalf("Fail to get the group: %s", err.Error())
+	}
+	if group.State != healthcheck.StateHealthy {
+		t.Fatalf("Invalid group state %s", group.State)
+	}
+	store.SetHealthStaleness(0)
+	group, err = store.CachedGroup("", "web")
+	if err != nil {
+		t.Fatalf("Fail to get the group: %s", err.Error())
+	}
+	if group.State != healthcheck.StateUnhealthy {
+		t.Fatalf("Invalid group state %s", group.State)
+	}
+	// the results moved to another group leave the index
+	add("a", "api", healthcheck.StateHealthy)
+	_, err = store.CachedGroup("", "web")
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	store.TTL = 0
+	store.Purge()
+	_, err = store.Group("", "api")
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if len(store.groups) != 0 {
+		t.Fatalf("The groups index should be empty: %+v", store.groups)
+	}
+}
@@ -0,0 +1,178 @@
+package memorystore
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// Point the aggregated results of an healthcheck during a step
+type Point struct {
+	// Timestamp the start of the step
+	Timestamp    int64   `json:"timestamp"`
+	Executions   int     `json:"executions"`
+	SuccessRatio float64 `json:"success-ratio"`
+	// LatencyAvg the average duration in milliseconds
+	LatencyAvg float64 `json:"latency-avg"`
+	// LatencyMax the maximum duration in milliseconds
+	LatencyMax int64 `json:"latency-max"`
+}
+
+// Series the downsampled results of an healthcheck. The steps without
+// executions are omitted.
+type Series struct {
+	Name      string  `json:"name"`
+	Namespace string  `json:"namespace,omitempty"`
+	From      int64   `json:"from"`
+	To        int64   `json:"to"`
+	Step      int64   `json:"step"`
+	Points    []Point `json:"points"`
+}
+
+// MaxSeriesPoints the maximum number of steps of a series
+const MaxSeriesPoints = 10000
+
+// ValidateRange checks the time range of a series query
+func ValidateRange(from time.Time, to time.Time, step time.Duration) error {
+	if !from.Before(to) {
+		return fmt.Errorf("The start of the range should be before its end")
+	}
+	if step < time.Second || step%time.Second != 0 {
+		return fmt.Errorf("The step should be a whole number of seconds")
+	}
+	if to.Sub(from)/step > MaxSeriesPoints {
+		return fmt.Errorf("The range contains more than %d steps, increase the step", MaxSeriesPoints)
+	}
+	return nil
+}
+
+// downsample aggregates the results (sorted by timestamp) between from
+// (included) and to (excluded) by step
+func downsample(results []healthcheck.Result, from time.Time, to time.Time, step time.Duration) []Point {
+	points := []Point{}
+	stepSeconds := int64(step / time.Second)
+	var current *Point
+	successes := 0
+	var total int64
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.SuccessRatio = float64(successes) / float64(current.Executions)
+		current.LatencyAvg = float64(total) / float64(current.Executions)
+		points = append(points, *current)
+	}
+	for _, result := range results {
+		// the dependency failures were not executed
+		if result.DependencyFailure {
+			continue
+		}
+		if result.HealthcheckTimestamp < from.Unix() || result.HealthcheckTimestamp >= to.Unix() {
+			continue
+		}
+		timestamp := from.Unix() + (result.HealthcheckTimestamp-from.Unix())/stepSeconds*stepSeconds
+		if current == nil || current.Timestamp != timestamp {
+			flush()
+			current = &Point{Timestamp: timestamp}
+			successes = 0
+			total = 0
+		}
+		current.Executions++
+		if result.Success {
+			successes++
+		}
+		total += result.Duration
+		if result.Duration > current.LatencyMax {
+			current.LatencyMax = result.Duration
+		}
+	}
+	flush()
+	return points
+}
+
+// rangeResults returns the results of an healthcheck between from
+// (included) and to (excluded), read from the persisted results if the
+// persistence is enabled. It returns an error if the healthcheck is
+// unknown.
+func (m *MemoryStore) rangeResults(id string, from time.Time, to time.Time) ([]healthcheck.Result, error) {
+	m.lock.RLock()
+	var history []healthcheck.Result
+	h, found := m.History[id]
+	if found {
+		history = h.list()
+	}
+	m.lock.RUnlock()
+	if m.persistence != nil {
+		persisted, err := m.persistence.read(id, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if len(persisted) != 0 {
+			return persisted, nil
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("Result not found for healthcheck %s", id)
+	}
+	results := []healthcheck.Result{}
+	for _, result := range history {
+		if result.HealthcheckTimestamp >= from.Unix() && result.HealthcheckTimestamp < to.Unix() {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// checkNames returns the name and namespace of an healthcheck from its
+// results, or from its identifier if there is no result
+func checkNames(id string, results []healthcheck.Result) (string, string) {
+	if len(results) != 0 {
+		last := results[len(results)-1]
+		return last.Name, last.Namespace
+	}
+	if i := strings.LastIndex(id, "/"); i != -1 {
+		return id[i+1:], id[:i]
+	}
+	return id, ""
+}
+
+// GetSeries returns the downsampled success ratio and latency of an
+// healthcheck, computed on its history or on the persisted results if the
+// persistence is enabled
+func (m *MemoryStore) GetSeries(id string, from time.Time, to time.Time, step time.Duration) (Series, error) {
+	if err := ValidateRange(from, to, step); err != nil {
+		return Series{}, err
+	}
+	results, err := m.rangeResults(id, from, to)
+	if err != nil {
+		return Series{}, err
+	}
+	name, namespace := checkNames(id, results)
+	return Series{
+		Name:      name,
+		Namespace: namespace,
+		From:      from.Unix(),
+		To:        to.Unix(),
+		Step:      int64(step / time.Second),
+		Points:    downsample(results, from, to, step),
+	}, nil
+}
+
+// ListSeries returns the series of all the healthchecks
+func (m *MemoryStore) ListSeries(from time.Time, to time.Time, step time.Duration) ([]Series, error) {
+	if err := ValidateRange(from, to, step); err != nil {
+		return nil, err
+	}
+	result := []Series{}
+	for _, r := range m.List() {
+		series, err := m.GetSeries(r.ID(), from, to, step)
+		if err != nil {
+			// the healthcheck was purged in the meantime
+			continue
+		}
+		result = append(result, series)
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package memorystore
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestSeries(t *testing.T) {
+	store := NewMemoryStore(zap.NewExample(), 100)
+	for i := 0; i < 12; i++ {
+		store.Add(&healthcheck.Result{
+			Name:                 "foo",
+			Success:              i%3 != 0,
+			Duration:             int64(i),
+			HealthcheckTimestamp: int64(1000 + i*10),
+		})
+	}
+	// not executed, ignored
+	store.Add(&healthcheck.Result{
+		Name:                 "foo",
+		DependencyFailure:    true,
+		HealthcheckTimestamp: 1115,
+	})
+	series, err := store.GetSeries("foo", time.Unix(1000, 0), time.Unix(1120, 0), time.Minute)
+	if err != nil {
+		t.Fatalf("Fail to get the series: %v", err)
+	}
+	if series.Name != "foo" || series.Step != 60 || len(series.Points) != 2 {
+		t.Fatalf("Invalid series: %+v", series)
+	}
+	first := series.Points[0]
+	if first.Timestamp != 1000 || first.Executions != 6 || first.SuccessRatio != 4.0/6.0 || first.LatencyAvg != 2.5 || first.LatencyMax != 5 {
+		t.Fatalf("Invalid point: %+v", first)
+	}
+	second := series.Points[1]
+	if second.Timestamp != 1060 || second.Executions != 6 || second.LatencyMax != 11 {
+		t.Fatalf("Invalid point: %+v", second)
+	}
+	series, err = store.GetSeries("foo", time.Unix(1030, 0), time.Unix(1050, 0), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Fail to get the series: %v", err)
+	}
+	if len(series.Points) != 2 || series.Points[0].Timestamp != 1030 || series.Points[1].Timestamp != 1040 {
+		t.Fatalf("Invalid series: %+v", series)
+	}
+	_, err = store.GetSeries("bar", time.Unix(1000, 0), time.Unix(1120, 0), time.Minute)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	invalid := []struct {
+		from time.Time
+		to   time.Time
+		step time.Duration
+	}{
+		{from: time.Unix(1120, 0), to: time.Unix(1000, 0), step: time.Minute},
+		{from: time.Unix(1000, 0), to: time.Unix(1120, 0), step: 1500 * time.Millisecond},
+		{from: time.Unix(0, 0), to: time.Unix(1000000, 0), step: time.Second},
+	}
+	for _, c := range invalid {
+		_, err = store.GetSeries("foo", c.from, c.to, c.step)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %v", c)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package memorystore
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// SLA the availability of an healthcheck, or of a group of healthchecks,
+// during a window
+type SLA struct {
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	// Label the label value of an aggregate
+	Label  string   `json:"label,omitempty"`
+	Checks []string `json:"checks,omitempty"`
+	From   int64    `json:"from"`
+	To     int64    `json:"to"`
+	// Partial true if the results do not cover the whole window, From
+	// being the timestamp of the first result
+	Partial bool `json:"partial,omitempty"`
+	// Availability the percentage of time the healthchecks were healthy
+	Availability float64 `json:"availability"`
+	Executions   int     `json:"executions"`
+	Outages      int     `json:"outages"`
+	// MTTR the mean time to recovery of the resolved outages, in seconds
+	MTTR float64 `json:"mttr"`
+	// LongestOutage the longest outage in seconds, including the outage
+	// in progress
+	LongestOutage int64 `json:"longest-outage"`
+	// Annotations the known incidents during the window
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+// availability the raw availability data of an healthcheck
+type availability struct {
+	// start the start of the covered range, partial being true if the
+	// results do not cover the whole window
+	start      time.Time
+	partial    bool
+	covered    time.Duration
+	up         time.Duration
+	executions int
+	// resolved the durations of the resolved outages
+	resolved []time.Duration
+	longest  time.Duration
+	outages  int
+}
+
+// ParseWindow parses a window duration, supporting the d (day) unit in
+// addition to the Go duration units
+func ParseWindow(value string) (time.Duration, error) {
+	var window time.Duration
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.ParseUint(strings.TrimSuffix(value, "d"), 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid window %s", value)
+		}
+		window = time.Duration(days) * 24 * time.Hour
+	} else {
+		var err error
+		window, err = time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid window %s", value)
+		}
+	}
+	if window <= 0 {
+		return 0, fmt.Errorf("The window should be positive")
+	}
+	return window, nil
+}
+
+// computeAvailability computes the availability from results sorted by
+// timestamp. The state of a result lasts until the next result or the
+// end of the window. The failures during a maintenance window are not
+// counted as unavailability, and the results of the healthchecks not
+// executed because of a dependency failure are ignored. The window is
+// partially covered if the results do not start before the window.
+func computeAvailability(results windowResults, from time.Time, to time.Time) availability {
+	a := availability{start: from}
+	if !results.complete {
+		a.partial = true
+		a.start = to
+		if len(results.results) != 0 {
+			a.start = time.Unix(results.results[0].HealthcheckTimestamp, 0)
+		}
+	}
+	var outageStart time.Time
+	inOutage := false
+	executed := []healthcheck.Result{}
+	for _, result := range results.results {
+		if !result.DependencyFailure {
+			executed = append(executed, result)
+		}
+	}
+	for i, result := range executed {
+		start := time.Unix(result.HealthcheckTimestamp, 0)
+		end := to
+		if i+1 < len(executed) {
+			end = time.Unix(executed[i+1].HealthcheckTimestamp, 0)
+		}
+		a.executions++
+		a.covered += end.Sub(start)
+		healthy := result.Healthy() || result.Silenced
+		if healthy {
+			a.up += end.Sub(start)
+			if inOutage {
+				outage := start.Sub(outageStart)
+				a.resolved = append(a.resolved, outage)
+				if outage > a.longest {
+					a.longest = outage
+				}
+				inOutage = false
+			}
+			continue
+		}
+		if !inOutage {
+			inOutage = true
+			outageStart = start
+			a.outages++
+		}
+	}
+	if inOutage {
+		if outage := to.Sub(outageStart); outage > a.longest {
+			a.longest = outage
+		}
+	}
+	return a
+}
+
+// merge aggregates the availability of several healthchecks, the covered
+// range starting with the earliest healthcheck
+func (a *availability) merge(other availability) {
+	if a.start.IsZero() || other.start.Before(a.start) {
+		a.start = other.start
+	}
+	a.partial = a.partial || other.partial
+	a.covered += other.covered
+	a.up += other.up
+	a.executions += other.executions
+	a.resolved = append(a.resolved, other.resolved...)
+	a.outages += other.outages
+	if other.longest > a.longest {
+		a.longest = other.longest
+	}
+}
+
+// sla converts the availability to a SLA
+func (a *availability) sla(to time.Time) SLA {
+	result := SLA{
+		From:          a.start.Unix(),
+		To:            to.Unix(),
+		Partial:       a.partial,
+		Executions:    a.executions,
+		Outages:       a.outages,
+		LongestOutage: int64(a.longest / time.Second),
+	}
+	if a.covered > 0 {
+		result.Availability = 100 * float64(a.up) / float64(a.covered)
+	}
+	if len(a.resolved) != 0 {
+		var total time.Duration
+		for _, outage := range a.resolved {
+			total += outage
+		}
+		result.MTTR = (total / time.Duration(len(a.resolved))).Seconds()
+	}
+	return result
+}
+
+// GetSLA returns the availability of an healthcheck during the window
+// ending now
+func (m *MemoryStore) GetSLA(id string, window time.Duration) (SLA, error) {
+	to := time.Now()
+	from := to.Add(-window)
+	windows, err := m.windowResults([]string{id}, from, to)
+	if err != nil {
+		return SLA{}, err
+	}
+	checkWindow, ok := windows[id]
+	if !ok {
+		return SLA{}, fmt.Errorf("Result not found for healthcheck %s", id)
+	}
+	a := computeAvailability(checkWindow, from, to)
+	result := a.sla(to)
+	results := checkWindow.results
+	result.Name, result.Namespace = checkNames(id, results)
+	// the group of the healthcheck may have changed during the window
+	groups := make(map[string]bool)
+	if current, err := m.Get(id); err == nil {
+		results = append(results, current)
+	}
+	for _, r := range results {
+		if r.Group != "" {
+			groups[healthcheck.ID(r.Namespace, r.Group)] = true
+		}
+	}
+	result.Annotations = m.rangeAnnotations(map[string]bool{id: true}, groups, from, to)
+	return result, nil
+}
+
+// AggregateSLA returns the availability of the healthchecks during the
+// window ending now, aggregated by the value of a label. The shadow
+// healthchecks and the healthchecks for which the filter returns false are
+// ignored. The results of the healthchecks are read in a single pass.
+func (m *MemoryStore) AggregateSLA(label string, window time.Duration, filter func(healthcheck.Result) bool) []SLA {
+	to := time.Now()
+	from := to.Add(-window)
+	selected := []healthcheck.Result{}
+	ids := []string{}
+	for _, current := range m.List() {
+		if current.Shadow || !filter(current) {
+			continue
+		}
+		if _, ok := current.Labels[label]; !ok {
+			continue
+		}
+		selected = append(selected, current)
+		ids = append(ids, current.ID())
+	}
+	windows, err := m.windowResults(ids, from, to)
+	if err != nil {
+		m.Logger.Error(err.Error())
+		return []SLA{}
+	}
+	groups := make(map[string]*availability)
+	checks := make(map[string][]string)
+	checkIDs := make(map[string]map[string]bool)
+	checkGroups := make(map[string]map[string]bool)
+	for _, current := range selected {
+		value := current.Labels[label]
+		checkWindow, ok := windows[current.ID()]
+		if !ok {
+			// the healthcheck was purged in the meantime
+			continue
+		}
+		if _, ok := groups[value]; !ok {
+			groups[value] = &availability{}
+			checkIDs[value] = make(map[string]bool)
+			checkGroups[value] = make(map[string]bool)
+		}
+		groups[value].merge(computeAvailability(checkWindow, from, to))
+		checks[value] = append(checks[value], current.ID())
+		checkIDs[value][current.ID()] = true
+		if current.Group != "" {
+			checkGroups[value][healthcheck.ID(current.Namespace, current.Group)] = true
+		}
+	}
+	result := []SLA{}
+	for value, a := range groups {
+		sla := a.sla(to)
+		sla.Label = value
+		sla.Checks = checks[value]
+		sla.Annotations = m.rangeAnnotations(checkIDs[value], checkGroups[value], from, to)
+		result = append(result, sla)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Label < result[j].Label
+	})
+	return result
+}
+
+// windowResults the results of an healthcheck during a window
+type windowResults struct {
+	results []healthcheck.Result
+	// complete true if the healthcheck has results older than the
+	// window, its results covering the whole window
+	complete bool
+}
+
+// windowResults returns the results of healthchecks between from
+// (included) and to (excluded), read from the persisted results if the
+// persistence is enabled. The histories and the persisted results are
+// read in a single pass, the unknown healthchecks being ignored.
+func (m *MemoryStore) windowResults(ids []string, from time.Time, to time.Time) (map[string]windowResults, error) {
+	windows := make(map[string]windowResults)
+	m.lock.RLock()
+	for _, id := range ids {
+		h, ok := m.History[id]
+		if !ok {
+			continue
+		}
+		history := h.list()
+		w := windowResults{
+			results:  []healthcheck.Result{},
+			complete: len(history) != 0 && history[0].HealthcheckTimestamp < from.Unix(),
+		}
+		for _, result := range history {
+			if result.HealthcheckTimestamp >= from.Unix() && result.HealthcheckTimestamp < to.Unix() {
+				w.results = append(w.results, result)
+			}
+		}
+		windows[id] = w
+	}
+	m.lock.RUnlock()
+	if m.persistence != nil {
+		persisted, err := m.persistence.window(ids, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for id, w := range persisted {
+			if len(w.results) != 0 {
+				windows[id] = w
+			}
+		}
+	}
+	return windows, nil
+}
@@ -0,0 +1,143 @@
+package memorystore
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestParseWindow(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected time.Duration
+		valid    bool
+	}{
+		{in: "30d", expected: 30 * 24 * time.Hour, valid: true},
+		{in: "12h", expected: 12 * time.Hour, valid: true},
+		{in: "d", valid: false},
+		{in: "-1h", valid: false},
+		{in: "foo", valid: false},
+	}
+	for _, c := range cases {
+		window, err := ParseWindow(c.in)
+		if c.valid && (err != nil || window != c.expected) {
+			t.Fatalf("Invalid window for %s: %s %v", c.in, window, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Was expecting an error for %s", c.in)
+		}
+	}
+}
+
+func TestComputeAvailability(t *testing.T) {
+	result := func(timestamp int64, success bool) healthcheck.Result {
+		return healthcheck.Result{Name: "foo", Success: success, HealthcheckTimestamp: timestamp}
+	}
+	results := []healthcheck.Result{
+		result(0, true),
+		result(100, false),
+		result(110, false),
+		result(130, true),
+		// not executed
+		{Name: "foo", DependencyFailure: true, HealthcheckTimestamp: 140},
+		result(200, false),
+		{Name: "foo", Success: false, Silenced: true, HealthcheckTimestamp: 250},
+		result(300, true),
+		result(350, false),
+	}
+	a := computeAvailability(windowResults{results: results, complete: true}, time.Unix(0, 0), time.Unix(400, 0))
+	sla := a.sla(time.Unix(400, 0))
+	// down from 100 to 130, 200 to 250 and 350 to 400
+	if sla.Availability != 100*270.0/400.0 {
+		t.Fatalf("Invalid availability %f", sla.Availability)
+	}
+	if sla.Executions != 8 || sla.Outages != 3 {
+		t.Fatalf("Invalid SLA %+v", sla)
+	}
+	// the outages 100-130 and 200-250 (maintenance from 250) are
+	// resolved, the last one is in progress
+	if sla.MTTR != 40 || sla.LongestOutage != 50 {
+		t.Fatalf("Invalid SLA %+v", sla)
+	}
+	if sla.Partial || sla.From != 0 {
+		t.Fatalf("Invalid SLA range %+v", sla)
+	}
+	// the window is not covered by the results
+	a = computeAvailability(windowResults{results: results[1:]}, time.Unix(0, 0), time.Unix(400, 0))
+	sla = a.sla(time.Unix(400, 0))
+	if !sla.Partial || sla.From != 100 || sla.Availability != 100*170.0/300.0 {
+		t.Fatalf("Invalid SLA %+v", sla)
+	}
+}
+
+func TestAggregateSLA(t *testing.T) {
+	store := NewMemoryStore(zap.NewExample(), 10)
+	now := time.Now()
+	checks := []struct {
+		name    string
+		team    string
+		success bool
+		shadow  bool
+	}{
+		{name: "foo", team: "a", success: true},
+		{name: "bar", team: "a", success: false},
+		{name: "baz", team: "b", success: true},
+		{name: "qux", team: "", success: true},
+		{name: "canary", team: "b", success: false, shadow: true},
+	}
+	// baz is covering the whole window
+	store.Add(&healthcheck.Result{
+		Name:                 "baz",
+		Labels:               map[string]string{"team": "b"},
+		Success:              true,
+		HealthcheckTimestamp: now.Add(-48 * time.Hour).Unix(),
+	})
+	for _, c := range checks {
+		labels := map[string]string{}
+		if c.team != "" {
+			labels["team"] = c.team
+		}
+		store.Add(&healthcheck.Result{
+			Name:                 c.name,
+			Labels:               labels,
+			Success:              c.success,
+			Shadow:               c.shadow,
+			HealthcheckTimestamp: now.Add(-time.Hour).Unix(),
+		})
+	}
+	sla, err := store.GetSLA("foo", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Fail to compute the SLA: %v", err)
+	}
+	if sla.Name != "foo" || sla.Availability != 100 {
+		t.Fatalf("Invalid SLA %+v", sla)
+	}
+	// the history of foo does not cover the window
+	if !sla.Partial || sla.From != now.Add(-time.Hour).Unix() {
+		t.Fatalf("Invalid SLA range %+v", sla)
+	}
+	sla, err = store.GetSLA("baz", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Fail to compute the SLA: %v", err)
+	}
+	if sla.Partial || sla.From > now.Add(-23*time.Hour).Unix() {
+		t.Fatalf("Invalid SLA range %+v", sla)
+	}
+	_, err = store.GetSLA("unknown", 24*time.Hour)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	aggregates := store.AggregateSLA("team", 24*time.Hour, func(healthcheck.Result) bool { return true })
+	if len(aggregates) != 2 {
+		t.Fatalf("Invalid aggregates %+v", aggregates)
+	}
+	if aggregates[0].Label != "a" || len(aggregates[0].Checks) != 2 || aggregates[0].Availability < 49 || aggregates[0].Availability > 51 || aggregates[0].Outages != 1 || !aggregates[0].Partial {
+		t.Fatalf("Invalid aggregate %+v", aggregates[0])
+	}
+	if aggregates[1].Label != "b" || aggregates[1].Availability != 100 || len(aggregates[1].Checks) != 1 || aggregates[1].Partial {
+		t.Fatalf("Invalid aggregate %+v", aggregates[1])
+	}
+}
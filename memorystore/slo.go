@@ -0,0 +1,248 @@
+package memorystore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// SourceSLO the source of the SLO results
+const SourceSLO = "slo"
+
+// BurnRate the burn rate of the error budget during a window
+type BurnRate struct {
+	// Window the window duration in seconds
+	Window     int64   `json:"window"`
+	Executions int     `json:"executions"`
+	Bad        int     `json:"bad"`
+	BurnRate   float64 `json:"burn-rate"`
+}
+
+// SLOStatus the evaluation of the SLO of an healthcheck
+type SLOStatus struct {
+	Name        string          `json:"name"`
+	Namespace   string          `json:"namespace,omitempty"`
+	SLO         healthcheck.SLO `json:"slo"`
+	LongWindow  BurnRate        `json:"long-window"`
+	ShortWindow BurnRate        `json:"short-window"`
+	// Burning true if the burn rates of both windows exceed the threshold
+	Burning bool `json:"burning"`
+}
+
+// ID returns the identifier of the healthcheck
+func (s *SLOStatus) ID() string {
+	return healthcheck.ID(s.Namespace, s.Name)
+}
+
+// sloBucket the executions of an healthcheck during a second
+type sloBucket struct {
+	timestamp int64
+	total     int
+	bad       int
+}
+
+// sloWindow the running counters of the executions of a SLO window, the
+// executions being grouped by second
+type sloWindow struct {
+	duration time.Duration
+	buckets  []sloBucket
+	// start the executions older than start were evicted
+	start int64
+	total int
+	bad   int
+}
+
+// add counts an execution, and evicts the executions which left the
+// window
+func (w *sloWindow) add(timestamp int64, bad bool) {
+	if timestamp < w.start {
+		return
+	}
+	// the results are usually received in order
+	i := len(w.buckets)
+	for i > 0 && w.buckets[i-1].timestamp > timestamp {
+		i--
+	}
+	if i == 0 || w.buckets[i-1].timestamp != timestamp {
+		w.buckets = append(w.buckets, sloBucket{})
+		copy(w.buckets[i+1:], w.buckets[i:])
+		w.buckets[i] = sloBucket{timestamp: timestamp}
+	} else {
+		i--
+	}
+	w.buckets[i].total++
+	w.total++
+	if bad {
+		w.buckets[i].bad++
+		w.bad++
+	}
+	w.evict(time.Unix(timestamp, 0))
+}
+
+// evict removes the executions older than the window ending at the given
+// time
+func (w *sloWindow) evict(now time.Time) {
+	start := now.Add(-w.duration).Unix()
+	if start <= w.start {
+		return
+	}
+	w.start = start
+	n := 0
+	for n < len(w.buckets) && w.buckets[n].timestamp < start {
+		w.total -= w.buckets[n].total
+		w.bad -= w.buckets[n].bad
+		n++
+	}
+	w.buckets = w.buckets[n:]
+}
+
+// burnRate returns the burn rate of the error budget during the window
+func (w *sloWindow) burnRate(slo *healthcheck.SLO) BurnRate {
+	result := BurnRate{
+		Window:     int64(w.duration / time.Second),
+		Executions: w.total,
+		Bad:        w.bad,
+	}
+	if result.Executions != 0 {
+		result.BurnRate = slo.Burn(float64(result.Bad) / float64(result.Executions))
+	}
+	return result
+}
+
+// sloCounters the good and total executions of an healthcheck during the
+// long and short windows of its SLO, updated for each result
+type sloCounters struct {
+	slo   healthcheck.SLO
+	long  sloWindow
+	short sloWindow
+}
+
+// newSLOCounters creates the counters of a SLO
+func newSLOCounters(slo healthcheck.SLO) *sloCounters {
+	long, short := slo.Windows()
+	return &sloCounters{
+		slo:   slo,
+		long:  sloWindow{duration: long},
+		short: sloWindow{duration: short},
+	}
+}
+
+// add counts the execution of a result. The results of the healthchecks
+// not executed because of a dependency failure are ignored.
+func (c *sloCounters) add(result *healthcheck.Result) {
+	if result.DependencyFailure {
+		return
+	}
+	bad := !c.slo.Good(*result)
+	c.long.add(result.HealthcheckTimestamp, bad)
+	c.short.add(result.HealthcheckTimestamp, bad)
+}
+
+// recordSLO updates the SLO counters of an healthcheck with a result. The
+// counters are initialized from the persisted results, or from the
+// history, when the healthcheck gets a SLO or when its SLO changes. The
+// lock should be held.
+func (m *MemoryStore) recordSLO(id string, result *healthcheck.Result) {
+	if result.SLO == nil {
+		delete(m.slos, id)
+		return
+	}
+	counters, ok := m.slos[id]
+	if !ok || counters.slo != *result.SLO {
+		counters = newSLOCounters(*result.SLO)
+		var previous []healthcheck.Result
+		if h, ok := m.History[id]; ok {
+			previous = h.list()
+		}
+		if m.persistence != nil {
+			to := time.Unix(result.HealthcheckTimestamp, 0)
+			persisted, err := m.persistence.read(id, to.Add(-counters.long.duration), to)
+			if err != nil {
+				m.Logger.Error(err.Error())
+			} else if len(persisted) != 0 {
+				previous = persisted
+			}
+		}
+		for i := range previous {
+			counters.add(&previous[i])
+		}
+		m.slos[id] = counters
+	}
+	counters.add(result)
+}
+
+// GetSLO evaluates the SLO of an healthcheck on the executions of the
+// windows ending at the given time. The SLO is read from the latest
+// result.
+func (m *MemoryStore) GetSLO(id string, now time.Time) (SLOStatus, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	current, ok := m.Results[id]
+	if !ok {
+		return SLOStatus{}, fmt.Errorf("Result not found for healthcheck %s", id)
+	}
+	counters, ok := m.slos[id]
+	if current.SLO == nil || !ok {
+		return SLOStatus{}, fmt.Errorf("The healthcheck %s has no SLO", id)
+	}
+	counters.long.evict(now)
+	counters.short.evict(now)
+	slo := counters.slo
+	status := SLOStatus{
+		Name:        current.Name,
+		Namespace:   current.Namespace,
+		SLO:         slo,
+		LongWindow:  counters.long.burnRate(&slo),
+		ShortWindow: counters.short.burnRate(&slo),
+	}
+	threshold := slo.Threshold()
+	status.Burning = status.LongWindow.BurnRate >= threshold && status.ShortWindow.BurnRate >= threshold
+	return status, nil
+}
+
+// ListSLO evaluates the SLO of all the healthcheck having one
+func (m *MemoryStore) ListSLO(now time.Time) []SLOStatus {
+	result := []SLOStatus{}
+	for _, current := range m.List() {
+		if current.SLO == nil {
+			continue
+		}
+		status, err := m.GetSLO(current.ID(), now)
+		if err != nil {
+			// the healthcheck was purged in the meantime
+			continue
+		}
+		result = append(result, status)
+	}
+	return result
+}
+
+// Result builds the synthetic result of the SLO, successful if the SLO is
+// not burning. The result is named after the healthcheck, suffixed by
+// -slo.
+func (s *SLOStatus) Result() *healthcheck.Result {
+	state := healthcheck.StateHealthy
+	message := "the error budget is not burning"
+	if s.Burning {
+		state = healthcheck.StateUnhealthy
+		message = fmt.Sprintf("the error budget is burning: burn rate %.2f over %s and %.2f over %s (threshold %.2f)",
+			s.LongWindow.BurnRate,
+			time.Duration(s.LongWindow.Window)*time.Second,
+			s.ShortWindow.BurnRate,
+			time.Duration(s.ShortWindow.Window)*time.Second,
+			s.SLO.Threshold())
+	}
+	return &healthcheck.Result{
+		Name:                 fmt.Sprintf("%s-slo", s.Name),
+		Namespace:            s.Namespace,
+		Summary:              fmt.Sprintf("SLO of the healthcheck %s (%.3f%%)", s.Name, s.SLO.Objective),
+		Labels:               map[string]string{"slo": s.Name},
+		Success:              !s.Burning,
+		State:                state,
+		HealthcheckTimestamp: time.Now().Unix(),
+		Message:              message,
+		Source:               SourceSLO,
+		Severity:             healthcheck.SeverityCritical,
+	}
+}
@@ -0,0 +1,97 @@
+package memorystore
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestGetSLO(t *testing.T) {
+	store := NewMemoryStore(zap.NewExample(), 20)
+	now := time.Now()
+	slo := &healthcheck.SLO{
+		Objective:   99,
+		Latency:     healthcheck.Duration(100 * time.Millisecond),
+		LongWindow:  healthcheck.Duration(time.Hour),
+		ShortWindow: healthcheck.Duration(10 * time.Minute),
+	}
+	add := func(ago time.Duration, success bool, duration int64) {
+		store.Add(&healthcheck.Result{
+			Name:                 "foo",
+			Success:              success,
+			Duration:             duration,
+			HealthcheckTimestamp: now.Add(-ago).Unix(),
+			SLO:                  slo,
+		})
+	}
+	// outside the windows
+	add(2*time.Hour, false, 10)
+	for i := 0; i < 8; i++ {
+		add(30*time.Minute, true, 10)
+	}
+	add(20*time.Minute, false, 10)
+	// too slow
+	add(20*time.Minute, true, 200)
+	// not executed
+	store.Add(&healthcheck.Result{
+		Name:                 "foo",
+		DependencyFailure:    true,
+		HealthcheckTimestamp: now.Add(-time.Minute).Unix(),
+		SLO:                  slo,
+	})
+	status, err := store.GetSLO("foo", now)
+	if err != nil {
+		t.Fatalf("Fail to get the SLO\n%v", err)
+	}
+	if status.LongWindow.Executions != 10 || status.LongWindow.Bad != 2 {
+		t.Fatalf("Invalid long window %+v", status.LongWindow)
+	}
+	// 20% of bad executions for an error budget of 1%
+	if status.LongWindow.BurnRate < 19.99 || status.LongWindow.BurnRate > 20.01 {
+		t.Fatalf("Invalid burn rate %f", status.LongWindow.BurnRate)
+	}
+	if status.ShortWindow.Executions != 0 || status.Burning {
+		t.Fatalf("Invalid SLO status %+v", status)
+	}
+	add(time.Minute, false, 10)
+	status, err = store.GetSLO("foo", now)
+	if err != nil {
+		t.Fatalf("Fail to get the SLO\n%v", err)
+	}
+	if status.ShortWindow.Executions != 1 || !status.Burning {
+		t.Fatalf("The SLO should be burning %+v", status)
+	}
+	if result := status.Result(); result.Name != "foo-slo" || result.Success || result.Source != SourceSLO {
+		t.Fatalf("Invalid SLO result %+v", result)
+	}
+	if len(store.ListSLO(now)) != 1 {
+		t.Fatalf("Invalid SLO list")
+	}
+	// the counters are rebuilt from the history when the SLO changes
+	updated := *slo
+	updated.Objective = 90
+	store.Add(&healthcheck.Result{
+		Name:                 "foo",
+		Success:              true,
+		Duration:             10,
+		HealthcheckTimestamp: now.Unix(),
+		SLO:                  &updated,
+	})
+	status, err = store.GetSLO("foo", now)
+	if err != nil {
+		t.Fatalf("Fail to get the SLO\n%v", err)
+	}
+	if status.SLO.Objective != 90 || status.LongWindow.Executions != 12 || status.LongWindow.Bad != 3 {
+		t.Fatalf("Invalid SLO status %+v", status)
+	}
+	store.Add(&healthcheck.Result{Name: "bar", Success: true, HealthcheckTimestamp: now.Unix()})
+	if _, err := store.GetSLO("bar", now); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if len(store.ListSLO(now)) != 1 {
+		t.Fatalf("Invalid SLO list")
+	}
+}
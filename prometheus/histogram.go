@@ -0,0 +1,76 @@
+package prometheus
+
+import (
+	"errors"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// NativeHistograms the configuration of the native histograms of the
+// healthchecks durations. Their exponential buckets keep an accurate
+// distribution for the latencies spanning microseconds to seconds.
+// The native histograms are only exposed using the protobuf format.
+type NativeHistograms struct {
+	// BucketFactor the maximum growth factor between two consecutive
+	// buckets, 1.1 by default
+	BucketFactor float64 `yaml:"bucket-factor"`
+	// MaxBuckets the maximum number of buckets of each histogram, the
+	// resolution being reduced above, 160 by default
+	MaxBuckets uint32 `yaml:"max-buckets"`
+	// MinResetDuration the histograms exceeding MaxBuckets are reset
+	// instead if they were not reset since this duration, 1h by default
+	MinResetDuration time.Duration `yaml:"min-reset-duration"`
+	// ClassicBuckets also exposes the fixed buckets, for the scrapers
+	// not supporting the native histograms
+	ClassicBuckets bool `yaml:"classic-buckets"`
+}
+
+// UnmarshalYAML parses the native histograms configuration from YAML
+func (n *NativeHistograms) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawNativeHistograms NativeHistograms
+	raw := rawNativeHistograms{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	if raw.BucketFactor == 0 {
+		raw.BucketFactor = 1.1
+	}
+	if raw.BucketFactor <= 1 {
+		return errors.New("The native histograms bucket-factor should be greater than 1")
+	}
+	if raw.MaxBuckets == 0 {
+		raw.MaxBuckets = 160
+	}
+	if raw.MinResetDuration == 0 {
+		raw.MinResetDuration = time.Hour
+	}
+	if raw.MinResetDuration < 0 {
+		return errors.New("The native histograms min-reset-duration should be positive")
+	}
+	*n = NativeHistograms(raw)
+	return nil
+}
+
+// SetNativeHistograms exposes the histograms of the healthchecks durations
+// created afterwards as native histograms. The histograms use the fixed
+// buckets if the configuration is nil.
+func (p *Prometheus) SetNativeHistograms(config *NativeHistograms) {
+	p.nativeHistograms = config
+}
+
+// DurationHistogramOpts returns the options of an histogram of the
+// healthchecks durations, using the native histograms if configured
+func (p *Prometheus) DurationHistogramOpts(opts prom.HistogramOpts) prom.HistogramOpts {
+	config := p.nativeHistograms
+	if config == nil {
+		return opts
+	}
+	if !config.ClassicBuckets {
+		opts.Buckets = nil
+	}
+	opts.NativeHistogramBucketFactor = config.BucketFactor
+	opts.NativeHistogramMaxBucketNumber = config.MaxBuckets
+	opts.NativeHistogramMinResetDuration = config.MinResetDuration
+	return opts
+}
@@ -0,0 +1,83 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+)
+
+func TestNativeHistogramsConfig(t *testing.T) {
+	var config NativeHistograms
+	err := yaml.Unmarshal([]byte("classic-buckets: true"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration:\n%v", err)
+	}
+	expected := NativeHistograms{
+		BucketFactor:     1.1,
+		MaxBuckets:       160,
+		MinResetDuration: time.Hour,
+		ClassicBuckets:   true,
+	}
+	if config != expected {
+		t.Fatalf("Invalid configuration %+v", config)
+	}
+	for _, invalid := range []string{"bucket-factor: 0.5", "min-reset-duration: -1s"} {
+		err := yaml.Unmarshal([]byte(invalid), &config)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", invalid)
+		}
+	}
+}
+
+func TestDurationHistogramOpts(t *testing.T) {
+	p, err := New()
+	if err != nil {
+		t.Fatalf("Fail to create the component:\n%v", err)
+	}
+	opts := prom.HistogramOpts{
+		Name:    "duration_seconds",
+		Help:    "duration",
+		Buckets: []float64{0.1, 1},
+	}
+	histo := prom.NewHistogram(p.DurationHistogramOpts(opts))
+	if err := p.Register(histo); err != nil {
+		t.Fatalf("Fail to register the histogram:\n%v", err)
+	}
+	p.SetNativeHistograms(&NativeHistograms{BucketFactor: 1.1, MaxBuckets: 160})
+	nativeOpts := opts
+	nativeOpts.Name = "native_duration_seconds"
+	native := prom.NewHistogram(p.DurationHistogramOpts(nativeOpts))
+	if err := p.Register(native); err != nil {
+		t.Fatalf("Fail to register the histogram:\n%v", err)
+	}
+	for _, value := range []float64{0.000005, 0.00002, 0.3, 2} {
+		histo.Observe(value)
+		native.Observe(value)
+	}
+	families, err := p.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics:\n%v", err)
+	}
+	found := 0
+	for _, family := range families {
+		switch family.GetName() {
+		case "duration_seconds":
+			h := family.GetMetric()[0].GetHistogram()
+			if len(h.GetBucket()) != 2 || h.GetSchema() != 0 || len(h.GetPositiveSpan()) != 0 {
+				t.Fatalf("Invalid classic histogram %v", h)
+			}
+			found++
+		case "native_duration_seconds":
+			h := family.GetMetric()[0].GetHistogram()
+			if len(h.GetBucket()) != 0 || h.GetSchema() != 3 || len(h.GetPositiveSpan()) == 0 {
+				t.Fatalf("Invalid native histogram %v", h)
+			}
+			found++
+		}
+	}
+	if found != 2 {
+		t.Fatalf("The histograms were not found")
+	}
+}
@@ -14,19 +14,32 @@ type Prometheus struct {
 	Config   *Configuration
 	Logger   *zap.Logger
 	Registry *prom.Registry
+	// registerer registers the metrics in the registry, adding the
+	// constant labels
+	registerer prom.Registerer
+	// nativeHistograms the native histograms of the healthchecks
+	// durations, nil for the fixed buckets
+	nativeHistograms *NativeHistograms
 }
 
 // New creates a new Prometheus component
 func New() (*Prometheus, error) {
+	return NewWithLabels(nil)
+}
+
+// NewWithLabels creates a new Prometheus component adding constant labels
+// to all the registered metrics
+func NewWithLabels(labels map[string]string) (*Prometheus, error) {
 	reg := prom.NewRegistry()
 	p := &Prometheus{
-		Registry: reg,
+		Registry:   reg,
+		registerer: prom.WrapRegistererWith(prom.Labels(labels), reg),
 	}
 	err := p.Register(collectors.NewGoCollector())
 	if err != nil {
 		return nil, err
 	}
-	err = reg.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	err = p.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 	if err != nil {
 		return nil, err
 	}
@@ -35,12 +48,12 @@ func New() (*Prometheus, error) {
 
 // Register adds a metric to the component
 func (p *Prometheus) Register(collector prom.Collector) error {
-	return p.Registry.Register(collector)
+	return p.registerer.Register(collector)
 }
 
 // Unregister removes a metric from the component
 func (p *Prometheus) Unregister(collector prom.Collector) {
-	p.Registry.Unregister(collector)
+	p.registerer.Unregister(collector)
 }
 
 // Handler returns the handler for the prometheus component
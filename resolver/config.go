@@ -0,0 +1,69 @@
+package resolver
+
+import (
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Configuration the caching resolver configuration
+type Configuration struct {
+	// Upstreams the DNS servers queried by the resolver (ip or ip:port).
+	// The servers of /etc/resolv.conf are used if empty.
+	Upstreams []string
+	// MinTTL and MaxTTL bound the TTL of the records
+	MinTTL time.Duration `yaml:"min-ttl"`
+	MaxTTL time.Duration `yaml:"max-ttl"`
+	// NegativeTTL the cache duration of the names not resolved by the
+	// upstreams
+	NegativeTTL time.Duration `yaml:"negative-ttl"`
+	// Timeout the timeout of the queries to an upstream
+	Timeout time.Duration
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the DNS cache configuration")
+	}
+	for i, upstream := range raw.Upstreams {
+		address, err := upstreamAddress(upstream)
+		if err != nil {
+			return err
+		}
+		raw.Upstreams[i] = address
+	}
+	if raw.MinTTL < 0 || raw.MaxTTL < 0 || raw.NegativeTTL < 0 || raw.Timeout < 0 {
+		return errors.New("The DNS cache durations should be positive")
+	}
+	if raw.MaxTTL == 0 {
+		raw.MaxTTL = time.Hour
+	}
+	if raw.MinTTL > raw.MaxTTL {
+		return errors.New("The DNS cache min-ttl should be lower than max-ttl")
+	}
+	if raw.NegativeTTL == 0 {
+		raw.NegativeTTL = 30 * time.Second
+	}
+	if raw.Timeout == 0 {
+		raw.Timeout = 2 * time.Second
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
+
+// upstreamAddress returns the address of an upstream, using the port 53
+// by default
+func upstreamAddress(upstream string) (string, error) {
+	if ip := net.ParseIP(upstream); ip != nil {
+		return net.JoinHostPort(upstream, "53"), nil
+	}
+	host, _, err := net.SplitHostPort(upstream)
+	if err != nil || net.ParseIP(host) == nil {
+		return "", errors.Errorf("Invalid DNS upstream %s, it should be an IP address with an optional port", upstream)
+	}
+	return upstream, nil
+}
@@ -0,0 +1,135 @@
+package resolver
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	typeA     uint16 = 1
+	typeCNAME uint16 = 5
+	typeAAAA  uint16 = 28
+	classINET uint16 = 1
+
+	// rcodeNameError the domain does not exist (NXDOMAIN)
+	rcodeNameError = 3
+
+	headerSize = 12
+)
+
+// response a parsed DNS response
+type response struct {
+	id        uint16
+	rcode     int
+	truncated bool
+	ips       []net.IP
+	// ttl the lowest TTL of the answers
+	ttl uint32
+}
+
+// buildQuery builds a recursive DNS query
+func buildQuery(id uint16, name string, qtype uint16) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if len(name) == 0 || len(name) > 253 {
+		return nil, errors.Errorf("Invalid domain name %s", name)
+	}
+	msg := make([]byte, headerSize, headerSize+len(name)+6)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	// recursion desired
+	binary.BigEndian.PutUint16(msg[2:], 0x0100)
+	binary.BigEndian.PutUint16(msg[4:], 1)
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, errors.Errorf("Invalid domain name %s", name)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, classINET)
+	return msg, nil
+}
+
+// skipName returns the offset following the name starting at offset
+func skipName(msg []byte, offset int) (int, error) {
+	for {
+		if offset >= len(msg) {
+			return 0, errors.New("Invalid DNS response: truncated name")
+		}
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			return offset + 1, nil
+		case length&0xC0 == 0xC0:
+			// compression pointer
+			return offset + 2, nil
+		default:
+			offset += length + 1
+		}
+	}
+}
+
+// parseResponse parses a DNS response, returning the A and AAAA records
+// of the answer section
+func parseResponse(msg []byte) (*response, error) {
+	if len(msg) < headerSize {
+		return nil, errors.New("Invalid DNS response: truncated header")
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&0x8000 == 0 {
+		return nil, errors.New("Invalid DNS response: not a response")
+	}
+	result := &response{
+		id:        binary.BigEndian.Uint16(msg[0:]),
+		rcode:     int(flags & 0x000F),
+		truncated: flags&0x0200 != 0,
+	}
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	answers := int(binary.BigEndian.Uint16(msg[6:]))
+	offset := headerSize
+	var err error
+	for i := 0; i < questions; i++ {
+		offset, err = skipName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset += 4
+	}
+	first := true
+	for i := 0; i < answers; i++ {
+		offset, err = skipName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		if offset+10 > len(msg) {
+			return nil, errors.New("Invalid DNS response: truncated record")
+		}
+		rtype := binary.BigEndian.Uint16(msg[offset:])
+		ttl := binary.BigEndian.Uint32(msg[offset+4:])
+		length := int(binary.BigEndian.Uint16(msg[offset+8:]))
+		offset += 10
+		if offset+length > len(msg) {
+			return nil, errors.New("Invalid DNS response: truncated record")
+		}
+		data := msg[offset : offset+length]
+		offset += length
+		switch {
+		case rtype == typeA && length == net.IPv4len:
+			result.ips = append(result.ips, net.IP(append([]byte{}, data...)))
+		case rtype == typeAAAA && length == net.IPv6len:
+			result.ips = append(result.ips, net.IP(append([]byte{}, data...)))
+		case rtype == typeCNAME:
+		default:
+			continue
+		}
+		if first || ttl < result.ttl {
+			result.ttl = ttl
+			first = false
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,286 @@
+package resolver
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// resolvConf the file containing the system DNS servers
+const resolvConf = "/etc/resolv.conf"
+
+// entry a cached resolution. The entry is pending until ready is closed.
+type entry struct {
+	ready   chan struct{}
+	addrs   []net.IPAddr
+	err     error
+	expires time.Time
+}
+
+// Resolver a caching DNS resolver. The resolutions are cached for the
+// TTL of the records, and the names not resolved by the upstreams for the
+// negative TTL. Concurrent resolutions of the same name are done once.
+type Resolver struct {
+	Logger *zap.Logger
+	Config *Configuration
+
+	upstreams []string
+	// fallback resolves the names unknown to the upstreams (hosts file,
+	// search domains...)
+	fallback func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+	lock      sync.Mutex
+	cache     map[string]*entry
+	lastPurge time.Time
+}
+
+// systemUpstreams returns the DNS servers of /etc/resolv.conf
+func systemUpstreams() ([]string, error) {
+	file, err := os.Open(resolvConf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read %s", resolvConf)
+	}
+	defer file.Close()
+	upstreams := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			address, err := upstreamAddress(fields[1])
+			if err == nil {
+				upstreams = append(upstreams, address)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "Fail to read %s", resolvConf)
+	}
+	if len(upstreams) == 0 {
+		return nil, fmt.Errorf("No DNS server found in %s", resolvConf)
+	}
+	return upstreams, nil
+}
+
+// New creates a new caching resolver
+func New(logger *zap.Logger, config *Configuration) (*Resolver, error) {
+	upstreams := config.Upstreams
+	if len(upstreams) == 0 {
+		var err error
+		upstreams, err = systemUpstreams()
+		if err != nil {
+			return nil, err
+		}
+	}
+	logger.Info(fmt.Sprintf("Resolving the healthchecks targets using %s", strings.Join(upstreams, ", ")))
+	return &Resolver{
+		Logger:    logger,
+		Config:    config,
+		upstreams: upstreams,
+		fallback:  net.DefaultResolver.LookupIPAddr,
+		cache:     make(map[string]*entry),
+	}, nil
+}
+
+// purge removes the expired entries.
+// The function is *not* thread-safe.
+func (r *Resolver) purge(now time.Time) {
+	if now.Sub(r.lastPurge) < r.Config.MaxTTL {
+		return
+	}
+	for host, e := range r.cache {
+		select {
+		case <-e.ready:
+			if !now.Before(e.expires) {
+				delete(r.cache, host)
+			}
+		default:
+		}
+	}
+	r.lastPurge = now
+}
+
+// LookupIPAddr resolves a host, using the cache if possible
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+	key := strings.ToLower(strings.TrimSuffix(host, "."))
+	now := time.Now()
+	r.lock.Lock()
+	r.purge(now)
+	e, ok := r.cache[key]
+	if ok {
+		select {
+		case <-e.ready:
+			if now.Before(e.expires) {
+				r.lock.Unlock()
+				return e.addrs, e.err
+			}
+			ok = false
+		default:
+		}
+	}
+	if !ok {
+		e = &entry{ready: make(chan struct{})}
+		r.cache[key] = e
+		// the resolution does not depend on the context of the caller,
+		// it is shared with the concurrent callers
+		go r.resolve(key, e)
+	}
+	r.lock.Unlock()
+	select {
+	case <-e.ready:
+		return e.addrs, e.err
+	case <-ctx.Done():
+		return nil, &net.DNSError{Err: ctx.Err().Error(), Name: host, IsTimeout: true}
+	}
+}
+
+// resolve resolves a host and fills the cache entry. The entry is removed
+// if the upstreams could not be reached.
+func (r *Resolver) resolve(host string, e *entry) {
+	defer close(e.ready)
+	timeout := time.Duration(len(r.upstreams)) * r.Config.Timeout * 2
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var ips []net.IP
+	var ttl uint32
+	found := false
+	for _, qtype := range []uint16{typeA, typeAAAA} {
+		resp, err := r.query(ctx, host, qtype)
+		if err != nil {
+			r.Logger.Error(fmt.Sprintf("Fail to resolve %s: %s", host, err.Error()))
+			e.err = &net.DNSError{Err: err.Error(), Name: host, IsTemporary: true}
+			r.lock.Lock()
+			delete(r.cache, host)
+			r.lock.Unlock()
+			return
+		}
+		if len(resp.ips) == 0 {
+			continue
+		}
+		if !found || resp.ttl < ttl {
+			ttl = resp.ttl
+		}
+		found = true
+		ips = append(ips, resp.ips...)
+	}
+	now := time.Now()
+	if !found {
+		addrs, err := r.fallback(ctx, host)
+		e.expires = now.Add(r.Config.NegativeTTL)
+		if err != nil {
+			e.err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+			return
+		}
+		e.addrs = addrs
+		return
+	}
+	duration := time.Duration(ttl) * time.Second
+	if duration < r.Config.MinTTL {
+		duration = r.Config.MinTTL
+	}
+	if duration > r.Config.MaxTTL {
+		duration = r.Config.MaxTTL
+	}
+	e.expires = now.Add(duration)
+	e.addrs = make([]net.IPAddr, 0, len(ips))
+	for _, ip := range ips {
+		e.addrs = append(e.addrs, net.IPAddr{IP: ip})
+	}
+}
+
+// query sends a query to the upstreams until one of them answers
+func (r *Resolver) query(ctx context.Context, host string, qtype uint16) (*response, error) {
+	id := uint16(rand.Intn(1 << 16))
+	query, err := buildQuery(id, host, qtype)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, upstream := range r.upstreams {
+		resp, err := r.exchange(ctx, "udp", upstream, query, id)
+		if err == nil && resp.truncated {
+			resp, err = r.exchange(ctx, "tcp", upstream, query, id)
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.rcode != 0 && resp.rcode != rcodeNameError {
+			lastErr = fmt.Errorf("The DNS server %s returned the error code %d", upstream, resp.rcode)
+			continue
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
+
+// exchange sends a query to an upstream and reads its response
+func (r *Resolver) exchange(ctx context.Context, network string, upstream string, query []byte, id uint16) (*response, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.Config.Timeout)
+	defer cancel()
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, network, upstream)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to connect to the DNS server %s", upstream)
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to set the deadline of the DNS query")
+	}
+	if network == "tcp" {
+		msg := binary.BigEndian.AppendUint16(make([]byte, 0, len(query)+2), uint16(len(query)))
+		_, err = conn.Write(append(msg, query...))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to send the DNS query to %s", upstream)
+		}
+		length := make([]byte, 2)
+		_, err = io.ReadFull(conn, length)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to read the DNS response from %s", upstream)
+		}
+		msg = make([]byte, binary.BigEndian.Uint16(length))
+		_, err = io.ReadFull(conn, msg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to read the DNS response from %s", upstream)
+		}
+		resp, err := parseResponse(msg)
+		if err != nil {
+			return nil, err
+		}
+		if resp.id != id {
+			return nil, fmt.Errorf("Invalid DNS response ID from %s", upstream)
+		}
+		return resp, nil
+	}
+	_, err = conn.Write(query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to send the DNS query to %s", upstream)
+	}
+	buffer := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to read the DNS response from %s", upstream)
+		}
+		resp, err := parseResponse(buffer[:n])
+		// ignore the invalid or unexpected responses
+		if err == nil && resp.id == id {
+			return resp, nil
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package resolver
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+// dnsServer a fake DNS server answering with an A record for
+// foo.example.com and NXDOMAIN for the other names
+type dnsServer struct {
+	conn    net.PacketConn
+	lock    sync.Mutex
+	queries int
+}
+
+func newDNSServer(t *testing.T, ttl uint32) *dnsServer {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the DNS server: %v", err)
+	}
+	server := &dnsServer{conn: conn}
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			server.lock.Lock()
+			server.queries++
+			server.lock.Unlock()
+			query := buffer[:n]
+			end, _ := skipName(query, headerSize)
+			qname := query[headerSize:end]
+			qtype := binary.BigEndian.Uint16(query[end:])
+			msg := append([]byte{}, query[:end+4]...)
+			// response, recursion desired and available
+			binary.BigEndian.PutUint16(msg[2:], 0x8180)
+			if string(qname) != "\x03foo\x07example\x03com\x00" {
+				binary.BigEndian.PutUint16(msg[2:], 0x8183)
+			} else if qtype == typeA {
+				binary.BigEndian.PutUint16(msg[6:], 1)
+				// pointer to the question name
+				msg = append(msg, 0xC0, headerSize)
+				msg = binary.BigEndian.AppendUint16(msg, typeA)
+				msg = binary.BigEndian.AppendUint16(msg, classINET)
+				msg = binary.BigEndian.AppendUint32(msg, ttl)
+				msg = binary.BigEndian.AppendUint16(msg, 4)
+				msg = append(msg, 127, 0, 0, 1)
+			}
+			_, _ = conn.WriteTo(msg, addr)
+		}
+	}()
+	return server
+}
+
+func (s *dnsServer) count() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.queries
+}
+
+func TestConfiguration(t *testing.T) {
+	var config Configuration
+	err := yaml.Unmarshal([]byte(`upstreams: ["127.0.0.1", "[::1]:5353"]`), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration: %v", err)
+	}
+	if config.Upstreams[0] != "127.0.0.1:53" || config.Upstreams[1] != "[::1]:5353" {
+		t.Fatalf("Invalid upstreams %v", config.Upstreams)
+	}
+	if config.MaxTTL != time.Hour || config.NegativeTTL != 30*time.Second || config.Timeout != 2*time.Second {
+		t.Fatalf("Invalid default values %+v", config)
+	}
+	for _, c := range []string{`upstreams: ["dns.example.com"]`, `min-ttl: 2h`, `timeout: -1s`} {
+		err := yaml.Unmarshal([]byte(c), &config)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", c)
+		}
+	}
+}
+
+func TestLookupIPAddr(t *testing.T) {
+	server := newDNSServer(t, 1)
+	defer server.conn.Close()
+	resolver, err := New(zap.NewExample(), &Configuration{
+		Upstreams:   []string{server.conn.LocalAddr().String()},
+		MaxTTL:      time.Hour,
+		NegativeTTL: time.Hour,
+		Timeout:     time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the resolver: %v", err)
+	}
+	fallbacks := 0
+	resolver.fallback = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		fallbacks++
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addrs, err := resolver.LookupIPAddr(ctx, "Foo.example.com.")
+			if err != nil {
+				t.Errorf("Fail to resolve: %v", err)
+				return
+			}
+			if len(addrs) != 1 || !addrs[0].IP.Equal(net.IPv4(127, 0, 0, 1)) {
+				t.Errorf("Invalid addresses %v", addrs)
+			}
+		}()
+	}
+	wg.Wait()
+	// the concurrent resolutions are done once (A and AAAA queries)
+	if server.count() != 2 {
+		t.Fatalf("Invalid number of queries %d", server.count())
+	}
+	// the TTL is respected
+	time.Sleep(1100 * time.Millisecond)
+	_, err = resolver.LookupIPAddr(ctx, "foo.example.com")
+	if err != nil {
+		t.Fatalf("Fail to resolve: %v", err)
+	}
+	if server.count() != 4 {
+		t.Fatalf("The entry should be expired, %d queries", server.count())
+	}
+	// negative caching
+	for i := 0; i < 2; i++ {
+		_, err = resolver.LookupIPAddr(ctx, "bar.example.com")
+		if err == nil {
+			t.Fatalf("Was expecting an error")
+		}
+		if dnsErr, ok := err.(*net.DNSError); !ok || !dnsErr.IsNotFound {
+			t.Fatalf("Invalid error %v", err)
+		}
+	}
+	if server.count() != 6 || fallbacks != 1 {
+		t.Fatalf("The negative result should be cached, %d queries", server.count())
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, "10.0.0.1")
+	if err != nil || len(addrs) != 1 || server.count() != 6 {
+		t.Fatalf("The IP addresses should not be resolved")
+	}
+}
+
+func TestLookupIPAddrUnreachable(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen: %v", err)
+	}
+	// the server never answers
+	defer conn.Close()
+	resolver, err := New(zap.NewExample(), &Configuration{
+		Upstreams:   []string{conn.LocalAddr().String()},
+		MaxTTL:      time.Hour,
+		NegativeTTL: time.Hour,
+		Timeout:     100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the resolver: %v", err)
+	}
+	_, err = resolver.LookupIPAddr(context.Background(), "foo.example.com")
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	// the failures are not cached
+	resolver.lock.Lock()
+	defer resolver.lock.Unlock()
+	if len(resolver.cache) != 0 {
+		t.Fatalf("The failure should not be cached")
+	}
+}
@@ -0,0 +1,157 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// FileSuffix the suffix of the fields read from a file. For example,
+	// password_file: /etc/secret sets the password field to the content
+	// of /etc/secret.
+	FileSuffix = "_file"
+	// VaultSuffix the suffix of the fields read from Vault. The value is
+	// the secret path and the key, separated by #. For example,
+	// password_vault: secret/data/db#password.
+	VaultSuffix = "_vault"
+)
+
+// resolver replaces the secrets references in a YAML document
+type resolver struct {
+	suffix string
+	fetch  func(reference string) (string, error)
+}
+
+// walk replaces the references in a value, and returns true if a
+// reference was replaced
+func (r *resolver) walk(value interface{}) (interface{}, bool, error) {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		modified := false
+		result := make(yaml.MapSlice, 0, len(v))
+		for _, item := range v {
+			key, ok := item.Key.(string)
+			if ok && strings.HasSuffix(key, r.suffix) && len(key) > len(r.suffix) {
+				reference, ok := item.Value.(string)
+				if !ok {
+					return nil, false, fmt.Errorf("The field %s should be a string", key)
+				}
+				secret, err := r.fetch(reference)
+				if err != nil {
+					return nil, false, errors.Wrapf(err, "Fail to resolve the field %s", key)
+				}
+				result = append(result, yaml.MapItem{Key: strings.TrimSuffix(key, r.suffix), Value: secret})
+				modified = true
+				continue
+			}
+			newValue, replaced, err := r.walk(item.Value)
+			if err != nil {
+				return nil, false, err
+			}
+			modified = modified || replaced
+			result = append(result, yaml.MapItem{Key: item.Key, Value: newValue})
+		}
+		return result, modified, nil
+	case []interface{}:
+		modified := false
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			newValue, replaced, err := r.walk(item)
+			if err != nil {
+				return nil, false, err
+			}
+			modified = modified || replaced
+			result = append(result, newValue)
+		}
+		return result, modified, nil
+	}
+	return value, false, nil
+}
+
+// resolve replaces the references in a YAML document. The document is
+// returned unmodified if it does not contain references.
+func (r *resolver) resolve(content []byte) ([]byte, error) {
+	var document yaml.MapSlice
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, errors.Wrap(err, "Fail to read the yaml document")
+	}
+	result, modified, err := r.walk(document)
+	if err != nil {
+		return nil, err
+	}
+	if !modified {
+		return content, nil
+	}
+	return yaml.Marshal(result)
+}
+
+// ResolveFiles replaces the fields suffixed by _file by the content of
+// the files. The trailing newlines are removed.
+func ResolveFiles(content []byte) ([]byte, error) {
+	r := resolver{
+		suffix: FileSuffix,
+		fetch: func(path string) (string, error) {
+			secret, err := os.ReadFile(path)
+			if err != nil {
+				return "", errors.Wrapf(err, "Fail to read the secret file %s", path)
+			}
+			return strings.TrimRight(string(secret), "\r\n"), nil
+		},
+	}
+	return r.resolve(content)
+}
+
+// ResolveVault replaces the fields suffixed by _vault by the secrets read
+// from Vault. vault can be nil if Vault is not configured, an error is
+// then returned if the document references Vault secrets.
+func ResolveVault(content []byte, vault *Vault) ([]byte, error) {
+	secrets := make(map[string]map[string]interface{})
+	r := resolver{
+		suffix: VaultSuffix,
+		fetch: func(reference string) (string, error) {
+			if vault == nil {
+				return "", errors.New("Vault is not configured")
+			}
+			parts := strings.SplitN(reference, "#", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return "", fmt.Errorf("Invalid Vault reference %s, the format is <path>#<key>", reference)
+			}
+			path, key := parts[0], parts[1]
+			secret, ok := secrets[path]
+			if !ok {
+				var err error
+				secret, err = vault.Read(path)
+				if err != nil {
+					return "", err
+				}
+				secrets[path] = secret
+			}
+			value, ok := secret[key]
+			if !ok {
+				return "", fmt.Errorf("The key %s does not exist in the Vault secret %s", key, path)
+			}
+			return fmt.Sprintf("%v", value), nil
+		},
+	}
+	return r.resolve(content)
+}
+
+// Placeholders replaces the fields suffixed by _file and _vault by a
+// placeholder, in order to validate a configuration without reading the
+// secrets.
+func Placeholders(content []byte, placeholder string) ([]byte, error) {
+	fetch := func(reference string) (string, error) {
+		return placeholder, nil
+	}
+	r := resolver{suffix: FileSuffix, fetch: fetch}
+	content, err := r.resolve(content)
+	if err != nil {
+		return nil, err
+	}
+	r = resolver{suffix: VaultSuffix, fetch: fetch}
+	return r.resolve(content)
+}
@@ -0,0 +1,148 @@
+package secret
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+type testConfiguration struct {
+	HTTP struct {
+		BasicAuth struct {
+			Username string
+			Password string
+		} `yaml:"basic-auth"`
+	}
+	Exporters []struct {
+		Name    string
+		Headers map[string]string
+	}
+}
+
+func TestResolveFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "password")
+	err := os.WriteFile(path, []byte("secret\n"), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the secret: %s", err.Error())
+	}
+	in := fmt.Sprintf(`
+http:
+  basic-auth:
+    username: "foo"
+    password_file: "%s"
+exporters:
+  - name: "bar"
+    headers:
+      Authorization_file: "%s"
+`, path, path)
+	content, err := ResolveFiles([]byte(in))
+	if err != nil {
+		t.Fatalf("Fail to resolve the secrets: %s", err.Error())
+	}
+	var config testConfiguration
+	err = yaml.UnmarshalStrict(content, &config)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %s", err.Error())
+	}
+	if config.HTTP.BasicAuth.Password != "secret" || config.HTTP.BasicAuth.Username != "foo" {
+		t.Fatalf("Invalid basic auth %v", config.HTTP.BasicAuth)
+	}
+	if config.Exporters[0].Headers["Authorization"] != "secret" {
+		t.Fatalf("Invalid headers %v", config.Exporters[0].Headers)
+	}
+	in = "content: foo"
+	content, err = ResolveFiles([]byte(in))
+	if err != nil {
+		t.Fatalf("Fail to resolve the secrets: %s", err.Error())
+	}
+	if string(content) != in {
+		t.Fatalf("The content should not be modified: %s", string(content))
+	}
+	_, err = ResolveFiles([]byte("password_file: /does/not/exist"))
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestResolveVault(t *testing.T) {
+	renewed := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/http":
+			fmt.Fprint(w, `{"data": {"data": {"password": "kv2"}, "metadata": {"version": 1}}}`)
+		case "/v1/kv/exporter":
+			fmt.Fprint(w, `{"data": {"token": "kv1"}}`)
+		case "/v1/auth/token/renew-self":
+			if r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			renewed = true
+			fmt.Fprint(w, `{"auth": {"client_token": "token"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	vault, err := NewVault(&VaultConfiguration{Address: ts.URL, Token: "token"})
+	if err != nil {
+		t.Fatalf("Fail to create the Vault client: %s", err.Error())
+	}
+	in := `
+http:
+  basic-auth:
+    username: "foo"
+    password_vault: "secret/data/http#password"
+exporters:
+  - name: "bar"
+    headers:
+      Authorization_vault: "kv/exporter#token"
+`
+	content, err := ResolveVault([]byte(in), vault)
+	if err != nil {
+		t.Fatalf("Fail to resolve the secrets: %s", err.Error())
+	}
+	var config testConfiguration
+	err = yaml.UnmarshalStrict(content, &config)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %s", err.Error())
+	}
+	if config.HTTP.BasicAuth.Password != "kv2" {
+		t.Fatalf("Invalid basic auth %v", config.HTTP.BasicAuth)
+	}
+	if config.Exporters[0].Headers["Authorization"] != "kv1" {
+		t.Fatalf("Invalid headers %v", config.Exporters[0].Headers)
+	}
+	errorCases := []string{
+		"password_vault: secret/data/http",
+		"password_vault: secret/data/http#missing",
+		"password_vault: secret/data/missing#password",
+	}
+	for _, c := range errorCases {
+		_, err := ResolveVault([]byte(c), vault)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", c)
+		}
+	}
+	_, err = ResolveVault([]byte("password_vault: secret/data/http#password"), nil)
+	if err == nil {
+		t.Fatalf("Was expecting an error when Vault is not configured")
+	}
+	err = vault.RenewToken()
+	if err != nil {
+		t.Fatalf("Fail to renew the token: %s", err.Error())
+	}
+	if !renewed {
+		t.Fatalf("The token was not renewed")
+	}
+}
@@ -0,0 +1,155 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/tls"
+)
+
+// DefaultRenewInterval the default interval between two renewals of the
+// Vault token and secrets
+const DefaultRenewInterval = healthcheck.Duration(5 * time.Minute)
+
+// VaultConfiguration the HashiCorp Vault configuration.
+// The address and the token default to the VAULT_ADDR and VAULT_TOKEN
+// environment variables.
+type VaultConfiguration struct {
+	Address       string
+	Token         string
+	Namespace     string
+	RenewInterval healthcheck.Duration `yaml:"renew-interval"`
+	Key           string
+	Cert          string
+	Cacert        string
+	Insecure      bool
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *VaultConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration VaultConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the Vault configuration")
+	}
+	if raw.Address == "" {
+		raw.Address = os.Getenv("VAULT_ADDR")
+	}
+	if raw.Token == "" {
+		raw.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if raw.Address == "" {
+		return errors.New("The Vault address is missing")
+	}
+	if raw.Token == "" {
+		return errors.New("The Vault token is missing")
+	}
+	if raw.RenewInterval == 0 {
+		raw.RenewInterval = DefaultRenewInterval
+	}
+	if raw.RenewInterval < healthcheck.Duration(10*time.Second) {
+		return errors.New("The Vault renew interval should be greater or equal than 10 seconds")
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	*configuration = VaultConfiguration(raw)
+	return nil
+}
+
+// Vault a client reading secrets from HashiCorp Vault
+type Vault struct {
+	Config *VaultConfiguration
+	Client *http.Client
+	URL    string
+}
+
+// NewVault creates a new Vault client
+func NewVault(config *VaultConfiguration) (*Vault, error) {
+	tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, "", config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	return &Vault{
+		Config: config,
+		URL:    strings.TrimSuffix(config.Address, "/"),
+		Client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+			Timeout: time.Second * 10,
+		},
+	}, nil
+}
+
+// request sends a request to the Vault API and decodes the response data
+func (v *Vault) request(method string, path string) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	reqURL := fmt.Sprintf("%s/v1/%s", v.URL, strings.TrimPrefix(path, "/"))
+	var body io.Reader
+	if method == "POST" {
+		body = bytes.NewBufferString("{}")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Vault: fail to create request for %s", reqURL)
+	}
+	req.Header.Set("User-Agent", "Cabourotte")
+	req.Header.Set("X-Vault-Token", v.Config.Token)
+	if v.Config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Config.Namespace)
+	}
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Vault: fail to send request to %s", reqURL)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read request body")
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Vault: request to %s failed, status %d, body %s", reqURL, resp.StatusCode, string(respBody))
+	}
+	var payload struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &payload); err != nil {
+		return nil, errors.Wrapf(err, "Vault: fail to convert the payload from json")
+	}
+	return payload.Data, nil
+}
+
+// Read reads a secret. Both the KV version 1 and version 2 engines are
+// supported.
+func (v *Vault) Read(path string) (map[string]interface{}, error) {
+	data, err := v.request("GET", path)
+	if err != nil {
+		return nil, err
+	}
+	// the KV version 2 engine wraps the secret with its metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			return nested, nil
+		}
+	}
+	return data, nil
+}
+
+// RenewToken renews the Vault token
+func (v *Vault) RenewToken() error {
+	_, err := v.request("POST", "auth/token/renew-self")
+	return err
+}
@@ -0,0 +1,59 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SignatureHeader the header containing the signature of the payload
+	SignatureHeader = "X-Cabourotte-Signature"
+	// TimestampHeader the header containing the unix timestamp of the
+	// signature
+	TimestampHeader = "X-Cabourotte-Timestamp"
+	// prefix the prefix of the signatures, identifying the algorithm
+	prefix = "sha256="
+)
+
+// Sign computes the signature of a payload sent at the given unix
+// timestamp: the HMAC-SHA256 of `<timestamp>.<payload>`, hex encoded and
+// prefixed by `sha256=`. Including the timestamp in the signature allows
+// the receivers to reject the replayed payloads.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return prefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify verifies the signature and the timestamp headers of a payload.
+// The payloads signed more than the tolerance before or after now are
+// rejected.
+func Verify(secret string, timestamp string, signature string, payload []byte, now time.Time, tolerance time.Duration) error {
+	if timestamp == "" || signature == "" {
+		return errors.New("The payload signature is missing")
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid signature timestamp %s", timestamp)
+	}
+	skew := now.Sub(time.Unix(ts, 0))
+	if skew > tolerance || skew < -tolerance {
+		return fmt.Errorf("The signature timestamp %s is outside of the tolerance of %s", timestamp, tolerance)
+	}
+	if !strings.HasPrefix(signature, prefix) {
+		return errors.New("Invalid signature algorithm, sha256 expected")
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, ts, payload))) {
+		return errors.New("Invalid payload signature")
+	}
+	return nil
+}
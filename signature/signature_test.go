@@ -0,0 +1,43 @@
+package signature
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	payload := []byte(`[{"name":"foo"}]`)
+	signature := Sign("secret", now.Unix(), payload)
+	timestamp := fmt.Sprintf("%d", now.Unix())
+	cases := []struct {
+		secret    string
+		timestamp string
+		signature string
+		payload   []byte
+		now       time.Time
+		valid     bool
+	}{
+		{secret: "secret", timestamp: timestamp, signature: signature, payload: payload, now: now, valid: true},
+		{secret: "secret", timestamp: timestamp, signature: signature, payload: payload, now: now.Add(4 * time.Minute), valid: true},
+		{secret: "secret", timestamp: timestamp, signature: signature, payload: payload, now: now.Add(-4 * time.Minute), valid: true},
+		{secret: "other", timestamp: timestamp, signature: signature, payload: payload, now: now, valid: false},
+		{secret: "secret", timestamp: timestamp, signature: signature, payload: []byte(`[]`), now: now, valid: false},
+		{secret: "secret", timestamp: timestamp, signature: signature, payload: payload, now: now.Add(10 * time.Minute), valid: false},
+		{secret: "secret", timestamp: "1700000001", signature: signature, payload: payload, now: now, valid: false},
+		{secret: "secret", timestamp: "", signature: signature, payload: payload, now: now, valid: false},
+		{secret: "secret", timestamp: timestamp, signature: "", payload: payload, now: now, valid: false},
+		{secret: "secret", timestamp: "foo", signature: signature, payload: payload, now: now, valid: false},
+		{secret: "secret", timestamp: timestamp, signature: "md5=abc", payload: payload, now: now, valid: false},
+	}
+	for i, c := range cases {
+		err := Verify(c.secret, c.timestamp, c.signature, c.payload, c.now, 5*time.Minute)
+		if c.valid && err != nil {
+			t.Fatalf("Case %d: the signature should be valid: %s", i, err.Error())
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Case %d: was expecting an error", i)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// StateReady notifies that the service startup is finished
+	StateReady = "READY=1"
+	// StateReloading notifies that the service is reloading its
+	// configuration
+	StateReloading = "RELOADING=1"
+	// StateStopping notifies that the service is shutting down
+	StateStopping = "STOPPING=1"
+	// StateWatchdog updates the watchdog timestamp
+	StateWatchdog = "WATCHDOG=1"
+)
+
+// Notify sends a state notification to systemd (sd_notify). It returns
+// false if the service was not started by systemd with Type=notify (the
+// NOTIFY_SOCKET variable is not set).
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// abstract sockets are prefixed by @
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, errors.Wrapf(err, "Fail to connect to the systemd notification socket")
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	if err != nil {
+		return false, errors.Wrapf(err, "Fail to send the notification %s to systemd", state)
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the watchdog timeout configured by systemd
+// (WatchdogSec), or 0 if the watchdog is disabled for this process
+func WatchdogInterval() (time.Duration, error) {
+	value := os.Getenv("WATCHDOG_USEC")
+	if value == "" {
+		return 0, nil
+	}
+	usec, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, errors.Errorf("Invalid WATCHDOG_USEC value %s", value)
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" {
+		p, err := strconv.Atoi(pid)
+		if err != nil {
+			return 0, errors.Errorf("Invalid WATCHDOG_PID value %s", pid)
+		}
+		if p != os.Getpid() {
+			return 0, nil
+		}
+	}
+	return time.Duration(usec) * time.Microsecond, nil
+}
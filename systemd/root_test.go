@@ -0,0 +1,75 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	sent, err := Notify(StateReady)
+	if err != nil || sent {
+		t.Fatalf("The notification should be ignored without socket: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Fail to create the socket\n%v", err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+	sent, err = Notify(StateReady)
+	if err != nil || !sent {
+		t.Fatalf("Fail to send the notification\n%v", err)
+	}
+	buffer := make([]byte, 64)
+	err = conn.SetReadDeadline(time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("Fail to set the deadline\n%v", err)
+	}
+	n, err := conn.Read(buffer)
+	if err != nil {
+		t.Fatalf("Fail to read the notification\n%v", err)
+	}
+	if string(buffer[:n]) != StateReady {
+		t.Fatalf("Invalid notification %s", string(buffer[:n]))
+	}
+	t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "missing.sock"))
+	_, err = Notify(StateReady)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	cases := []struct {
+		usec     string
+		pid      string
+		expected time.Duration
+		err      bool
+	}{
+		{"", "", 0, false},
+		{"30000000", "", 30 * time.Second, false},
+		{"30000000", pid, 30 * time.Second, false},
+		{"30000000", "1", 0, false},
+		{"abc", "", 0, true},
+		{"-1", "", 0, true},
+		{"30000000", "abc", 0, true},
+	}
+	for _, c := range cases {
+		t.Setenv("WATCHDOG_USEC", c.usec)
+		t.Setenv("WATCHDOG_PID", c.pid)
+		interval, err := WatchdogInterval()
+		if (err != nil) != c.err {
+			t.Fatalf("Invalid error for %+v: %v", c, err)
+		}
+		if interval != c.expected {
+			t.Fatalf("Invalid interval %s for %+v", interval, c)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultServiceName the default service name of the spans
+const DefaultServiceName = "cabourotte"
+
+// Configuration the tracing configuration. The spans are exported using
+// OTLP over HTTP with JSON encoding.
+type Configuration struct {
+	// Endpoint the OTLP collector URL, the spans are sent to its
+	// /v1/traces path
+	Endpoint    string
+	ServiceName string `yaml:"service-name"`
+	Headers     map[string]string
+	// SampleRatio the ratio of the healthchecks executions which are
+	// traced, between 0 and 1
+	SampleRatio float64 `yaml:"sample-ratio"`
+	// Interval the spans are exported every interval
+	Interval time.Duration
+	// Timeout the timeout of the export requests
+	Timeout  time.Duration
+	Key      string
+	Cert     string
+	Cacert   string
+	Insecure bool
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{
+		SampleRatio: 1,
+	}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the tracing configuration")
+	}
+	if raw.Endpoint == "" {
+		return errors.New("The tracing endpoint is missing")
+	}
+	endpoint, err := url.Parse(raw.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return errors.New("Invalid tracing endpoint, it should be an http or https URL")
+	}
+	if raw.ServiceName == "" {
+		raw.ServiceName = DefaultServiceName
+	}
+	if raw.SampleRatio < 0 || raw.SampleRatio > 1 {
+		return errors.New("The tracing sample ratio should be between 0 and 1")
+	}
+	if raw.Interval < 0 || raw.Timeout < 0 {
+		return errors.New("The tracing interval and timeout should be positive")
+	}
+	if raw.Interval == 0 {
+		raw.Interval = 5 * time.Second
+	}
+	if raw.Timeout == 0 {
+		raw.Timeout = 5 * time.Second
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
@@ -0,0 +1,162 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/appclacks/cabourotte/tls"
+)
+
+// bufferSize the maximum number of traces waiting to be exported, the
+// new traces are dropped when the buffer is full
+const bufferSize = 10000
+
+// Tracer exports the healthchecks executions spans to an OTLP collector
+type Tracer struct {
+	Logger *zap.Logger
+	Config *Configuration
+	URL    string
+	Client *http.Client
+
+	lock  sync.Mutex
+	spans []*Span
+
+	tick *time.Ticker
+	t    tomb.Tomb
+}
+
+// New creates a new tracer
+func New(logger *zap.Logger, config *Configuration) (*Tracer, error) {
+	tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, "", config.Insecure)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to build the tracing TLS configuration")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &Tracer{
+		Logger: logger,
+		Config: config,
+		URL:    strings.TrimSuffix(config.Endpoint, "/") + "/v1/traces",
+		Client: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+		},
+	}, nil
+}
+
+// Sampled returns true if an execution should be traced
+func (t *Tracer) Sampled() bool {
+	return t.Config.SampleRatio >= 1 || rand.Float64() < t.Config.SampleRatio
+}
+
+// Export adds a span, with its children, to the spans to export
+func (t *Tracer) Export(span *Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if len(t.spans) >= bufferSize {
+		t.Logger.Debug("The tracing buffer is full, dropping the span")
+		return
+	}
+	t.spans = append(t.spans, span)
+}
+
+// payload builds the OTLP export request body
+func (t *Tracer) payload(spans []*Span) ([]byte, error) {
+	otlpSpans := []otlpSpan{}
+	for _, span := range spans {
+		otlpSpans = append(otlpSpans, span.flatten()...)
+	}
+	request := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]string{
+						"service.name": t.Config.ServiceName,
+					}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]string{
+							"name": "cabourotte",
+						},
+						"spans": otlpSpans,
+					},
+				},
+			},
+		},
+	}
+	return json.Marshal(request)
+}
+
+// flush sends the buffered spans to the collector
+func (t *Tracer) flush() error {
+	t.lock.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.lock.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := t.payload(spans)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to convert the spans to json")
+	}
+	request, err := http.NewRequest("POST", t.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return errors.Wrapf(err, "Fail to build the tracing request")
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for k, v := range t.Config.Headers {
+		request.Header.Set(k, v)
+	}
+	response, err := t.Client.Do(request)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to send the spans")
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("Fail to send the spans, the collector returned status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// Start starts exporting the spans periodically
+func (t *Tracer) Start() {
+	t.Logger.Info(fmt.Sprintf("Starting the tracer, exporting the spans to %s", t.URL))
+	t.tick = time.NewTicker(t.Config.Interval)
+	t.t.Go(func() error {
+		for {
+			select {
+			case <-t.tick.C:
+				err := t.flush()
+				if err != nil {
+					t.Logger.Error(err.Error())
+				}
+			case <-t.t.Dying():
+				return nil
+			}
+		}
+	})
+}
+
+// Stop stops the tracer, exporting the remaining spans
+func (t *Tracer) Stop() error {
+	t.Logger.Info("Stopping the tracer")
+	t.tick.Stop()
+	t.t.Kill(nil)
+	err := t.t.Wait()
+	if err != nil {
+		return err
+	}
+	return t.flush()
+}
@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfiguration(t *testing.T) {
+	cases := []struct {
+		in    string
+		valid bool
+	}{
+		{in: `endpoint: "http://localhost:4318"`, valid: true},
+		{in: `endpoint: "https://collector:4318/"
+sample-ratio: 0.5
+interval: 10s`, valid: true},
+		{in: `service-name: "foo"`, valid: false},
+		{in: `endpoint: "localhost:4318"`, valid: false},
+		{in: `endpoint: "http://localhost:4318"
+sample-ratio: 2`, valid: false},
+		{in: `endpoint: "http://localhost:4318"
+key: "/tmp/key"`, valid: false},
+	}
+	for _, c := range cases {
+		var config Configuration
+		err := yaml.Unmarshal([]byte(c.in), &config)
+		if c.valid && err != nil {
+			t.Fatalf("The configuration should be valid: %s\n%s", c.in, err.Error())
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("The configuration should be invalid: %s", c.in)
+		}
+	}
+	var config Configuration
+	err := yaml.Unmarshal([]byte(`endpoint: "http://localhost:4318"`), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration: %s", err.Error())
+	}
+	if config.ServiceName != DefaultServiceName || config.SampleRatio != 1 || config.Interval != 5*time.Second {
+		t.Fatalf("Invalid default values: %+v", config)
+	}
+}
+
+func TestExport(t *testing.T) {
+	var body map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Authorization") != "token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Fail to read the body: %s", err.Error())
+		}
+		err = json.Unmarshal(content, &body)
+		if err != nil {
+			t.Fatalf("Invalid body: %s", err.Error())
+		}
+	}))
+	defer ts.Close()
+	tracer, err := New(zap.NewExample(), &Configuration{
+		Endpoint:    ts.URL,
+		ServiceName: "cabourotte",
+		Headers:     map[string]string{"Authorization": "token"},
+		SampleRatio: 1,
+		Interval:    time.Hour,
+		Timeout:     time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the tracer: %s", err.Error())
+	}
+	tracer.Start()
+	start := time.Now()
+	span := NewSpan("healthcheck foo", start, start.Add(time.Second))
+	span.Error = "failure"
+	child := span.Child("connect", start, start.Add(time.Millisecond))
+	tracer.Export(span)
+	err = tracer.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the tracer: %s", err.Error())
+	}
+	if body == nil {
+		t.Fatalf("The spans were not exported")
+	}
+	resourceSpans := body["resourceSpans"].([]interface{})
+	scopeSpans := resourceSpans[0].(map[string]interface{})["scopeSpans"].([]interface{})
+	spans := scopeSpans[0].(map[string]interface{})["spans"].([]interface{})
+	if len(spans) != 2 {
+		t.Fatalf("Invalid number of spans: %d", len(spans))
+	}
+	root := spans[0].(map[string]interface{})
+	if root["traceId"] != span.TraceIDString() || root["name"] != "healthcheck foo" {
+		t.Fatalf("Invalid span: %v", root)
+	}
+	if root["status"].(map[string]interface{})["message"] != "failure" {
+		t.Fatalf("Invalid span status: %v", root)
+	}
+	exportedChild := spans[1].(map[string]interface{})
+	if exportedChild["traceId"] != span.TraceIDString() || exportedChild["parentSpanId"] != root["spanId"] {
+		t.Fatalf("Invalid child span: %v", exportedChild)
+	}
+	if child.ParentID != span.SpanID {
+		t.Fatalf("Invalid child parent")
+	}
+}
@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+const (
+	// kindInternal the OTLP internal span kind
+	kindInternal = 1
+	// kindClient the OTLP client span kind
+	kindClient = 3
+
+	// statusOK the OTLP ok status code
+	statusOK = 1
+	// statusError the OTLP error status code
+	statusError = 2
+)
+
+// Span a span, built once the traced operation is done
+type Span struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	ParentID   [8]byte
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	// Error the error message, the span status is ok if empty
+	Error    string
+	Children []*Span
+}
+
+// randomBytes fills the slice with random bytes
+func randomBytes(b []byte) {
+	// crypto/rand never returns an error on supported platforms
+	_, _ = rand.Read(b)
+}
+
+// NewSpan creates a root span, starting a new trace
+func NewSpan(name string, start time.Time, end time.Time) *Span {
+	span := &Span{
+		Name:       name,
+		Start:      start,
+		End:        end,
+		Attributes: make(map[string]string),
+	}
+	randomBytes(span.TraceID[:])
+	randomBytes(span.SpanID[:])
+	return span
+}
+
+// Child adds a child span to the span
+func (s *Span) Child(name string, start time.Time, end time.Time) *Span {
+	child := &Span{
+		TraceID:    s.TraceID,
+		ParentID:   s.SpanID,
+		Name:       name,
+		Start:      start,
+		End:        end,
+		Attributes: make(map[string]string),
+	}
+	randomBytes(child.SpanID[:])
+	s.Children = append(s.Children, child)
+	return child
+}
+
+// TraceIDString returns the hex encoded trace ID
+func (s *Span) TraceIDString() string {
+	return hex.EncodeToString(s.TraceID[:])
+}
+
+// otlpValue an OTLP attribute value
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// otlpAttribute an OTLP attribute
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpStatus an OTLP span status
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// otlpSpan an OTLP span
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+// otlpAttributes converts attributes to OTLP attributes
+func otlpAttributes(attributes map[string]string) []otlpAttribute {
+	result := make([]otlpAttribute, 0, len(attributes))
+	for k, v := range attributes {
+		result = append(result, otlpAttribute{Key: k, Value: otlpValue{StringValue: v}})
+	}
+	return result
+}
+
+// flatten converts the span and its children to OTLP spans
+func (s *Span) flatten() []otlpSpan {
+	kind := kindInternal
+	parent := ""
+	if s.ParentID != [8]byte{} {
+		kind = kindClient
+		parent = hex.EncodeToString(s.ParentID[:])
+	}
+	status := otlpStatus{Code: statusOK}
+	if s.Error != "" {
+		status = otlpStatus{Code: statusError, Message: s.Error}
+	}
+	result := []otlpSpan{
+		{
+			TraceID:           s.TraceIDString(),
+			SpanID:            hex.EncodeToString(s.SpanID[:]),
+			ParentSpanID:      parent,
+			Name:              s.Name,
+			Kind:              kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.Attributes),
+			Status:            status,
+		},
+	}
+	for _, child := range s.Children {
+		result = append(result, child.flatten()...)
+	}
+	return result
+}